@@ -50,6 +50,8 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/config-check", getConfigCheck).Methods("GET")
 	r.HandleFunc("/config", getRuntimeConfig).Methods("GET")
 	r.HandleFunc("/tagger-list", getTaggerList).Methods("GET")
+	r.HandleFunc("/containerd/containers/{id}/restart", restartContainerdTask).Methods("POST")
+	r.HandleFunc("/containerd/cgroups", getContainerdCgroups).Methods("GET")
 }
 
 func stopAgent(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// restartContainerdTask restarts the task of a container of the default
+// containerd namespace, for the remediations of the containers run without
+// an orchestrator. It requires containerd_allow_task_restart, read on every
+// request, the requests are logged for audit with their reason query
+// parameter.
+func restartContainerdTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	log.Infof("Audit: restart of containerd container %s requested through the agent API, reason: %q", id, r.URL.Query().Get("reason"))
+
+	// The options of the agent configuration are resolved once by the
+	// containerd util, the current ones are read instead
+	opts := containerdconfig.Options()
+	cu, err := containerd.GetContainerdUtil(&opts)
+	if err == nil {
+		err = cu.RestartTask(r.Context(), id)
+	}
+	if err != nil {
+		code := 500
+		if containerd.ErrorKind(err) == containerd.ErrRestartDisabled {
+			code = 403
+		}
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	j, _ := json.Marshal("")
+	w.Write(j)
+}
+
+// getContainerdCgroups lists the cgroups of the containers of the default
+// containerd namespace, for the system-probe to map the events of its eBPF
+// programs to the containers by cgroup ID or path.
+func getContainerdCgroups(w http.ResponseWriter, r *http.Request) {
+	cgroups, err := containerd.ContainerCgroups(config.Datadog.GetString("container_cgroup_root"))
+	if err != nil {
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	j, _ := json.Marshal(cgroups)
+	w.Write(j)
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	containerdcontainers "github.com/containerd/containerd/containers"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestRestartContainerdTask(t *testing.T) {
+	defer config.Datadog.Set("containerd_allow_task_restart", config.Datadog.GetBool("containerd_allow_task_restart"))
+	config.Datadog.Set("containerd_allow_task_restart", true)
+
+	daemon := containerdtest.NewDaemon()
+	ns := config.Datadog.GetString("containerd_namespace")
+	require.NoError(t, daemon.AddContainer(ns, &containerdtest.Container{Record: containerdcontainers.Container{ID: "redis"}}))
+	startedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, daemon.StartTask(ns, "redis", &containerdtest.Task{Pid: 42, StartedAt: startedAt}))
+	defer containerd.DefaultProvider().SetForTests(daemon.Util(ns))()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/containerd/containers/{id}/restart", restartContainerdTask).Methods("POST")
+	req := httptest.NewRequest("POST", "/containerd/containers/redis/restart?reason=oom", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// The task was started again
+	ctn, err := daemon.Util(ns).LoadContainer("redis")
+	require.NoError(t, err)
+	task := ctn.(*containerdtest.Container).TaskState
+	require.NotNil(t, task)
+	assert.True(t, task.StartedAt.After(startedAt))
+
+	// The restarts of unknown containers fail
+	req = httptest.NewRequest("POST", "/containerd/containers/nginx/restart", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !containerd
+
+package agent
+
+import (
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const noContainerdErrorString = "containerd is not compiled in this agent"
+
+func restartContainerdTask(w http.ResponseWriter, r *http.Request) {
+	log.Error(noContainerdErrorString)
+	http.Error(w, noContainerdErrorString, 500)
+}
+
+func getContainerdCgroups(w http.ResponseWriter, r *http.Request) {
+	log.Error(noContainerdErrorString)
+	http.Error(w, noContainerdErrorString, 500)
+}
@@ -26,4 +26,6 @@ type TaggerListResponse struct {
 type TaggerListEntity struct {
 	Sources []string `json:"sources"`
 	Tags    []string `json:"tags"`
+	// Provenance holds the sources of every tag, see Tags
+	Provenance map[string][]string `json:"provenance,omitempty"`
 }
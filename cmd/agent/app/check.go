@@ -8,6 +8,8 @@ package app
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
@@ -30,6 +32,10 @@ var (
 	checkName  string
 	checkDelay int
 	logLevel   string
+
+	// checkProfile prints the time spent in the phases of the checks
+	// implementing check.Profiler
+	checkProfile bool
 )
 
 // Make the check cmd aggregator never flush by setting a very high interval
@@ -42,6 +48,7 @@ func init() {
 	checkCmd.Flags().IntVarP(&checkTimes, "check-times", "t", 1, "number of times to run the check")
 	checkCmd.Flags().StringVarP(&logLevel, "log-level", "l", "", "set the log level (default 'off')")
 	checkCmd.Flags().IntVarP(&checkDelay, "delay", "d", 100, "delay between running the check and grabbing the metrics in miliseconds")
+	checkCmd.Flags().BoolVar(&checkProfile, "profile", false, "print the time spent in each phase of the check runs, for the checks supporting it")
 	checkCmd.SetArgs([]string{"checkName"})
 }
 
@@ -126,6 +133,14 @@ var checkCmd = &cobra.Command{
 		}
 
 		for _, c := range cs {
+			profiler, canProfile := c.(check.Profiler)
+			if checkProfile {
+				if canProfile {
+					profiler.EnableProfiling()
+				} else {
+					color.Yellow("The %s check does not support profiling", c)
+				}
+			}
 			s := runCheck(c, agg)
 
 			// Sleep for a while to allow the aggregator to finish ingesting all the metrics/events/sc
@@ -135,6 +150,10 @@ var checkCmd = &cobra.Command{
 
 			checkStatus, _ := status.GetCheckStatus(c, s)
 			fmt.Println(string(checkStatus))
+
+			if checkProfile && canProfile {
+				printProfile(profiler.Profile())
+			}
 		}
 
 		if checkRate == false && checkTimes < 2 {
@@ -196,3 +215,14 @@ func printMetrics(agg *aggregator.BufferedAggregator) {
 		fmt.Println(string(j))
 	}
 }
+
+// printProfile prints the timings of the phases of a check as a table
+func printProfile(timings []check.PhaseTiming) {
+	fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.BlueString("Profile")))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Phase\tCalls\tTotal\tAverage\tMax\t")
+	for _, t := range timings {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t\n", t.Phase, t.Calls, t.Total, t.Average(), t.Max)
+	}
+	w.Flush()
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	containerdCmd = &cobra.Command{
+		Use:   "containerd",
+		Short: "Query the containerd daemon monitored by the Agent",
+		Long:  ``,
+	}
+
+	containerdLogsCmd = &cobra.Command{
+		Use:   "logs <container-id>",
+		Short: "Print the logs of a container",
+		Long: `Prints the logs of a container created by the CRI plugin of containerd,
+read from the log file written by containerd, for the hosts without kubectl or
+crictl. The log file is found from the containerd metadata of the container.`,
+		Args: cobra.ExactArgs(1),
+		RunE: doContainerdLogs,
+	}
+
+	containerdNamespace  string
+	containerdLogsTail   int
+	containerdLogsFollow bool
+)
+
+func init() {
+	containerdCmd.PersistentFlags().StringVarP(&containerdNamespace, "namespace", "n", "", "containerd namespace of the container, defaults to the namespace of the configuration")
+	containerdLogsCmd.Flags().IntVar(&containerdLogsTail, "tail", -1, "number of lines to print from the end of the logs, all of them if negative")
+	containerdLogsCmd.Flags().BoolVarP(&containerdLogsFollow, "follow", "f", false, "print the new lines until interrupted")
+
+	containerdCmd.AddCommand(containerdLogsCmd)
+	AgentCmd.AddCommand(containerdCmd)
+}
+
+func doContainerdLogs(cmd *cobra.Command, args []string) error {
+	config.SetupLogger("off", "", "", false, true, false)
+	err := common.SetupConfig(confFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to set up global agent configuration: %v", err)
+	}
+
+	opts := containerdconfig.Options()
+	if containerdNamespace != "" {
+		opts.Namespace = containerdNamespace
+	}
+	cu, err := containerd.GetContainerdUtil(&opts)
+	if err != nil {
+		return fmt.Errorf("unable to connect to containerd: %v", err)
+	}
+	defer cu.Close()
+
+	path, err := containerd.ContainerLogPath(cu, args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalCh)
+	go func() {
+		select {
+		case <-signalCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return containerd.TailLog(ctx, path, containerdLogsTail, containerdLogsFollow, os.Stdout)
+}
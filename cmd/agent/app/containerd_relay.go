@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package app
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/containerdrelay"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The utils of the agents reading the containers from the cluster-agent,
+// with containerd_via_cluster_agent, are built by the relay for every command
+func init() {
+	containerd.DefaultProvider().SetRelay(func(o containerd.Options) containerd.ContainerdItf {
+		return containerdrelay.NewUtil(o.Namespace, o.Logger, fetchContainerdContainers)
+	})
+}
+
+// fetchContainerdContainers queries the cluster-agent for the containers of the node
+func fetchContainerdContainers() (containerdrelay.NodeContainers, error) {
+	hostname, err := util.GetHostname()
+	if err != nil {
+		return containerdrelay.NodeContainers{}, err
+	}
+	dca, err := clusteragent.GetClusterAgentClient()
+	if err != nil {
+		return containerdrelay.NodeContainers{}, err
+	}
+	return dca.GetContainerdContainers(hostname)
+}
+
+// startContainerdRelay posts the containers of the node to the cluster-agent
+// every containerd_relay_interval until the agent stops, if
+// containerd_relay_enabled is set. The relay runs in the privileged agent
+// mounting the containerd socket, the unprivileged agents of the node read
+// the containers from the cluster-agent with containerd_via_cluster_agent.
+func startContainerdRelay() error {
+	if !config.Datadog.GetBool("containerd_relay_enabled") {
+		return nil
+	}
+	interval := config.Datadog.GetDuration("containerd_relay_interval") * time.Second
+	if interval <= 0 {
+		return nil
+	}
+	hostname, err := util.GetHostname()
+	if err != nil {
+		return err
+	}
+	dca, err := clusteragent.GetClusterAgentClient()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := relayContainerdContainers(dca, hostname); err != nil {
+				log.Warnf("Cannot relay the containerd containers to the cluster-agent: %s", err)
+			}
+			select {
+			case <-common.MainCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// relayContainerdContainers posts the containers of the collected namespaces
+func relayContainerdContainers(dca *clusteragent.DCAClient, hostname string) error {
+	utils, err := containerd.GetNamespacedUtils()
+	if err != nil {
+		return err
+	}
+	snapshot, err := containerdrelay.Collect(utils)
+	if err != nil {
+		return err
+	}
+	return dca.PostContainerdContainers(hostname, snapshot)
+}
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !containerd
+
+package app
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func startContainerdRelay() error {
+	if config.Datadog.GetBool("containerd_relay_enabled") {
+		log.Warn("containerd_relay_enabled is set but the agent is built without containerd support")
+	}
+	return nil
+}
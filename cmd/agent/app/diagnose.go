@@ -6,11 +6,17 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/diagnose"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/fatih/color"
@@ -18,7 +24,15 @@ import (
 )
 
 func init() {
+	diagnoseCommand.AddCommand(diagnoseContainersCommand)
 	AgentCmd.AddCommand(diagnoseCommand)
+
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "tagger populated",
+		Stage:       diagnosis.StageTagger,
+		Run:         diagnoseTagger,
+		Remediation: "Check that the agent is running, and look for tagger collector errors in the agent logs",
+	})
 }
 
 var diagnoseCommand = &cobra.Command{
@@ -28,7 +42,35 @@ var diagnoseCommand = &cobra.Command{
 	Run:   doDiagnose,
 }
 
+var diagnoseContainersCommand = &cobra.Command{
+	Use:   "containers",
+	Short: "Execute a step by step diagnosis of the container monitoring",
+	Long: `Checks in sequence that the container runtime socket is reachable and
+accessible, that container stats can be fetched, that the kubelet is reachable
+and that the running agent tagger holds container tags. A remediation hint is
+printed for every failed step.`,
+	Run: doDiagnoseContainers,
+}
+
 func doDiagnose(cmd *cobra.Command, args []string) {
+	setupDiagnose()
+
+	err := diagnose.RunAll(color.Output)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func doDiagnoseContainers(cmd *cobra.Command, args []string) {
+	setupDiagnose()
+
+	err := diagnose.RunContainers(color.Output)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupDiagnose() {
 	// Global config setup
 	if confFilePath != "" {
 		if err := common.SetupConfig(confFilePath); err != nil {
@@ -53,9 +95,33 @@ func doDiagnose(cmd *cobra.Command, args []string) {
 		log.Errorf("Error while setting up logging, exiting: %v", err)
 		panic(err)
 	}
+}
 
-	err = diagnose.RunAll(color.Output)
+// diagnoseTagger checks that the tagger of the running agent knows containers
+func diagnoseTagger() error {
+	c := util.GetClient(false)
+	if err := util.SetAuthToken(); err != nil {
+		return err
+	}
+	r, err := util.DoGet(c, taggerListURL)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to query the agent (running?): %s", err)
+	}
+	tr := response.TaggerListResponse{}
+	if err = json.Unmarshal(r, &tr); err != nil {
+		return err
+	}
+
+	ctrCount := 0
+	for entity := range tr.Entities {
+		switch containers.RuntimeForEntity(entity) {
+		case containers.RuntimeNameDocker, containers.RuntimeNameContainerd, containers.RuntimeNameCRIO:
+			ctrCount++
+		}
+	}
+	log.Infof("the tagger holds %d entities, %d containers", len(tr.Entities), ctrCount)
+	if ctrCount == 0 {
+		return errors.New("no container in the tagger")
 	}
+	return nil
 }
@@ -241,6 +241,11 @@ func StartAgent() error {
 		log.Warnf("Metadata collection disabled, only do that if another agent/dogstatsd is running on this host")
 	}
 
+	// relay the containerd containers to the cluster-agent
+	if err = startContainerdRelay(); err != nil {
+		log.Errorf("Could not start the containerd relay: %v", err)
+	}
+
 	// start dependent services
 	startDependentServices()
 	return nil
@@ -318,6 +323,11 @@ func StopAgent() {
 	if common.MetadataScheduler != nil {
 		common.MetadataScheduler.Stop()
 	}
+	// dogstatsd is stopped, the buckets it filled can be checkpointed
+	// before the forwarder checkpoints its pending transactions
+	if err := aggregator.CheckpointDefaultAggregator(); err != nil {
+		log.Errorf("Could not checkpoint the aggregator: %s", err)
+	}
 	api.StopServer()
 	jmx.StopJmxfetch()
 	if common.Forwarder != nil {
@@ -23,6 +23,7 @@ import (
 var (
 	jsonStatus      bool
 	prettyPrintJSON bool
+	statusFormat    string
 	statusFilePath  string
 )
 
@@ -30,6 +31,7 @@ func init() {
 	AgentCmd.AddCommand(statusCmd)
 	statusCmd.Flags().BoolVarP(&jsonStatus, "json", "j", false, "print out raw json")
 	statusCmd.Flags().BoolVarP(&prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", "text", "output format: text, json or prometheus")
 	statusCmd.Flags().StringVarP(&statusFilePath, "file", "o", "", "Output the status command to a file")
 }
 
@@ -45,6 +47,13 @@ var statusCmd = &cobra.Command{
 		if flagNoColor {
 			color.NoColor = true
 		}
+		switch statusFormat {
+		case "text", "prometheus":
+		case "json":
+			jsonStatus = true
+		default:
+			return fmt.Errorf("unknown status format %q, valid formats are text, json and prometheus", statusFormat)
+		}
 		err = requestStatus()
 		if err != nil {
 			return err
@@ -54,7 +63,10 @@ var statusCmd = &cobra.Command{
 }
 
 func requestStatus() error {
-	fmt.Printf("Getting the status from the agent.\n\n")
+	// The machine-readable formats are printed alone to be parsable
+	if statusFormat == "text" && !jsonStatus && !prettyPrintJSON {
+		fmt.Printf("Getting the status from the agent.\n\n")
+	}
 	var e error
 	var s string
 	c := util.GetClient(false) // FIX: get certificates right then make this true
@@ -86,6 +98,12 @@ func requestStatus() error {
 		s = prettyJSON.String()
 	} else if jsonStatus {
 		s = string(r)
+	} else if statusFormat == "prometheus" {
+		promStatus, err := status.FormatStatusPrometheus(r)
+		if err != nil {
+			return err
+		}
+		s = promStatus
 	} else {
 		formattedStatus, err := status.FormatStatus(r)
 		if err != nil {
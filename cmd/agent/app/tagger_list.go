@@ -73,6 +73,10 @@ var taggerListCommand = &cobra.Command{
 			for i, tag := range tagItem.Tags {
 				tagInfo := strings.Split(tag, ":")
 				fmt.Fprintf(color.Output, fmt.Sprintf("%s:%s", color.BlueString(tagInfo[0]), color.CyanString(strings.Join(tagInfo[1:], ":"))))
+				// The source of every tag is printed if several sources tag the entity
+				if sources, found := tagItem.Provenance[tag]; found && len(tagItem.Sources) > 1 {
+					fmt.Fprint(color.Output, color.YellowString("(%s)", strings.Join(sources, ",")))
+				}
 				if i != len(tagItem.Tags)-1 {
 					fmt.Fprintf(color.Output, " ")
 				}
@@ -48,12 +48,13 @@ func SetupAutoConfig(confdPath string) {
 
 	// Add the configuration providers
 	// File Provider is hardocded and always enabled
+	// The confd_layers override the files of confdPath
+	confdLayers := append([]string{confdPath}, config.Datadog.GetStringSlice("confd_layers")...)
 	confSearchPaths := []string{
-		confdPath,
 		filepath.Join(GetDistPath(), "conf.d"),
 		"",
 	}
-	AC.AddConfigProvider(providers.NewFileConfigProvider(confSearchPaths), false, 0)
+	AC.AddConfigProvider(providers.NewLayeredFileConfigProvider(confdLayers, confSearchPaths), false, 0)
 
 	// Register additional configuration providers
 	var CP []config.ConfigurationProviders
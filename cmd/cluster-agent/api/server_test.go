@@ -51,6 +51,11 @@ func TestValidateTokenMiddleware(t *testing.T) {
 			"bandit!",
 			http.StatusForbidden,
 		},
+		{
+			"/api/v1/containerd/containers/node",
+			"abc123",
+			http.StatusOK,
+		},
 	}
 
 	for i, tt := range tests {
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/containerdrelay"
+)
+
+// installContainerdRelayEndpoints registers the endpoints relaying the
+// containerd metadata from the privileged relays to the node-agents
+func installContainerdRelayEndpoints(r *mux.Router, sc clusteragent.ServerContext) {
+	r.HandleFunc("/containerd/containers/{nodeName}", postContainerdContainers(sc)).Methods("POST")
+	r.HandleFunc("/containerd/containers/{nodeName}", getContainerdContainers(sc)).Methods("GET")
+}
+
+// postContainerdContainers is used by the containerd relay of the nodes
+func postContainerdContainers(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ContainerdRelayStore == nil {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeName := mux.Vars(r)["nodeName"]
+
+		var containers containerdrelay.NodeContainers
+		if err := json.NewDecoder(r.Body).Decode(&containers); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			apiRequests.WithLabelValues(
+				"postContainerdContainers",
+				strconv.Itoa(http.StatusBadRequest),
+			).Inc()
+			return
+		}
+		sc.ContainerdRelayStore.Post(nodeName, containers)
+		w.WriteHeader(http.StatusOK)
+		apiRequests.WithLabelValues(
+			"postContainerdContainers",
+			strconv.Itoa(http.StatusOK),
+		).Inc()
+	}
+}
+
+// getContainerdContainers is used by the node-agents querying containerd
+// through the cluster-agent
+func getContainerdContainers(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	if sc.ContainerdRelayStore == nil {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeName := mux.Vars(r)["nodeName"]
+
+		containers, err := sc.ContainerdRelayStore.Get(nodeName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			apiRequests.WithLabelValues(
+				"getContainerdContainers",
+				strconv.Itoa(http.StatusServiceUnavailable),
+			).Inc()
+			return
+		}
+		// writeJSONResponse is only built with the cluster checks
+		body, err := json.Marshal(containers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apiRequests.WithLabelValues(
+				"getContainerdContainers",
+				strconv.Itoa(http.StatusInternalServerError),
+			).Inc()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		apiRequests.WithLabelValues(
+			"getContainerdContainers",
+			strconv.Itoa(http.StatusOK),
+		).Inc()
+	}
+}
@@ -40,6 +40,7 @@ func Install(r *mux.Router, sc clusteragent.ServerContext) {
 	r.HandleFunc("/tags/pod", getAllMetadata).Methods("GET")
 	r.HandleFunc("/tags/node/{nodeName}", getNodeMetadata).Methods("GET")
 	installClusterCheckEndpoints(r, sc)
+	installContainerdRelayEndpoints(r, sc)
 }
 
 // getNodeMetadata is only used when the node agent hits the DCA for the list of labels
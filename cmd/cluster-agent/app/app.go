@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -24,6 +25,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/api/healthprobe"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/containerdrelay"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
@@ -195,7 +197,8 @@ func start(cmd *cobra.Command, args []string) error {
 	clusterCheckHandler := setupClusterCheck(mainCtx)
 	// start the cmd HTTPS server
 	sc := clusteragent.ServerContext{
-		ClusterCheckHandler: clusterCheckHandler,
+		ClusterCheckHandler:  clusterCheckHandler,
+		ContainerdRelayStore: setupContainerdRelay(),
 	}
 	if err = api.StartServer(sc); err != nil {
 		return log.Errorf("Error while starting api server, exiting: %v", err)
@@ -252,3 +255,12 @@ func setupClusterCheck(ctx context.Context) *clusterchecks.Handler {
 	log.Info("Started cluster check Autodiscovery")
 	return handler
 }
+
+func setupContainerdRelay() *containerdrelay.Store {
+	if !config.Datadog.GetBool("cluster_agent.containerd_relay.enabled") {
+		return nil
+	}
+	ttl := config.Datadog.GetDuration("cluster_agent.containerd_relay.ttl") * time.Second
+	log.Infof("Relaying the containerd metadata of the nodes, expired after %s", ttl)
+	return containerdrelay.NewStore(ttl)
+}
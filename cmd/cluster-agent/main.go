@@ -32,7 +32,7 @@ import (
 func main() {
 	// Expose the registered metrics via HTTP.
 	http.Handle("/metrics", promhttp.Handler())
-	go http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", config.Datadog.GetInt("metrics_port")), nil)
+	go http.ListenAndServe(fmt.Sprintf(":%d", config.Datadog.GetInt("metrics_port")), nil)
 
 	if err := app.ClusterAgentCmd.Execute(); err != nil {
 		log.Error(err)
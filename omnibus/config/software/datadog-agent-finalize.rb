@@ -34,6 +34,8 @@ build do
             delete "#{conf_dir}/apm.yaml.default"
             # load isn't supported by windows
             delete "#{conf_dir}/load.d"
+            # kernel_inventory is linux only
+            delete "#{conf_dir}/kernel_inventory.d"
 
             # cleanup clutter
             delete "#{install_dir}/etc"
@@ -88,6 +90,7 @@ build do
         elsif osx?
             # Remove linux specific configs
             delete "#{install_dir}/etc/conf.d/file_handle.d"
+            delete "#{install_dir}/etc/conf.d/kernel_inventory.d"
 
             # remove windows specific configs
             delete "#{install_dir}/etc/conf.d/winproc.d"
@@ -100,6 +100,8 @@ func init() {
 	newFlushTimeStats("EventFlushTime")
 	newFlushTimeStats("MainFlushTime")
 	newFlushTimeStats("MetricSketchFlushTime")
+	// Delay between the scheduled flush and its start
+	newFlushTimeStats("MainFlushDelay")
 	aggregatorExpvars.Set("Flush", expvar.Func(expStatsMap(flushTimeStats)))
 
 	newFlushCountStats("ServiceChecks")
@@ -133,6 +135,11 @@ func InitAggregator(s *serializer.Serializer, hostname, agentName string) *Buffe
 func InitAggregatorWithFlushInterval(s *serializer.Serializer, hostname, agentName string, flushInterval time.Duration) *BufferedAggregator {
 	aggregatorInit.Do(func() {
 		aggregatorInstance = NewBufferedAggregator(s, hostname, agentName, flushInterval)
+		if path := checkpointPath(); path != "" {
+			if err := aggregatorInstance.restoreCheckpoint(path, time.Now()); err != nil {
+				log.Warnf("Could not restore the aggregator checkpoint: %s", err)
+			}
+		}
 		go aggregatorInstance.run()
 	})
 
@@ -166,6 +173,9 @@ type BufferedAggregator struct {
 	TickerChan         <-chan time.Time // For test/benchmark purposes: it allows the flush to be controlled from the outside
 	health             *health.Handle
 	agentName          string // Name of the agent for telemetry metrics (agent / cluster-agent)
+
+	// checkpointIn receives the checkpoint requests, see checkpoint.go
+	checkpointIn chan checkpointRequest
 }
 
 // NewBufferedAggregator instantiates a BufferedAggregator
@@ -183,6 +193,7 @@ func NewBufferedAggregator(s *serializer.Serializer, hostname, agentName string,
 		hostname:           hostname,
 		hostnameUpdate:     make(chan string),
 		hostnameUpdateDone: make(chan struct{}),
+		checkpointIn:       make(chan checkpointRequest),
 		health:             health.Register("aggregator"),
 		agentName:          agentName,
 	}
@@ -259,6 +270,11 @@ func (agg *BufferedAggregator) handleSenderSample(ss senderMetricSample) {
 	if checkSampler, ok := agg.checkSamplers[ss.id]; ok {
 		if ss.commit {
 			checkSampler.commit(timeNowNano())
+		} else if ss.batch != nil {
+			for _, sample := range ss.batch {
+				sample.Tags = deduplicateTags(sample.Tags)
+				checkSampler.addSample(sample)
+			}
 		} else {
 			ss.metricSample.Tags = deduplicateTags(ss.metricSample.Tags)
 			checkSampler.addSample(ss.metricSample)
@@ -484,8 +500,9 @@ func (agg *BufferedAggregator) run() {
 	for {
 		select {
 		case <-agg.health.C:
-		case <-agg.TickerChan:
+		case tick := <-agg.TickerChan:
 			start := time.Now()
+			addFlushTime("MainFlushDelay", int64(start.Sub(tick)))
 			agg.flush()
 			addFlushTime("MainFlushTime", int64(time.Since(start)))
 			aggregatorNumberOfFlush.Add(1)
@@ -493,7 +510,11 @@ func (agg *BufferedAggregator) run() {
 			aggregatorDogstatsdMetricSample.Add(1)
 			agg.addSample(sample, timeNowNano())
 		case ss := <-agg.checkMetricIn:
-			aggregatorChecksMetricSample.Add(1)
+			if ss.batch != nil {
+				aggregatorChecksMetricSample.Add(int64(len(ss.batch)))
+			} else {
+				aggregatorChecksMetricSample.Add(1)
+			}
 			agg.handleSenderSample(ss)
 		case sc := <-agg.serviceCheckIn:
 			aggregatorServiceCheck.Add(1)
@@ -506,6 +527,8 @@ func (agg *BufferedAggregator) run() {
 			agg.hostname = h
 			changeAllSendersDefaultHostname(h)
 			agg.hostnameUpdateDone <- struct{}{}
+		case req := <-agg.checkpointIn:
+			req.done <- agg.writeCheckpoint(req.path, time.Now())
 		}
 	}
 }
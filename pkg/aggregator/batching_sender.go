@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package aggregator
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// BatchingSender is a Sender buffering the metric samples of a check run,
+// submitted with SendBatch once size samples are buffered and on Commit.
+// The service checks and the events are submitted right away. A
+// BatchingSender is not safe for concurrent use.
+type BatchingSender struct {
+	Sender
+	size    int
+	pending []*metrics.MetricSample
+}
+
+// NewBatchingSender returns a BatchingSender submitting the samples to
+// sender by batches of size samples
+func NewBatchingSender(sender Sender, size int) *BatchingSender {
+	return &BatchingSender{
+		Sender:  sender,
+		size:    size,
+		pending: make([]*metrics.MetricSample, 0, size),
+	}
+}
+
+func (b *BatchingSender) add(metric string, value float64, hostname string, tags []string, mType metrics.MetricType) {
+	b.pending = append(b.pending, &metrics.MetricSample{
+		Name:       metric,
+		Value:      value,
+		Mtype:      mType,
+		Tags:       tags,
+		Host:       hostname,
+		SampleRate: 1,
+		Timestamp:  timeNowNano(),
+	})
+	if len(b.pending) >= b.size {
+		b.Flush()
+	}
+}
+
+// Flush submits the buffered samples
+func (b *BatchingSender) Flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	// The batch belongs to the aggregator once submitted
+	b.Sender.SendBatch(b.pending)
+	b.pending = make([]*metrics.MetricSample, 0, b.size)
+}
+
+// Commit submits the buffered samples, then commits the run
+func (b *BatchingSender) Commit() {
+	b.Flush()
+	b.Sender.Commit()
+}
+
+// RemainingPointBudget accounts for the buffered samples
+func (b *BatchingSender) RemainingPointBudget() int64 {
+	remaining := b.Sender.RemainingPointBudget()
+	if remaining == UnlimitedPointBudget {
+		return remaining
+	}
+	if remaining <= int64(len(b.pending)) {
+		return 0
+	}
+	return remaining - int64(len(b.pending))
+}
+
+// SendBatch submits the buffered samples, then samples
+func (b *BatchingSender) SendBatch(samples []*metrics.MetricSample) {
+	b.Flush()
+	b.Sender.SendBatch(samples)
+}
+
+// Gauge buffers a gauge sample
+func (b *BatchingSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.GaugeType)
+}
+
+// Rate buffers a rate sample
+func (b *BatchingSender) Rate(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.RateType)
+}
+
+// Count buffers a count sample
+func (b *BatchingSender) Count(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.CountType)
+}
+
+// MonotonicCount buffers a monotonic count sample
+func (b *BatchingSender) MonotonicCount(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.MonotonicCountType)
+}
+
+// Counter buffers a counter sample
+func (b *BatchingSender) Counter(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.CounterType)
+}
+
+// Histogram buffers a histogram sample
+func (b *BatchingSender) Histogram(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.HistogramType)
+}
+
+// Historate buffers a historate sample
+func (b *BatchingSender) Historate(metric string, value float64, hostname string, tags []string) {
+	b.add(metric, value, hostname, tags, metrics.HistorateType)
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestBatchingSender(t *testing.T) {
+	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
+	checkSender := newCheckSender(checkID1, "default-hostname", senderMetricSampleChan, serviceCheckChan, nil)
+	checkSender.SetPointBudget(10)
+	sender := NewBatchingSender(checkSender, 3)
+
+	sender.Gauge("my.metric", 1.0, "", []string{"foo"})
+	sender.Rate("my.rate_metric", 2.0, "my-hostname", nil)
+	assert.Len(t, senderMetricSampleChan, 0)
+	// The buffered samples count in the budget
+	assert.EqualValues(t, 8, sender.RemainingPointBudget())
+
+	// The service checks are not buffered
+	sender.ServiceCheck("my_service.can_connect", metrics.ServiceCheckOK, "", nil, "")
+	assert.Len(t, serviceCheckChan, 1)
+
+	// The batch is submitted once full
+	sender.MonotonicCount("my.monotonic_count_metric", 12.0, "", nil)
+	require.Len(t, senderMetricSampleChan, 1)
+	ss := <-senderMetricSampleChan
+	require.Len(t, ss.batch, 3)
+	assert.Equal(t, &metrics.MetricSample{Name: "my.metric", Value: 1.0, Mtype: metrics.GaugeType, Tags: []string{"foo"}, Host: "default-hostname", SampleRate: 1, Timestamp: ss.batch[0].Timestamp}, ss.batch[0])
+	assert.Equal(t, "my-hostname", ss.batch[1].Host)
+	assert.Equal(t, metrics.MonotonicCountType, ss.batch[2].Mtype)
+	assert.EqualValues(t, 7, sender.RemainingPointBudget())
+
+	// The pending samples are submitted before the commit
+	sender.Histogram("my.histo_metric", 3.0, "", nil)
+	sender.Commit()
+	require.Len(t, senderMetricSampleChan, 2)
+	ss = <-senderMetricSampleChan
+	require.Len(t, ss.batch, 1)
+	assert.Equal(t, metrics.HistogramType, ss.batch[0].Mtype)
+	ss = <-senderMetricSampleChan
+	assert.True(t, ss.commit)
+
+	// Nothing is submitted when nothing is pending
+	sender.Commit()
+	ss = <-senderMetricSampleChan
+	assert.True(t, ss.commit)
+}
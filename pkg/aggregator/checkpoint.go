@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// checkpointVersion is the version of the checkpoint format, the checkpoints
+// of other versions are discarded
+const checkpointVersion = 1
+
+// checkpointFileName is the name of the aggregator checkpoint in checkpoint_path
+const checkpointFileName = "aggregator.json"
+
+// aggregatorCheckpoint is the state of the dogstatsd buckets written on
+// shutdown, so that the next agent process resumes their aggregation
+// rather than sending partial buckets
+type aggregatorCheckpoint struct {
+	Version        int                 `json:"version"`
+	AgentVersion   string              `json:"agent_version"`
+	CreatedAt      int64               `json:"created_at"`
+	Interval       int64               `json:"interval"`
+	LastCutOffTime int64               `json:"last_cutoff_time"`
+	Contexts       []contextCheckpoint `json:"contexts"`
+	Buckets        []bucketCheckpoint  `json:"buckets"`
+	Counters       []counterCheckpoint `json:"counters"`
+}
+
+type contextCheckpoint struct {
+	Key      ckey.ContextKey `json:"key"`
+	Context  Context         `json:"context"`
+	LastSeen float64         `json:"last_seen"`
+}
+
+type bucketCheckpoint struct {
+	Timestamp int64                             `json:"timestamp"`
+	Metrics   []metrics.ContextMetricCheckpoint `json:"metrics"`
+}
+
+type counterCheckpoint struct {
+	Key         ckey.ContextKey `json:"key"`
+	LastSampled float64         `json:"last_sampled"`
+}
+
+// checkpointRequest is handled by the run loop, as the sampler is not
+// protected by a lock
+type checkpointRequest struct {
+	path string
+	done chan error
+}
+
+// checkpointPath returns the path of the aggregator checkpoint, or an
+// empty string if checkpoint_on_shutdown is disabled
+func checkpointPath() string {
+	if !config.Datadog.GetBool("checkpoint_on_shutdown") {
+		return ""
+	}
+	return filepath.Join(config.Datadog.GetString("checkpoint_path"), checkpointFileName)
+}
+
+// CheckpointDefaultAggregator writes the dogstatsd buckets of the default
+// aggregator to disk if checkpoint_on_shutdown is enabled. It is meant to be
+// called on shutdown, once dogstatsd is stopped.
+func CheckpointDefaultAggregator() error {
+	path := checkpointPath()
+	if path == "" || aggregatorInstance == nil {
+		return nil
+	}
+	return aggregatorInstance.Checkpoint(path)
+}
+
+// Checkpoint writes the dogstatsd buckets, including the ones not complete
+// yet, to path and removes them from the aggregator so that they are not
+// flushed by this process. The sketches and the series of the checks are
+// flushed as usual.
+func (agg *BufferedAggregator) Checkpoint(path string) error {
+	done := make(chan error)
+	agg.checkpointIn <- checkpointRequest{path: path, done: done}
+	return <-done
+}
+
+// writeCheckpoint must be called from the run loop
+func (agg *BufferedAggregator) writeCheckpoint(path string, now time.Time) error {
+	cp := agg.sampler.checkpoint()
+	cp.CreatedAt = now.Unix()
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// The contexts can hold sensitive tags
+	if err := util.WriteFileAtomic(path, content, 0600); err != nil {
+		return fmt.Errorf("cannot write the aggregator checkpoint: %s", err)
+	}
+	// The zeros of the counters would overwrite the checkpointed buckets
+	agg.sampler.metricsByTimestamp = map[int64]metrics.ContextMetrics{}
+	agg.sampler.counterLastSampledByContext = map[ckey.ContextKey]float64{}
+	log.Infof("Wrote %d dogstatsd buckets to %s", len(cp.Buckets), path)
+	return nil
+}
+
+// restoreCheckpoint restores the buckets written by a previous process, the
+// checkpoint is removed so that it is never restored twice. It must be
+// called before the run loop starts.
+func (agg *BufferedAggregator) restoreCheckpoint(path string, now time.Time) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("cannot remove the aggregator checkpoint, it is not restored: %s", err)
+	}
+
+	var cp aggregatorCheckpoint
+	if err := json.Unmarshal(content, &cp); err != nil {
+		return fmt.Errorf("cannot decode the aggregator checkpoint: %s", err)
+	}
+	switch {
+	case cp.Version != checkpointVersion:
+		return fmt.Errorf("the aggregator checkpoint written by agent %s has version %d, %d is expected", cp.AgentVersion, cp.Version, checkpointVersion)
+	case cp.Interval != agg.sampler.interval:
+		return fmt.Errorf("the aggregator checkpoint has an interval of %ds, %ds is expected", cp.Interval, agg.sampler.interval)
+	case float64(now.Unix()-cp.CreatedAt) > defaultExpiry:
+		return fmt.Errorf("the aggregator checkpoint is older than %ds", int(defaultExpiry))
+	}
+	if err := agg.sampler.restore(&cp); err != nil {
+		return err
+	}
+	log.Infof("Restored %d dogstatsd buckets written by agent %s", len(cp.Buckets), cp.AgentVersion)
+	return nil
+}
+
+// checkpoint returns the state of the buckets and of their contexts
+func (s *TimeSampler) checkpoint() *aggregatorCheckpoint {
+	cp := &aggregatorCheckpoint{
+		Version:        checkpointVersion,
+		AgentVersion:   version.AgentVersion,
+		Interval:       s.interval,
+		LastCutOffTime: s.lastCutOffTime,
+	}
+	for key, context := range s.contextResolver.contextsByKey {
+		cp.Contexts = append(cp.Contexts, contextCheckpoint{
+			Key:      key,
+			Context:  *context,
+			LastSeen: s.contextResolver.lastSeenByKey[key],
+		})
+	}
+	for timestamp, contextMetrics := range s.metricsByTimestamp {
+		cp.Buckets = append(cp.Buckets, bucketCheckpoint{Timestamp: timestamp, Metrics: contextMetrics.Checkpoint()})
+	}
+	for key, lastSampled := range s.counterLastSampledByContext {
+		cp.Counters = append(cp.Counters, counterCheckpoint{Key: key, LastSampled: lastSampled})
+	}
+	return cp
+}
+
+// restore adds the buckets of a checkpoint to the sampler. The buckets
+// already sampled by this process are kept, their samples cannot be merged.
+func (s *TimeSampler) restore(cp *aggregatorCheckpoint) error {
+	buckets := make(map[int64]metrics.ContextMetrics, len(cp.Buckets))
+	for _, b := range cp.Buckets {
+		contextMetrics, err := metrics.RestoreContextMetrics(b.Metrics)
+		if err != nil {
+			return fmt.Errorf("cannot restore the bucket %d: %s", b.Timestamp, err)
+		}
+		buckets[b.Timestamp] = contextMetrics
+	}
+
+	for _, c := range cp.Contexts {
+		context := c.Context
+		if _, found := s.contextResolver.contextsByKey[c.Key]; !found {
+			s.contextResolver.contextsByKey[c.Key] = &context
+		}
+		if c.LastSeen > s.contextResolver.lastSeenByKey[c.Key] {
+			s.contextResolver.lastSeenByKey[c.Key] = c.LastSeen
+		}
+	}
+	for timestamp, contextMetrics := range buckets {
+		if _, found := s.metricsByTimestamp[timestamp]; found {
+			log.Warnf("The dogstatsd bucket %d was already sampled, the checkpoint is ignored for it", timestamp)
+			continue
+		}
+		s.metricsByTimestamp[timestamp] = contextMetrics
+	}
+	for _, c := range cp.Counters {
+		if c.LastSampled > s.counterLastSampledByContext[c.Key] {
+			s.counterLastSampledByContext[c.Key] = c.LastSampled
+		}
+	}
+	if cp.LastCutOffTime > s.lastCutOffTime {
+		s.lastCutOffTime = cp.LastCutOffTime
+	}
+	return nil
+}
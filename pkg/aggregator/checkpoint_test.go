@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package aggregator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestCheckpointRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggregator-checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint", checkpointFileName)
+	now := time.Unix(12346, 0)
+
+	count := metrics.MetricSample{Name: "my.count", Value: 3, Mtype: metrics.CountType, Tags: []string{"foo"}, SampleRate: 1}
+	gauge := metrics.MetricSample{Name: "my.gauge", Value: 7, Mtype: metrics.GaugeType, SampleRate: 1}
+
+	// The first process samples the beginning of the bucket, then stops
+	previous := NewBufferedAggregator(nil, "", "agent", time.Hour)
+	previous.addSample(&count, 12345)
+	previous.addSample(&gauge, 12335)
+	require.NoError(t, previous.writeCheckpoint(path, now))
+	assert.Empty(t, previous.sampler.metricsByTimestamp)
+	assert.Empty(t, previous.sampler.flush(12360))
+
+	// The next process resumes the aggregation of the bucket
+	next := NewBufferedAggregator(nil, "", "agent", time.Hour)
+	require.NoError(t, next.restoreCheckpoint(path, now.Add(10*time.Second)))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	count.Value = 2
+	next.addSample(&count, 12347)
+	series := next.sampler.flush(12360)
+	require.Len(t, series, 2)
+	for _, serie := range series {
+		switch serie.Name {
+		case "my.count":
+			assert.Equal(t, []metrics.Point{{Ts: 12340, Value: 5}}, serie.Points)
+			assert.Equal(t, []string{"foo"}, serie.Tags)
+		case "my.gauge":
+			assert.Equal(t, []metrics.Point{{Ts: 12330, Value: 7}}, serie.Points)
+		default:
+			t.Errorf("unexpected serie %s", serie.Name)
+		}
+	}
+
+	// The checkpoint is only restored once
+	assert.NoError(t, next.restoreCheckpoint(path, now))
+}
+
+func TestRestoreCheckpointDiscarded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggregator-checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, checkpointFileName)
+	now := time.Unix(12346, 0)
+
+	for name, tc := range map[string]struct {
+		content string
+		now     time.Time
+	}{
+		"other version":  {`{"version": 2, "interval": 10, "created_at": 12346}`, now},
+		"other interval": {`{"version": 1, "interval": 15, "created_at": 12346}`, now},
+		"too old":        {`{"version": 1, "interval": 10, "created_at": 12346}`, now.Add(time.Hour)},
+		"corrupted":      {`{"version": 1, "interv`, now},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.content), 0600))
+			agg := NewBufferedAggregator(nil, "", "agent", time.Hour)
+			assert.Error(t, agg.restoreCheckpoint(path, tc.now))
+			assert.Empty(t, agg.sampler.metricsByTimestamp)
+			_, err := os.Stat(path)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
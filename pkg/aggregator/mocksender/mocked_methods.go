@@ -44,6 +44,36 @@ func (m *MockSender) Gauge(metric string, value float64, hostname string, tags [
 	m.Called(metric, value, hostname, tags)
 }
 
+//SendRawMetricSample enables the raw sample mock call.
+func (m *MockSender) SendRawMetricSample(sample *metrics.MetricSample) {
+	m.Called(sample)
+}
+
+//SendBatch records each sample of the batch as a call of its metric type,
+//so that the batched samples are asserted like the others.
+func (m *MockSender) SendBatch(samples []*metrics.MetricSample) {
+	for _, s := range samples {
+		switch s.Mtype {
+		case metrics.GaugeType:
+			m.Gauge(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.RateType:
+			m.Rate(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.CountType:
+			m.Count(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.MonotonicCountType:
+			m.MonotonicCount(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.CounterType:
+			m.Counter(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.HistogramType:
+			m.Histogram(s.Name, s.Value, s.Host, s.Tags)
+		case metrics.HistorateType:
+			m.Historate(s.Name, s.Value, s.Host, s.Tags)
+		default:
+			m.SendRawMetricSample(s)
+		}
+	}
+}
+
 //ServiceCheck enables the service check mock call.
 func (m *MockSender) ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string) {
 	m.Called(checkName, status, hostname, tags, message)
@@ -69,3 +99,18 @@ func (m *MockSender) GetMetricStats() map[string]int64 {
 	m.Called()
 	return make(map[string]int64)
 }
+
+//SetPointBudget enables the set point budget mock call.
+func (m *MockSender) SetPointBudget(budget int64) {
+	m.Called(budget)
+}
+
+//RemainingPointBudget enables the remaining point budget mock call.
+func (m *MockSender) RemainingPointBudget() int64 {
+	return m.Called().Get(0).(int64)
+}
+
+//Backpressure enables the backpressure mock call.
+func (m *MockSender) Backpressure() bool {
+	return m.Called().Bool(0)
+}
@@ -48,9 +48,13 @@ func (m *MockSender) SetupAcceptAll() {
 		mock.AnythingOfType("[]string"),                   // Tags
 		mock.AnythingOfType("string"),                     // message
 	).Return()
+	m.On("SendRawMetricSample", mock.AnythingOfType("*metrics.MetricSample")).Return()
 	m.On("Event", mock.AnythingOfType("metrics.Event")).Return()
 	m.On("GetMetricStats", mock.AnythingOfType("map[string]int64")).Return()
 	m.On("DisableDefaultHostname", mock.AnythingOfType("bool")).Return()
+	m.On("SetPointBudget", mock.AnythingOfType("int64")).Return()
+	m.On("RemainingPointBudget").Return(int64(aggregator.UnlimitedPointBudget))
+	m.On("Backpressure").Return(false)
 	m.On("Commit").Return()
 }
 
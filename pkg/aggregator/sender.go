@@ -14,9 +14,18 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
+// UnlimitedPointBudget is the remaining point budget of a sender without budget
+const UnlimitedPointBudget = -1
+
+// saturationRatio is the fill ratio of the aggregator input queue above
+// which the senders report backpressure
+const saturationRatio = 0.8
+
 var senderInstance *checkSender
 var senderInit sync.Once
 var senderPool *checkSenderPool
@@ -31,10 +40,14 @@ type Sender interface {
 	Counter(metric string, value float64, hostname string, tags []string)
 	Histogram(metric string, value float64, hostname string, tags []string)
 	Historate(metric string, value float64, hostname string, tags []string)
+	SendBatch(samples []*metrics.MetricSample)
 	ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string)
 	Event(e metrics.Event)
 	GetMetricStats() map[string]int64
 	DisableDefaultHostname(disable bool)
+	SetPointBudget(budget int64)
+	RemainingPointBudget() int64
+	Backpressure() bool
 }
 
 type metricStats struct {
@@ -56,6 +69,7 @@ type checkSender struct {
 	id                      check.ID
 	defaultHostname         string
 	defaultHostnameDisabled bool
+	pointBudget             int64 // metric samples per run, 0 is unlimited
 	metricStats             metricStats
 	priormetricStats        metricStats
 	smsOut                  chan<- senderMetricSample
@@ -67,6 +81,9 @@ type senderMetricSample struct {
 	id           check.ID
 	metricSample *metrics.MetricSample
 	commit       bool
+	// batch holds the samples submitted at once with SendBatch, the
+	// metricSample is nil
+	batch []*metrics.MetricSample
 }
 
 type checkSenderPool struct {
@@ -84,6 +101,7 @@ func newCheckSender(id check.ID, defaultHostname string, smsOut chan<- senderMet
 	return &checkSender{
 		id:               id,
 		defaultHostname:  defaultHostname,
+		pointBudget:      config.Datadog.GetInt64("check_point_budget"),
 		smsOut:           smsOut,
 		serviceCheckOut:  serviceCheckOut,
 		eventOut:         eventOut,
@@ -152,10 +170,44 @@ func (s *checkSender) DisableDefaultHostname(disable bool) {
 	s.defaultHostnameDisabled = disable
 }
 
+// SetPointBudget overrides the number of metric samples the check is
+// expected to submit per run, 0 removes the budget. The budget is advisory:
+// samples over budget are still submitted.
+func (s *checkSender) SetPointBudget(budget int64) {
+	s.metricStats.Lock.Lock()
+	defer s.metricStats.Lock.Unlock()
+	s.pointBudget = budget
+}
+
+// RemainingPointBudget returns the number of metric samples the check can
+// still submit during the current run, or UnlimitedPointBudget if it has
+// no budget. Checks can sample their metrics when it runs low.
+func (s *checkSender) RemainingPointBudget() int64 {
+	s.metricStats.Lock.RLock()
+	defer s.metricStats.Lock.RUnlock()
+	if s.pointBudget <= 0 {
+		return UnlimitedPointBudget
+	}
+	if s.metricStats.MetricSamples >= s.pointBudget {
+		return 0
+	}
+	return s.pointBudget - s.metricStats.MetricSamples
+}
+
+// Backpressure returns whether the aggregator input queue is nearly full or
+// the forwarder is dropping payloads. Submissions block or are lost while
+// the pipeline is saturated, so checks should submit fewer metrics.
+func (s *checkSender) Backpressure() bool {
+	if float64(len(s.smsOut)) >= saturationRatio*float64(cap(s.smsOut)) {
+		return true
+	}
+	return forwarder.IsSaturated()
+}
+
 // Commit commits the metric samples that were added during a check run
 // Should be called at the end of every check run
 func (s *checkSender) Commit() {
-	s.smsOut <- senderMetricSample{s.id, &metrics.MetricSample{}, true}
+	s.smsOut <- senderMetricSample{s.id, &metrics.MetricSample{}, true, nil}
 	s.cyclemetricStats()
 }
 
@@ -186,8 +238,12 @@ func (s *checkSender) cyclemetricStats() {
 
 // SendRawMetricSample sends the raw sample
 // Useful for testing - submitting precomputed samples.
+// The default hostname is set if the sample has none.
 func (s *checkSender) SendRawMetricSample(sample *metrics.MetricSample) {
-	s.smsOut <- senderMetricSample{s.id, sample, false}
+	if sample.Host == "" && !s.defaultHostnameDisabled {
+		sample.Host = s.defaultHostname
+	}
+	s.smsOut <- senderMetricSample{s.id, sample, false, nil}
 }
 
 func (s *checkSender) sendMetricSample(metric string, value float64, hostname string, tags []string, mType metrics.MetricType) {
@@ -207,7 +263,7 @@ func (s *checkSender) sendMetricSample(metric string, value float64, hostname st
 		metricSample.Host = s.defaultHostname
 	}
 
-	s.smsOut <- senderMetricSample{s.id, metricSample, false}
+	s.smsOut <- senderMetricSample{s.id, metricSample, false, nil}
 
 	s.metricStats.Lock.Lock()
 	s.metricStats.MetricSamples++
@@ -253,6 +309,36 @@ func (s *checkSender) Historate(metric string, value float64, hostname string, t
 	s.sendMetricSample(metric, value, hostname, tags, metrics.HistorateType)
 }
 
+// SendBatch submits samples to the aggregator at once, with a single
+// queueing and locking of the aggregator instead of one per sample, for the
+// checks submitting thousands of samples per run. The samples without
+// timestamp are timestamped now, and the default hostname is set if they
+// have none. The samples must not be modified after submission.
+func (s *checkSender) SendBatch(samples []*metrics.MetricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	now := timeNowNano()
+	for _, sample := range samples {
+		if sample.Timestamp == 0 {
+			sample.Timestamp = now
+		}
+		if sample.SampleRate == 0 {
+			sample.SampleRate = 1
+		}
+		if sample.Host == "" && !s.defaultHostnameDisabled {
+			sample.Host = s.defaultHostname
+		}
+	}
+	log.Trace("Batch of ", len(samples), " samples")
+
+	s.smsOut <- senderMetricSample{s.id, nil, false, samples}
+
+	s.metricStats.Lock.Lock()
+	s.metricStats.MetricSamples += int64(len(samples))
+	s.metricStats.Lock.Unlock()
+}
+
 // SendRawServiceCheck sends the raw service check
 // Useful for testing - submitting precomputed service check.
 func (s *checkSender) SendRawServiceCheck(sc *metrics.ServiceCheck) {
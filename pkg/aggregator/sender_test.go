@@ -256,6 +256,12 @@ func TestCheckSenderHostname(t *testing.T) {
 				SourceTypeName: "docker",
 			}
 			checkSender.Event(submittedEvent)
+			checkSender.SendRawMetricSample(&metrics.MetricSample{
+				Name:  "my.raw.metric",
+				Value: 1.0,
+				Mtype: metrics.GaugeType,
+				Host:  tc.submittedHostname,
+			})
 
 			gaugeSenderSample := <-senderMetricSampleChan
 			assert.EqualValues(t, checkID1, gaugeSenderSample.id)
@@ -263,6 +269,12 @@ func TestCheckSenderHostname(t *testing.T) {
 			assert.Equal(t, tc.expectedHostname, gaugeSenderSample.metricSample.Host)
 			assert.Equal(t, false, gaugeSenderSample.commit)
 
+			// Skip the commit
+			<-senderMetricSampleChan
+			rawSenderSample := <-senderMetricSampleChan
+			assert.Equal(t, "my.raw.metric", rawSenderSample.metricSample.Name)
+			assert.Equal(t, tc.expectedHostname, rawSenderSample.metricSample.Host)
+
 			serviceCheck := <-serviceCheckChan
 			assert.Equal(t, "my_service.can_connect", serviceCheck.CheckName)
 			assert.Equal(t, metrics.ServiceCheckOK, serviceCheck.Status)
@@ -300,3 +312,70 @@ func TestChangeAllSendersDefaultHostname(t *testing.T) {
 	gaugeSenderSample = <-senderMetricSampleChan
 	assert.Equal(t, "hostname1", gaugeSenderSample.metricSample.Host)
 }
+
+func TestCheckSenderPointBudget(t *testing.T) {
+	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, nil, nil)
+	assert.EqualValues(t, UnlimitedPointBudget, checkSender.RemainingPointBudget())
+
+	checkSender.SetPointBudget(2)
+	assert.EqualValues(t, 2, checkSender.RemainingPointBudget())
+	checkSender.Gauge("my.metric", 1.0, "", nil)
+	assert.EqualValues(t, 1, checkSender.RemainingPointBudget())
+
+	// The budget is advisory, samples over budget are submitted
+	checkSender.Gauge("my.metric", 1.0, "", nil)
+	checkSender.Gauge("my.metric", 1.0, "", nil)
+	assert.EqualValues(t, 0, checkSender.RemainingPointBudget())
+	assert.Len(t, senderMetricSampleChan, 3)
+
+	// The budget is per run
+	checkSender.Commit()
+	assert.EqualValues(t, 2, checkSender.RemainingPointBudget())
+
+	checkSender.SetPointBudget(0)
+	assert.EqualValues(t, UnlimitedPointBudget, checkSender.RemainingPointBudget())
+}
+
+func TestCheckSenderBackpressure(t *testing.T) {
+	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, nil, nil)
+
+	for i := 0; i < 7; i++ {
+		checkSender.Gauge("my.metric", 1.0, "", nil)
+	}
+	assert.False(t, checkSender.Backpressure())
+
+	checkSender.Gauge("my.metric", 1.0, "", nil)
+	assert.True(t, checkSender.Backpressure())
+
+	<-senderMetricSampleChan
+	assert.False(t, checkSender.Backpressure())
+}
+
+func TestCheckSenderSendBatch(t *testing.T) {
+	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	checkSender := newCheckSender(checkID1, "default-hostname", senderMetricSampleChan, nil, nil)
+	checkSender.SetPointBudget(5)
+
+	checkSender.SendBatch(nil)
+	assert.Len(t, senderMetricSampleChan, 0)
+
+	checkSender.SendBatch([]*metrics.MetricSample{
+		{Name: "my.metric", Value: 1.0, Mtype: metrics.GaugeType, Host: "my-hostname"},
+		{Name: "my.rate_metric", Value: 2.0, Mtype: metrics.RateType, Timestamp: 12},
+	})
+	require.Len(t, senderMetricSampleChan, 1)
+	assert.EqualValues(t, 3, checkSender.RemainingPointBudget())
+
+	ss := <-senderMetricSampleChan
+	assert.EqualValues(t, checkID1, ss.id)
+	assert.Nil(t, ss.metricSample)
+	assert.False(t, ss.commit)
+	require.Len(t, ss.batch, 2)
+	assert.Equal(t, "my-hostname", ss.batch[0].Host)
+	assert.NotZero(t, ss.batch[0].Timestamp)
+	assert.EqualValues(t, 1, ss.batch[0].SampleRate)
+	assert.Equal(t, "default-hostname", ss.batch[1].Host)
+	assert.EqualValues(t, 12, ss.batch[1].Timestamp)
+}
@@ -27,7 +27,8 @@ func Serve(ctx context.Context, port int) error {
 	if port == 0 {
 		return errors.New("port should be non-zero")
 	}
-	ln, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%v", port))
+	// Listen on every IPv4 and IPv6 interface
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
 	if err != nil {
 		return err
 	}
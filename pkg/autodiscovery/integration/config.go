@@ -54,6 +54,7 @@ type Config struct {
 type CommonInstanceConfig struct {
 	MinCollectionInterval int    `yaml:"min_collection_interval"`
 	EmptyDefaultHostname  bool   `yaml:"empty_default_hostname"`
+	PointBudget           int64  `yaml:"point_budget"`
 	Name                  string `yaml:"name"`
 	Namespace             string `yaml:"namespace"`
 }
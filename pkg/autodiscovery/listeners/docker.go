@@ -59,6 +59,9 @@ func init() {
 
 // NewDockerListener creates a client connection to Docker and instantiate a DockerListener with it
 func NewDockerListener() (ServiceListener, error) {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeNameDocker); err != nil {
+		return nil, err
+	}
 	d, err := docker.GetDockerUtil()
 	if err != nil {
 		return nil, err
@@ -191,7 +191,7 @@ func (l *ECSListener) createService(c ecs.Container, firstRun bool) (ECSService,
 
 // GetEntity returns the unique entity name linked to that service
 func (s *ECSService) GetEntity() string {
-	return containers.BuildEntityName(s.runtime, s.cID)
+	return containers.BuildContainerEntityName(s.runtime, s.cID)
 }
 
 // GetADIdentifiers returns a set of AD identifiers for a container.
@@ -18,6 +18,11 @@ import (
 
 const defaultGraceDuration = 60 * time.Second
 
+// registryMirrorsStatus returns the health of the container registry mirrors
+// of the node, reported along the node status. It is set by the container
+// runtimes compiled in.
+var registryMirrorsStatus func() []types.RegistryMirrorStatus
+
 // ClusterChecksConfigProvider implements the ConfigProvider interface
 // for the cluster check feature.
 type ClusterChecksConfigProvider struct {
@@ -78,6 +83,9 @@ func (c *ClusterChecksConfigProvider) IsUpToDate() (bool, error) {
 	status := types.NodeStatus{
 		LastChange: c.lastChange,
 	}
+	if registryMirrorsStatus != nil && config.Datadog.GetBool("containerd_report_registry_mirrors") {
+		status.RegistryMirrors = registryMirrorsStatus()
+	}
 
 	reply, err := c.dcaClient.PostClusterCheckStatus(c.nodeName, status)
 	if err != nil {
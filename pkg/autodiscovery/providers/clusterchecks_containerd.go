@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package providers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	registryMirrorProbeInterval = 60 * time.Second
+	registryMirrorProbeTimeout  = 2 * time.Second
+)
+
+// registryMirrorProber caches the health of the containerd registry mirrors,
+// as the node status is reported more often than the mirrors are probed
+type registryMirrorProber struct {
+	m         sync.Mutex
+	client    *http.Client
+	lastProbe time.Time
+	statuses  []types.RegistryMirrorStatus
+	// For testing
+	listMirrors func() ([]containerd.RegistryMirror, error)
+}
+
+func newRegistryMirrorProber() *registryMirrorProber {
+	return &registryMirrorProber{
+		client:      &http.Client{Timeout: registryMirrorProbeTimeout},
+		listMirrors: listContainerdRegistryMirrors,
+	}
+}
+
+func listContainerdRegistryMirrors() ([]containerd.RegistryMirror, error) {
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil {
+		return nil, err
+	}
+	return cu.RegistryMirrors()
+}
+
+// status returns the cached statuses, probing the mirrors again if they are outdated
+func (p *registryMirrorProber) status() []types.RegistryMirrorStatus {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if time.Since(p.lastProbe) < registryMirrorProbeInterval {
+		return p.statuses
+	}
+	p.lastProbe = time.Now()
+
+	mirrors, err := p.listMirrors()
+	if err != nil {
+		log.Debugf("Cannot list the containerd registry mirrors: %s", err)
+		p.statuses = nil
+		return nil
+	}
+
+	// Probe the mirrors concurrently to bound the status report delay
+	statuses := make([]types.RegistryMirrorStatus, len(mirrors))
+	var wg sync.WaitGroup
+	for i, mirror := range mirrors {
+		wg.Add(1)
+		go func(i int, mirror containerd.RegistryMirror) {
+			defer wg.Done()
+			statuses[i] = types.RegistryMirrorStatus{
+				Registry: mirror.Registry,
+				Endpoint: mirror.Endpoint,
+				Healthy:  true,
+			}
+			if err := containerd.CheckRegistryMirror(p.client, mirror.Endpoint); err != nil {
+				statuses[i].Healthy = false
+				statuses[i].Message = err.Error()
+			}
+		}(i, mirror)
+	}
+	wg.Wait()
+
+	p.statuses = statuses
+	return statuses
+}
+
+func init() {
+	registryMirrorsStatus = newRegistryMirrorProber().status
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestRegistryMirrorProber(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer unhealthy.Close()
+
+	listCalls := 0
+	prober := newRegistryMirrorProber()
+	prober.listMirrors = func() ([]containerd.RegistryMirror, error) {
+		listCalls++
+		return []containerd.RegistryMirror{
+			{Registry: "docker.io", Endpoint: healthy.URL},
+			{Registry: "quay.io", Endpoint: unhealthy.URL},
+		}, nil
+	}
+
+	statuses := prober.status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "docker.io", statuses[0].Registry)
+	assert.Equal(t, healthy.URL, statuses[0].Endpoint)
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, "quay.io", statuses[1].Registry)
+	assert.False(t, statuses[1].Healthy)
+	assert.Contains(t, statuses[1].Message, "502")
+
+	// Statuses are cached until the next probe interval
+	assert.Equal(t, statuses, prober.status())
+	assert.Equal(t, 1, listCalls)
+
+	prober.lastProbe = time.Now().Add(-registryMirrorProbeInterval)
+	prober.status()
+	assert.Equal(t, 2, listCalls)
+}
@@ -45,6 +45,8 @@ type configEntry struct {
 
 // FileConfigProvider collect configuration files from disk
 type FileConfigProvider struct {
+	// layers are searched before paths, by decreasing priority
+	layers []string
 	paths  []string
 	Errors map[string]string
 }
@@ -58,6 +60,19 @@ func NewFileConfigProvider(paths []string) *FileConfigProvider {
 	}
 }
 
+// NewLayeredFileConfigProvider creates a new FileConfigProvider searching for
+// configuration files in layered directories, ordered by increasing priority,
+// then on the given paths. A file of a layer overrides the files with the same
+// relative path in the lower layers, eg. host/redisdb.d/conf.yaml replaces
+// base/redisdb.d/conf.yaml. The files of paths are all collected.
+func NewLayeredFileConfigProvider(layers []string, paths []string) *FileConfigProvider {
+	c := NewFileConfigProvider(paths)
+	for i := len(layers) - 1; i >= 0; i-- {
+		c.layers = append(c.layers, layers[i])
+	}
+	return c
+}
+
 // Collect scans provided paths searching for configuration files. When found,
 // it parses the files and try to unmarshall Yaml contents into a CheckConfig
 // instance
@@ -65,8 +80,18 @@ func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
 	configs := []integration.Config{}
 	configNames := make(map[string]struct{}) // use this map as a python set
 	defaultConfigs := []integration.Config{}
+	// relative paths of the files found in the higher layers
+	overridden := make(map[string]struct{})
+
+	searchPaths := make([]string, 0, len(c.layers)+len(c.paths))
+	searchPaths = append(searchPaths, c.layers...)
+	searchPaths = append(searchPaths, c.paths...)
 
-	for _, path := range c.paths {
+	for i, path := range searchPaths {
+		var layerFiles map[string]struct{}
+		if i < len(c.layers) {
+			layerFiles = overridden
+		}
 		log.Infof("%v: searching for configuration files at: %s", c, path)
 
 		entries, err := readDirPtr(path)
@@ -78,7 +103,7 @@ func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
 		for _, entry := range entries {
 			// We support only one level of nesting for check configs
 			if entry.IsDir() {
-				dirConfigs := c.collectDir(path, entry)
+				dirConfigs := c.collectDir(path, entry, layerFiles)
 				if len(dirConfigs.defaults) > 0 {
 					defaultConfigs = append(defaultConfigs, dirConfigs.defaults...)
 				}
@@ -93,6 +118,10 @@ func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
 				continue
 			}
 
+			if isOverridden(layerFiles, entry.Name()) {
+				log.Debugf("Skipping %s, overridden by a higher layer", filepath.Join(path, entry.Name()))
+				continue
+			}
 			entry := c.collectEntry(entry, path, "")
 			// we don't collect metric files from the root dir (which check is it for? that's nonsensical!)
 			if entry.err != nil || entry.isMetric {
@@ -193,8 +222,22 @@ func (c *FileConfigProvider) collectEntry(file os.FileInfo, path string, integra
 	return entry
 }
 
+// isOverridden returns whether a file of a layer is overridden by a higher
+// layer, and marks it as found for the lower ones. layerFiles is nil if the
+// file is not in a layer.
+func isOverridden(layerFiles map[string]struct{}, relPath string) bool {
+	if layerFiles == nil {
+		return false
+	}
+	if _, found := layerFiles[relPath]; found {
+		return true
+	}
+	layerFiles[relPath] = struct{}{}
+	return false
+}
+
 // collectDir collects entries in subdirectories of the main conf folder
-func (c *FileConfigProvider) collectDir(parentPath string, folder os.FileInfo) configPkg {
+func (c *FileConfigProvider) collectDir(parentPath string, folder os.FileInfo, layerFiles map[string]struct{}) configPkg {
 	configs := []integration.Config{}
 	defaultConfigs := []integration.Config{}
 	otherConfigs := []integration.Config{}
@@ -220,6 +263,10 @@ func (c *FileConfigProvider) collectDir(parentPath string, folder os.FileInfo) c
 	// try to load any config file in it
 	for _, sEntry := range subEntries {
 		if !sEntry.IsDir() {
+			if isOverridden(layerFiles, filepath.Join(folder.Name(), sEntry.Name())) {
+				log.Debugf("Skipping %s, overridden by a higher layer", filepath.Join(dirPath, sEntry.Name()))
+				continue
+			}
 
 			entry := c.collectEntry(sEntry, dirPath, integrationName)
 			if entry.err != nil {
@@ -125,3 +125,19 @@ func TestCollect(t *testing.T) {
 	// incorrect configs get saved in the Errors map (invalid.yaml & notaconfig.yaml & ad_deprecated.yaml)
 	assert.Equal(t, 3, len(provider.Errors))
 }
+
+func TestCollectLayers(t *testing.T) {
+	provider := NewLayeredFileConfigProvider([]string{"tests/layers/base", "tests/layers/site", "tests/layers/host"}, []string{"tests/layers/base"})
+	configs, err := provider.Collect()
+	assert.Nil(t, err)
+
+	layers := make(map[string][]string)
+	for _, c := range configs {
+		layers[c.Name] = append(layers[c.Name], string(c.InitConfig)+string(c.MetricConfig))
+	}
+
+	// the highest layer wins, file by file, the files of the search
+	// paths are not overridden
+	assert.ElementsMatch(t, []string{"layer: host\n", "layer: base\n"}, layers["http_check"])
+	assert.ElementsMatch(t, []string{"layer: site\n", "- include:\n    domain: site\n", "layer: base\n"}, layers["redisdb"])
+}
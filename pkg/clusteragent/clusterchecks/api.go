@@ -8,6 +8,8 @@
 package clusterchecks
 
 import (
+	"errors"
+
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 )
 
@@ -48,3 +50,14 @@ func (h *Handler) PostStatus(nodeName string, status types.NodeStatus) (types.St
 	}
 	return response, err
 }
+
+// GetRegistryMirrorsHealth returns the health of the container registry
+// mirrors, aggregated from the status reports of the node agents
+func (h *Handler) GetRegistryMirrorsHealth() ([]types.RegistryMirrorHealth, error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if h.dispatcher == nil {
+		return nil, errors.New("cluster-check discovery is not running")
+	}
+	return h.dispatcher.getRegistryMirrorsHealth(), nil
+}
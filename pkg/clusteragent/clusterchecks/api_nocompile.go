@@ -13,6 +13,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
 )
 
 var (
@@ -34,6 +35,16 @@ func NewHandler(_ *autodiscovery.AutoConfig) (*Handler, error) {
 	return nil, ErrNotCompiled
 }
 
+// GetHandler not implemented
+func GetHandler() (*Handler, error) {
+	return nil, ErrNotCompiled
+}
+
+// GetRegistryMirrorsHealth not implemented
+func (h *Handler) GetRegistryMirrorsHealth() ([]types.RegistryMirrorHealth, error) {
+	return nil, ErrNotCompiled
+}
+
 // Run not implemented
 func (h *Handler) Run(_ context.Context) error {
 	return ErrNotCompiled
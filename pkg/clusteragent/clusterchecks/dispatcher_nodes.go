@@ -9,6 +9,7 @@ package clusterchecks
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
@@ -45,6 +46,49 @@ func (d *dispatcher) processNodeStatus(nodeName string, status types.NodeStatus)
 	return (node.lastConfigChange == status.LastChange), nil
 }
 
+// getRegistryMirrorsHealth aggregates the registry mirror statuses
+// last reported by the nodes, sorted by registry and endpoint
+func (d *dispatcher) getRegistryMirrorsHealth() []types.RegistryMirrorHealth {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	mirrors := make(map[string]*types.RegistryMirrorHealth)
+	for name, node := range d.store.nodes {
+		node.RLock()
+		for _, status := range node.lastStatus.RegistryMirrors {
+			key := status.Registry + "|" + status.Endpoint
+			health, found := mirrors[key]
+			if !found {
+				health = &types.RegistryMirrorHealth{
+					Registry:       status.Registry,
+					Endpoint:       status.Endpoint,
+					UnhealthyNodes: make(map[string]string),
+				}
+				mirrors[key] = health
+			}
+			if status.Healthy {
+				health.HealthyNodes = append(health.HealthyNodes, name)
+			} else {
+				health.UnhealthyNodes[name] = status.Message
+			}
+		}
+		node.RUnlock()
+	}
+
+	healths := make([]types.RegistryMirrorHealth, 0, len(mirrors))
+	for _, health := range mirrors {
+		sort.Strings(health.HealthyNodes)
+		healths = append(healths, *health)
+	}
+	sort.Slice(healths, func(i, j int) bool {
+		if healths[i].Registry != healths[j].Registry {
+			return healths[i].Registry < healths[j].Registry
+		}
+		return healths[i].Endpoint < healths[j].Endpoint
+	})
+	return healths
+}
+
 // getLeastBusyNode returns the name of the node that is assigned
 // the lowest number of checks. In case of equality, one is chosen
 // randomly, based on map iterations being randomized.
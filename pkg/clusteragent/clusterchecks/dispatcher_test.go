@@ -130,6 +130,48 @@ func TestProcessNodeStatus(t *testing.T) {
 	requireNotLocked(t, dispatcher.store)
 }
 
+func TestGetRegistryMirrorsHealth(t *testing.T) {
+	dispatcher := newDispatcher()
+	assert.Len(t, dispatcher.getRegistryMirrorsHealth(), 0)
+
+	dispatcher.processNodeStatus("nodeA", types.NodeStatus{
+		RegistryMirrors: []types.RegistryMirrorStatus{
+			{Registry: "docker.io", Endpoint: "https://mirror.local", Healthy: true},
+			{Registry: "quay.io", Endpoint: "https://quay-mirror.local", Healthy: false, Message: "connection refused"},
+		},
+	})
+	dispatcher.processNodeStatus("nodeB", types.NodeStatus{
+		RegistryMirrors: []types.RegistryMirrorStatus{
+			{Registry: "docker.io", Endpoint: "https://mirror.local", Healthy: true},
+		},
+	})
+	dispatcher.processNodeStatus("nodeC", types.NodeStatus{})
+
+	assert.Equal(t, []types.RegistryMirrorHealth{
+		{
+			Registry:       "docker.io",
+			Endpoint:       "https://mirror.local",
+			HealthyNodes:   []string{"nodeA", "nodeB"},
+			UnhealthyNodes: map[string]string{},
+		},
+		{
+			Registry:       "quay.io",
+			Endpoint:       "https://quay-mirror.local",
+			UnhealthyNodes: map[string]string{"nodeA": "connection refused"},
+		},
+	}, dispatcher.getRegistryMirrorsHealth())
+
+	// Expired nodes are not accounted for
+	nodeB, _ := dispatcher.store.getNodeStore("nodeB")
+	nodeB.heartbeat = timestampNow() - 35
+	dispatcher.expireNodes()
+	healths := dispatcher.getRegistryMirrorsHealth()
+	assert.Len(t, healths, 2)
+	assert.Equal(t, []string{"nodeA"}, healths[0].HealthyNodes)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
 func TestGetLeastBusyNode(t *testing.T) {
 	dispatcher := newDispatcher()
 
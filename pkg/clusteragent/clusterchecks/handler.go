@@ -19,6 +19,11 @@ const (
 	schedulerName = "clusterchecks"
 )
 
+var (
+	globalHandler    *Handler
+	globalHandlerMux sync.Mutex
+)
+
 // The handler is the glue holding all components for cluster-checks management
 type Handler struct {
 	m          sync.Mutex
@@ -35,9 +40,24 @@ func NewHandler(ac *autodiscovery.AutoConfig) (*Handler, error) {
 	h := &Handler{
 		autoconfig: ac,
 	}
+
+	globalHandlerMux.Lock()
+	globalHandler = h
+	globalHandlerMux.Unlock()
 	return h, nil
 }
 
+// GetHandler returns the Handler created by NewHandler, for the
+// cluster-level checks reporting on the node-agents statuses
+func GetHandler() (*Handler, error) {
+	globalHandlerMux.Lock()
+	defer globalHandlerMux.Unlock()
+	if globalHandler == nil {
+		return nil, errors.New("cluster-check discovery is not enabled")
+	}
+	return globalHandler, nil
+}
+
 // Run is the main goroutine for the handler. It has to
 // be called in a goroutine with a cancellable context.
 func (h *Handler) Run(ctx context.Context) {
@@ -9,7 +9,26 @@ import "github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 
 // NodeStatus holds the status report from the node-agent
 type NodeStatus struct {
-	LastChange int64 `json:"last_change"`
+	LastChange      int64                  `json:"last_change"`
+	RegistryMirrors []RegistryMirrorStatus `json:"registry_mirrors,omitempty"`
+}
+
+// RegistryMirrorStatus holds the health of a container registry
+// mirror, as seen by a node-agent
+type RegistryMirrorStatus struct {
+	Registry string `json:"registry"`
+	Endpoint string `json:"endpoint"`
+	Healthy  bool   `json:"healthy"`
+	Message  string `json:"message,omitempty"`
+}
+
+// RegistryMirrorHealth aggregates the statuses of a registry
+// mirror reported by all the node-agents
+type RegistryMirrorHealth struct {
+	Registry       string
+	Endpoint       string
+	HealthyNodes   []string
+	UnhealthyNodes map[string]string // Node name to error message
 }
 
 // StatusResponse holds the DCA response for a status report
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdrelay
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Collect returns the snapshot of the containers of every namespace of
+// utils, posted to the cluster-agent by the relay of the node
+func Collect(utils []containerd.ContainerdItf) (NodeContainers, error) {
+	snapshot := NodeContainers{
+		Timestamp:  time.Now(),
+		Namespaces: make(map[string][]Container, len(utils)),
+	}
+	for i, cu := range utils {
+		if i == 0 {
+			version, err := cu.Metadata()
+			if err != nil {
+				return snapshot, err
+			}
+			snapshot.Version, snapshot.Revision = version.Version, version.Revision
+		}
+		ctns, err := cu.Containers()
+		if err != nil {
+			return snapshot, err
+		}
+		relayed := make([]Container, 0, len(ctns))
+		for _, ctn := range ctns {
+			info, err := cu.Info(ctn)
+			if err != nil {
+				// The container was deleted since the listing
+				log.Debugf("Cannot get the info of container %s in namespace %s: %s", ctn.ID(), cu.Namespace(), err)
+				continue
+			}
+			relayed = append(relayed, Container{
+				ID:          info.ID,
+				Image:       info.Image,
+				Labels:      info.Labels,
+				CreatedAt:   info.CreatedAt,
+				UpdatedAt:   info.UpdatedAt,
+				StartedAt:   ctn.StartedAt(),
+				Runtime:     info.Runtime.Name,
+				SnapshotKey: info.SnapshotKey,
+				SandboxID:   info.SandboxID,
+			})
+		}
+		snapshot.Namespaces[cu.Namespace()] = relayed
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+/*
+Package containerdrelay holds the containerd metadata relayed through the
+cluster-agent, for the nodes where the containerd socket is only mounted
+in a privileged relay and not in the node-agent. The relay posts the
+snapshots built by Collect, stored by the cluster-agent in a Store, and the
+node-agent serves them with a Util.
+*/
+package containerdrelay
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store keeps the last metadata pushed by the relay of every node. The
+// metadata of a node is expired when its relay did not push it for ttl.
+type Store struct {
+	ttl time.Duration
+
+	m     sync.RWMutex
+	nodes map[string]NodeContainers
+}
+
+// NewStore returns an empty Store
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:   ttl,
+		nodes: make(map[string]NodeContainers),
+	}
+}
+
+// Post replaces the metadata of a node
+func (s *Store) Post(nodeName string, containers NodeContainers) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.nodes[nodeName] = containers
+}
+
+// Get returns the metadata of a node, an error if its relay never
+// pushed it or if it expired
+func (s *Store) Get(nodeName string) (NodeContainers, error) {
+	return s.get(nodeName, time.Now())
+}
+
+func (s *Store) get(nodeName string, now time.Time) (NodeContainers, error) {
+	s.m.RLock()
+	containers, found := s.nodes[nodeName]
+	s.m.RUnlock()
+	if !found {
+		return NodeContainers{}, fmt.Errorf("no containerd metadata relayed for node %s", nodeName)
+	}
+	if now.Sub(containers.Timestamp) > s.ttl {
+		s.m.Lock()
+		// The relay may have pushed since
+		if current := s.nodes[nodeName]; current.Timestamp.Equal(containers.Timestamp) {
+			delete(s.nodes, nodeName)
+		}
+		s.m.Unlock()
+		return NodeContainers{}, fmt.Errorf("the containerd metadata of node %s expired, last relayed at %s", nodeName, containers.Timestamp)
+	}
+	return containers, nil
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerdrelay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(time.Minute)
+	now := time.Now()
+
+	_, err := s.get("node1", now)
+	assert.Error(t, err)
+
+	posted := NodeContainers{
+		Timestamp: now,
+		Version:   "v1.7.0",
+		Namespaces: map[string][]Container{
+			"k8s.io": {{ID: "redis", Image: "redis:5", Runtime: "io.containerd.runc.v2"}},
+		},
+	}
+	s.Post("node1", posted)
+	containers, err := s.get("node1", now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, posted, containers)
+
+	// The relay stopped pushing
+	_, err = s.get("node1", now.Add(2*time.Minute))
+	assert.Error(t, err)
+	assert.Empty(t, s.nodes)
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerdrelay
+
+import "time"
+
+// NodeContainers is the containerd metadata of a node, pushed to the
+// cluster-agent by the privileged relay of the node and served to the
+// unprivileged node-agent
+type NodeContainers struct {
+	// Timestamp is the time the metadata was collected by the relay
+	Timestamp time.Time `json:"timestamp"`
+	Version   string    `json:"version"`
+	Revision  string    `json:"revision"`
+	// Namespaces maps the collected namespaces to their containers
+	Namespaces map[string][]Container `json:"namespaces"`
+}
+
+// Container is the metadata of a containerd container, StartedAt is zero if
+// it has no running task
+type Container struct {
+	ID          string            `json:"id"`
+	Image       string            `json:"image"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	StartedAt   time.Time         `json:"started_at"`
+	Runtime     string            `json:"runtime"`
+	SnapshotKey string            `json:"snapshot_key,omitempty"`
+	SandboxID   string            `json:"sandbox_id,omitempty"`
+}
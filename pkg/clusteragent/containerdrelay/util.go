@@ -0,0 +1,434 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdrelay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/sandbox"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+// snapshotTTL is the time the snapshot of the Util is reused before
+// querying the cluster-agent again
+const snapshotTTL = 5 * time.Second
+
+// Util is a containerd.ContainerdItf serving the container metadata posted
+// to the cluster-agent by the containerd relay of the node, see Collect. It
+// lets the agent collect the containers without mounting the containerd
+// socket. Only the container metadata is relayed: the task, image and
+// daemon queries return ErrUnsupported errors, and no event is received.
+type Util struct {
+	namespace string
+	log       containerd.Logger
+	// fetch returns the snapshot of the node
+	fetch func() (NodeContainers, error)
+
+	mu        sync.Mutex
+	snapshot  NodeContainers
+	fetchedAt time.Time
+	fetchErr  error
+
+	// closed ends the event subscriptions once the util is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ containerd.ContainerdItf = &Util{}
+
+// NewUtil returns a Util of a namespace, serving the snapshots of the node
+// returned by fetch
+func NewUtil(namespace string, log containerd.Logger, fetch func() (NodeContainers, error)) *Util {
+	return &Util{
+		namespace: namespace,
+		log:       log,
+		fetch:     fetch,
+		closed:    make(chan struct{}),
+	}
+}
+
+// nodeContainers returns the last snapshot of the node, fetched again
+// when older than snapshotTTL
+func (r *Util) nodeContainers() (NodeContainers, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.fetchedAt) < snapshotTTL {
+		return r.snapshot, r.fetchErr
+	}
+	snapshot, err := r.fetch()
+	r.fetchedAt = time.Now()
+	if err != nil {
+		r.log.Debugf("Cannot get the containerd containers from the cluster-agent: %s", err)
+		r.fetchErr = &containerd.Error{Kind: containerd.ErrNotServing, Err: fmt.Errorf("cannot get the containers from the cluster-agent: %s", err)}
+		return r.snapshot, r.fetchErr
+	}
+	r.snapshot, r.fetchErr = snapshot, nil
+	return r.snapshot, nil
+}
+
+// containers returns the relayed containers of the namespace of the util
+func (r *Util) containers() ([]Container, error) {
+	snapshot, err := r.nodeContainers()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Namespaces[r.namespace], nil
+}
+
+// container returns a relayed container of the namespace of the util
+func (r *Util) container(id string) (*relayContainer, error) {
+	ctns, err := r.containers()
+	if err != nil {
+		return nil, err
+	}
+	for _, ctn := range ctns {
+		if ctn.ID == id {
+			return newRelayContainer(ctn), nil
+		}
+	}
+	return nil, fmt.Errorf("container %q in namespace %q: %w", id, r.namespace, errdefs.ErrNotFound)
+}
+
+// unsupported returns the error of the queries the relay does not serve
+func (r *Util) unsupported(query string) error {
+	return &containerd.Error{Kind: containerd.ErrUnsupported, Err: fmt.Errorf("%s is not relayed by the cluster-agent", query)}
+}
+
+// CachedContainers implements containerd.ContainerdItf, the relayed
+// containers have no spec
+func (r *Util) CachedContainers() ([]containerd.CachedContainer, error) {
+	ctns, err := r.containers()
+	if err != nil {
+		return nil, err
+	}
+	cached := make([]containerd.CachedContainer, 0, len(ctns))
+	for _, ctn := range ctns {
+		cached = append(cached, containerd.CachedContainer{
+			ID:        ctn.ID,
+			Name:      containerd.ContainerNameResolver().Resolve(ctn.ID, ctn.Labels, nil),
+			Image:     ctn.Image,
+			Labels:    ctn.Labels,
+			CreatedAt: ctn.CreatedAt,
+			SandboxID: ctn.SandboxID,
+
+			RuntimeHandler: containerd.RuntimeHandler(ctn.Runtime),
+		})
+	}
+	return cached, nil
+}
+
+// Capabilities implements containerd.ContainerdItf
+func (r *Util) Capabilities() (*containerd.Capabilities, error) {
+	return nil, r.unsupported("the capabilities of the daemon")
+}
+
+// CaptureTaskOutput implements containerd.ContainerdItf
+func (r *Util) CaptureTaskOutput(ctx context.Context, ctn containerdclient.Container, limit int) (*containerd.TaskOutput, error) {
+	return nil, r.unsupported("the capture of the task outputs")
+}
+
+// Close implements containerd.ContainerdItf, the event subscriptions end
+// with an error so that the long-lived consumers get a new util
+func (r *Util) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+	return nil
+}
+
+// CollectAll implements containerd.ContainerdItf
+func (r *Util) CollectAll(ctx context.Context) ([]containerd.ContainerTaskMetrics, error) {
+	return nil, r.unsupported("the task metrics")
+}
+
+// ConfigDump implements containerd.ContainerdItf
+func (r *Util) ConfigDump() (*containerd.ConfigDump, error) {
+	return nil, r.unsupported("the configuration of the daemon")
+}
+
+// Containers implements containerd.ContainerdItf
+func (r *Util) Containers() ([]containerd.Container, error) {
+	ctns, err := r.containers()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]containerd.Container, 0, len(ctns))
+	for _, ctn := range ctns {
+		result = append(result, containerd.NewContainer(newRelayContainer(ctn), ctn.CreatedAt, ctn.StartedAt))
+	}
+	return result, nil
+}
+
+// ContentSizes implements containerd.ContainerdItf
+func (r *Util) ContentSizes() (map[string]int64, error) {
+	return nil, r.unsupported("the content store")
+}
+
+// ContentStatuses implements containerd.ContainerdItf
+func (r *Util) ContentStatuses() ([]content.Status, error) {
+	return nil, r.unsupported("the content store")
+}
+
+// ContentStoreUsage implements containerd.ContainerdItf
+func (r *Util) ContentStoreUsage(ctx context.Context) (*containerd.ContentUsage, error) {
+	return nil, r.unsupported("the content store")
+}
+
+// Exec implements containerd.ContainerdItf
+func (r *Util) Exec(ctx context.Context, ctn containerdclient.Container, cmd []string) (*containerd.ExecResult, error) {
+	return nil, r.unsupported("the exec of commands")
+}
+
+// GetEvents implements containerd.ContainerdItf, the subscriptions receive
+// no event
+func (r *Util) GetEvents() containerdclient.EventService {
+	return relayEventService{closed: r.closed}
+}
+
+// Health implements containerd.ContainerdItf, the relay is not serving if
+// the cluster-agent cannot be queried
+func (r *Util) Health() error {
+	_, err := r.nodeContainers()
+	return err
+}
+
+// ImageManifest implements containerd.ContainerdItf
+func (r *Util) ImageManifest(ctn containerdclient.Container) (*containerd.ImageManifest, error) {
+	return nil, r.unsupported("the image manifests")
+}
+
+// ImageSize implements containerd.ContainerdItf
+func (r *Util) ImageSize(ctn containerdclient.Container) (int64, error) {
+	return 0, r.unsupported("the image sizes")
+}
+
+// Images implements containerd.ContainerdItf
+func (r *Util) Images() ([]containerd.Image, error) {
+	return nil, r.unsupported("the image store")
+}
+
+// Info implements containerd.ContainerdItf
+func (r *Util) Info(ctn containerdclient.Container) (containers.Container, error) {
+	c, err := r.container(ctn.ID())
+	if err != nil {
+		return containers.Container{}, err
+	}
+	return c.info, nil
+}
+
+// LeaseContent implements containerd.ContainerdItf
+func (r *Util) LeaseContent(ctx context.Context, desc ocispec.Descriptor) error {
+	return r.unsupported("the leases")
+}
+
+// LoadContainer implements containerd.ContainerdItf
+func (r *Util) LoadContainer(id string) (containerdclient.Container, error) {
+	return r.container(id)
+}
+
+// Metadata implements containerd.ContainerdItf, it returns the version of
+// the daemon the containers were relayed from
+func (r *Util) Metadata() (containerdclient.Version, error) {
+	snapshot, err := r.nodeContainers()
+	if err != nil {
+		return containerdclient.Version{}, err
+	}
+	return containerdclient.Version{Version: snapshot.Version, Revision: snapshot.Revision}, nil
+}
+
+// Namespace implements containerd.ContainerdItf
+func (r *Util) Namespace() string {
+	return r.namespace
+}
+
+// Namespaces implements containerd.ContainerdItf, only the namespaces
+// collected by the relay are listed
+func (r *Util) Namespaces() ([]string, error) {
+	snapshot, err := r.nodeContainers()
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(snapshot.Namespaces))
+	for ns := range snapshot.Namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// NetNSPath implements containerd.ContainerdItf
+func (r *Util) NetNSPath(ctx context.Context, ctn containerdclient.Container) (string, error) {
+	return "", r.unsupported("the network namespaces of the containers")
+}
+
+// Plugins implements containerd.ContainerdItf
+func (r *Util) Plugins(ctx context.Context) ([]containerd.PluginInfo, error) {
+	return nil, r.unsupported("the configuration of the daemon")
+}
+
+// RegistryMirrors implements containerd.ContainerdItf
+func (r *Util) RegistryMirrors() ([]containerd.RegistryMirror, error) {
+	return nil, r.unsupported("the configuration of the daemon")
+}
+
+// RestartTask implements containerd.ContainerdItf
+func (r *Util) RestartTask(ctx context.Context, id string) error {
+	return r.unsupported("the restart of the containers")
+}
+
+// SandboxStatus implements containerd.ContainerdItf
+func (r *Util) SandboxStatus(id string) (sandbox.ControllerStatus, error) {
+	return sandbox.ControllerStatus{}, r.unsupported("the sandbox API")
+}
+
+// Sandboxes implements containerd.ContainerdItf
+func (r *Util) Sandboxes() ([]sandbox.Sandbox, error) {
+	return nil, r.unsupported("the sandbox API")
+}
+
+// Spec implements containerd.ContainerdItf
+func (r *Util) Spec(ctn containerdclient.Container) (*oci.Spec, error) {
+	return nil, r.unsupported("the OCI spec")
+}
+
+// TaskMetrics implements containerd.ContainerdItf
+func (r *Util) TaskMetrics(ctn containerdclient.Container) (*types.Metric, error) {
+	return nil, r.unsupported("the task metrics")
+}
+
+// TaskPids implements containerd.ContainerdItf
+func (r *Util) TaskPids(ctn containerdclient.Container) ([]containerdclient.ProcessInfo, error) {
+	return nil, r.unsupported("the task processes")
+}
+
+// TaskPressure implements containerd.ContainerdItf
+func (r *Util) TaskPressure(pid uint32) (*containerd.TaskPressure, error) {
+	return nil, r.unsupported("the task pressure")
+}
+
+// TaskProcesses implements containerd.ContainerdItf
+func (r *Util) TaskProcesses(ctx context.Context, ctn containerdclient.Container) ([]containerd.TaskProcess, error) {
+	return nil, r.unsupported("the task processes")
+}
+
+// TaskStatus implements containerd.ContainerdItf
+func (r *Util) TaskStatus(ctn containerdclient.Container) (containerdclient.Status, error) {
+	return containerdclient.Status{}, r.unsupported("the task status")
+}
+
+// TaskVMStats implements containerd.ContainerdItf
+func (r *Util) TaskVMStats(id string, pid uint32) (*containerd.TaskVMStats, error) {
+	return nil, r.unsupported("the task metrics")
+}
+
+// UpperDirSize implements containerd.ContainerdItf
+func (r *Util) UpperDirSize(ctx context.Context, ctn containerdclient.Container) (int64, error) {
+	return 0, r.unsupported("the snapshots")
+}
+
+// VerifyImageContent implements containerd.ContainerdItf
+func (r *Util) VerifyImageContent(ctx context.Context, ctn containerdclient.Container) (*containerd.ImageVerification, error) {
+	return nil, r.unsupported("the content store")
+}
+
+// WalkContainers implements containerd.ContainerdItf, the relayed
+// containers are filtered by the agent
+func (r *Util) WalkContainers(ctx context.Context, fn func(containerd.CachedContainer) error, filters ...string) error {
+	ctns, err := r.CachedContainers()
+	if err != nil {
+		return err
+	}
+	return containerd.WalkCachedContainers(ctns, fn, filters...)
+}
+
+// WithLease implements containerd.ContainerdItf
+func (r *Util) WithLease(ctx context.Context) (context.Context, func(), error) {
+	return ctx, func() {}, r.unsupported("the leases")
+}
+
+// relayContainer is a containerd.Container relayed by the cluster-agent
+type relayContainer struct {
+	// The methods of containerd.Container other than ID, Info and Labels
+	// are not implemented
+	containerdclient.Container
+
+	info containers.Container
+}
+
+func newRelayContainer(ctn Container) *relayContainer {
+	return &relayContainer{info: containers.Container{
+		ID:          ctn.ID,
+		Image:       ctn.Image,
+		Labels:      ctn.Labels,
+		CreatedAt:   ctn.CreatedAt,
+		UpdatedAt:   ctn.UpdatedAt,
+		Runtime:     containers.RuntimeInfo{Name: ctn.Runtime},
+		SnapshotKey: ctn.SnapshotKey,
+		SandboxID:   ctn.SandboxID,
+	}}
+}
+
+// ID implements containerd.Container
+func (c *relayContainer) ID() string {
+	return c.info.ID
+}
+
+// Info implements containerd.Container
+func (c *relayContainer) Info(ctx context.Context, opts ...containerdclient.InfoOpts) (containers.Container, error) {
+	return c.info, nil
+}
+
+// Labels implements containerd.Container
+func (c *relayContainer) Labels(ctx context.Context) (map[string]string, error) {
+	return c.info.Labels, nil
+}
+
+// relayEventService is the event service of the Util, the relay does not
+// forward the events of the daemon
+type relayEventService struct {
+	closed chan struct{}
+}
+
+// Publish implements containerd.EventService
+func (relayEventService) Publish(ctx context.Context, topic string, event events.Event) error {
+	return &containerd.Error{Kind: containerd.ErrUnsupported, Err: fmt.Errorf("the events are not relayed by the cluster-agent")}
+}
+
+// Forward implements containerd.EventService
+func (relayEventService) Forward(ctx context.Context, envelope *events.Envelope) error {
+	return &containerd.Error{Kind: containerd.ErrUnsupported, Err: fmt.Errorf("the events are not relayed by the cluster-agent")}
+}
+
+// Subscribe implements containerd.EventService, the subscription receives
+// nothing until ctx is done or the util is closed
+func (s relayEventService) Subscribe(ctx context.Context, filters ...string) (<-chan *events.Envelope, <-chan error) {
+	ch := make(chan *events.Envelope)
+	errs := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			errs <- ctx.Err()
+		case <-s.closed:
+			errs <- &containerd.Error{Kind: containerd.ErrNotServing, Err: fmt.Errorf("the relay util was closed")}
+		}
+	}()
+	return ch, errs
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdrelay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+type testLogger struct{}
+
+func (testLogger) Debugf(format string, params ...interface{})       {}
+func (testLogger) Infof(format string, params ...interface{})        {}
+func (testLogger) Warnf(format string, params ...interface{}) error  { return nil }
+func (testLogger) Errorf(format string, params ...interface{}) error { return nil }
+
+func TestRelayRoundTrip(t *testing.T) {
+	createdAt := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: containers.Container{
+		ID:        "redis",
+		Image:     "redis:5",
+		Labels:    map[string]string{"app": "redis"},
+		CreatedAt: createdAt,
+		Runtime:   containers.RuntimeInfo{Name: "io.containerd.runc.v2"},
+	}}))
+
+	snapshot, err := Collect([]containerd.ContainerdItf{daemon.Util("k8s.io")})
+	require.NoError(t, err)
+	assert.Equal(t, "v1.7.0", snapshot.Version)
+	require.Len(t, snapshot.Namespaces["k8s.io"], 1)
+
+	fetches := 0
+	relay := NewUtil("k8s.io", testLogger{}, func() (NodeContainers, error) {
+		fetches++
+		return snapshot, nil
+	})
+
+	namespaces, err := relay.Namespaces()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k8s.io"}, namespaces)
+
+	version, err := relay.Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, containerdclient.Version{Version: "v1.7.0", Revision: "containerdtest"}, version)
+
+	ctns, err := relay.Containers()
+	require.NoError(t, err)
+	require.Len(t, ctns, 1)
+	info, err := relay.Info(ctns[0])
+	require.NoError(t, err)
+	assert.Equal(t, "redis:5", info.Image)
+	assert.Equal(t, createdAt, info.CreatedAt)
+	labels, err := ctns[0].Labels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "redis"}, labels)
+
+	cached, err := relay.CachedContainers()
+	require.NoError(t, err)
+	require.Len(t, cached, 1)
+	assert.Equal(t, "runc", cached[0].RuntimeHandler)
+
+	_, err = relay.LoadContainer("deleted")
+	assert.True(t, errdefs.IsNotFound(err))
+
+	_, err = relay.TaskStatus(ctns[0])
+	assert.Equal(t, containerd.ErrUnsupported, containerd.ErrorKind(err))
+
+	// The snapshot is fetched once per snapshotTTL
+	assert.Equal(t, 1, fetches)
+}
+
+func TestRelayUtilHealth(t *testing.T) {
+	relay := NewUtil("k8s.io", testLogger{}, func() (NodeContainers, error) {
+		return NodeContainers{}, errors.New("unexpected response: 404 - 404 Not Found")
+	})
+	assert.Equal(t, containerd.ErrNotServing, containerd.ErrorKind(relay.Health()))
+	_, err := relay.Containers()
+	assert.Equal(t, containerd.ErrNotServing, containerd.ErrorKind(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, errs := relay.GetEvents().Subscribe(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+
+	// The subscriptions end once the util is closed
+	_, errs = relay.GetEvents().Subscribe(context.Background())
+	require.NoError(t, relay.Close())
+	assert.Equal(t, containerd.ErrNotServing, containerd.ErrorKind(<-errs))
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package check
+
+import (
+	"sync"
+	"time"
+)
+
+// Profiler is implemented by the checks timing the phases of their runs,
+// printed by `agent check --profile`
+type Profiler interface {
+	// EnableProfiling starts timing the phases of the next runs
+	EnableProfiling()
+	// Profile returns the time spent in each phase since profiling was
+	// enabled, in the order the phases were first timed
+	Profile() []PhaseTiming
+}
+
+// PhaseTiming is the time spent in a phase of the runs of a check
+type PhaseTiming struct {
+	Phase string
+	// Calls is the number of times the phase was timed
+	Calls int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Average returns the average time of a call
+func (t PhaseTiming) Average() time.Duration {
+	if t.Calls == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Calls)
+}
+
+// PhaseProfile accumulates the PhaseTiming of the phases of a check, it is
+// safe for concurrent use. A nil PhaseProfile records nothing, so that the
+// checks can time their phases unconditionally.
+type PhaseProfile struct {
+	mu      sync.Mutex
+	timings []PhaseTiming
+	// index holds the position of the phases in timings
+	index map[string]int
+}
+
+// NewPhaseProfile returns an empty PhaseProfile
+func NewPhaseProfile() *PhaseProfile {
+	return &PhaseProfile{index: make(map[string]int)}
+}
+
+// Record adds a call of duration d to phase
+func (p *PhaseProfile) Record(phase string, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, found := p.index[phase]
+	if !found {
+		i = len(p.timings)
+		p.index[phase] = i
+		p.timings = append(p.timings, PhaseTiming{Phase: phase})
+	}
+	t := &p.timings[i]
+	t.Calls++
+	t.Total += d
+	if d > t.Max {
+		t.Max = d
+	}
+}
+
+// Since adds a call to phase that started at start, to be deferred
+func (p *PhaseProfile) Since(phase string, start time.Time) {
+	if p == nil {
+		return
+	}
+	p.Record(phase, time.Since(start))
+}
+
+// Timings returns a copy of the timings of the phases, in the order they
+// were first recorded
+func (p *PhaseProfile) Timings() []PhaseTiming {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PhaseTiming(nil), p.timings...)
+}
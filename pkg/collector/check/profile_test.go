@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseProfile(t *testing.T) {
+	var disabled *PhaseProfile
+	disabled.Record("list", time.Second)
+	disabled.Since("list", time.Now())
+	assert.Nil(t, disabled.Timings())
+
+	p := NewPhaseProfile()
+	p.Record("list", 3*time.Millisecond)
+	p.Record("stats", time.Millisecond)
+	p.Record("stats", 5*time.Millisecond)
+	p.Record("list", time.Millisecond)
+
+	timings := p.Timings()
+	assert.Equal(t, []PhaseTiming{
+		{Phase: "list", Calls: 2, Total: 4 * time.Millisecond, Max: 3 * time.Millisecond},
+		{Phase: "stats", Calls: 2, Total: 6 * time.Millisecond, Max: 5 * time.Millisecond},
+	}, timings)
+	assert.Equal(t, 3*time.Millisecond, timings[1].Average())
+	assert.Equal(t, time.Duration(0), PhaseTiming{}.Average())
+
+	// The timings returned are not updated
+	p.Record("list", time.Millisecond)
+	assert.Equal(t, 2, timings[0].Calls)
+}
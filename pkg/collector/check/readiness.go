@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package check
+
+import (
+	"sync"
+)
+
+// ReadinessGate returns a channel closed once the dependencies of a check
+// are ready, eg. the client of the runtime it monitors is connected. The
+// gate is expected to close the channel after a bounded wait even if they
+// never get ready, for the check to report their failure. A nil channel
+// lets the check run right away.
+type ReadinessGate func() <-chan struct{}
+
+var (
+	readinessGates    = make(map[string]ReadinessGate)
+	readinessGatesMux sync.RWMutex
+)
+
+// RegisterReadinessGate makes the collector scheduler defer the checks
+// named name until gate is ready
+func RegisterReadinessGate(name string, gate ReadinessGate) {
+	readinessGatesMux.Lock()
+	defer readinessGatesMux.Unlock()
+	readinessGates[name] = gate
+}
+
+// GetReadinessGate returns the gate registered for the checks named name,
+// or nil if they can be scheduled right away
+func GetReadinessGate(name string) ReadinessGate {
+	readinessGatesMux.RLock()
+	defer readinessGatesMux.RUnlock()
+	return readinessGates[name]
+}
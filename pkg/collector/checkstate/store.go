@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package checkstate provides a key-value store persisted on disk, for the
+// checks to keep cursors and high-water marks across agent restarts.
+package checkstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MaxValueSize bounds the size of a value, the state is meant for cursors
+// and high-water marks, not for data
+const MaxValueSize = 64 * 1024
+
+var (
+	stores    = make(map[check.ID]*Store)
+	storesMux sync.Mutex
+
+	unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// Store is the persistent state of a check instance. Every write is flushed
+// to a file named after the check ID in the check_state_path directory, so
+// the state survives agent restarts. As the check ID derives from the
+// instance configuration, a new state is started when it changes.
+type Store struct {
+	path string
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// Get returns the store of a check instance, loading it from disk on first
+// use. A state file that cannot be read is logged and the state starts
+// empty, so that a corrupted file does not prevent the check from running.
+func Get(id check.ID) *Store {
+	storesMux.Lock()
+	defer storesMux.Unlock()
+
+	if s, found := stores[id]; found {
+		return s
+	}
+	s := &Store{
+		path:   filepath.Join(config.Datadog.GetString("check_state_path"), fileName(id)),
+		values: make(map[string]string),
+	}
+	if err := s.load(); err != nil {
+		log.Warnf("Cannot load the persistent state of check %s, starting with an empty state: %s", id, err)
+	}
+	stores[id] = s
+	return s
+}
+
+// fileName returns the name of the state file of a check instance
+func fileName(id check.ID) string {
+	return unsafeFileChars.ReplaceAllString(string(id), "_") + ".json"
+}
+
+func (s *Store) load() error {
+	content, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, &s.values)
+}
+
+// Read returns the value of a key, false if it is not set
+func (s *Store) Read(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, found := s.values[key]
+	return value, found
+}
+
+// Write sets the value of a key and flushes the state to disk
+func (s *Store) Write(key, value string) error {
+	if len(value) > MaxValueSize {
+		return fmt.Errorf("the value of %s is %d bytes long, the maximum is %d", key, len(value), MaxValueSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, found := s.values[key]
+	s.values[key] = value
+	if err := s.flush(); err != nil {
+		if found {
+			s.values[key] = previous
+		} else {
+			delete(s.values, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a key and flushes the state to disk
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, found := s.values[key]
+	if !found {
+		return nil
+	}
+	delete(s.values, key)
+	if err := s.flush(); err != nil {
+		s.values[key] = previous
+		return err
+	}
+	return nil
+}
+
+// Keys returns the keys set in the store, sorted
+func (s *Store) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flush writes the state to a temporary file renamed over the state file,
+// so that a crash during the write does not corrupt the previous state.
+// s.mu must be held.
+func (s *Store) flush() error {
+	content, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("cannot create the check state directory: %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package checkstate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func setupStateDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "check_state")
+	require.NoError(t, err)
+	previous := config.Datadog.GetString("check_state_path")
+	config.Datadog.Set("check_state_path", filepath.Join(dir, "check_state"))
+	return dir, func() {
+		config.Datadog.Set("check_state_path", previous)
+		stores = make(map[check.ID]*Store)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	dir, teardown := setupStateDir(t)
+	defer teardown()
+
+	id := check.ID("journald:system:1d5a7a2b9e3c4f60")
+	s := Get(id)
+	assert.True(t, s == Get(id))
+
+	_, found := s.Read("cursor")
+	assert.False(t, found)
+	require.NoError(t, s.Write("cursor", "s=7f2a;i=42"))
+	require.NoError(t, s.Write("last_id", "1042"))
+	require.NoError(t, s.Delete("last_id"))
+	require.NoError(t, s.Delete("unknown"))
+
+	_, err := os.Stat(filepath.Join(dir, "check_state", "journald_system_1d5a7a2b9e3c4f60.json"))
+	assert.NoError(t, err)
+
+	// A restarted agent reads the state back
+	stores = make(map[check.ID]*Store)
+	s = Get(id)
+	value, found := s.Read("cursor")
+	assert.True(t, found)
+	assert.Equal(t, "s=7f2a;i=42", value)
+	assert.Equal(t, []string{"cursor"}, s.Keys())
+
+	// Stores are namespaced per check instance
+	_, found = Get("journald:apps:9a8b7c6d5e4f3a2b").Read("cursor")
+	assert.False(t, found)
+}
+
+func TestStoreLimitsAndErrors(t *testing.T) {
+	dir, teardown := setupStateDir(t)
+	defer teardown()
+
+	s := Get("mysql:5f1c0ab3a2e4d6f7")
+	assert.Error(t, s.Write("dump", strings.Repeat("a", MaxValueSize+1)))
+	assert.Empty(t, s.Keys())
+
+	// A corrupted state file starts an empty state
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "check_state"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "check_state", "postgres_1a2b3c4d5e6f7a8b.json"), []byte("{not json"), 0644))
+	s = Get("postgres:1a2b3c4d5e6f7a8b")
+	assert.Empty(t, s.Keys())
+	require.NoError(t, s.Write("xmin", "1234"))
+}
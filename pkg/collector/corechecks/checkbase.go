@@ -14,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/checkstate"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -61,7 +62,7 @@ func (c *CheckBase) Configure(data integration.Data, initConfig integration.Data
 }
 
 // CommonConfigure is called when checks implement their own Configure method,
-// in order to setup common options (run interval, empty hostname)
+// in order to setup common options (run interval, empty hostname, point budget)
 func (c *CheckBase) CommonConfigure(instance integration.Data) error {
 	commonOptions := integration.CommonInstanceConfig{}
 	err := yaml.Unmarshal(instance, &commonOptions)
@@ -84,6 +85,16 @@ func (c *CheckBase) CommonConfigure(instance integration.Data) error {
 		}
 		s.DisableDefaultHostname(true)
 	}
+
+	// Override the point budget if specified
+	if commonOptions.PointBudget > 0 {
+		s, err := aggregator.GetSender(c.checkID)
+		if err != nil {
+			log.Errorf("failed to retrieve a sender for check %s: %s", string(c.ID()), err)
+			return err
+		}
+		s.SetPointBudget(commonOptions.PointBudget)
+	}
 	return nil
 }
 
@@ -133,6 +144,13 @@ func (c *CheckBase) ID() check.ID {
 	return c.checkID
 }
 
+// PersistentState returns the state of the check instance that is kept
+// across agent restarts, eg. the cursor of the last collected entry.
+// It must be called after the check ID is built.
+func (c *CheckBase) PersistentState() *checkstate.Store {
+	return checkstate.Get(c.ID())
+}
+
 // GetWarnings grabs the latest integration warnings for the check.
 func (c *CheckBase) GetWarnings() []error {
 	if len(c.latestWarnings) == 0 {
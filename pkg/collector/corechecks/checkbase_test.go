@@ -68,3 +68,15 @@ func TestCommonConfigureCustomID(t *testing.T) {
 	assert.Equal(t, string(mycheck.ID()), "test:foobar:bd63a7031add5db9")
 	mockSender.AssertExpectations(t)
 }
+
+func TestCommonConfigurePointBudget(t *testing.T) {
+	mycheck := &dummyCheck{
+		CheckBase: NewCheckBase("test"),
+	}
+	mockSender := mocksender.NewMockSender(mycheck.ID())
+
+	mockSender.On("SetPointBudget", int64(500)).Return().Once()
+	err := mycheck.CommonConfigure([]byte("point_budget: 500"))
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
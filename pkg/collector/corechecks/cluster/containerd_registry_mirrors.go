@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	registryMirrorsCheckName   = "containerd_registry_mirrors"
+	registryMirrorServiceCheck = "containerd.registry_mirror.can_connect"
+	// Number of failing nodes detailed in the service check message
+	maxReportedMirrorNodes = 5
+)
+
+// registryMirrorsConfig is the instance configuration of the check
+type registryMirrorsConfig struct {
+	Tags []string `yaml:"tags"`
+}
+
+// registryMirrorsCheck reports one service check per containerd registry
+// mirror, aggregating the probes of all the node agents reporting to the
+// cluster agent, instead of one service check per node and mirror.
+type registryMirrorsCheck struct {
+	core.CheckBase
+	instance *registryMirrorsConfig
+	// For testing
+	getHealth func() ([]types.RegistryMirrorHealth, error)
+}
+
+func (c *registryMirrorsConfig) parse(data []byte) error {
+	return yaml.Unmarshal(data, c)
+}
+
+// Configure parses the check configuration and init the check
+func (c *registryMirrorsCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	err := c.CommonConfigure(data)
+	if err != nil {
+		return err
+	}
+	return c.instance.parse(data)
+}
+
+// Run executes the check
+func (c *registryMirrorsCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+	defer sender.Commit()
+
+	// The node agents report to the leader, followers hold no status
+	if config.Datadog.GetBool("leader_election") {
+		err = c.runLeaderElection()
+		if err == apiserver.ErrNotLeader {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	healths, err := c.getHealth()
+	if err != nil {
+		return fmt.Errorf("cannot get the registry mirrors reported by the node agents: %s", err)
+	}
+	for _, health := range healths {
+		c.reportMirror(sender, health)
+	}
+	return nil
+}
+
+// reportMirror sends the service check and the node counts of a mirror. The service
+// check is critical if no node can reach the mirror, warning if only some of them can.
+func (c *registryMirrorsCheck) reportMirror(sender aggregator.Sender, health types.RegistryMirrorHealth) {
+	tags := append([]string{
+		"registry:" + health.Registry,
+		"mirror_endpoint:" + health.Endpoint,
+	}, c.instance.Tags...)
+
+	sender.Gauge("containerd.registry_mirror.nodes.healthy", float64(len(health.HealthyNodes)), "", tags)
+	sender.Gauge("containerd.registry_mirror.nodes.unhealthy", float64(len(health.UnhealthyNodes)), "", tags)
+
+	status := metrics.ServiceCheckOK
+	switch {
+	case len(health.UnhealthyNodes) == 0:
+	case len(health.HealthyNodes) == 0:
+		status = metrics.ServiceCheckCritical
+	default:
+		status = metrics.ServiceCheckWarning
+	}
+	sender.ServiceCheck(registryMirrorServiceCheck, status, "", tags, unhealthyNodesMessage(health))
+}
+
+// unhealthyNodesMessage details the errors of the first failing nodes
+func unhealthyNodesMessage(health types.RegistryMirrorHealth) string {
+	if len(health.UnhealthyNodes) == 0 {
+		return ""
+	}
+	nodes := make([]string, 0, len(health.UnhealthyNodes))
+	for node := range health.UnhealthyNodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	var details []string
+	for i, node := range nodes {
+		if i == maxReportedMirrorNodes {
+			details = append(details, fmt.Sprintf("and %d more", len(nodes)-i))
+			break
+		}
+		details = append(details, fmt.Sprintf("%s: %s", node, health.UnhealthyNodes[node]))
+	}
+	return fmt.Sprintf("%d/%d nodes cannot reach the mirror: %s",
+		len(nodes), len(nodes)+len(health.HealthyNodes), strings.Join(details, ", "))
+}
+
+func (c *registryMirrorsCheck) runLeaderElection() error {
+	leaderEngine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		c.Warn("Failed to instantiate the Leader Elector. Not running the containerd registry mirrors check.")
+		return err
+	}
+
+	err = leaderEngine.EnsureLeaderElectionRuns()
+	if err != nil {
+		c.Warn("Leader Election process failed to start")
+		return err
+	}
+
+	if !leaderEngine.IsLeader() {
+		log.Debugf("Leader is %q. %s will not run the containerd registry mirrors check", leaderEngine.GetLeader(), leaderEngine.HolderIdentity)
+		return apiserver.ErrNotLeader
+	}
+	return nil
+}
+
+// clusterRegistryMirrorsHealth returns the mirror statuses collected by the cluster-check handler
+func clusterRegistryMirrorsHealth() ([]types.RegistryMirrorHealth, error) {
+	handler, err := clusterchecks.GetHandler()
+	if err != nil {
+		return nil, err
+	}
+	return handler.GetRegistryMirrorsHealth()
+}
+
+func registryMirrorsFactory() check.Check {
+	return &registryMirrorsCheck{
+		CheckBase: core.NewCheckBase(registryMirrorsCheckName),
+		instance:  &registryMirrorsConfig{},
+		getHealth: clusterRegistryMirrorsHealth,
+	}
+}
+
+func init() {
+	core.RegisterCheck(registryMirrorsCheckName, registryMirrorsFactory)
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestRegistryMirrorsCheck(t *testing.T) {
+	mirrorsCheck := registryMirrorsFactory().(*registryMirrorsCheck)
+	require.NoError(t, mirrorsCheck.Configure([]byte(`tags: ["foo:bar"]`), nil))
+	mirrorsCheck.getHealth = func() ([]types.RegistryMirrorHealth, error) {
+		return []types.RegistryMirrorHealth{
+			{
+				Registry:     "docker.io",
+				Endpoint:     "https://mirror.local",
+				HealthyNodes: []string{"nodeA", "nodeB"},
+			},
+			{
+				Registry:       "gcr.io",
+				Endpoint:       "https://gcr-mirror.local",
+				HealthyNodes:   []string{"nodeA"},
+				UnhealthyNodes: map[string]string{"nodeB": "connection refused"},
+			},
+			{
+				Registry:       "quay.io",
+				Endpoint:       "https://quay-mirror.local",
+				UnhealthyNodes: map[string]string{"nodeB": "timeout", "nodeA": "unexpected response: 502 Bad Gateway"},
+			},
+		}, nil
+	}
+
+	dockerTags := []string{"registry:docker.io", "mirror_endpoint:https://mirror.local", "foo:bar"}
+	gcrTags := []string{"registry:gcr.io", "mirror_endpoint:https://gcr-mirror.local", "foo:bar"}
+	quayTags := []string{"registry:quay.io", "mirror_endpoint:https://quay-mirror.local", "foo:bar"}
+
+	mockSender := mocksender.NewMockSender(mirrorsCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, mirrorsCheck.Run())
+
+	mockSender.AssertMetric(t, "Gauge", "containerd.registry_mirror.nodes.healthy", 2, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.registry_mirror.nodes.unhealthy", 0, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.registry_mirror.nodes.unhealthy", 1, "", gcrTags)
+	mockSender.AssertServiceCheck(t, registryMirrorServiceCheck, metrics.ServiceCheckOK, "", dockerTags, "")
+	mockSender.AssertServiceCheck(t, registryMirrorServiceCheck, metrics.ServiceCheckWarning, "", gcrTags,
+		"1/2 nodes cannot reach the mirror: nodeB: connection refused")
+	mockSender.AssertServiceCheck(t, registryMirrorServiceCheck, metrics.ServiceCheckCritical, "", quayTags,
+		"2/2 nodes cannot reach the mirror: nodeA: unexpected response: 502 Bad Gateway, nodeB: timeout")
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 3)
+
+	mirrorsCheck.getHealth = func() ([]types.RegistryMirrorHealth, error) {
+		return nil, errors.New("cluster-check discovery is not enabled")
+	}
+	assert.Error(t, mirrorsCheck.Run())
+}
+
+func TestUnhealthyNodesMessage(t *testing.T) {
+	health := types.RegistryMirrorHealth{
+		HealthyNodes:   []string{"node0"},
+		UnhealthyNodes: make(map[string]string),
+	}
+	assert.Equal(t, "", unhealthyNodesMessage(health))
+
+	for _, node := range []string{"node1", "node2", "node3", "node4", "node5", "node6", "node7"} {
+		health.UnhealthyNodes[node] = "timeout"
+	}
+	assert.Equal(t, "7/8 nodes cannot reach the mirror: node1: timeout, node2: timeout, node3: timeout, node4: timeout, node5: timeout, and 2 more",
+		unhealthyNodesMessage(health))
+}
@@ -14,6 +14,7 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
@@ -53,6 +54,9 @@ type KubeASCheck struct {
 	configMapAvailable    bool
 	ac                    *apiserver.APIClient
 	oshiftAPILevel        apiserver.OpenShiftAPILevel
+	annotationRules       metrics.EventAnnotationRules
+	// For testing
+	podAnnotations func(namespace, name string) (map[string]string, error)
 }
 
 func (c *KubeASConfig) parse(data []byte) error {
@@ -78,6 +82,8 @@ func (k *KubeASCheck) Configure(config, initConfig integration.Data) error {
 		return err
 	}
 
+	k.annotationRules = metrics.GetEventAnnotationRules()
+
 	log.Debugf("Running config %s", config)
 	return nil
 }
@@ -177,10 +183,12 @@ func (k *KubeASCheck) Run() error {
 
 // KubernetesASFactory is exported for integration testing.
 func KubernetesASFactory() check.Check {
-	return &KubeASCheck{
+	k := &KubeASCheck{
 		CheckBase: core.NewCheckBase(kubernetesAPIServerCheckName),
 		instance:  &KubeASConfig{},
 	}
+	k.podAnnotations = k.getPodAnnotations
+	return k
 }
 
 func (k *KubeASCheck) runLeaderElection() error {
@@ -341,12 +349,28 @@ ITER_EVENTS:
 			k.Warnf("Error while formatting bundled events, %s. Not submitting", err.Error())
 			continue
 		}
+		if len(k.annotationRules) > 0 && bundle.podName != "" {
+			annotations, err := k.podAnnotations(bundle.namespace, bundle.podName)
+			if err != nil {
+				log.Debugf("Could not get the annotations of pod %s/%s: %s", bundle.namespace, bundle.podName, err)
+			}
+			k.annotationRules.Apply(&datadogEv, annotations)
+		}
 		datadogEv.Tags = append(datadogEv.Tags, k.instance.Tags...)
 		sender.Event(datadogEv)
 	}
 	return nil
 }
 
+// getPodAnnotations returns the annotations of a pod from the API server
+func (k *KubeASCheck) getPodAnnotations(namespace, name string) (map[string]string, error) {
+	pod, err := k.ac.Cl.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pod.Annotations, nil
+}
+
 func init() {
 	core.RegisterCheck(kubernetesAPIServerCheckName, KubernetesASFactory)
 }
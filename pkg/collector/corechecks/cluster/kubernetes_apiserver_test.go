@@ -293,3 +293,44 @@ func TestProcessEvent(t *testing.T) {
 	mocked.AssertNotCalled(t, "Event")
 	mocked.AssertExpectations(t)
 }
+
+func TestProcessEventAnnotationRules(t *testing.T) {
+	podEv := createEvent(1, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	nodeEv := createEvent(1, "default", "localhost", "Node", "e63e74fa-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "MissingClusterDNS", "MountVolume.SetUp succeeded", 709662600)
+
+	var queriedPods []string
+	kubeASCheck := &KubeASCheck{
+		instance: &KubeASConfig{
+			Tags: []string{"test"},
+		},
+		CheckBase: core.NewCheckBase(kubernetesAPIServerCheckName),
+		annotationRules: metrics.NewEventAnnotationRules([]metrics.EventAnnotationRule{
+			{Annotation: "example.com/team", Tag: "team"},
+			{Annotation: "example.com/criticality", Value: "high", AlertType: "error"},
+		}),
+		podAnnotations: func(namespace, name string) (map[string]string, error) {
+			queriedPods = append(queriedPods, namespace+"/"+name)
+			return map[string]string{
+				"example.com/team":        "storage",
+				"example.com/criticality": "high",
+			}, nil
+		},
+	}
+	mocked := mocksender.NewMockSender(kubeASCheck.ID())
+	mocked.On("Event", mock.AnythingOfType("metrics.Event"))
+
+	kubeASCheck.processEvents(mocked, []*v1.Event{podEv, nodeEv}, false)
+	mocked.AssertNumberOfCalls(t, "Event", 2)
+	assert.Equal(t, []string{"default/dca-789976f5d7-2ljx6"}, queriedPods)
+
+	for _, call := range mocked.Calls {
+		ev := call.Arguments.Get(0).(metrics.Event)
+		if ev.AggregationKey == "kubernetes_apiserver:e6417a7f-f566-11e7-9749-0e4863e1cbf4" {
+			assert.Equal(t, metrics.EventAlertTypeError, ev.AlertType)
+			assert.Contains(t, ev.Tags, "team:storage")
+		} else {
+			assert.Equal(t, metrics.EventAlertType(""), ev.AlertType)
+			assert.NotContains(t, ev.Tags, "team:storage")
+		}
+	}
+}
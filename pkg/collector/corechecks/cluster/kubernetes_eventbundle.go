@@ -28,6 +28,7 @@ type kubernetesEventBundle struct {
 	lastTimestamp float64        // Used for the modified events in the bundle to specify when they last occurred
 	countByAction map[string]int // Map of count per action to aggregate several events from the same ObjUid in one event
 	nodename      string         // Stores the nodename that should be used to submit the events
+	podName       string         // Name of the pod the events are about, empty for other objects
 }
 
 func newKubernetesEventBundler(objUid types.UID, compName string) *kubernetesEventBundle {
@@ -61,6 +62,9 @@ func (k *kubernetesEventBundle) addEvent(event *v1.Event) error {
 	if event.InvolvedObject.Kind == "Node" || event.InvolvedObject.Kind == "Pod" {
 		k.nodename = event.Source.Host
 	}
+	if event.InvolvedObject.Kind == "Pod" {
+		k.podName = event.InvolvedObject.Name
+	}
 
 	return nil
 }
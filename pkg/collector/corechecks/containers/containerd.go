@@ -0,0 +1,470 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/events"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	containerdCheckName = "containerd"
+	// containerdUnreachableServiceCheck reports the background health
+	// probes of the containerd util, between two runs of the checks
+	containerdUnreachableServiceCheck = "datadog.agent.containerd.unreachable"
+	// containerdCanConnectServiceCheck reports the configuration issues
+	// preventing the check from querying containerd
+	containerdCanConnectServiceCheck = "containerd.can_connect"
+	// containerdStaleTag flags the metrics reported from the last known
+	// state while containerd is unavailable
+	containerdStaleTag = "containerd.stale:true"
+)
+
+// ContainerdConfig holds the config of the check
+type ContainerdConfig struct {
+	Tags                  []string `yaml:"tags"`
+	CollectImageMetrics   bool     `yaml:"collect_image_metrics"`
+	CollectContainerState bool     `yaml:"collect_container_state"`
+	CollectPodSandboxes   bool     `yaml:"collect_pod_sandboxes"`
+	CollectTaskMetrics    bool     `yaml:"collect_task_metrics"`
+	CollectPodMetrics     bool     `yaml:"collect_pod_metrics"`
+	CollectContainerChurn bool     `yaml:"collect_container_churn"`
+	VerifyImageContent    bool     `yaml:"verify_image_content"`
+	// CollectDiskUsage measures the writable layer of the containers once
+	// per DiskUsageInterval seconds
+	CollectDiskUsage  bool `yaml:"collect_disk_usage"`
+	DiskUsageInterval int  `yaml:"disk_usage_interval"`
+	// CaptureShortLivedContainers samples the tasks when they start and
+	// exit, to report the usage of the ones exiting between two runs
+	CaptureShortLivedContainers bool `yaml:"capture_short_lived_containers"`
+	// StaleImageDays is the age of the unused images reported as stale
+	StaleImageDays int `yaml:"stale_image_days"`
+	// ListingBudget bounds the listing of the namespaces and of their
+	// containers, in seconds
+	ListingBudget int `yaml:"listing_budget"`
+	// NamespaceIntervals are the intervals between the collections of the
+	// namespaces, in seconds, by namespace. The other namespaces are
+	// collected at every run.
+	NamespaceIntervals map[string]int `yaml:"namespace_intervals"`
+	// MaxStaleIntervals is the number of runs the last known container
+	// states are reported while containerd is unavailable
+	MaxStaleIntervals int `yaml:"max_stale_intervals"`
+	// ExecProbes are run in the matching containers at every run
+	ExecProbes []containerdExecProbe `yaml:"exec_probes"`
+	// CriticalPlugins are the plugins whose initialization is reported, by
+	// name, eg. io.containerd.grpc.v1.cri
+	CriticalPlugins []string `yaml:"critical_plugins"`
+	// RegistryProbes are the image references resolved on their registry
+	// with the credentials of the CRI plugin, once per
+	// RegistryProbeInterval seconds
+	RegistryProbes        []string `yaml:"registry_probes"`
+	RegistryProbeInterval int      `yaml:"registry_probe_interval"`
+
+	// DisabledMetricFamilies are the families of task metrics not reported,
+	// see containerd_sampling.go
+	DisabledMetricFamilies []string `yaml:"disabled_metric_families"`
+	// TaskSamplePercent is the percentage of the containers whose task
+	// metrics are reported, to bound the metrics of the densest nodes
+	TaskSamplePercent int `yaml:"task_sample_percent"`
+	// MetricBatchSize is the number of samples submitted to the aggregator
+	// at once, 0 submits them one by one
+	MetricBatchSize int `yaml:"metric_batch_size"`
+	// ClockSkewThreshold is the skew between the clocks of the agent and
+	// of containerd above which a warning is logged, in seconds
+	ClockSkewThreshold int `yaml:"clock_skew_threshold"`
+}
+
+// ContainerdCheck grabs containerd events and image metrics
+type ContainerdCheck struct {
+	core.CheckBase
+	instance      *ContainerdConfig
+	hostname      string
+	collectEvents bool
+	// utils hands out the containerd utils, the DefaultProvider if nil
+	utils *containerd.Provider
+	// annotationRules are applied to the events with the container labels
+	annotationRules metrics.EventAnnotationRules
+	// namespaceFilter drops the events of the namespaces out of the collection scope
+	namespaceFilter containerd.NamespaceFilter
+	// watcher is nil if neither the events nor the image metrics are collected
+	watcher      *containerdEventWatcher
+	imageTracker *containerd.ImageEventTracker
+	stateTracker *containerd.ContainerStateTracker
+	churnTracker *containerd.ContainerChurnTracker
+	// lifetimeTracker is nil unless capture_short_lived_containers is set
+	lifetimeTracker *containerd.TaskLifetimeTracker
+	// skewTracker is nil if the watcher is
+	skewTracker *containerd.ClockSkewTracker
+
+	// protects the state updated by the watcher between two runs
+	sync.Mutex
+	pendingEvents []containerdEvent
+	imageStats    containerdImageStats
+
+	// verifiedImages holds the last verification time of the image
+	// contents, only accessed by Run
+	verifiedImages map[string]time.Time
+	// staleRuns counts the runs since containerd is unavailable, only
+	// accessed by Run
+	staleRuns int
+	// namespaceRuns and namespaceContainers hold the last collection time
+	// and container count of the namespaces, only accessed by Run
+	namespaceRuns       map[string]time.Time
+	namespaceContainers map[string]int
+	// registryProbes holds the last result of the registry probes, by
+	// reference, only accessed by Run
+	registryProbes map[string]registryProbeResult
+	// diskUsage holds the last measure of the writable layer of the
+	// containers, by namespace and ID, only accessed by Run
+	diskUsage map[string]containerDiskUsage
+	// cpuSamples holds the last CPU usage of the tasks, by namespace and
+	// ID, only accessed by Run
+	cpuSamples map[string]cpuSample
+
+	// storePath is the file persisting the container states and the churn,
+	// empty if they are not persisted. storedContainers holds the stored
+	// containers by ID, only accessed by Run.
+	storePath        string
+	storedContainers map[string]containerd.StoredContainer
+
+	// profile times the phases of the runs, it is nil unless profiling
+	// is enabled by `agent check --profile`
+	profile *check.PhaseProfile
+
+	// clockSkewed is set while the clock skew exceeds the threshold, to
+	// warn once, only accessed by Run
+	clockSkewed bool
+}
+
+func init() {
+	core.RegisterCheck(containerdCheckName, ContainerdFactory)
+	containerd.RegisterHealthListener(reportContainerdHealth)
+	check.RegisterReadinessGate(containerdCheckName, containerdReadinessGate)
+}
+
+// containerdReadinessGate defers the check until the agent is connected
+// to containerd, for it not to fail while containerd is starting
+func containerdReadinessGate() <-chan struct{} {
+	maxWait := config.Datadog.GetDuration("containerd_readiness_max_wait") * time.Second
+	if maxWait <= 0 {
+		return nil
+	}
+	return containerd.WaitReady(maxWait)
+}
+
+// reportContainerdHealth sends the outcome of a containerd health probe
+func reportContainerdHealth(status containerd.HealthStatus) {
+	sender, err := aggregator.GetDefaultSender()
+	if err != nil {
+		log.Debugf("Cannot report the containerd health: %s", err)
+		return
+	}
+	tags := []string{"socket_path:" + status.SocketPath, "containerd_namespace:" + status.Namespace}
+	if status.Err != nil {
+		sender.ServiceCheck(containerdUnreachableServiceCheck, metrics.ServiceCheckCritical, "", tags, status.Err.Error())
+	} else {
+		sender.ServiceCheck(containerdUnreachableServiceCheck, metrics.ServiceCheckOK, "", tags, "")
+	}
+	sender.Commit()
+}
+
+// ContainerdFactory is exported for integration testing
+func ContainerdFactory() check.Check {
+	return &ContainerdCheck{
+		CheckBase: core.NewCheckBase(containerdCheckName),
+		instance:  &ContainerdConfig{},
+		utils:     containerd.DefaultProvider(),
+	}
+}
+
+// provider returns the Provider handing out the containerd utils of the
+// check
+func (c *ContainerdCheck) provider() *containerd.Provider {
+	if c.utils == nil {
+		return containerd.DefaultProvider()
+	}
+	return c.utils
+}
+
+// Parse parses the ContainerdCheck config and set default values
+func (c *ContainerdConfig) Parse(data []byte) error {
+	// default values
+	c.CollectImageMetrics = true
+	c.CollectContainerState = true
+	c.CollectPodSandboxes = true
+	c.CollectTaskMetrics = true
+	c.CollectContainerChurn = true
+	c.StaleImageDays = 30
+	c.MaxStaleIntervals = 3
+	c.ListingBudget = 10
+	c.CriticalPlugins = defaultCriticalPlugins
+	c.RegistryProbeInterval = 300
+	c.TaskSamplePercent = 100
+	c.MetricBatchSize = 500
+	c.ClockSkewThreshold = 5
+	c.DiskUsageInterval = 300
+
+	return yaml.Unmarshal(data, c)
+}
+
+// Configure parses the check configuration and init the check
+func (c *ContainerdCheck) Configure(config, initConfig integration.Data) error {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeNameContainerd); err != nil {
+		return err
+	}
+	err := c.CommonConfigure(config)
+	if err != nil {
+		return err
+	}
+
+	c.hostname, err = util.GetHostname()
+	if err != nil {
+		log.Warnf("Can't get hostname, containerd events will not have it: %s", err)
+	}
+	c.annotationRules = metrics.GetEventAnnotationRules()
+	c.namespaceFilter = containerdconfig.NamespaceFilter()
+
+	if err = c.instance.Parse(config); err != nil {
+		return err
+	}
+	if unknown := c.instance.unknownMetricFamilies(); len(unknown) > 0 {
+		log.Warnf("Ignoring the unknown containerd metric families %v, the families are %v", unknown, metricFamilies)
+	}
+	return nil
+}
+
+// Run executes the check
+func (c *ContainerdCheck) Run() error {
+	defer c.profile.Since(profileTotal, time.Now())
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+	if c.instance.MetricBatchSize > 0 {
+		// The tens of thousands of samples of the dense nodes are
+		// submitted by batches
+		sender = aggregator.NewBatchingSender(sender, c.instance.MetricBatchSize)
+	}
+
+	if ok, err := c.checkConnectivity(sender); !ok {
+		sender.Commit()
+		return err
+	}
+
+	// The events are streamed by containerd, the watcher
+	// handles them in the background between two runs
+	if c.watcher == nil {
+		c.startWatcher()
+	}
+	if c.watcher != nil && c.watcher.buffer != nil {
+		sender.MonotonicCount("containerd.events.dropped", float64(c.watcher.buffer.Dropped()), "", c.instance.Tags)
+	}
+
+	if c.collectEvents {
+		c.reportEvents(c.flushEvents(), sender)
+	}
+	if c.imageTracker != nil {
+		if cu, err := c.provider().Get(nil); err == nil {
+			if err = c.imageTracker.RefreshContentSizes(cu); err != nil {
+				log.Debugf("Cannot list the containerd content: %s", err)
+			}
+		}
+		c.reportImageMetrics(c.flushImageStats(), sender)
+	}
+	if c.stateTracker != nil {
+		c.reportContainerStates(c.stateTracker.States(), time.Now(), nil, sender)
+	}
+	if c.instance.CollectPodSandboxes {
+		c.collectPodSandboxes(sender)
+	}
+	c.collectNamespaces(sender)
+	if c.churnTracker != nil {
+		c.reportContainerChurn(c.churnTracker.Churn(time.Now()), sender)
+	}
+	c.saveContainerStore(time.Now())
+	if c.lifetimeTracker != nil {
+		c.reportTaskLifetimes(c.lifetimeTracker.Flush(), sender)
+	}
+	if c.skewTracker != nil {
+		c.reportClockSkew(c.skewTracker, sender)
+	}
+	if c.instance.CollectImageMetrics {
+		c.collectContentUsage(sender)
+	}
+	if c.instance.VerifyImageContent {
+		c.verifyImageContents(sender, time.Now())
+	}
+	if len(c.instance.ExecProbes) > 0 {
+		c.runExecProbes(sender)
+	}
+	if len(c.instance.CriticalPlugins) > 0 {
+		c.collectPluginHealth(sender)
+	}
+	if len(c.instance.RegistryProbes) > 0 {
+		c.probeRegistries(sender)
+	}
+
+	commitStart := time.Now()
+	sender.Commit()
+	c.profile.Since(profileAggregation, commitStart)
+	return nil
+}
+
+// checkConnectivity returns whether the run can query containerd. The runs
+// are skipped while containerd is unavailable, as the unreachable service
+// check already reports it, only the last known container states are sent.
+// Configuration issues like a wrong namespace or missing socket permissions
+// are reported as a critical service check.
+func (c *ContainerdCheck) checkConnectivity(sender aggregator.Sender) (bool, error) {
+	cu, err := c.provider().Get(nil)
+	if err == nil {
+		err = containerd.CheckNamespace(cu)
+	}
+
+	switch {
+	case err == nil:
+		sender.ServiceCheck(containerdCanConnectServiceCheck, metrics.ServiceCheckOK, "", c.instance.Tags, "")
+		c.staleRuns = 0
+		return true, nil
+	case containerd.IsTransient(err):
+		log.Debugf("containerd is unavailable, skipping the run: %s", err)
+		c.reportStaleStates(time.Now(), sender)
+		return false, nil
+	case containerd.ErrorKind(err) != nil:
+		sender.ServiceCheck(containerdCanConnectServiceCheck, metrics.ServiceCheckCritical, "", c.instance.Tags, err.Error())
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// startWatcher subscribes to the events needed by the enabled features
+func (c *ContainerdCheck) startWatcher() {
+	var filters []string
+	var poll func(containerd.ContainerdItf)
+
+	c.collectEvents = config.Datadog.GetBool("containerd_collect_events")
+	if c.collectEvents {
+		filters = append(filters, containerdTaskEventFilters...)
+	}
+	if c.instance.CollectImageMetrics {
+		c.imageTracker = containerd.NewImageEventTracker(c.imageEventHooks())
+		filters = append(filters, containerd.ImageEventFilters...)
+		poll = func(cu containerd.ContainerdItf) {
+			if err := c.imageTracker.PollIngests(cu); err != nil {
+				log.Debugf("Cannot list the containerd ingests: %s", err)
+			}
+		}
+	}
+	if c.instance.CollectContainerState {
+		c.stateTracker = containerd.NewContainerStateTracker()
+		filters = append(filters, containerd.ContainerStateFilters...)
+	}
+	if c.instance.CollectContainerChurn {
+		c.churnTracker = containerd.NewContainerChurnTracker()
+		filters = append(filters, containerd.ContainerChurnFilters...)
+	}
+	if c.instance.CaptureShortLivedContainers {
+		c.lifetimeTracker = containerd.NewTaskLifetimeTracker(c.sampleNamespaceTask)
+		filters = append(filters, containerd.TaskLifetimeFilters...)
+	}
+	if len(filters) == 0 {
+		return
+	}
+	if c.stateTracker != nil || c.churnTracker != nil {
+		if path := config.Datadog.GetString("containerd_metadata_store_path"); path != "" {
+			c.loadContainerStore(path)
+		}
+	}
+
+	// containerd drops the events of the namespaces out of scope
+	c.watcher = newContainerdEventWatcher(c.provider(), c.namespaceFilter.EventFilters(filters...), c.handleEnvelope, poll)
+	c.skewTracker = containerd.NewClockSkewTracker()
+	c.watcher.skew = c.skewTracker
+	if size := config.Datadog.GetInt("containerd_event_buffer_size"); size > 0 {
+		c.watcher.buffer = containerd.NewEventBuffer(size)
+	}
+	if path := config.Datadog.GetString("containerd_event_bookmark_path"); path != "" {
+		bookmark, err := containerd.LoadEventBookmark(path)
+		if err != nil {
+			log.Warnf("Cannot load the containerd event bookmark, the events missed before are not replayed: %s", err)
+		}
+		c.watcher.bookmark = bookmark
+		c.watcher.filters = append(c.watcher.filters, c.namespaceFilter.EventFilters(containerd.EventBookmarkFilters...)...)
+	}
+	go c.watcher.run()
+}
+
+// handleEnvelope dispatches the events received by the watcher
+func (c *ContainerdCheck) handleEnvelope(envelope *events.Envelope) {
+	if envelope == nil || c.namespaceFilter.IsExcluded(envelope.Namespace) {
+		return
+	}
+	containerd.RecordEvent(envelope)
+	if c.churnTracker != nil {
+		c.churnTracker.HandleEnvelope(envelope)
+	}
+	if c.lifetimeTracker != nil && (envelope.Topic == containerd.TaskStartTopic || envelope.Topic == containerd.TaskExitTopic) {
+		if err := c.lifetimeTracker.HandleEnvelope(envelope); err != nil {
+			log.Debugf("Cannot decode containerd event: %s", err)
+		}
+	}
+	// Exit, checkpoint and create events are both sent as Datadog events and tracked
+	if c.stateTracker != nil {
+		switch envelope.Topic {
+		case containerd.TaskCreateTopic, containerd.TaskStartTopic, containerd.TaskExitTopic,
+			containerd.TaskPausedTopic, containerd.TaskResumedTopic, containerd.TaskCheckpointedTopic,
+			containerd.ContainerDeleteTopic:
+			if err := c.stateTracker.HandleEnvelope(envelope); err != nil {
+				log.Debugf("Cannot decode containerd event: %s", err)
+			}
+		}
+	}
+	switch envelope.Topic {
+	case containerdTaskOOMTopic, containerdTaskExitTopic, containerd.TaskCheckpointedTopic, containerd.TaskCreateTopic:
+		// The task events can be subscribed to for the state tracker only
+		if !c.collectEvents {
+			return
+		}
+		if ev, ok := parseContainerdEnvelope(envelope); ok {
+			c.addEvent(ev)
+		}
+	case containerd.TaskStartTopic, containerd.TaskPausedTopic, containerd.TaskResumedTopic, containerd.ContainerDeleteTopic,
+		containerd.ContainerCreateTopic:
+	default:
+		if c.imageTracker == nil {
+			return
+		}
+		if err := c.imageTracker.HandleEnvelope(envelope); err != nil {
+			log.Debugf("Cannot decode containerd event: %s", err)
+		}
+	}
+}
+
+// Stop stops the event watcher
+func (c *ContainerdCheck) Stop() {
+	if c.watcher != nil {
+		c.watcher.stop()
+		c.watcher = nil
+	}
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+// reportContainerChurn sends the number of containers created and deleted
+// over the last minute, for the node and for every namespace with a
+// creation or a deletion. The events of the excluded namespaces are
+// dropped by the watcher.
+func (c *ContainerdCheck) reportContainerChurn(churn map[string]containerd.ContainerChurn, sender aggregator.Sender) {
+	var total int
+	for ns, nsChurn := range churn {
+		total += nsChurn.Total()
+		tags := c.namespaceTags(ns)
+		sender.Gauge("containerd.namespace.containers.churn", float64(nsChurn.Total()), "", tags)
+		sender.Gauge("containerd.namespace.containers.created", float64(nsChurn.Created), "", tags)
+		sender.Gauge("containerd.namespace.containers.deleted", float64(nsChurn.Deleted), "", tags)
+	}
+	sender.Gauge("containerd.containers.churn", float64(total), "", c.instance.Tags)
+}
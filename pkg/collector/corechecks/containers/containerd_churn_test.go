@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/events"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdContainerChurn(t *testing.T) {
+	check := &ContainerdCheck{
+		instance:        &ContainerdConfig{Tags: []string{"env:prod"}},
+		namespaceFilter: containerd.NewNamespaceFilter(nil, []string{"buildkit"}),
+		churnTracker:    containerd.NewContainerChurnTracker(),
+	}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		check.handleEnvelope(&events.Envelope{Namespace: "k8s.io", Topic: containerd.ContainerCreateTopic, Timestamp: now})
+	}
+	check.handleEnvelope(&events.Envelope{Namespace: "k8s.io", Topic: containerd.ContainerDeleteTopic, Timestamp: now})
+	check.handleEnvelope(&events.Envelope{Namespace: "buildkit", Topic: containerd.ContainerCreateTopic, Timestamp: now})
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportContainerChurn(check.churnTracker.Churn(now), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.churn", 4, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers.churn", 4, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers.created", 3, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers.deleted", 1, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 4)
+
+	// The node churn is sent without events
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportContainerChurn(check.churnTracker.Churn(now.Add(2*time.Minute)), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.churn", 0, "", []string{"env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 1)
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// reportClockSkew sends the skew between the clocks of the agent and of
+// containerd estimated from the events received since the last run, in
+// seconds. A warning is logged once the skew exceeds the threshold, as the
+// uptimes and the churn computed from the timestamps of the events are
+// wrong then. Nothing is sent if no event was received.
+func (c *ContainerdCheck) reportClockSkew(tracker *containerd.ClockSkewTracker, sender aggregator.Sender) {
+	skew, observed := tracker.Flush()
+	if !observed {
+		return
+	}
+	sender.Gauge("containerd.clock_skew", skew.Seconds(), "", c.instance.Tags)
+
+	threshold := time.Duration(c.instance.ClockSkewThreshold) * time.Second
+	skewed := threshold > 0 && (skew > threshold || skew < -threshold)
+	if skewed && !c.clockSkewed {
+		log.Warnf("The clock of containerd is skewed by %s from the one of the agent, the container uptimes and churn are inaccurate", -skew)
+	} else if !skewed && c.clockSkewed {
+		log.Infof("The clock of containerd is no longer skewed from the one of the agent")
+	}
+	c.clockSkewed = skewed
+}
@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdClockSkew(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}, ClockSkewThreshold: 5},
+	}
+	tracker := containerd.NewClockSkewTracker()
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+
+	// Nothing is sent without events
+	check.reportClockSkew(tracker, mockSender)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 0)
+
+	now := time.Now()
+	tracker.Observe(now.Add(-30*time.Second), now)
+	check.reportClockSkew(tracker, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.clock_skew", 30, "", []string{"env:prod"})
+	assert.True(t, check.clockSkewed)
+
+	tracker.Observe(now.Add(-2*time.Second), now)
+	check.reportClockSkew(tracker, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.clock_skew", 2, "", []string{"env:prod"})
+	assert.False(t, check.clockSkewed)
+}
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// imageVerificationInterval is the minimum time between two
+	// verifications of the content of an image, as every blob is read
+	imageVerificationInterval = time.Hour
+	// imageVerificationTimeout bounds the verification of an image
+	imageVerificationTimeout = 5 * time.Minute
+)
+
+// collectContentUsage reports the disk usage of the content store, like
+// docker.data.used, in total and per namespace
+func (c *ContainerdCheck) collectContentUsage(sender aggregator.Sender) {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.instance.ListingBudget)*time.Second)
+	defer cancel()
+	usage, err := cu.ContentStoreUsage(ctx)
+	if err != nil {
+		log.Debugf("Cannot get the content store usage: %s", err)
+		return
+	}
+	c.reportContentUsage(usage, sender)
+}
+
+func (c *ContainerdCheck) reportContentUsage(usage *containerd.ContentUsage, sender aggregator.Sender) {
+	sender.Gauge("containerd.data.used", float64(usage.Total), "", c.instance.Tags)
+	for ns, size := range usage.Namespaces {
+		if c.namespaceFilter.IsExcluded(ns) {
+			continue
+		}
+		sender.Gauge("containerd.namespace.data.used", float64(size), "", c.namespaceTags(ns))
+	}
+}
+
+// verifyImageContents verifies the content of the images of the containers
+// not verified in the last imageVerificationInterval, and reports the
+// tampered blobs as security events
+func (c *ContainerdCheck) verifyImageContents(sender aggregator.Sender, now time.Time) {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return
+	}
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Warnf("Cannot list the containers to verify their image: %s", err)
+		return
+	}
+	if c.verifiedImages == nil {
+		c.verifiedImages = make(map[string]time.Time)
+	}
+
+	for _, cached := range ctns {
+		if cached.Image == "" || now.Sub(c.verifiedImages[cached.Image]) < imageVerificationInterval {
+			continue
+		}
+		ctn, err := cu.LoadContainer(cached.ID)
+		if err != nil {
+			log.Debugf("Cannot load container %s to verify its image: %s", cached.ID, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), imageVerificationTimeout)
+		result, err := cu.VerifyImageContent(ctx, ctn)
+		cancel()
+		if err != nil {
+			log.Warnf("Cannot verify the content of image %s: %s", cached.Image, err)
+			continue
+		}
+		c.verifiedImages[cached.Image] = now
+		log.Debugf("Verified %d blobs of image %s, %d are missing from the content store", result.Verified, result.Image, result.Missing)
+		if len(result.Mismatches) > 0 {
+			sender.Event(c.contentMismatchEvent(result, now))
+		}
+	}
+}
+
+// contentMismatchEvent returns the security event reporting the blobs of
+// an image whose data does not match their digest
+func (c *ContainerdCheck) contentMismatchEvent(result *containerd.ImageVerification, now time.Time) metrics.Event {
+	var lines []string
+	for _, m := range result.Mismatches {
+		lines = append(lines, fmt.Sprintf("%s\t%s\tactual digest %s", m.MediaType, m.Digest, m.Actual))
+	}
+	return metrics.Event{
+		Title:          fmt.Sprintf("Content of image %s modified on %s", result.Image, c.hostname),
+		Text:           fmt.Sprintf("%%%%%% \n```\n%s\n```\n %%%%%%", strings.Join(lines, "\n")),
+		Priority:       metrics.EventPriorityNormal,
+		AlertType:      metrics.EventAlertTypeError,
+		Host:           c.hostname,
+		SourceTypeName: containerdCheckName,
+		EventType:      containerdCheckName,
+		Ts:             now.Unix(),
+		AggregationKey: fmt.Sprintf("containerd:image:%s", result.Target),
+		Tags:           append([]string{"image_name:" + result.Image, "security:content_mismatch"}, c.instance.Tags...),
+	}
+}
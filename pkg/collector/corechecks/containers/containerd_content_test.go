@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdContentUsage(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	// The redis layer is pulled in both namespaces, it is stored once
+	daemon.AddContent("k8s.io", "sha256:redis", 1000)
+	daemon.AddContent("k8s.io", "sha256:nginx", 300)
+	daemon.AddContent("moby", "sha256:redis", 1000)
+	daemon.AddContent("buildkit", "sha256:cache", 50)
+	usage, err := daemon.Util("k8s.io").ContentStoreUsage(context.Background())
+	require.NoError(t, err)
+
+	check := &ContainerdCheck{
+		instance:        &ContainerdConfig{Tags: []string{"env:prod"}},
+		namespaceFilter: containerd.NewNamespaceFilter(nil, []string{"buildkit"}),
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportContentUsage(usage, mockSender)
+
+	mockSender.AssertMetric(t, "Gauge", "containerd.data.used", 1350, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.data.used", 1300, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.data.used", 1000, "", []string{"containerd_namespace:moby", "env:prod"})
+	mockSender.AssertNotCalled(t, "Gauge", "containerd.namespace.data.used", float64(50), "", []string{"containerd_namespace:buildkit", "env:prod"})
+}
+
+func TestContainerdContentMismatchEvent(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+		hostname: "node-1",
+	}
+	now := time.Now()
+	ev := check.contentMismatchEvent(&containerd.ImageVerification{
+		Image:    "docker.io/library/redis:5",
+		Target:   "sha256:a8e0f5ec5a0f0bd4d2bc0fe71f17e8bd9a3dc1a4c8c0a84cb7d60aa6c6f5e4f2",
+		Verified: 4,
+		Mismatches: []containerd.ContentMismatch{{
+			Digest:    "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			Actual:    "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+		}},
+	}, now)
+
+	assert.Equal(t, "Content of image docker.io/library/redis:5 modified on node-1", ev.Title)
+	assert.Contains(t, ev.Text, "sha256:1111111111111111111111111111111111111111111111111111111111111111\tactual digest sha256:2222222222222222222222222222222222222222222222222222222222222222")
+	assert.Equal(t, metrics.EventAlertTypeError, ev.AlertType)
+	assert.Equal(t, "containerd:image:sha256:a8e0f5ec5a0f0bd4d2bc0fe71f17e8bd9a3dc1a4c8c0a84cb7d60aa6c6f5e4f2", ev.AggregationKey)
+	assert.Equal(t, []string{"image_name:docker.io/library/redis:5", "security:content_mismatch", "env:prod"}, ev.Tags)
+	assert.Equal(t, now.Unix(), ev.Ts)
+}
@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// containerDiskUsage is the last measure of the writable layer of a
+// container, unsupported if its snapshot cannot be measured
+type containerDiskUsage struct {
+	used        int64
+	unsupported bool
+	measuredAt  time.Time
+}
+
+// collectDiskUsage reports the disk used by the writable layer of the
+// containers of a namespace, to find the containers filling the disk of
+// the node. The layers are measured once per disk_usage_interval, the
+// last measure is reported in between. The measures of a run are bounded
+// by listing_budget, the layers not measured in time are measured at the
+// next run.
+func (c *ContainerdCheck) collectDiskUsage(cu containerd.ContainerdItf, now time.Time, sender aggregator.Sender) {
+	namespace := cu.Namespace()
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Debugf("Cannot list the containers of namespace %s to measure their disk usage: %s", namespace, err)
+		return
+	}
+	if c.diskUsage == nil {
+		c.diskUsage = make(map[string]containerDiskUsage)
+	}
+	c.forgetDiskUsage(namespace, ctns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.instance.ListingBudget)*time.Second)
+	defer cancel()
+	interval := time.Duration(c.instance.DiskUsageInterval) * time.Second
+	for _, cached := range ctns {
+		key := namespace + "/" + cached.ID
+		usage, measured := c.diskUsage[key]
+		if (!measured || now.Sub(usage.measuredAt) >= interval) && ctx.Err() == nil {
+			used, err := measureUpperDir(ctx, cu, cached.ID)
+			switch {
+			case containerd.ErrorKind(err) == containerd.ErrUnsupported:
+				// Tried again at the next interval, like a measure
+				log.Debugf("The disk usage of container %s is not reported: %s", cached.ID, err)
+				usage, measured = containerDiskUsage{unsupported: true, measuredAt: now}, true
+				c.diskUsage[key] = usage
+			case err != nil:
+				log.Debugf("Cannot measure the disk usage of container %s: %s", cached.ID, err)
+			default:
+				usage, measured = containerDiskUsage{used: used, measuredAt: now}, true
+				c.diskUsage[key] = usage
+			}
+		}
+		if measured && !usage.unsupported {
+			c.reportDiskUsage(namespace, cached.ID, usage.used, sender)
+		}
+	}
+}
+
+// measureUpperDir returns the disk used by the writable layer of a container
+func measureUpperDir(ctx context.Context, cu containerd.ContainerdItf, id string) (int64, error) {
+	ctn, err := cu.LoadContainer(id)
+	if err != nil {
+		return 0, err
+	}
+	return cu.UpperDirSize(ctx, ctn)
+}
+
+// forgetDiskUsage drops the measures of the deleted containers of a namespace
+func (c *ContainerdCheck) forgetDiskUsage(namespace string, ctns []containerd.CachedContainer) {
+	current := make(map[string]struct{}, len(ctns))
+	for _, ctn := range ctns {
+		current[namespace+"/"+ctn.ID] = struct{}{}
+	}
+	for key := range c.diskUsage {
+		if _, found := current[key]; !found && strings.HasPrefix(key, namespace+"/") {
+			delete(c.diskUsage, key)
+		}
+	}
+}
+
+// reportDiskUsage sends the disk used by the writable layer of a container
+func (c *ContainerdCheck) reportDiskUsage(namespace, id string, used int64, sender aggregator.Sender) {
+	tags, err := tagger.Tag(containerd.EntityID(id), true)
+	if err != nil {
+		log.Debugf("no tags for %s: %s", id, err)
+	}
+	tags = append(append(tags, "containerd_namespace:"+namespace), c.instance.Tags...)
+	sender.Gauge("containerd.disk.used", float64(used), "", tags)
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdDiskUsage(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	redis := &containerdtest.Container{
+		Record:       ctrcontainers.Container{ID: "redis", Snapshotter: "overlayfs", SnapshotKey: "redis"},
+		UpperDirSize: 4096,
+	}
+	require.NoError(t, daemon.AddContainer("k8s.io", redis))
+	cu := daemon.Util("k8s.io")
+
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}, ListingBudget: 10, DiskUsageInterval: 300},
+	}
+	now := time.Now()
+	run := func(now time.Time) *mocksender.MockSender {
+		mockSender := mocksender.NewMockSender(check.ID())
+		mockSender.SetupAcceptAll()
+		check.collectDiskUsage(cu, now, mockSender)
+		return mockSender
+	}
+
+	mockSender := run(now)
+	mockSender.AssertMetric(t, "Gauge", "containerd.disk.used", 4096, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+
+	// The last measure is reported until the next interval
+	redis.UpperDirSize = 8192
+	mockSender = run(now.Add(time.Minute))
+	mockSender.AssertMetric(t, "Gauge", "containerd.disk.used", 4096, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender = run(now.Add(5 * time.Minute))
+	mockSender.AssertMetric(t, "Gauge", "containerd.disk.used", 8192, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+
+	// The containers without snapshot are not reported
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "build"}}))
+	require.NoError(t, daemon.DeleteContainer("k8s.io", "redis"))
+	mockSender = run(now.Add(10 * time.Minute))
+	mockSender.AssertNumberOfCalls(t, "Gauge", 0)
+	assert.Equal(t, map[string]containerDiskUsage{
+		"k8s.io/build": {unsupported: true, measuredAt: now.Add(10 * time.Minute)},
+	}, check.diskUsage)
+}
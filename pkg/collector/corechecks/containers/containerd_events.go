@@ -0,0 +1,227 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Image pull failures are not published on the containerd event stream,
+// they are only returned to the client pulling the image.
+const (
+	containerdTaskOOMTopic  = "/tasks/oom"
+	containerdTaskExitTopic = "/tasks/exit"
+
+	// containerdMaxPendingEvents bounds the events buffered between two runs
+	containerdMaxPendingEvents = 1000
+)
+
+var containerdTaskEventFilters = []string{
+	`topic=="` + containerdTaskOOMTopic + `"`,
+	`topic=="` + containerdTaskExitTopic + `"`,
+	`topic=="` + containerd.TaskCheckpointedTopic + `"`,
+	`topic=="` + containerd.TaskCreateTopic + `"`,
+}
+
+// containerdEventTitles are the templates of the Datadog event titles, formatted
+// with the container name, the hostname and the exit status for exit events.
+// The create events are only sent for the tasks restored from a checkpoint.
+var containerdEventTitles = map[string]string{
+	containerdTaskOOMTopic:           "Container %[1]s OOM killed on %[2]s",
+	containerdTaskExitTopic:          "Container %[1]s exited with %[3]d on %[2]s",
+	containerd.TaskCheckpointedTopic: "Container %[1]s checkpointed on %[2]s",
+	containerd.TaskCreateTopic:       "Container %[1]s restored from a checkpoint on %[2]s",
+}
+
+// containerdEvent is a containerd event selected to be sent to Datadog
+type containerdEvent struct {
+	topic       string
+	containerID string
+	exitStatus  uint32
+	timestamp   time.Time
+}
+
+// addEvent buffers an event until the next run of the check. A checkpoint
+// taken with --exit makes the task exit, possibly before the checkpoint
+// event is published: the exit is dropped in favor of the checkpoint so
+// that it does not look like a crash.
+func (c *ContainerdCheck) addEvent(ev containerdEvent) {
+	c.Lock()
+	defer c.Unlock()
+	switch ev.topic {
+	case containerdTaskExitTopic:
+		for _, pending := range c.pendingEvents {
+			if pending.topic == containerd.TaskCheckpointedTopic && pending.containerID == ev.containerID &&
+				containerd.IsCheckpointExit(ev.timestamp, pending.timestamp) {
+				return
+			}
+		}
+	case containerd.TaskCheckpointedTopic:
+		kept := c.pendingEvents[:0]
+		for _, pending := range c.pendingEvents {
+			if pending.topic != containerdTaskExitTopic || pending.containerID != ev.containerID ||
+				!containerd.IsCheckpointExit(pending.timestamp, ev.timestamp) {
+				kept = append(kept, pending)
+			}
+		}
+		c.pendingEvents = kept
+	}
+	if len(c.pendingEvents) >= containerdMaxPendingEvents {
+		log.Debugf("Too many pending containerd events, dropping %s for container %s", ev.topic, ev.containerID)
+		return
+	}
+	c.pendingEvents = append(c.pendingEvents, ev)
+}
+
+// flushEvents returns the buffered events and empties the buffer
+func (c *ContainerdCheck) flushEvents() []containerdEvent {
+	c.Lock()
+	defer c.Unlock()
+	events := c.pendingEvents
+	c.pendingEvents = nil
+	return events
+}
+
+// parseContainerdEnvelope returns the event to send for an envelope: every
+// OOM kill, the non-zero exits of the init process of the tasks, and the
+// checkpoints and restores of the tasks.
+func parseContainerdEnvelope(envelope *events.Envelope) (containerdEvent, bool) {
+	if envelope == nil {
+		return containerdEvent{}, false
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		log.Debugf("Cannot decode containerd event: %s", err)
+		return containerdEvent{}, false
+	}
+
+	switch e := ev.(type) {
+	case *apievents.TaskOOM:
+		return containerdEvent{
+			topic:       containerdTaskOOMTopic,
+			containerID: e.ContainerID,
+			timestamp:   envelope.Timestamp,
+		}, true
+	case *apievents.TaskExit:
+		// Processes exec'd in the container exit with their own ID
+		if e.ExitStatus == 0 || e.ID != e.ContainerID {
+			return containerdEvent{}, false
+		}
+		return containerdEvent{
+			topic:       containerdTaskExitTopic,
+			containerID: e.ContainerID,
+			exitStatus:  e.ExitStatus,
+			timestamp:   envelope.Timestamp,
+		}, true
+	case *apievents.TaskCheckpointed:
+		return containerdEvent{
+			topic:       containerd.TaskCheckpointedTopic,
+			containerID: e.ContainerID,
+			timestamp:   envelope.Timestamp,
+		}, true
+	case *apievents.TaskCreate:
+		if e.Checkpoint == "" {
+			return containerdEvent{}, false
+		}
+		return containerdEvent{
+			topic:       containerd.TaskCreateTopic,
+			containerID: e.ContainerID,
+			timestamp:   envelope.Timestamp,
+		}, true
+	default:
+		return containerdEvent{}, false
+	}
+}
+
+// reportEvents sends the containerd events to the Datadog event feed
+func (c *ContainerdCheck) reportEvents(events []containerdEvent, sender aggregator.Sender) {
+	for _, ev := range events {
+		entity := containerd.EntityID(ev.containerID)
+		tags, err := tagger.Tag(entity, true)
+		if err != nil {
+			log.Debugf("no tags for %s: %s", ev.containerID, err)
+		}
+		output := c.toDatadogEvent(ev, tags)
+		if len(c.annotationRules) > 0 {
+			c.annotationRules.Apply(&output, c.containerLabels(ev.containerID))
+		}
+		sender.Event(output)
+	}
+}
+
+// containerLabels returns the labels of a container, nil if
+// it cannot be found anymore or containerd is unreachable
+func (c *ContainerdCheck) containerLabels(containerID string) map[string]string {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return nil
+	}
+	ctn, err := cu.LoadContainer(containerID)
+	if err != nil {
+		log.Debugf("Cannot get the labels of container %s: %s", containerID, err)
+		return nil
+	}
+	info, err := cu.Info(ctn)
+	if err != nil {
+		log.Debugf("Cannot get the labels of container %s: %s", containerID, err)
+		return nil
+	}
+	return info.Labels
+}
+
+func (c *ContainerdCheck) toDatadogEvent(ev containerdEvent, tags []string) metrics.Event {
+	name := containerNameFromTags(tags)
+	if name == "" {
+		name = containerd.ShortID(ev.containerID)
+	}
+
+	action := strings.TrimPrefix(ev.topic, "/tasks/")
+	if ev.topic == containerd.TaskCreateTopic {
+		action = "restore"
+	}
+	output := metrics.Event{
+		Title:          fmt.Sprintf(containerdEventTitles[ev.topic], name, c.hostname, ev.exitStatus),
+		Text:           fmt.Sprintf("%%%%%% \n```\n%s\t%s\n```\n %%%%%%", action, ev.containerID),
+		Priority:       metrics.EventPriorityNormal,
+		AlertType:      metrics.EventAlertTypeWarning,
+		Host:           c.hostname,
+		SourceTypeName: containerdCheckName,
+		EventType:      containerdCheckName,
+		Ts:             ev.timestamp.Unix(),
+		AggregationKey: fmt.Sprintf("containerd:%s", ev.containerID),
+		Tags:           append(tags, c.instance.Tags...),
+	}
+	switch ev.topic {
+	case containerdTaskOOMTopic:
+		output.AlertType = metrics.EventAlertTypeError
+	case containerd.TaskCheckpointedTopic, containerd.TaskCreateTopic:
+		output.AlertType = metrics.EventAlertTypeInfo
+	}
+	return output
+}
+
+func containerNameFromTags(tags []string) string {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "container_name:") {
+			return strings.TrimPrefix(tag, "container_name:")
+		}
+	}
+	return ""
+}
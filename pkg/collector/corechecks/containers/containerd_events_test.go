@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func buildEnvelope(t *testing.T, topic string, ev interface{}, ts time.Time) *events.Envelope {
+	any, err := typeurl.MarshalAny(ev)
+	require.NoError(t, err)
+	return &events.Envelope{
+		Timestamp: ts,
+		Namespace: "k8s.io",
+		Topic:     topic,
+		Event:     any,
+	}
+}
+
+func TestParseContainerdEnvelope(t *testing.T) {
+	ts := time.Unix(1541000000, 0)
+
+	ev, ok := parseContainerdEnvelope(buildEnvelope(t, containerdTaskOOMTopic, &apievents.TaskOOM{ContainerID: "foo"}, ts))
+	assert.True(t, ok)
+	assert.Equal(t, containerdEvent{topic: containerdTaskOOMTopic, containerID: "foo", timestamp: ts}, ev)
+
+	ev, ok = parseContainerdEnvelope(buildEnvelope(t, containerdTaskExitTopic, &apievents.TaskExit{ContainerID: "foo", ID: "foo", ExitStatus: 137}, ts))
+	assert.True(t, ok)
+	assert.Equal(t, containerdEvent{topic: containerdTaskExitTopic, containerID: "foo", exitStatus: 137, timestamp: ts}, ev)
+
+	// Successful exits are ignored
+	_, ok = parseContainerdEnvelope(buildEnvelope(t, containerdTaskExitTopic, &apievents.TaskExit{ContainerID: "foo", ID: "foo"}, ts))
+	assert.False(t, ok)
+
+	// So are the exits of exec'd processes
+	_, ok = parseContainerdEnvelope(buildEnvelope(t, containerdTaskExitTopic, &apievents.TaskExit{ContainerID: "foo", ID: "exec-1", ExitStatus: 1}, ts))
+	assert.False(t, ok)
+
+	ev, ok = parseContainerdEnvelope(buildEnvelope(t, containerd.TaskCheckpointedTopic, &apievents.TaskCheckpointed{ContainerID: "foo", Checkpoint: "sha256:1"}, ts))
+	assert.True(t, ok)
+	assert.Equal(t, containerdEvent{topic: containerd.TaskCheckpointedTopic, containerID: "foo", timestamp: ts}, ev)
+
+	// Only the creations of tasks restored from a checkpoint are sent
+	ev, ok = parseContainerdEnvelope(buildEnvelope(t, containerd.TaskCreateTopic, &apievents.TaskCreate{ContainerID: "foo", Checkpoint: "sha256:1"}, ts))
+	assert.True(t, ok)
+	assert.Equal(t, containerdEvent{topic: containerd.TaskCreateTopic, containerID: "foo", timestamp: ts}, ev)
+	_, ok = parseContainerdEnvelope(buildEnvelope(t, containerd.TaskCreateTopic, &apievents.TaskCreate{ContainerID: "foo"}, ts))
+	assert.False(t, ok)
+
+	_, ok = parseContainerdEnvelope(buildEnvelope(t, "/tasks/start", &apievents.TaskStart{ContainerID: "foo"}, ts))
+	assert.False(t, ok)
+}
+
+func TestContainerdCheckpointExitEvents(t *testing.T) {
+	check := &ContainerdCheck{instance: &ContainerdConfig{}}
+	ts := time.Unix(1541000000, 0)
+
+	// The shim publishes the exit before the checkpoint
+	check.addEvent(containerdEvent{topic: containerdTaskExitTopic, containerID: "redis", exitStatus: 137, timestamp: ts})
+	check.addEvent(containerdEvent{topic: containerdTaskExitTopic, containerID: "nginx", exitStatus: 137, timestamp: ts})
+	check.addEvent(containerdEvent{topic: containerd.TaskCheckpointedTopic, containerID: "redis", timestamp: ts.Add(time.Second)})
+	assert.Equal(t, []containerdEvent{
+		{topic: containerdTaskExitTopic, containerID: "nginx", exitStatus: 137, timestamp: ts},
+		{topic: containerd.TaskCheckpointedTopic, containerID: "redis", timestamp: ts.Add(time.Second)},
+	}, check.flushEvents())
+
+	// Or the other way around
+	check.addEvent(containerdEvent{topic: containerd.TaskCheckpointedTopic, containerID: "redis", timestamp: ts})
+	check.addEvent(containerdEvent{topic: containerdTaskExitTopic, containerID: "redis", exitStatus: 137, timestamp: ts.Add(time.Second)})
+	// A crash long after the checkpoint is reported
+	check.addEvent(containerdEvent{topic: containerdTaskExitTopic, containerID: "redis", exitStatus: 1, timestamp: ts.Add(time.Hour)})
+	assert.Equal(t, []containerdEvent{
+		{topic: containerd.TaskCheckpointedTopic, containerID: "redis", timestamp: ts},
+		{topic: containerdTaskExitTopic, containerID: "redis", exitStatus: 1, timestamp: ts.Add(time.Hour)},
+	}, check.flushEvents())
+}
+
+func TestContainerdToDatadogEvent(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+		hostname: "myhost",
+	}
+	ts := time.Unix(1541000000, 0)
+
+	ev := check.toDatadogEvent(containerdEvent{
+		topic:       containerdTaskOOMTopic,
+		containerID: "0123456789abcdef",
+		timestamp:   ts,
+	}, []string{"container_name:redis", "short_image:redis"})
+	assert.Equal(t, "Container redis OOM killed on myhost", ev.Title)
+	assert.Equal(t, metrics.EventAlertTypeError, ev.AlertType)
+	assert.Equal(t, int64(1541000000), ev.Ts)
+	assert.Equal(t, "containerd:0123456789abcdef", ev.AggregationKey)
+	assert.Equal(t, []string{"container_name:redis", "short_image:redis", "env:prod"}, ev.Tags)
+
+	ev = check.toDatadogEvent(containerdEvent{
+		topic:       containerdTaskExitTopic,
+		containerID: "0123456789abcdef",
+		exitStatus:  1,
+		timestamp:   ts,
+	}, nil)
+	assert.Equal(t, "Container 0123456789ab exited with 1 on myhost", ev.Title)
+	assert.Equal(t, metrics.EventAlertTypeWarning, ev.AlertType)
+	assert.Equal(t, []string{"env:prod"}, ev.Tags)
+
+	ev = check.toDatadogEvent(containerdEvent{
+		topic:       containerd.TaskCreateTopic,
+		containerID: "0123456789abcdef",
+		timestamp:   ts,
+	}, []string{"container_name:redis"})
+	assert.Equal(t, "Container redis restored from a checkpoint on myhost", ev.Title)
+	assert.Contains(t, ev.Text, "restore\t0123456789abcdef")
+	assert.Equal(t, metrics.EventAlertTypeInfo, ev.AlertType)
+}
+
+func TestContainerdExcludedNamespaceEvents(t *testing.T) {
+	check := &ContainerdCheck{
+		instance:        &ContainerdConfig{},
+		collectEvents:   true,
+		namespaceFilter: containerd.NewNamespaceFilter(nil, []string{"buildkit"}),
+	}
+	ts := time.Unix(1541000000, 0)
+
+	envelope := buildEnvelope(t, containerdTaskOOMTopic, &apievents.TaskOOM{ContainerID: "builder"}, ts)
+	envelope.Namespace = "buildkit"
+	check.handleEnvelope(envelope)
+	assert.Empty(t, check.flushEvents())
+
+	check.handleEnvelope(buildEnvelope(t, containerdTaskOOMTopic, &apievents.TaskOOM{ContainerID: "redis"}, ts))
+	assert.Len(t, check.flushEvents(), 1)
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// containerdExecProbeServiceCheck reports the result of the exec
+	// probes, per container
+	containerdExecProbeServiceCheck = "containerd.exec_probe"
+	// defaultExecProbeTimeout bounds the probes without timeout
+	defaultExecProbeTimeout = 5 * time.Second
+)
+
+// containerdExecProbe is a command run in the matching containers, like a
+// docker HEALTHCHECK. The container is healthy if it exits with 0.
+type containerdExecProbe struct {
+	Name string `yaml:"name"`
+	// Image matches the containers by image name, with or without the
+	// registry, Labels by labels. The probe runs in every container if
+	// both are empty.
+	Image   string            `yaml:"image"`
+	Labels  map[string]string `yaml:"labels"`
+	Command []string          `yaml:"command"`
+	// Timeout is in seconds
+	Timeout int `yaml:"timeout"`
+}
+
+// matches returns whether the probe runs in a container
+func (p containerdExecProbe) matches(ctn containerd.CachedContainer) bool {
+	if p.Image != "" {
+		long, short, _, err := containers.SplitImageName(ctn.Image)
+		if err != nil || (p.Image != long && p.Image != short) {
+			return false
+		}
+	}
+	for k, v := range p.Labels {
+		if value, found := ctn.Labels[k]; !found || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (p containerdExecProbe) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return defaultExecProbeTimeout
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+// runExecProbes runs the exec probes in the running containers they match,
+// and reports a service check per probe and container
+func (c *ContainerdCheck) runExecProbes(sender aggregator.Sender) {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return
+	}
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Warnf("Cannot list the containers to probe: %s", err)
+		return
+	}
+
+	for _, cached := range ctns {
+		var probes []containerdExecProbe
+		for _, p := range c.instance.ExecProbes {
+			if p.matches(cached) {
+				probes = append(probes, p)
+			}
+		}
+		if len(probes) == 0 {
+			continue
+		}
+		ctn, err := cu.LoadContainer(cached.ID)
+		if err != nil {
+			log.Debugf("Cannot load container %s to probe it: %s", cached.ID, err)
+			continue
+		}
+		for _, p := range probes {
+			ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+			result, err := cu.Exec(ctx, ctn, p.Command)
+			cancel()
+			// The containers without a running task are not probed
+			if errdefs.IsNotFound(err) || errdefs.IsFailedPrecondition(err) {
+				log.Debugf("Container %s is not running, it is not probed: %s", cached.ID, err)
+				break
+			}
+			c.reportExecProbe(cached.ID, p, result, err, sender)
+		}
+	}
+}
+
+// reportExecProbe sends the service check of a probe: critical if the
+// command failed or timed out, unknown if it could not be run
+func (c *ContainerdCheck) reportExecProbe(id string, p containerdExecProbe, result *containerd.ExecResult, err error, sender aggregator.Sender) {
+	tags, tagErr := tagger.Tag(containerd.EntityID(id), true)
+	if tagErr != nil {
+		log.Debugf("no tags for %s: %s", id, tagErr)
+	}
+	tags = append(append([]string{"probe:" + p.Name}, tags...), c.instance.Tags...)
+
+	switch {
+	case containerd.ErrorKind(err) == containerd.ErrTimeout:
+		sender.ServiceCheck(containerdExecProbeServiceCheck, metrics.ServiceCheckCritical, "", tags,
+			fmt.Sprintf("%s timed out after %s", strings.Join(p.Command, " "), p.timeout()))
+	case err != nil:
+		log.Debugf("Cannot run probe %s in container %s: %s", p.Name, id, err)
+		sender.ServiceCheck(containerdExecProbeServiceCheck, metrics.ServiceCheckUnknown, "", tags, err.Error())
+	case result.ExitCode != 0:
+		sender.ServiceCheck(containerdExecProbeServiceCheck, metrics.ServiceCheckCritical, "", tags,
+			fmt.Sprintf("exit code %d: %s", result.ExitCode, result.Output))
+	default:
+		sender.ServiceCheck(containerdExecProbeServiceCheck, metrics.ServiceCheckOK, "", tags, "")
+	}
+}
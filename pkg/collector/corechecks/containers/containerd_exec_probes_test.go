@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdExecProbeMatches(t *testing.T) {
+	redis := containerd.CachedContainer{
+		ID:     "redis",
+		Image:  "docker.io/library/redis:5",
+		Labels: map[string]string{"app": "cache", "team": "storage"},
+	}
+	for name, tc := range map[string]struct {
+		probe   containerdExecProbe
+		matches bool
+	}{
+		"any container": {probe: containerdExecProbe{}, matches: true},
+		"short image":   {probe: containerdExecProbe{Image: "redis"}, matches: true},
+		"long image":    {probe: containerdExecProbe{Image: "docker.io/library/redis"}, matches: true},
+		"other image":   {probe: containerdExecProbe{Image: "nginx"}, matches: false},
+		"labels":        {probe: containerdExecProbe{Labels: map[string]string{"app": "cache"}}, matches: true},
+		"other label":   {probe: containerdExecProbe{Image: "redis", Labels: map[string]string{"app": "web"}}, matches: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.matches, tc.probe.matches(redis))
+		})
+	}
+}
+
+func TestContainerdReportExecProbe(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	probe := containerdExecProbe{Name: "ping", Command: []string{"redis-cli", "ping"}}
+	tags := []string{"probe:ping", "env:prod"}
+
+	for name, tc := range map[string]struct {
+		result  *containerd.ExecResult
+		err     error
+		status  metrics.ServiceCheckStatus
+		message string
+	}{
+		"healthy":   {result: &containerd.ExecResult{Output: "PONG\n"}, status: metrics.ServiceCheckOK},
+		"unhealthy": {result: &containerd.ExecResult{ExitCode: 1, Output: "LOADING"}, status: metrics.ServiceCheckCritical, message: "exit code 1: LOADING"},
+		"timeout": {
+			err:     &containerd.Error{Kind: containerd.ErrTimeout, Err: errors.New("context deadline exceeded")},
+			status:  metrics.ServiceCheckCritical,
+			message: "redis-cli ping timed out after 5s",
+		},
+		"exec error": {err: errors.New("no such file or directory"), status: metrics.ServiceCheckUnknown, message: "no such file or directory"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockSender := mocksender.NewMockSender(check.ID())
+			mockSender.SetupAcceptAll()
+			check.reportExecProbe("redis", probe, tc.result, tc.err, mockSender)
+			mockSender.AssertServiceCheck(t, containerdExecProbeServiceCheck, tc.status, "", tags, tc.message)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// containerdImageStats accumulates the image events between two runs
+type containerdImageStats struct {
+	pulls          []containerdImagePull
+	blobsPulled    int
+	blobsDeleted   int
+	reclaimedBytes int64
+}
+
+type containerdImagePull struct {
+	image    string
+	duration time.Duration
+}
+
+// imageEventHooks returns the hooks accumulating the image events
+func (c *ContainerdCheck) imageEventHooks() containerd.ImageEventHooks {
+	return containerd.ImageEventHooks{
+		PullStarted: func(ref string) {
+			c.Lock()
+			c.imageStats.blobsPulled++
+			c.Unlock()
+		},
+		PullCompleted: func(image string, duration time.Duration) {
+			c.Lock()
+			c.imageStats.pulls = append(c.imageStats.pulls, containerdImagePull{image: image, duration: duration})
+			c.Unlock()
+		},
+		ContentDeleted: func(digest string, size int64) {
+			c.Lock()
+			c.imageStats.blobsDeleted++
+			c.imageStats.reclaimedBytes += size
+			c.Unlock()
+		},
+	}
+}
+
+// flushImageStats returns the accumulated image stats and resets them
+func (c *ContainerdCheck) flushImageStats() containerdImageStats {
+	c.Lock()
+	defer c.Unlock()
+	stats := c.imageStats
+	c.imageStats = containerdImageStats{}
+	return stats
+}
+
+// reportImageMetrics sends the pulls by image, and the blobs downloaded
+// and garbage collected by the node
+func (c *ContainerdCheck) reportImageMetrics(stats containerdImageStats, sender aggregator.Sender) {
+	for _, pull := range stats.pulls {
+		tags := append(imageTags(pull.image), c.instance.Tags...)
+		sender.Count("containerd.image.pulls", 1, "", tags)
+		// The duration is unknown if the pull started before the check
+		if pull.duration > 0 {
+			sender.Histogram("containerd.image.pull.duration", pull.duration.Seconds(), "", tags)
+		}
+	}
+	sender.Count("containerd.image.pull.blobs", float64(stats.blobsPulled), "", c.instance.Tags)
+	sender.Count("containerd.image.gc.deleted_blobs", float64(stats.blobsDeleted), "", c.instance.Tags)
+	sender.Count("containerd.image.gc.reclaimed_bytes", float64(stats.reclaimedBytes), "", c.instance.Tags)
+}
+
+func imageTags(image string) []string {
+	long, short, tag, err := containers.SplitImageName(image)
+	if err != nil {
+		log.Debugf("Cannot split the image name %s: %s", image, err)
+		return []string{fmt.Sprintf("image_name:%s", image)}
+	}
+	tags := []string{
+		fmt.Sprintf("image_name:%s", long),
+		fmt.Sprintf("short_image:%s", short),
+	}
+	if tag != "" {
+		tags = append(tags, fmt.Sprintf("image_tag:%s", tag))
+	}
+	return tags
+}
+
+// containerdImageStore summarizes the image store of a namespace
+type containerdImageStore struct {
+	count     int
+	size      int64
+	stale     int
+	staleSize int64
+}
+
+// collectImageStore reports the number and the size of the images of a
+// namespace, and the ones unused by its containers for stale_image_days, to
+// tune the garbage collection of the images
+func (c *ContainerdCheck) collectImageStore(cu containerd.ContainerdItf, ctns []containerd.CachedContainer, now time.Time, sender aggregator.Sender) {
+	images, err := cu.Images()
+	if err != nil {
+		if containerd.ErrorKind(err) == containerd.ErrUnsupported {
+			log.Debugf("Image store metrics are not reported: %s", err)
+		} else {
+			log.Warnf("Cannot list the images of namespace %s: %s", cu.Namespace(), err)
+		}
+		return
+	}
+	staleAfter := time.Duration(c.instance.StaleImageDays) * 24 * time.Hour
+	store := summarizeImageStore(images, ctns, staleAfter, now)
+
+	tags := c.namespaceTags(cu.Namespace())
+	sender.Gauge("containerd.images.count", float64(store.count), "", tags)
+	sender.Gauge("containerd.images.size", float64(store.size), "", tags)
+	if staleAfter > 0 {
+		sender.Gauge("containerd.images.stale", float64(store.stale), "", tags)
+		sender.Gauge("containerd.images.stale_size", float64(store.staleSize), "", tags)
+	}
+}
+
+// summarizeImageStore counts the images by digest, as an image is recorded
+// under each of its names. An image is stale if no container uses it under
+// any of its names, and if it was not pulled nor tagged for staleAfter.
+func summarizeImageStore(images []containerd.Image, ctns []containerd.CachedContainer, staleAfter time.Duration, now time.Time) containerdImageStore {
+	used := make(map[string]bool, len(ctns))
+	for _, ctn := range ctns {
+		used[ctn.Image] = true
+	}
+
+	type storedImage struct {
+		size      int64
+		updatedAt time.Time
+		used      bool
+	}
+	byDigest := make(map[string]*storedImage)
+	for _, img := range images {
+		stored, found := byDigest[img.Digest.String()]
+		if !found {
+			stored = &storedImage{}
+			byDigest[img.Digest.String()] = stored
+		}
+		// The content of the image may still be pulled under one of its names
+		if img.Size > stored.size {
+			stored.size = img.Size
+		}
+		updatedAt := img.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = img.CreatedAt
+		}
+		if updatedAt.After(stored.updatedAt) {
+			stored.updatedAt = updatedAt
+		}
+		stored.used = stored.used || used[img.Name]
+	}
+
+	var store containerdImageStore
+	for _, stored := range byDigest {
+		store.count++
+		store.size += stored.size
+		if staleAfter > 0 && !stored.used && now.Sub(stored.updatedAt) > staleAfter {
+			store.stale++
+			store.staleSize += stored.size
+		}
+	}
+	return store
+}
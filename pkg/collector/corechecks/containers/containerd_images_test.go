@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdImageMetrics(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	hooks := check.imageEventHooks()
+	hooks.PullStarted("layer-sha256:aaa")
+	hooks.PullStarted("layer-sha256:bbb")
+	hooks.PullCompleted("docker.io/library/redis:5", 12*time.Second)
+	hooks.PullCompleted("docker.io/library/nginx:1", 0)
+	hooks.ContentDeleted("sha256:ccc", 2048)
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportImageMetrics(check.flushImageStats(), mockSender)
+
+	redisTags := []string{"image_name:docker.io/library/redis", "short_image:redis", "image_tag:5", "env:prod"}
+	nginxTags := []string{"image_name:docker.io/library/nginx", "short_image:nginx", "image_tag:1", "env:prod"}
+	mockSender.AssertMetric(t, "Count", "containerd.image.pulls", 1, "", redisTags)
+	mockSender.AssertMetric(t, "Histogram", "containerd.image.pull.duration", 12, "", redisTags)
+	mockSender.AssertMetric(t, "Count", "containerd.image.pulls", 1, "", nginxTags)
+	mockSender.AssertNumberOfCalls(t, "Histogram", 1)
+	mockSender.AssertMetric(t, "Count", "containerd.image.pull.blobs", 2, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Count", "containerd.image.gc.deleted_blobs", 1, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Count", "containerd.image.gc.reclaimed_bytes", 2048, "", []string{"env:prod"})
+
+	// Stats are reset after a flush
+	assert.Equal(t, containerdImageStats{}, check.flushImageStats())
+}
+
+func TestContainerdImageStore(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+	daemon := containerdtest.NewDaemon()
+	// redis is recorded by tag and by digest
+	daemon.AddImage("k8s.io", containerd.Image{Name: "docker.io/library/redis:5", Digest: "sha256:redis", Size: 1000, CreatedAt: old, UpdatedAt: old})
+	daemon.AddImage("k8s.io", containerd.Image{Name: "docker.io/library/redis@sha256:redis", Digest: "sha256:redis", Size: 1000, CreatedAt: old})
+	daemon.AddImage("k8s.io", containerd.Image{Name: "docker.io/library/nginx:1", Digest: "sha256:nginx", Size: 300, CreatedAt: old, UpdatedAt: old})
+	daemon.AddImage("k8s.io", containerd.Image{Name: "docker.io/library/busybox:1", Digest: "sha256:busybox", Size: 20, CreatedAt: old, UpdatedAt: now})
+	ctns := []containerd.CachedContainer{{ID: "redis", Image: "docker.io/library/redis@sha256:redis"}}
+
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}, StaleImageDays: 30},
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.collectImageStore(daemon.Util("k8s.io"), ctns, now, mockSender)
+
+	tags := []string{"containerd_namespace:k8s.io", "env:prod"}
+	mockSender.AssertMetric(t, "Gauge", "containerd.images.count", 3, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.images.size", 1320, "", tags)
+	// busybox was pulled again recently, only nginx is stale
+	mockSender.AssertMetric(t, "Gauge", "containerd.images.stale", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.images.stale_size", 300, "", tags)
+
+	// The stale images are not reported if stale_image_days is 0
+	check.instance.StaleImageDays = 0
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.collectImageStore(daemon.Util("k8s.io"), ctns, now, mockSender)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 2)
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+// sampleNamespaceTask is the containerd.TaskSampler of the lifetime
+// tracker, reading the task through the util of its namespace
+func (c *ContainerdCheck) sampleNamespaceTask(namespace, id string) (containerd.TaskSample, error) {
+	opts := containerdconfig.Options()
+	opts.Namespace = namespace
+	cu, err := c.provider().Get(&opts)
+	if err != nil {
+		return containerd.TaskSample{}, err
+	}
+	return sampleTask(cu, id)
+}
+
+// sampleTask reads the stats and the tags of the task of a container. The
+// tags are returned even if the stats cannot be read, eg. once the cgroup
+// of the task is gone.
+func sampleTask(cu containerd.ContainerdItf, id string) (containerd.TaskSample, error) {
+	var sample containerd.TaskSample
+	sample.Tags, _ = tagger.Tag(containerd.EntityID(id), true)
+
+	ctn, err := cu.LoadContainer(id)
+	if err != nil {
+		return sample, err
+	}
+	metric, err := cu.TaskMetrics(ctn)
+	if err != nil {
+		return sample, err
+	}
+	sample.Stats, err = containerd.DecodeTaskMetrics(metric)
+	return sample, err
+}
+
+// reportTaskLifetimes sends the usage of the tasks exited since the last
+// run over their lifetime, tagged with their exit code, so that the
+// containers living less than the check interval are accounted for. The
+// usage is not sent for the tasks that could not be sampled.
+func (c *ContainerdCheck) reportTaskLifetimes(lifetimes []containerd.TaskLifetime, sender aggregator.Sender) {
+	for _, l := range lifetimes {
+		tags := l.Tags
+		if len(tags) == 0 {
+			tags = []string{"container_id:" + l.ContainerID}
+		}
+		tags = append(append(tags[:len(tags):len(tags)],
+			"containerd_namespace:"+l.Namespace,
+			fmt.Sprintf("exit_code:%d", l.ExitStatus),
+		), c.instance.Tags...)
+
+		sender.Gauge("containerd.task.lifetime.duration", l.Duration().Seconds(), "", tags)
+		if l.Samples == 0 {
+			continue
+		}
+		sender.Gauge("containerd.task.lifetime.cpu.total", float64(l.CPUTotal), "", tags)
+		sender.Gauge("containerd.task.lifetime.mem.peak", float64(l.MaxMemory), "", tags)
+		sender.Gauge("containerd.task.lifetime.io.read_bytes", float64(l.ReadBytes), "", tags)
+		sender.Gauge("containerd.task.lifetime.io.write_bytes", float64(l.WriteBytes), "", tags)
+	}
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdTaskLifetimes(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	now := time.Now()
+	lifetimes := []containerd.TaskLifetime{
+		{
+			Namespace:   "k8s.io",
+			ContainerID: "job",
+			Tags:        []string{"kube_job:backup"},
+			StartedAt:   now.Add(-3 * time.Second),
+			ExitedAt:    now,
+			Samples:     2,
+			CPUTotal:    5000,
+			MaxMemory:   4096,
+			ReadBytes:   512,
+			WriteBytes:  192,
+		},
+		{
+			Namespace:   "k8s.io",
+			ContainerID: "crashed",
+			StartedAt:   now.Add(-time.Second),
+			ExitedAt:    now,
+			ExitStatus:  137,
+		},
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskLifetimes(lifetimes, mockSender)
+
+	tags := []string{"kube_job:backup", "containerd_namespace:k8s.io", "exit_code:0", "env:prod"}
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.duration", 3, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.cpu.total", 5000, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.mem.peak", 4096, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.io.read_bytes", 512, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.io.write_bytes", 192, "", tags)
+	// The usage of the tasks not sampled is unknown
+	mockSender.AssertMetric(t, "Gauge", "containerd.task.lifetime.duration", 1, "", []string{"container_id:crashed", "containerd_namespace:k8s.io", "exit_code:137", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 6)
+	// The tags of the lifetimes are not changed
+	assert.Equal(t, []string{"kube_job:backup"}, lifetimes[0].Tags)
+}
+
+func TestContainerdSampleTask(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "job"}}))
+
+	// The task of the container exited and was deleted
+	sample, err := sampleTask(daemon.Util("k8s.io"), "job")
+	assert.Error(t, err)
+	assert.Nil(t, sample.Stats)
+
+	_, err = sampleTask(daemon.Util("k8s.io"), "unknown")
+	assert.Error(t, err)
+}
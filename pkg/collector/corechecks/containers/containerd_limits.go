@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// cpuSample is the CPU usage of a task at a check run
+type cpuSample struct {
+	total uint64
+	at    time.Time
+}
+
+// containerLimits returns the resource limits set in the spec of the
+// containers of a namespace, by ID
+func (c *ContainerdCheck) containerLimits(cu containerd.ContainerdItf) map[string]containerd.ResourceLimits {
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Debugf("Cannot list the limits of the containers of namespace %s: %s", cu.Namespace(), err)
+		return nil
+	}
+	limits := make(map[string]containerd.ResourceLimits, len(ctns))
+	for _, ctn := range ctns {
+		limits[ctn.ID] = ctn.Limits
+	}
+	return limits
+}
+
+// reportTaskLimits sends the CPU and memory limits of the task of a
+// container, and the share of them it uses: its CPU usage since the
+// previous run over its CPU limit, and its working set over its memory
+// limit, to alert on the containers close to being throttled or OOM
+// killed. The memory limit is the one of the cgroup, or the one of the
+// spec if the cgroup is unlimited. The unlimited resources are not reported.
+func (c *ContainerdCheck) reportTaskLimits(namespace, id string, stats *containerd.TaskStats, limits containerd.ResourceLimits, now time.Time, sender aggregator.Sender) {
+	key := namespace + "/" + id
+	previous, sampled := c.cpuSamples[key]
+	if c.cpuSamples == nil {
+		c.cpuSamples = make(map[string]cpuSample)
+	}
+	c.cpuSamples[key] = cpuSample{total: stats.CPUTotal, at: now}
+
+	memoryLimit := stats.MemoryLimit
+	if memoryLimit == 0 {
+		memoryLimit = limits.Memory
+	}
+	reportCPU := limits.CPUs > 0 && c.instance.metricFamilyEnabled(metricFamilyCPU)
+	reportMemory := memoryLimit > 0 && c.instance.metricFamilyEnabled(metricFamilyMemory)
+	if !reportCPU && !reportMemory {
+		return
+	}
+
+	tags, err := tagger.Tag(containerd.EntityID(id), true)
+	if err != nil {
+		log.Debugf("no tags for %s: %s", id, err)
+	}
+	tags = append(append(tags, "containerd_namespace:"+namespace), c.instance.Tags...)
+
+	if reportCPU {
+		sender.Gauge("containerd.cpu.limit", limits.CPUs, "", tags)
+		// The counter restarts with the task
+		if elapsed := now.Sub(previous.at); sampled && elapsed > 0 && stats.CPUTotal >= previous.total {
+			usedCPUs := float64(stats.CPUTotal-previous.total) / float64(elapsed.Nanoseconds())
+			sender.Gauge("containerd.cpu.saturation", usedCPUs/limits.CPUs, "", tags)
+		}
+	}
+	if reportMemory {
+		sender.Gauge("containerd.mem.limit", float64(memoryLimit), "", tags)
+		sender.Gauge("containerd.mem.saturation", float64(stats.MemoryWorkingSet)/float64(memoryLimit), "", tags)
+	}
+}
+
+// forgetCPUSamples drops the CPU usage of the tasks of a namespace not
+// reported since the given time, eg. the tasks which exited
+func (c *ContainerdCheck) forgetCPUSamples(namespace string, since time.Time) {
+	for key, sample := range c.cpuSamples {
+		if strings.HasPrefix(key, namespace+"/") && sample.at.Before(since) {
+			delete(c.cpuSamples, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdTaskLimits(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	limits := containerd.ResourceLimits{CPUs: 0.5, Memory: 8192}
+	now := time.Now()
+	tags := []string{"containerd_namespace:k8s.io", "env:prod"}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskLimits("k8s.io", "redis", &containerd.TaskStats{CPUTotal: 1e9, MemoryLimit: 4096, MemoryWorkingSet: 1024}, limits, now, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.cpu.limit", 0.5, "", tags)
+	// The memory limit of the cgroup prevails
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.limit", 4096, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.saturation", 0.25, "", tags)
+	// The CPU usage is known from the second run
+	mockSender.AssertNumberOfCalls(t, "Gauge", 3)
+
+	// 0.4 CPU used over the last 10 seconds
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskLimits("k8s.io", "redis", &containerd.TaskStats{CPUTotal: 5e9, MemoryWorkingSet: 2048}, limits, now.Add(10*time.Second), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.cpu.saturation", 0.8, "", tags)
+	// The spec limits the memory of the unlimited cgroups
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.limit", 8192, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.saturation", 0.25, "", tags)
+
+	// The unlimited tasks are not reported
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskLimits("k8s.io", "nginx", &containerd.TaskStats{CPUTotal: 1e9}, containerd.ResourceLimits{}, now, mockSender)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 0)
+
+	// The exited tasks are forgotten
+	check.forgetCPUSamples("k8s.io", now.Add(time.Second))
+	assert.Len(t, check.cpuSamples, 1)
+	assert.Contains(t, check.cpuSamples, "k8s.io/redis")
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// collectNamespaces reports the metrics of every collected namespace, to
+// break down the nodes shared by several containerd clients, like
+// kubernetes, buildkit and docker. The namespaces and their containers are
+// listed within listing_budget, the namespaces not listed in time are
+// skipped. The namespaces of namespace_intervals are collected once per
+// interval, to bound the load of the namespaces like buildkit on the CI
+// nodes.
+func (c *ContainerdCheck) collectNamespaces(sender aggregator.Sender) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.instance.ListingBudget)*time.Second)
+	defer cancel()
+	start := time.Now()
+	utils, err := c.provider().NamespacedUtils(ctx)
+	c.profile.Since(profileList, start)
+	if err != nil {
+		log.Warnf("Cannot list the containerd namespaces: %s", err)
+		return
+	}
+	c.reportNamespaces(ctx, utils, time.Now(), sender)
+}
+
+// reportNamespaces sends the number of containers of each namespace, and
+// the image store metrics, the uptime of the containers and the task metrics
+// of the namespace if they are collected.
+// The metrics are tagged by containerd_namespace, but the number of
+// containers of the node, sent along with the container churn. The
+// namespaces not due are counted in the density from their last listing.
+func (c *ContainerdCheck) reportNamespaces(ctx context.Context, utils []containerd.ContainerdItf, now time.Time, sender aggregator.Sender) {
+	if c.namespaceContainers == nil {
+		c.namespaceContainers = make(map[string]int)
+	}
+	c.forgetNamespaces(utils)
+	due := c.dueNamespaces(utils, now)
+	start := time.Now()
+	listings := containerd.ListNamespaceContainers(ctx, due)
+	c.profile.Since(profileList, start)
+	for i, cu := range due {
+		listing := listings[i]
+		if listing.Err != nil {
+			delete(c.namespaceContainers, listing.Namespace)
+			if containerd.ErrorKind(listing.Err) == containerd.ErrTimeout {
+				log.Warnf("Skipping namespace %s: %s", listing.Namespace, listing.Err)
+				continue
+			}
+			log.Debugf("Cannot list the containers of namespace %s: %s", listing.Namespace, listing.Err)
+		} else {
+			c.namespaceContainers[listing.Namespace] = len(listing.Containers)
+			sender.Gauge("containerd.namespace.containers", float64(len(listing.Containers)), "", c.namespaceTags(listing.Namespace))
+			if c.instance.CollectImageMetrics {
+				c.collectImageStore(cu, listing.Containers, now, sender)
+			}
+		}
+		if c.instance.CollectContainerState {
+			c.reportUptimes(cu, now, sender)
+		}
+		if c.instance.CollectTaskMetrics {
+			c.collectTaskMetrics(cu, sender)
+		}
+		if c.instance.CollectDiskUsage {
+			c.collectDiskUsage(cu, now, sender)
+		}
+	}
+	// The namespaces skipped or not listed are not counted
+	if c.instance.CollectContainerChurn {
+		var density int
+		for _, cu := range utils {
+			density += c.namespaceContainers[cu.Namespace()]
+		}
+		sender.Gauge("containerd.containers.density", float64(density), "", c.instance.Tags)
+	}
+}
+
+// dueNamespaces returns the utils of the namespaces to collect at this run,
+// the namespaces of namespace_intervals are collected once per interval
+func (c *ContainerdCheck) dueNamespaces(utils []containerd.ContainerdItf, now time.Time) []containerd.ContainerdItf {
+	if len(c.instance.NamespaceIntervals) == 0 {
+		return utils
+	}
+	if c.namespaceRuns == nil {
+		c.namespaceRuns = make(map[string]time.Time)
+	}
+	due := make([]containerd.ContainerdItf, 0, len(utils))
+	for _, cu := range utils {
+		namespace := cu.Namespace()
+		interval := time.Duration(c.instance.NamespaceIntervals[namespace]) * time.Second
+		if last, found := c.namespaceRuns[namespace]; found && now.Sub(last) < interval {
+			continue
+		}
+		c.namespaceRuns[namespace] = now
+		due = append(due, cu)
+	}
+	return due
+}
+
+// forgetNamespaces drops the state of the namespaces deleted or out of the
+// collection scope, the namespaces of the tenants come and go
+func (c *ContainerdCheck) forgetNamespaces(utils []containerd.ContainerdItf) {
+	current := make(map[string]struct{}, len(utils))
+	for _, cu := range utils {
+		current[cu.Namespace()] = struct{}{}
+	}
+	for namespace := range c.namespaceContainers {
+		if _, found := current[namespace]; !found {
+			delete(c.namespaceContainers, namespace)
+		}
+	}
+	for namespace := range c.namespaceRuns {
+		if _, found := current[namespace]; !found {
+			delete(c.namespaceRuns, namespace)
+		}
+	}
+	for key := range c.diskUsage {
+		if _, found := current[strings.SplitN(key, "/", 2)[0]]; !found {
+			delete(c.diskUsage, key)
+		}
+	}
+	for key := range c.cpuSamples {
+		if _, found := current[strings.SplitN(key, "/", 2)[0]]; !found {
+			delete(c.cpuSamples, key)
+		}
+	}
+}
+
+// namespaceTags returns the tags of the roll-up metrics of a namespace
+func (c *ContainerdCheck) namespaceTags(namespace string) []string {
+	return append([]string{"containerd_namespace:" + namespace}, c.instance.Tags...)
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	"github.com/containerd/containerd/api/types"
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdNamespaces(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	running := &containerdtest.Task{
+		Pid:     42,
+		Status:  containerdclient.Status{Status: containerdclient.Running},
+		Metrics: &types.Metric{},
+	}
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "redis"}, TaskState: running}))
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "pause"}}))
+	require.NoError(t, daemon.AddContainer("buildkit", &containerdtest.Container{Record: ctrcontainers.Container{ID: "build"}}))
+	utils := []containerd.ContainerdItf{daemon.Util("k8s.io"), daemon.Util("buildkit")}
+
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportNamespaces(context.Background(), utils, time.Now(), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers", 2, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers", 1, "", []string{"containerd_namespace:buildkit", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 2)
+
+	// The tasks are counted with the task metrics
+	check.instance.CollectTaskMetrics = true
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportNamespaces(context.Background(), utils, time.Now(), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.tasks", 1, "", []string{"containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.tasks", 0, "", []string{"containerd_namespace:buildkit", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 4)
+
+	// The node density is sent with the container churn
+	check.instance.CollectTaskMetrics = false
+	check.instance.CollectContainerChurn = true
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportNamespaces(context.Background(), utils, time.Now(), mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.density", 3, "", []string{"env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 3)
+}
+
+func TestContainerdNamespaceIntervals(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "redis"}}))
+	require.NoError(t, daemon.AddContainer("buildkit", &containerdtest.Container{Record: ctrcontainers.Container{ID: "build"}}))
+	utils := []containerd.ContainerdItf{daemon.Util("k8s.io"), daemon.Util("buildkit")}
+
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{
+			CollectContainerChurn: true,
+			NamespaceIntervals:    map[string]int{"buildkit": 300},
+		},
+	}
+	now := time.Now()
+	run := func(now time.Time) *mocksender.MockSender {
+		mockSender := mocksender.NewMockSender(check.ID())
+		mockSender.SetupAcceptAll()
+		check.reportNamespaces(context.Background(), utils, now, mockSender)
+		return mockSender
+	}
+
+	mockSender := run(now)
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers", 1, "", []string{"containerd_namespace:buildkit"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 3)
+
+	// buildkit is not listed again before its interval, its last listing
+	// is still counted in the density
+	mockSender = run(now.Add(15 * time.Second))
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers", 1, "", []string{"containerd_namespace:k8s.io"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.density", 2, "", nil)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 2)
+
+	mockSender = run(now.Add(300 * time.Second))
+	mockSender.AssertMetric(t, "Gauge", "containerd.namespace.containers", 1, "", []string{"containerd_namespace:buildkit"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 3)
+
+	// The state of the deleted namespaces is dropped
+	utils = utils[:1]
+	run(now.Add(310 * time.Second))
+	assert.NotContains(t, check.namespaceRuns, "buildkit")
+	assert.NotContains(t, check.namespaceContainers, "buildkit")
+}
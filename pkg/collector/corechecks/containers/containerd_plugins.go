@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// containerdPluginServiceCheck reports the initialization of the critical
+// plugins, per plugin
+const containerdPluginServiceCheck = "containerd.plugin.health"
+
+// defaultCriticalPlugins are the plugins the pods cannot start without: the
+// CRI plugin, the default snapshotter of the CRI plugin and the task service
+var defaultCriticalPlugins = []string{
+	"io.containerd.grpc.v1.cri",
+	"io.containerd.snapshotter.v1.overlayfs",
+	"io.containerd.runtime.v2.task",
+	"io.containerd.service.v1.tasks-service",
+}
+
+// collectPluginHealth reports the health of the critical plugins, read
+// from the introspection service
+func (c *ContainerdCheck) collectPluginHealth(sender aggregator.Sender) {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.instance.ListingBudget)*time.Second)
+	defer cancel()
+	plugins, err := cu.Plugins(ctx)
+	if err != nil {
+		log.Debugf("Cannot list the containerd plugins: %s", err)
+		return
+	}
+	c.reportPluginHealth(plugins, sender)
+}
+
+// reportPluginHealth sends a service check per critical plugin, critical
+// with the initialization error of the plugins which failed to initialize.
+// The plugins not loaded, like the disabled ones, are not reported.
+func (c *ContainerdCheck) reportPluginHealth(plugins []containerd.PluginInfo, sender aggregator.Sender) {
+	loaded := make(map[string]containerd.PluginInfo, len(plugins))
+	for _, p := range plugins {
+		loaded[p.Name()] = p
+	}
+	for _, name := range c.instance.CriticalPlugins {
+		p, found := loaded[name]
+		if !found {
+			log.Debugf("containerd plugin %s is not loaded, its health is not reported", name)
+			continue
+		}
+		tags := append([]string{"plugin:" + name}, c.instance.Tags...)
+		if p.InitError != "" {
+			sender.ServiceCheck(containerdPluginServiceCheck, metrics.ServiceCheckCritical, "", tags, p.InitError)
+		} else {
+			sender.ServiceCheck(containerdPluginServiceCheck, metrics.ServiceCheckOK, "", tags, "")
+		}
+	}
+}
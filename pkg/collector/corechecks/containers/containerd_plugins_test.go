@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdPluginHealth(t *testing.T) {
+	instance := &ContainerdConfig{}
+	require.NoError(t, instance.Parse([]byte("tags: [env:prod]")))
+	assert.Equal(t, defaultCriticalPlugins, instance.CriticalPlugins)
+	check := &ContainerdCheck{instance: instance}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportPluginHealth([]containerd.PluginInfo{
+		{Type: "io.containerd.grpc.v1", ID: "cri", InitError: "invalid plugin config: no corresponding runtime configured in `containerd.runtimes` for `containerd` `default_runtime_name = \"runc\""},
+		{Type: "io.containerd.snapshotter.v1", ID: "overlayfs"},
+		{Type: "io.containerd.snapshotter.v1", ID: "devmapper", InitError: "devmapper not configured"},
+		{Type: "io.containerd.runtime.v2", ID: "task"},
+	}, mockSender)
+
+	mockSender.AssertServiceCheck(t, containerdPluginServiceCheck, metrics.ServiceCheckCritical, "", []string{"plugin:io.containerd.grpc.v1.cri", "env:prod"},
+		"invalid plugin config: no corresponding runtime configured in `containerd.runtimes` for `containerd` `default_runtime_name = \"runc\"")
+	mockSender.AssertServiceCheck(t, containerdPluginServiceCheck, metrics.ServiceCheckOK, "", []string{"plugin:io.containerd.snapshotter.v1.overlayfs", "env:prod"}, "")
+	mockSender.AssertServiceCheck(t, containerdPluginServiceCheck, metrics.ServiceCheckOK, "", []string{"plugin:io.containerd.runtime.v2.task", "env:prod"}, "")
+	// The plugins not critical or not loaded are not reported
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 3)
+
+	// The reports are disabled by an empty list
+	require.NoError(t, instance.Parse([]byte("critical_plugins: []")))
+	assert.Empty(t, instance.CriticalPlugins)
+}
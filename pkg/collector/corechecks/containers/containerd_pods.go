@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// podUsage is the usage of the tasks of a pod, summed over its containers
+type podUsage struct {
+	name      string
+	namespace string
+	// tasks counts the tasks of the workload containers, the sandbox
+	// container is not counted but its usage is
+	tasks      int
+	cpuTotal   uint64
+	cpuUser    uint64
+	cpuSystem  uint64
+	memUsage   uint64
+	workingSet uint64
+	rss        uint64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+// podRollup sums the task metrics of the containers of a namespace by pod.
+// The pods are read from the CRI labels of the containers, so that the pod
+// usage is known on the nodes whose kubelet cannot be queried.
+type podRollup struct {
+	// members are the pods of the containers, by container ID
+	members map[string]containerd.PodMember
+	// pods are keyed by pod UID, or by namespace and name if it is unknown
+	pods map[string]*podUsage
+}
+
+// newPodRollup returns a podRollup of the containers of a namespace, nil
+// if they cannot be listed. Only the containers of pods are streamed by the
+// daemon, the others are not held in memory.
+func newPodRollup(ctx context.Context, cu containerd.ContainerdItf) *podRollup {
+	r := &podRollup{
+		members: make(map[string]containerd.PodMember),
+		pods:    make(map[string]*podUsage),
+	}
+	err := cu.WalkContainers(ctx, func(ctn containerd.CachedContainer) error {
+		if m, ok := containerd.PodMemberOf(ctn.Labels); ok {
+			r.members[ctn.ID] = m
+		}
+		return nil
+	}, containerd.PodContainersFilter)
+	if err != nil {
+		log.Debugf("Cannot list the pods of the containers of namespace %s: %s", cu.Namespace(), err)
+		return nil
+	}
+	return r
+}
+
+// add sums the stats of the task of a container into the usage of its
+// pod, the containers out of pods are ignored
+func (r *podRollup) add(id string, stats *containerd.TaskStats) {
+	if r == nil {
+		return
+	}
+	m, found := r.members[id]
+	if !found {
+		return
+	}
+	key := m.PodUID
+	if key == "" {
+		key = m.PodNamespace + "/" + m.PodName
+	}
+	usage, found := r.pods[key]
+	if !found {
+		usage = &podUsage{name: m.PodName, namespace: m.PodNamespace}
+		r.pods[key] = usage
+	}
+	if !m.Sandbox {
+		usage.tasks++
+	}
+	usage.cpuTotal += stats.CPUTotal
+	usage.cpuUser += stats.CPUUser
+	usage.cpuSystem += stats.CPUSystem
+	usage.memUsage += stats.MemoryUsage
+	usage.workingSet += stats.MemoryWorkingSet
+	usage.rss += stats.MemoryRSS
+	for _, d := range stats.Devices {
+		usage.readBytes += d.ReadBytes
+		usage.writeBytes += d.WriteBytes
+	}
+}
+
+// reportPodUsage sends the usage of the pods of a namespace, tagged like
+// the pod metrics of the kubelet check so that the pod dashboards work
+// without it
+func (c *ContainerdCheck) reportPodUsage(namespace string, r *podRollup, sender aggregator.Sender) {
+	if r == nil {
+		return
+	}
+	for _, usage := range r.pods {
+		tags := append([]string{
+			"pod_name:" + usage.name,
+			"kube_namespace:" + usage.namespace,
+			"containerd_namespace:" + namespace,
+		}, c.instance.Tags...)
+		sender.Gauge("containerd.pod.tasks", float64(usage.tasks), "", tags)
+		if c.instance.metricFamilyEnabled(metricFamilyCPU) {
+			sender.Rate("containerd.pod.cpu.total", float64(usage.cpuTotal), "", tags)
+			sender.Rate("containerd.pod.cpu.user", float64(usage.cpuUser), "", tags)
+			sender.Rate("containerd.pod.cpu.system", float64(usage.cpuSystem), "", tags)
+		}
+		if c.instance.metricFamilyEnabled(metricFamilyMemory) {
+			sender.Gauge("containerd.pod.mem.current.usage", float64(usage.memUsage), "", tags)
+			sender.Gauge("containerd.pod.mem.working_set", float64(usage.workingSet), "", tags)
+			sender.Gauge("containerd.pod.mem.rss", float64(usage.rss), "", tags)
+		}
+		if c.instance.metricFamilyEnabled(metricFamilyIO) {
+			sender.Rate("containerd.pod.io.read_bytes", float64(usage.readBytes), "", tags)
+			sender.Rate("containerd.pod.io.write_bytes", float64(usage.writeBytes), "", tags)
+		}
+	}
+}
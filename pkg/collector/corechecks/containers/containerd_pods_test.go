@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"testing"
+
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdPodUsage(t *testing.T) {
+	pod := map[string]string{
+		"io.kubernetes.pod.name":      "redis-0",
+		"io.kubernetes.pod.namespace": "default",
+		"io.kubernetes.pod.uid":       "f3fb6a6a-9d51-11e8-8b6e-42010a840061",
+	}
+	sandbox := map[string]string{"io.cri-containerd.kind": "sandbox"}
+	for k, v := range pod {
+		sandbox[k] = v
+	}
+	daemon := containerdtest.NewDaemon()
+	for id, labels := range map[string]map[string]string{
+		"pause":    sandbox,
+		"redis":    pod,
+		"exporter": pod,
+		"buildkit": nil,
+	} {
+		require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: id, Labels: labels}}))
+	}
+
+	pods := newPodRollup(context.Background(), daemon.Util("k8s.io"))
+	require.NotNil(t, pods)
+	pods.add("pause", &containerd.TaskStats{CPUTotal: 10, MemoryUsage: 100})
+	pods.add("redis", &containerd.TaskStats{CPUTotal: 1000, CPUUser: 800, CPUSystem: 200, MemoryUsage: 4096, MemoryWorkingSet: 2048, MemoryRSS: 1024, Devices: []containerd.DeviceIO{
+		{Major: 8, ReadBytes: 512, WriteBytes: 256},
+	}})
+	pods.add("exporter", &containerd.TaskStats{CPUTotal: 100, MemoryUsage: 1024, MemoryWorkingSet: 512, MemoryRSS: 256})
+	// The containers out of pods are not summed
+	pods.add("buildkit", &containerd.TaskStats{CPUTotal: 5000})
+
+	check := &ContainerdCheck{instance: &ContainerdConfig{Tags: []string{"env:prod"}}}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportPodUsage("k8s.io", pods, mockSender)
+
+	tags := []string{"pod_name:redis-0", "kube_namespace:default", "containerd_namespace:k8s.io", "env:prod"}
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.tasks", 2, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.pod.cpu.total", 1110, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.pod.cpu.user", 800, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.pod.cpu.system", 200, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.mem.current.usage", 5220, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.mem.working_set", 2560, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.mem.rss", 1280, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.pod.io.read_bytes", 512, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.pod.io.write_bytes", 256, "", tags)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 4)
+	mockSender.AssertNumberOfCalls(t, "Rate", 5)
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+// The phases of the runs timed by `agent check containerd --profile`
+const (
+	// profileList is the listing of the namespaces and of their containers
+	profileList = "list"
+	// profileStats is the collection of the task metrics, and the decoding
+	// and the pressure of each task
+	profileStats = "stats"
+	// profileTags is the resolution of the tags of the tasks
+	profileTags = "tags"
+	// profileAggregation is the submission of the task and pod metrics to
+	// the aggregator
+	profileAggregation = "aggregation"
+	// profileTotal is the whole run
+	profileTotal = "total"
+)
+
+// EnableProfiling implements check.Profiler
+func (c *ContainerdCheck) EnableProfiling() {
+	c.profile = check.NewPhaseProfile()
+}
+
+// Profile implements check.Profiler
+func (c *ContainerdCheck) Profile() []check.PhaseTiming {
+	return c.profile.Timings()
+}
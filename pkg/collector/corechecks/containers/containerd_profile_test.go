@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdProfile(t *testing.T) {
+	c := &ContainerdCheck{
+		instance: &ContainerdConfig{},
+	}
+	var _ check.Profiler = c
+	mockSender := mocksender.NewMockSender(c.ID())
+	mockSender.SetupAcceptAll()
+	stats := &containerd.TaskStats{}
+
+	// The runs are not timed unless profiling is enabled
+	c.reportTaskMetrics("k8s.io", "redis", stats, nil, mockSender)
+	assert.Empty(t, c.Profile())
+
+	c.EnableProfiling()
+	c.reportTaskMetrics("k8s.io", "redis", stats, nil, mockSender)
+	c.reportTaskMetrics("k8s.io", "nginx", stats, nil, mockSender)
+	timings := c.Profile()
+	require.Len(t, timings, 2)
+	assert.Equal(t, profileTags, timings[0].Phase)
+	assert.Equal(t, 2, timings[0].Calls)
+	assert.Equal(t, profileAggregation, timings[1].Phase)
+	assert.Equal(t, 2, timings[1].Calls)
+}
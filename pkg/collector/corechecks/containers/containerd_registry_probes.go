@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+const (
+	// containerdRegistryReachableServiceCheck reports the result of the
+	// registry probes, per image reference
+	containerdRegistryReachableServiceCheck = "containerd.registry.reachable"
+	// registryProbeTimeout bounds the resolution of a reference
+	registryProbeTimeout = 10 * time.Second
+)
+
+// registryProbeResult is the last result of the probe of a reference
+type registryProbeResult struct {
+	probedAt time.Time
+	status   metrics.ServiceCheckStatus
+	message  string
+}
+
+// registryProber resolves an image reference on its registry
+type registryProber func(ctx context.Context, ref string) error
+
+// probeRegistries resolves the registry_probes references with the
+// credentials of the CRI plugin, see containerd.ProbeRegistry
+func (c *ContainerdCheck) probeRegistries(sender aggregator.Sender) {
+	configPath := containerdconfig.Options().ConfigPath
+	if configPath == "" {
+		configPath = containerd.DefaultConfigPath
+	}
+	client := &http.Client{Timeout: registryProbeTimeout}
+	probe := func(ctx context.Context, ref string) error {
+		return containerd.ProbeRegistry(ctx, configPath, ref, client)
+	}
+	c.reportRegistryProbes(probe, time.Now(), sender)
+}
+
+// reportRegistryProbes sends a service check per reference: OK if it is
+// resolved, WARNING if the registry is reachable but the reference does
+// not exist, CRITICAL if the registry is unreachable or rejects the
+// credentials. The references are probed once per registry_probe_interval,
+// the last result is sent in between.
+func (c *ContainerdCheck) reportRegistryProbes(probe registryProber, now time.Time, sender aggregator.Sender) {
+	if c.registryProbes == nil {
+		c.registryProbes = make(map[string]registryProbeResult)
+	}
+	interval := time.Duration(c.instance.RegistryProbeInterval) * time.Second
+	for _, ref := range c.instance.RegistryProbes {
+		result, found := c.registryProbes[ref]
+		if !found || now.Sub(result.probedAt) >= interval {
+			result = runRegistryProbe(probe, ref, now)
+			c.registryProbes[ref] = result
+		}
+		tags := append([]string{"registry:" + containerd.RegistryOf(ref), "image_reference:" + ref}, c.instance.Tags...)
+		sender.ServiceCheck(containerdRegistryReachableServiceCheck, result.status, "", tags, result.message)
+	}
+}
+
+func runRegistryProbe(probe registryProber, ref string, now time.Time) registryProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), registryProbeTimeout)
+	defer cancel()
+	result := registryProbeResult{probedAt: now, status: metrics.ServiceCheckOK}
+	switch err := probe(ctx, ref); {
+	case err == nil:
+	case errdefs.IsNotFound(err):
+		result.status = metrics.ServiceCheckWarning
+		result.message = "The registry is reachable but the reference is not found: " + err.Error()
+	default:
+		result.status = metrics.ServiceCheckCritical
+		result.message = err.Error()
+	}
+	return result
+}
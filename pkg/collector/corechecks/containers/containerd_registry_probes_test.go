@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestContainerdRegistryProbes(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{
+			Tags:                  []string{"env:prod"},
+			RegistryProbes:        []string{"registry.example.com/team/app:1.0", "registry.example.com/team/app:gone", "redis"},
+			RegistryProbeInterval: 300,
+		},
+	}
+	probes := 0
+	probe := func(ctx context.Context, ref string) error {
+		probes++
+		switch ref {
+		case "registry.example.com/team/app:gone":
+			return fmt.Errorf("%s: %w", ref, errdefs.ErrNotFound)
+		case "redis":
+			return errors.New("401 Unauthorized")
+		}
+		return nil
+	}
+	now := time.Now()
+
+	for _, at := range []time.Time{now, now.Add(time.Minute)} {
+		mockSender := mocksender.NewMockSender(check.ID())
+		mockSender.SetupAcceptAll()
+		check.reportRegistryProbes(probe, at, mockSender)
+		mockSender.AssertServiceCheck(t, containerdRegistryReachableServiceCheck, metrics.ServiceCheckOK, "",
+			[]string{"registry:registry.example.com", "image_reference:registry.example.com/team/app:1.0", "env:prod"}, "")
+		mockSender.AssertServiceCheck(t, containerdRegistryReachableServiceCheck, metrics.ServiceCheckWarning, "",
+			[]string{"registry:registry.example.com", "image_reference:registry.example.com/team/app:gone", "env:prod"},
+			"The registry is reachable but the reference is not found: registry.example.com/team/app:gone: not found")
+		mockSender.AssertServiceCheck(t, containerdRegistryReachableServiceCheck, metrics.ServiceCheckCritical, "",
+			[]string{"registry:docker.io", "image_reference:redis", "env:prod"}, "401 Unauthorized")
+	}
+	// The last results are sent until the next probes
+	assert.Equal(t, 3, probes)
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportRegistryProbes(probe, now.Add(5*time.Minute), mockSender)
+	assert.Equal(t, 6, probes)
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"hash/fnv"
+)
+
+// The families of task metrics that can be disabled with
+// disabled_metric_families
+const (
+	metricFamilyCPU      = "cpu"
+	metricFamilyMemory   = "memory"
+	metricFamilyIO       = "io"
+	metricFamilyPids     = "pids"
+	metricFamilyPressure = "pressure"
+)
+
+var metricFamilies = []string{metricFamilyCPU, metricFamilyMemory, metricFamilyIO, metricFamilyPids, metricFamilyPressure}
+
+// unknownMetricFamilies returns the disabled families that do not exist
+func (c *ContainerdConfig) unknownMetricFamilies() []string {
+	var unknown []string
+	for _, family := range c.DisabledMetricFamilies {
+		if !containsString(metricFamilies, family) {
+			unknown = append(unknown, family)
+		}
+	}
+	return unknown
+}
+
+// metricFamilyEnabled returns whether the task metrics of a family are
+// reported
+func (c *ContainerdConfig) metricFamilyEnabled(family string) bool {
+	return !containsString(c.DisabledMetricFamilies, family)
+}
+
+// taskSampled returns whether the task metrics of a container are reported
+// with task_sample_percent. The containers are picked from a hash of their
+// ID, so that the same ones are reported at every run and their rates are
+// computed. Every container is reported unless the percentage is between 1
+// and 99, collect_task_metrics disables the task metrics.
+func (c *ContainerdConfig) taskSampled(id string) bool {
+	if c.TaskSamplePercent <= 0 || c.TaskSamplePercent >= 100 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32()%100) < c.TaskSamplePercent
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdMetricFamilies(t *testing.T) {
+	var conf ContainerdConfig
+	require.NoError(t, conf.Parse([]byte("disabled_metric_families: [io, pressure, net]")))
+	assert.Equal(t, []string{"net"}, conf.unknownMetricFamilies())
+	check := &ContainerdCheck{instance: &conf}
+
+	stats := &containerd.TaskStats{
+		CPUTotal:    3000,
+		MemoryUsage: 2048,
+		Pids:        3,
+		Devices:     []containerd.DeviceIO{{Major: 8, Minor: 0, Name: "sda", ReadBytes: 4096}},
+	}
+	pressure := &containerd.TaskPressure{
+		CPU: &containerd.Pressure{Some: containerd.PressureData{Avg10: 1.5}},
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskMetrics("k8s.io", "redis", stats, pressure, mockSender)
+
+	tags := []string{"containerd_namespace:k8s.io"}
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.total", 3000, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.current.usage", 2048, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.pids.current", 3, "", tags)
+	mockSender.AssertNotCalled(t, "Rate", "containerd.io.read_bytes", 4096.0, "", []string{"device:sda", "containerd_namespace:k8s.io"})
+	mockSender.AssertNotCalled(t, "Gauge", "containerd.cpu.pressure", 1.5, "", []string{"stall:some", "containerd_namespace:k8s.io"})
+}
+
+func TestContainerdTaskSampling(t *testing.T) {
+	var conf ContainerdConfig
+	require.NoError(t, conf.Parse(nil))
+	assert.Equal(t, 100, conf.TaskSamplePercent)
+	assert.True(t, conf.taskSampled("redis"))
+
+	conf.TaskSamplePercent = 25
+	var sampled int
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("container-%d", i)
+		if conf.taskSampled(id) {
+			sampled++
+		}
+		// The same containers are sampled at every run
+		assert.Equal(t, conf.taskSampled(id), conf.taskSampled(id))
+	}
+	assert.InDelta(t, 250, sampled, 50)
+}
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// collectPodSandboxes reports the pod sandboxes served by the sandbox API,
+// the releases of containerd older than 1.7 are silently skipped
+func (c *ContainerdCheck) collectPodSandboxes(sender aggregator.Sender) {
+	cu, err := c.provider().Get(nil)
+	if err != nil {
+		return
+	}
+	pods, err := containerd.ListPodSandboxes(cu)
+	if err != nil {
+		if containerd.ErrorKind(err) == containerd.ErrUnsupported {
+			log.Debugf("Pod sandboxes are not reported: %s", err)
+		} else {
+			log.Warnf("Cannot list the containerd pod sandboxes: %s", err)
+		}
+		return
+	}
+	c.reportPodSandboxes(pods, sender)
+}
+
+// reportPodSandboxes sends the number of containers of every pod sandbox,
+// and the number of sandboxes per state
+func (c *ContainerdCheck) reportPodSandboxes(pods []containerd.PodSandbox, sender aggregator.Sender) {
+	states := make(map[string]int)
+	for _, pod := range pods {
+		states[pod.State]++
+		if pod.PodName == "" {
+			continue
+		}
+		tags := append([]string{
+			"pod_name:" + pod.PodName,
+			"kube_namespace:" + pod.PodNamespace,
+			"sandbox_state:" + pod.State,
+		}, c.instance.Tags...)
+		sender.Gauge("containerd.pod.containers", float64(len(pod.Containers)), "", tags)
+	}
+	for state, count := range states {
+		tags := append([]string{"sandbox_state:" + state}, c.instance.Tags...)
+		sender.Gauge("containerd.pod.sandboxes", float64(count), "", tags)
+	}
+}
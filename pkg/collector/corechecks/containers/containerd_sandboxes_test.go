@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/sandbox"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdPodSandboxes(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	pods := []containerd.PodSandbox{
+		{ID: "sb-redis", PodName: "redis-0", PodNamespace: "default", State: "sandbox_ready", Containers: []string{"redis", "exporter"}},
+		{ID: "sb-nginx", PodName: "nginx-1", PodNamespace: "web", State: "sandbox_ready", Containers: []string{"nginx"}},
+		{ID: "sb-orphan", State: containerd.SandboxStateUnknown},
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportPodSandboxes(pods, mockSender)
+
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.containers", 2, "", []string{"pod_name:redis-0", "kube_namespace:default", "sandbox_state:sandbox_ready", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.containers", 1, "", []string{"pod_name:nginx-1", "kube_namespace:web", "sandbox_state:sandbox_ready", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.sandboxes", 2, "", []string{"sandbox_state:sandbox_ready", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.sandboxes", 1, "", []string{"sandbox_state:unknown", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 4)
+}
+
+func TestContainerdCollectPodSandboxes(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	daemon.AddSandbox("k8s.io", sandbox.Sandbox{ID: "sb-redis"}, sandbox.ControllerStatus{SandboxID: "sb-redis", State: "SANDBOX_READY"})
+	provider := containerd.NewProvider()
+	provider.SetForTests(daemon.Util("k8s.io"))
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{},
+		utils:    provider,
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.collectPodSandboxes(mockSender)
+
+	mockSender.AssertMetric(t, "Gauge", "containerd.pod.sandboxes", 1, "", []string{"sandbox_state:sandbox_ready"})
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// reportContainerStates sends the restart count of the containers whose
+// task starts were seen, their checkpoints and restores, the uptime of
+// their running task and their status. The containers are tagged with
+// their stored tags until the tagger resolves them after a restart.
+func (c *ContainerdCheck) reportContainerStates(states map[string]containerd.ContainerState, now time.Time, extraTags []string, sender aggregator.Sender) {
+	for id, state := range states {
+		entity := containerd.EntityID(id)
+		tags, err := tagger.Tag(entity, true)
+		if err != nil {
+			log.Debugf("no tags for %s: %s", id, err)
+		}
+		if len(tags) == 0 {
+			tags = c.storedTags(id)
+		}
+		tags = append(append(tags, c.instance.Tags...), extraTags...)
+
+		sender.Gauge("containerd.containers.restarts", float64(state.RestartCount), "", tags)
+		sender.Gauge("containerd.containers.checkpoints", float64(state.CheckpointCount), "", tags)
+		sender.Gauge("containerd.containers.restores", float64(state.RestoreCount), "", tags)
+		if state.Running() {
+			sender.Gauge("containerd.containers.uptime", now.Sub(state.StartedAt).Seconds(), "", tags)
+		}
+		if state.Status != "" {
+			sender.Gauge("containerd.containers.status", 1, "", append(tags, "container_status:"+string(state.Status)))
+		}
+	}
+}
+
+// reportStaleStates sends the container states kept by the state tracker
+// for the first max_stale_intervals runs containerd is unavailable, tagged
+// containerd.stale:true. The events are not received meanwhile, the states
+// are the last known ones.
+func (c *ContainerdCheck) reportStaleStates(now time.Time, sender aggregator.Sender) {
+	c.staleRuns++
+	if c.stateTracker == nil || c.staleRuns > c.instance.MaxStaleIntervals {
+		return
+	}
+	c.reportContainerStates(c.stateTracker.States(), now, []string{containerdStaleTag}, sender)
+}
+
+// reportUptimes sends the uptime of the running containers whose task start
+// was not seen, eg. started before the agent, from the start time of the
+// init process of their task. The uptime of the others is sent with their
+// state.
+func (c *ContainerdCheck) reportUptimes(cu containerd.ContainerdItf, now time.Time, sender aggregator.Sender) {
+	ctns, err := cu.Containers()
+	if err != nil {
+		log.Debugf("Cannot list the containers of namespace %s: %s", cu.Namespace(), err)
+		return
+	}
+	for _, ctn := range ctns {
+		if ctn.StartedAt().IsZero() {
+			continue
+		}
+		if c.stateTracker != nil {
+			if state, found := c.stateTracker.State(ctn.ID()); found && state.Running() {
+				continue
+			}
+		}
+		tags, err := tagger.Tag(containerd.EntityID(ctn.ID()), true)
+		if err != nil {
+			log.Debugf("no tags for %s: %s", ctn.ID(), err)
+		}
+		tags = append(tags, c.instance.Tags...)
+		sender.Gauge("containerd.containers.uptime", now.Sub(ctn.StartedAt()).Seconds(), "", tags)
+	}
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdContainerStates(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	now := time.Now()
+	states := map[string]containerd.ContainerState{
+		"redis": {Status: containerd.ContainerStatusRunning, RestartCount: 3, StartedAt: now.Add(-30 * time.Second)},
+		"nginx": {Status: containerd.ContainerStatusCheckpointed, RestartCount: 5, CheckpointCount: 2, RestoreCount: 1},
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportContainerStates(states, now, nil, mockSender)
+
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.restarts", 3, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.uptime", 30, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.status", 1, "", []string{"env:prod", "container_status:running"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.restarts", 5, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.checkpoints", 2, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.restores", 1, "", []string{"env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.status", 1, "", []string{"env:prod", "container_status:checkpointed"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 9)
+}
+
+func TestContainerdStaleStates(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	envelopes, _ := daemon.Events().Subscribe(ctx, containerd.ContainerStateFilters...)
+	running := &containerdtest.Task{Pid: 42, Status: containerdclient.Status{Status: containerdclient.Running}}
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "redis"}, TaskState: running}))
+
+	check := &ContainerdCheck{
+		instance:     &ContainerdConfig{Tags: []string{"env:prod"}, MaxStaleIntervals: 2},
+		stateTracker: containerd.NewContainerStateTracker(),
+	}
+	require.NoError(t, check.stateTracker.HandleEnvelope(<-envelopes))
+
+	// The last known states are reported for the first two runs
+	for run := 0; run < 2; run++ {
+		mockSender := mocksender.NewMockSender(check.ID())
+		mockSender.SetupAcceptAll()
+		check.reportStaleStates(time.Now(), mockSender)
+		mockSender.AssertMetric(t, "Gauge", "containerd.containers.status", 1, "", []string{"env:prod", "containerd.stale:true", "container_status:running"})
+		mockSender.AssertMetric(t, "Gauge", "containerd.containers.restarts", 0, "", []string{"env:prod", "containerd.stale:true"})
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportStaleStates(time.Now(), mockSender)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 0)
+}
+
+func TestContainerdUptimes(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	envelopes, _ := daemon.Events().Subscribe(ctx, containerd.ContainerStateFilters...)
+	now := time.Now()
+	// redis started before the agent, nginx after
+	redis := &containerdtest.Task{Pid: 42, StartedAt: now.Add(-time.Hour)}
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "redis"}, TaskState: redis}))
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "stopped"}}))
+	stateTracker := containerd.NewContainerStateTracker()
+	<-envelopes
+	nginx := &containerdtest.Task{Pid: 43, StartedAt: now.Add(-time.Minute)}
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "nginx"}, TaskState: nginx}))
+	require.NoError(t, stateTracker.HandleEnvelope(<-envelopes))
+
+	check := &ContainerdCheck{
+		instance:     &ContainerdConfig{Tags: []string{"env:prod"}},
+		stateTracker: stateTracker,
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportUptimes(daemon.Util("k8s.io"), now, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.uptime", 3600, "", []string{"env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 1)
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// loadContainerStore restores the container states and the churn persisted
+// by the previous agent, before the events missed meanwhile are replayed
+func (c *ContainerdCheck) loadContainerStore(path string) {
+	c.storePath = path
+	store, err := containerd.LoadContainerStore(path)
+	if err != nil {
+		log.Warnf("Cannot load the containerd metadata store, the restart counts and the churn start from zero: %s", err)
+		return
+	}
+	c.storedContainers = make(map[string]containerd.StoredContainer, len(store.Containers))
+	for _, ctn := range store.Containers {
+		c.storedContainers[ctn.ID] = ctn
+	}
+	if c.stateTracker != nil {
+		c.stateTracker.Restore(store.Containers)
+	}
+	if c.churnTracker != nil {
+		c.churnTracker.Restore(store.Churn)
+	}
+}
+
+// saveContainerStore persists the container states and the churn, with the
+// tags and the creation time of the containers. The tags and the creation
+// time of the containers which cannot be resolved anymore are the stored
+// ones.
+func (c *ContainerdCheck) saveContainerStore(now time.Time) {
+	if c.storePath == "" {
+		return
+	}
+	store := containerd.ContainerStore{SavedAt: now}
+	if c.stateTracker != nil {
+		store.Containers = c.stateTracker.Snapshot()
+	}
+	if c.churnTracker != nil {
+		store.Churn = c.churnTracker.Snapshot(now)
+	}
+
+	createdAt := make(map[string]map[string]time.Time)
+	stored := make(map[string]containerd.StoredContainer, len(store.Containers))
+	for i := range store.Containers {
+		ctn := &store.Containers[i]
+		prev := c.storedContainers[ctn.ID]
+		if tags, _ := tagger.Tag(containerd.EntityID(ctn.ID), true); len(tags) > 0 {
+			ctn.Tags = tags
+		} else {
+			ctn.Tags = prev.Tags
+		}
+		if ctn.Namespace == "" {
+			ctn.Namespace = prev.Namespace
+		}
+		if _, found := createdAt[ctn.Namespace]; !found && ctn.Namespace != "" {
+			createdAt[ctn.Namespace] = c.creationTimes(ctn.Namespace)
+		}
+		if ts, found := createdAt[ctn.Namespace][ctn.ID]; found {
+			ctn.CreatedAt = ts
+		} else {
+			ctn.CreatedAt = prev.CreatedAt
+		}
+		stored[ctn.ID] = *ctn
+	}
+	c.storedContainers = stored
+
+	if err := store.Save(c.storePath); err != nil {
+		log.Debugf("Cannot save the containerd metadata store: %s", err)
+	}
+}
+
+// creationTimes returns the creation time of the containers of a namespace,
+// by ID, from the container cache of its util
+func (c *ContainerdCheck) creationTimes(namespace string) map[string]time.Time {
+	opts := containerdconfig.Options()
+	opts.Namespace = namespace
+	cu, err := c.provider().Get(&opts)
+	if err != nil {
+		return nil
+	}
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Debugf("Cannot list the containers of namespace %s: %s", namespace, err)
+		return nil
+	}
+	times := make(map[string]time.Time, len(ctns))
+	for _, ctn := range ctns {
+		times[ctn.ID] = ctn.CreatedAt
+	}
+	return times
+}
+
+// storedTags returns the stored tags of a container, to tag its state until
+// the tagger resolves them after a restart of the agent
+func (c *ContainerdCheck) storedTags(id string) []string {
+	tags := c.storedContainers[id].Tags
+	return tags[:len(tags):len(tags)]
+}
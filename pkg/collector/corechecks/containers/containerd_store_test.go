@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestContainerdContainerStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "containerd_metadata.json")
+
+	now := time.Now()
+	check := &ContainerdCheck{
+		instance:     &ContainerdConfig{},
+		stateTracker: containerd.NewContainerStateTracker(),
+		churnTracker: containerd.NewContainerChurnTracker(),
+	}
+	check.loadContainerStore(path)
+	check.stateTracker.Restore([]containerd.StoredContainer{{ID: "redis", Status: containerd.ContainerStatusStopped, RestartCount: 4}})
+	check.churnTracker.HandleEnvelope(&events.Envelope{Namespace: "k8s.io", Topic: containerd.ContainerCreateTopic, Timestamp: now})
+	check.storedContainers = map[string]containerd.StoredContainer{
+		"redis": {ID: "redis", Tags: []string{"kube_deployment:redis"}, CreatedAt: now.Add(-time.Hour)},
+	}
+	check.saveContainerStore(now)
+
+	// The next agent goes on from the stored states
+	restarted := &ContainerdCheck{
+		instance:     &ContainerdConfig{Tags: []string{"env:prod"}},
+		stateTracker: containerd.NewContainerStateTracker(),
+		churnTracker: containerd.NewContainerChurnTracker(),
+	}
+	restarted.loadContainerStore(path)
+	assert.Equal(t, map[string]containerd.ContainerChurn{"k8s.io": {Created: 1}}, restarted.churnTracker.Churn(now))
+	state, found := restarted.stateTracker.State("redis")
+	require.True(t, found)
+	assert.Equal(t, 4, state.RestartCount)
+	assert.True(t, restarted.storedContainers["redis"].CreatedAt.Equal(now.Add(-time.Hour)))
+
+	// The states are tagged with the stored tags until the tagger resolves them
+	mockSender := mocksender.NewMockSender(restarted.ID())
+	mockSender.SetupAcceptAll()
+	restarted.reportContainerStates(restarted.stateTracker.States(), now, nil, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.containers.restarts", 4, "", []string{"kube_deployment:redis", "env:prod"})
+	assert.Equal(t, []string{"kube_deployment:redis"}, restarted.storedContainers["redis"].Tags)
+}
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// collectTaskMetrics reports the number of tasks of a namespace, and the
+// metrics of the tasks sampled with task_sample_percent, see
+// reportTaskMetrics, with their limits, the host usage of their VM, and their
+// sum by pod if collect_pod_metrics is set.
+func (c *ContainerdCheck) collectTaskMetrics(cu containerd.ContainerdItf, sender aggregator.Sender) {
+	namespace := cu.Namespace()
+	start := time.Now()
+	runStart := start
+	results, err := cu.CollectAll(context.Background())
+	c.profile.Since(profileStats, start)
+	if err != nil {
+		if containerd.ErrorKind(err) == containerd.ErrUnsupported {
+			log.Debugf("Task metrics are not reported: %s", err)
+		} else {
+			log.Warnf("Cannot collect the containerd task metrics of namespace %s: %s", namespace, err)
+		}
+		return
+	}
+	sender.Gauge("containerd.namespace.tasks", float64(len(results)), "", c.namespaceTags(namespace))
+
+	var diskDevices map[string]string
+	collectPressure := true
+	vmRuntimes := c.vmRuntimeHandlers(cu)
+	limits := c.containerLimits(cu)
+	// vmReported holds the hypervisor pids reported, the VM of a pod is
+	// reported once
+	vmReported := make(map[uint32]struct{})
+	var pods *podRollup
+	if c.instance.CollectPodMetrics {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.instance.ListingBudget)*time.Second)
+		pods = newPodRollup(ctx, cu)
+		cancel()
+	}
+	for _, r := range results {
+		if !c.instance.taskSampled(r.ContainerID) {
+			continue
+		}
+		start := time.Now()
+		if r.Err != nil {
+			log.Debugf("Cannot collect the metrics of the task of %s: %s", r.ContainerID, r.Err)
+			continue
+		}
+		if handler, found := vmRuntimes[r.ContainerID]; found {
+			if _, reported := vmReported[r.Pid]; !reported {
+				vmStats, err := cu.TaskVMStats(r.ContainerID, r.Pid)
+				if err != nil {
+					log.Debugf("Cannot read the VM stats of the task of %s: %s", r.ContainerID, err)
+				} else {
+					vmReported[r.Pid] = struct{}{}
+					c.reportTaskVMStats(namespace, r.ContainerID, handler, vmStats, sender)
+				}
+			}
+		}
+		stats, err := containerd.DecodeTaskMetrics(r.Metrics)
+		if err != nil {
+			log.Debugf("Cannot decode the metrics of the task of %s: %s", r.ContainerID, err)
+			continue
+		}
+		for i, d := range stats.Devices {
+			if d.Name != "" {
+				continue
+			}
+			if diskDevices == nil {
+				if diskDevices, err = containerd.DiskDevices(config.Datadog.GetString("container_proc_root")); err != nil {
+					log.Debugf("Cannot name the block devices of the tasks: %s", err)
+					diskDevices = map[string]string{}
+				}
+			}
+			stats.Devices[i].Name = diskDevices[d.ID()]
+		}
+
+		var pressure *containerd.TaskPressure
+		if collectPressure {
+			pressure, err = cu.TaskPressure(r.Pid)
+			if containerd.ErrorKind(err) == containerd.ErrUnsupported {
+				log.Debugf("Task pressure is not reported: %s", err)
+				collectPressure = false
+			} else if err != nil {
+				log.Debugf("Cannot read the pressure of the task of %s: %s", r.ContainerID, err)
+			}
+		}
+		c.profile.Since(profileStats, start)
+		c.reportTaskMetrics(namespace, r.ContainerID, stats, pressure, sender)
+		c.reportTaskLimits(namespace, r.ContainerID, stats, limits[r.ContainerID], time.Now(), sender)
+		pods.add(r.ContainerID, stats)
+	}
+	c.forgetCPUSamples(namespace, runStart)
+	start = time.Now()
+	c.reportPodUsage(namespace, pods, sender)
+	c.profile.Since(profileAggregation, start)
+}
+
+// vmRuntimeHandlers returns the runtime handler of the containers of a
+// namespace run in virtual machines, by ID
+func (c *ContainerdCheck) vmRuntimeHandlers(cu containerd.ContainerdItf) map[string]string {
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		log.Debugf("Cannot list the runtimes of the containers of namespace %s: %s", cu.Namespace(), err)
+		return nil
+	}
+	handlers := make(map[string]string)
+	for _, ctn := range ctns {
+		if containerd.IsVMRuntime(ctn.RuntimeHandler) {
+			handlers[ctn.ID] = ctn.RuntimeHandler
+		}
+	}
+	return handlers
+}
+
+// reportTaskVMStats sends the host memory used by the VM of a task and by
+// its shim, tagged by runtime handler
+func (c *ContainerdCheck) reportTaskVMStats(namespace, id, handler string, stats *containerd.TaskVMStats, sender aggregator.Sender) {
+	tags, err := tagger.Tag(containerd.EntityID(id), true)
+	if err != nil {
+		log.Debugf("no tags for %s: %s", id, err)
+	}
+	tags = append(append(tags, "containerd_namespace:"+namespace, "runtime_handler:"+handler), c.instance.Tags...)
+
+	sender.Gauge("containerd.vm.mem.rss", float64(stats.VMMemoryRSS), "", tags)
+	if stats.ShimRSS > 0 {
+		sender.Gauge("containerd.vm.shim.mem.rss", float64(stats.ShimRSS), "", tags)
+	}
+}
+
+// reportTaskMetrics sends the cgroup v1 or v2 metrics of the task of a
+// container. The memory usage is split into the working set, the RSS, the
+// page cache and the kernel memory, to size the requests and limits. The
+// pressure is the avg10 share of stalled time, tagged by stall type. The I/O
+// is tagged by device, named after the diskstats of the host, or by its
+// major:minor number if it is not named.
+func (c *ContainerdCheck) reportTaskMetrics(namespace, id string, stats *containerd.TaskStats, pressure *containerd.TaskPressure, sender aggregator.Sender) {
+	start := time.Now()
+	entity := containerd.EntityID(id)
+	tags, err := tagger.Tag(entity, true)
+	if err != nil {
+		log.Debugf("no tags for %s: %s", id, err)
+	}
+	c.profile.Since(profileTags, start)
+	defer c.profile.Since(profileAggregation, time.Now())
+	tags = append(append(tags, "containerd_namespace:"+namespace), c.instance.Tags...)
+
+	if c.instance.metricFamilyEnabled(metricFamilyCPU) {
+		sender.Rate("containerd.cpu.total", float64(stats.CPUTotal), "", tags)
+		sender.Rate("containerd.cpu.user", float64(stats.CPUUser), "", tags)
+		sender.Rate("containerd.cpu.system", float64(stats.CPUSystem), "", tags)
+		sender.Rate("containerd.cpu.throttled.periods", float64(stats.CPUThrottledPeriods), "", tags)
+		sender.Rate("containerd.cpu.throttled.time", float64(stats.CPUThrottledTime), "", tags)
+	}
+	if c.instance.metricFamilyEnabled(metricFamilyMemory) {
+		sender.Gauge("containerd.mem.current.usage", float64(stats.MemoryUsage), "", tags)
+		if stats.MemoryLimit > 0 {
+			sender.Gauge("containerd.mem.current.limit", float64(stats.MemoryLimit), "", tags)
+		}
+		sender.Gauge("containerd.mem.working_set", float64(stats.MemoryWorkingSet), "", tags)
+		sender.Gauge("containerd.mem.rss", float64(stats.MemoryRSS), "", tags)
+		sender.Gauge("containerd.mem.cache", float64(stats.MemoryCache), "", tags)
+		sender.Gauge("containerd.mem.kernel", float64(stats.KernelMemory), "", tags)
+		sender.Gauge("containerd.mem.swap.usage", float64(stats.SwapUsage), "", tags)
+	}
+	if c.instance.metricFamilyEnabled(metricFamilyPids) {
+		sender.Gauge("containerd.pids.current", float64(stats.Pids), "", tags)
+	}
+	if c.instance.metricFamilyEnabled(metricFamilyIO) {
+		for _, d := range stats.Devices {
+			device := d.Name
+			if device == "" {
+				device = d.ID()
+			}
+			deviceTags := append([]string{"device:" + device}, tags...)
+			sender.Rate("containerd.io.read_bytes", float64(d.ReadBytes), "", deviceTags)
+			sender.Rate("containerd.io.write_bytes", float64(d.WriteBytes), "", deviceTags)
+			sender.Rate("containerd.io.read_ops", float64(d.ReadOps), "", deviceTags)
+			sender.Rate("containerd.io.write_ops", float64(d.WriteOps), "", deviceTags)
+		}
+	}
+
+	if pressure == nil || !c.instance.metricFamilyEnabled(metricFamilyPressure) {
+		return
+	}
+	reportPressure := func(name string, p *containerd.Pressure) {
+		if p == nil {
+			return
+		}
+		sender.Gauge(name, p.Some.Avg10, "", append([]string{"stall:some"}, tags...))
+		sender.Gauge(name, p.Full.Avg10, "", append([]string{"stall:full"}, tags...))
+	}
+	reportPressure("containerd.cpu.pressure", pressure.CPU)
+	reportPressure("containerd.memory.pressure", pressure.Memory)
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"testing"
+
+	containerdclient "github.com/containerd/containerd"
+	"github.com/containerd/containerd/api/types"
+	ctrcontainers "github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdTaskMetrics(t *testing.T) {
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	stats := &containerd.TaskStats{
+		CgroupVersion:       containerd.CgroupV2Stats,
+		CPUTotal:            3000,
+		CPUUser:             2000,
+		CPUSystem:           1000,
+		CPUThrottledPeriods: 2,
+		CPUThrottledTime:    500,
+		MemoryUsage:         2048,
+		MemoryLimit:         4096,
+		MemoryWorkingSet:    1536,
+		MemoryRSS:           1024,
+		MemoryCache:         768,
+		KernelMemory:        112,
+		SwapUsage:           128,
+		Pids:                3,
+		Devices: []containerd.DeviceIO{
+			{Major: 8, Minor: 0, Name: "sda", ReadBytes: 4096, WriteBytes: 8192, ReadOps: 1, WriteOps: 2},
+			{Major: 259, Minor: 0, WriteBytes: 512, WriteOps: 1},
+		},
+	}
+	pressure := &containerd.TaskPressure{
+		CPU: &containerd.Pressure{Some: containerd.PressureData{Avg10: 1.5}},
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskMetrics("k8s.io", "redis", stats, pressure, mockSender)
+
+	tags := []string{"containerd_namespace:k8s.io", "env:prod"}
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.total", 3000, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.user", 2000, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.system", 1000, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.throttled.periods", 2, "", tags)
+	mockSender.AssertMetric(t, "Rate", "containerd.cpu.throttled.time", 500, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.current.usage", 2048, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.current.limit", 4096, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.working_set", 1536, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.rss", 1024, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.cache", 768, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.kernel", 112, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.swap.usage", 128, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.pids.current", 3, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.cpu.pressure", 1.5, "", []string{"stall:some", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Gauge", "containerd.cpu.pressure", 0, "", []string{"stall:full", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 10)
+	mockSender.AssertMetric(t, "Rate", "containerd.io.read_bytes", 4096, "", []string{"device:sda", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Rate", "containerd.io.write_bytes", 8192, "", []string{"device:sda", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Rate", "containerd.io.read_ops", 1, "", []string{"device:sda", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Rate", "containerd.io.write_ops", 2, "", []string{"device:sda", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertMetric(t, "Rate", "containerd.io.write_bytes", 512, "", []string{"device:259:0", "containerd_namespace:k8s.io", "env:prod"})
+	mockSender.AssertNumberOfCalls(t, "Rate", 13)
+
+	// No limit nor pressure on an unlimited cgroup v1 container
+	mockSender = mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.reportTaskMetrics("k8s.io", "nginx", &containerd.TaskStats{CgroupVersion: containerd.CgroupV1Stats, MemoryUsage: 1024}, nil, mockSender)
+	mockSender.AssertMetric(t, "Gauge", "containerd.mem.current.usage", 1024, "", tags)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 7)
+}
+
+func TestContainerdTaskVMStats(t *testing.T) {
+	vmStats := &containerd.TaskVMStats{VMMemoryRSS: 2048, ShimRSS: 512}
+	task := func(pid uint32, vm *containerd.TaskVMStats) *containerdtest.Task {
+		return &containerdtest.Task{
+			Pid:     pid,
+			Status:  containerdclient.Status{Status: containerdclient.Running},
+			Metrics: &types.Metric{},
+			VMStats: vm,
+		}
+	}
+	kata := ctrcontainers.RuntimeInfo{Name: "io.containerd.kata-qemu.v2"}
+	daemon := containerdtest.NewDaemon()
+	// The containers of a kata pod share its VM, reported once
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "a-sandbox", Runtime: kata}, TaskState: task(42, vmStats)}))
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "b-redis", Runtime: kata}, TaskState: task(42, vmStats)}))
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: ctrcontainers.Container{ID: "nginx", Runtime: ctrcontainers.RuntimeInfo{Name: "io.containerd.runc.v2"}}, TaskState: task(43, nil)}))
+
+	check := &ContainerdCheck{
+		instance: &ContainerdConfig{Tags: []string{"env:prod"}},
+	}
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	check.collectTaskMetrics(daemon.Util("k8s.io"), mockSender)
+
+	tags := []string{"containerd_namespace:k8s.io", "runtime_handler:kata-qemu", "env:prod"}
+	mockSender.AssertMetric(t, "Gauge", "containerd.vm.mem.rss", 2048, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "containerd.vm.shim.mem.rss", 512, "", tags)
+	// The guest metrics of the tasks are empty, they are not reported
+	mockSender.AssertNumberOfCalls(t, "Gauge", 3)
+}
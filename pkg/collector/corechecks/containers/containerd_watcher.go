@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	containerdWatchRetryDelay    = 10 * time.Second
+	containerdPollInterval       = 1 * time.Second
+	containerdBookmarkSavePeriod = 10 * time.Second
+)
+
+// containerdEventWatcher subscribes to the containerd events in the
+// background and passes them to handle, between two runs of the check
+type containerdEventWatcher struct {
+	utils   *containerd.Provider
+	filters []string
+	handle  func(*events.Envelope)
+	// poll is called every containerdPollInterval while subscribed, it can be nil
+	poll func(containerd.ContainerdItf)
+	// bookmark is the last processed event, the missed events are
+	// replayed from it on every subscription. It can be nil.
+	bookmark *containerd.EventBookmark
+	// buffer queues the events while handle is busy, it can be nil
+	buffer *containerd.EventBuffer
+	// skew observes the timestamps of the events received live, it can
+	// be nil
+	skew   *containerd.ClockSkewTracker
+	stopCh chan struct{}
+}
+
+func newContainerdEventWatcher(utils *containerd.Provider, filters []string, handle func(*events.Envelope), poll func(containerd.ContainerdItf)) *containerdEventWatcher {
+	return &containerdEventWatcher{
+		utils:   utils,
+		filters: filters,
+		handle:  handle,
+		poll:    poll,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// run watches the events until stop is called, the subscription is
+// renewed when the connection to containerd is lost
+func (w *containerdEventWatcher) run() {
+	defer w.saveBookmark()
+	for {
+		if err := w.watch(); err != nil {
+			log.Warnf("Cannot watch containerd events, retrying in %s: %s", containerdWatchRetryDelay, err)
+		} else {
+			return
+		}
+		select {
+		case <-w.stopCh:
+			return
+		case <-time.After(containerdWatchRetryDelay):
+		}
+	}
+}
+
+func (w *containerdEventWatcher) watch() error {
+	cu, err := w.utils.Get(nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), cu.Namespace()))
+	defer cancel()
+	messages, errs := cu.GetEvents().Subscribe(ctx, w.filters...)
+	if w.buffer != nil {
+		messages = w.buffer.Buffer(ctx, messages)
+	}
+	// The events received while replaying are the ones of the new state
+	w.replay()
+
+	ticker := time.NewTicker(containerdPollInterval)
+	defer ticker.Stop()
+	saveTicker := time.NewTicker(containerdBookmarkSavePeriod)
+	defer saveTicker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return nil
+		case <-ticker.C:
+			if w.poll != nil {
+				w.poll(cu)
+			}
+		case <-saveTicker.C:
+			w.saveBookmark()
+		case msg := <-messages:
+			w.handleEnvelope(msg)
+		case err := <-errs:
+			if err == nil {
+				err = fmt.Errorf("event stream closed")
+			}
+			return err
+		}
+	}
+}
+
+// handleEnvelope updates the bookmark with the event received live and
+// passes it to handle, unless it was already replayed
+func (w *containerdEventWatcher) handleEnvelope(envelope *events.Envelope) {
+	if w.skew != nil && envelope != nil {
+		w.skew.Observe(envelope.Timestamp, time.Now())
+	}
+	if w.bookmark != nil {
+		fresh, err := w.bookmark.HandleEnvelope(envelope)
+		if err != nil {
+			log.Debugf("Cannot decode containerd event: %s", err)
+		}
+		if !fresh {
+			return
+		}
+	}
+	w.handle(envelope)
+}
+
+// replay passes to handle the events missed since the bookmark in every
+// collected namespace
+func (w *containerdEventWatcher) replay() {
+	if w.bookmark == nil {
+		return
+	}
+	utils, err := w.utils.NamespacedUtils(context.Background())
+	if err != nil {
+		log.Warnf("Cannot replay the missed containerd events: %s", err)
+		return
+	}
+	for _, cu := range utils {
+		missed, err := w.bookmark.Replay(cu)
+		if err != nil {
+			log.Warnf("Cannot replay the missed containerd events of namespace %s: %s", cu.Namespace(), err)
+			continue
+		}
+		if len(missed) > 0 {
+			log.Infof("Replaying %d containerd events missed in namespace %s", len(missed), cu.Namespace())
+		}
+		for _, envelope := range missed {
+			w.handle(envelope)
+		}
+	}
+}
+
+func (w *containerdEventWatcher) saveBookmark() {
+	if w.bookmark == nil {
+		return
+	}
+	if err := w.bookmark.Save(); err != nil {
+		log.Debugf("Cannot save the containerd event bookmark: %s", err)
+	}
+}
+
+func (w *containerdEventWatcher) stop() {
+	close(w.stopCh)
+}
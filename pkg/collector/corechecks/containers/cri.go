@@ -8,6 +8,8 @@
 package containers
 
 import (
+	"time"
+
 	yaml "gopkg.in/yaml.v2"
 	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
 
@@ -34,7 +36,8 @@ type CRIConfig struct {
 // CRICheck grabs CRI metrics
 type CRICheck struct {
 	core.CheckBase
-	instance *CRIConfig
+	instance  *CRIConfig
+	tagBuffer *tagBuffer
 }
 
 func init() {
@@ -62,11 +65,15 @@ func (c *CRIConfig) Parse(data []byte) error {
 
 // Configure parses the check configuration and init the check
 func (c *CRICheck) Configure(config, initConfig integration.Data) error {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeSelectionCRI); err != nil {
+		return err
+	}
 	err := c.CommonConfigure(config)
 	if err != nil {
 		return err
 	}
 
+	c.tagBuffer = newTagBuffer()
 	return c.instance.Parse(config)
 }
 
@@ -88,7 +95,9 @@ func (c *CRICheck) Run() error {
 		c.Warnf("Cannot get containers from the CRI: %s", err)
 		return err
 	}
+	runStart := time.Now()
 	c.processContainerStats(sender, util.Runtime, containerStats)
+	c.tagBuffer.flushStale(sender, runStart)
 
 	sender.Commit()
 	return nil
@@ -97,19 +106,20 @@ func (c *CRICheck) Run() error {
 // processContainerStats extracts metrics from the protobuf object
 func (c *CRICheck) processContainerStats(sender aggregator.Sender, runtime string, containerStats map[string]*pb.ContainerStats) {
 	for cid, stats := range containerStats {
-		entityID := containers.BuildEntityName(runtime, cid)
+		entityID := containers.BuildContainerEntityName(runtime, cid)
 		tags, err := tagger.Tag(entityID, true)
 		if err != nil {
 			log.Errorf("Could not collect tags for container %s: %s", cid[:12], err)
 		}
 		tags = append(tags, "runtime:"+runtime)
 		tags = append(tags, c.instance.Tags...)
-		sender.Gauge("cri.mem.rss", float64(stats.GetMemory().GetWorkingSetBytes().GetValue()), "", tags)
+		ctrSender := c.tagBuffer.entitySender(sender, entityID)
+		ctrSender.Gauge("cri.mem.rss", float64(stats.GetMemory().GetWorkingSetBytes().GetValue()), "", tags)
 		// Cumulative CPU usage (sum across all cores) since object creation.
-		sender.Rate("cri.cpu.usage", float64(stats.GetCpu().GetUsageCoreNanoSeconds().GetValue()), "", tags)
+		ctrSender.Rate("cri.cpu.usage", float64(stats.GetCpu().GetUsageCoreNanoSeconds().GetValue()), "", tags)
 		if c.instance.CollectDisk {
-			sender.Gauge("cri.disk.used", float64(stats.GetWritableLayer().GetUsedBytes().GetValue()), "", tags)
-			sender.Gauge("cri.disk.inodes", float64(stats.GetWritableLayer().GetInodesUsed().GetValue()), "", tags)
+			ctrSender.Gauge("cri.disk.used", float64(stats.GetWritableLayer().GetUsedBytes().GetValue()), "", tags)
+			ctrSender.Gauge("cri.disk.inodes", float64(stats.GetWritableLayer().GetInodesUsed().GetValue()), "", tags)
 		}
 	}
 }
@@ -73,6 +73,8 @@ type DockerCheck struct {
 	lastEventTime               time.Time
 	dockerHostname              string
 	cappedSender                *cappedSender
+	tagBuffer                   *tagBuffer
+	annotationRules             metrics.EventAnnotationRules
 	collectContainerSizeCounter uint64
 }
 
@@ -173,6 +175,7 @@ func (d *DockerCheck) Run() error {
 
 	collectingContainerSizeDuringThisRun := d.instance.CollectContainerSize && d.collectContainerSizeCounter == 0
 
+	runStart := time.Now()
 	images := map[string]*containerPerImage{}
 	for _, c := range cList {
 		updateContainerRunningCount(images, c)
@@ -184,47 +187,48 @@ func (d *DockerCheck) Run() error {
 			log.Errorf("Could not collect tags for container %s: %s", c.ID[:12], err)
 		}
 		tags = append(tags, d.instance.Tags...)
+		ctrSender := d.tagBuffer.entitySender(sender, c.EntityID)
 
 		if c.CPU != nil {
-			sender.Rate("docker.cpu.system", float64(c.CPU.System), "", tags)
-			sender.Rate("docker.cpu.user", float64(c.CPU.User), "", tags)
-			sender.Rate("docker.cpu.usage", c.CPU.UsageTotal, "", tags)
-			sender.Gauge("docker.cpu.shares", float64(c.CPU.Shares), "", tags)
-			sender.Rate("docker.cpu.throttled", float64(c.CPUNrThrottled), "", tags)
+			ctrSender.Rate("docker.cpu.system", float64(c.CPU.System), "", tags)
+			ctrSender.Rate("docker.cpu.user", float64(c.CPU.User), "", tags)
+			ctrSender.Rate("docker.cpu.usage", c.CPU.UsageTotal, "", tags)
+			ctrSender.Gauge("docker.cpu.shares", float64(c.CPU.Shares), "", tags)
+			ctrSender.Rate("docker.cpu.throttled", float64(c.CPUNrThrottled), "", tags)
 		} else {
 			log.Debugf("Empty CPU metrics for container %s", c.ID[:12])
 		}
 		if c.Memory != nil {
-			sender.Gauge("docker.mem.cache", float64(c.Memory.Cache), "", tags)
-			sender.Gauge("docker.mem.rss", float64(c.Memory.RSS), "", tags)
+			ctrSender.Gauge("docker.mem.cache", float64(c.Memory.Cache), "", tags)
+			ctrSender.Gauge("docker.mem.rss", float64(c.Memory.RSS), "", tags)
 			if c.Memory.SwapPresent == true {
-				sender.Gauge("docker.mem.swap", float64(c.Memory.Swap), "", tags)
+				ctrSender.Gauge("docker.mem.swap", float64(c.Memory.Swap), "", tags)
 			}
 
 			if c.Memory.HierarchicalMemoryLimit > 0 && c.Memory.HierarchicalMemoryLimit < uint64(math.Pow(2, 60)) {
-				sender.Gauge("docker.mem.limit", float64(c.Memory.HierarchicalMemoryLimit), "", tags)
+				ctrSender.Gauge("docker.mem.limit", float64(c.Memory.HierarchicalMemoryLimit), "", tags)
 				if c.Memory.HierarchicalMemoryLimit != 0 {
-					sender.Gauge("docker.mem.in_use", float64(c.Memory.RSS)/float64(c.Memory.HierarchicalMemoryLimit), "", tags)
+					ctrSender.Gauge("docker.mem.in_use", float64(c.Memory.RSS)/float64(c.Memory.HierarchicalMemoryLimit), "", tags)
 				}
 			}
 
 			if c.Memory.HierarchicalMemSWLimit > 0 && c.Memory.HierarchicalMemSWLimit < uint64(math.Pow(2, 60)) {
-				sender.Gauge("docker.mem.sw_limit", float64(c.Memory.HierarchicalMemSWLimit), "", tags)
+				ctrSender.Gauge("docker.mem.sw_limit", float64(c.Memory.HierarchicalMemSWLimit), "", tags)
 				if c.Memory.HierarchicalMemSWLimit != 0 {
-					sender.Gauge("docker.mem.sw_in_use",
+					ctrSender.Gauge("docker.mem.sw_in_use",
 						float64(c.Memory.Swap+c.Memory.RSS)/float64(c.Memory.HierarchicalMemSWLimit), "", tags)
 				}
 			}
 
 			if c.SoftMemLimit > 0 && c.SoftMemLimit < uint64(math.Pow(2, 60)) {
-				sender.Gauge("docker.mem.soft_limit", float64(c.SoftMemLimit), "", tags)
+				ctrSender.Gauge("docker.mem.soft_limit", float64(c.SoftMemLimit), "", tags)
 			}
 		} else {
 			log.Debugf("Empty memory metrics for container %s", c.ID[:12])
 		}
 
 		if c.IO != nil {
-			d.reportIOMetrics(c.IO, tags, sender)
+			d.reportIOMetrics(c.IO, tags, ctrSender)
 		} else {
 			log.Debugf("Empty IO metrics for container %s", c.ID[:12])
 		}
@@ -236,8 +240,8 @@ func (d *DockerCheck) Run() error {
 					continue
 				}
 				ifaceTags := append(tags, fmt.Sprintf("docker_network:%s", netStat.NetworkName))
-				sender.Rate("docker.net.bytes_sent", float64(netStat.BytesSent), "", ifaceTags)
-				sender.Rate("docker.net.bytes_rcvd", float64(netStat.BytesRcvd), "", ifaceTags)
+				ctrSender.Rate("docker.net.bytes_sent", float64(netStat.BytesSent), "", ifaceTags)
+				ctrSender.Rate("docker.net.bytes_rcvd", float64(netStat.BytesRcvd), "", ifaceTags)
 			}
 		} else {
 			log.Debugf("Empty network metrics for container %s", c.ID[:12])
@@ -250,12 +254,14 @@ func (d *DockerCheck) Run() error {
 			} else if info.SizeRw == nil || info.SizeRootFs == nil {
 				log.Warnf("Docker inspect did not return the container size: %s", c.ID[:12])
 			} else {
-				sender.Gauge("docker.container.size_rw", float64(*info.SizeRw), "", tags)
-				sender.Gauge("docker.container.size_rootfs", float64(*info.SizeRootFs), "", tags)
+				ctrSender.Gauge("docker.container.size_rw", float64(*info.SizeRw), "", tags)
+				ctrSender.Gauge("docker.container.size_rootfs", float64(*info.SizeRootFs), "", tags)
 			}
 		}
 	}
 
+	d.tagBuffer.flushStale(sender, runStart)
+
 	if d.instance.CollectContainerSize {
 		// Update the container size counter, used to collect them less often as they are costly
 		d.collectContainerSizeCounter =
@@ -367,12 +373,17 @@ func (d *DockerCheck) reportIOMetrics(io *cmetrics.CgroupIOStat, tags []string,
 
 // Configure parses the check configuration and init the check
 func (d *DockerCheck) Configure(config, initConfig integration.Data) error {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeNameDocker); err != nil {
+		return err
+	}
 	err := d.CommonConfigure(config)
 	if err != nil {
 		return err
 	}
 
 	d.instance.Parse(config)
+	d.tagBuffer = newTagBuffer()
+	d.annotationRules = metrics.GetEventAnnotationRules()
 
 	if len(d.instance.FilteredEventType) == 0 {
 		d.instance.FilteredEventType = []string{"top", "exec_create", "exec_start", "exec_die"}
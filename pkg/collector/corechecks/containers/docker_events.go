@@ -82,6 +82,10 @@ func (d *DockerCheck) reportEvents(events []*docker.ContainerEvent, sender aggre
 		if err != nil {
 			log.Warnf("can't submit event: %s", err)
 		} else {
+			// Docker events carry the labels of their container
+			for _, containerEv := range bundle.events {
+				d.annotationRules.Apply(&ev, containerEv.Attributes)
+			}
 			ev.Tags = append(ev.Tags, d.instance.Tags...)
 			sender.Event(ev)
 		}
@@ -14,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
 )
 
@@ -86,6 +87,14 @@ func (s *cappedSender) Rate(metric string, value float64, hostname string, tags
 	return
 }
 
+// SendRawMetricSample forwards the samples buffered by the tagBuffer to the
+// wrapped sender. They are not capped, the buffer holds a few runs at most.
+func (s *cappedSender) SendRawMetricSample(sample *metrics.MetricSample) {
+	if rawSender, ok := s.Sender.(rawMetricSender); ok {
+		rawSender.SendRawMetricSample(sample)
+	}
+}
+
 func (s *cappedSender) getPoint(cacheKey string) (*ratePoint, bool) {
 	prev, found := cache.Cache.Get(cacheKey)
 	if !found {
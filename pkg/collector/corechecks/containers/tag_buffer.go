@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+/*
+ * The orchestrator tags of a new container are only known once the kubelet
+ * lists its pod, which can take a few check runs, and its runtime tags once
+ * the runtime collector, eg. containerd, processes its start. Instead of
+ * sending its first datapoints without these tags, the metrics of the
+ * container are held until the tagger resolves them, for at most
+ * container_metrics_tags_buffer seconds, then sent with their original
+ * timestamp and the full tags.
+ */
+
+// tagBuffer holds the metrics of the containers whose tags are not resolved yet
+type tagBuffer struct {
+	duration time.Duration
+	entities map[string]*bufferedEntity
+	// For testing
+	hasOrchestratorTags func(entity string) bool
+	hasRuntimeTags      func(entity string) bool
+	tag                 func(entity string, highCard bool) ([]string, error)
+}
+
+// bufferedEntity tracks the buffering state of a container
+type bufferedEntity struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	resolved  bool
+	samples   []*metrics.MetricSample
+}
+
+// rawMetricSender sends metric samples with their own timestamp
+type rawMetricSender interface {
+	SendRawMetricSample(sample *metrics.MetricSample)
+}
+
+// newTagBuffer returns a tagBuffer, or nil if the buffering is disabled
+func newTagBuffer() *tagBuffer {
+	duration := config.Datadog.GetDuration("container_metrics_tags_buffer") * time.Second
+	if duration <= 0 {
+		return nil
+	}
+	return &tagBuffer{
+		duration:            duration,
+		entities:            make(map[string]*bufferedEntity),
+		hasOrchestratorTags: tagger.HasOrchestratorTags,
+		hasRuntimeTags:      tagger.HasRuntimeTags,
+		tag:                 tagger.Tag,
+	}
+}
+
+// entitySender returns the sender to use for the metrics of a container. The
+// metrics are buffered while the container tags are not resolved, the buffered
+// metrics being flushed on the first call after they are resolved or the
+// buffering duration is exceeded.
+func (b *tagBuffer) entitySender(sender aggregator.Sender, entity string) aggregator.Sender {
+	if b == nil {
+		return sender
+	}
+	// Buffered samples are sent with their original timestamp
+	rawSender, ok := sender.(rawMetricSender)
+	if !ok {
+		return sender
+	}
+
+	now := time.Now()
+	e, found := b.entities[entity]
+	if !found {
+		e = &bufferedEntity{firstSeen: now}
+		b.entities[entity] = e
+	}
+	e.lastSeen = now
+	if e.resolved {
+		return sender
+	}
+
+	resolved := b.hasOrchestratorTags(entity) && b.hasRuntimeTags(entity)
+	if !resolved && now.Sub(e.firstSeen) < b.duration {
+		return &bufferingSender{Sender: sender, entity: e}
+	}
+	e.resolved = true
+	b.flushEntity(rawSender, entity, e)
+	return sender
+}
+
+// flushStale sends the metrics of the containers that were not seen since
+// the given time, eg. short-lived containers, and stops tracking them
+func (b *tagBuffer) flushStale(sender aggregator.Sender, since time.Time) {
+	if b == nil {
+		return
+	}
+	rawSender, ok := sender.(rawMetricSender)
+	if !ok {
+		return
+	}
+	for entity, e := range b.entities {
+		if e.lastSeen.Before(since) {
+			b.flushEntity(rawSender, entity, e)
+			delete(b.entities, entity)
+		}
+	}
+}
+
+// flushEntity sends the buffered samples of a container with its current tags
+func (b *tagBuffer) flushEntity(sender rawMetricSender, entity string, e *bufferedEntity) {
+	if len(e.samples) == 0 {
+		return
+	}
+	entityTags, err := b.tag(entity, true)
+	if err != nil {
+		log.Debugf("Could not collect tags for %s, sending its buffered metrics with the previous tags: %s", entity, err)
+	}
+	for _, sample := range e.samples {
+		// The aggregator deduplicates the tags already known when buffering
+		sample.Tags = utils.ConcatenateTags([][]string{entityTags, sample.Tags})
+		sender.SendRawMetricSample(sample)
+	}
+	log.Debugf("Sent %d buffered samples for %s", len(e.samples), entity)
+	e.samples = nil
+}
+
+// bufferingSender stores the metric samples of a container in the buffer
+type bufferingSender struct {
+	aggregator.Sender
+	entity *bufferedEntity
+}
+
+func (s *bufferingSender) buffer(metric string, value float64, hostname string, tags []string, mType metrics.MetricType) {
+	s.entity.samples = append(s.entity.samples, &metrics.MetricSample{
+		Name:       metric,
+		Value:      value,
+		Mtype:      mType,
+		Tags:       append([]string(nil), tags...),
+		Host:       hostname,
+		SampleRate: 1,
+		Timestamp:  float64(time.Now().UnixNano()) / float64(time.Second),
+	})
+}
+
+// Gauge buffers a gauge sample
+func (s *bufferingSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.GaugeType)
+}
+
+// Rate buffers a rate sample
+func (s *bufferingSender) Rate(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.RateType)
+}
+
+// Count buffers a count sample
+func (s *bufferingSender) Count(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.CountType)
+}
+
+// MonotonicCount buffers a monotonic count sample
+func (s *bufferingSender) MonotonicCount(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.MonotonicCountType)
+}
+
+// Counter buffers a counter sample
+func (s *bufferingSender) Counter(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.CounterType)
+}
+
+// Histogram buffers a histogram sample
+func (s *bufferingSender) Histogram(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.HistogramType)
+}
+
+// Historate buffers a historate sample
+func (s *bufferingSender) Historate(metric string, value float64, hostname string, tags []string) {
+	s.buffer(metric, value, hostname, tags, metrics.HistorateType)
+}
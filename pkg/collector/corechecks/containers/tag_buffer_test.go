@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func newTestTagBuffer(resolved map[string]bool) *tagBuffer {
+	return &tagBuffer{
+		duration: 30 * time.Second,
+		entities: make(map[string]*bufferedEntity),
+		hasOrchestratorTags: func(entity string) bool {
+			return resolved[entity]
+		},
+		hasRuntimeTags: func(entity string) bool {
+			return true
+		},
+		tag: func(entity string, highCard bool) ([]string, error) {
+			if resolved[entity] {
+				return []string{"image_name:redis", "pod_name:redis-0"}, nil
+			}
+			return []string{"image_name:redis"}, nil
+		},
+	}
+}
+
+func rawSamples(sender *mocksender.MockSender) []*metrics.MetricSample {
+	var samples []*metrics.MetricSample
+	for _, call := range sender.Calls {
+		if call.Method == "SendRawMetricSample" {
+			samples = append(samples, call.Arguments.Get(0).(*metrics.MetricSample))
+		}
+	}
+	return samples
+}
+
+func TestTagBufferDisabled(t *testing.T) {
+	var buffer *tagBuffer
+	sender := mocksender.NewMockSender("")
+	assert.Equal(t, sender, buffer.entitySender(sender, "docker://abcd"))
+	buffer.flushStale(sender, time.Now())
+}
+
+func TestTagBufferResolution(t *testing.T) {
+	resolved := make(map[string]bool)
+	buffer := newTestTagBuffer(resolved)
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	// Tags are not resolved, the metrics are held
+	ctrSender := buffer.entitySender(sender, "docker://abcd")
+	assert.NotEqual(t, sender, ctrSender)
+	ctrSender.Rate("docker.cpu.usage", 10, "", []string{"image_name:redis"})
+	ctrSender.Gauge("docker.mem.rss", 100, "", []string{"image_name:redis"})
+	ctrSender.ServiceCheck("docker.exit", metrics.ServiceCheckOK, "", nil, "")
+	sender.AssertNumberOfCalls(t, "Rate", 0)
+	sender.AssertNumberOfCalls(t, "Gauge", 0)
+	sender.AssertNumberOfCalls(t, "ServiceCheck", 1)
+
+	// Containers with resolved tags are not buffered
+	resolved["docker://ef01"] = true
+	assert.Equal(t, sender, buffer.entitySender(sender, "docker://ef01"))
+
+	// The buffered metrics are sent with the full tags once resolved
+	resolved["docker://abcd"] = true
+	ctrSender = buffer.entitySender(sender, "docker://abcd")
+	assert.Equal(t, sender, ctrSender)
+
+	samples := rawSamples(sender)
+	require.Len(t, samples, 2)
+	assert.Equal(t, "docker.cpu.usage", samples[0].Name)
+	assert.Equal(t, metrics.RateType, samples[0].Mtype)
+	assert.Equal(t, 10.0, samples[0].Value)
+	assert.Contains(t, samples[0].Tags, "pod_name:redis-0")
+	assert.NotZero(t, samples[0].Timestamp)
+	assert.Equal(t, "docker.mem.rss", samples[1].Name)
+	assert.Equal(t, metrics.GaugeType, samples[1].Mtype)
+
+	// The container is not buffered again
+	resolved["docker://abcd"] = false
+	assert.Equal(t, sender, buffer.entitySender(sender, "docker://abcd"))
+}
+
+func TestTagBufferRuntimeTags(t *testing.T) {
+	buffer := newTestTagBuffer(map[string]bool{"containerd://abcd": true})
+	reported := false
+	buffer.hasRuntimeTags = func(entity string) bool {
+		return reported
+	}
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	// The runtime collector did not report the container yet
+	buffer.entitySender(sender, "containerd://abcd").Gauge("containerd.mem.rss", 100, "", nil)
+	assert.Len(t, rawSamples(sender), 0)
+
+	reported = true
+	assert.Equal(t, sender, buffer.entitySender(sender, "containerd://abcd"))
+	require.Len(t, rawSamples(sender), 1)
+}
+
+func TestTagBufferExpiry(t *testing.T) {
+	buffer := newTestTagBuffer(nil)
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	buffer.entitySender(sender, "docker://abcd").Gauge("docker.mem.rss", 100, "", nil)
+	assert.Len(t, rawSamples(sender), 0)
+
+	// Tags are never resolved, the metrics are sent after the buffering duration
+	buffer.entities["docker://abcd"].firstSeen = time.Now().Add(-time.Minute)
+	assert.Equal(t, sender, buffer.entitySender(sender, "docker://abcd"))
+	samples := rawSamples(sender)
+	require.Len(t, samples, 1)
+	assert.Equal(t, []string{"image_name:redis"}, samples[0].Tags)
+}
+
+func TestTagBufferFlushStale(t *testing.T) {
+	buffer := newTestTagBuffer(nil)
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	buffer.entitySender(sender, "docker://abcd").Gauge("docker.mem.rss", 100, "", nil)
+	runStart := time.Now()
+	buffer.entitySender(sender, "docker://ef01").Gauge("docker.mem.rss", 200, "", nil)
+
+	// The container that exited before the run is flushed and forgotten
+	buffer.flushStale(sender, runStart)
+	samples := rawSamples(sender)
+	require.Len(t, samples, 1)
+	assert.Equal(t, 100.0, samples[0].Value)
+	assert.NotContains(t, buffer.entities, "docker://abcd")
+	assert.Contains(t, buffer.entities, "docker://ef01")
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/subprocess"
 	"github.com/DataDog/datadog-agent/pkg/config"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -94,24 +95,32 @@ func (c *APMCheck) run() error {
 	if err != nil {
 		return err
 	}
+	stderrTail := subprocess.NewLineTail(subprocess.DefaultTailSize)
+	stderrDone := make(chan struct{})
 	go func() {
+		defer close(stderrDone)
 		in := bufio.NewScanner(stderr)
 		for in.Scan() {
 			log.Error(in.Text())
+			stderrTail.Add(in.Text())
 		}
 	}()
 
 	if err = cmd.Start(); err != nil {
+		subprocess.Record(subprocess.NewExitReport(c.String(), cmd, err, stderrTail))
 		return retryExitError(err)
 	}
 
 	processDone := make(chan error)
 	go func() {
+		// Reading stderr to its end before waiting keeps its last lines
+		<-stderrDone
 		processDone <- cmd.Wait()
 	}()
 
 	select {
 	case err = <-processDone:
+		subprocess.Record(subprocess.NewExitReport(c.String(), cmd, err, stderrTail))
 		return retryExitError(err)
 	case <-c.stop:
 		err = cmd.Process.Signal(os.Kill)
@@ -43,7 +43,7 @@ func (c *JMXCheck) Run() error {
 
 	select {
 	case <-state.runnerError:
-		return fmt.Errorf("jmxfetch exited, stopping %s : %s", c.name, err)
+		return fmt.Errorf("jmxfetch exited, stopping %s: %s", c.name, state.getExitReport().Reason())
 	case <-c.stop:
 		log.Infof("jmx check %s stopped", c.name)
 	}
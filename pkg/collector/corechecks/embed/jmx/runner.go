@@ -20,6 +20,7 @@ import (
 type runner struct {
 	jmxfetch *jmxfetch.JMXFetch
 	started  bool
+	stopped  bool
 }
 
 // checkInstanceCfg lists the config options on the instance against which we make some sanity checks
@@ -111,6 +112,7 @@ func (r *runner) configureRunner(instance, initConfig integration.Data) error {
 
 func (r *runner) stopRunner() error {
 	if r.jmxfetch != nil && r.started {
+		r.stopped = true
 		return r.jmxfetch.Stop()
 	}
 	return nil
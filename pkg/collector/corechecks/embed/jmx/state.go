@@ -14,6 +14,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/subprocess"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -22,6 +23,7 @@ type jmxState struct {
 	configs     *cache.BasicCache
 	runnerError chan struct{}
 	runner      *runner
+	exitReport  subprocess.ExitReport
 	lock        *sync.Mutex
 }
 
@@ -40,11 +42,32 @@ func (s *jmxState) scheduleCheck(c *JMXCheck) error {
 		if err != nil {
 			return err
 		}
+		go s.watchRunner()
 	}
 	s.configs.Add(string(c.id), c.config)
 	return nil
 }
 
+// watchRunner stops the JMX checks with the exit reason of jmxfetch
+// if it exits without being asked to
+func (s *jmxState) watchRunner() {
+	s.runner.jmxfetch.Wait()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.runner.stopped {
+		return
+	}
+	s.exitReport = s.runner.jmxfetch.ExitReport()
+	close(s.runnerError)
+}
+
+func (s *jmxState) getExitReport() subprocess.ExitReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.exitReport
+}
+
 func (s *jmxState) unscheduleCheck(c *JMXCheck) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -86,6 +109,8 @@ func GetScheduledConfigsModificationTimestamp() int64 {
 
 // StopJmxfetch stops the jmxfetch process if it is running
 func StopJmxfetch() {
+	state.lock.Lock()
+	defer state.lock.Unlock()
 	err := state.runner.stopRunner()
 	if err != nil {
 		log.Errorf("failure to kill jmxfetch process: %s", err)
@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const kernelInventoryCheckName = "kernel_inventory"
+
+// For testing
+var procModulesPath = "/proc/modules"
+
+// kernelInventoryConfig holds the instance configuration of the check
+type kernelInventoryConfig struct {
+	BPFFSPath     string   `yaml:"bpf_fs_path"`
+	CollectEvents bool     `yaml:"collect_events"`
+	Tags          []string `yaml:"tags"`
+}
+
+// kernelInventory is a snapshot of the loaded kernel modules and
+// of the eBPF objects pinned in the bpf filesystem
+type kernelInventory struct {
+	modules map[string]bool
+	bpf     map[string]bool
+}
+
+// kernelInventoryCheck reports the kernel modules and pinned eBPF
+// programs and maps, and sends an event when they change
+type kernelInventoryCheck struct {
+	core.CheckBase
+	instance *kernelInventoryConfig
+	hostname string
+	previous *kernelInventory
+}
+
+func (c *kernelInventoryConfig) parse(data []byte) error {
+	// default values
+	c.BPFFSPath = "/sys/fs/bpf"
+	c.CollectEvents = true
+
+	return yaml.Unmarshal(data, c)
+}
+
+// Configure parses the check configuration and init the check
+func (c *kernelInventoryCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	err := c.CommonConfigure(data)
+	if err != nil {
+		return err
+	}
+
+	c.hostname, err = util.GetHostname()
+	if err != nil {
+		log.Warnf("Can't get hostname, kernel inventory events will not have it: %s", err)
+	}
+
+	return c.instance.parse(data)
+}
+
+// Run executes the check
+func (c *kernelInventoryCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	inventory := &kernelInventory{}
+	inventory.modules, err = readKernelModules(procModulesPath)
+	if err != nil {
+		return err
+	}
+	inventory.bpf, err = readPinnedBPFObjects(c.instance.BPFFSPath)
+	if err != nil {
+		// The bpf filesystem is not always mounted, keep reporting modules
+		log.Debugf("Cannot list pinned eBPF objects in %s: %s", c.instance.BPFFSPath, err)
+	}
+
+	sender.Gauge("system.kernel.modules.loaded", float64(len(inventory.modules)), "", c.instance.Tags)
+	sender.Gauge("system.kernel.bpf.pinned", float64(len(inventory.bpf)), "", c.instance.Tags)
+
+	// The first run only sets the baseline
+	if c.instance.CollectEvents && c.previous != nil {
+		now := time.Now().Unix()
+		for _, ev := range c.diffEvents("Kernel module", "module", c.previous.modules, inventory.modules) {
+			ev.Ts = now
+			sender.Event(ev)
+		}
+		for _, ev := range c.diffEvents("Pinned eBPF object", "bpf", c.previous.bpf, inventory.bpf) {
+			ev.Ts = now
+			sender.Event(ev)
+		}
+	}
+	c.previous = inventory
+
+	sender.Commit()
+	return nil
+}
+
+// diffEvents returns one event for the added and one for the removed entries
+func (c *kernelInventoryCheck) diffEvents(kind, key string, previous, current map[string]bool) []metrics.Event {
+	var events []metrics.Event
+	if added := missingFrom(previous, current); len(added) > 0 {
+		events = append(events, c.buildEvent(fmt.Sprintf("%s loaded", kind), key, added))
+	}
+	if removed := missingFrom(current, previous); len(removed) > 0 {
+		events = append(events, c.buildEvent(fmt.Sprintf("%s unloaded", kind), key, removed))
+	}
+	return events
+}
+
+func (c *kernelInventoryCheck) buildEvent(title, key string, names []string) metrics.Event {
+	textLines := []string{"%%% ", "```"}
+	textLines = append(textLines, names...)
+	textLines = append(textLines, "```", " %%%")
+
+	return metrics.Event{
+		Title:          fmt.Sprintf("%s on %s: %s", title, c.hostname, strings.Join(names, ", ")),
+		Text:           strings.Join(textLines, "\n"),
+		Priority:       metrics.EventPriorityNormal,
+		AlertType:      metrics.EventAlertTypeInfo,
+		Host:           c.hostname,
+		SourceTypeName: kernelInventoryCheckName,
+		EventType:      kernelInventoryCheckName,
+		AggregationKey: fmt.Sprintf("%s:%s", kernelInventoryCheckName, key),
+		Tags:           c.instance.Tags,
+	}
+}
+
+// missingFrom returns the sorted keys of current absent from reference
+func missingFrom(reference, current map[string]bool) []string {
+	var missing []string
+	for name := range current {
+		if !reference[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// readKernelModules returns the names of the modules listed in /proc/modules
+func readKernelModules(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	modules := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		modules[fields[0]] = true
+	}
+	return modules, scanner.Err()
+}
+
+// readPinnedBPFObjects returns the paths, relative to the bpf filesystem
+// root, of the pinned eBPF programs and maps
+func readPinnedBPFObjects(root string) (map[string]bool, error) {
+	objects := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		objects[rel] = true
+		return nil
+	})
+	return objects, err
+}
+
+func kernelInventoryFactory() check.Check {
+	return &kernelInventoryCheck{
+		CheckBase: core.NewCheckBase(kernelInventoryCheckName),
+		instance:  &kernelInventoryConfig{},
+	}
+}
+
+func init() {
+	core.RegisterCheck(kernelInventoryCheckName, kernelInventoryFactory)
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+// +build linux
+
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+var (
+	sampleModules1 = []byte("nf_nat 32768 1 xt_MASQUERADE, Live 0x0000000000000000\noverlay 77824 12 - Live 0x0000000000000000\n")
+	sampleModules2 = []byte("overlay 77824 12 - Live 0x0000000000000000\nbr_netfilter 24576 0 - Live 0x0000000000000000\n")
+)
+
+func TestReadKernelModules(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "modules")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	writeSampleFile(tmpFile, sampleModules1)
+
+	modules, err := readKernelModules(tmpFile.Name())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"nf_nat": true, "overlay": true}, modules)
+}
+
+func TestReadPinnedBPFObjects(t *testing.T) {
+	root, err := ioutil.TempDir("", "bpf")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "tc", "globals"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "tc", "globals", "cilium_calls"), nil, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "prog"), nil, 0600))
+
+	objects, err := readPinnedBPFObjects(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"prog": true, "tc/globals/cilium_calls": true}, objects)
+}
+
+func TestKernelInventoryCheck(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "modules")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	procModulesPath = writeSampleFile(tmpFile, sampleModules1)
+	defer func() { procModulesPath = "/proc/modules" }()
+
+	root, err := ioutil.TempDir("", "bpf")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	kernelCheck := kernelInventoryFactory().(*kernelInventoryCheck)
+	err = kernelCheck.Configure([]byte("bpf_fs_path: "+root+"\ntags: [\"foo:bar\"]"), nil)
+	require.NoError(t, err)
+	tags := []string{"foo:bar"}
+
+	// First run only reports the gauges
+	mockSender := mocksender.NewMockSender(kernelCheck.ID())
+	mockSender.On("Gauge", "system.kernel.modules.loaded", 2.0, "", tags).Return().Times(1)
+	mockSender.On("Gauge", "system.kernel.bpf.pinned", 0.0, "", tags).Return().Times(1)
+	mockSender.On("Commit").Return().Times(1)
+	require.NoError(t, kernelCheck.Run())
+	mockSender.AssertExpectations(t)
+	mockSender.AssertNumberOfCalls(t, "Event", 0)
+
+	// Second run reports the changes
+	require.NoError(t, ioutil.WriteFile(procModulesPath, sampleModules2, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "prog"), nil, 0600))
+
+	mockSender = mocksender.NewMockSender(kernelCheck.ID())
+	mockSender.On("Gauge", "system.kernel.modules.loaded", 2.0, "", tags).Return().Times(1)
+	mockSender.On("Gauge", "system.kernel.bpf.pinned", 1.0, "", tags).Return().Times(1)
+	mockSender.On("Event", mock.AnythingOfType("metrics.Event")).Return().Times(3)
+	mockSender.On("Commit").Return().Times(1)
+	require.NoError(t, kernelCheck.Run())
+	mockSender.AssertExpectations(t)
+
+	var titles []string
+	for _, call := range mockSender.Calls {
+		if call.Method != "Event" {
+			continue
+		}
+		ev := call.Arguments.Get(0).(metrics.Event)
+		assert.Equal(t, "kernel_inventory", ev.SourceTypeName)
+		assert.Equal(t, tags, ev.Tags)
+		titles = append(titles, ev.Title)
+	}
+	hostname := kernelCheck.hostname
+	assert.Equal(t, []string{
+		"Kernel module loaded on " + hostname + ": br_netfilter",
+		"Kernel module unloaded on " + hostname + ": nf_nat",
+		"Pinned eBPF object loaded on " + hostname + ": prog",
+	}, titles)
+}
@@ -10,6 +10,8 @@
 PyObject* SubmitMetric(PyObject*, char*, MetricType, char*, float, PyObject*, char*);
 PyObject* SubmitServiceCheck(PyObject*, char*, char*, int, PyObject*, char*, char*);
 PyObject* SubmitEvent(PyObject*, char*, PyObject*);
+PyObject* GetRemainingPointBudget(char*);
+PyObject* GetBackpressure(char*);
 
 // _must_ be in the same order as the MetricType enum
 char* MetricTypeNames[] = {
@@ -84,10 +86,44 @@ static PyObject *submit_event(PyObject *self, PyObject *args) {
     return SubmitEvent(check, check_id, event);
 }
 
+static PyObject *remaining_point_budget(PyObject *self, PyObject *args) {
+    char *check_id;
+
+    PyGILState_STATE gstate;
+    gstate = PyGILState_Ensure();
+
+    // aggregator.remaining_point_budget(check_id)
+    if (!PyArg_ParseTuple(args, "s", &check_id)) {
+      PyGILState_Release(gstate);
+      return NULL;
+    }
+
+    PyGILState_Release(gstate);
+    return GetRemainingPointBudget(check_id);
+}
+
+static PyObject *backpressure(PyObject *self, PyObject *args) {
+    char *check_id;
+
+    PyGILState_STATE gstate;
+    gstate = PyGILState_Ensure();
+
+    // aggregator.backpressure(check_id)
+    if (!PyArg_ParseTuple(args, "s", &check_id)) {
+      PyGILState_Release(gstate);
+      return NULL;
+    }
+
+    PyGILState_Release(gstate);
+    return GetBackpressure(check_id);
+}
+
 static PyMethodDef AggMethods[] = {
   {"submit_metric", (PyCFunction)submit_metric, METH_VARARGS, "Submit metrics to the aggregator."},
   {"submit_service_check", (PyCFunction)submit_service_check, METH_VARARGS, "Submit service checks to the aggregator."},
   {"submit_event", (PyCFunction)submit_event, METH_VARARGS, "Submit events to the aggregator."},
+  {"remaining_point_budget", (PyCFunction)remaining_point_budget, METH_VARARGS, "Get the number of metric samples the check can still submit during its run, -1 if unlimited."},
+  {"backpressure", (PyCFunction)backpressure, METH_VARARGS, "Get whether the aggregator or the forwarder is saturated."},
   {NULL, NULL}  // guards
 };
 
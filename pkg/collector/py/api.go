@@ -129,6 +129,33 @@ func SubmitEvent(check *C.PyObject, checkID *C.char, event *C.PyObject) *C.PyObj
 	return C._none()
 }
 
+// GetRemainingPointBudget is the method exposed to Python scripts to get the
+// number of metric samples they can still submit during the check run
+//export GetRemainingPointBudget
+func GetRemainingPointBudget(checkID *C.char) *C.PyObject {
+	sender, err := aggregator.GetSender(chk.ID(C.GoString(checkID)))
+	if err != nil || sender == nil {
+		log.Errorf("Error getting the point budget from the Sender: %v", err)
+		return C.PyInt_FromLong(C.long(aggregator.UnlimitedPointBudget))
+	}
+	return C.PyInt_FromLong(C.long(sender.RemainingPointBudget()))
+}
+
+// GetBackpressure is the method exposed to Python scripts to know whether
+// the aggregator or the forwarder is saturated
+//export GetBackpressure
+func GetBackpressure(checkID *C.char) *C.PyObject {
+	sender, err := aggregator.GetSender(chk.ID(C.GoString(checkID)))
+	if err != nil || sender == nil {
+		log.Errorf("Error getting the backpressure from the Sender: %v", err)
+		return C.PyBool_FromLong(0)
+	}
+	if sender.Backpressure() {
+		return C.PyBool_FromLong(1)
+	}
+	return C.PyBool_FromLong(0)
+}
+
 // extractEventFromDict returns an `Event` populated with the fields of the passed event py object
 // The caller needs to check the returned `error`, any non-nil value indicates that the error flag is set
 // on the python interpreter.
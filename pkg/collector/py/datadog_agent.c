@@ -24,6 +24,8 @@ PyObject* LogMessage(char *message, int logLevel);
 PyObject* GetConfig(char *key);
 PyObject* GetSubprocessOutput(char **args, int argc, int raise);
 PyObject* SetExternalTags(const char *hostname, const char *source_type, char **tags, int tags_s);
+PyObject* ReadPersistentState(char *check_id, char *key);
+PyObject* WritePersistentState(char *check_id, char *key, char *value);
 
 // Exceptions
 PyObject* SubprocessOutputEmptyError;
@@ -43,6 +45,41 @@ static PyObject *get_config(PyObject *self, PyObject *args) {
     return GetConfig(key);
 }
 
+static PyObject *read_persistent_state(PyObject *self, PyObject *args) {
+    char *check_id;
+    char *key;
+
+    PyGILState_STATE gstate;
+    gstate = PyGILState_Ensure();
+
+    // datadog_agent.read_persistent_state(check_id, key)
+    if (!PyArg_ParseTuple(args, "ss", &check_id, &key)) {
+      PyGILState_Release(gstate);
+      return NULL;
+    }
+
+    PyGILState_Release(gstate);
+    return ReadPersistentState(check_id, key);
+}
+
+static PyObject *write_persistent_state(PyObject *self, PyObject *args) {
+    char *check_id;
+    char *key;
+    char *value;
+
+    PyGILState_STATE gstate;
+    gstate = PyGILState_Ensure();
+
+    // datadog_agent.write_persistent_state(check_id, key, value)
+    if (!PyArg_ParseTuple(args, "sss", &check_id, &key, &value)) {
+      PyGILState_Release(gstate);
+      return NULL;
+    }
+
+    PyGILState_Release(gstate);
+    return WritePersistentState(check_id, key, value);
+}
+
 static PyObject *log_message(PyObject *self, PyObject *args) {
     char *message;
     int  log_level;
@@ -238,6 +275,8 @@ static PyMethodDef datadogAgentMethods[] = {
   {"get_clustername", GetClusterName, METH_VARARGS, "Get the agent cluster name."},
   {"log", log_message, METH_VARARGS, "Log a message through the agent logger."},
   {"set_external_tags", set_external_tags, METH_VARARGS, "Send external host tags."},
+  {"read_persistent_state", read_persistent_state, METH_VARARGS, "Read a value of the persistent state of a check instance."},
+  {"write_persistent_state", write_persistent_state, METH_VARARGS, "Write a value of the persistent state of a check instance."},
   {NULL, NULL}
 };
 
@@ -15,6 +15,8 @@ import (
 	"syscall"
 	"unsafe"
 
+	chk "github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/checkstate"
 	"github.com/DataDog/datadog-agent/pkg/metadata/externalhost"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -308,6 +310,37 @@ func SetExternalTags(hostname, sourceType *C.char, tags **C.char, tagsLen C.int)
 	return C._none()
 }
 
+// ReadPersistentState returns a value of the persistent state of a check
+// instance, or None if the key is not set.
+// Indirectly used by the C function `read_persistent_state` that's mapped to `datadog_agent.read_persistent_state`.
+//export ReadPersistentState
+func ReadPersistentState(checkID, key *C.char) *C.PyObject {
+	value, found := checkstate.Get(chk.ID(C.GoString(checkID))).Read(C.GoString(key))
+	if !found {
+		return C._none()
+	}
+
+	cValue := C.CString(value)
+	pyValue := C.PyString_FromString(cValue)
+	C.free(unsafe.Pointer(cValue))
+	return pyValue
+}
+
+// WritePersistentState sets a value of the persistent state of a check
+// instance, raising an exception if it cannot be saved.
+// Indirectly used by the C function `write_persistent_state` that's mapped to `datadog_agent.write_persistent_state`.
+//export WritePersistentState
+func WritePersistentState(checkID, key, value *C.char) *C.PyObject {
+	goCheckID := C.GoString(checkID)
+	if err := checkstate.Get(chk.ID(goCheckID)).Write(C.GoString(key), C.GoString(value)); err != nil {
+		cErr := C.CString(fmt.Sprintf("could not save the persistent state of check %s: %v", goCheckID, err))
+		C.PyErr_SetString(C.PyExc_Exception, cErr)
+		C.free(unsafe.Pointer(cErr))
+		return nil
+	}
+	return C._none()
+}
+
 func initDatadogAgent() {
 	C.initdatadogagent()
 }
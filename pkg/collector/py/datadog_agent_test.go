@@ -1,8 +1,12 @@
 package py
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/collector/checkstate"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metadata/externalhost"
 
 	"github.com/stretchr/testify/assert"
@@ -30,3 +34,31 @@ func TestAddExternalTagsBindings(t *testing.T) {
 	eTags := externalhost.ExternalTags{"test-source-type": []string{"tag1", "tag2", "tag3"}}
 	assert.Equal(t, tuple[1], eTags)
 }
+
+func TestPersistentStateBindings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check_state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config.Datadog.Set("check_state_path", dir)
+
+	gstate := newStickyLock()
+	defer gstate.unlock()
+
+	module := python.PyImport_ImportModule("persistent_state")
+	require.NotNil(t, module)
+	module.GetAttrString("write").Call(python.PyTuple_New(0), python.PyDict_New())
+
+	value, found := checkstate.Get("testcheck:1d5a7a2b9e3c4f60").Read("cursor")
+	assert.True(t, found)
+	assert.Equal(t, "s=7f2a;i=42", value)
+
+	args := python.PyTuple_New(1)
+	python.PyTuple_SetItem(args, 0, python.PyString_FromString("cursor"))
+	res := module.GetAttrString("read").Call(args, python.PyDict_New())
+	require.NotNil(t, res)
+	assert.Equal(t, "s=7f2a;i=42", python.PyString_AsString(res))
+
+	python.PyTuple_SetItem(args, 0, python.PyString_FromString("unknown"))
+	res = module.GetAttrString("read").Call(args, python.PyDict_New())
+	assert.Equal(t, python.Py_None, res)
+}
@@ -0,0 +1,9 @@
+import datadog_agent
+
+
+def write():
+    datadog_agent.write_persistent_state("testcheck:1d5a7a2b9e3c4f60", "cursor", "s=7f2a;i=42")
+
+
+def read(key):
+    return datadog_agent.read_persistent_state("testcheck:1d5a7a2b9e3c4f60", key)
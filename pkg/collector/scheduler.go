@@ -39,6 +39,11 @@ type CheckScheduler struct {
 	loaders        []check.Loader
 	collector      *Collector
 	m              sync.RWMutex
+
+	// deferred holds the checks waiting for their readiness gate, with
+	// the channel closed to cancel them when they are unscheduled
+	deferred    map[check.ID]chan struct{}
+	deferredMux sync.Mutex
 }
 
 // InitCheckScheduler creates and returns a check scheduler
@@ -47,6 +52,7 @@ func InitCheckScheduler(collector *Collector) *CheckScheduler {
 		collector:      collector,
 		configToChecks: make(map[string][]check.ID),
 		loaders:        make([]check.Loader, 0, len(loaders.LoaderCatalog())),
+		deferred:       make(map[check.ID]chan struct{}),
 	}
 	// add the check loaders
 	for _, loader := range loaders.LoaderCatalog() {
@@ -60,14 +66,75 @@ func InitCheckScheduler(collector *Collector) *CheckScheduler {
 func (s *CheckScheduler) Schedule(configs []integration.Config) {
 	checks := s.GetChecksFromConfigs(configs, true)
 	for _, c := range checks {
-		log.Infof("Scheduling check %s", c)
-		_, err := s.collector.RunCheck(c)
-		if err != nil {
-			log.Errorf("Unable to run Check %s: %v", c, err)
-			errorStats.setRunError(c.ID(), err.Error())
+		if gate := check.GetReadinessGate(c.String()); gate != nil {
+			s.runWhenReady(c, gate())
 			continue
 		}
+		s.runCheck(c)
+	}
+}
+
+// runCheck sends a check to the collector
+func (s *CheckScheduler) runCheck(c check.Check) {
+	log.Infof("Scheduling check %s", c)
+	_, err := s.collector.RunCheck(c)
+	if err != nil {
+		log.Errorf("Unable to run Check %s: %v", c, err)
+		errorStats.setRunError(c.ID(), err.Error())
+	}
+}
+
+// runWhenReady runs a check once ready is closed, unless it is unscheduled
+// meanwhile, so that the checks of a runtime don't fail until the agent is
+// connected to it
+func (s *CheckScheduler) runWhenReady(c check.Check, ready <-chan struct{}) {
+	if ready == nil {
+		s.runCheck(c)
+		return
+	}
+	select {
+	case <-ready:
+		s.runCheck(c)
+		return
+	default:
+	}
+
+	cancel := make(chan struct{})
+	s.deferredMux.Lock()
+	s.deferred[c.ID()] = cancel
+	s.deferredMux.Unlock()
+	log.Infof("Deferring check %s until its dependencies are ready", c)
+
+	go func() {
+		select {
+		case <-ready:
+		case <-cancel:
+			return
+		}
+		// the check is run under the lock for Unschedule to stop it once
+		// it is running
+		s.deferredMux.Lock()
+		defer s.deferredMux.Unlock()
+		if s.deferred[c.ID()] != cancel {
+			// unscheduled meanwhile
+			return
+		}
+		delete(s.deferred, c.ID())
+		s.runCheck(c)
+	}()
+}
+
+// cancelDeferred cancels a check waiting for its readiness gate, and
+// returns whether it was waiting
+func (s *CheckScheduler) cancelDeferred(id check.ID) bool {
+	s.deferredMux.Lock()
+	defer s.deferredMux.Unlock()
+	cancel, found := s.deferred[id]
+	if found {
+		close(cancel)
+		delete(s.deferred, id)
 	}
+	return found
 }
 
 // Unschedule unschedules checks matching configs
@@ -82,6 +149,10 @@ func (s *CheckScheduler) Unschedule(configs []integration.Config) {
 		ids := s.configToChecks[digest]
 		stopped := map[check.ID]struct{}{}
 		for _, id := range ids {
+			if s.cancelDeferred(id) {
+				stopped[id] = struct{}{}
+				continue
+			}
 			// `StopCheck` might time out so we don't risk to block
 			// the polling loop forever
 			err := s.collector.StopCheck(id)
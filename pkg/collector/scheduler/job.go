@@ -43,6 +43,7 @@ type jobQueue struct {
 	buckets             []*jobBucket
 	bucketTicker        *time.Ticker
 	lastTick            time.Time
+	scheduleDelay       time.Duration // delay of the last check enqueued at the last tick
 	sparseStep          uint
 	currentBucketIdx    uint
 	schedulingBucketIdx uint
@@ -134,9 +135,10 @@ func (jq *jobQueue) stats() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"Interval": jq.interval / time.Second,
-		"Buckets":  nBuckets,
-		"Size":     nJobs,
+		"Interval":      jq.interval / time.Second,
+		"Buckets":       nBuckets,
+		"Size":          nJobs,
+		"ScheduleDelay": jq.scheduleDelay.Seconds(),
 	}
 }
 
@@ -194,7 +196,10 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 				return false
 			}
 		}
+		// Enqueuing blocks while the runners are busy, delaying the checks
+		delay := time.Since(t)
 		jq.mu.Lock()
+		jq.scheduleDelay = delay
 		jq.currentBucketIdx = (jq.currentBucketIdx + 1) % uint(len(jq.buckets))
 		jq.mu.Unlock()
 	case <-jq.health.C:
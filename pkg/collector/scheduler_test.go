@@ -7,6 +7,7 @@ package collector
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
@@ -26,3 +27,43 @@ func TestAddLoader(t *testing.T) {
 	s.AddLoader(&MockLoader{}) // noop
 	assert.Len(t, s.loaders, 1)
 }
+
+type gatedLoader struct{}
+
+func (l *gatedLoader) Load(config integration.Config) ([]check.Check, error) {
+	return []check.Check{NewCheckUnique(check.ID(config.Name), config.Name)}, nil
+}
+
+func TestScheduleReadinessGate(t *testing.T) {
+	ready := make(chan struct{})
+	check.RegisterReadinessGate("gated", func() <-chan struct{} { return ready })
+	check.RegisterReadinessGate("unscheduled", func() <-chan struct{} { return make(chan struct{}) })
+	check.RegisterReadinessGate("ungated", func() <-chan struct{} { return nil })
+
+	c := NewCollector()
+	defer c.Stop()
+	s := &CheckScheduler{
+		collector:      c,
+		configToChecks: make(map[string][]check.ID),
+		deferred:       make(map[check.ID]chan struct{}),
+	}
+	s.AddLoader(&gatedLoader{})
+
+	gated := integration.Config{Name: "gated", Instances: []integration.Data{integration.Data("{}")}}
+	unscheduled := integration.Config{Name: "unscheduled", Instances: []integration.Data{integration.Data("{}")}}
+	ungated := integration.Config{Name: "ungated", Instances: []integration.Data{integration.Data("{}")}}
+	s.Schedule([]integration.Config{gated, unscheduled, ungated})
+
+	assert.True(t, c.find("ungated"))
+	assert.False(t, c.find("gated"))
+	assert.False(t, c.find("unscheduled"))
+
+	// The checks unscheduled while waiting are never run
+	s.Unschedule([]integration.Config{unscheduled})
+	assert.NotContains(t, s.configToChecks, unscheduled.Digest())
+	assert.NotContains(t, s.deferred, check.ID("unscheduled"))
+
+	close(ready)
+	assert.Eventually(t, func() bool { return c.find("gated") }, time.Second, 10*time.Millisecond)
+	assert.False(t, c.find("unscheduled"))
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package subprocess
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ServiceCheckName is the service check sent when a subprocess exits
+const ServiceCheckName = "datadog.agent.subprocess_status"
+
+// ExitReport describes how a subprocess run by a check exited
+type ExitReport struct {
+	Name       string   `json:"name"`
+	Command    string   `json:"command"`
+	Pid        int      `json:"pid"`
+	ExitCode   int      `json:"exit_code"`
+	Signal     string   `json:"signal,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	StderrTail []string `json:"stderr_tail"`
+	// UserTime and SystemTime are the CPU times in seconds
+	UserTime   float64 `json:"user_time"`
+	SystemTime float64 `json:"system_time"`
+	// MaxRSS is the peak resident memory in bytes, 0 if unknown
+	MaxRSS    int64 `json:"max_rss"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// NewExitReport builds the report of cmd from the error returned by its
+// Start or Wait method and the tail of its standard error, which can be nil
+func NewExitReport(name string, cmd *exec.Cmd, err error, stderr *LineTail) ExitReport {
+	r := ExitReport{
+		Name:      name,
+		Command:   cmd.Path,
+		Timestamp: time.Now().Unix(),
+	}
+	if stderr != nil {
+		r.StderrTail = stderr.Lines()
+	}
+
+	state := cmd.ProcessState
+	if state == nil {
+		// The process did not start or could not be waited for
+		r.ExitCode = -1
+		if err != nil {
+			r.Error = err.Error()
+		}
+		return r
+	}
+
+	r.Pid = state.Pid()
+	r.UserTime = state.UserTime().Seconds()
+	r.SystemTime = state.SystemTime().Seconds()
+	r.MaxRSS = maxRSS(state)
+	if status, ok := state.Sys().(syscall.WaitStatus); ok {
+		r.ExitCode = status.ExitStatus()
+		if status.Signaled() {
+			r.Signal = status.Signal().String()
+		}
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Failed returns whether the subprocess exited abnormally
+func (r ExitReport) Failed() bool {
+	return r.ExitCode != 0 || r.Signal != "" || r.Error != ""
+}
+
+// Reason returns a human readable description of the exit
+func (r ExitReport) Reason() string {
+	switch {
+	case r.Error != "" && r.Pid == 0:
+		return fmt.Sprintf("%s failed to start: %s", r.Name, r.Error)
+	case r.Error != "":
+		return fmt.Sprintf("%s failed: %s", r.Name, r.Error)
+	case r.Signal != "":
+		return fmt.Sprintf("%s (pid %d) was killed by signal: %s", r.Name, r.Pid, r.Signal)
+	}
+	return fmt.Sprintf("%s (pid %d) exited with code %d", r.Name, r.Pid, r.ExitCode)
+}
+
+// String returns the reason of the exit followed by the stderr tail
+func (r ExitReport) String() string {
+	if len(r.StderrTail) == 0 {
+		return r.Reason()
+	}
+	return fmt.Sprintf("%s, last lines of stderr:\n%s", r.Reason(), strings.Join(r.StderrTail, "\n"))
+}
+
+var (
+	reports  = make(map[string]ExitReport)
+	reportsM sync.RWMutex
+)
+
+// Record stores the report for the status page and sends the subprocess
+// service check, critical if the subprocess failed
+func Record(r ExitReport) {
+	reportsM.Lock()
+	reports[r.Name] = r
+	reportsM.Unlock()
+
+	status := metrics.ServiceCheckOK
+	if r.Failed() {
+		log.Errorf("%s", r)
+		status = metrics.ServiceCheckCritical
+	} else {
+		log.Infof("%s", r.Reason())
+	}
+
+	sender, err := aggregator.GetDefaultSender()
+	if err != nil {
+		log.Debugf("Not sending the %s service check for %s: %s", ServiceCheckName, r.Name, err)
+		return
+	}
+	sender.ServiceCheck(ServiceCheckName, status, "", []string{"subprocess:" + r.Name}, r.String())
+	sender.Commit()
+}
+
+// GetExitReports returns the last report of every subprocess, by name
+func GetExitReports() []ExitReport {
+	reportsM.RLock()
+	defer reportsM.RUnlock()
+	res := make([]ExitReport, 0, len(reports))
+	for _, r := range reports {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package subprocess
+
+import (
+	"bufio"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCommand(t *testing.T, name string, args ...string) ExitReport {
+	cmd := exec.Command(name, args...)
+	stderr, err := cmd.StderrPipe()
+	require.NoError(t, err)
+	tail := NewLineTail(2)
+
+	if err := cmd.Start(); err != nil {
+		return NewExitReport("test", cmd, err, tail)
+	}
+	in := bufio.NewScanner(stderr)
+	for in.Scan() {
+		tail.Add(in.Text())
+	}
+	return NewExitReport("test", cmd, cmd.Wait(), tail)
+}
+
+func TestExitReportSuccess(t *testing.T) {
+	r := runCommand(t, "sh", "-c", "exit 0")
+	assert.False(t, r.Failed())
+	assert.Equal(t, 0, r.ExitCode)
+	assert.NotZero(t, r.Pid)
+	assert.Empty(t, r.StderrTail)
+}
+
+func TestExitReportExitCode(t *testing.T) {
+	r := runCommand(t, "sh", "-c", "echo one >&2; echo two >&2; echo three >&2; exit 3")
+	assert.True(t, r.Failed())
+	assert.Equal(t, 3, r.ExitCode)
+	assert.Empty(t, r.Error)
+	assert.Equal(t, []string{"two", "three"}, r.StderrTail)
+	assert.Contains(t, r.Reason(), "exited with code 3")
+	assert.Contains(t, r.String(), "two\nthree")
+}
+
+func TestExitReportSignal(t *testing.T) {
+	r := runCommand(t, "sh", "-c", "kill -9 $$")
+	assert.True(t, r.Failed())
+	assert.Equal(t, "killed", r.Signal)
+	assert.Contains(t, r.Reason(), "killed by signal")
+}
+
+func TestExitReportStartFailure(t *testing.T) {
+	r := runCommand(t, "/nonexistent/binary")
+	assert.True(t, r.Failed())
+	assert.Equal(t, -1, r.ExitCode)
+	assert.Contains(t, r.Reason(), "failed to start")
+}
+
+func TestRecord(t *testing.T) {
+	Record(ExitReport{Name: "zzz", ExitCode: 1})
+	Record(ExitReport{Name: "aaa"})
+	Record(ExitReport{Name: "zzz", ExitCode: 2})
+
+	reports := GetExitReports()
+	require.Len(t, reports, 2)
+	assert.Equal(t, "aaa", reports[0].Name)
+	assert.Equal(t, "zzz", reports[1].Name)
+	assert.Equal(t, 2, reports[1].ExitCode)
+}
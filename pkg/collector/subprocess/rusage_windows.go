@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package subprocess
+
+import "os"
+
+// maxRSS is not reported by the process state on Windows
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package subprocess
+
+import "sync"
+
+// DefaultTailSize is the number of stderr lines kept for the exit reports
+const DefaultTailSize = 20
+
+// LineTail keeps the last lines written by a subprocess, it is safe for
+// concurrent use
+type LineTail struct {
+	m     sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// NewLineTail returns a LineTail keeping the last size lines
+func NewLineTail(size int) *LineTail {
+	if size <= 0 {
+		size = DefaultTailSize
+	}
+	return &LineTail{lines: make([]string, size)}
+}
+
+// Add appends a line, evicting the oldest one if the tail is full
+func (t *LineTail) Add(line string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.lines[t.next] = line
+	t.next = (t.next + 1) % len(t.lines)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Lines returns the kept lines, oldest first
+func (t *LineTail) Lines() []string {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if !t.full {
+		return append([]string{}, t.lines[:t.next]...)
+	}
+	return append(append([]string{}, t.lines[t.next:]...), t.lines[:t.next]...)
+}
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package subprocess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineTail(t *testing.T) {
+	tail := NewLineTail(3)
+	assert.Empty(t, tail.Lines())
+
+	tail.Add("a")
+	tail.Add("b")
+	assert.Equal(t, []string{"a", "b"}, tail.Lines())
+
+	tail.Add("c")
+	assert.Equal(t, []string{"a", "b", "c"}, tail.Lines())
+
+	tail.Add("d")
+	tail.Add("e")
+	assert.Equal(t, []string{"c", "d", "e"}, tail.Lines())
+}
@@ -93,10 +93,12 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("tag_value_split_separator", map[string]string{})
 	config.BindEnvAndSetDefault("conf_path", ".")
 	config.BindEnvAndSetDefault("confd_path", defaultConfdPath)
+	config.BindEnvAndSetDefault("confd_layers", []string{})
 	config.BindEnvAndSetDefault("additional_checksd", defaultAdditionalChecksPath)
 	config.BindEnvAndSetDefault("log_payloads", false)
 	config.BindEnvAndSetDefault("log_file", "")
 	config.BindEnvAndSetDefault("log_level", "info")
+	config.BindEnvAndSetDefault("log_level_overrides", map[string]string{})
 	config.BindEnvAndSetDefault("log_to_syslog", false)
 	config.BindEnvAndSetDefault("log_to_console", true)
 	config.BindEnvAndSetDefault("logging_frequency", int64(20))
@@ -112,9 +114,15 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("default_integration_http_timeout", 9)
 	config.BindEnvAndSetDefault("enable_metadata_collection", true)
 	config.BindEnvAndSetDefault("enable_gohai", true)
+	config.BindEnvAndSetDefault("listening_ports_fingerprinting", false)
 	config.BindEnvAndSetDefault("check_runners", int64(4))
+	config.BindEnvAndSetDefault("check_state_path", filepath.Join(defaultRunPath, "check_state"))
+	config.BindEnvAndSetDefault("check_point_budget", int64(0)) // metric samples per check run, 0 is unlimited
+	config.BindEnvAndSetDefault("checkpoint_on_shutdown", false)
+	config.BindEnvAndSetDefault("checkpoint_path", filepath.Join(defaultRunPath, "checkpoint"))
 	config.BindEnvAndSetDefault("auth_token_file_path", "")
 	config.BindEnvAndSetDefault("bind_host", "localhost")
+	config.BindEnvAndSetDefault("prefer_ipv6", false)
 	config.BindEnvAndSetDefault("health_port", int64(0))
 
 	// if/when the default is changed to true, make the default platform
@@ -136,6 +144,11 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_recovery_interval", DefaultForwarderRecoveryInterval)
 	config.BindEnvAndSetDefault("forwarder_recovery_reset", false)
 
+	// Intake failover settings
+	config.SetDefault("forwarder_failover_endpoints", map[string][]string{})
+	config.BindEnvAndSetDefault("forwarder_failover_hysteresis", 0.2)
+	config.BindEnvAndSetDefault("forwarder_failover_min_dwell", 60)
+
 	// Use to output logs in JSON format
 	config.BindEnvAndSetDefault("log_format_json", false)
 
@@ -165,6 +178,7 @@ func initConfig(config Config) {
 	config.BindEnv("procfs_path")
 	config.BindEnv("container_proc_root")
 	config.BindEnv("container_cgroup_root")
+	config.BindEnvAndSetDefault("container_list_max_staleness", int64(10)) // in seconds
 
 	config.BindEnvAndSetDefault("proc_root", "/proc")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
@@ -183,6 +197,7 @@ func initConfig(config Config) {
 	// Forwarder
 	config.BindEnvAndSetDefault("forwarder_timeout", 20)
 	config.BindEnvAndSetDefault("forwarder_retry_queue_max_size", 30)
+	config.BindEnvAndSetDefault("forwarder_retry_priority_aging", 600)
 	config.BindEnvAndSetDefault("forwarder_num_workers", 1)
 	// Dogstatsd
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
@@ -190,6 +205,10 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_buffer_size", 1024*8) // 8KB buffer
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_socket_owner", "")
+	config.BindEnvAndSetDefault("dogstatsd_socket_group", "")
+	config.BindEnvAndSetDefault("dogstatsd_socket_mode", "0722")
+	config.BindEnvAndSetDefault("dogstatsd_socket_selinux_label", "")
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
 	config.BindEnvAndSetDefault("dogstatsd_stats_enable", false)
 	config.BindEnvAndSetDefault("dogstatsd_stats_buffer", 10)
@@ -209,6 +228,16 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("extra_listeners", []string{})
 	config.BindEnvAndSetDefault("extra_config_providers", []string{})
 
+	// Container runtime collected, auto|docker|containerd|cri
+	config.BindEnvAndSetDefault("container_runtime", "auto")
+
+	// Time the tags of the deleted entities are kept, in seconds
+	config.BindEnvAndSetDefault("tagger_deletion_grace_period", int64(300))
+	// Time the samples of the new containers wait for their runtime tags,
+	// in seconds, 0 is disabled
+	config.BindEnvAndSetDefault("tagger_deferral_max_delay", int64(0))
+	config.BindEnvAndSetDefault("tagger_deferral_max_pending", 10000)
+
 	// Docker
 	config.BindEnvAndSetDefault("docker_query_timeout", int64(5))
 	config.BindEnvAndSetDefault("docker_labels_as_tags", map[string]string{})
@@ -217,12 +246,46 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("kubernetes_pod_annotations_as_tags", map[string]string{})
 	config.BindEnvAndSetDefault("kubernetes_node_labels_as_tags", map[string]string{})
 	config.BindEnvAndSetDefault("container_cgroup_prefix", "")
+	config.BindEnvAndSetDefault("container_metrics_tags_buffer", 0) // in seconds, 0 is disabled
+	config.SetDefault("event_annotation_rules", nil)
 
 	// CRI
 	config.BindEnvAndSetDefault("cri_socket_path", "")              // empty is disabled
 	config.BindEnvAndSetDefault("cri_connection_timeout", int64(1)) // in seconds
 	config.BindEnvAndSetDefault("cri_query_timeout", int64(5))      // in seconds
 
+	// Containerd
+	config.BindEnvAndSetDefault("containerd_namespace", "k8s.io")
+	config.BindEnvAndSetDefault("containerd_labels_as_tags", map[string]string{})
+	config.BindEnvAndSetDefault("containerd_annotations_as_tags", map[string]string{})
+	config.BindEnvAndSetDefault("containerd_namespaces", []string{})
+	config.BindEnvAndSetDefault("containerd_exclude_namespaces", []string{})
+	config.BindEnvAndSetDefault("containerd_collect_events", false)
+	config.BindEnvAndSetDefault("containerd_flavor", "auto")
+	config.BindEnvAndSetDefault("containerd_config_path", "") // empty is the path of the flavor
+	config.BindEnvAndSetDefault("containerd_report_registry_mirrors", false)
+	config.BindEnvAndSetDefault("containerd_keepalive_time", int64(300))       // in seconds
+	config.BindEnvAndSetDefault("containerd_health_check_interval", int64(15)) // in seconds, 0 is disabled
+	config.BindEnvAndSetDefault("containerd_max_concurrent_queries", 10)
+	config.BindEnvAndSetDefault("containerd_cache_max_staleness", int64(300)) // in seconds
+	config.BindEnvAndSetDefault("containerd_debug_grpc", false)
+	config.BindEnvAndSetDefault("containerd_debug_grpc_history", 100)
+	config.BindEnvAndSetDefault("containerd_rate_limit", 0.0) // in calls per second, 0 is unlimited
+	config.BindEnvAndSetDefault("containerd_rate_limit_burst", 20)
+	config.BindEnvAndSetDefault("containerd_event_bookmark_path", filepath.Join(defaultRunPath, "containerd_event_bookmark.json"))
+	config.BindEnvAndSetDefault("containerd_metadata_store_path", filepath.Join(defaultRunPath, "containerd_metadata.json"))
+	config.BindEnvAndSetDefault("containerd_config_watch_interval", int64(30)) // in seconds, 0 is disabled
+	config.BindEnvAndSetDefault("containerd_event_buffer_size", 1000)
+	config.BindEnvAndSetDefault("containerd_relay_enabled", false)
+	config.BindEnvAndSetDefault("containerd_relay_interval", int64(15)) // in seconds
+	config.BindEnvAndSetDefault("containerd_via_cluster_agent", false)
+	config.BindEnvAndSetDefault("containerd_allow_task_restart", false)
+	config.BindEnvAndSetDefault("containerd_env_scrub_patterns", []string{})
+	config.BindEnvAndSetDefault("containerd_readiness_max_wait", int64(120)) // in seconds, 0 is disabled
+	config.BindEnvAndSetDefault("containerd_container_name_labels", []string{})
+	config.BindEnvAndSetDefault("containerd_init_container_names", []string{})
+	config.BindEnvAndSetDefault("containerd_sidecar_container_names", []string{})
+
 	// Kubernetes
 	config.BindEnvAndSetDefault("kubernetes_kubelet_host", "")
 	config.BindEnvAndSetDefault("kubernetes_http_kubelet_port", 10255)
@@ -254,6 +317,8 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("cluster_agent.auth_token", "")
 	config.BindEnvAndSetDefault("cluster_agent.url", "")
 	config.BindEnvAndSetDefault("cluster_agent.kubernetes_service_name", "datadog-cluster-agent")
+	config.BindEnvAndSetDefault("cluster_agent.containerd_relay.enabled", false)
+	config.BindEnvAndSetDefault("cluster_agent.containerd_relay.ttl", 60) // in seconds
 	config.BindEnvAndSetDefault("metrics_port", "5000")
 
 	// ECS
@@ -432,6 +497,10 @@ func Load() error {
 		log.Warnf("config.load() error %v", err)
 		return err
 	}
+	if err := mergeIncludes(Datadog); err != nil {
+		log.Warnf("config.load() error %v", err)
+		return err
+	}
 	log.Infof("config.load succeeded")
 
 	// We have to init the secrets package before we can use it to decrypt
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package containerdconfig reads the containerd settings of the agent
+// configuration for the containerd util, which does not depend on it.
+// Importing the package sets it as the containerd.AgentConfig.
+package containerdconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func init() {
+	containerd.SetAgentConfig(agentConfig{})
+}
+
+// reloadableSettings are the settings read again from the configuration file
+// by the config watch, with the value they take when removed from the file
+var reloadableSettings = map[string]string{
+	"cri_socket_path":      "",
+	"containerd_namespace": containerd.DefaultNamespace,
+}
+
+// startConfigWatch starts the config watch, on the platforms supporting it
+var startConfigWatch = func() {}
+
+// agentConfig is the containerd.AgentConfig of the agent configuration
+type agentConfig struct{}
+
+func (agentConfig) Options() containerd.Options {
+	startConfigWatch()
+	return Options()
+}
+
+func (agentConfig) NamespaceFilter() containerd.NamespaceFilter {
+	return NamespaceFilter()
+}
+
+func (agentConfig) NameResolver() containerd.NameResolver {
+	return NameResolver()
+}
+
+func (agentConfig) TypeResolver() containerd.TypeResolver {
+	return TypeResolver()
+}
+
+// Options returns the containerd.Options matching the agent configuration
+func Options() containerd.Options {
+	return containerd.Options{
+		SocketPath:           config.Datadog.GetString("cri_socket_path"),
+		Flavor:               containerd.Flavor(config.Datadog.GetString("containerd_flavor")),
+		Namespace:            config.Datadog.GetString("containerd_namespace"),
+		ConnectionTimeout:    config.Datadog.GetDuration("cri_connection_timeout") * time.Second,
+		QueryTimeout:         config.Datadog.GetDuration("cri_query_timeout") * time.Second,
+		KeepaliveTime:        config.Datadog.GetDuration("containerd_keepalive_time") * time.Second,
+		HealthCheckInterval:  config.Datadog.GetDuration("containerd_health_check_interval") * time.Second,
+		ConfigPath:           config.Datadog.GetString("containerd_config_path"),
+		MaxConcurrentQueries: config.Datadog.GetInt("containerd_max_concurrent_queries"),
+		CacheMaxStaleness:    config.Datadog.GetDuration("containerd_cache_max_staleness") * time.Second,
+		ProcRoot:             config.Datadog.GetString("container_proc_root"),
+		CgroupRoot:           config.Datadog.GetString("container_cgroup_root"),
+		DebugGRPC:            config.Datadog.GetBool("containerd_debug_grpc"),
+		GRPCCallHistory:      config.Datadog.GetInt("containerd_debug_grpc_history"),
+		ViaClusterAgent:      config.Datadog.GetBool("containerd_via_cluster_agent"),
+		AllowTaskRestart:     config.Datadog.GetBool("containerd_allow_task_restart"),
+		EnvScrubPatterns:     config.Datadog.GetStringSlice("containerd_env_scrub_patterns"),
+		RateLimit:            config.Datadog.GetFloat64("containerd_rate_limit"),
+		RateLimitBurst:       config.Datadog.GetInt("containerd_rate_limit_burst"),
+	}
+}
+
+// NamespaceFilter returns the namespaces collected by the agent,
+// containerd_namespace if containerd_namespaces is empty, except the
+// containerd_exclude_namespaces ones
+func NamespaceFilter() containerd.NamespaceFilter {
+	include := config.Datadog.GetStringSlice("containerd_namespaces")
+	if ns := config.Datadog.GetString("containerd_namespace"); len(include) == 0 && ns != "" {
+		include = []string{ns}
+	}
+	return containerd.NewNamespaceFilter(include, config.Datadog.GetStringSlice("containerd_exclude_namespaces"))
+}
+
+// NameResolver returns the resolver of the container names of
+// containerd_container_name_labels, containerd.DefaultContainerNameLabels
+// if unset
+func NameResolver() containerd.NameResolver {
+	sources := config.Datadog.GetStringSlice("containerd_container_name_labels")
+	if len(sources) == 0 {
+		sources = containerd.DefaultContainerNameLabels
+	}
+	return containerd.NewNameResolver(sources)
+}
+
+// TypeResolver returns the resolver of the container types of
+// containerd_init_container_names and containerd_sidecar_container_names,
+// containerd.DefaultInitContainerNames and
+// containerd.DefaultSidecarContainerNames if unset
+func TypeResolver() containerd.TypeResolver {
+	initNames := config.Datadog.GetStringSlice("containerd_init_container_names")
+	if len(initNames) == 0 {
+		initNames = containerd.DefaultInitContainerNames
+	}
+	sidecarNames := config.Datadog.GetStringSlice("containerd_sidecar_container_names")
+	if len(sidecarNames) == 0 {
+		sidecarNames = containerd.DefaultSidecarContainerNames
+	}
+	return containerd.NewTypeResolver(initNames, sidecarNames)
+}
+
+// configWatchInterval returns the interval of the config watch, zero if
+// it is disabled
+func configWatchInterval() time.Duration {
+	return config.Datadog.GetDuration("containerd_config_watch_interval") * time.Second
+}
+
+// reloadConfig reads the reloadableSettings again from the configuration
+// file at path, except the ones set through environment variables
+func reloadConfig(path string) error {
+	if path == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("cannot parse %s: %s", path, err)
+	}
+	for key, defaultValue := range reloadableSettings {
+		if _, found := os.LookupEnv("DD_" + strings.ToUpper(key)); found {
+			continue
+		}
+		value := defaultValue
+		if v, found := settings[key]; found && v != nil {
+			value = fmt.Sprint(v)
+		}
+		if config.Datadog.GetString(key) != value {
+			config.Datadog.Set(key, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerdconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestOptions(t *testing.T) {
+	mockConfig := config.Mock()
+
+	opts := Options()
+	// The socket is discovered by the util if unset
+	assert.Equal(t, "", opts.SocketPath)
+	assert.Equal(t, containerd.DefaultNamespace, opts.Namespace)
+	assert.Equal(t, 1*time.Second, opts.ConnectionTimeout)
+	assert.Equal(t, 5*time.Second, opts.QueryTimeout)
+	assert.Equal(t, 5*time.Minute, opts.KeepaliveTime)
+	assert.Equal(t, 15*time.Second, opts.HealthCheckInterval)
+
+	mockConfig.Set("cri_socket_path", "/run/containerd/containerd.sock")
+	mockConfig.Set("containerd_namespace", "moby")
+	mockConfig.Set("cri_query_timeout", 10)
+	mockConfig.Set("containerd_health_check_interval", 0)
+
+	opts = Options()
+	assert.Equal(t, "/run/containerd/containerd.sock", opts.SocketPath)
+	assert.Equal(t, "moby", opts.Namespace)
+	assert.Equal(t, 10*time.Second, opts.QueryTimeout)
+	assert.Equal(t, time.Duration(0), opts.HealthCheckInterval)
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	mockConfig := config.Mock()
+
+	filter := NamespaceFilter()
+	assert.False(t, filter.IsExcluded("k8s.io"))
+	assert.True(t, filter.IsExcluded("moby"))
+
+	mockConfig.Set("containerd_namespaces", []string{"k8s.io", "moby"})
+	mockConfig.Set("containerd_exclude_namespaces", []string{"moby"})
+	filter = NamespaceFilter()
+	assert.False(t, filter.IsExcluded("k8s.io"))
+	assert.True(t, filter.IsExcluded("moby"))
+	assert.True(t, filter.IsExcluded("buildkit"))
+}
+
+func TestReloadConfig(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("cri_socket_path", "/var/run/containerd/containerd.sock")
+	mockConfig.Set("containerd_namespace", "moby")
+
+	f, err := ioutil.TempFile("", "datadog.yaml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("cri_socket_path: /run/containerd/containerd.sock\ncontainerd_namespace: k8s.io\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	os.Setenv("DD_CONTAINERD_NAMESPACE", "moby")
+	defer os.Unsetenv("DD_CONTAINERD_NAMESPACE")
+	require.NoError(t, reloadConfig(f.Name()))
+	assert.Equal(t, "/run/containerd/containerd.sock", mockConfig.GetString("cri_socket_path"))
+	// The environment variables take precedence over the file
+	assert.Equal(t, "moby", mockConfig.GetString("containerd_namespace"))
+
+	// The settings removed from the file are reset
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("api_key: foo\n"), 0644))
+	require.NoError(t, reloadConfig(f.Name()))
+	assert.Equal(t, "", mockConfig.GetString("cri_socket_path"))
+}
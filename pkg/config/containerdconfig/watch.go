@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdconfig
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var configWatchOnce sync.Once
+
+func init() {
+	startConfigWatch = watchConfig
+}
+
+// watchConfig starts watching the containerd endpoint of the agent
+// configuration in the background, if containerd_config_watch_interval is set
+func watchConfig() {
+	interval := configWatchInterval()
+	if interval <= 0 {
+		return
+	}
+	configWatchOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				checkConfig()
+			}
+		}()
+	})
+}
+
+// checkConfig reloads the configuration file and hands its endpoint to the
+// containerd util, which reconnects if it changed
+func checkConfig() {
+	if err := reloadConfig(config.Datadog.ConfigFileUsed()); err != nil {
+		log.Debugf("Cannot reload the containerd settings: %s", err)
+		return
+	}
+	containerd.DefaultProvider().UpdateConfig(Options())
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxIncludeDepth bounds the nesting of the include directives
+const maxIncludeDepth = 8
+
+type includeDirective struct {
+	Include []string `yaml:"include"`
+}
+
+// mergeIncludes merges the files included by the main configuration file
+// into config, in the order returned by resolveIncludes
+func mergeIncludes(config Config) error {
+	mainFile := config.ConfigFileUsed()
+	if mainFile == "" {
+		return nil
+	}
+	files, err := resolveIncludes(mainFile)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("could not read included configuration file %s: %s", file, err)
+		}
+		if err := config.MergeConfig(bytes.NewReader(content)); err != nil {
+			return fmt.Errorf("could not merge included configuration file %s: %s", file, err)
+		}
+		log.Infof("Merged included configuration file %s", file)
+	}
+	return nil
+}
+
+// resolveIncludes returns the files to merge after file, following the
+// include directives depth-first. The paths of a directive are relative to
+// the file declaring it and can be glob patterns, their matches being
+// sorted lexically. A file is merged after the file including it, so that
+// site and host specific files can override a shared base. A file included
+// several times is only merged at its first occurrence.
+func resolveIncludes(file string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	if abs, err := filepath.Abs(file); err == nil {
+		seen[abs] = true
+	}
+	err := resolveIncludesRec(file, seen, &files, 0)
+	return files, err
+}
+
+func resolveIncludesRec(file string, seen map[string]bool, files *[]string, depth int) error {
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("too many nested include directives in %s, the maximum depth is %d", file, maxIncludeDepth)
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("could not read configuration file %s: %s", file, err)
+	}
+	var directive includeDirective
+	if err := yaml.Unmarshal(content, &directive); err != nil {
+		return fmt.Errorf("could not parse the include directive of %s: %s", file, err)
+	}
+
+	for _, pattern := range directive.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(file), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include pattern %q in %s: %s", pattern, file, err)
+		}
+		if len(matches) == 0 {
+			log.Warnf("The include pattern %q of %s matches no file", pattern, file)
+		}
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			abs, err := filepath.Abs(match)
+			if err != nil {
+				return err
+			}
+			if seen[abs] {
+				log.Debugf("Configuration file %s is already included, skipping it", abs)
+				continue
+			}
+			seen[abs] = true
+			*files = append(*files, abs)
+			if err := resolveIncludesRec(abs, seen, files, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
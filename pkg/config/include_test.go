@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestResolveIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "datadog.yaml"), `
+api_key: base
+include:
+  - datadog.d/site.yaml
+  - datadog.d/host-*.yaml
+  - datadog.d/missing.yaml
+`)
+	writeConfigFile(t, filepath.Join(dir, "datadog.d", "site.yaml"), `
+site: datadoghq.eu
+include:
+  - common/*.yaml
+`)
+	writeConfigFile(t, filepath.Join(dir, "datadog.d", "common", "tags.yaml"), "tags: [team:infra]\n")
+	writeConfigFile(t, filepath.Join(dir, "datadog.d", "host-b.yaml"), "hostname: b\n")
+	// Included twice, merged once
+	writeConfigFile(t, filepath.Join(dir, "datadog.d", "host-a.yaml"), "include: [site.yaml]\n")
+
+	files, err := resolveIncludes(filepath.Join(dir, "datadog.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "datadog.d", "site.yaml"),
+		filepath.Join(dir, "datadog.d", "common", "tags.yaml"),
+		filepath.Join(dir, "datadog.d", "host-a.yaml"),
+		filepath.Join(dir, "datadog.d", "host-b.yaml"),
+	}, files)
+}
+
+func TestResolveIncludesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// No include directive
+	writeConfigFile(t, filepath.Join(dir, "datadog.yaml"), "api_key: base\n")
+	files, err := resolveIncludes(filepath.Join(dir, "datadog.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// The main file including itself is not merged again
+	writeConfigFile(t, filepath.Join(dir, "datadog.yaml"), "include: [datadog.yaml]\n")
+	files, err = resolveIncludes(filepath.Join(dir, "datadog.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// Invalid included file
+	writeConfigFile(t, filepath.Join(dir, "datadog.yaml"), "include: [invalid.yaml]\n")
+	writeConfigFile(t, filepath.Join(dir, "invalid.yaml"), "include: {a: b}\n")
+	_, err = resolveIncludes(filepath.Join(dir, "datadog.yaml"))
+	assert.Error(t, err)
+}
@@ -75,7 +75,10 @@ func SetupLogger(logLevel, logFile, uri string, rfc, logToConsole, jsonFormat bo
 		seelogLogLevel = "warn"
 	}
 
-	configTemplate := fmt.Sprintf(`<seelog minlevel="%s">`, seelogLogLevel)
+	// The modules whose level is overridden are filtered by the agent logger,
+	// seelog lets their logs through
+	overrides := Datadog.GetStringMapString("log_level_overrides")
+	configTemplate := fmt.Sprintf(`<seelog minlevel="%s">`, log.LowestLevel(seelogLogLevel, overrides))
 
 	formatID := "common"
 	if jsonFormat {
@@ -121,6 +124,9 @@ func SetupLogger(logLevel, logFile, uri string, rfc, logToConsole, jsonFormat bo
 	seelog.ReplaceLogger(logger)
 
 	log.SetupDatadogLogger(logger, seelogLogLevel)
+	if err := log.SetModuleLevels(overrides); err != nil {
+		log.Warnf("Ignoring log_level_overrides: %s", err)
+	}
 	return nil
 }
 
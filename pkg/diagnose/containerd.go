@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package diagnose
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func init() {
+	diagnosis.Register("containerd availability", diagnoseContainerd)
+
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "containerd socket permissions",
+		Stage:       diagnosis.StageRuntime,
+		Run:         diagnoseContainerdSocket,
+		Remediation: "Run the agent as root or give the agent user read and write access to the containerd socket",
+	})
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "containerd socket reachable",
+		Stage:       diagnosis.StageRuntime,
+		Run:         diagnoseContainerdReachable,
+		Remediation: "Check that containerd is running and that its socket is mounted in the agent container, or set cri_socket_path to the containerd socket",
+	})
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "containerd namespace listable",
+		Stage:       diagnosis.StageRuntime,
+		Run:         diagnoseContainerdNamespace,
+		Remediation: "Set containerd_namespace to the namespace of your containers, k8s.io for Kubernetes",
+	})
+}
+
+// diagnoseContainerd checks in sequence that the containerd socket exists
+// and is usable by the agent, that the daemon answers on it with a supported
+// version and that the configured namespace exists. The returned error
+// carries a hint on how to fix the first failing check.
+func diagnoseContainerd() error {
+	o := containerd.DefaultProvider().ConfigOptions()
+	if err := containerd.CheckSocket(o.SocketPath); err != nil {
+		return err
+	}
+	log.Infof("containerd socket %s is usable by the agent", o.SocketPath)
+
+	c, err := containerd.NewContainerdUtil(o)
+	defer c.Close()
+	if err != nil {
+		return fmt.Errorf("%s, check that containerd is running and listening on this socket", err)
+	}
+
+	v, err := c.Metadata()
+	if err != nil {
+		return err
+	}
+	if err := containerd.CheckVersion(v.Version); err != nil {
+		return err
+	}
+	log.Infof("containerd %s (revision %s) is supported", v.Version, v.Revision)
+	if caps, err := c.Capabilities(); err == nil {
+		log.Infof("Capabilities of %s", caps)
+	}
+
+	if err := containerd.CheckNamespace(c); err != nil {
+		return fmt.Errorf("%s, set containerd_namespace to the namespace of your containers, k8s.io for Kubernetes", err)
+	}
+	log.Infof("containerd namespace %s exists", c.Namespace())
+	return nil
+}
+
+func diagnoseContainerdSocket() error {
+	return containerd.CheckSocket(containerd.DefaultProvider().ConfigOptions().SocketPath)
+}
+
+func diagnoseContainerdReachable() error {
+	c, err := containerd.NewContainerdUtil(containerd.DefaultProvider().ConfigOptions())
+	defer c.Close()
+	if err != nil {
+		return err
+	}
+	v, err := c.Metadata()
+	if err != nil {
+		return err
+	}
+	return containerd.CheckVersion(v.Version)
+}
+
+func diagnoseContainerdNamespace() error {
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil {
+		return err
+	}
+	if err := containerd.CheckNamespace(cu); err != nil {
+		return err
+	}
+	ctns, err := cu.Containers()
+	if err != nil {
+		return err
+	}
+	log.Infof("found %d containers in namespace %s", len(ctns), cu.Namespace())
+	return nil
+}
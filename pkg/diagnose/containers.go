@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package diagnose
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/cihub/seelog"
+	"github.com/fatih/color"
+)
+
+// RunContainers runs the containers diagnosis steps in sequence, output it in writer.
+// The remediation of the failed steps is printed after their status.
+func RunContainers(w io.Writer) error {
+	if w != color.Output {
+		color.NoColor = true
+	}
+
+	// Use temporarily a custom logger to our Writer
+	customLogger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(w, seelog.DebugLvl, "[%LEVEL] %FuncShort: %Msg - %Ns%n")
+	if err != nil {
+		return err
+	}
+	log.RegisterAdditionalLogger("diagnose", customLogger)
+	defer log.UnregisterAdditionalLogger("diagnose")
+
+	if len(diagnosis.ContainerSteps) == 0 {
+		fmt.Fprintln(w, "No container diagnosis available in this build")
+		return nil
+	}
+
+	passed := 0
+	for _, step := range diagnosis.ContainerSteps {
+		fmt.Fprintln(w, fmt.Sprintf("=== Running %s ===", color.BlueString(step.Name)))
+		if err := step.Run(); err != nil {
+			fmt.Fprintln(w, fmt.Sprintf("[ERROR] %s", err))
+			fmt.Fprintln(w, fmt.Sprintf("===> %s", color.RedString("FAIL")))
+			if step.Remediation != "" {
+				fmt.Fprintln(w, fmt.Sprintf("Hint: %s", step.Remediation))
+			}
+			fmt.Fprintln(w)
+			continue
+		}
+		passed++
+		fmt.Fprintln(w, fmt.Sprintf("===> %s\n", color.GreenString("PASS")))
+	}
+	fmt.Fprintln(w, fmt.Sprintf("%d/%d container diagnosis steps passed", passed, len(diagnosis.ContainerSteps)))
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package diagnose
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunContainers(t *testing.T) {
+	// The steps registered by the runtimes of the build are not run
+	defer func(steps []diagnosis.ContainerStep) { diagnosis.ContainerSteps = steps }(diagnosis.ContainerSteps)
+	diagnosis.ContainerSteps = nil
+
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "tagger",
+		Stage:       diagnosis.StageTagger,
+		Run:         func() error { return errors.New("empty") },
+		Remediation: "start the agent",
+	})
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:  "socket",
+		Stage: diagnosis.StageRuntime,
+		Run:   func() error { return nil },
+	})
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:  "permissions",
+		Stage: diagnosis.StageRuntime,
+		Run:   func() error { return nil },
+	})
+
+	w := &bytes.Buffer{}
+	RunContainers(w)
+
+	assert.Equal(t, "=== Running socket ===\n===> PASS\n\n"+
+		"=== Running permissions ===\n===> PASS\n\n"+
+		"=== Running tagger ===\n[ERROR] empty\n===> FAIL\nHint: start the agent\n\n"+
+		"2/3 container diagnosis steps passed\n", w.String())
+}
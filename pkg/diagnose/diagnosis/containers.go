@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package diagnosis
+
+import "sort"
+
+// Stage orders the steps of the containers diagnosis
+type Stage int
+
+// Stages of the containers diagnosis, run in this order
+const (
+	StageRuntime Stage = iota
+	StageStats
+	StageOrchestrator
+	StageTagger
+)
+
+// ContainerStep is a step of the containers diagnosis. Remediation
+// is printed when the step fails to guide the user to a fix.
+type ContainerStep struct {
+	Name        string
+	Stage       Stage
+	Run         Diagnosis
+	Remediation string
+}
+
+// ContainerSteps holds every compiled-in containers diagnosis step
+var ContainerSteps []ContainerStep
+
+// RegisterContainerStep registers a step that will be called on diagnose containers.
+// The steps of a stage are run in their registration order.
+func RegisterContainerStep(step ContainerStep) {
+	ContainerSteps = append(ContainerSteps, step)
+	sort.SliceStable(ContainerSteps, func(i, j int) bool {
+		return ContainerSteps[i].Stage < ContainerSteps[j].Stage
+	})
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"sync"
+)
+
+// deferredOrigin holds the packets of an origin waiting for its runtime tags
+type deferredOrigin struct {
+	packets [][]byte
+	// resolved is set once the tags of the origin are known, its packets
+	// are then handled by the worker draining it
+	resolved bool
+	tags     []string
+}
+
+// deferredPackets holds the packets of the origins whose runtime tags are
+// pending, see tagger.Defer. The packets of an origin are handled by the
+// workers in their order of arrival once its tags are resolved, the packets
+// received meanwhile are queued behind them. The resolutions never block,
+// so that the tagger is not held by a stopped server.
+type deferredPackets struct {
+	mu      sync.RWMutex
+	origins map[string]*deferredOrigin
+	// ready lists the resolved origins to drain, signaled on readyC
+	ready  []string
+	readyC chan struct{}
+	// held is the number of packets held, at most max
+	held int
+	max  int
+}
+
+func newDeferredPackets(max int) *deferredPackets {
+	return &deferredPackets{
+		origins: make(map[string]*deferredOrigin),
+		readyC:  make(chan struct{}, 1),
+		max:     max,
+	}
+}
+
+// add holds the contents of a packet of an origin if the origin is held
+// already or if pending returns true. It returns whether the packet was
+// taken, and whether it is the first of the origin, whose tags are then
+// awaited. Once max packets are held, the packets of the held origins are
+// taken and dropped, they would overtake the held ones otherwise.
+func (d *deferredPackets) add(origin string, contents []byte, pending func(origin string) bool) (bool, bool) {
+	d.mu.RLock()
+	_, found := d.origins[origin]
+	d.mu.RUnlock()
+	if !found && !pending(origin) {
+		return false, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	o, found := d.origins[origin]
+	if d.held >= d.max {
+		if found {
+			dogstatsdDeferredPacketsDropped.Add(1)
+		}
+		return found, false
+	}
+	if !found {
+		o = &deferredOrigin{}
+		d.origins[origin] = o
+	}
+	// The packet is returned to the pool by the caller
+	o.packets = append(o.packets, append([]byte(nil), contents...))
+	d.held++
+	return true, !found
+}
+
+// resolve marks an origin resolved with its tags, and signals the workers
+func (d *deferredPackets) resolve(origin string, tags []string) {
+	d.mu.Lock()
+	if o, found := d.origins[origin]; found && !o.resolved {
+		o.resolved = true
+		o.tags = tags
+		d.ready = append(d.ready, origin)
+	}
+	d.mu.Unlock()
+
+	select {
+	case d.readyC <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next resolved origin to drain
+func (d *deferredPackets) next() (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.ready) == 0 {
+		return "", false
+	}
+	origin := d.ready[0]
+	d.ready = d.ready[1:]
+	return origin, true
+}
+
+// take returns the packets of a resolved origin with its tags, the origin
+// is forgotten once it holds no packet
+func (d *deferredPackets) take(origin string) ([][]byte, []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	o, found := d.origins[origin]
+	if !found {
+		return nil, nil
+	}
+	packets := o.packets
+	o.packets = nil
+	d.held -= len(packets)
+	if len(packets) == 0 {
+		delete(d.origins, origin)
+	}
+	return packets, o.tags
+}
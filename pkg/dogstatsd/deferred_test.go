@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeferredPackets(t *testing.T) {
+	d := newDeferredPackets(3)
+	pending := map[string]bool{"container_id://abcd": true}
+	isPending := func(origin string) bool { return pending[origin] }
+
+	// The origins whose tags are known are not held
+	held, _ := d.add("container_id://ef01", []byte("a:1|c"), isPending)
+	assert.False(t, held)
+
+	held, first := d.add("container_id://abcd", []byte("a:1|c"), isPending)
+	assert.True(t, held)
+	assert.True(t, first)
+	// The packets of a held origin are queued behind, even if its tags are
+	// known meanwhile
+	pending["container_id://abcd"] = false
+	held, first = d.add("container_id://abcd", []byte("a:2|c"), isPending)
+	assert.True(t, held)
+	assert.False(t, first)
+
+	_, ready := d.next()
+	assert.False(t, ready)
+	d.resolve("container_id://abcd", []string{"image_name:redis"})
+	<-d.readyC
+	origin, ready := d.next()
+	assert.True(t, ready)
+	assert.Equal(t, "container_id://abcd", origin)
+
+	// The packets received while draining are handled after the others
+	packets, tags := d.take(origin)
+	assert.Equal(t, [][]byte{[]byte("a:1|c"), []byte("a:2|c")}, packets)
+	assert.Equal(t, []string{"image_name:redis"}, tags)
+	held, _ = d.add("container_id://abcd", []byte("a:3|c"), isPending)
+	assert.True(t, held)
+	packets, _ = d.take(origin)
+	assert.Equal(t, [][]byte{[]byte("a:3|c")}, packets)
+	packets, _ = d.take(origin)
+	assert.Empty(t, packets)
+	assert.Empty(t, d.origins)
+	assert.Equal(t, 0, d.held)
+
+	// At most max packets are held
+	pending["container_id://2345"] = true
+	for i := 0; i < 3; i++ {
+		held, _ = d.add("container_id://2345", []byte("a:1|c"), isPending)
+		assert.True(t, held)
+	}
+	// The packets of the held origins are dropped then, not to overtake
+	// the held ones
+	dropped := dogstatsdDeferredPacketsDropped.Value()
+	held, first = d.add("container_id://2345", []byte("a:2|c"), isPending)
+	assert.True(t, held)
+	assert.False(t, first)
+	assert.Equal(t, dropped+1, dogstatsdDeferredPacketsDropped.Value())
+	// The packets of the other origins are handled right away
+	pending["container_id://6789"] = true
+	held, _ = d.add("container_id://6789", []byte("a:1|c"), isPending)
+	assert.False(t, held)
+	assert.Equal(t, dropped+1, dogstatsdDeferredPacketsDropped.Value())
+
+	d.resolve("container_id://2345", nil)
+	packets, _ = d.take("container_id://2345")
+	assert.Equal(t, [][]byte{[]byte("a:1|c"), []byte("a:1|c"), []byte("a:1|c")}, packets)
+	assert.Equal(t, 0, d.held)
+}
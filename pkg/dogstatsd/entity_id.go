@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// entityIDTagPrefix prefixes the tag set by the client libraries from the
+// DD_ENTITY_ID environment variable, the UID of the pod they run in, when
+// origin detection is not available
+const entityIDTagPrefix = "dd.internal.entity_id:"
+
+// getEntityTags is overridden in tests
+var getEntityTags = tagger.Tag
+
+// resolveEntityID replaces the entity ID tag of a message by the tags of
+// the pod it designates. The tags are kept as is if there is no entity ID.
+func resolveEntityID(tags []string) []string {
+	for i, tag := range tags {
+		if !strings.HasPrefix(tag, entityIDTagPrefix) {
+			continue
+		}
+		// The internal tag is never forwarded to the backend
+		tags = append(tags[:i], tags[i+1:]...)
+
+		entity := kubelet.PodUIDToEntityName(tag[len(entityIDTagPrefix):])
+		if entity == "" {
+			return tags
+		}
+		entityTags, err := getEntityTags(entity, tagger.IsFullCardinality())
+		if err != nil {
+			log.Tracef("Cannot get tags for entity %s: %s", entity, err)
+			return tags
+		}
+		return append(tags, entityTags...)
+	}
+	return tags
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEntityID(t *testing.T) {
+	defer func(f func(string, bool) ([]string, error)) { getEntityTags = f }(getEntityTags)
+	getEntityTags = func(entity string, highCard bool) ([]string, error) {
+		if entity == "kubernetes_pod://f3fb6a6a-9d51-11e8-8b6e-42010a840061" {
+			return []string{"pod_name:redis-0", "kube_namespace:default"}, nil
+		}
+		return nil, fmt.Errorf("unknown entity %s", entity)
+	}
+
+	for name, tc := range map[string]struct {
+		tags     []string
+		expected []string
+	}{
+		"no entity id": {
+			tags:     []string{"env:prod"},
+			expected: []string{"env:prod"},
+		},
+		"pod tags": {
+			tags:     []string{"env:prod", "dd.internal.entity_id:f3fb6a6a-9d51-11e8-8b6e-42010a840061", "version:1"},
+			expected: []string{"env:prod", "version:1", "pod_name:redis-0", "kube_namespace:default"},
+		},
+		"unknown pod": {
+			tags:     []string{"dd.internal.entity_id:abcd", "env:prod"},
+			expected: []string{"env:prod"},
+		},
+		"empty entity id": {
+			tags:     []string{"dd.internal.entity_id:"},
+			expected: []string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, resolveEntityID(tc.tags))
+		})
+	}
+}
+
+func TestParseMetricMessageWithEntityID(t *testing.T) {
+	defer func(f func(string, bool) ([]string, error)) { getEntityTags = f }(getEntityTags)
+	getEntityTags = func(entity string, highCard bool) ([]string, error) {
+		return []string{"pod_name:redis-0"}, nil
+	}
+
+	sample, err := parseMetricMessage([]byte("daemon:666|g|#sometag,dd.internal.entity_id:abcd"), "", "default-hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sometag", "pod_name:redis-0"}, resolveEntityID(sample.Tags))
+}
@@ -14,6 +14,7 @@ import (
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/socket"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 )
@@ -66,13 +67,13 @@ func NewUDSListener(packetOut chan *Packet, packetPool *PacketPool) (*UDSListene
 		}
 	}
 
-	conn, err := net.ListenUnixgram("unixgram", address)
+	perms, err := socket.PermissionsFromConfig("dogstatsd_socket")
 	if err != nil {
-		return nil, fmt.Errorf("can't listen: %s", err)
+		return nil, fmt.Errorf("dogstatsd-uds: %s", err)
 	}
-	err = os.Chmod(socketPath, 0722)
+	conn, err := socket.ListenUnixgram(address.Name, perms)
 	if err != nil {
-		return nil, fmt.Errorf("can't set the socket at write only: %s", err)
+		return nil, fmt.Errorf("can't listen: %s", err)
 	}
 
 	if originDetection {
@@ -81,7 +82,7 @@ func NewUDSListener(packetOut chan *Packet, packetPool *PacketPool) (*UDSListene
 			log.Errorf("dogstatsd-uds: error enabling origin detection: %s", err)
 			originDetection = false
 		} else {
-			log.Debugf("dogstatsd-uds: enabling origin detection on %s", conn.LocalAddr())
+			log.Debugf("dogstatsd-uds: enabling origin detection on %s", socketPath)
 
 		}
 	}
@@ -108,13 +109,13 @@ func NewUDSListener(packetOut chan *Packet, packetPool *PacketPool) (*UDSListene
 		}
 	}
 
-	log.Debugf("dogstatsd-uds: %s successfully initialized", conn.LocalAddr())
+	log.Debugf("dogstatsd-uds: %s successfully initialized", socketPath)
 	return listener, nil
 }
 
 // Listen runs the intake loop. Should be called in its own goroutine
 func (l *UDSListener) Listen() {
-	log.Infof("dogstatsd-uds: starting to listen on %s", l.conn.LocalAddr())
+	log.Infof("dogstatsd-uds: starting to listen on %s", config.Datadog.GetString("dogstatsd_socket"))
 	for {
 		var n int
 		var err error
@@ -68,6 +68,16 @@ func TestNewUDSListener(t *testing.T) {
 	t.Run("working", func(tt *testing.T) {
 		testWorkingNewUDSListener(tt, socketPath)
 	})
+	t.Run("custom_mode", func(tt *testing.T) {
+		mockConfig.Set("dogstatsd_socket_mode", "0720")
+		defer mockConfig.Set("dogstatsd_socket_mode", "0722")
+		s, err := NewUDSListener(nil, packetPoolUDS)
+		require.Nil(tt, err)
+		defer s.Stop()
+		fi, err := os.Stat(socketPath)
+		require.Nil(tt, err)
+		assert.Equal(tt, "Srwx-w----", fi.Mode().String())
+	})
 }
 
 func TestStartStopUDSListener(t *testing.T) {
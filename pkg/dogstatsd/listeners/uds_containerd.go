@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package listeners
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func init() {
+	entityForPIDFallback = containerdEntityForPID
+}
+
+// containerdEntityForPID resolves the containerd container of a PID from its
+// cgroups, for containers whose shim or cgroups don't follow the known formats
+func containerdEntityForPID(pid int32) (string, error) {
+	entity, err := containerdEntityForCgroups(int(pid))
+	if err != nil {
+		log.Debugf("Cannot resolve the containerd container of PID %d: %s", pid, err)
+		return "", containers.ErrNoContainerMatch
+	}
+	if entity == "" {
+		return "", containers.ErrNoContainerMatch
+	}
+	return entity, nil
+}
+
+// containerdEntityForCgroups returns the tagger entity (container_id://<id>)
+// of the containerd container a process runs in. The container ID is parsed
+// from the cgroups of the process, then checked against containerd. When no
+// ID can be parsed, the cgroups are matched against the ones of the
+// containerd containers. It returns an empty entity for processes running on
+// the host.
+func containerdEntityForCgroups(pid int) (string, error) {
+	containerID, err := metrics.ContainerIDForPID(pid)
+	if err != nil {
+		return "", err
+	}
+	if containerID == "" {
+		containerID, err = containerdIDForCgroups(pid)
+		if err != nil || containerID == "" {
+			return "", err
+		}
+	}
+	if _, err := containerd.Resolve(containerID); err != nil {
+		return "", err
+	}
+	return containerd.EntityID(containerID), nil
+}
+
+// containerdIDForCgroups matches the cgroups of a process against the ones
+// of the containerd containers
+func containerdIDForCgroups(pid int) (string, error) {
+	paths, err := metrics.CgroupPathsForPID(pid)
+	if err != nil {
+		return "", err
+	}
+	// The memory and pids controllers are always set by the OCI runtimes
+	for _, controller := range []string{"memory", "pids"} {
+		cgroupPath, found := paths[controller]
+		if !found || cgroupPath == "/" {
+			continue
+		}
+		id, err := containerd.ContainerIDForCgroup(cgroupPath)
+		if err != nil || id != "" {
+			return id, err
+		}
+	}
+	return "", nil
+}
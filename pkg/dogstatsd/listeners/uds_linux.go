@@ -22,6 +22,11 @@ const (
 	pidToEntityCacheDuration  = time.Minute
 )
 
+// entityForPIDFallback is used when the container runtime of a PID
+// can't be detected from its parent processes, it is set by the
+// runtime specific origin detection files if they are compiled in.
+var entityForPIDFallback func(pid int32) (string, error)
+
 // getUDSAncillarySize gets the needed buffer size to retrieve the ancillary data
 // from the out of band channel. We only get the header + 1 credentials struct
 // and discard any information added by the sender.
@@ -81,6 +86,9 @@ func getEntityForPID(pid int32) (string, error) {
 	}
 
 	entity, err := containers.EntityForPID(pid)
+	if (err == containers.ErrNoRuntimeMatch || err == containers.ErrNoContainerMatch) && entityForPIDFallback != nil {
+		entity, err = entityForPIDFallback(pid)
+	}
 	switch err {
 	case nil:
 		// No error, yay!
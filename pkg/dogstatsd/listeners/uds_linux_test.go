@@ -20,6 +20,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"golang.org/x/sys/unix"
 )
 
@@ -49,3 +50,27 @@ func TestUDSPassCred(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, enabled, 1)
 }
+
+func TestEntityForPIDFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dd-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir) // clean up
+	mockConfig := config.Mock()
+	mockConfig.Set("container_proc_root", dir)
+
+	defer func(f func(int32) (string, error)) { entityForPIDFallback = f }(entityForPIDFallback)
+	entityForPIDFallback = func(pid int32) (string, error) {
+		if pid == 4242 {
+			return "containerd://foo", nil
+		}
+		return "", containers.ErrNoContainerMatch
+	}
+
+	entity, err := getEntityForPID(4242)
+	assert.Nil(t, err)
+	assert.Equal(t, "containerd://foo", entity)
+
+	entity, err = getEntityForPID(4243)
+	assert.Nil(t, err)
+	assert.Equal(t, NoOrigin, entity)
+}
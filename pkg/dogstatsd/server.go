@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -31,6 +32,7 @@ var (
 	dogstatsdEventPackets            = expvar.Int{}
 	dogstatsdMetricParseErrors       = expvar.Int{}
 	dogstatsdMetricPackets           = expvar.Int{}
+	dogstatsdDeferredPacketsDropped  = expvar.Int{}
 )
 
 func init() {
@@ -40,6 +42,7 @@ func init() {
 	dogstatsdExpvars.Set("EventPackets", &dogstatsdEventPackets)
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
+	dogstatsdExpvars.Set("DeferredPacketsDropped", &dogstatsdDeferredPacketsDropped)
 }
 
 // Server represent a Dogstatsd server
@@ -56,6 +59,9 @@ type Server struct {
 	histToDist       bool
 	histToDistPrefix string
 	extraTags        []string
+	// deferred holds the packets of the new containers until their tags
+	// are known
+	deferred *deferredPackets
 }
 
 // NewServer returns a running Dogstatsd server
@@ -125,6 +131,7 @@ func NewServer(metricOut chan<- *metrics.MetricSample, eventOut chan<- metrics.E
 		histToDist:       histToDist,
 		histToDistPrefix: histToDistPrefix,
 		extraTags:        extraTags,
+		deferred:         newDeferredPackets(config.Datadog.GetInt("tagger_deferral_max_pending")),
 	}
 
 	forwardHost := config.Datadog.GetString("statsd_forward_host")
@@ -132,7 +139,7 @@ func NewServer(metricOut chan<- *metrics.MetricSample, eventOut chan<- metrics.E
 
 	if forwardHost != "" && forwardPort != 0 {
 
-		forwardAddress := fmt.Sprintf("%s:%d", forwardHost, forwardPort)
+		forwardAddress := net.JoinHostPort(forwardHost, strconv.Itoa(forwardPort))
 
 		con, err := net.Dial("udp", forwardAddress)
 
@@ -193,82 +200,136 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 		case <-s.stopChan:
 			return
 		case <-s.health.C:
+		case <-s.deferred.readyC:
+			s.handleDeferred(metricOut, eventOut, serviceCheckOut)
 		case packet := <-s.packetIn:
-			extraTags := s.extraTags
-
-			if packet.Origin != listeners.NoOrigin {
-				log.Tracef("Dogstatsd receive from %s: %s", packet.Origin, packet.Contents)
-				originTags, err := tagger.Tag(packet.Origin, tagger.IsFullCardinality())
-				if err != nil {
-					log.Errorf(err.Error())
-				} else {
-					extraTags = append(extraTags, originTags...)
-				}
-				log.Tracef("Tags for %s: %s", packet.Origin, originTags)
-			} else {
+			if packet.Origin == listeners.NoOrigin {
 				log.Tracef("Dogstatsd receive: %s", packet.Contents)
+				s.handlePacket(packet.Contents, s.extraTags, metricOut, eventOut, serviceCheckOut)
+				// Return the packet object back to the object pool for reuse
+				s.packetPool.Put(packet)
+				continue
 			}
 
-			for {
-				message := nextMessage(&packet.Contents)
-				if message == nil {
-					break
-				}
-
-				if s.Statistics != nil {
-					s.Statistics.StatEvent(1)
+			log.Tracef("Dogstatsd receive from %s: %s", packet.Origin, packet.Contents)
+			origin := packet.Origin
+			// The packets of a new container are handled once its tags are
+			// known, their contents are copied to return the packet to the pool
+			if taken, first := s.deferred.add(origin, packet.Contents, tagger.RuntimeTagsPending); taken {
+				s.packetPool.Put(packet)
+				if first {
+					tagger.Defer(origin, tagger.IsFullCardinality(), func(originTags []string) {
+						s.deferred.resolve(origin, originTags)
+					})
 				}
+				continue
+			}
 
-				if bytes.HasPrefix(message, []byte("_sc")) {
-					serviceCheck, err := parseServiceCheckMessage(message, s.defaultHostname)
-					if err != nil {
-						log.Errorf("Dogstatsd: error parsing service check: %s", err)
-						dogstatsdServiceCheckParseErrors.Add(1)
-						continue
-					}
-					if len(extraTags) > 0 {
-						serviceCheck.Tags = append(serviceCheck.Tags, extraTags...)
-					}
-					dogstatsdServiceCheckPackets.Add(1)
-					serviceCheckOut <- *serviceCheck
-				} else if bytes.HasPrefix(message, []byte("_e")) {
-					event, err := parseEventMessage(message, s.defaultHostname)
-					if err != nil {
-						log.Errorf("Dogstatsd: error parsing event: %s", err)
-						dogstatsdEventParseErrors.Add(1)
-						continue
-					}
-					if len(extraTags) > 0 {
-						event.Tags = append(event.Tags, extraTags...)
-					}
-					dogstatsdEventPackets.Add(1)
-					eventOut <- *event
-				} else {
-					sample, err := parseMetricMessage(message, s.metricPrefix, s.defaultHostname)
-					if err != nil {
-						log.Errorf("Dogstatsd: error parsing metrics: %s", err)
-						dogstatsdMetricParseErrors.Add(1)
-						continue
-					}
-					if len(extraTags) > 0 {
-						sample.Tags = append(sample.Tags, extraTags...)
-					}
-					dogstatsdMetricPackets.Add(1)
-					metricOut <- sample
-					if s.histToDist && sample.Mtype == metrics.HistogramType {
-						distSample := sample.Copy()
-						distSample.Name = s.histToDistPrefix + distSample.Name
-						distSample.Mtype = metrics.DistributionType
-						metricOut <- distSample
-					}
-				}
+			originTags, err := tagger.Tag(origin, tagger.IsFullCardinality())
+			if err != nil {
+				log.Errorf(err.Error())
 			}
-			// Return the packet object back to the object pool for reuse
+			log.Tracef("Tags for %s: %s", origin, originTags)
+			s.handlePacket(packet.Contents, s.originExtraTags(originTags), metricOut, eventOut, serviceCheckOut)
 			s.packetPool.Put(packet)
 		}
 	}
 }
 
+// handleDeferred handles the packets of the origins whose tags were resolved,
+// in their order of arrival
+func (s *Server) handleDeferred(metricOut chan<- *metrics.MetricSample, eventOut chan<- metrics.Event, serviceCheckOut chan<- metrics.ServiceCheck) {
+	for {
+		origin, ok := s.deferred.next()
+		if !ok {
+			return
+		}
+		for {
+			packets, originTags := s.deferred.take(origin)
+			if len(packets) == 0 {
+				break
+			}
+			log.Tracef("Tags for %s: %s", origin, originTags)
+			extraTags := s.originExtraTags(originTags)
+			for _, contents := range packets {
+				s.handlePacket(contents, extraTags, metricOut, eventOut, serviceCheckOut)
+			}
+		}
+	}
+}
+
+// originExtraTags returns the tags added to the messages of an origin
+func (s *Server) originExtraTags(originTags []string) []string {
+	if len(originTags) == 0 {
+		return s.extraTags
+	}
+	extraTags := make([]string, 0, len(s.extraTags)+len(originTags))
+	extraTags = append(extraTags, s.extraTags...)
+	return append(extraTags, originTags...)
+}
+
+// handlePacket parses the messages of a packet and sends them with the
+// extra tags
+func (s *Server) handlePacket(contents []byte, extraTags []string, metricOut chan<- *metrics.MetricSample, eventOut chan<- metrics.Event, serviceCheckOut chan<- metrics.ServiceCheck) {
+	for {
+		message := nextMessage(&contents)
+		if message == nil {
+			break
+		}
+
+		if s.Statistics != nil {
+			s.Statistics.StatEvent(1)
+		}
+
+		if bytes.HasPrefix(message, []byte("_sc")) {
+			serviceCheck, err := parseServiceCheckMessage(message, s.defaultHostname)
+			if err != nil {
+				log.Errorf("Dogstatsd: error parsing service check: %s", err)
+				dogstatsdServiceCheckParseErrors.Add(1)
+				continue
+			}
+			serviceCheck.Tags = resolveEntityID(serviceCheck.Tags)
+			if len(extraTags) > 0 {
+				serviceCheck.Tags = append(serviceCheck.Tags, extraTags...)
+			}
+			dogstatsdServiceCheckPackets.Add(1)
+			serviceCheckOut <- *serviceCheck
+		} else if bytes.HasPrefix(message, []byte("_e")) {
+			event, err := parseEventMessage(message, s.defaultHostname)
+			if err != nil {
+				log.Errorf("Dogstatsd: error parsing event: %s", err)
+				dogstatsdEventParseErrors.Add(1)
+				continue
+			}
+			event.Tags = resolveEntityID(event.Tags)
+			if len(extraTags) > 0 {
+				event.Tags = append(event.Tags, extraTags...)
+			}
+			dogstatsdEventPackets.Add(1)
+			eventOut <- *event
+		} else {
+			sample, err := parseMetricMessage(message, s.metricPrefix, s.defaultHostname)
+			if err != nil {
+				log.Errorf("Dogstatsd: error parsing metrics: %s", err)
+				dogstatsdMetricParseErrors.Add(1)
+				continue
+			}
+			sample.Tags = resolveEntityID(sample.Tags)
+			if len(extraTags) > 0 {
+				sample.Tags = append(sample.Tags, extraTags...)
+			}
+			dogstatsdMetricPackets.Add(1)
+			metricOut <- sample
+			if s.histToDist && sample.Mtype == metrics.HistogramType {
+				distSample := sample.Copy()
+				distSample.Name = s.histToDistPrefix + distSample.Name
+				distSample.Mtype = metrics.DistributionType
+				metricOut <- distSample
+			}
+		}
+	}
+}
+
 // Stop stops a running Dogstatsd server
 func (s *Server) Stop() {
 	close(s.stopChan)
@@ -186,6 +186,16 @@ func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths,
 		log.Errorf("Could not zip docker ps: %s", err)
 	}
 
+	err = zipContainerd(tempDir, hostname)
+	if err != nil {
+		log.Errorf("Could not zip containerd diagnostics: %s", err)
+	}
+
+	err = zipContainerdGRPCCalls(tempDir, hostname)
+	if err != nil {
+		log.Errorf("Could not zip containerd gRPC calls: %s", err)
+	}
+
 	err = zipTypeperfData(tempDir, hostname)
 	if err != nil {
 		log.Errorf("Could not write typeperf data: %s", err)
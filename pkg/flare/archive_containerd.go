@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package flare
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+// zipContainerd writes the state of the containerd runtime: the resolved
+// socket path, the daemon version, the containers per namespace, the last
+// events and connection attempts of the agent
+func zipContainerd(tempDir, hostname string) error {
+	d := containerd.GetDiagnostics()
+
+	var output bytes.Buffer
+	fmt.Fprintf(&output, "Socket path: %s\n", d.SocketPath)
+	if d.Err != "" {
+		fmt.Fprintf(&output, "Error: %s\n", d.Err)
+	} else {
+		fmt.Fprintf(&output, "Version: %s (revision %s)\n", d.Version, d.Revision)
+	}
+
+	w := tabwriter.NewWriter(&output, 20, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "\n=== Namespaces ===")
+	fmt.Fprintln(w, "NAMESPACE\tCONTAINERS\tERROR\t")
+	for _, ns := range d.Namespaces {
+		fmt.Fprintf(w, "%s\t%d\t%s\t\n", ns.Name, ns.Containers, ns.Err)
+	}
+
+	fmt.Fprintf(w, "\n=== Last %d events ===\n", containerd.FlareEventHistory)
+	fmt.Fprintln(w, "TIMESTAMP\tNAMESPACE\tTOPIC\t")
+	for _, ev := range containerd.RecordedEvents() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", ev.Timestamp.Format(time.RFC3339Nano), ev.Namespace, ev.Topic)
+	}
+
+	fmt.Fprintln(w, "\n=== Connection attempts ===")
+	fmt.Fprintln(w, "START\tSOCKET\tNAMESPACE\tDURATION\tERROR\t")
+	for _, a := range containerd.ConnectionAttempts() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n",
+			a.Start.Format(time.RFC3339Nano), a.SocketPath, a.Namespace, a.Duration, a.Err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	f := filepath.Join(tempDir, hostname, "containerd.log")
+	file, err := NewRedactingWriter(f, os.ModePerm, false)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(output.Bytes())
+	return err
+}
+
+// zipContainerdGRPCCalls writes the last gRPC calls made to containerd,
+// recorded if containerd_debug_grpc is enabled
+func zipContainerdGRPCCalls(tempDir, hostname string) error {
+	calls := containerd.GRPCCalls()
+	if len(calls) == 0 {
+		return nil
+	}
+
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 20, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "START\tMETHOD\tNAMESPACE\tDURATION\tERROR\t")
+	for _, c := range calls {
+		method := c.Method
+		if c.Stream {
+			method += " (stream)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n",
+			c.Start.Format(time.RFC3339Nano), method, c.Namespace, c.Duration, c.Err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	f := filepath.Join(tempDir, hostname, "containerd_grpc_calls.log")
+	file, err := NewRedactingWriter(f, os.ModePerm, false)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(output.Bytes())
+	return err
+}
@@ -0,0 +1,16 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !containerd
+
+package flare
+
+func zipContainerd(tempDir, hostname string) error {
+	return nil
+}
+
+func zipContainerdGRPCCalls(tempDir, hostname string) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// checkpointVersion is the version of the checkpoint format, the checkpoints
+// of other versions are discarded
+const checkpointVersion = 1
+
+// checkpointFileName is the name of the forwarder checkpoint in checkpoint_path
+const checkpointFileName = "forwarder.json"
+
+// forwarderCheckpoint holds the transactions not flushed when the forwarder
+// stopped, they are retried by the next agent process
+type forwarderCheckpoint struct {
+	Version      int                     `json:"version"`
+	AgentVersion string                  `json:"agent_version"`
+	Transactions []transactionCheckpoint `json:"transactions"`
+}
+
+type transactionCheckpoint struct {
+	// ConfigDomain is the configured domain of the transaction, the domain
+	// forwarders are keyed by a domain including the agent version
+	ConfigDomain string              `json:"config_domain"`
+	Endpoint     string              `json:"endpoint"`
+	Headers      http.Header         `json:"headers"`
+	Payload      []byte              `json:"payload"`
+	ErrorCount   int                 `json:"error_count"`
+	Priority     TransactionPriority `json:"priority"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+// checkpointPath returns the path of the forwarder checkpoint, or an
+// empty string if checkpoint_on_shutdown is disabled
+func checkpointPath() string {
+	if !config.Datadog.GetBool("checkpoint_on_shutdown") {
+		return ""
+	}
+	return filepath.Join(config.Datadog.GetString("checkpoint_path"), checkpointFileName)
+}
+
+// writeCheckpoint writes the pending transactions of every domain to path
+func (f *DefaultForwarder) writeCheckpoint(path string, pending map[string][]Transaction) error {
+	cp := forwarderCheckpoint{Version: checkpointVersion, AgentVersion: version.AgentVersion}
+	for domain, transactions := range pending {
+		for _, t := range transactions {
+			httpTransaction, ok := t.(*HTTPTransaction)
+			if !ok || httpTransaction.Payload == nil {
+				continue
+			}
+			cp.Transactions = append(cp.Transactions, transactionCheckpoint{
+				ConfigDomain: f.configDomains[domain],
+				Endpoint:     httpTransaction.Endpoint,
+				Headers:      httpTransaction.Headers,
+				Payload:      *httpTransaction.Payload,
+				ErrorCount:   httpTransaction.ErrorCount,
+				Priority:     httpTransaction.Priority,
+				CreatedAt:    httpTransaction.createdAt,
+			})
+		}
+	}
+	if len(cp.Transactions) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// The transactions hold the API keys
+	if err := util.WriteFileAtomic(path, content, 0600); err != nil {
+		return fmt.Errorf("cannot write the forwarder checkpoint: %s", err)
+	}
+	log.Infof("Wrote %d pending transactions to %s", len(cp.Transactions), path)
+	return nil
+}
+
+// restoreCheckpoint queues the transactions written by a previous process
+// for retry, the checkpoint is removed so that they are never sent twice.
+// The domain forwarders must be started.
+func (f *DefaultForwarder) restoreCheckpoint(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("cannot remove the forwarder checkpoint, it is not restored: %s", err)
+	}
+
+	var cp forwarderCheckpoint
+	if err := json.Unmarshal(content, &cp); err != nil {
+		return fmt.Errorf("cannot decode the forwarder checkpoint: %s", err)
+	}
+	if cp.Version != checkpointVersion {
+		return fmt.Errorf("the forwarder checkpoint written by agent %s has version %d, %d is expected", cp.AgentVersion, cp.Version, checkpointVersion)
+	}
+
+	domains := make(map[string]string, len(f.configDomains))
+	for domain, configDomain := range f.configDomains {
+		domains[configDomain] = domain
+	}
+	restored := 0
+	for _, tc := range cp.Transactions {
+		df, found := f.domainForwarders[domains[tc.ConfigDomain]]
+		if !found {
+			continue
+		}
+		payload := tc.Payload
+		t := NewHTTPTransaction()
+		t.Domain = domains[tc.ConfigDomain]
+		t.Endpoint = tc.Endpoint
+		t.Headers = tc.Headers
+		t.Payload = &payload
+		t.ErrorCount = tc.ErrorCount
+		t.Priority = tc.Priority
+		t.createdAt = tc.CreatedAt
+		df.requeuedTransaction <- t
+		restored++
+	}
+	if dropped := len(cp.Transactions) - restored; dropped > 0 {
+		log.Warnf("Dropped %d transactions of the forwarder checkpoint whose domain is not configured anymore", dropped)
+	}
+	log.Infof("Restored %d pending transactions written by agent %s", restored, cp.AgentVersion)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func readCheckpoint(t *testing.T, path string) forwarderCheckpoint {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var cp forwarderCheckpoint
+	require.NoError(t, json.Unmarshal(content, &cp))
+	return cp
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forwarder-checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mockConfig := config.Mock()
+	mockConfig.Set("checkpoint_on_shutdown", true)
+	mockConfig.Set("checkpoint_path", dir)
+	path := filepath.Join(dir, checkpointFileName)
+
+	payload := []byte(`{"events":{}}`)
+	forwarder := NewDefaultForwarder(map[string][]string{testDomain: {"api-key-1"}})
+	require.NoError(t, forwarder.Start())
+	transactions := forwarder.createHTTPTransactions(eventsEndpoint, Payloads{&payload}, false, nil)
+	require.Len(t, transactions, 1)
+	forwarder.domainForwarders[testVersionDomain].requeuedTransaction <- transactions[0]
+	forwarder.Stop()
+
+	cp := readCheckpoint(t, path)
+	assert.Equal(t, checkpointVersion, cp.Version)
+	require.Len(t, cp.Transactions, 1)
+	assert.Equal(t, testDomain, cp.Transactions[0].ConfigDomain)
+	assert.Equal(t, eventsEndpoint, cp.Transactions[0].Endpoint)
+	assert.Equal(t, "api-key-1", cp.Transactions[0].Headers.Get(apiHTTPHeaderKey))
+	assert.Equal(t, payload, cp.Transactions[0].Payload)
+	assert.Equal(t, PriorityAlerts, cp.Transactions[0].Priority)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// The next forwarder retries the transaction, and checkpoints it
+	// again if it could not send it before stopping
+	next := NewDefaultForwarder(map[string][]string{testDomain: {"api-key-1"}})
+	require.NoError(t, next.Start())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	next.Stop()
+	assert.Equal(t, cp, readCheckpoint(t, path))
+}
+
+func TestRestoreCheckpointOtherVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forwarder-checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, checkpointFileName)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 2, "transactions": [{"config_domain": "http://app.datadoghq.com"}]}`), 0600))
+
+	forwarder := NewDefaultForwarder(monoKeysDomains)
+	require.NoError(t, forwarder.Start())
+	defer forwarder.Stop()
+	assert.Error(t, forwarder.restoreCheckpoint(path))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The intake rejects the points timestamped outside of this window around its own clock
+const (
+	maxPointFutureSkew = 10 * time.Minute
+	maxPointPastSkew   = 1 * time.Hour
+)
+
+var (
+	// clockSkew is the offset in seconds of the host clock to the
+	// intake clock, read from the Date header of the responses
+	clockSkew = expvar.Float{}
+	// clockSkewWarning is set when the skew causes points to be rejected
+	clockSkewWarning = expvar.String{}
+)
+
+func initClockSkewExpvars() {
+	forwarderExpvars.Set("ClockSkew", &clockSkew)
+	forwarderExpvars.Set("ClockSkewWarning", &clockSkewWarning)
+}
+
+// updateClockSkew estimates the host clock skew from an intake response. The
+// Date header has a one second precision, it is compared to the middle of the
+// request round trip.
+func updateClockSkew(resp *http.Response, sentAt, receivedAt time.Time) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	localTime := sentAt.Add(receivedAt.Sub(sentAt) / 2)
+	skew := localTime.Sub(date)
+	clockSkew.Set(skew.Seconds())
+
+	warning := clockSkewWarningFor(skew)
+	if warning != "" && clockSkewWarning.Value() == "" {
+		log.Warn(warning)
+	}
+	clockSkewWarning.Set(warning)
+}
+
+// clockSkewWarningFor returns a warning if the points would be rejected
+func clockSkewWarningFor(skew time.Duration) string {
+	switch {
+	case skew > maxPointFutureSkew:
+		return fmt.Sprintf("the host clock is %s ahead of the Datadog intake, points more than %s in the future are rejected", skew.Round(time.Second), maxPointFutureSkew)
+	case -skew > maxPointPastSkew:
+		return fmt.Sprintf("the host clock is %s behind the Datadog intake, points more than %s in the past are rejected", (-skew).Round(time.Second), maxPointPastSkew)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateClockSkew(t *testing.T) {
+	defer clockSkew.Set(0)
+	defer clockSkewWarning.Set("")
+
+	intakeTime := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Date", intakeTime.Format(http.TimeFormat))
+
+	// In sync, compared to the middle of the round trip
+	updateClockSkew(resp, intakeTime.Add(-time.Second), intakeTime.Add(time.Second))
+	assert.Equal(t, 0.0, clockSkew.Value())
+	assert.Equal(t, "", clockSkewWarning.Value())
+
+	// Ahead of the intake
+	updateClockSkew(resp, intakeTime.Add(20*time.Minute), intakeTime.Add(20*time.Minute))
+	assert.Equal(t, 1200.0, clockSkew.Value())
+	assert.Contains(t, clockSkewWarning.Value(), "20m0s ahead")
+
+	// Behind the intake, but not enough to be rejected
+	updateClockSkew(resp, intakeTime.Add(-30*time.Minute), intakeTime.Add(-30*time.Minute))
+	assert.Equal(t, -1800.0, clockSkew.Value())
+	assert.Equal(t, "", clockSkewWarning.Value())
+
+	// Missing header
+	updateClockSkew(&http.Response{Header: http.Header{}}, intakeTime, intakeTime)
+	assert.Equal(t, -1800.0, clockSkew.Value())
+}
+
+func TestClockSkewWarningFor(t *testing.T) {
+	assert.Equal(t, "", clockSkewWarningFor(5*time.Minute))
+	assert.Equal(t, "", clockSkewWarningFor(-50*time.Minute))
+	assert.NotEqual(t, "", clockSkewWarningFor(11*time.Minute))
+	assert.NotEqual(t, "", clockSkewWarningFor(-61*time.Minute))
+}
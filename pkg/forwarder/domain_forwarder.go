@@ -48,6 +48,11 @@ type domainForwarder struct {
 	m                   sync.Mutex // To control Start/Stop races
 	isRetrying          int32
 	blockedList         *blockedEndpoints
+	selector            *endpointSelector
+
+	// retryPriorityAging is the wait after which a retried transaction
+	// moves up one priority class, zero to disable it
+	retryPriorityAging time.Duration
 }
 
 func newDomainForwarder(domain string, numberOfWorkers int, retryQueueLimit int) *domainForwarder {
@@ -60,11 +65,49 @@ func newDomainForwarder(domain string, numberOfWorkers int, retryQueueLimit int)
 	}
 }
 
-type byCreatedTime []Transaction
+// setFailoverDomains lets the workers send the transactions to one of
+// failoverDomains when the primary domain is unhealthy
+func (f *domainForwarder) setFailoverDomains(failoverDomains []string) {
+	if len(failoverDomains) == 0 {
+		f.selector = nil
+		return
+	}
+	f.selector = newEndpointSelector(f.domain, failoverDomains)
+}
 
-func (v byCreatedTime) Len() int           { return len(v) }
-func (v byCreatedTime) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
-func (v byCreatedTime) Less(i, j int) bool { return v[i].GetCreatedAt().After(v[j].GetCreatedAt()) }
+// retryOrder sorts the retry queue by priority class, then from the newest
+// transaction to the oldest. The sort keys are computed once per transaction.
+type retryOrder struct {
+	transactions []Transaction
+	priorities   []TransactionPriority
+	createdAt    []time.Time
+}
+
+func newRetryOrder(transactions []Transaction, now time.Time, aging time.Duration) retryOrder {
+	v := retryOrder{
+		transactions: transactions,
+		priorities:   make([]TransactionPriority, len(transactions)),
+		createdAt:    make([]time.Time, len(transactions)),
+	}
+	for i, t := range transactions {
+		v.createdAt[i] = t.GetCreatedAt()
+		v.priorities[i] = agedPriority(t.GetPriority(), v.createdAt[i], now, aging)
+	}
+	return v
+}
+
+func (v retryOrder) Len() int { return len(v.transactions) }
+func (v retryOrder) Swap(i, j int) {
+	v.transactions[i], v.transactions[j] = v.transactions[j], v.transactions[i]
+	v.priorities[i], v.priorities[j] = v.priorities[j], v.priorities[i]
+	v.createdAt[i], v.createdAt[j] = v.createdAt[j], v.createdAt[i]
+}
+func (v retryOrder) Less(i, j int) bool {
+	if v.priorities[i] != v.priorities[j] {
+		return v.priorities[i] > v.priorities[j]
+	}
+	return v.createdAt[i].After(v.createdAt[j])
+}
 
 func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 	// In case it takes more that flushInterval to sort and retry
@@ -79,7 +122,7 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 	droppedRetryQueueFull := 0
 	droppedWorkerBusy := 0
 
-	sort.Sort(byCreatedTime(f.retryQueue))
+	sort.Sort(newRetryOrder(f.retryQueue, retryBefore, f.retryPriorityAging))
 
 	for _, t := range f.retryQueue {
 		if !f.blockedList.isBlock(t.GetTarget()) {
@@ -153,6 +196,7 @@ func (f *domainForwarder) Start() error {
 
 	for i := 0; i < f.numberOfWorkers; i++ {
 		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList)
+		w.selector = f.selector
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
@@ -164,19 +208,38 @@ func (f *domainForwarder) Start() error {
 
 // Stop stops a domainForwarder, all transactions not yet flushed will be lost.
 func (f *domainForwarder) Stop() {
+	f.stopAndDrain()
+}
+
+// stopAndDrain stops a domainForwarder and returns the transactions not
+// flushed yet: the retry queue, the transactions waiting for a worker and
+// the ones interrupted by the stop.
+func (f *domainForwarder) stopAndDrain() []Transaction {
 	// Lock so we can't start a Forwarder while is stopping
 	f.m.Lock()
 	defer f.m.Unlock()
 
 	if f.internalState == Stopped {
 		log.Warnf("the forwarder is already stopped")
-		return
+		return nil
 	}
 
 	f.stopRetry <- true
 	for _, w := range f.workers {
 		w.Stop()
 	}
+	pending := f.retryQueue
+	for _, c := range []chan Transaction{f.requeuedTransaction, f.highPrio, f.lowPrio} {
+	drain:
+		for {
+			select {
+			case t := <-c:
+				pending = append(pending, t)
+			default:
+				break drain
+			}
+		}
+	}
 	f.workers = []*Worker{}
 	f.retryQueue = []Transaction{}
 	close(f.highPrio)
@@ -184,6 +247,7 @@ func (f *domainForwarder) Stop() {
 	close(f.requeuedTransaction)
 	log.Info("domainForwarder stopped")
 	f.internalState = Stopped
+	return pending
 }
 
 func (f *domainForwarder) State() uint32 {
@@ -200,6 +264,7 @@ func (f *domainForwarder) sendHTTPTransactions(transaction Transaction) error {
 	case f.highPrio <- transaction:
 	default:
 		transactionsDroppedOnInput.Add(1)
+		recordInputDrop()
 		return fmt.Errorf("the forwarder input queue for %s is full: dropping transaction", f.domain)
 	}
 	return nil
@@ -220,3 +220,40 @@ func TestForwarderRetryLimitQueue(t *testing.T) {
 	// assert that the oldest transaction was dropped
 	assert.Equal(t, transaction2, forwarder.retryQueue[0])
 }
+
+func TestForwarderRetryPriority(t *testing.T) {
+	forwarder := newDomainForwarder("test", 1, 10)
+	forwarder.init()
+	forwarder.retryPriorityAging = 10 * time.Minute
+	now := time.Now()
+
+	metadata := newTestTransaction()
+	metadata.priority = PriorityMetadata
+	metadata.On("GetCreatedAt").Return(now).Times(1)
+	metadata.On("GetTarget").Return("").Times(1)
+	series := newTestTransaction()
+	series.priority = PriorityMetrics
+	series.On("GetCreatedAt").Return(now).Times(1)
+	series.On("GetTarget").Return("").Times(1)
+	events := newTestTransaction()
+	events.priority = PriorityAlerts
+	events.On("GetCreatedAt").Return(now.Add(-time.Minute)).Times(1)
+	events.On("GetTarget").Return("").Times(1)
+	// Aged up to the metrics class, after the newer series
+	oldMetadata := newTestTransaction()
+	oldMetadata.priority = PriorityMetadata
+	oldMetadata.On("GetCreatedAt").Return(now.Add(-15 * time.Minute)).Times(1)
+	oldMetadata.On("GetTarget").Return("").Times(1)
+
+	forwarder.requeueTransaction(metadata)
+	forwarder.requeueTransaction(oldMetadata)
+	forwarder.requeueTransaction(series)
+	forwarder.requeueTransaction(events)
+	forwarder.retryTransactions(now)
+
+	require.Len(t, forwarder.lowPrio, 4)
+	assert.Equal(t, events, <-forwarder.lowPrio)
+	assert.Equal(t, series, <-forwarder.lowPrio)
+	assert.Equal(t, oldMetadata, <-forwarder.lowPrio)
+	assert.Equal(t, metadata, <-forwarder.lowPrio)
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"expvar"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// endpointStatsAlpha is the weight of a new sample in the moving averages
+	endpointStatsAlpha = 0.2
+)
+
+var (
+	// intakeEndpoints is the intake currently selected for every
+	// domain with failover endpoints
+	intakeEndpoints = expvar.Map{}
+)
+
+func initEndpointSelectorExpvars() {
+	intakeEndpoints.Init()
+	forwarderExpvars.Set("IntakeEndpoints", &intakeEndpoints)
+}
+
+type endpointStats struct {
+	domain string
+	// latency is the moving average of the transaction latency, in seconds
+	latency float64
+	// errorRate is the moving average of the transaction failures, between 0 and 1
+	errorRate float64
+	samples   int
+	updatedAt time.Time
+}
+
+// endpointSelector picks the intake a domainForwarder sends its transactions
+// to among the primary domain and its failover endpoints, ordered by priority.
+// The selection is sticky: the current endpoint is kept for at least minDwell,
+// and is only replaced by an endpoint scoring better by more than the
+// hysteresis ratio, so that transient latency spikes don't flip the traffic
+// from one region to another.
+type endpointSelector struct {
+	m          sync.Mutex
+	endpoints  []*endpointStats
+	current    int
+	switchedAt time.Time
+	expvar     expvar.String
+
+	hysteresis float64
+	minDwell   time.Duration
+	// errorPenalty is the cost in seconds of a failed transaction in the
+	// endpoint score
+	errorPenalty float64
+
+	now func() time.Time
+}
+
+func newEndpointSelector(domain string, failoverDomains []string) *endpointSelector {
+	hysteresis := config.Datadog.GetFloat64("forwarder_failover_hysteresis")
+	if hysteresis < 0 {
+		log.Warnf("Configured forwarder_failover_hysteresis (%v) is negative; 0 will be used", hysteresis)
+		hysteresis = 0
+	}
+
+	s := &endpointSelector{
+		endpoints:    []*endpointStats{{domain: domain}},
+		hysteresis:   hysteresis,
+		minDwell:     config.Datadog.GetDuration("forwarder_failover_min_dwell") * time.Second,
+		errorPenalty: config.Datadog.GetFloat64("forwarder_timeout"),
+		now:          time.Now,
+	}
+	for _, d := range failoverDomains {
+		s.endpoints = append(s.endpoints, &endpointStats{domain: d})
+	}
+	s.switchedAt = s.now()
+	s.expvar.Set(util.SanitizeURL(domain))
+	intakeEndpoints.Set(util.SanitizeURL(domain), &s.expvar)
+	return s
+}
+
+// pick returns the domain the next transaction should be sent to
+func (s *endpointSelector) pick() string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	now := s.now()
+	if now.Sub(s.switchedAt) >= s.minDwell {
+		s.reselect(now)
+	}
+	return s.endpoints[s.current].domain
+}
+
+// observe records the outcome of a transaction sent to domain
+func (s *endpointSelector) observe(domain string, latency time.Duration, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, e := range s.endpoints {
+		if e.domain != domain {
+			continue
+		}
+		failed := 0.0
+		if err != nil {
+			failed = 1
+		}
+		now := s.now()
+		if e.samples == 0 {
+			e.latency = latency.Seconds()
+			e.errorRate = failed
+		} else {
+			e.errorRate = e.decayedErrorRate(now, s.minDwell)
+			e.latency += endpointStatsAlpha * (latency.Seconds() - e.latency)
+			e.errorRate += endpointStatsAlpha * (failed - e.errorRate)
+		}
+		e.samples++
+		e.updatedAt = now
+		return
+	}
+}
+
+// decayedErrorRate halves the error rate every halfLife without sample, so
+// that an endpoint we failed over from is tried again once it had time to
+// recover
+func (e *endpointStats) decayedErrorRate(now time.Time, halfLife time.Duration) float64 {
+	if halfLife <= 0 {
+		return 0
+	}
+	elapsed := now.Sub(e.updatedAt)
+	if elapsed <= 0 {
+		return e.errorRate
+	}
+	return e.errorRate * math.Pow(0.5, float64(elapsed)/float64(halfLife))
+}
+
+// score returns the expected cost of a transaction sent to an endpoint, the
+// lower the better. Endpoints without samples are assumed as fast as the
+// current one, without errors.
+func (s *endpointSelector) score(e *endpointStats, now time.Time) float64 {
+	latency := e.latency
+	if e.samples == 0 {
+		latency = s.endpoints[s.current].latency
+	}
+	return latency + s.errorPenalty*e.decayedErrorRate(now, s.minDwell)
+}
+
+func (s *endpointSelector) reselect(now time.Time) {
+	currentScore := s.score(s.endpoints[s.current], now)
+
+	next := s.current
+	for i, e := range s.endpoints {
+		if i == s.current {
+			continue
+		}
+		score := s.score(e, now)
+		// Fail back to a higher priority endpoint as soon as it is on par
+		// with the current one, move to a lower priority endpoint only if
+		// it is significantly better
+		if i < s.current && score <= currentScore*(1+s.hysteresis) {
+			next = i
+			break
+		}
+		if score*(1+s.hysteresis) < currentScore && (next == s.current || score < s.score(s.endpoints[next], now)) {
+			next = i
+		}
+	}
+
+	if next == s.current {
+		return
+	}
+	log.Warnf("Switching intake from %q (score %.3f) to %q (score %.3f)",
+		util.SanitizeURL(s.endpoints[s.current].domain), currentScore,
+		util.SanitizeURL(s.endpoints[next].domain), s.score(s.endpoints[next], now))
+	s.current = next
+	s.switchedAt = now
+	s.expvar.Set(util.SanitizeURL(s.endpoints[next].domain))
+}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEndpointSelector(now *time.Time) *endpointSelector {
+	s := newEndpointSelector("https://primary", []string{"https://secondary", "https://tertiary"})
+	s.now = func() time.Time { return *now }
+	s.switchedAt = *now
+	s.minDwell = time.Minute
+	s.hysteresis = 0.2
+	s.errorPenalty = 20
+	return s
+}
+
+func TestEndpointSelectorDefaultsToPrimary(t *testing.T) {
+	now := time.Now()
+	s := newTestEndpointSelector(&now)
+
+	assert.Equal(t, "https://primary", s.pick())
+	s.observe("https://primary", 100*time.Millisecond, nil)
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, "https://primary", s.pick())
+	assert.Equal(t, "https://primary", s.expvar.Value())
+}
+
+func TestEndpointSelectorFailover(t *testing.T) {
+	now := time.Now()
+	s := newTestEndpointSelector(&now)
+
+	// Errors on the primary don't move the traffic before the dwell time
+	for i := 0; i < 5; i++ {
+		s.observe("https://primary", time.Second, fmt.Errorf("error 503"))
+	}
+	assert.Equal(t, "https://primary", s.pick())
+
+	// The first untried failover endpoint is selected
+	now = now.Add(time.Minute)
+	assert.Equal(t, "https://secondary", s.pick())
+	assert.Equal(t, "https://secondary", s.expvar.Value())
+
+	// The selection is sticky
+	s.observe("https://secondary", 200*time.Millisecond, nil)
+	s.observe("https://secondary", 2*time.Second, fmt.Errorf("timeout"))
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, "https://secondary", s.pick())
+}
+
+func TestEndpointSelectorHysteresis(t *testing.T) {
+	now := time.Now()
+	s := newTestEndpointSelector(&now)
+
+	s.observe("https://primary", 1000*time.Millisecond, nil)
+	s.observe("https://tertiary", 900*time.Millisecond, nil)
+	now = now.Add(2 * time.Minute)
+
+	// 10% faster is within the hysteresis
+	assert.Equal(t, "https://primary", s.pick())
+
+	// 50% faster is not
+	for i := 0; i < 10; i++ {
+		s.observe("https://tertiary", 500*time.Millisecond, nil)
+	}
+	assert.Equal(t, "https://tertiary", s.pick())
+}
+
+func TestEndpointSelectorFailback(t *testing.T) {
+	now := time.Now()
+	s := newTestEndpointSelector(&now)
+
+	s.observe("https://primary", 100*time.Millisecond, nil)
+	for i := 0; i < 5; i++ {
+		s.observe("https://primary", 100*time.Millisecond, fmt.Errorf("error 503"))
+	}
+	now = now.Add(time.Minute)
+	require.Equal(t, "https://secondary", s.pick())
+	s.observe("https://secondary", 300*time.Millisecond, nil)
+
+	// The primary errors decay, the traffic goes back to it once on par
+	now = now.Add(time.Minute)
+	assert.Equal(t, "https://secondary", s.pick())
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, "https://primary", s.pick())
+}
+
+func TestWorkerFailover(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	secondaryRequests := 0
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryRequests++
+	}))
+	defer secondary.Close()
+
+	now := time.Now()
+	selector := newEndpointSelector(primary.URL, []string{secondary.URL})
+	selector.now = func() time.Time { return now }
+	selector.switchedAt = now
+	selector.minDwell = time.Minute
+
+	requeue := make(chan Transaction, 10)
+	w := NewWorker(nil, nil, requeue, newBlockedEndpoints())
+	w.selector = selector
+
+	payload := []byte("{}")
+	transaction := NewHTTPTransaction()
+	transaction.Domain = primary.URL
+	transaction.Endpoint = seriesEndpoint
+	transaction.Payload = &payload
+
+	w.process(context.Background(), transaction)
+	require.Len(t, requeue, 1)
+	<-requeue
+
+	now = now.Add(time.Minute)
+	w.blockedList = newBlockedEndpoints()
+	w.process(context.Background(), transaction)
+	assert.Len(t, requeue, 0)
+	assert.Equal(t, 1, secondaryRequests)
+	assert.Equal(t, secondary.URL, transaction.Domain)
+}
+
+func TestFailoverDomains(t *testing.T) {
+	domains := failoverDomains([]string{"https://app.datadoghq.eu", "https://intake.example.com", "://invalid"})
+	require.Len(t, domains, 2)
+	assert.Contains(t, domains[0], ".agent.datadoghq.eu")
+	assert.Equal(t, "https://intake.example.com", domains[1])
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -48,6 +49,8 @@ func init() {
 	initDomainForwarderExpvars()
 	initTransactionExpvars()
 	initForwarderHealthExpvars()
+	initClockSkewExpvars()
+	initEndpointSelectorExpvars()
 }
 
 const (
@@ -104,6 +107,13 @@ type DefaultForwarder struct {
 	healthChecker    *forwarderHealth
 	internalState    uint32
 	m                sync.Mutex // To control Start/Stop races
+
+	// configDomains maps the domains to their configured value, which
+	// does not include the agent version
+	configDomains map[string]string
+	// checkpointPath is where the pending transactions are written on
+	// Stop and restored from on Start, empty if disabled
+	checkpointPath string
 }
 
 // NewDefaultForwarder returns a new DefaultForwarder.
@@ -112,25 +122,52 @@ func NewDefaultForwarder(keysPerDomains map[string][]string) *DefaultForwarder {
 		NumberOfWorkers:  config.Datadog.GetInt("forwarder_num_workers"),
 		domainForwarders: map[string]*domainForwarder{},
 		keysPerDomains:   map[string][]string{},
+		configDomains:    map[string]string{},
+		checkpointPath:   checkpointPath(),
 		internalState:    Stopped,
 		healthChecker:    &forwarderHealth{keysPerDomains: keysPerDomains},
 	}
 	numWorkers := config.Datadog.GetInt("forwarder_num_workers")
 	retryQueueMaxSize := config.Datadog.GetInt("forwarder_retry_queue_max_size")
+	retryPriorityAging := time.Duration(config.Datadog.GetInt("forwarder_retry_priority_aging")) * time.Second
+
+	var failoverEndpoints map[string][]string
+	if err := config.Datadog.UnmarshalKey("forwarder_failover_endpoints", &failoverEndpoints); err != nil {
+		log.Errorf("Could not parse forwarder_failover_endpoints, failover is disabled: %s", err)
+	}
 
-	for domain, keys := range keysPerDomains {
-		domain, _ := config.AddAgentVersionToDomain(domain, "app")
+	for configDomain, keys := range keysPerDomains {
+		domain, _ := config.AddAgentVersionToDomain(configDomain, "app")
 		if keys == nil || len(keys) == 0 {
 			log.Errorf("No API keys for domain '%s', dropping domain ", domain)
 		} else {
 			f.keysPerDomains[domain] = keys
-			f.domainForwarders[domain] = newDomainForwarder(domain, numWorkers, retryQueueMaxSize)
+			f.configDomains[domain] = configDomain
+			df := newDomainForwarder(domain, numWorkers, retryQueueMaxSize)
+			df.setFailoverDomains(failoverDomains(failoverEndpoints[configDomain]))
+			df.retryPriorityAging = retryPriorityAging
+			f.domainForwarders[domain] = df
 		}
 	}
 
 	return f
 }
 
+// failoverDomains prefixes the failover endpoints with the agent version, like
+// the primary domains
+func failoverDomains(endpoints []string) []string {
+	domains := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		domain, err := config.AddAgentVersionToDomain(endpoint, "app")
+		if err != nil {
+			log.Errorf("Could not parse failover endpoint '%s', ignoring it: %s", endpoint, err)
+			continue
+		}
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
@@ -144,6 +181,11 @@ func (f *DefaultForwarder) Start() error {
 	for _, df := range f.domainForwarders {
 		df.Start()
 	}
+	if f.checkpointPath != "" {
+		if err := f.restoreCheckpoint(f.checkpointPath); err != nil {
+			log.Warnf("Could not restore the forwarder checkpoint: %s", err)
+		}
+	}
 
 	// log endpoints configuration
 	endpointLogs := make([]string, 0, len(f.keysPerDomains))
@@ -172,8 +214,14 @@ func (f *DefaultForwarder) Stop() {
 
 	f.internalState = Stopped
 
-	for _, df := range f.domainForwarders {
-		df.Stop()
+	pending := make(map[string][]Transaction, len(f.domainForwarders))
+	for domain, df := range f.domainForwarders {
+		pending[domain] = df.stopAndDrain()
+	}
+	if f.checkpointPath != "" {
+		if err := f.writeCheckpoint(f.checkpointPath, pending); err != nil {
+			log.Errorf("Could not checkpoint the pending transactions: %s", err)
+		}
 	}
 
 	f.healthChecker.Stop()
@@ -192,6 +240,7 @@ func (f *DefaultForwarder) State() uint32 {
 
 func (f *DefaultForwarder) createHTTPTransactions(endpoint string, payloads Payloads, apiKeyInQueryString bool, extra http.Header) []*HTTPTransaction {
 	transactions := []*HTTPTransaction{}
+	priority := transactionPriority(endpoint, extra)
 	for _, payload := range payloads {
 		for domain, apiKeys := range f.keysPerDomains {
 			for _, apiKey := range apiKeys {
@@ -203,6 +252,7 @@ func (f *DefaultForwarder) createHTTPTransactions(endpoint string, payloads Payl
 				t.Domain = domain
 				t.Endpoint = transactionEndpoint
 				t.Payload = payload
+				t.Priority = priority
 				t.Headers.Set(apiHTTPHeaderKey, apiKey)
 				t.Headers.Set(versionHTTPHeaderKey, version.AgentVersion)
 
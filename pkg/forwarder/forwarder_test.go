@@ -162,6 +162,7 @@ func TestSubmitV1Intake(t *testing.T) {
 		require.NotNil(t, tr)
 		httpTr := tr.(*HTTPTransaction)
 		assert.Equal(t, "application/json", httpTr.Headers.Get("Content-Type"))
+		assert.Equal(t, PriorityMetadata, httpTr.Priority)
 	case <-time.After(1 * time.Second):
 		require.Fail(t, "highPrio queue should contain a transaction")
 	}
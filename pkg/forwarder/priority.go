@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"time"
+)
+
+// TransactionPriority is the class of a transaction: after an outage, the
+// retry queue sends the transactions of the higher classes first and drops
+// the ones of the lower classes first.
+type TransactionPriority int
+
+const (
+	// PriorityMetadata is the class of the metadata payloads
+	PriorityMetadata TransactionPriority = iota - 1
+	// PriorityMetrics is the class of the series and sketches, and the
+	// default one
+	PriorityMetrics
+	// PriorityAlerts is the class of the events and service checks
+	PriorityAlerts
+)
+
+func (p TransactionPriority) String() string {
+	switch p {
+	case PriorityMetadata:
+		return "metadata"
+	case PriorityMetrics:
+		return "metrics"
+	case PriorityAlerts:
+		return "alerts"
+	default:
+		return "unknown"
+	}
+}
+
+// transactionPriority returns the class of the payloads sent to an endpoint.
+// The v1 intake receives both the events and the metadata: the events are
+// told apart since they are the only compressed ones.
+func transactionPriority(endpoint string, extra http.Header) TransactionPriority {
+	switch endpoint {
+	case eventsEndpoint, serviceChecksEndpoint, v1CheckRunsEndpoint:
+		return PriorityAlerts
+	case hostMetadataEndpoint, metadataEndpoint:
+		return PriorityMetadata
+	case v1IntakeEndpoint:
+		if extra.Get("Content-Encoding") != "" {
+			return PriorityAlerts
+		}
+		return PriorityMetadata
+	default:
+		return PriorityMetrics
+	}
+}
+
+// agedPriority returns the priority of a transaction waiting in the retry
+// queue since createdAt: it moves up one class every aging period, so that
+// the lower classes are not starved by a long partial outage. A zero aging
+// disables it.
+func agedPriority(priority TransactionPriority, createdAt time.Time, now time.Time, aging time.Duration) TransactionPriority {
+	if aging > 0 && now.After(createdAt) {
+		priority += TransactionPriority(now.Sub(createdAt) / aging)
+	}
+	if priority > PriorityAlerts {
+		return PriorityAlerts
+	}
+	return priority
+}
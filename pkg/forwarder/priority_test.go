@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionPriority(t *testing.T) {
+	compressed := make(http.Header)
+	compressed.Set("Content-Encoding", "deflate")
+
+	assert.Equal(t, PriorityAlerts, transactionPriority(eventsEndpoint, nil))
+	assert.Equal(t, PriorityAlerts, transactionPriority(v1CheckRunsEndpoint, nil))
+	assert.Equal(t, PriorityMetrics, transactionPriority(v1SeriesEndpoint, nil))
+	assert.Equal(t, PriorityMetrics, transactionPriority(sketchSeriesEndpoint, nil))
+	assert.Equal(t, PriorityMetadata, transactionPriority(hostMetadataEndpoint, nil))
+	assert.Equal(t, PriorityAlerts, transactionPriority(v1IntakeEndpoint, compressed))
+	assert.Equal(t, PriorityMetadata, transactionPriority(v1IntakeEndpoint, make(http.Header)))
+}
+
+func TestAgedPriority(t *testing.T) {
+	now := time.Now()
+	aging := 10 * time.Minute
+
+	assert.Equal(t, PriorityMetadata, agedPriority(PriorityMetadata, now.Add(-5*time.Minute), now, aging))
+	assert.Equal(t, PriorityMetrics, agedPriority(PriorityMetadata, now.Add(-15*time.Minute), now, aging))
+	assert.Equal(t, PriorityAlerts, agedPriority(PriorityMetadata, now.Add(-time.Hour), now, aging))
+	assert.Equal(t, PriorityAlerts, agedPriority(PriorityAlerts, now.Add(-time.Hour), now, aging))
+	// Disabled
+	assert.Equal(t, PriorityMetadata, agedPriority(PriorityMetadata, now.Add(-time.Hour), now, 0))
+}
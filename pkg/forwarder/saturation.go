@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// saturationWindow is how long the forwarder is considered saturated after
+// dropping a transaction because its input queue was full
+const saturationWindow = 30 * time.Second
+
+// lastInputDrop is the time of the last transaction dropped on input, in
+// nanoseconds since the epoch
+var lastInputDrop int64
+
+func recordInputDrop() {
+	atomic.StoreInt64(&lastInputDrop, time.Now().UnixNano())
+}
+
+// IsSaturated returns whether the forwarder dropped transactions because
+// its input queue was full during the last 30 seconds. Payloads submitted
+// while it is saturated are likely to be dropped too.
+func IsSaturated() bool {
+	last := atomic.LoadInt64(&lastInputDrop)
+	return last != 0 && time.Since(time.Unix(0, last)) < saturationWindow
+}
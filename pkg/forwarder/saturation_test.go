@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package forwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSaturated(t *testing.T) {
+	defer func(last int64) { lastInputDrop = last }(lastInputDrop)
+
+	lastInputDrop = 0
+	assert.False(t, IsSaturated())
+
+	recordInputDrop()
+	assert.True(t, IsSaturated())
+
+	lastInputDrop = time.Now().Add(-saturationWindow - time.Second).UnixNano()
+	assert.False(t, IsSaturated())
+}
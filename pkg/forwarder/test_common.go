@@ -16,6 +16,7 @@ import (
 type testTransaction struct {
 	mock.Mock
 	processed chan bool
+	priority  TransactionPriority
 }
 
 func newTestTransaction() *testTransaction {
@@ -37,6 +38,10 @@ func (t *testTransaction) GetTarget() string {
 	return t.Called().Get(0).(string)
 }
 
+func (t *testTransaction) GetPriority() TransactionPriority {
+	return t.priority
+}
+
 // MockedForwarder a mocked forwarder to be use in other module to test their dependencies with the forwarder
 type MockedForwarder struct {
 	mock.Mock
@@ -93,6 +93,8 @@ type HTTPTransaction struct {
 	Payload *[]byte
 	// ErrorCount is the number of times this HTTPTransaction failed to be processed.
 	ErrorCount int
+	// Priority is the class of the HTTPTransaction in the retry queue.
+	Priority TransactionPriority
 
 	createdAt time.Time
 }
@@ -102,6 +104,7 @@ type Transaction interface {
 	Process(ctx context.Context, client *http.Client) error
 	GetCreatedAt() time.Time
 	GetTarget() string
+	GetPriority() TransactionPriority
 }
 
 // NewHTTPTransaction returns a new HTTPTransaction.
@@ -118,6 +121,11 @@ func (t *HTTPTransaction) GetCreatedAt() time.Time {
 	return t.createdAt
 }
 
+// GetPriority returns the priority class of the HTTPTransaction.
+func (t *HTTPTransaction) GetPriority() TransactionPriority {
+	return t.Priority
+}
+
 // GetTarget return the url used by the transaction
 func (t *HTTPTransaction) GetTarget() string {
 	url := t.Domain + t.Endpoint
@@ -139,6 +147,7 @@ func (t *HTTPTransaction) Process(ctx context.Context, client *http.Client) erro
 	}
 	req = req.WithContext(ctx)
 	req.Header = t.Headers
+	sentAt := time.Now()
 	resp, err := client.Do(req)
 
 	if err != nil {
@@ -151,6 +160,7 @@ func (t *HTTPTransaction) Process(ctx context.Context, client *http.Client) erro
 		return fmt.Errorf("error while sending transaction, rescheduling it: %s", util.SanitizeURL(err.Error()))
 	}
 	defer resp.Body.Close()
+	updateClockSkew(resp, sentAt, time.Now())
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
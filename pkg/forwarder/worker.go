@@ -34,6 +34,9 @@ type Worker struct {
 	stopChan    chan bool
 	stopped     chan struct{}
 	blockedList *blockedEndpoints
+	// selector routes the transactions to the healthiest intake, nil if
+	// the domain has no failover endpoints
+	selector *endpointSelector
 }
 
 // NewWorker returns a new worker to consume Transaction from inputChan
@@ -131,12 +134,28 @@ func (w *Worker) process(ctx context.Context, t Transaction) {
 		}
 	}
 
+	// Route the transaction to the intake currently selected for its domain
+	var domain string
+	if httpTransaction, ok := t.(*HTTPTransaction); ok && w.selector != nil {
+		domain = w.selector.pick()
+		httpTransaction.Domain = domain
+	}
+
 	// Run the endpoint through our blockedEndpoints circuit breaker
 	target := t.GetTarget()
 	if w.blockedList.isBlock(target) {
 		requeue()
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
-	} else if err := t.Process(ctx, w.Client); err != nil {
+		return
+	}
+
+	start := time.Now()
+	err := t.Process(ctx, w.Client)
+	if domain != "" && ctx.Err() == nil {
+		w.selector.observe(domain, time.Since(start), err)
+	}
+
+	if err != nil {
 		w.blockedList.close(target)
 		requeue()
 		log.Errorf("Error while processing transaction: %v", err)
@@ -15,12 +15,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	api "github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/subprocess"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/executable"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -72,6 +74,11 @@ type JMXFetch struct {
 	defaultJmxCommand  string
 	cmd                *exec.Cmd
 	exitFilePath       string
+	stderrTail         *subprocess.LineTail
+	stopping           uint32
+	done               chan struct{}
+	waitErr            error
+	exitReport         subprocess.ExitReport
 }
 
 func (j *JMXFetch) setDefaults() {
@@ -111,7 +118,7 @@ func (j *JMXFetch) Start() error {
 		classpath = fmt.Sprintf("%s%s%s", strings.Join(j.JavaCustomJarPaths, string(os.PathListSeparator)), string(os.PathListSeparator), classpath)
 	}
 	bindHost := config.Datadog.GetString("bind_host")
-	if bindHost == "" || bindHost == "0.0.0.0" {
+	if bindHost == "" || bindHost == "0.0.0.0" || bindHost == "::" {
 		bindHost = "localhost"
 	}
 
@@ -215,20 +222,43 @@ func (j *JMXFetch) Start() error {
 	if err != nil {
 		return err
 	}
+	j.stderrTail = subprocess.NewLineTail(subprocess.DefaultTailSize)
+	stderrDone := make(chan struct{})
 	go func() {
+		defer close(stderrDone)
 		in := bufio.NewScanner(stderr)
 		for in.Scan() {
 			log.Error(in.Text())
+			j.stderrTail.Add(in.Text())
 		}
 	}()
 
 	log.Debugf("Args: %v", subprocessArgs)
 
-	return j.cmd.Start()
+	atomic.StoreUint32(&j.stopping, 0)
+	j.done = make(chan struct{})
+	if err := j.cmd.Start(); err != nil {
+		subprocess.Record(subprocess.NewExitReport("jmxfetch", j.cmd, err, j.stderrTail))
+		return err
+	}
+
+	go func() {
+		// Reading stderr to its end before waiting keeps its last lines
+		<-stderrDone
+		j.waitErr = j.cmd.Wait()
+		j.exitReport = subprocess.NewExitReport("jmxfetch", j.cmd, j.waitErr, j.stderrTail)
+		// Exits requested by Stop are not reported
+		if atomic.LoadUint32(&j.stopping) == 0 {
+			subprocess.Record(j.exitReport)
+		}
+		close(j.done)
+	}()
+	return nil
 }
 
 // Stop stops the JMXFetch process
 func (j *JMXFetch) Stop() error {
+	atomic.StoreUint32(&j.stopping, 1)
 	if j.JmxExitFile == "" {
 		stopped := make(chan struct{})
 
@@ -264,5 +294,12 @@ func (j *JMXFetch) Stop() error {
 
 // Wait waits for the end of the JMXFetch process and returns the error code
 func (j *JMXFetch) Wait() error {
-	return j.cmd.Wait()
+	<-j.done
+	return j.waitErr
+}
+
+// ExitReport returns the exit code, stderr tail and resource usage of the
+// JMXFetch process, it is only valid once Wait returned
+func (j *JMXFetch) ExitReport() subprocess.ExitReport {
+	return j.exitReport
 }
@@ -12,13 +12,15 @@ import (
 
 // Logs source types
 const (
-	TCPType          = "tcp"
-	UDPType          = "udp"
-	FileType         = "file"
-	ContainerdType   = "containerd"
-	DockerType       = "docker"
-	JournaldType     = "journald"
-	WindowsEventType = "windows_event"
+	TCPType                = "tcp"
+	UDPType                = "udp"
+	FileType               = "file"
+	ContainerdType         = "containerd"
+	DockerType             = "docker"
+	JournaldType           = "journald"
+	WindowsEventType       = "windows_event"
+	AuditdType             = "auditd"
+	ContainerLifecycleType = "container_lifecycle"
 )
 
 // Logs rule types
@@ -47,7 +49,7 @@ type LogsConfig struct {
 	Type string
 
 	Port int    // Network
-	Path string // File, Journald
+	Path string // File, Journald, Auditd
 
 	IncludeUnits []string `mapstructure:"include_units" json:"include_units"` // Journald
 	ExcludeUnits []string `mapstructure:"exclude_units" json:"exclude_units"` // Journald
@@ -60,11 +62,14 @@ type LogsConfig struct {
 	ChannelPath string `mapstructure:"channel_path" json:"channel_path"` // Windows Event
 	Query       string // Windows Event
 
+	BaselineRules bool `mapstructure:"baseline_rules" json:"baseline_rules"` // Auditd
+
 	Service         string
 	Source          string
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	Quota           *QuotaConfig     `mapstructure:"log_quota" json:"log_quota"`
 }
 
 // Validate returns an error if the config is misconfigured
@@ -82,6 +87,11 @@ func (c *LogsConfig) Validate() error {
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
 	}
+	if c.Quota != nil {
+		if err := c.Quota.validate(); err != nil {
+			return err
+		}
+	}
 	return c.validateProcessingRules()
 }
 
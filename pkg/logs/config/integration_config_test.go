@@ -20,6 +20,7 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: UDPType, Port: 5678},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
+		{Type: DockerType, Quota: &QuotaConfig{LinesPerSecond: 100, Overflow: QuotaTag}},
 	}
 
 	for _, config := range validConfigs {
@@ -41,6 +42,7 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []ProcessingRule{{Pattern: ".*"}}},
+		{Type: DockerType, Quota: &QuotaConfig{}},
 	}
 
 	for _, config := range invalidConfigs {
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Quota overflow behaviors
+const (
+	QuotaDrop   = "drop"
+	QuotaSample = "sample"
+	QuotaTag    = "tag"
+)
+
+// QuotaExceededTag is added to the logs sent over quota
+const QuotaExceededTag = "log_quota_exceeded:true"
+
+// defaultQuotaSampleRate is the ratio of the logs over quota sent with the sample behavior
+const defaultQuotaSampleRate = 0.1
+
+// QuotaConfig limits the rate and volume of logs a source can send, logs
+// over quota are either dropped, sampled, or sent with QuotaExceededTag.
+type QuotaConfig struct {
+	BytesPerSecond int     `mapstructure:"bytes_per_second" json:"bytes_per_second"`
+	LinesPerSecond int     `mapstructure:"lines_per_second" json:"lines_per_second"`
+	Overflow       string  // drop (default), sample, tag
+	SampleRate     float64 `mapstructure:"sample_rate" json:"sample_rate"`
+}
+
+// validate returns an error if the quota is misconfigured
+func (c *QuotaConfig) validate() error {
+	switch {
+	case c.BytesPerSecond < 0 || c.LinesPerSecond < 0:
+		return fmt.Errorf("quota limits can't be negative")
+	case c.BytesPerSecond == 0 && c.LinesPerSecond == 0:
+		return fmt.Errorf("quota must have a bytes_per_second or lines_per_second limit")
+	case c.SampleRate < 0 || c.SampleRate > 1:
+		return fmt.Errorf("quota sample_rate must be between 0 and 1")
+	}
+	switch c.Overflow {
+	case "", QuotaDrop, QuotaSample, QuotaTag:
+		return nil
+	default:
+		return fmt.Errorf("quota overflow %s is not supported", c.Overflow)
+	}
+}
+
+// Quota enforces a QuotaConfig over one second windows,
+// it is shared by all the pipelines processing the logs of a source.
+type Quota struct {
+	config      *QuotaConfig
+	sampleEvery int64
+
+	mu          sync.Mutex
+	windowStart time.Time
+	bytes       int
+	lines       int
+	overflowed  int64
+}
+
+// NewQuota returns a new Quota enforcing config
+func NewQuota(config *QuotaConfig) *Quota {
+	rate := config.SampleRate
+	if rate == 0 {
+		rate = defaultQuotaSampleRate
+	}
+	return &Quota{
+		config:      config,
+		sampleEvery: int64(math.Round(1 / rate)),
+	}
+}
+
+// Check accounts for a log of the given size, it returns whether the log
+// should be sent, and whether it is over quota.
+func (q *Quota) Check(size int) (send bool, overQuota bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(q.windowStart) >= time.Second {
+		q.windowStart = now
+		q.bytes = 0
+		q.lines = 0
+	}
+	q.bytes += size
+	q.lines++
+
+	if (q.config.BytesPerSecond == 0 || q.bytes <= q.config.BytesPerSecond) &&
+		(q.config.LinesPerSecond == 0 || q.lines <= q.config.LinesPerSecond) {
+		return true, false
+	}
+
+	q.overflowed++
+	switch q.config.Overflow {
+	case QuotaTag:
+		return true, true
+	case QuotaSample:
+		return (q.overflowed-1)%q.sampleEvery == 0, true
+	default:
+		return false, true
+	}
+}
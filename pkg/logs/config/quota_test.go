@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaValidate(t *testing.T) {
+	assert.Nil(t, (&QuotaConfig{LinesPerSecond: 10}).validate())
+	assert.Nil(t, (&QuotaConfig{BytesPerSecond: 1024, Overflow: QuotaSample, SampleRate: 0.5}).validate())
+	assert.NotNil(t, (&QuotaConfig{}).validate())
+	assert.NotNil(t, (&QuotaConfig{LinesPerSecond: -1}).validate())
+	assert.NotNil(t, (&QuotaConfig{LinesPerSecond: 10, Overflow: "foo"}).validate())
+	assert.NotNil(t, (&QuotaConfig{LinesPerSecond: 10, SampleRate: 2}).validate())
+}
+
+func TestQuotaLines(t *testing.T) {
+	quota := NewQuota(&QuotaConfig{LinesPerSecond: 2})
+	for i := 0; i < 2; i++ {
+		send, overQuota := quota.Check(10)
+		assert.True(t, send)
+		assert.False(t, overQuota)
+	}
+	send, overQuota := quota.Check(10)
+	assert.False(t, send)
+	assert.True(t, overQuota)
+
+	// A new window resets the counters
+	quota.windowStart = time.Now().Add(-time.Second)
+	send, overQuota = quota.Check(10)
+	assert.True(t, send)
+	assert.False(t, overQuota)
+}
+
+func TestQuotaBytes(t *testing.T) {
+	quota := NewQuota(&QuotaConfig{BytesPerSecond: 100, Overflow: QuotaTag})
+	send, overQuota := quota.Check(100)
+	assert.True(t, send)
+	assert.False(t, overQuota)
+	send, overQuota = quota.Check(1)
+	assert.True(t, send)
+	assert.True(t, overQuota)
+}
+
+func TestQuotaSample(t *testing.T) {
+	quota := NewQuota(&QuotaConfig{LinesPerSecond: 1, Overflow: QuotaSample, SampleRate: 0.25})
+	quota.Check(1)
+
+	sent := 0
+	for i := 0; i < 8; i++ {
+		send, overQuota := quota.Check(1)
+		assert.True(t, overQuota)
+		if send {
+			sent++
+		}
+	}
+	assert.Equal(t, 2, sent)
+}
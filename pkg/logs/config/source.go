@@ -19,6 +19,8 @@ type LogSource struct {
 	inputs   map[string]bool
 	lock     *sync.Mutex
 	Messages *Messages
+	// Quota is shared by the pipelines processing the logs of the source, nil if not configured
+	Quota *Quota
 	// sourceType is the type of the source that we are tailing whereas Config.Type is the type of the tailer
 	// that reads log lines for this source. E.g, a sourceType == containerd and Config.Type == file means that
 	// the agent is tailing a file to read logs of a containerd container
@@ -27,7 +29,7 @@ type LogSource struct {
 
 // NewLogSource creates a new log source.
 func NewLogSource(name string, config *LogsConfig) *LogSource {
-	return &LogSource{
+	source := &LogSource{
 		Name:     name,
 		Config:   config,
 		Status:   NewLogStatus(),
@@ -35,6 +37,10 @@ func NewLogSource(name string, config *LogsConfig) *LogSource {
 		lock:     &sync.Mutex{},
 		Messages: NewMessages(),
 	}
+	if config != nil && config.Quota != nil {
+		source.Quota = NewQuota(config.Quota)
+	}
+	return source
 }
 
 // AddInput registers an input as being handled by this source.
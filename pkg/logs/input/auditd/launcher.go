@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package auditd
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// Launcher is in charge of starting and stopping new auditd tailers
+type Launcher struct {
+	sources          chan *config.LogSource
+	pipelineProvider pipeline.Provider
+	tailers          map[string]*Tailer
+	stop             chan struct{}
+}
+
+// NewLauncher returns a new Launcher.
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider) *Launcher {
+	return &Launcher{
+		sources:          sources.GetAddedForType(config.AuditdType),
+		pipelineProvider: pipelineProvider,
+		tailers:          make(map[string]*Tailer),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts new tailers.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			tailer := NewTailer(source, l.pipelineProvider.NextPipelineChan())
+			identifier := tailer.socketPath()
+			if _, exists := l.tailers[identifier]; exists {
+				// set up only one tailer per socket
+				continue
+			}
+			tailer.Start()
+			l.tailers[identifier] = tailer
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops all active tailers
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := restart.NewParallelStopper()
+	for identifier, tailer := range l.tailers {
+		stopper.Add(tailer)
+		delete(l.tailers, identifier)
+	}
+	stopper.Stop()
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package auditd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// endOfEventType is the type of the record closing a multi-record event
+const endOfEventType = "EOE"
+
+// Record is a single line sent by the audit dispatcher, ex:
+// type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no
+type Record struct {
+	Type      string
+	Timestamp string
+	Serial    uint64
+	Fields    map[string]string
+	Raw       string
+}
+
+// Event is a group of records sharing the same serial number
+type Event struct {
+	Timestamp string
+	Serial    uint64
+	Records   []*Record
+}
+
+// parseRecord parses a record in the string format of the audit dispatcher,
+// returns an error if the header is malformed.
+func parseRecord(line string) (*Record, error) {
+	record := &Record{
+		Fields: make(map[string]string),
+		Raw:    line,
+	}
+
+	// header: type=<type> msg=audit(<timestamp>:<serial>):
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "type=") || !strings.HasPrefix(fields[1], "msg=audit(") {
+		return nil, fmt.Errorf("invalid audit record header: %q", line)
+	}
+	record.Type = strings.TrimPrefix(fields[0], "type=")
+
+	header := strings.TrimSuffix(strings.TrimPrefix(fields[1], "msg=audit("), "):")
+	sep := strings.LastIndex(header, ":")
+	if sep == -1 {
+		return nil, fmt.Errorf("invalid audit record header: %q", line)
+	}
+	serial, err := strconv.ParseUint(header[sep+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit record serial: %q", line)
+	}
+	record.Timestamp = header[:sep]
+	record.Serial = serial
+
+	if len(fields) == 3 {
+		parseFields(fields[2], record.Fields)
+	}
+	return record, nil
+}
+
+// parseFields parses the key=value pairs of a record body, values
+// can be double quoted or single quoted when they contain spaces.
+func parseFields(body string, fields map[string]string) {
+	for len(body) > 0 {
+		body = strings.TrimLeft(body, " ")
+		eq := strings.Index(body, "=")
+		if eq == -1 {
+			return
+		}
+		key := body[:eq]
+		body = body[eq+1:]
+
+		var value string
+		if len(body) > 0 && (body[0] == '"' || body[0] == '\'') {
+			end := strings.IndexByte(body[1:], body[0])
+			if end == -1 {
+				value, body = body[1:], ""
+			} else {
+				value, body = body[1:end+1], body[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(body, ' ')
+			if end == -1 {
+				value, body = body, ""
+			} else {
+				value, body = body[:end], body[end:]
+			}
+		}
+		fields[key] = value
+	}
+}
+
+// getContent returns the event as a json-string, the raw records
+// are kept in "message" and the parsed ones in an "auditd" attribute.
+func (e *Event) getContent() []byte {
+	records := make([]map[string]string, 0, len(e.Records))
+	raw := make([]string, 0, len(e.Records))
+	for _, r := range e.Records {
+		record := make(map[string]string, len(r.Fields)+1)
+		for k, v := range r.Fields {
+			record[k] = v
+		}
+		record["type"] = r.Type
+		records = append(records, record)
+		raw = append(raw, r.Raw)
+	}
+
+	payload := map[string]interface{}{
+		"message": strings.Join(raw, "\n"),
+		"auditd": map[string]interface{}{
+			"timestamp": e.Timestamp,
+			"serial":    e.Serial,
+			"records":   records,
+		},
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		// ensure the message has some content if the json encoding failed
+		content = []byte(strings.Join(raw, "\n"))
+	}
+	return content
+}
+
+// getStatus returns warning for events reporting an anomaly,
+// info otherwise.
+func (e *Event) getStatus() string {
+	for _, r := range e.Records {
+		if strings.HasPrefix(r.Type, "ANOM_") {
+			return message.StatusWarning
+		}
+	}
+	return message.StatusInfo
+}
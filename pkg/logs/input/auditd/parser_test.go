@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package auditd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRecord(t *testing.T) {
+	line := `type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 comm="cat" exe="/usr/bin/cat" key="sshd config"`
+	record, err := parseRecord(line)
+	require.NoError(t, err)
+	assert.Equal(t, "SYSCALL", record.Type)
+	assert.Equal(t, "1364481363.243", record.Timestamp)
+	assert.Equal(t, uint64(24287), record.Serial)
+	assert.Equal(t, line, record.Raw)
+	assert.Equal(t, map[string]string{
+		"arch":    "c000003e",
+		"syscall": "2",
+		"success": "no",
+		"exit":    "-13",
+		"comm":    "cat",
+		"exe":     "/usr/bin/cat",
+		"key":     "sshd config",
+	}, record.Fields)
+
+	record, err = parseRecord(`type=USER_LOGIN msg=audit(1364481363.243:24288): pid=1 msg='op=login acct="root" res=failed'`)
+	require.NoError(t, err)
+	assert.Equal(t, `op=login acct="root" res=failed`, record.Fields["msg"])
+
+	record, err = parseRecord(`type=EOE msg=audit(1364481363.243:24287):`)
+	require.NoError(t, err)
+	assert.Equal(t, endOfEventType, record.Type)
+	assert.Len(t, record.Fields, 0)
+}
+
+func TestParseRecordInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"foo bar",
+		"type=SYSCALL arch=c000003e",
+		"type=SYSCALL msg=audit(1364481363.243): arch=c000003e",
+		"type=SYSCALL msg=audit(1364481363.243:foo): arch=c000003e",
+	} {
+		_, err := parseRecord(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestEventContent(t *testing.T) {
+	syscall, err := parseRecord(`type=SYSCALL msg=audit(1364481363.243:24287): syscall=2 success=no`)
+	require.NoError(t, err)
+	cwd, err := parseRecord(`type=CWD msg=audit(1364481363.243:24287): cwd="/root"`)
+	require.NoError(t, err)
+	event := &Event{Timestamp: "1364481363.243", Serial: 24287, Records: []*Record{syscall, cwd}}
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(event.getContent(), &payload))
+	assert.Equal(t, syscall.Raw+"\n"+cwd.Raw, payload["message"])
+	assert.Equal(t, map[string]interface{}{
+		"timestamp": "1364481363.243",
+		"serial":    24287.0,
+		"records": []interface{}{
+			map[string]interface{}{"type": "SYSCALL", "syscall": "2", "success": "no"},
+			map[string]interface{}{"type": "CWD", "cwd": "/root"},
+		},
+	}, payload["auditd"])
+	assert.Equal(t, message.StatusInfo, event.getStatus())
+
+	anomaly, err := parseRecord(`type=ANOM_PROMISCUOUS msg=audit(1364481363.243:24288): dev=eth0 prom=256`)
+	require.NoError(t, err)
+	event = &Event{Timestamp: "1364481363.243", Serial: 24288, Records: []*Record{anomaly}}
+	assert.Equal(t, message.StatusWarning, event.getStatus())
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package auditd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// managedRulesKey is the key set on the rules installed by the agent,
+// it is used to remove them without touching the other rules of the host.
+const managedRulesKey = "datadog"
+
+// auditctlCommand is the command used to manage the rules, for testing
+var auditctlCommand = "auditctl"
+
+// baselineRules is the managed ruleset, watching the changes on the
+// identity, authentication and audit configuration files, and the
+// loading of kernel modules.
+var baselineRules = []string{
+	"-w /etc/passwd -p wa",
+	"-w /etc/shadow -p wa",
+	"-w /etc/group -p wa",
+	"-w /etc/gshadow -p wa",
+	"-w /etc/sudoers -p wa",
+	"-w /etc/ssh/sshd_config -p wa",
+	"-w /etc/audit/ -p wa",
+	"-w /sbin/insmod -p x",
+	"-w /sbin/rmmod -p x",
+	"-w /sbin/modprobe -p x",
+	"-a always,exit -F arch=b64 -S init_module,finit_module,delete_module",
+}
+
+// installRules adds the baseline rules to the kernel, the rules that can't
+// be installed (ex: watches on missing files) are skipped.
+func installRules() {
+	installed := 0
+	for _, rule := range baselineRules {
+		args := append(strings.Fields(rule), "-k", managedRulesKey)
+		if err := auditctl(args...); err != nil {
+			log.Warnf("Could not install audit rule %q: %s", rule, err)
+			continue
+		}
+		installed++
+	}
+	log.Infof("Installed %d/%d baseline audit rules", installed, len(baselineRules))
+}
+
+// removeRules deletes the rules installed by installRules
+func removeRules() {
+	if err := auditctl("-D", "-k", managedRulesKey); err != nil {
+		log.Warnf("Could not remove the baseline audit rules: %s", err)
+	}
+}
+
+func auditctl(args ...string) error {
+	out, err := exec.Command(auditctlCommand, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
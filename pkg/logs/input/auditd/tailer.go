@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package auditd
+
+import (
+	"bufio"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// defaultSocketPath is the socket of the af_unix plugin of the audit dispatcher
+	defaultSocketPath = "/var/run/audispd_events"
+	// auditdIntegration is the source and service of the messages
+	auditdIntegration = "auditd"
+)
+
+var (
+	// reconnectDelay is the time waited before connecting again to the dispatcher
+	reconnectDelay = 10 * time.Second
+	// flushTimeout is the time after which an event is sent when no closing record was received
+	flushTimeout = time.Second
+)
+
+// Tailer reads the records of the audit dispatcher and sends one message per event.
+type Tailer struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	records    chan *Record
+	pending    *Event
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewTailer returns a new tailer.
+func NewTailer(source *config.LogSource, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		outputChan: outputChan,
+		records:    make(chan *Record),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start installs the baseline rules when enabled and starts reading the records.
+func (t *Tailer) Start() {
+	if t.source.Config.BaselineRules {
+		installRules()
+	}
+	t.source.AddInput(t.socketPath())
+	log.Info("Start tailing audit records from ", t.socketPath())
+	go t.read()
+	go t.forward()
+}
+
+// Stop stops the tailer and removes the baseline rules.
+func (t *Tailer) Stop() {
+	log.Info("Stop tailing audit records from ", t.socketPath())
+	close(t.stop)
+	<-t.done
+	t.source.RemoveInput(t.socketPath())
+	if t.source.Config.BaselineRules {
+		removeRules()
+	}
+}
+
+// socketPath returns the path of the dispatcher socket
+func (t *Tailer) socketPath() string {
+	if t.source.Config.Path != "" {
+		return t.source.Config.Path
+	}
+	return defaultSocketPath
+}
+
+// read connects to the dispatcher socket and parses the records,
+// reconnecting until the tailer is stopped.
+func (t *Tailer) read() {
+	for {
+		conn, err := net.Dial("unix", t.socketPath())
+		if err != nil {
+			t.source.Status.Error(err)
+			log.Warnf("Could not connect to the audit dispatcher, retrying in %s: %s", reconnectDelay, err)
+		} else {
+			t.source.Status.Success()
+			t.readConn(conn)
+		}
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// readConn parses the records read on conn until it is closed or the tailer is stopped.
+func (t *Tailer) readConn(conn net.Conn) {
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-t.stop:
+		case <-closed:
+		}
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		record, err := parseRecord(scanner.Text())
+		if err != nil {
+			log.Debug(err)
+			continue
+		}
+		select {
+		case t.records <- record:
+		case <-t.stop:
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warnf("Could not read audit records: %s", err)
+	}
+}
+
+// forward groups the records by event and sends the events to the pipeline.
+func (t *Tailer) forward() {
+	defer close(t.done)
+	flushTimer := time.NewTimer(flushTimeout)
+	defer flushTimer.Stop()
+	for {
+		select {
+		case <-t.stop:
+			t.flush()
+			return
+		case <-flushTimer.C:
+			t.flush()
+		case record := <-t.records:
+			if t.pending != nil && t.pending.Serial != record.Serial {
+				t.flush()
+			}
+			if record.Type == endOfEventType {
+				t.flush()
+				continue
+			}
+			if t.pending == nil {
+				t.pending = &Event{Timestamp: record.Timestamp, Serial: record.Serial}
+			}
+			t.pending.Records = append(t.pending.Records, record)
+			if !flushTimer.Stop() {
+				select {
+				case <-flushTimer.C:
+				default:
+				}
+			}
+			flushTimer.Reset(flushTimeout)
+		}
+	}
+}
+
+// flush sends the pending event to the pipeline
+func (t *Tailer) flush() {
+	if t.pending == nil {
+		return
+	}
+	origin := message.NewOrigin(t.source)
+	origin.SetSource(auditdIntegration)
+	origin.SetService(auditdIntegration)
+	t.outputChan <- message.NewMessage(t.pending.getContent(), origin, t.pending.getStatus())
+	t.pending = nil
+}
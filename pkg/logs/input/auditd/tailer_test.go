@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package auditd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestTailerGroupsRecordsByEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "audispd_events")
+
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.AuditdType, Path: socketPath})
+	outputChan := make(chan *message.Message, 10)
+	tailer := NewTailer(source, outputChan)
+	tailer.Start()
+	defer tailer.Stop()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	records := []string{
+		`type=SYSCALL msg=audit(1364481363.243:24287): syscall=2 success=no`,
+		`type=CWD msg=audit(1364481363.243:24287): cwd="/root"`,
+		`type=EOE msg=audit(1364481363.243:24287):`,
+		`type=USER_LOGIN msg=audit(1364481364.001:24288): pid=1 res=failed`,
+		`type=CONFIG_CHANGE msg=audit(1364481365.001:24289): op=add_rule res=1`,
+	}
+	_, err = fmt.Fprintln(conn, strings.Join(records, "\n"))
+	require.NoError(t, err)
+
+	var msg *message.Message
+	for i, expected := range []string{"24287", "24288", "24289"} {
+		select {
+		case msg = <-outputChan:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for event", "event %d", i)
+		}
+		assert.Contains(t, string(msg.Content), `"serial":`+expected)
+		assert.Equal(t, auditdIntegration, msg.Origin.Source())
+	}
+	assert.Contains(t, string(msg.Content), "CONFIG_CHANGE")
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -23,8 +24,16 @@ import (
 // When a docker launcher can not be initialized properly and when the log collection is enabled for all containers,
 // the launcher will attempt to initialize a kubernetes launcher which will detect and tail all the logs files localized
 // in '/var/log/pods' of all the containers running on the kubernetes cluster.
+// When container_runtime selects another runtime than docker, the kubernetes launcher
+// is used directly.
 func NewLauncher(collectAll bool, sources *config.LogSources, services *service.Services, pipelineProvider pipeline.Provider, registry auditor.Registry) restart.Restartable {
 	switch {
+	case !containers.IsRuntimeSelected(containers.RuntimeNameDocker):
+		kubernetesLauncher, err := kubernetes.NewLauncher(sources, services)
+		if err == nil {
+			return kubernetesLauncher
+		}
+		log.Warnf("Could not setup the kubernetes launcher: %v", err)
 	case collectAll:
 		// attempt to initialize a docker launcher
 		launcher, err := docker.NewLauncher(sources, services, pipelineProvider, registry)
@@ -207,13 +207,22 @@ func (l *Launcher) getConfigPath(container kubelet.ContainerStatus) string {
 	return fmt.Sprintf("%s/%s.%s", configPathPrefix, container.Name, configPathSuffix)
 }
 
+// getOCIAnnotations returns the annotations of the OCI spec of a container,
+// overridden in tests
+var getOCIAnnotations = ociAnnotations
+
 // getAnnotation returns the logs-config annotation for container if present.
+// The annotation of the pod takes precedence over the one of the OCI spec of
+// the container, set by the containerd runtimes.
 // FIXME: Reuse the annotation logic from AD
 func (l *Launcher) getAnnotation(pod *kubelet.Pod, container kubelet.ContainerStatus) string {
 	configPath := l.getConfigPath(container)
 	if annotation, exists := pod.Metadata.Annotations[configPath]; exists {
 		return annotation
 	}
+	if annotation, exists := getOCIAnnotations(container.ID)[configPath]; exists {
+		return annotation
+	}
 	return ""
 }
 
@@ -227,9 +236,10 @@ func (l *Launcher) getPath(pod *kubelet.Pod, container kubelet.ContainerStatus)
 	return fmt.Sprintf("%s/%s/%s/*.log", podsDirectoryPath, pod.Metadata.UID, container.Name)
 }
 
-// getTags returns all the tags of the container
+// getTags returns all the tags of the container, the containerd containers
+// are looked up by their canonical entity name
 func (l *Launcher) getTags(container kubelet.ContainerStatus) []string {
-	tags, _ := tagger.Tag(container.ID, true)
+	tags, _ := tagger.Tag(containers.CanonicalEntityName(container.ID), true)
 	return tags
 }
 
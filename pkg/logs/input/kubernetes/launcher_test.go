@@ -103,6 +103,50 @@ func TestGetSourceShouldFailWithInvalidAutoDiscoveryAnnotation(t *testing.T) {
 	assert.Nil(t, source)
 }
 
+func TestGetSourceShouldBeOverridenByOCIAnnotation(t *testing.T) {
+	defer func() { getOCIAnnotations = ociAnnotations }()
+	getOCIAnnotations = func(containerID string) map[string]string {
+		if containerID != "containerd://boo" {
+			return nil
+		}
+		return map[string]string{
+			"ad.datadoghq.com/foo.logs": `[{"source":"oci_source","service":"oci_service","log_processing_rules":[{"type":"exclude_at_match","name":"exclude_health","pattern":"GET /health"}]}]`,
+		}
+	}
+	launcher := &Launcher{}
+	container := kubelet.ContainerStatus{
+		Name:  "foo",
+		Image: "bar",
+		ID:    "containerd://boo",
+	}
+	pod := &kubelet.Pod{
+		Metadata: kubelet.PodMetadata{
+			Name:      "fuz",
+			Namespace: "buu",
+			UID:       "baz",
+		},
+		Status: kubelet.Status{
+			Containers: []kubelet.ContainerStatus{container},
+		},
+	}
+
+	source, err := launcher.getSource(pod, container)
+	assert.Nil(t, err)
+	assert.Equal(t, "oci_source", source.Config.Source)
+	assert.Equal(t, "oci_service", source.Config.Service)
+	assert.Len(t, source.Config.ProcessingRules, 1)
+	assert.Equal(t, "exclude_health", source.Config.ProcessingRules[0].Name)
+
+	// The annotation of the pod takes precedence
+	pod.Metadata.Annotations = map[string]string{
+		"ad.datadoghq.com/foo.logs": `[{"source":"pod_source","service":"pod_service"}]`,
+	}
+	source, err = launcher.getSource(pod, container)
+	assert.Nil(t, err)
+	assert.Equal(t, "pod_source", source.Config.Source)
+	assert.Len(t, source.Config.ProcessingRules, 0)
+}
+
 func TestGetSourceAddContainerdParser(t *testing.T) {
 	launcher := &Launcher{}
 	container := kubelet.ContainerStatus{
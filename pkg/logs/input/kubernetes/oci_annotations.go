@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet,containerd
+
+package kubernetes
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ociAnnotations returns the annotations of the OCI spec of a containerd
+// container, eg. set by nerdctl or by the pod_annotations of the CRI plugin.
+// It returns nil for the containers of the other runtimes.
+func ociAnnotations(containerID string) map[string]string {
+	runtime, id := containers.SplitEntityName(containerID)
+	if runtime != containers.RuntimeNameContainerd {
+		return nil
+	}
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil {
+		log.Debugf("Cannot read the annotations of container %s: %v", id, err)
+		return nil
+	}
+	ctn, err := cu.LoadContainer(id)
+	if err != nil {
+		log.Debugf("Cannot read the annotations of container %s: %v", id, err)
+		return nil
+	}
+	spec, err := cu.Spec(ctn)
+	if err != nil {
+		log.Debugf("Cannot read the annotations of container %s: %v", id, err)
+		return nil
+	}
+	return spec.Annotations
+}
@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet,!containerd
+
+package kubernetes
+
+// ociAnnotations returns nil, the OCI specs are read from containerd
+func ociAnnotations(containerID string) map[string]string {
+	return nil
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package lifecycle
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+)
+
+// Launcher is in charge of starting and stopping the lifecycle tailer
+type Launcher struct {
+	sources          chan *config.LogSource
+	pipelineProvider pipeline.Provider
+	tailer           *Tailer
+	stop             chan struct{}
+}
+
+// NewLauncher returns a new Launcher.
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider) *Launcher {
+	return &Launcher{
+		sources:          sources.GetAddedForType(config.ContainerLifecycleType),
+		pipelineProvider: pipelineProvider,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts the tailer on the first source.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			if l.tailer != nil {
+				// the transitions are sent once, whatever the number of sources
+				continue
+			}
+			l.tailer = NewTailer(source, l.pipelineProvider.NextPipelineChan())
+			l.tailer.Start()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the tailer
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	if l.tailer != nil {
+		l.tailer.Stop()
+		l.tailer = nil
+	}
+}
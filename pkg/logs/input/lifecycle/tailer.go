@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/containerd/containerd/namespaces"
+
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// lifecycleSource is the source of the messages
+	lifecycleSource = "container_lifecycle"
+	// lifecycleService is the service of the messages
+	lifecycleService = "containerd"
+)
+
+// reconnectDelay is the time waited before subscribing again to the events
+var reconnectDelay = 10 * time.Second
+
+// Tailer sends one message per lifecycle transition of the containerd
+// containers: created, running, exited and deleted.
+type Tailer struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	tracker    *containerd.LifecycleTracker
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewTailer returns a new tailer.
+func NewTailer(source *config.LogSource, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		outputChan: outputChan,
+		tracker:    containerd.NewLifecycleTracker(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts subscribing to the events of containerd.
+func (t *Tailer) Start() {
+	t.source.AddInput(lifecycleService)
+	log.Info("Start tailing the container lifecycle events of containerd")
+	go t.run()
+}
+
+// Stop stops the tailer.
+func (t *Tailer) Stop() {
+	log.Info("Stop tailing the container lifecycle events of containerd")
+	close(t.stop)
+	<-t.done
+	t.source.RemoveInput(lifecycleService)
+}
+
+// run subscribes to the events until the tailer is stopped, subscribing
+// again when the stream breaks
+func (t *Tailer) run() {
+	defer close(t.done)
+	for {
+		cu, err := containerd.GetContainerdUtil(nil)
+		if err == nil {
+			t.source.Status.Success()
+			err = t.stream(cu)
+		}
+		if err != nil {
+			t.source.Status.Error(err)
+			log.Warnf("Could not receive the containerd events, subscribing again in %s: %s", reconnectDelay, err)
+		}
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// stream forwards the transitions of the events of the subscription until
+// it breaks or the tailer is stopped
+func (t *Tailer) stream(cu containerd.ContainerdItf) error {
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), cu.Namespace()))
+	defer cancel()
+	filters := containerdconfig.NamespaceFilter().EventFilters(containerd.LifecycleFilters...)
+	messages, errs := cu.GetEvents().Subscribe(ctx, filters...)
+	for {
+		select {
+		case <-t.stop:
+			return nil
+		case envelope := <-messages:
+			transition, err := t.tracker.HandleEnvelope(envelope)
+			if err != nil {
+				log.Debugf("Cannot handle containerd event %s: %s", envelope.Topic, err)
+				continue
+			}
+			if transition != nil {
+				t.send(transition)
+			}
+		case err := <-errs:
+			if err == nil {
+				err = fmt.Errorf("event stream closed")
+			}
+			return err
+		}
+	}
+}
+
+// send sends the message of a transition to the pipeline
+func (t *Tailer) send(transition *containerd.LifecycleTransition) {
+	origin := message.NewOrigin(t.source)
+	origin.SetSource(lifecycleSource)
+	origin.SetService(lifecycleService)
+	tags, err := tagger.Tag(containerd.EntityID(transition.ContainerID), true)
+	if err != nil {
+		log.Debugf("no tags for %s: %s", transition.ContainerID, err)
+	}
+	origin.SetTags(append(tags, "containerd_namespace:"+transition.Namespace))
+	select {
+	case t.outputChan <- message.NewMessage(getContent(transition), origin, getStatus(transition)):
+	case <-t.stop:
+	}
+}
+
+// getContent returns the structured content of the message of a transition
+func getContent(transition *containerd.LifecycleTransition) []byte {
+	attributes := map[string]interface{}{
+		"namespace":    transition.Namespace,
+		"container_id": transition.ContainerID,
+		"from":         transition.From,
+		"to":           transition.To,
+		"timestamp":    transition.Timestamp.UnixNano() / int64(time.Millisecond),
+	}
+	msg := fmt.Sprintf("Container %s of namespace %s %s", transition.ContainerID, transition.Namespace, transition.To)
+	if transition.To == containerd.LifecycleExited {
+		attributes["exit_code"] = transition.ExitCode
+		attributes["oom_killed"] = transition.OOMKilled
+		msg += fmt.Sprintf(" with code %d", transition.ExitCode)
+		if transition.Signal > 0 {
+			attributes["signal"] = transition.Signal
+			msg += fmt.Sprintf(", killed by signal %d", transition.Signal)
+		}
+		if transition.OOMKilled {
+			msg += ", out of memory"
+		}
+	}
+	payload := map[string]interface{}{
+		"message":             msg,
+		"container_lifecycle": attributes,
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		// ensure the message has some content if the json encoding failed
+		content = []byte(msg)
+	}
+	return content
+}
+
+// getStatus returns the status of the message of a transition, the exits
+// on error are warnings, and the OOM kills are errors
+func getStatus(transition *containerd.LifecycleTransition) string {
+	switch {
+	case transition.OOMKilled:
+		return message.StatusError
+	case transition.To == containerd.LifecycleExited && transition.ExitCode != 0:
+		return message.StatusWarning
+	}
+	return message.StatusInfo
+}
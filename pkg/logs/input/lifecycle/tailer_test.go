@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package lifecycle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestGetContent(t *testing.T) {
+	ts := time.Unix(1540000000, 500000000)
+	for name, tc := range map[string]struct {
+		transition *containerd.LifecycleTransition
+		message    string
+		attributes map[string]interface{}
+		status     string
+	}{
+		"started": {
+			transition: &containerd.LifecycleTransition{Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: containerd.LifecycleCreated, To: containerd.LifecycleRunning},
+			message:    "Container redis of namespace k8s.io running",
+			attributes: map[string]interface{}{"namespace": "k8s.io", "container_id": "redis", "from": "created", "to": "running", "timestamp": float64(1540000000500)},
+			status:     message.StatusInfo,
+		},
+		"exited": {
+			transition: &containerd.LifecycleTransition{Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: containerd.LifecycleRunning, To: containerd.LifecycleExited, ExitCode: 1},
+			message:    "Container redis of namespace k8s.io exited with code 1",
+			attributes: map[string]interface{}{"namespace": "k8s.io", "container_id": "redis", "from": "running", "to": "exited", "timestamp": float64(1540000000500), "exit_code": float64(1), "oom_killed": false},
+			status:     message.StatusWarning,
+		},
+		"oom killed": {
+			transition: &containerd.LifecycleTransition{Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: containerd.LifecycleRunning, To: containerd.LifecycleExited, ExitCode: 137, Signal: 9, OOMKilled: true},
+			message:    "Container redis of namespace k8s.io exited with code 137, killed by signal 9, out of memory",
+			attributes: map[string]interface{}{"namespace": "k8s.io", "container_id": "redis", "from": "running", "to": "exited", "timestamp": float64(1540000000500), "exit_code": float64(137), "signal": float64(9), "oom_killed": true},
+			status:     message.StatusError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var payload struct {
+				Message    string                 `json:"message"`
+				Attributes map[string]interface{} `json:"container_lifecycle"`
+			}
+			require.NoError(t, json.Unmarshal(getContent(tc.transition), &payload))
+			assert.Equal(t, tc.message, payload.Message)
+			assert.Equal(t, tc.attributes, payload.Attributes)
+			assert.Equal(t, tc.status, getStatus(tc.transition))
+		})
+	}
+}
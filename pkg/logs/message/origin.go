@@ -72,6 +72,11 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags adds tags to the origin.
+func (o *Origin) AddTags(tags ...string) {
+	o.tags = append(o.tags, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
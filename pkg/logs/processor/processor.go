@@ -52,6 +52,10 @@ func (p *Processor) run() {
 	for msg := range p.inputChan {
 		metrics.LogsDecoded.Add(1)
 		if shouldProcess, redactedMsg := applyRedactingRules(msg); shouldProcess {
+			if !applyQuota(msg) {
+				metrics.LogsOverQuota.Add(1)
+				continue
+			}
 			metrics.LogsProcessed.Add(1)
 
 			// Encode the message to its final format
@@ -66,6 +70,20 @@ func (p *Processor) run() {
 	}
 }
 
+// applyQuota returns whether a message should be sent depending on the quota
+// of its source, the messages over quota may be tagged instead of dropped
+func applyQuota(msg *message.Message) bool {
+	quota := msg.Origin.LogSource.Quota
+	if quota == nil {
+		return true
+	}
+	send, overQuota := quota.Check(len(msg.Content))
+	if send && overQuota {
+		msg.Origin.AddTags(config.QuotaExceededTag)
+	}
+	return send
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
 // and a copy of the message with some fields redacted, depending on config
 func applyRedactingRules(msg *message.Message) (bool, []byte) {
@@ -147,3 +147,29 @@ func TestTruncate(t *testing.T) {
 	_, redactedMessage = applyRedactingRules(newMessage([]byte("hello"), source, ""))
 	assert.Equal(t, []byte("hello"), redactedMessage)
 }
+
+func TestQuota(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		Quota: &config.QuotaConfig{LinesPerSecond: 1, Overflow: config.QuotaTag},
+	})
+
+	msg := newMessage([]byte("hello"), source, "")
+	assert.True(t, applyQuota(msg))
+	assert.NotContains(t, msg.Origin.Tags(), config.QuotaExceededTag)
+
+	msg = newMessage([]byte("world"), source, "")
+	assert.True(t, applyQuota(msg))
+	assert.Contains(t, msg.Origin.Tags(), config.QuotaExceededTag)
+
+	source = config.NewLogSource("", &config.LogsConfig{
+		Quota: &config.QuotaConfig{LinesPerSecond: 1},
+	})
+	assert.True(t, applyQuota(newMessage([]byte("hello"), source, "")))
+	assert.False(t, applyQuota(newMessage([]byte("world"), source, "")))
+
+	// No quota
+	source = config.NewLogSource("", &config.LogsConfig{})
+	for i := 0; i < 10; i++ {
+		assert.True(t, applyQuota(newMessage([]byte("hello"), source, "")))
+	}
+}
@@ -131,6 +131,9 @@ func toDictionary(c *config.LogsConfig) map[string]interface{} {
 	case config.WindowsEventType:
 		dictionary["ChannelPath"] = c.ChannelPath
 		dictionary["Query"] = c.Query
+	case config.AuditdType:
+		dictionary["Path"] = c.Path
+		dictionary["BaselineRules"] = c.BaselineRules
 	}
 	for k, v := range dictionary {
 		if v == "" {
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/metadata/containerimages"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	"github.com/DataDog/datadog-agent/pkg/util"
+)
+
+// ContainerImagesCollector sends the inventory of the images of the
+// containers of the host. It is only scheduled if listed in
+// metadata_providers.
+type ContainerImagesCollector struct{}
+
+// Send collects the data needed and submits the payload
+func (ci *ContainerImagesCollector) Send(s *serializer.Serializer) error {
+	hostname, _ := util.GetHostname()
+
+	images, err := containerimages.GetPayload(hostname)
+	if err != nil {
+		return fmt.Errorf("unable to list the container images: %s", err)
+	}
+	payload := map[string]interface{}{
+		"container_images": images,
+	}
+	if err := s.SendJSONToV1Intake(payload); err != nil {
+		return fmt.Errorf("unable to serialize container images metadata payload, %s", err)
+	}
+	return nil
+}
+
+func init() {
+	catalog["container_images"] = new(ContainerImagesCollector)
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerimages
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// GetPayload returns the images of the containers of every collected
+// containerd namespace. The content is read from the containerd content
+// store, the images are not pulled nor unpacked.
+func GetPayload(hostname string) (*Payload, error) {
+	utils, err := containerd.GetNamespacedUtils()
+	if err != nil {
+		return nil, err
+	}
+	payload := &Payload{
+		Images: []Image{},
+		Meta:   map[string]string{"host": hostname},
+	}
+	for _, cu := range utils {
+		images, err := containerd.ListContainerImages(cu)
+		if err != nil {
+			log.Debugf("Could not list the images of namespace %s: %s", cu.Namespace(), err)
+			continue
+		}
+		for _, image := range images {
+			payload.Images = append(payload.Images, newImage(cu.Namespace(), image))
+		}
+	}
+	return payload, nil
+}
+
+func newImage(namespace string, image containerd.ContainerImage) Image {
+	img := Image{
+		Name:         image.Name,
+		Namespace:    namespace,
+		Runtime:      containers.RuntimeNameContainerd,
+		Digest:       image.Digest.String(),
+		MediaType:    image.MediaType,
+		ConfigDigest: image.ConfigDigest.String(),
+		Layers:       make([]Layer, 0, len(image.Layers)),
+		ContainerIDs: image.ContainerIDs,
+		OS:           image.Platform.OS,
+		Architecture: image.Platform.Architecture,
+		Variant:      image.Platform.Variant,
+	}
+	for _, layer := range image.Layers {
+		img.Layers = append(img.Layers, Layer{
+			Digest:    layer.Digest.String(),
+			MediaType: layer.MediaType,
+			Size:      layer.Size,
+			DiffID:    layer.DiffID.String(),
+		})
+	}
+	return img
+}
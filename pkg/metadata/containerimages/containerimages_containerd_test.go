@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerimages
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func TestNewImage(t *testing.T) {
+	image := containerd.ContainerImage{
+		ImageManifest: containerd.ImageManifest{
+			Name:         "docker.io/library/redis:7",
+			Digest:       digest.FromString("index"),
+			MediaType:    ocispec.MediaTypeImageIndex,
+			ConfigDigest: digest.FromString("config"),
+			Platform:     ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			Layers: []containerd.ImageLayer{
+				{Digest: digest.FromString("layer-1"), MediaType: ocispec.MediaTypeImageLayerGzip, Size: 100, DiffID: digest.FromString("diff-1")},
+				{Digest: digest.FromString("layer-2"), MediaType: ocispec.MediaTypeImageLayerGzip, Size: 200},
+			},
+		},
+		ContainerIDs: []string{"redis-1", "redis-2"},
+	}
+
+	assert.Equal(t, Image{
+		Name:         "docker.io/library/redis:7",
+		Namespace:    "k8s.io",
+		Runtime:      "containerd",
+		Digest:       digest.FromString("index").String(),
+		MediaType:    ocispec.MediaTypeImageIndex,
+		ConfigDigest: digest.FromString("config").String(),
+		Layers: []Layer{
+			{Digest: digest.FromString("layer-1").String(), MediaType: ocispec.MediaTypeImageLayerGzip, Size: 100, DiffID: digest.FromString("diff-1").String()},
+			{Digest: digest.FromString("layer-2").String(), MediaType: ocispec.MediaTypeImageLayerGzip, Size: 200},
+		},
+		ContainerIDs: []string{"redis-1", "redis-2"},
+		OS:           "linux",
+		Architecture: "arm64",
+		Variant:      "v8",
+	}, newImage("k8s.io", image))
+}
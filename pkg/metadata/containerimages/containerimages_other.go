@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !containerd
+
+package containerimages
+
+import "errors"
+
+// GetPayload is only implemented for containerd
+func GetPayload(hostname string) (*Payload, error) {
+	return nil, errors.New("the container images are only collected from containerd, build with the containerd tag")
+}
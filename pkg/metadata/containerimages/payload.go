@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package containerimages collects the manifests and layers of the images
+// of the containers running on the host, for the image inventory.
+package containerimages
+
+// Payload handles the JSON unmarshalling of the container images payload
+type Payload struct {
+	Images []Image           `json:"images"`
+	Meta   map[string]string `json:"meta"`
+}
+
+// Image is the manifest of an image used by containers of the host
+type Image struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Runtime is the container runtime storing the image, eg. containerd
+	Runtime      string   `json:"runtime"`
+	Digest       string   `json:"digest"`
+	MediaType    string   `json:"media_type"`
+	ConfigDigest string   `json:"config_digest"`
+	Layers       []Layer  `json:"layers"`
+	ContainerIDs []string `json:"container_ids"`
+	// OS, Architecture and Variant are the platform of the manifest run by
+	// the containers, omitted if the image config is not in the content store
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Layer is a layer of an image manifest
+type Layer struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+	// DiffID is the digest of the uncompressed layer, omitted if the
+	// image config is not in the content store
+	DiffID string `json:"diff_id,omitempty"`
+}
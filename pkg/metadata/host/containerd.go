@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package host
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/metadata/host/container"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func init() {
+	container.RegisterMetadataProvider("containerd", getContainerdMetadata)
+}
+
+func getContainerdMetadata() (map[string]string, error) {
+	opts := containerd.DefaultProvider().ConfigOptions()
+	cu, err := containerd.GetContainerdUtil(&opts)
+	if err != nil {
+		return make(map[string]string), err
+	}
+	return containerdMetadata(cu, opts, containerdconfig.NamespaceFilter())
+}
+
+// containerdMetadata returns the version of the daemon, and the socket, the
+// flavor, the collected namespaces and the default runtime handler when
+// known, for the runtime breakdowns of the fleet
+func containerdMetadata(cu containerd.ContainerdItf, opts containerd.Options, filter containerd.NamespaceFilter) (map[string]string, error) {
+	metadata := make(map[string]string)
+	v, err := cu.Metadata()
+	if err != nil {
+		return metadata, err
+	}
+	metadata["containerd_version"] = v.Version
+	metadata["containerd_revision"] = v.Revision
+
+	if namespaces, err := containerd.GetNamespaces(cu, filter); err == nil {
+		metadata["containerd_namespaces"] = strings.Join(namespaces, ",")
+	} else {
+		log.Debugf("Cannot list the containerd namespaces for the host metadata: %s", err)
+	}
+	// The socket and the configuration of the daemon are not local to the
+	// agents reading the containers from the cluster-agent
+	if opts.ViaClusterAgent {
+		return metadata, nil
+	}
+	metadata["containerd_socket"] = opts.SocketPath
+	metadata["containerd_flavor"] = string(opts.Flavor)
+	if runtime, err := containerd.ReadDefaultRuntimeName(opts.ConfigPath); err == nil {
+		metadata["containerd_default_runtime"] = runtime
+	} else {
+		log.Debugf("Cannot read the containerd default runtime for the host metadata: %s", err)
+	}
+
+	return metadata, nil
+}
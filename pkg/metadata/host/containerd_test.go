@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package host
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	containerdclient "github.com/containerd/containerd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+// unreachableUtil is a util whose daemon does not answer
+type unreachableUtil struct {
+	*containerdtest.Util
+}
+
+func (unreachableUtil) Metadata() (containerdclient.Version, error) {
+	return containerdclient.Version{}, &containerd.Error{Kind: containerd.ErrNotServing, Err: errors.New("connection refused")}
+}
+
+func TestContainerdMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.toml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte("version = 2\n"), 0644))
+
+	daemon := containerdtest.NewDaemon()
+	for _, ns := range []string{"moby", "k8s.io", "default"} {
+		daemon.AddNamespace(ns)
+	}
+	cu := daemon.Util("k8s.io")
+	opts := containerd.Options{SocketPath: "/run/containerd/containerd.sock", Flavor: containerd.FlavorUpstream, ConfigPath: configPath}
+	filter := containerd.NewNamespaceFilter(nil, []string{"default"})
+
+	metadata, err := containerdMetadata(cu, opts, filter)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"containerd_version":         "v1.7.0",
+		"containerd_revision":        "containerdtest",
+		"containerd_namespaces":      "k8s.io,moby",
+		"containerd_socket":          "/run/containerd/containerd.sock",
+		"containerd_flavor":          "upstream",
+		"containerd_default_runtime": "runc",
+	}, metadata)
+
+	// The host of the agent reading the cluster-agent does not run the daemon
+	opts.ViaClusterAgent = true
+	metadata, err = containerdMetadata(cu, opts, filter)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"containerd_version":    "v1.7.0",
+		"containerd_revision":   "containerdtest",
+		"containerd_namespaces": "k8s.io,moby",
+	}, metadata)
+
+	_, err = containerdMetadata(unreachableUtil{cu}, opts, filter)
+	assert.Equal(t, containerd.ErrNotServing, containerd.ErrorKind(err))
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/metadata/listeningports"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	"github.com/DataDog/datadog-agent/pkg/util"
+)
+
+// ListeningPortsCollector sends the inventory of the sockets listening on
+// the host. It is only scheduled if listed in metadata_providers.
+type ListeningPortsCollector struct{}
+
+// Send collects the data needed and submits the payload
+func (lp *ListeningPortsCollector) Send(s *serializer.Serializer) error {
+	hostname, _ := util.GetHostname()
+
+	ports, err := listeningports.GetPayload(hostname)
+	if err != nil {
+		return fmt.Errorf("unable to list the listening ports: %s", err)
+	}
+	payload := map[string]interface{}{
+		"listening_ports": ports,
+	}
+	if err := s.SendJSONToV1Intake(payload); err != nil {
+		return fmt.Errorf("unable to serialize listening ports metadata payload, %s", err)
+	}
+	return nil
+}
+
+func init() {
+	catalog["listening_ports"] = new(ListeningPortsCollector)
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listeningports
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fingerprintTimeout = 500 * time.Millisecond
+	maxBannerSize      = 256
+)
+
+// bannerPrefixes map the greeting sent by a server on connection to its service
+var bannerPrefixes = []struct {
+	prefix  string
+	service string
+}{
+	{"SSH-", "ssh"},
+	{"220 ", "smtp_or_ftp"},
+	{"+OK", "pop3"},
+	{"* OK", "imap"},
+	{"RFB ", "vnc"},
+}
+
+// dialAddress returns the address to dial a listening socket, loopback
+// for the wildcard addresses
+func dialAddress(ip net.IP, port uint16) string {
+	host := ip.String()
+	switch {
+	case ip.Equal(net.IPv4zero):
+		host = "127.0.0.1"
+	case ip.Equal(net.IPv6unspecified):
+		host = "::1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// fingerprint guesses the service of a TCP port from the banner sent on
+// connection, or else from the answer to a HTTP request. Nothing else is
+// sent, so that the probe is harmless for the services. It returns an
+// empty string if the service is not recognized.
+func fingerprint(address string) string {
+	if service := probe(address, nil); service != "" {
+		return service
+	}
+	return probe(address, []byte("HEAD / HTTP/1.0\r\n\r\n"))
+}
+
+func probe(address string, request []byte) string {
+	conn, err := net.DialTimeout("tcp", address, fingerprintTimeout)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(fingerprintTimeout))
+
+	if request != nil {
+		if _, err = conn.Write(request); err != nil {
+			return ""
+		}
+	}
+	banner := make([]byte, maxBannerSize)
+	n, _ := conn.Read(banner)
+	return serviceFromBanner(banner[:n])
+}
+
+func serviceFromBanner(banner []byte) string {
+	if len(banner) == 0 {
+		return ""
+	}
+	text := string(banner)
+	if strings.HasPrefix(text, "HTTP/") {
+		return "http"
+	}
+	for _, p := range bannerPrefixes {
+		if strings.HasPrefix(text, p.prefix) {
+			return p.service
+		}
+	}
+	// The MySQL handshake starts with a 3 bytes length, a sequence ID of 0
+	// and the protocol version 10
+	if len(banner) > 5 && banner[3] == 0 && banner[4] == 10 {
+		return "mysql"
+	}
+	return "unknown"
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listeningports
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceFromBanner(t *testing.T) {
+	assert.Equal(t, "", serviceFromBanner(nil))
+	assert.Equal(t, "ssh", serviceFromBanner([]byte("SSH-2.0-OpenSSH_7.4\r\n")))
+	assert.Equal(t, "http", serviceFromBanner([]byte("HTTP/1.1 404 Not Found\r\n")))
+	assert.Equal(t, "mysql", serviceFromBanner([]byte{0x4a, 0, 0, 0, 10, '5', '.', '7'}))
+	assert.Equal(t, "unknown", serviceFromBanner([]byte("hello")))
+}
+
+func TestDialAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:22", dialAddress(net.IPv4zero, 22))
+	assert.Equal(t, "[::1]:8080", dialAddress(net.IPv6unspecified, 8080))
+	assert.Equal(t, "10.0.2.15:80", dialAddress(net.ParseIP("10.0.2.15"), 80))
+}
+
+func TestFingerprint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// An HTTP server sends nothing until it receives a request
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+					conn.Write([]byte("HTTP/1.0 200 OK\r\n\r\n"))
+				}
+			}(conn)
+		}
+	}()
+	assert.Equal(t, "http", fingerprint(ln.Addr().String()))
+
+	// Closed ports are not recognized
+	ln.Close()
+	assert.Equal(t, "", fingerprint(ln.Addr().String()))
+}
@@ -0,0 +1,253 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package listeningports
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// States of the sockets in /proc/net: listening TCP sockets, and the UDP
+// sockets that are bound but not connected
+const (
+	tcpListenState = "0A"
+	udpUnconnState = "07"
+)
+
+// socketFiles are the files of /proc/<pid>/net listing the sockets
+var socketFiles = []struct {
+	protocol string
+	state    string
+}{
+	{"tcp", tcpListenState},
+	{"tcp6", tcpListenState},
+	{"udp", udpUnconnState},
+	{"udp6", udpUnconnState},
+}
+
+// socket is a listening socket read from /proc/net
+type socket struct {
+	protocol string
+	ip       net.IP
+	port     uint16
+	inode    string
+}
+
+// process owns sockets
+type process struct {
+	pid   int
+	netns string
+}
+
+// GetPayload returns the sockets listening in every network namespace of
+// the host, with their owning process. The processes are read from the
+// procfs at container_proc_root, the host one when the agent runs in a
+// container with the host PID namespace.
+func GetPayload(hostname string) (*Payload, error) {
+	procRoot := config.Datadog.GetString("container_proc_root")
+	processes, err := listProcesses(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []Port{}
+	fingerprinting := config.Datadog.GetBool("listening_ports_fingerprinting")
+	agentNetns, _ := os.Readlink("/proc/self/ns/net")
+
+	for netns, pids := range groupByNetns(processes) {
+		inodes := socketInodes(procRoot, pids)
+		// The sockets of a namespace are listed in the net directory of
+		// any of its processes
+		sockets, err := readSockets(filepath.Join(procRoot, strconv.Itoa(pids[0]), "net"))
+		if err != nil {
+			log.Debugf("Cannot list the sockets of network namespace %s: %s", netns, err)
+			continue
+		}
+		for _, s := range sockets {
+			port := Port{
+				Protocol: s.protocol,
+				Address:  s.ip.String(),
+				Port:     s.port,
+			}
+			if pid, found := inodes[s.inode]; found {
+				port.PID = pid
+				port.Process = processName(procRoot, pid)
+				if containerID, err := metrics.ContainerIDForPID(pid); err == nil {
+					port.ContainerID = containerID
+				}
+			}
+			// Only the ports of the agent network namespace can be dialed
+			if fingerprinting && netns == agentNetns && strings.HasPrefix(s.protocol, "tcp") {
+				port.Service = fingerprint(dialAddress(s.ip, s.port))
+			}
+			ports = append(ports, port)
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].Address < ports[j].Address
+	})
+	return &Payload{
+		Ports: ports,
+		Meta: map[string]string{
+			"host": hostname,
+		},
+	}, nil
+}
+
+// listProcesses returns the processes of the procfs with their network
+// namespace, the processes whose namespace cannot be read are skipped
+func listProcesses(procRoot string) ([]process, error) {
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+	var processes []process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		netns, err := os.Readlink(filepath.Join(procRoot, entry.Name(), "ns", "net"))
+		if err != nil {
+			continue
+		}
+		processes = append(processes, process{pid: pid, netns: netns})
+	}
+	return processes, nil
+}
+
+func groupByNetns(processes []process) map[string][]int {
+	byNetns := make(map[string][]int)
+	for _, p := range processes {
+		byNetns[p.netns] = append(byNetns[p.netns], p.pid)
+	}
+	return byNetns
+}
+
+// socketInodes maps the socket inodes opened by the processes to their PID
+func socketInodes(procRoot string, pids []int) map[string]int {
+	inodes := make(map[string]int)
+	for _, pid := range pids {
+		fdDir := filepath.Join(procRoot, strconv.Itoa(pid), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if _, found := inodes[inode]; !found {
+				inodes[inode] = pid
+			}
+		}
+	}
+	return inodes
+}
+
+func processName(procRoot string, pid int) string {
+	comm, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// readSockets returns the listening sockets of a net directory of procfs
+func readSockets(netDir string) ([]socket, error) {
+	var sockets []socket
+	for _, file := range socketFiles {
+		f, err := os.Open(filepath.Join(netDir, file.protocol))
+		if os.IsNotExist(err) {
+			// IPv6 is disabled
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fileSockets, err := parseSockets(f, file.protocol, file.state)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %s", file.protocol, err)
+		}
+		sockets = append(sockets, fileSockets...)
+	}
+	return sockets, nil
+}
+
+// parseSockets parses a /proc/net/{tcp,udp}[6] file, keeping the sockets
+// in the given state
+func parseSockets(r io.Reader, protocol, state string) ([]socket, error) {
+	var sockets []socket
+	scanner := bufio.NewScanner(r)
+	// Skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		ip, port, err := parseAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, socket{
+			protocol: protocol,
+			ip:       ip,
+			port:     port,
+			inode:    fields[9],
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseAddress parses an address of /proc/net, eg. 0100007F:1F90. The IP
+// is made of 32 bits words in host byte order.
+func parseAddress(address string) (net.IP, uint16, error) {
+	parts := strings.Split(address, ":")
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("invalid address %q", address)
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", address)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port in %q", address)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+	return ip, uint16(port), nil
+}
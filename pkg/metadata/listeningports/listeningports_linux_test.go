@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package listeningports
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1389 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21384 1 0000000000000000 100 0 0 10 0
+   1: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 18342 1 0000000000000000 100 0 0 10 0
+   2: 0F02000A:0016 0202000A:C5E2 01 00000000:00000000 02:000A2D5E 00000000     0        0 40211 4 0000000000000000 20 4 31 10 -1
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 25671 1 0000000000000000 100 0 0 10 0
+   1: 00000000000000000000000001000000:0CEA 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 25672 1 0000000000000000 100 0 0 10 0
+`
+
+const procNetUDP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  12: 0100007F:1FBD 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 30015 2 0000000000000000 0
+`
+
+func TestParseSockets(t *testing.T) {
+	sockets, err := parseSockets(strings.NewReader(procNetTCP), "tcp", tcpListenState)
+	require.NoError(t, err)
+	assert.Equal(t, []socket{
+		{protocol: "tcp", ip: net.IPv4(127, 0, 0, 1).To4(), port: 5001, inode: "21384"},
+		{protocol: "tcp", ip: net.IPv4zero.To4(), port: 22, inode: "18342"},
+	}, sockets)
+
+	sockets, err = parseSockets(strings.NewReader(procNetTCP6), "tcp6", tcpListenState)
+	require.NoError(t, err)
+	require.Len(t, sockets, 2)
+	assert.Equal(t, "::", sockets[0].ip.String())
+	assert.Equal(t, uint16(8080), sockets[0].port)
+	assert.Equal(t, "::1", sockets[1].ip.String())
+	assert.Equal(t, uint16(3306), sockets[1].port)
+
+	_, err = parseSockets(strings.NewReader("header\n 0: 0100007F 00000000:0000 0A 0 0 0 0 0 0 1\n"), "tcp", tcpListenState)
+	assert.Error(t, err)
+}
+
+func TestGetPayload(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+
+	// Process 42 listens on the tcp and udp sockets, process 7 is in
+	// another network namespace with no socket
+	writeFile(t, procRoot, "42/net/tcp", procNetTCP)
+	writeFile(t, procRoot, "42/net/udp", procNetUDP)
+	writeFile(t, procRoot, "42/comm", "redis-server\n")
+	writeFile(t, procRoot, "42/cgroup", "")
+	writeFile(t, procRoot, "7/net/tcp", "header\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "42", "ns"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "42", "fd"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "7", "ns"), 0755))
+	require.NoError(t, os.Symlink("net:[4026531992]", filepath.Join(procRoot, "42", "ns", "net")))
+	require.NoError(t, os.Symlink("net:[4026532200]", filepath.Join(procRoot, "7", "ns", "net")))
+	require.NoError(t, os.Symlink("socket:[21384]", filepath.Join(procRoot, "42", "fd", "3")))
+	require.NoError(t, os.Symlink("/dev/null", filepath.Join(procRoot, "42", "fd", "4")))
+	require.NoError(t, os.Symlink("socket:[30015]", filepath.Join(procRoot, "42", "fd", "5")))
+
+	mockConfig := config.Mock()
+	mockConfig.Set("container_proc_root", procRoot)
+
+	payload, err := GetPayload("myhost")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "myhost"}, payload.Meta)
+	assert.Equal(t, []Port{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 5001, PID: 42, Process: "redis-server"},
+		{Protocol: "udp", Address: "127.0.0.1", Port: 8125, PID: 42, Process: "redis-server"},
+	}, payload.Ports)
+}
+
+func writeFile(t *testing.T, root, name, content string) {
+	path := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !linux
+
+package listeningports
+
+import "errors"
+
+// GetPayload is only implemented on Linux
+func GetPayload(hostname string) (*Payload, error) {
+	return nil, errors.New("the listening ports are only collected on Linux hosts")
+}
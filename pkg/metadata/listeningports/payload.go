@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package listeningports collects the sockets listening on the host with
+// the process and container owning them, for the service inventory.
+package listeningports
+
+// Payload handles the JSON unmarshalling of the listening ports payload
+type Payload struct {
+	Ports []Port            `json:"ports"`
+	Meta  map[string]string `json:"meta"`
+}
+
+// Port is a listening socket
+type Port struct {
+	// Protocol is tcp, tcp6, udp or udp6
+	Protocol    string `json:"protocol"`
+	Address     string `json:"address"`
+	Port        uint16 `json:"port"`
+	PID         int    `json:"pid,omitempty"`
+	Process     string `json:"process,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	// Service is guessed from the banner of the TCP ports if
+	// listening_ports_fingerprinting is enabled, eg. ssh or http
+	Service string `json:"service,omitempty"`
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+)
+
+// MetricCheckpoint is the serializable state of a metric, allowing another
+// agent process to resume its aggregation. Only the fields of its type are set.
+type MetricCheckpoint struct {
+	Type              MetricType       `json:"type"`
+	Value             float64          `json:"value,omitempty"`
+	Sampled           bool             `json:"sampled,omitempty"`
+	Interval          int64            `json:"interval,omitempty"`
+	HasPreviousSample bool             `json:"has_previous_sample,omitempty"`
+	PreviousSample    float64          `json:"previous_sample,omitempty"`
+	PreviousTimestamp float64          `json:"previous_timestamp,omitempty"`
+	Sample            float64          `json:"sample,omitempty"`
+	Timestamp         float64          `json:"timestamp,omitempty"`
+	Samples           []WeightedSample `json:"samples,omitempty"`
+	Sum               float64          `json:"sum,omitempty"`
+	Count             int64            `json:"count,omitempty"`
+	Values            []string         `json:"values,omitempty"`
+}
+
+// WeightedSample is a sample of a histogram
+type WeightedSample struct {
+	Value  float64 `json:"value"`
+	Weight int64   `json:"weight"`
+}
+
+// ContextMetricCheckpoint is the checkpoint of the metric of a context
+type ContextMetricCheckpoint struct {
+	ContextKey ckey.ContextKey  `json:"context_key"`
+	Metric     MetricCheckpoint `json:"metric"`
+}
+
+// Checkpoint returns the state of the metrics of every context
+func (m ContextMetrics) Checkpoint() []ContextMetricCheckpoint {
+	checkpoints := make([]ContextMetricCheckpoint, 0, len(m))
+	for contextKey, metric := range m {
+		cp, err := checkpointMetric(metric)
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, ContextMetricCheckpoint{ContextKey: contextKey, Metric: cp})
+	}
+	return checkpoints
+}
+
+// RestoreContextMetrics returns the ContextMetrics matching checkpoints
+func RestoreContextMetrics(checkpoints []ContextMetricCheckpoint) (ContextMetrics, error) {
+	m := MakeContextMetrics()
+	for _, cp := range checkpoints {
+		metric, err := restoreMetric(cp.Metric)
+		if err != nil {
+			return nil, err
+		}
+		m[cp.ContextKey] = metric
+	}
+	return m, nil
+}
+
+func checkpointMetric(metric Metric) (MetricCheckpoint, error) {
+	switch m := metric.(type) {
+	case *Gauge:
+		return MetricCheckpoint{Type: GaugeType, Value: m.gauge, Sampled: m.sampled}, nil
+	case *Count:
+		return MetricCheckpoint{Type: CountType, Value: m.value, Sampled: m.sampled}, nil
+	case *Counter:
+		return MetricCheckpoint{Type: CounterType, Value: m.value, Sampled: m.sampled, Interval: m.interval}, nil
+	case *Rate:
+		return MetricCheckpoint{
+			Type:              RateType,
+			PreviousSample:    m.previousSample,
+			PreviousTimestamp: m.previousTimestamp,
+			Sample:            m.sample,
+			Timestamp:         m.timestamp,
+		}, nil
+	case *MonotonicCount:
+		return MetricCheckpoint{
+			Type:              MonotonicCountType,
+			Value:             m.value,
+			Sampled:           m.sampledSinceLastFlush,
+			HasPreviousSample: m.hasPreviousSample,
+			PreviousSample:    m.previousSample,
+			Sample:            m.currentSample,
+		}, nil
+	case *Histogram:
+		cp := MetricCheckpoint{Type: HistogramType}
+		checkpointHistogram(m, &cp)
+		return cp, nil
+	case *Historate:
+		cp := MetricCheckpoint{
+			Type:              HistorateType,
+			Sampled:           m.sampled,
+			PreviousSample:    m.previousSample,
+			PreviousTimestamp: m.previousTimestamp,
+		}
+		checkpointHistogram(&m.histogram, &cp)
+		return cp, nil
+	case *Set:
+		cp := MetricCheckpoint{Type: SetType}
+		for value := range m.values {
+			cp.Values = append(cp.Values, value)
+		}
+		sort.Strings(cp.Values)
+		return cp, nil
+	default:
+		return MetricCheckpoint{}, fmt.Errorf("unsupported metric %T", metric)
+	}
+}
+
+func checkpointHistogram(h *Histogram, cp *MetricCheckpoint) {
+	cp.Interval = h.interval
+	cp.Sum = h.sum
+	cp.Count = h.count
+	for _, s := range h.samples {
+		cp.Samples = append(cp.Samples, WeightedSample{Value: s.value, Weight: s.weight})
+	}
+}
+
+func restoreMetric(cp MetricCheckpoint) (Metric, error) {
+	switch cp.Type {
+	case GaugeType:
+		return &Gauge{gauge: cp.Value, sampled: cp.Sampled}, nil
+	case CountType:
+		return &Count{value: cp.Value, sampled: cp.Sampled}, nil
+	case CounterType:
+		return &Counter{value: cp.Value, sampled: cp.Sampled, interval: cp.Interval}, nil
+	case RateType:
+		return &Rate{
+			previousSample:    cp.PreviousSample,
+			previousTimestamp: cp.PreviousTimestamp,
+			sample:            cp.Sample,
+			timestamp:         cp.Timestamp,
+		}, nil
+	case MonotonicCountType:
+		return &MonotonicCount{
+			value:                 cp.Value,
+			sampledSinceLastFlush: cp.Sampled,
+			hasPreviousSample:     cp.HasPreviousSample,
+			previousSample:        cp.PreviousSample,
+			currentSample:         cp.Sample,
+		}, nil
+	case HistogramType:
+		// The aggregates and percentiles come from the current configuration
+		h := NewHistogram(cp.Interval)
+		restoreHistogram(h, cp)
+		return h, nil
+	case HistorateType:
+		h := NewHistorate(cp.Interval)
+		restoreHistogram(&h.histogram, cp)
+		h.sampled = cp.Sampled
+		h.previousSample = cp.PreviousSample
+		h.previousTimestamp = cp.PreviousTimestamp
+		return h, nil
+	case SetType:
+		s := NewSet()
+		for _, value := range cp.Values {
+			s.values[value] = true
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported metric type %d", cp.Type)
+	}
+}
+
+func restoreHistogram(h *Histogram, cp MetricCheckpoint) {
+	h.sum = cp.Sum
+	h.count = cp.Count
+	for _, s := range cp.Samples {
+		h.samples = append(h.samples, weightSample{value: s.Value, weight: s.Weight})
+	}
+}
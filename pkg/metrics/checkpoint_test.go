@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+)
+
+// restoreThroughJSON checkpoints m, then restores it from its JSON encoding
+func restoreThroughJSON(t *testing.T, m ContextMetrics) ContextMetrics {
+	content, err := json.Marshal(m.Checkpoint())
+	require.NoError(t, err)
+	var checkpoints []ContextMetricCheckpoint
+	require.NoError(t, json.Unmarshal(content, &checkpoints))
+	restored, err := RestoreContextMetrics(checkpoints)
+	require.NoError(t, err)
+	return restored
+}
+
+func TestContextMetricsCheckpoint(t *testing.T) {
+	samples := []*MetricSample{
+		{Name: "gauge", Value: 1, Mtype: GaugeType, SampleRate: 1},
+		{Name: "count", Value: 2, Mtype: CountType, SampleRate: 1},
+		{Name: "counter", Value: 3, Mtype: CounterType, SampleRate: 1},
+		{Name: "rate", Value: 4, Mtype: RateType, SampleRate: 1},
+		{Name: "monotonic", Value: 5, Mtype: MonotonicCountType, SampleRate: 1},
+		{Name: "histogram", Value: 6, Mtype: HistogramType, SampleRate: 1},
+		{Name: "historate", Value: 7, Mtype: HistorateType, SampleRate: 1},
+		{Name: "set", RawValue: "abc", Mtype: SetType, SampleRate: 1},
+	}
+
+	original := MakeContextMetrics()
+	restored := MakeContextMetrics()
+	for _, s := range samples {
+		key := ckey.Generate(s.Name, "", nil)
+		original.AddSample(key, s, 10, 10)
+		restored.AddSample(key, s, 10, 10)
+	}
+	restored = restoreThroughJSON(t, restored)
+	assert.Equal(t, original, restored)
+
+	// The restored metrics keep aggregating the samples of the next process
+	for _, s := range samples {
+		next := *s
+		next.Value *= 2
+		next.RawValue = "def"
+		key := ckey.Generate(s.Name, "", nil)
+		original.AddSample(key, &next, 20, 10)
+		restored.AddSample(key, &next, 20, 10)
+	}
+	expectedSeries, expectedErrs := original.Flush(30)
+	series, errs := restored.Flush(30)
+	assert.ElementsMatch(t, expectedSeries, series)
+	assert.Equal(t, expectedErrs, errs)
+}
+
+func TestRestoreContextMetricsUnknownType(t *testing.T) {
+	_, err := RestoreContextMetrics([]ContextMetricCheckpoint{{Metric: MetricCheckpoint{Type: DistributionType}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// EventAnnotationRule maps an annotation of the container or pod an event
+// is about to a tag of the event, and/or to an alert type override.
+// For docker and containerd events, the container labels are used as
+// annotations.
+type EventAnnotationRule struct {
+	// Annotation is the key of the annotation
+	Annotation string `mapstructure:"annotation"`
+	// Value restricts the rule to the annotations having this value, if set
+	Value string `mapstructure:"value"`
+	// Tag is the name of the tag added to the event, its value
+	// being the annotation value
+	Tag string `mapstructure:"tag"`
+	// AlertType overrides the alert type of the event
+	AlertType string `mapstructure:"alert_type"`
+
+	alertType EventAlertType
+}
+
+// EventAnnotationRules is a list of rules applied in order, the
+// alert type of the last matching rule overriding the previous ones
+type EventAnnotationRules []EventAnnotationRule
+
+// GetEventAnnotationRules returns the valid rules of the
+// event_annotation_rules setting. Invalid rules are logged and skipped.
+func GetEventAnnotationRules() EventAnnotationRules {
+	if !config.Datadog.IsSet("event_annotation_rules") {
+		return nil
+	}
+	var raw []EventAnnotationRule
+	if err := config.Datadog.UnmarshalKey("event_annotation_rules", &raw); err != nil {
+		log.Errorf("Could not parse event_annotation_rules, ignoring them: %s", err)
+		return nil
+	}
+	return NewEventAnnotationRules(raw)
+}
+
+// NewEventAnnotationRules validates the given rules and returns the valid ones
+func NewEventAnnotationRules(raw []EventAnnotationRule) EventAnnotationRules {
+	var rules EventAnnotationRules
+	for _, rule := range raw {
+		if err := rule.validate(); err != nil {
+			log.Errorf("Ignoring event annotation rule on %q: %s", rule.Annotation, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (r *EventAnnotationRule) validate() error {
+	if r.Annotation == "" {
+		return fmt.Errorf("annotation is required")
+	}
+	if r.Tag == "" && r.AlertType == "" {
+		return fmt.Errorf("tag or alert_type is required")
+	}
+	if r.AlertType != "" {
+		alertType, err := GetAlertTypeFromString(r.AlertType)
+		if err != nil {
+			return err
+		}
+		r.alertType = alertType
+	}
+	return nil
+}
+
+// Apply adds the tags and overrides the alert type of the
+// event according to the annotations of its container or pod
+func (rules EventAnnotationRules) Apply(ev *Event, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	for _, rule := range rules {
+		value, found := annotations[rule.Annotation]
+		if !found || (rule.Value != "" && value != rule.Value) {
+			continue
+		}
+		if rule.Tag != "" {
+			tag := rule.Tag
+			if value != "" {
+				tag = fmt.Sprintf("%s:%s", rule.Tag, value)
+			}
+			if !containsTag(ev.Tags, tag) {
+				ev.Tags = append(ev.Tags, tag)
+			}
+		}
+		if rule.alertType != "" {
+			ev.AlertType = rule.alertType
+		}
+	}
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetEventAnnotationRules(t *testing.T) {
+	mockConfig := config.Mock()
+	assert.Nil(t, GetEventAnnotationRules())
+
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- annotation: example.com/team
+  tag: team
+- annotation: example.com/criticality
+  value: high
+  alert_type: error
+- annotation: example.com/missing-action
+- tag: missing-annotation
+- annotation: example.com/invalid
+  alert_type: critical
+`), &raw))
+	mockConfig.Set("event_annotation_rules", raw)
+
+	rules := GetEventAnnotationRules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "example.com/team", rules[0].Annotation)
+	assert.Equal(t, "team", rules[0].Tag)
+	assert.Equal(t, "high", rules[1].Value)
+	assert.Equal(t, EventAlertTypeError, rules[1].alertType)
+}
+
+func TestApplyEventAnnotationRules(t *testing.T) {
+	rules := NewEventAnnotationRules([]EventAnnotationRule{
+		{Annotation: "example.com/team", Tag: "team"},
+		{Annotation: "example.com/paging", Tag: "paging"},
+		{Annotation: "example.com/criticality", Value: "high", AlertType: "error"},
+		{Annotation: "example.com/muted", AlertType: "info"},
+	})
+	require.Len(t, rules, 4)
+
+	ev := Event{AlertType: EventAlertTypeWarning, Tags: []string{"image_name:redis"}}
+	rules.Apply(&ev, nil)
+	assert.Equal(t, EventAlertTypeWarning, ev.AlertType)
+	assert.Equal(t, []string{"image_name:redis"}, ev.Tags)
+
+	rules.Apply(&ev, map[string]string{
+		"example.com/team":        "storage",
+		"example.com/paging":      "",
+		"example.com/criticality": "low",
+	})
+	assert.Equal(t, EventAlertTypeWarning, ev.AlertType)
+	assert.Equal(t, []string{"image_name:redis", "team:storage", "paging"}, ev.Tags)
+
+	// Tags are not duplicated when several containers of the event match
+	rules.Apply(&ev, map[string]string{
+		"example.com/team":        "storage",
+		"example.com/criticality": "high",
+	})
+	assert.Equal(t, EventAlertTypeError, ev.AlertType)
+	assert.Equal(t, []string{"image_name:redis", "team:storage", "paging"}, ev.Tags)
+
+	// The last matching rule wins
+	rules.Apply(&ev, map[string]string{
+		"example.com/criticality": "high",
+		"example.com/muted":       "true",
+	})
+	assert.Equal(t, EventAlertTypeInfo, ev.AlertType)
+}
@@ -42,6 +42,7 @@ func FormatStatus(data []byte) (string, error) {
 	aggregatorStats := stats["aggregatorStats"]
 	dogstatsdStats := stats["dogstatsdStats"]
 	jmxStats := stats["JMXStatus"]
+	subprocessStats := stats["SubprocessStatus"]
 	logsStats := stats["logsStats"]
 	dcaStats := stats["clusterAgentStatus"]
 	endpointsInfos := stats["endpointsInfos"]
@@ -50,6 +51,7 @@ func FormatStatus(data []byte) (string, error) {
 	renderHeader(b, stats)
 	renderChecksStats(b, runnerStats, pyLoaderStats, autoConfigStats, checkSchedulerStats, "")
 	renderJMXFetchStatus(b, jmxStats)
+	renderSubprocessStatus(b, subprocessStats)
 	renderForwarderStatus(b, forwarderStats)
 	renderEndpointsInfos(b, endpointsInfos)
 	renderLogsStatus(b, logsStats)
@@ -203,6 +205,17 @@ func renderJMXFetchStatus(w io.Writer, jmxStats interface{}) {
 	}
 }
 
+func renderSubprocessStatus(w io.Writer, subprocessStats interface{}) {
+	stats := make(map[string]interface{})
+	stats["SubprocessStatus"] = subprocessStats
+	t := template.Must(template.New("subprocesses.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "subprocesses.tmpl")))
+
+	err := t.Execute(w, stats)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func renderLogsStatus(w io.Writer, logsStats interface{}) {
 	t := template.Must(template.New("logsagent.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "logsagent.tmpl")))
 	err := t.Execute(w, logsStats)
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// prometheusPrefix prefixes the names of the status metrics
+const prometheusPrefix = "datadog_agent_"
+
+// pipelineStats are the status sections exported as pipeline counters,
+// by the name of their metrics
+var pipelineStats = map[string]string{
+	"aggregator": "aggregatorStats",
+	"dogstatsd":  "dogstatsdStats",
+	"forwarder":  "forwarderStats",
+}
+
+// promSample is a sample of the Prometheus text exposition format
+type promSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// promMetrics holds the samples of the status metrics by metric name
+type promMetrics struct {
+	types   map[string]string
+	samples map[string][]promSample
+}
+
+func (m *promMetrics) add(name, kind string, labels map[string]string, value float64) {
+	name = prometheusPrefix + name
+	m.types[name] = kind
+	m.samples[name] = append(m.samples[name], promSample{labels: labels, value: value})
+}
+
+// FormatStatusPrometheus takes a json bytestring and returns the check and
+// pipeline counters of the status in the Prometheus text exposition format
+func FormatStatusPrometheus(data []byte) (string, error) {
+	stats := make(map[string]interface{})
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return "", err
+	}
+
+	m := &promMetrics{
+		types:   make(map[string]string),
+		samples: make(map[string][]promSample),
+	}
+	m.add("info", "gauge", map[string]string{"version": fmt.Sprint(stats["version"])}, 1)
+	addCheckMetrics(m, stats["runnerStats"])
+	for name, section := range pipelineStats {
+		addPipelineMetrics(m, name, stats[section])
+	}
+	addSubprocessMetrics(m, stats["SubprocessStatus"])
+
+	var b bytes.Buffer
+	writePrometheus(&b, m)
+	return b.String(), nil
+}
+
+// addCheckMetrics exports the runs, errors and last error of every check instance
+func addCheckMetrics(m *promMetrics, runnerStats interface{}) {
+	runner, _ := runnerStats.(map[string]interface{})
+	checks, _ := runner["Checks"].(map[string]interface{})
+	for checkName, instances := range checks {
+		instancesMap, _ := instances.(map[string]interface{})
+		for checkID, instance := range instancesMap {
+			s, ok := instance.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			labels := map[string]string{"check": checkName, "check_id": checkID}
+			m.add("check_runs_total", "counter", labels, number(s["TotalRuns"]))
+			m.add("check_errors_total", "counter", labels, number(s["TotalErrors"]))
+			m.add("check_warnings_total", "counter", labels, number(s["TotalWarnings"]))
+			m.add("check_metric_samples_total", "counter", labels, number(s["TotalMetricSamples"]))
+			m.add("check_events_total", "counter", labels, number(s["TotalEvents"]))
+			m.add("check_service_checks_total", "counter", labels, number(s["TotalServiceChecks"]))
+			m.add("check_average_execution_time_seconds", "gauge", labels, number(s["AverageExecutionTime"])/1000)
+			m.add("check_last_execution_time_seconds", "gauge", labels, number(s["LastExecutionTime"])/1000)
+
+			lastError, _ := s["LastError"].(string)
+			failed := 0.0
+			if lastError != "" {
+				failed = 1
+				errLabels := map[string]string{"check": checkName, "check_id": checkID, "message": string(lastErrorMessage(lastError))}
+				m.add("check_last_error", "gauge", errLabels, 1)
+			}
+			m.add("check_last_run_failed", "gauge", labels, failed)
+		}
+	}
+}
+
+// addPipelineMetrics exports every counter of a status section, the name
+// of the metrics being the path of the counters in the section
+func addPipelineMetrics(m *promMetrics, name string, section interface{}) {
+	switch v := section.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			addPipelineMetrics(m, name+"_"+toSnakeCase(key), child)
+		}
+	case float64:
+		m.add(name, "gauge", nil, v)
+	}
+}
+
+// addSubprocessMetrics exports the exit code of the subprocesses run by checks
+func addSubprocessMetrics(m *promMetrics, subprocessStatus interface{}) {
+	reports, _ := subprocessStatus.([]interface{})
+	for _, report := range reports {
+		r, ok := report.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		labels := map[string]string{"subprocess": fmt.Sprint(r["name"])}
+		m.add("subprocess_exit_code", "gauge", labels, number(r["exit_code"]))
+		m.add("subprocess_last_exit_timestamp_seconds", "gauge", labels, number(r["timestamp"]))
+	}
+}
+
+func writePrometheus(w io.Writer, m *promMetrics) {
+	names := make([]string, 0, len(m.samples))
+	for name := range m.samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, m.types[name])
+		for _, s := range m.samples[name] {
+			fmt.Fprintf(w, "%s%s %v\n", name, formatLabels(s.labels), s.value)
+		}
+	}
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// escapeLabelValue keeps the first line of a label value and escapes it
+func escapeLabelValue(v string) string {
+	if i := strings.IndexByte(v, '\n'); i >= 0 {
+		v = v[:i]
+	}
+	return labelValueEscaper.Replace(v)
+}
+
+// toSnakeCase converts the expvar names, like HTTPErrorsByCode, to metric
+// name components, like http_errors_by_code
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			// An upper case letter starts a word if it follows a lower case
+			// letter or a digit, or if it ends an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLower(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func number(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
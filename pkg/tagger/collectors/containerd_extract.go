@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package collectors
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	containerdcontainers "github.com/containerd/containerd/containers"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Labels set by the CRI plugin on the containers of kubernetes pods
+const (
+	criPodNameLabel       = "io.kubernetes.pod.name"
+	criPodNamespaceLabel  = "io.kubernetes.pod.namespace"
+	criContainerNameLabel = "io.kubernetes.container.name"
+	criPodUIDLabel        = "io.kubernetes.pod.uid"
+)
+
+// containerdExtractTags extracts tags from the containerd metadata of a container
+// and the platform of its image, empty if unknown. The labels and the OCI
+// annotations matching the patterns of labelsAsTags and annotationsAsTags are
+// added as tags too, once the spec is scrubbed by scrubber.
+func containerdExtractTags(info containerdcontainers.Container, platform ocispec.Platform, labelsAsTags, annotationsAsTags map[string]string, scrubber *containerd.EnvScrubber) ([]string, []string) {
+	tags := utils.NewTagList()
+
+	containerdExtractImage(tags, info.Image)
+	if platform.Architecture != "" {
+		// The architecture actually run, eg. amd64 emulated on an arm64 node
+		tags.AddLow("image_arch", platform.Architecture)
+	}
+	if handler := containerd.RuntimeHandler(info.Runtime.Name); handler != "" {
+		// Tells the sandboxed containers, eg. kata or gvisor, from the runc ones
+		tags.AddLow("runtime_class", handler)
+	}
+	containerdExtractLabels(tags, info.Labels)
+	containerdExtractAsTags(tags, info.Labels, labelsAsTags)
+	var annotations map[string]string
+	if spec := scrubber.ScrubSpec(containerdSpec(info)); spec != nil {
+		annotations = spec.Annotations
+		containerdExtractAsTags(tags, spec.Annotations, annotationsAsTags)
+		containerdExtractGPUs(tags, spec)
+	}
+
+	tags.AddHigh("container_id", info.ID)
+	// The name is resolved like in the checks, eg. CRI containers get the
+	// name of their pod container
+	tags.AddHigh("container_name", containerd.ContainerNameResolver().Resolve(info.ID, info.Labels, annotations))
+	if containerType := containerd.ContainerTypeResolver().Resolve(info.Labels, annotations); containerType != "" {
+		// Tells the init containers from the running workload
+		tags.AddLow("container_type", containerType)
+	}
+
+	return tags.Compute()
+}
+
+func containerdExtractImage(tags *utils.TagList, image string) {
+	if image == "" {
+		return
+	}
+	imageName, shortImage, imageTag, err := containers.SplitImageName(image)
+	if err != nil {
+		log.Debugf("Cannot split %s: %s", image, err)
+		return
+	}
+	tags.AddLow("image_name", imageName)
+	tags.AddLow("short_image", shortImage)
+	tags.AddLow("image_tag", imageTag)
+}
+
+// containerdExtractLabels extracts the pod tags from the labels of
+// the CRI plugin, for the containers of pods not yet known to the kubelet
+func containerdExtractLabels(tags *utils.TagList, labels map[string]string) {
+	for labelName, labelValue := range labels {
+		switch labelName {
+		case criPodNameLabel:
+			tags.AddHigh("pod_name", labelValue)
+		case criPodNamespaceLabel:
+			tags.AddLow("kube_namespace", labelValue)
+		case criContainerNameLabel:
+			tags.AddLow("kube_container_name", labelValue)
+		}
+	}
+}
+
+// containerdExtractAsTags adds the values whose lower-cased name matches a
+// pattern of mapping, as the tag it maps to. Like docker_labels_as_tags, tags
+// prefixed with + are high cardinality, and like kubernetes_pod_labels_as_tags,
+// %%label%% is replaced by the name of the label.
+func containerdExtractAsTags(tags *utils.TagList, values map[string]string, mapping map[string]string) {
+	for name, value := range values {
+		for pattern, tmpl := range mapping {
+			if ok, _ := filepath.Match(pattern, strings.ToLower(name)); ok {
+				tags.AddAuto(resolveTag(tmpl, name), value)
+			}
+		}
+	}
+}
+
+// containerdSpec returns the OCI spec of a container, decoded from its
+// metadata, nil if unknown
+func containerdSpec(info containerdcontainers.Container) *specs.Spec {
+	if info.Spec == nil {
+		return nil
+	}
+	var spec specs.Spec
+	if err := json.Unmarshal(info.Spec.GetValue(), &spec); err != nil {
+		log.Debugf("Cannot decode the spec of container %s: %s", info.ID, err)
+		return nil
+	}
+	return &spec
+}
+
+// containerdExtractGPUs tags the containers with the NVIDIA GPUs allocated
+// to them, like the metrics of the nvml check
+func containerdExtractGPUs(tags *utils.TagList, spec *specs.Spec) {
+	gpus := containerd.SpecGPUDevices(spec)
+	for _, uuid := range gpus.UUIDs {
+		tags.AddLow("gpu_uuid", uuid)
+	}
+	for _, index := range gpus.Indexes {
+		tags.AddLow("gpu_index", index)
+	}
+}
+
+// containerdExtractPodTags extracts the tags of the pod of a CRI container,
+// so that the pod entity is tagged before the kubelet lists it
+func containerdExtractPodTags(labels map[string]string) ([]string, []string) {
+	tags := utils.NewTagList()
+	if podName, found := labels[criPodNameLabel]; found {
+		tags.AddHigh("pod_name", podName)
+	}
+	if podNamespace, found := labels[criPodNamespaceLabel]; found {
+		tags.AddLow("kube_namespace", podNamespace)
+	}
+	return tags.Compute()
+}
@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package collectors
+
+import (
+	"fmt"
+	"testing"
+
+	containerdcontainers "github.com/containerd/containerd/containers"
+	"github.com/containerd/typeurl/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerdExtractTags(t *testing.T) {
+	spec, err := typeurl.MarshalAny(&specs.Spec{Annotations: map[string]string{
+		"org.opencontainers.image.version": "1.2.3",
+		"com.example.owner":                "alice",
+	}})
+	require.NoError(t, err)
+	secretSpec, err := typeurl.MarshalAny(&specs.Spec{Annotations: map[string]string{
+		"com.example.db-password": "s3cr3t",
+	}})
+	require.NoError(t, err)
+	gpuSpec, err := typeurl.MarshalAny(&specs.Spec{
+		Process: &specs.Process{Env: []string{"NVIDIA_VISIBLE_DEVICES=GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3"}},
+		Annotations: map[string]string{
+			"cdi.k8s.io/nvidia-device-plugin": "nvidia.com/gpu=1",
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		testName          string
+		info              containerdcontainers.Container
+		platform          ocispec.Platform
+		labelsAsTags      map[string]string
+		annotationsAsTags map[string]string
+		expectedLow       []string
+		expectedHigh      []string
+	}{
+		{
+			testName:     "standalone",
+			info:         containerdcontainers.Container{ID: "foo"},
+			expectedLow:  []string{},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+		{
+			testName: "image",
+			info: containerdcontainers.Container{
+				ID:    "foo",
+				Image: "docker.io/library/redis:4.0",
+			},
+			expectedLow:  []string{"image_name:docker.io/library/redis", "short_image:redis", "image_tag:4.0"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+		{
+			testName: "image platform",
+			info: containerdcontainers.Container{
+				ID:    "foo",
+				Image: "docker.io/library/redis:4.0",
+			},
+			platform:     ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			expectedLow:  []string{"image_name:docker.io/library/redis", "short_image:redis", "image_tag:4.0", "image_arch:arm64"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+		{
+			testName: "runtime",
+			info: containerdcontainers.Container{
+				ID:      "foo",
+				Runtime: containerdcontainers.RuntimeInfo{Name: "io.containerd.kata.v2"},
+			},
+			expectedLow:  []string{"runtime_class:kata"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+		{
+			testName: "kubernetes",
+			info: containerdcontainers.Container{
+				ID:    "foo",
+				Image: "docker.io/library/redis:4.0",
+				Labels: map[string]string{
+					"io.kubernetes.pod.name":       "redis-75586d7d7c-l8cbp",
+					"io.kubernetes.pod.namespace":  "default",
+					"io.kubernetes.container.name": "redis",
+					"io.kubernetes.pod.uid":        "9d6b2d9e-d0b6-11e8-a6a8-42010a840004",
+				},
+			},
+			expectedLow: []string{
+				"image_name:docker.io/library/redis", "short_image:redis", "image_tag:4.0",
+				"kube_namespace:default", "kube_container_name:redis", "container_type:main",
+			},
+			expectedHigh: []string{"container_id:foo", "container_name:redis", "pod_name:redis-75586d7d7c-l8cbp"},
+		},
+		{
+			testName: "kubernetes init container",
+			info: containerdcontainers.Container{
+				ID:    "foo",
+				Image: "docker.io/istio/proxyv2:1.9.0",
+				Labels: map[string]string{
+					"io.kubernetes.pod.name":       "redis-75586d7d7c-l8cbp",
+					"io.kubernetes.pod.namespace":  "default",
+					"io.kubernetes.container.name": "istio-init",
+				},
+			},
+			expectedLow: []string{
+				"image_name:docker.io/istio/proxyv2", "short_image:proxyv2", "image_tag:1.9.0",
+				"kube_namespace:default", "kube_container_name:istio-init", "container_type:init",
+			},
+			expectedHigh: []string{"container_id:foo", "container_name:istio-init", "pod_name:redis-75586d7d7c-l8cbp"},
+		},
+		{
+			testName: "labels as tags",
+			info: containerdcontainers.Container{
+				ID: "foo",
+				Labels: map[string]string{
+					"com.example.Team":    "containers",
+					"com.example.service": "redis",
+					"com.example.build":   "1234",
+					"other":               "ignored",
+				},
+			},
+			labelsAsTags: map[string]string{
+				"com.example.team":  "team",
+				"com.example.build": "+build",
+				"com.example.serv*": "%%label%%",
+			},
+			expectedLow:  []string{"team:containers", "com.example.service:redis"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo", "build:1234"},
+		},
+		{
+			testName: "annotations as tags",
+			info: containerdcontainers.Container{
+				ID:     "foo",
+				Labels: map[string]string{"com.example.owner": "bob"},
+				Spec:   spec,
+			},
+			annotationsAsTags: map[string]string{
+				"org.opencontainers.image.version": "version",
+				"com.example.*":                    "+owner",
+			},
+			expectedLow:  []string{"version:1.2.3"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo", "owner:alice"},
+		},
+		{
+			testName: "scrubbed annotations",
+			info: containerdcontainers.Container{
+				ID:   "foo",
+				Spec: secretSpec,
+			},
+			annotationsAsTags: map[string]string{
+				"com.example.*": "%%label%%",
+			},
+			expectedLow:  []string{"com.example.db-password:********"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+		{
+			testName: "gpu",
+			info: containerdcontainers.Container{
+				ID:   "foo",
+				Spec: gpuSpec,
+			},
+			expectedLow:  []string{"gpu_uuid:GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3", "gpu_index:1"},
+			expectedHigh: []string{"container_id:foo", "container_name:foo"},
+		},
+	}
+
+	for i, test := range testCases {
+		t.Run(fmt.Sprintf("case %d: %s", i, test.testName), func(t *testing.T) {
+			low, high := containerdExtractTags(test.info, test.platform, test.labelsAsTags, test.annotationsAsTags, nil)
+			assert.ElementsMatch(t, test.expectedLow, low)
+			assert.ElementsMatch(t, test.expectedHigh, high)
+		})
+	}
+}
+
+func TestContainerdExtractPodTags(t *testing.T) {
+	low, high := containerdExtractPodTags(map[string]string{
+		"io.kubernetes.pod.name":       "redis-75586d7d7c-l8cbp",
+		"io.kubernetes.pod.namespace":  "default",
+		"io.kubernetes.container.name": "redis",
+		"io.kubernetes.pod.uid":        "9d6b2d9e-d0b6-11e8-a6a8-42010a840004",
+	})
+	assert.ElementsMatch(t, []string{"kube_namespace:default"}, low)
+	assert.ElementsMatch(t, []string{"pod_name:redis-75586d7d7c-l8cbp"}, high)
+}
@@ -0,0 +1,436 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	containerdcontainers "github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/typeurl/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/containerdconfig"
+	"github.com/DataDog/datadog-agent/pkg/errors"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	containerdCollectorName = "containerd"
+
+	containerdTaskStartTopic       = "/tasks/start"
+	containerdContainerUpdateTopic = "/containers/update"
+	containerdContainerDeleteTopic = "/containers/delete"
+)
+
+// containerdStreamRetryDelay is the time waited before subscribing again to
+// the containerd events after an error
+var containerdStreamRetryDelay = 10 * time.Second
+
+// ContainerdCollector listens to events on the containerd socket to get new/deleted
+// containers and feed a stream of TagInfo. Tags are extracted from the container
+// image and from the labels set by the CRI plugin. The pods of CRI containers
+// are tagged too, so that the dogstatsd metrics sent with the pod UID as entity
+// ID are tagged as soon as the pod starts. The tags are extracted again when
+// the labels of a container are updated, eg. by ctr containers label.
+type ContainerdCollector struct {
+	// containerdUtil is replaced when the containerd endpoint changes, it
+	// is read through util
+	containerdUtil containerd.ContainerdItf
+	utilMux        sync.RWMutex
+	stop           chan struct{}
+	infoOut        chan<- []*TagInfo
+	// namespaceFilter drops the events of the namespaces out of the collection scope
+	namespaceFilter containerd.NamespaceFilter
+	// buffer queues the events while they are processed, it can be nil
+	buffer *containerd.EventBuffer
+	// labelsAsTags and annotationsAsTags map the patterns of the labels and
+	// of the OCI annotations to their tag names
+	labelsAsTags      map[string]string
+	annotationsAsTags map[string]string
+	// scrubber scrubs the annotations of the specs before they are tagged
+	scrubber *containerd.EnvScrubber
+
+	// podContainers tracks the containers of every pod UID, so that the pod
+	// entity is deleted with its last container
+	podContainers map[string]map[string]struct{}
+	containerPods map[string]string // container ID -> pod UID
+	podsMux       sync.Mutex
+
+	// resolve loads the containers of a namespace, containerd.ResolveNamespace
+	// if nil
+	resolve func(namespace, id string) (containerdclient.Container, error)
+	// namespaceUtil returns the util of another namespace than the one of
+	// the collector, containerd.Provider.NamespaceUtil if nil
+	namespaceUtil func(namespace string) (containerd.ContainerdItf, error)
+}
+
+// Detect tries to connect to the containerd socket and returns success
+func (c *ContainerdCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeNameContainerd); err != nil {
+		return NoCollection, err
+	}
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil {
+		return NoCollection, err
+	}
+
+	c.containerdUtil = cu
+	c.namespaceFilter = containerdconfig.NamespaceFilter()
+	c.scrubber = containerd.NewEnvScrubber(containerdconfig.Options().EnvScrubPatterns)
+	if size := config.Datadog.GetInt("containerd_event_buffer_size"); size > 0 {
+		c.buffer = containerd.NewEventBuffer(size)
+	}
+	c.stop = make(chan struct{})
+	c.infoOut = out
+	// The patterns are lower-cased, like the names matched against them
+	c.labelsAsTags = retrieveMappingFromConfig("containerd_labels_as_tags")
+	c.annotationsAsTags = retrieveMappingFromConfig("containerd_annotations_as_tags")
+	c.podContainers = make(map[string]map[string]struct{})
+	c.containerPods = make(map[string]string)
+
+	return StreamCollection, nil
+}
+
+// Stream runs the continuous event watching loop and sends new info
+// to the channel. But be called in a goroutine. The events are watched
+// again after containerdStreamRetryDelay when they cannot be received, or
+// right away on the new util if the containerd endpoint changed.
+func (c *ContainerdCollector) Stream() error {
+	healthHandle := health.Register("tagger-containerd")
+	defer healthHandle.Deregister()
+	for {
+		cu := c.util()
+		err := c.streamEvents(cu, healthHandle)
+		if err == nil {
+			return nil
+		}
+		if c.refreshUtil(cu) {
+			log.Infof("The containerd endpoint changed, watching the events of %s", c.util().Namespace())
+			continue
+		}
+		log.Warnf("Error receiving containerd events, retrying in %s: %s", containerdStreamRetryDelay, err)
+		if !c.waitRetry(healthHandle) {
+			return nil
+		}
+	}
+}
+
+// streamEvents watches the events of cu until Stop is called, it returns
+// nil then, or the error ending the subscription
+func (c *ContainerdCollector) streamEvents(cu containerd.ContainerdItf, healthHandle *health.Handle) error {
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), cu.Namespace()))
+	defer cancel()
+	messages, errs := cu.GetEvents().Subscribe(ctx, c.namespaceFilter.EventFilters(
+		`topic=="`+containerdTaskStartTopic+`"`,
+		`topic=="`+containerdContainerUpdateTopic+`"`,
+		`topic=="`+containerdContainerDeleteTopic+`"`,
+	)...)
+	if c.buffer != nil {
+		messages = c.buffer.Buffer(ctx, messages)
+	}
+	c.warmCache()
+
+	for {
+		select {
+		case <-c.stop:
+			return nil
+		case <-healthHandle.C:
+		case msg := <-messages:
+			c.processEvent(msg)
+		case err := <-errs:
+			if err == nil {
+				err = fmt.Errorf("event stream closed")
+			}
+			return err
+		}
+	}
+}
+
+// waitRetry waits for containerdStreamRetryDelay, it returns false if Stop
+// is called in the meantime
+func (c *ContainerdCollector) waitRetry(healthHandle *health.Handle) bool {
+	retry := time.After(containerdStreamRetryDelay)
+	for {
+		select {
+		case <-c.stop:
+			return false
+		case <-healthHandle.C:
+		case <-retry:
+			return true
+		}
+	}
+}
+
+// util returns the util the collector queries
+func (c *ContainerdCollector) util() containerd.ContainerdItf {
+	c.utilMux.RLock()
+	defer c.utilMux.RUnlock()
+	return c.containerdUtil
+}
+
+// refreshUtil replaces the util if it was closed after a change of the
+// containerd endpoint of the configuration, it returns whether it did
+func (c *ContainerdCollector) refreshUtil(previous containerd.ContainerdItf) bool {
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil || cu == previous {
+		return false
+	}
+	c.utilMux.Lock()
+	c.containerdUtil = cu
+	c.utilMux.Unlock()
+	return true
+}
+
+// Stop queues a shutdown of ContainerdCollector
+func (c *ContainerdCollector) Stop() error {
+	c.stop <- struct{}{}
+	return nil
+}
+
+// Fetch gets the tags of a given container or pod on-demand (cache miss)
+func (c *ContainerdCollector) Fetch(entity string) ([]string, []string, error) {
+	if strings.HasPrefix(entity, kubelet.KubePodPrefix) {
+		return c.fetchForPodUID(strings.TrimPrefix(entity, kubelet.KubePodPrefix))
+	}
+	entity = containers.CanonicalEntityName(entity)
+	cID := strings.TrimPrefix(entity, containers.ContainerEntityPrefix)
+	if cID == entity || len(cID) == 0 {
+		return nil, nil, nil
+	}
+	return c.fetchForContainerdID(cID)
+}
+
+func (c *ContainerdCollector) processEvent(envelope *events.Envelope) {
+	if envelope == nil || c.namespaceFilter.IsExcluded(envelope.Namespace) {
+		return
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		log.Debugf("Cannot decode containerd event: %s", err)
+		return
+	}
+
+	var infos []*TagInfo
+	switch e := ev.(type) {
+	case *apievents.ContainerDelete:
+		infos = []*TagInfo{{
+			Entity:       containerd.EntityID(e.ID),
+			Source:       containerdCollectorName,
+			DeleteEntity: true,
+		}}
+		if podUID, lastContainer := c.untrackPodContainer(e.ID); lastContainer {
+			infos = append(infos, &TagInfo{
+				Entity:       kubelet.PodUIDToEntityName(podUID),
+				Source:       containerdCollectorName,
+				DeleteEntity: true,
+			})
+		}
+	case *apievents.TaskStart:
+		infos = c.containerTagInfos(envelope.Namespace, e.ContainerID)
+	case *apievents.ContainerUpdate:
+		// The labels may have changed, the tags replace the cached ones
+		infos = c.containerTagInfos(envelope.Namespace, e.ID)
+	default:
+		return // Nothing to see here
+	}
+	c.infoOut <- infos
+}
+
+// containerTagInfos returns the tag infos of a container of a namespace read
+// from the daemon, an info without tags if it cannot be read
+func (c *ContainerdCollector) containerTagInfos(namespace, cID string) []*TagInfo {
+	cu, ctn, info, err := c.loadInfo(namespace, cID)
+	if err != nil {
+		return []*TagInfo{{
+			Entity: containerd.EntityID(cID),
+			Source: containerdCollectorName,
+		}}
+	}
+	return c.tagInfos(info, c.imagePlatform(cu, ctn))
+}
+
+// warmCache sends the tags of the existing containers of the collected
+// namespaces and of their pods, so that the containers started before the
+// agent are tagged without a cache miss
+func (c *ContainerdCollector) warmCache() {
+	utils, err := containerd.GetNamespacedUtils()
+	if err != nil {
+		log.Debugf("Cannot list the containerd namespaces to warm the tagger cache: %s", err)
+		utils = []containerd.ContainerdItf{c.util()}
+	}
+	var infos []*TagInfo
+	for _, cu := range utils {
+		ctns, err := cu.Containers()
+		if err != nil {
+			log.Debugf("Cannot list the containers of namespace %s to warm the tagger cache: %s", cu.Namespace(), err)
+			continue
+		}
+		for _, ctn := range ctns {
+			info, err := cu.Info(ctn)
+			if err != nil {
+				log.Debugf("Failed to get info of container %s - %s", ctn.ID(), err)
+				continue
+			}
+			infos = append(infos, c.tagInfos(info, c.imagePlatform(cu, ctn))...)
+		}
+	}
+	if len(infos) > 0 {
+		c.infoOut <- infos
+	}
+}
+
+// tagInfos returns the tags of a container and, for the containers created
+// by the CRI plugin, the tags of their pod
+func (c *ContainerdCollector) tagInfos(info containerdcontainers.Container, platform ocispec.Platform) []*TagInfo {
+	low, high := containerdExtractTags(info, platform, c.labelsAsTags, c.annotationsAsTags, c.scrubber)
+	infos := []*TagInfo{{
+		Entity:       containerd.EntityID(info.ID),
+		Source:       containerdCollectorName,
+		LowCardTags:  low,
+		HighCardTags: high,
+	}}
+
+	podUID := info.Labels[criPodUIDLabel]
+	if podUID == "" {
+		return infos
+	}
+	c.trackPodContainer(podUID, info.ID)
+	podLow, podHigh := containerdExtractPodTags(info.Labels)
+	return append(infos, &TagInfo{
+		Entity:       kubelet.PodUIDToEntityName(podUID),
+		Source:       containerdCollectorName,
+		LowCardTags:  podLow,
+		HighCardTags: podHigh,
+	})
+}
+
+func (c *ContainerdCollector) trackPodContainer(podUID, cID string) {
+	c.podsMux.Lock()
+	defer c.podsMux.Unlock()
+	if _, found := c.podContainers[podUID]; !found {
+		c.podContainers[podUID] = make(map[string]struct{})
+	}
+	c.podContainers[podUID][cID] = struct{}{}
+	c.containerPods[cID] = podUID
+}
+
+// untrackPodContainer returns the pod UID of a deleted container, and whether
+// it was the last container of the pod
+func (c *ContainerdCollector) untrackPodContainer(cID string) (string, bool) {
+	c.podsMux.Lock()
+	defer c.podsMux.Unlock()
+	podUID, found := c.containerPods[cID]
+	if !found {
+		return "", false
+	}
+	delete(c.containerPods, cID)
+	delete(c.podContainers[podUID], cID)
+	if len(c.podContainers[podUID]) > 0 {
+		return podUID, false
+	}
+	delete(c.podContainers, podUID)
+	return podUID, true
+}
+
+// utilOf returns the util of a namespace, the one of the collector if the
+// namespace is empty, eg. for the cache misses
+func (c *ContainerdCollector) utilOf(namespace string) (containerd.ContainerdItf, error) {
+	cu := c.util()
+	if namespace == "" || namespace == cu.Namespace() {
+		return cu, nil
+	}
+	if c.namespaceUtil != nil {
+		return c.namespaceUtil(namespace)
+	}
+	return containerd.DefaultProvider().NamespaceUtil(namespace)
+}
+
+// loadInfo loads a container of a namespace, with the util of the namespace
+func (c *ContainerdCollector) loadInfo(namespace, cID string) (containerd.ContainerdItf, containerdclient.Container, containerdcontainers.Container, error) {
+	cu, err := c.utilOf(namespace)
+	if err != nil {
+		log.Debugf("Failed to get the util of namespace %s - %s", namespace, err)
+		return nil, nil, containerdcontainers.Container{}, err
+	}
+	resolve := c.resolve
+	if resolve == nil {
+		resolve = containerd.ResolveNamespace
+	}
+	ctn, err := resolve(namespace, cID)
+	if err != nil {
+		log.Debugf("Failed to load container %s - %s", cID, err)
+		return nil, nil, containerdcontainers.Container{}, err
+	}
+	info, err := cu.Info(ctn)
+	if err != nil {
+		log.Debugf("Failed to get info of container %s - %s", cID, err)
+		return nil, nil, containerdcontainers.Container{}, err
+	}
+	return cu, ctn, info, nil
+}
+
+// imagePlatform returns the platform of the image of a container, read from
+// the content store of cu, or an empty platform if it cannot be read
+func (c *ContainerdCollector) imagePlatform(cu containerd.ContainerdItf, ctn containerdclient.Container) ocispec.Platform {
+	manifest, err := cu.ImageManifest(ctn)
+	if err != nil {
+		log.Debugf("Failed to get the image platform of container %s - %s", ctn.ID(), err)
+		return ocispec.Platform{}
+	}
+	return manifest.Platform
+}
+
+// fetchForContainerdID gets the tags of a container of the namespace of the
+// collector, the entities of the cache misses have no namespace
+func (c *ContainerdCollector) fetchForContainerdID(cID string) ([]string, []string, error) {
+	cu, ctn, info, err := c.loadInfo("", cID)
+	if err != nil {
+		return nil, nil, err
+	}
+	low, high := containerdExtractTags(info, c.imagePlatform(cu, ctn), c.labelsAsTags, c.annotationsAsTags, c.scrubber)
+	return low, high, nil
+}
+
+// fetchForPodUID gets the tags of a pod from the labels of its containers,
+// read from the container cache of the util
+func (c *ContainerdCollector) fetchForPodUID(podUID string) ([]string, []string, error) {
+	ctns, err := c.util().CachedContainers()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ctn := range ctns {
+		if ctn.Labels[criPodUIDLabel] != podUID {
+			continue
+		}
+		c.trackPodContainer(podUID, ctn.ID)
+		low, high := containerdExtractPodTags(ctn.Labels)
+		return low, high, nil
+	}
+	return nil, nil, errors.NewNotFound(kubelet.PodUIDToEntityName(podUID))
+}
+
+func containerdFactory() Collector {
+	return &ContainerdCollector{}
+}
+
+func init() {
+	registerCollector(containerdCollectorName, containerdFactory, NodeRuntime)
+}
@@ -0,0 +1,226 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package collectors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	containerdcontainers "github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/errors"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd/containerdtest"
+)
+
+func TestContainerdPodTagInfos(t *testing.T) {
+	out := make(chan []*TagInfo, 10)
+	c := &ContainerdCollector{
+		infoOut:       out,
+		podContainers: make(map[string]map[string]struct{}),
+		containerPods: make(map[string]string),
+	}
+	podLabels := map[string]string{
+		"io.kubernetes.pod.name":      "redis-0",
+		"io.kubernetes.pod.namespace": "default",
+		"io.kubernetes.pod.uid":       "9d6b2d9e-d0b6-11e8-a6a8-42010a840004",
+	}
+
+	// Standalone containers have no pod
+	infos := c.tagInfos(containerdcontainers.Container{ID: "standalone"}, ocispec.Platform{})
+	require.Len(t, infos, 1)
+	assert.Equal(t, "container_id://standalone", infos[0].Entity)
+
+	// The pod is tagged with its first container
+	infos = c.tagInfos(containerdcontainers.Container{ID: "sandbox", Labels: podLabels}, ocispec.Platform{})
+	require.Len(t, infos, 2)
+	assert.Equal(t, "container_id://sandbox", infos[0].Entity)
+	assert.Equal(t, "kubernetes_pod://9d6b2d9e-d0b6-11e8-a6a8-42010a840004", infos[1].Entity)
+	assert.Equal(t, []string{"kube_namespace:default"}, infos[1].LowCardTags)
+	assert.Equal(t, []string{"pod_name:redis-0"}, infos[1].HighCardTags)
+	c.tagInfos(containerdcontainers.Container{ID: "redis", Labels: podLabels}, ocispec.Platform{})
+
+	// The pod is deleted with its last container
+	c.processEvent(deleteEnvelope(t, "redis"))
+	infos = <-out
+	require.Len(t, infos, 1)
+	assert.Equal(t, "container_id://redis", infos[0].Entity)
+
+	c.processEvent(deleteEnvelope(t, "sandbox"))
+	infos = <-out
+	require.Len(t, infos, 2)
+	assert.Equal(t, "kubernetes_pod://9d6b2d9e-d0b6-11e8-a6a8-42010a840004", infos[1].Entity)
+	assert.True(t, infos[1].DeleteEntity)
+	assert.Empty(t, c.podContainers)
+	assert.Empty(t, c.containerPods)
+}
+
+func deleteEnvelope(t *testing.T, id string) *events.Envelope {
+	event, err := typeurl.MarshalAny(&apievents.ContainerDelete{ID: id})
+	require.NoError(t, err)
+	return &events.Envelope{Topic: containerdContainerDeleteTopic, Event: event}
+}
+
+func TestContainerdExcludedNamespaceEvents(t *testing.T) {
+	out := make(chan []*TagInfo, 10)
+	c := &ContainerdCollector{
+		infoOut:         out,
+		namespaceFilter: containerd.NewNamespaceFilter([]string{"k8s.io"}, nil),
+		podContainers:   make(map[string]map[string]struct{}),
+		containerPods:   make(map[string]string),
+	}
+
+	envelope := deleteEnvelope(t, "buildkit-worker")
+	envelope.Namespace = "buildkit"
+	c.processEvent(envelope)
+	assert.Len(t, out, 0)
+
+	envelope = deleteEnvelope(t, "redis")
+	envelope.Namespace = "k8s.io"
+	c.processEvent(envelope)
+	assert.Len(t, out, 1)
+}
+
+func TestContainerdLabelUpdate(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("default", &containerdtest.Container{Record: containerdcontainers.Container{
+		ID:     "redis",
+		Labels: map[string]string{"team": "storage"},
+	}}))
+	cu := daemon.Util("default")
+	out := make(chan []*TagInfo, 10)
+	c := &ContainerdCollector{
+		containerdUtil: cu,
+		resolve:        func(namespace, id string) (containerdclient.Container, error) { return cu.LoadContainer(id) },
+		infoOut:        out,
+		labelsAsTags:   map[string]string{"team": "team"},
+		podContainers:  make(map[string]map[string]struct{}),
+		containerPods:  make(map[string]string),
+	}
+
+	require.NoError(t, daemon.SetLabels("default", "redis", map[string]string{"team": "cache"}))
+	event, err := typeurl.MarshalAny(&apievents.ContainerUpdate{ID: "redis", Labels: map[string]string{"team": "cache"}})
+	require.NoError(t, err)
+	c.processEvent(&events.Envelope{Namespace: "default", Topic: containerdContainerUpdateTopic, Event: event})
+
+	infos := <-out
+	require.Len(t, infos, 1)
+	assert.Equal(t, "container_id://redis", infos[0].Entity)
+	assert.Contains(t, infos[0].LowCardTags, "team:cache")
+	assert.False(t, infos[0].DeleteEntity)
+}
+
+func TestContainerdOtherNamespaceEvents(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("moby", &containerdtest.Container{Record: containerdcontainers.Container{
+		ID:     "builder",
+		Labels: map[string]string{"team": "ci"},
+	}}))
+	out := make(chan []*TagInfo, 10)
+	c := &ContainerdCollector{
+		containerdUtil: daemon.Util("k8s.io"),
+		resolve: func(namespace, id string) (containerdclient.Container, error) {
+			return daemon.Util(namespace).LoadContainer(id)
+		},
+		namespaceUtil: func(namespace string) (containerd.ContainerdItf, error) {
+			return daemon.Util(namespace), nil
+		},
+		infoOut:       out,
+		labelsAsTags:  map[string]string{"team": "team"},
+		podContainers: make(map[string]map[string]struct{}),
+		containerPods: make(map[string]string),
+	}
+
+	// The container is loaded from the util of the namespace of the event
+	event, err := typeurl.MarshalAny(&apievents.TaskStart{ContainerID: "builder"})
+	require.NoError(t, err)
+	c.processEvent(&events.Envelope{Namespace: "moby", Topic: containerdTaskStartTopic, Event: event})
+
+	infos := <-out
+	require.Len(t, infos, 1)
+	assert.Equal(t, "container_id://builder", infos[0].Entity)
+	assert.Contains(t, infos[0].LowCardTags, "team:ci")
+}
+
+func TestContainerdFetchPod(t *testing.T) {
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("k8s.io", &containerdtest.Container{Record: containerdcontainers.Container{
+		ID: "redis",
+		Labels: map[string]string{
+			"io.kubernetes.pod.name":      "redis-0",
+			"io.kubernetes.pod.namespace": "default",
+			"io.kubernetes.pod.uid":       "9d6b2d9e-d0b6-11e8-a6a8-42010a840004",
+		},
+	}}))
+	c := &ContainerdCollector{
+		containerdUtil: daemon.Util("k8s.io"),
+		podContainers:  make(map[string]map[string]struct{}),
+		containerPods:  make(map[string]string),
+	}
+
+	low, high, err := c.Fetch("kubernetes_pod://9d6b2d9e-d0b6-11e8-a6a8-42010a840004")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kube_namespace:default"}, low)
+	assert.Equal(t, []string{"pod_name:redis-0"}, high)
+	assert.Equal(t, "9d6b2d9e-d0b6-11e8-a6a8-42010a840004", c.containerPods["redis"])
+
+	_, _, err = c.Fetch("kubernetes_pod://unknown")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestContainerdStreamResubscribes(t *testing.T) {
+	defer func(delay time.Duration) { containerdStreamRetryDelay = delay }(containerdStreamRetryDelay)
+	containerdStreamRetryDelay = 10 * time.Millisecond
+
+	daemon := containerdtest.NewDaemon()
+	require.NoError(t, daemon.AddContainer("default", &containerdtest.Container{Record: containerdcontainers.Container{
+		ID:     "redis",
+		Labels: map[string]string{"team": "storage"},
+	}}))
+	cu := daemon.Util("default")
+	defer containerd.DefaultProvider().SetForTests(cu)()
+	out := make(chan []*TagInfo, 10)
+	c := &ContainerdCollector{
+		containerdUtil: cu,
+		resolve:        func(namespace, id string) (containerdclient.Container, error) { return cu.LoadContainer(id) },
+		stop:           make(chan struct{}),
+		infoOut:        out,
+		labelsAsTags:   map[string]string{"team": "team"},
+		podContainers:  make(map[string]map[string]struct{}),
+		containerPods:  make(map[string]string),
+	}
+	done := make(chan error)
+	go func() { done <- c.Stream() }()
+
+	require.True(t, daemon.Events().WaitForSubscribers(1, time.Second))
+	<-out // warm cache
+	daemon.Events().Fail(fmt.Errorf("connection reset"))
+
+	// The events are received again after the retry delay
+	require.True(t, daemon.Events().WaitForSubscribers(1, time.Second))
+	<-out // warm cache
+	require.NoError(t, daemon.Events().Send("default", containerdTaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}))
+	infos := <-out
+	require.Len(t, infos, 1)
+	assert.Equal(t, "container_id://redis", infos[0].Entity)
+
+	require.NoError(t, c.Stop())
+	require.NoError(t, <-done)
+	status := health.GetStatus()
+	assert.NotContains(t, status.Healthy, "tagger-containerd")
+	assert.NotContains(t, status.Unhealthy, "tagger-containerd")
+}
@@ -34,6 +34,9 @@ type DockerCollector struct {
 
 // Detect tries to connect to the docker socket and returns success
 func (c *DockerCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	if err := containers.CheckRuntimeSelected(containers.RuntimeNameDocker); err != nil {
+		return NoCollection, err
+	}
 	du, err := docker.GetDockerUtil()
 	if err != nil {
 		return NoCollection, err
@@ -131,7 +131,7 @@ func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 			cLow, cHigh := cTags.Compute()
 			info := &TagInfo{
 				Source:       kubeletCollectorName,
-				Entity:       container.ID,
+				Entity:       containers.CanonicalEntityName(container.ID),
 				HighCardTags: cHigh,
 				LowCardTags:  cLow,
 			}
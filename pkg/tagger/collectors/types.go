@@ -35,9 +35,13 @@ type Collector interface {
 // CollectorPriority helps resolving dupe tags from collectors
 type CollectorPriority int
 
-// List of collector priorities
+// List of collector priorities. When several collectors tag an entity with
+// tags of the same name, only the tags of the collector with the highest
+// priority are kept, eg. the ones of the kubelet over the ones of
+// containerd, then over the ones read from the CRI.
 const (
-	NodeRuntime CollectorPriority = iota
+	NodeLowLevelRuntime CollectorPriority = iota
+	NodeRuntime
 	NodeOrchestrator
 	ClusterOrchestrator
 )
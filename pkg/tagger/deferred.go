@@ -0,0 +1,274 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package tagger
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+/*
+ * The runtime collectors, eg. containerd, report the tags of a new container
+ * once they process its start event, which can come after the first samples
+ * the container sends, eg. to dogstatsd. Instead of sending them with the
+ * tags known so far, the callers can defer them until the tags are reported,
+ * for at most tagger_deferral_max_delay seconds. An entity whose tags are
+ * not reported in time is not deferred again, until it is forgotten.
+ */
+
+// deferredRetention is the time the entities given up on are remembered
+const deferredRetention = time.Hour
+
+// deferredCallback is a caller waiting for the tags of an entity
+type deferredCallback struct {
+	highCard bool
+	fn       func(tags []string)
+}
+
+// deferredEntity holds the callers waiting for the tags of an entity
+type deferredEntity struct {
+	deadline  time.Time
+	callbacks []deferredCallback
+}
+
+// resolvedEntity holds the callbacks of an entity to call
+type resolvedEntity struct {
+	entity    string
+	callbacks []deferredCallback
+}
+
+// deferredTags holds the callers waiting for the runtime tags of the new
+// containers, by entity
+type deferredTags struct {
+	sync.Mutex
+	maxDelay time.Duration
+	// maxPending bounds the number of callbacks held
+	maxPending int
+	pending    int
+	entities   map[string]*deferredEntity
+	// resolved queues the callbacks to call, in order, by the goroutine of
+	// callResolved, signaled on ready
+	resolved []resolvedEntity
+	ready    chan struct{}
+}
+
+func newDeferredTags() *deferredTags {
+	return &deferredTags{
+		entities: make(map[string]*deferredEntity),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// configure sets the limits of the deferral, a zero maxDelay disables it
+func (d *deferredTags) configure(maxDelay time.Duration, maxPending int) {
+	d.Lock()
+	defer d.Unlock()
+	d.maxDelay = maxDelay
+	d.maxPending = maxPending
+}
+
+func (d *deferredTags) enabled() bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.maxDelay > 0
+}
+
+// add holds cb until the tags of entity are reported, it returns false if
+// the entity was given up on or too many callbacks are held
+func (d *deferredTags) add(entity string, cb deferredCallback, now time.Time) bool {
+	d.Lock()
+	defer d.Unlock()
+	if d.maxDelay <= 0 || d.pending >= d.maxPending {
+		return false
+	}
+	e, found := d.entities[entity]
+	if !found {
+		e = &deferredEntity{deadline: now.Add(d.maxDelay)}
+		d.entities[entity] = e
+	}
+	if !now.Before(e.deadline) {
+		return false
+	}
+	e.callbacks = append(e.callbacks, cb)
+	d.pending++
+	return true
+}
+
+// take forgets entity and returns its callbacks
+func (d *deferredTags) take(entity string) []deferredCallback {
+	d.Lock()
+	defer d.Unlock()
+	e, found := d.entities[entity]
+	if !found {
+		return nil
+	}
+	delete(d.entities, entity)
+	d.pending -= len(e.callbacks)
+	return e.callbacks
+}
+
+// expire returns the callbacks of the entities whose deadline passed at
+// now, by entity. The entities are remembered as given up on.
+func (d *deferredTags) expire(now time.Time) map[string][]deferredCallback {
+	d.Lock()
+	defer d.Unlock()
+	expired := make(map[string][]deferredCallback)
+	for entity, e := range d.entities {
+		if len(e.callbacks) == 0 || now.Before(e.deadline) {
+			continue
+		}
+		expired[entity] = e.callbacks
+		d.pending -= len(e.callbacks)
+		e.callbacks = nil
+	}
+	return expired
+}
+
+// takeAll forgets every entity and returns their callbacks, by entity
+func (d *deferredTags) takeAll() map[string][]deferredCallback {
+	d.Lock()
+	defer d.Unlock()
+	all := make(map[string][]deferredCallback)
+	for entity, e := range d.entities {
+		if len(e.callbacks) > 0 {
+			all[entity] = e.callbacks
+		}
+	}
+	d.entities = make(map[string]*deferredEntity)
+	d.pending = 0
+	return all
+}
+
+// queue queues the callbacks of an entity to call
+func (d *deferredTags) queue(entity string, callbacks []deferredCallback) {
+	if len(callbacks) == 0 {
+		return
+	}
+	d.Lock()
+	d.resolved = append(d.resolved, resolvedEntity{entity: entity, callbacks: callbacks})
+	d.Unlock()
+	select {
+	case d.ready <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue returns the callbacks queued
+func (d *deferredTags) dequeue() []resolvedEntity {
+	d.Lock()
+	defer d.Unlock()
+	resolved := d.resolved
+	d.resolved = nil
+	return resolved
+}
+
+// prune forgets the entities given up on before the given time
+func (d *deferredTags) prune(before time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	for entity, e := range d.entities {
+		if len(e.callbacks) == 0 && e.deadline.Before(before) {
+			delete(d.entities, entity)
+		}
+	}
+}
+
+// HasRuntimeTags returns false if a runtime collector streaming the events
+// of the containers, eg. containerd, is running but did not report tags for
+// the entity yet, eg. when it did not process the start of a new container.
+func (t *Tagger) HasRuntimeTags(entity string) bool {
+	t.RLock()
+	defer t.RUnlock()
+
+	runtimeRunning := false
+	for name := range t.streamers {
+		if collectors.CollectorPriorities[name] != collectors.NodeRuntime {
+			continue
+		}
+		runtimeRunning = true
+		if t.tagStore.hasSourceTags(entity, name) {
+			return true
+		}
+	}
+	return !runtimeRunning
+}
+
+// RuntimeTagsPending returns true if entity is a container whose runtime
+// tags are not reported yet, and whose samples can be deferred with Defer
+func (t *Tagger) RuntimeTagsPending(entity string) bool {
+	if !t.deferred.enabled() {
+		return false
+	}
+	if !strings.HasPrefix(containers.CanonicalEntityName(entity), containers.ContainerEntityPrefix) {
+		return false
+	}
+	return !t.HasRuntimeTags(entity)
+}
+
+// Defer calls fn with the tags of entity once its runtime tags are
+// reported, or once tagger_deferral_max_delay seconds passed. fn is called
+// right away if the tags are known already, the deferral is disabled, or
+// too many callers are waiting, else it is called from the goroutine calling
+// the deferred callers in turn, fn must not block.
+func (t *Tagger) Defer(entity string, highCard bool, fn func(tags []string)) {
+	entity = containers.CanonicalEntityName(entity)
+	// The tags may be fetched on demand
+	tags, _ := t.Tag(entity, highCard)
+	if !t.RuntimeTagsPending(entity) || !t.deferred.add(entity, deferredCallback{highCard: highCard, fn: fn}, time.Now()) {
+		fn(tags)
+		return
+	}
+	// The tags may have been reported in the meantime
+	if t.HasRuntimeTags(entity) {
+		t.resolveDeferred(entity)
+	}
+}
+
+// resolveDeferred queues the callers waiting for the tags of entity
+func (t *Tagger) resolveDeferred(entity string) {
+	t.deferred.queue(entity, t.deferred.take(entity))
+}
+
+// resolveDeferredInfos calls the callers waiting for the entities of infos
+// whose tags are reported, or which are deleted
+func (t *Tagger) resolveDeferredInfos(infos []*collectors.TagInfo) {
+	for _, info := range infos {
+		if info == nil {
+			continue
+		}
+		entity := containers.CanonicalEntityName(info.Entity)
+		if info.DeleteEntity || t.HasRuntimeTags(entity) {
+			t.resolveDeferred(entity)
+		}
+	}
+}
+
+// callResolved calls the callbacks queued from a single goroutine, until
+// done is closed
+func (t *Tagger) callResolved(done chan struct{}) {
+	for {
+		select {
+		case <-t.deferred.ready:
+			t.callDeferred()
+		case <-done:
+			t.callDeferred()
+			return
+		}
+	}
+}
+
+func (t *Tagger) callDeferred() {
+	for _, resolved := range t.deferred.dequeue() {
+		for _, cb := range resolved.callbacks {
+			tags, _ := t.Tag(resolved.entity, cb.highCard)
+			cb.fn(tags)
+		}
+	}
+}
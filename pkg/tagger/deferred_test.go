@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package tagger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/errors"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+func TestDeferredTags(t *testing.T) {
+	d := newDeferredTags()
+	now := time.Now()
+	cb := deferredCallback{fn: func([]string) {}}
+
+	// Disabled
+	assert.False(t, d.add("container_id://abcd", cb, now))
+
+	d.configure(5*time.Second, 3)
+	assert.True(t, d.add("container_id://abcd", cb, now))
+	assert.True(t, d.add("container_id://abcd", cb, now.Add(time.Second)))
+	assert.True(t, d.add("container_id://ef01", cb, now))
+	// Too many callbacks are held
+	assert.False(t, d.add("container_id://2345", cb, now))
+
+	assert.Len(t, d.take("container_id://ef01"), 1)
+	assert.Empty(t, d.expire(now.Add(time.Second)))
+	expired := d.expire(now.Add(5 * time.Second))
+	assert.Len(t, expired["container_id://abcd"], 2)
+	assert.Equal(t, 0, d.pending)
+
+	// The entity given up on is not deferred again until forgotten
+	assert.False(t, d.add("container_id://abcd", cb, now.Add(6*time.Second)))
+	d.prune(now.Add(6 * time.Second))
+	assert.True(t, d.add("container_id://abcd", cb, now.Add(6*time.Second)))
+	assert.Len(t, d.takeAll(), 1)
+	assert.Empty(t, d.entities)
+
+	// The callbacks are queued for the caller goroutine
+	d.queue("container_id://abcd", nil)
+	assert.Empty(t, d.dequeue())
+	d.queue("container_id://abcd", []deferredCallback{cb})
+	d.queue("container_id://ef01", []deferredCallback{cb, cb})
+	<-d.ready
+	resolved := d.dequeue()
+	assert.Len(t, resolved, 2)
+	assert.Equal(t, "container_id://abcd", resolved[0].entity)
+	assert.Len(t, resolved[1].callbacks, 2)
+	assert.Empty(t, d.dequeue())
+}
+
+func TestDefer(t *testing.T) {
+	c := &DummyCollector{}
+	c.On("Detect", mock.Anything).Return(collectors.StreamCollection, nil)
+	c.On("Stream").Return(nil)
+	c.On("Stop").Return(nil)
+	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, errors.NewNotFound(""))
+	catalog := collectors.Catalog{"runtime": func() collectors.Collector { return c }}
+	collectors.CollectorPriorities["runtime"] = collectors.NodeRuntime
+	defer delete(collectors.CollectorPriorities, "runtime")
+
+	tagger := newTagger()
+	tagger.Init(catalog)
+	defer tagger.Stop()
+
+	received := make(chan []string, 1)
+	receive := func(tags []string) { received <- tags }
+
+	// The deferral is disabled
+	assert.False(t, tagger.RuntimeTagsPending("container_id://abcd"))
+	tagger.Defer("container_id://abcd", true, receive)
+	assert.Empty(t, <-received)
+
+	tagger.deferred.configure(time.Minute, 100)
+	assert.True(t, tagger.RuntimeTagsPending("container_id://abcd"))
+	// Only the containers are deferred
+	assert.False(t, tagger.RuntimeTagsPending("kubernetes_pod_uid://1234"))
+
+	tagger.Defer("containerd://abcd", true, receive)
+	select {
+	case tags := <-received:
+		require.FailNow(t, "the tags are not reported yet", "received %v", tags)
+	default:
+	}
+
+	tagger.infoIn <- []*collectors.TagInfo{{
+		Entity:      "container_id://abcd",
+		Source:      "runtime",
+		LowCardTags: []string{"image_name:redis"},
+	}}
+	select {
+	case tags := <-received:
+		assert.Equal(t, []string{"image_name:redis"}, tags)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the deferred callback was not called")
+	}
+
+	// The tags are known now
+	assert.False(t, tagger.RuntimeTagsPending("container_id://abcd"))
+	tagger.Defer("container_id://abcd", false, receive)
+	assert.Equal(t, []string{"image_name:redis"}, <-received)
+}
@@ -35,6 +35,30 @@ func Tag(entity string, highCard bool) ([]string, error) {
 	return defaultTagger.Tag(entity, highCard)
 }
 
+// HasOrchestratorTags queries the defaultTagger to know if the orchestrator
+// tags of an entity are resolved
+func HasOrchestratorTags(entity string) bool {
+	return defaultTagger.HasOrchestratorTags(entity)
+}
+
+// HasRuntimeTags queries the defaultTagger to know if the runtime tags of
+// an entity are reported
+func HasRuntimeTags(entity string) bool {
+	return defaultTagger.HasRuntimeTags(entity)
+}
+
+// RuntimeTagsPending queries the defaultTagger to know if the samples of an
+// entity can be deferred until its runtime tags are reported
+func RuntimeTagsPending(entity string) bool {
+	return defaultTagger.RuntimeTagsPending(entity)
+}
+
+// Defer calls fn with the tags of an entity once the defaultTagger knows
+// its runtime tags, see Tagger.Defer
+func Defer(entity string, highCard bool, fn func(tags []string)) {
+	defaultTagger.Defer(entity, highCard, fn)
+}
+
 // Stop queues a stop signal to the defaultTagger
 func Stop() error {
 	return defaultTagger.Stop()
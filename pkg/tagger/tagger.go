@@ -13,10 +13,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/errors"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/retry"
 )
 
@@ -34,8 +36,13 @@ type Tagger struct {
 	pullTicker  *time.Ticker
 	pruneTicker *time.Ticker
 	retryTicker *time.Ticker
+	deferTicker *time.Ticker
 	stop        chan bool
 	health      *health.Handle
+	// deferred holds the callers waiting for the runtime tags of the new
+	// containers, see Defer. deferredDone stops their caller.
+	deferred     *deferredTags
+	deferredDone chan struct{}
 }
 
 type collectorReply struct {
@@ -57,8 +64,10 @@ func newTagger() *Tagger {
 		fetchers:    make(map[string]collectors.Fetcher),
 		infoIn:      make(chan []*collectors.TagInfo, 5),
 		pullTicker:  time.NewTicker(5 * time.Second),
-		pruneTicker: time.NewTicker(5 * time.Minute),
+		pruneTicker: time.NewTicker(1 * time.Minute),
 		retryTicker: time.NewTicker(30 * time.Second),
+		deferTicker: time.NewTicker(1 * time.Second),
+		deferred:    newDeferredTags(),
 		stop:        make(chan bool),
 	}
 }
@@ -71,6 +80,9 @@ func (t *Tagger) Init(catalog collectors.Catalog) {
 
 	// Only register the health check when the tagger is started
 	t.health = health.Register("tagger")
+	t.tagStore.deletionGracePeriod = config.Datadog.GetDuration("tagger_deletion_grace_period") * time.Second
+	t.deferred.configure(config.Datadog.GetDuration("tagger_deferral_max_delay")*time.Second, config.Datadog.GetInt("tagger_deferral_max_pending"))
+	t.deferredDone = make(chan struct{})
 
 	// Populate collector candidate list from catalog
 	// as we'll remove entries we need to copy the map
@@ -82,6 +94,7 @@ func (t *Tagger) Init(catalog collectors.Catalog) {
 	log.Info("starting the tagging system")
 
 	t.startCollectors()
+	go t.callResolved(t.deferredDone)
 	go t.run()
 	go t.pull()
 }
@@ -101,6 +114,12 @@ func (t *Tagger) run() error {
 			t.pullTicker.Stop()
 			t.pruneTicker.Stop()
 			t.retryTicker.Stop()
+			t.deferTicker.Stop()
+			// The deferred callers get the tags known so far
+			for entity, callbacks := range t.deferred.takeAll() {
+				t.deferred.queue(entity, callbacks)
+			}
+			close(t.deferredDone)
 			t.health.Deregister()
 			return nil
 		case <-t.health.C:
@@ -108,12 +127,19 @@ func (t *Tagger) run() error {
 			for _, info := range msg {
 				t.tagStore.processTagInfo(info)
 			}
+			t.resolveDeferredInfos(msg)
+		case <-t.deferTicker.C:
+			for entity, callbacks := range t.deferred.expire(time.Now()) {
+				t.deferred.queue(entity, callbacks)
+			}
 		case <-t.retryTicker.C:
 			go t.startCollectors()
 		case <-t.pullTicker.C:
 			go t.pull()
 		case <-t.pruneTicker.C:
-			t.tagStore.prune()
+			now := time.Now()
+			t.tagStore.prune(now)
+			t.deferred.prune(now.Add(-deferredRetention))
 		}
 	}
 }
@@ -228,6 +254,7 @@ func (t *Tagger) Tag(entity string, highCard bool) ([]string, error) {
 	if entity == "" {
 		return nil, fmt.Errorf("empty entity ID")
 	}
+	entity = containers.CanonicalEntityName(entity)
 	cachedTags, sources, _ := t.tagStore.lookup(entity, highCard)
 
 	if len(sources) == len(t.fetchers) {
@@ -274,6 +301,26 @@ IterCollectors:
 	return copyArray(computedTags), nil
 }
 
+// HasOrchestratorTags returns false if an orchestrator collector is running
+// but did not report tags for the entity yet, eg. when the kubelet does not
+// list the pod of a new container yet.
+func (t *Tagger) HasOrchestratorTags(entity string) bool {
+	t.RLock()
+	defer t.RUnlock()
+
+	orchestratorRunning := false
+	for name := range t.fetchers {
+		if collectors.CollectorPriorities[name] != collectors.NodeOrchestrator {
+			continue
+		}
+		orchestratorRunning = true
+		if t.tagStore.hasSourceTags(entity, name) {
+			return true
+		}
+	}
+	return !orchestratorRunning
+}
+
 // List the content of the tagger
 func (t *Tagger) List(highCard bool) response.TaggerListResponse {
 	r := response.TaggerListResponse{
@@ -287,6 +334,7 @@ func (t *Tagger) List(highCard bool) response.TaggerListResponse {
 		tags, sources, _ := et.get(highCard)
 		entity.Tags = copyArray(tags)
 		entity.Sources = copyArray(sources)
+		entity.Provenance = et.provenance(tags)
 		r.Entities[entityID] = entity
 	}
 
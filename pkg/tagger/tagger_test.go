@@ -286,6 +286,39 @@ func TestErrNotFound(t *testing.T) {
 	c.AssertNumberOfCalls(t, "Fetch", 2)
 }
 
+func TestHasOrchestratorTags(t *testing.T) {
+	catalog := collectors.Catalog{"stream": NewDummyStreamer, "pull": NewDummyPuller}
+	tagger := newTagger()
+	tagger.Init(catalog)
+
+	// No orchestrator collector
+	assert.True(t, tagger.HasOrchestratorTags("entity_name"))
+
+	collectors.CollectorPriorities["pull"] = collectors.NodeOrchestrator
+	defer delete(collectors.CollectorPriorities, "pull")
+
+	tagger.tagStore.processTagInfo(&collectors.TagInfo{
+		Entity:      "entity_name",
+		Source:      "stream",
+		LowCardTags: []string{"image_name:redis"},
+	})
+	assert.False(t, tagger.HasOrchestratorTags("entity_name"))
+
+	// The orchestrator does not know the entity yet
+	tagger.tagStore.processTagInfo(&collectors.TagInfo{
+		Entity: "entity_name",
+		Source: "pull",
+	})
+	assert.False(t, tagger.HasOrchestratorTags("entity_name"))
+
+	tagger.tagStore.processTagInfo(&collectors.TagInfo{
+		Entity:      "entity_name",
+		Source:      "pull",
+		LowCardTags: []string{"kube_namespace:default"},
+	})
+	assert.True(t, tagger.HasOrchestratorTags("entity_name"))
+}
+
 func TestSafeCache(t *testing.T) {
 	catalog := collectors.Catalog{"pull": NewDummyPuller}
 	tagger := newTagger()
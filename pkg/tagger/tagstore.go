@@ -7,7 +7,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
@@ -23,6 +25,8 @@ type entityTags struct {
 	cachedAll    []string // Low + high
 	cachedLow    []string // Sub-slice of cachedAll
 	tagsHash     string
+	// cachedProvenance holds the sources of every tag of cachedAll
+	cachedProvenance map[string][]string
 }
 
 // tagStore stores entity tags in memory and handles search and collation.
@@ -31,13 +35,16 @@ type tagStore struct {
 	storeMutex    sync.RWMutex
 	store         map[string]*entityTags
 	toDeleteMutex sync.RWMutex
-	toDelete      map[string]struct{} // set emulation
+	toDelete      map[string]time.Time // entity -> deletion time
+	// deletionGracePeriod is the time the tags of a deleted entity are
+	// kept, to tag the metrics and logs sent after its deletion
+	deletionGracePeriod time.Duration
 }
 
 func newTagStore() *tagStore {
 	return &tagStore{
 		store:    make(map[string]*entityTags),
-		toDelete: make(map[string]struct{}),
+		toDelete: make(map[string]time.Time),
 	}
 }
 
@@ -51,23 +58,32 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	if info.Source == "" {
 		return fmt.Errorf("empty source name, skipping message")
 	}
+	// The collectors may name the same container differently, eg. the
+	// kubelet uses containerd://<id> for the containerd containers
+	entity := containers.CanonicalEntityName(info.Entity)
+	s.toDeleteMutex.Lock()
 	if info.DeleteEntity {
-		s.toDeleteMutex.Lock()
-		s.toDelete[info.Entity] = struct{}{}
+		// The grace period starts with the first deletion
+		if _, found := s.toDelete[entity]; !found {
+			s.toDelete[entity] = time.Now()
+		}
 		s.toDeleteMutex.Unlock()
 		return nil
 	}
+	// The entity is back, eg. a container created again with the same ID
+	delete(s.toDelete, entity)
+	s.toDeleteMutex.Unlock()
 
 	// TODO: check if real change
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
-	storedTags, exist := s.store[info.Entity]
+	storedTags, exist := s.store[entity]
 	if !exist {
 		storedTags = &entityTags{
 			lowCardTags:  make(map[string][]string),
 			highCardTags: make(map[string][]string),
 		}
-		s.store[info.Entity] = storedTags
+		s.store[entity] = storedTags
 	}
 
 	storedTags.Lock()
@@ -95,8 +111,9 @@ func computeTagsHash(tags []string) string {
 }
 
 // prune will lock the store and delete tags for the entity previously
-// passed as delete. This is to be called regularly from the user class.
-func (s *tagStore) prune() error {
+// passed as delete, once their deletion grace period is over at now.
+// This is to be called regularly from the user class.
+func (s *tagStore) prune(now time.Time) error {
 	s.toDeleteMutex.Lock()
 	defer s.toDeleteMutex.Unlock()
 
@@ -106,14 +123,17 @@ func (s *tagStore) prune() error {
 
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
-	for entity := range s.toDelete {
+	pruned := 0
+	for entity, deletedAt := range s.toDelete {
+		if now.Sub(deletedAt) < s.deletionGracePeriod {
+			continue
+		}
 		delete(s.store, entity)
+		delete(s.toDelete, entity)
+		pruned++
 	}
 
-	log.Debugf("pruned %d removed entities, %d remaining", len(s.toDelete), len(s.store))
-
-	// Start fresh
-	s.toDelete = make(map[string]struct{})
+	log.Debugf("pruned %d removed entities, %d pending, %d remaining", pruned, len(s.toDelete), len(s.store))
 
 	return nil
 }
@@ -125,7 +145,7 @@ func (s *tagStore) prune() error {
 func (s *tagStore) lookup(entity string, highCard bool) ([]string, []string, string) {
 	s.storeMutex.RLock()
 	defer s.storeMutex.RUnlock()
-	storedTags, present := s.store[entity]
+	storedTags, present := s.store[containers.CanonicalEntityName(entity)]
 
 	if present == false {
 		return nil, nil, ""
@@ -133,10 +153,25 @@ func (s *tagStore) lookup(entity string, highCard bool) ([]string, []string, str
 	return storedTags.get(highCard)
 }
 
+// hasSourceTags returns true if the source reported non-empty tags for the entity
+func (s *tagStore) hasSourceTags(entity, source string) bool {
+	s.storeMutex.RLock()
+	defer s.storeMutex.RUnlock()
+	storedTags, present := s.store[containers.CanonicalEntityName(entity)]
+	if !present {
+		return false
+	}
+
+	storedTags.RLock()
+	defer storedTags.RUnlock()
+	return len(storedTags.lowCardTags[source]) > 0 || len(storedTags.highCardTags[source]) > 0
+}
+
 type tagPriority struct {
 	tag        string                       // full tag
 	priority   collectors.CollectorPriority // collector priority
 	isHighCard bool                         // is the tag high cardinality
+	source     string                       // collector name
 }
 
 func (e *entityTags) get(highCard bool) ([]string, []string, string) {
@@ -166,6 +201,7 @@ func (e *entityTags) get(highCard bool) ([]string, []string, string) {
 
 	var lowCardTags []string
 	var highCardTags []string
+	provenance := make(map[string][]string)
 	for _, tags := range tagPrioMapper {
 		for i := 0; i < len(tags); i++ {
 			insert := true
@@ -179,6 +215,7 @@ func (e *entityTags) get(highCard bool) ([]string, []string, string) {
 			if !insert {
 				continue
 			}
+			provenance[tags[i].tag] = append(provenance[tags[i].tag], tags[i].source)
 			if tags[i].isHighCard {
 				highCardTags = append(highCardTags, tags[i].tag)
 				continue
@@ -188,6 +225,9 @@ func (e *entityTags) get(highCard bool) ([]string, []string, string) {
 	}
 
 	tags := append(lowCardTags, highCardTags...)
+	for _, sources := range provenance {
+		sort.Strings(sources)
+	}
 
 	// Write cache
 	e.cacheValid = true
@@ -195,6 +235,7 @@ func (e *entityTags) get(highCard bool) ([]string, []string, string) {
 	e.cachedAll = tags
 	e.cachedLow = e.cachedAll[:len(lowCardTags)]
 	e.tagsHash = computeTagsHash(e.cachedAll)
+	e.cachedProvenance = provenance
 
 	if highCard {
 		return tags, sources, e.tagsHash
@@ -202,6 +243,22 @@ func (e *entityTags) get(highCard bool) ([]string, []string, string) {
 	return lowCardTags, sources, e.tagsHash
 }
 
+// provenance returns the sources of the tags returned by get, the tags of
+// the sources with a lower priority are overridden by the ones of the
+// sources with a higher priority with the same name, eg. the tags of the
+// containerd collector by the ones of the kubelet collector
+func (e *entityTags) provenance(tags []string) map[string][]string {
+	e.RLock()
+	defer e.RUnlock()
+	provenance := make(map[string][]string, len(tags))
+	for _, tag := range tags {
+		if sources, found := e.cachedProvenance[tag]; found {
+			provenance[tag] = copyArray(sources)
+		}
+	}
+	return provenance
+}
+
 func insertWithPriority(tagPrioMapper map[string][]tagPriority, tags []string, source string, isHighCard bool) {
 	priority, found := collectors.CollectorPriorities[source]
 	if !found {
@@ -215,6 +272,7 @@ func insertWithPriority(tagPrioMapper map[string][]tagPriority, tags []string, s
 			tag:        t,
 			priority:   priority,
 			isHighCard: isHighCard,
+			source:     source,
 		})
 	}
 }
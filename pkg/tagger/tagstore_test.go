@@ -8,6 +8,7 @@ package tagger
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -82,6 +83,33 @@ func (s *StoreTestSuite) TestLookupNotPresent() {
 	assert.Nil(s.T(), sources)
 }
 
+func (s *StoreTestSuite) TestContainerdEntityNames() {
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "kubelet",
+		Entity:      "containerd://5bef08742407ef",
+		LowCardTags: []string{"kube_namespace:default"},
+	})
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "containerd",
+		Entity:      "container_id://5bef08742407ef",
+		LowCardTags: []string{"image_name:redis"},
+	})
+
+	tags, sources, _ := s.store.lookup("container_id://5bef08742407ef", false)
+	assert.ElementsMatch(s.T(), []string{"kube_namespace:default", "image_name:redis"}, tags)
+	assert.Len(s.T(), sources, 2)
+	assert.True(s.T(), s.store.hasSourceTags("containerd://5bef08742407ef", "kubelet"))
+
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:       "kubelet",
+		Entity:       "containerd://5bef08742407ef",
+		DeleteEntity: true,
+	})
+	s.store.prune(time.Now())
+	tags, _, _ = s.store.lookup("container_id://5bef08742407ef", false)
+	assert.Nil(s.T(), tags)
+}
+
 func (s *StoreTestSuite) TestPrune() {
 	s.store.toDeleteMutex.RLock()
 	assert.Len(s.T(), s.store.toDelete, 0)
@@ -127,7 +155,7 @@ func (s *StoreTestSuite) TestPrune() {
 	assert.Len(s.T(), sourcesHigh, 1)
 	assert.Equal(s.T(), "c84d937037763631", hashHigh)
 
-	s.store.prune()
+	s.store.prune(time.Now())
 
 	// deletion map should be empty now
 	s.store.toDeleteMutex.RLock()
@@ -144,7 +172,7 @@ func (s *StoreTestSuite) TestPrune() {
 	assert.Len(s.T(), sourcesHigh, 1)
 	assert.Equal(s.T(), "c84d937037763631", hashHigh)
 
-	err := s.store.prune()
+	err := s.store.prune(time.Now())
 	assert.Nil(s.T(), err)
 
 	// No impact if nothing is queued
@@ -157,6 +185,49 @@ func (s *StoreTestSuite) TestPrune() {
 
 }
 
+func (s *StoreTestSuite) TestPruneGracePeriod() {
+	s.store.deletionGracePeriod = 5 * time.Minute
+	for _, entity := range []string{"test1", "test2"} {
+		s.store.processTagInfo(&collectors.TagInfo{
+			Source:      "source1",
+			Entity:      entity,
+			LowCardTags: []string{"tag"},
+		})
+		s.store.processTagInfo(&collectors.TagInfo{
+			Source:       "source1",
+			Entity:       entity,
+			DeleteEntity: true,
+		})
+	}
+	// test2 is created again, its deletion is canceled
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "source1",
+		Entity:      "test2",
+		LowCardTags: []string{"tag"},
+	})
+
+	// The tags are kept during the grace period
+	s.store.prune(time.Now().Add(time.Minute))
+	tags, _, _ := s.store.lookup("test1", false)
+	assert.Equal(s.T(), []string{"tag"}, tags)
+
+	// A second deletion does not extend it
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:       "source1",
+		Entity:       "test1",
+		DeleteEntity: true,
+	})
+	s.store.prune(time.Now().Add(6 * time.Minute))
+	tags, _, _ = s.store.lookup("test1", false)
+	assert.Nil(s.T(), tags)
+	tags, _, _ = s.store.lookup("test2", false)
+	assert.Equal(s.T(), []string{"tag"}, tags)
+
+	s.store.toDeleteMutex.RLock()
+	assert.Len(s.T(), s.store.toDelete, 0)
+	s.store.toDeleteMutex.RUnlock()
+}
+
 func TestStoreSuite(t *testing.T) {
 	suite.Run(t, &StoreTestSuite{})
 }
@@ -250,6 +321,35 @@ func TestDuplicateSourceTags(t *testing.T) {
 	assert.Equal(t, "b4e89f91534288c8", hash)
 }
 
+func TestTagProvenance(t *testing.T) {
+	etags := entityTags{
+		lowCardTags:  make(map[string][]string),
+		highCardTags: make(map[string][]string),
+	}
+	collectors.CollectorPriorities = map[string]collectors.CollectorPriority{
+		"kubelet":    collectors.NodeOrchestrator,
+		"containerd": collectors.NodeRuntime,
+		"cri":        collectors.NodeLowLevelRuntime,
+	}
+	etags.lowCardTags["kubelet"] = []string{"kube_namespace:default", "image_tag:7"}
+	etags.lowCardTags["containerd"] = []string{"image_name:redis", "image_tag:latest", "short_image:redis"}
+	etags.lowCardTags["cri"] = []string{"image_name:docker.io/library/redis", "short_image:redis"}
+	etags.highCardTags["containerd"] = []string{"container_id:5bef08742407ef"}
+
+	tags, _, _ := etags.get(true)
+	assert.ElementsMatch(t, []string{"kube_namespace:default", "image_tag:7", "image_name:redis", "short_image:redis", "container_id:5bef08742407ef"}, tags)
+	assert.Equal(t, map[string][]string{
+		"kube_namespace:default":      {"kubelet"},
+		"image_tag:7":                 {"kubelet"},
+		"image_name:redis":            {"containerd"},
+		"short_image:redis":           {"containerd"},
+		"container_id:5bef08742407ef": {"containerd"},
+	}, etags.provenance(tags))
+
+	tags, _, _ = etags.get(false)
+	assert.Len(t, etags.provenance(tags), 4)
+}
+
 func shuffleTags(tags []string) {
 	for i := range tags {
 		j := rand.Intn(i + 1)
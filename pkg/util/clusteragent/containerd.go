@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/containerdrelay"
+)
+
+const dcaContainerdContainersPath = "api/v1/containerd/containers"
+
+// PostContainerdContainers is called by the containerd relay of the node
+func (c *DCAClient) PostContainerdContainers(nodeName string, containers containerdrelay.NodeContainers) error {
+	queryBody, err := json.Marshal(containers)
+	if err != nil {
+		return err
+	}
+
+	// https://host:port/api/v1/containerd/containers/{nodeName}
+	rawURL := c.leaderClient.buildURL(dcaContainerdContainersPath, nodeName)
+	req, err := http.NewRequest("POST", rawURL, bytes.NewBuffer(queryBody))
+	if err != nil {
+		return err
+	}
+	req.Header = c.clusterAgentAPIRequestHeaders
+
+	resp, err := c.leaderClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// GetContainerdContainers is called by the node-agents querying containerd
+// through the cluster-agent
+func (c *DCAClient) GetContainerdContainers(nodeName string) (containerdrelay.NodeContainers, error) {
+	var containers containerdrelay.NodeContainers
+
+	// https://host:port/api/v1/containerd/containers/{nodeName}
+	rawURL := c.leaderClient.buildURL(dcaContainerdContainersPath, nodeName)
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return containers, err
+	}
+	req.Header = c.clusterAgentAPIRequestHeaders
+
+	resp, err := c.leaderClient.Do(req)
+	if err != nil {
+		return containers, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return containers, fmt.Errorf("unexpected response: %d - %s", resp.StatusCode, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return containers, err
+	}
+	err = json.Unmarshal(b, &containers)
+	return containers, err
+}
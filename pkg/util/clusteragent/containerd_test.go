@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"fmt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/containerdrelay"
+)
+
+var dummyNodeContainers = `{
+"version": "1.7.0",
+"namespaces": {
+  "k8s.io": [
+    {
+      "id": "redis",
+      "image": "redis:5",
+      "runtime": "io.containerd.runc.v2"
+    }
+  ]
+}
+}`
+
+func (suite *clusterAgentSuite) TestContainerdRelay() {
+	dca, err := newDummyClusterAgent()
+	require.NoError(suite.T(), err)
+
+	dca.rawResponses["/api/v1/containerd/containers/mynode"] = dummyNodeContainers
+
+	ts, p, err := dca.StartTLS()
+	defer ts.Close()
+	require.NoError(suite.T(), err)
+	mockConfig.Set("cluster_agent.url", fmt.Sprintf("https://127.0.0.1:%d", p))
+
+	ca, err := GetClusterAgentClient()
+	require.NoError(suite.T(), err)
+
+	err = ca.PostContainerdContainers("mynode", containerdrelay.NodeContainers{Version: "1.7.0"})
+	require.NoError(suite.T(), err)
+
+	containers, err := ca.GetContainerdContainers("mynode")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "1.7.0", containers.Version)
+	require.Len(suite.T(), containers.Namespaces["k8s.io"], 1)
+	assert.Equal(suite.T(), "redis:5", containers.Namespaces["k8s.io"][0].Image)
+
+	_, err = ca.GetContainerdContainers("othernode")
+	assert.Error(suite.T(), err)
+}
@@ -87,6 +87,39 @@ func CopyFileAll(src, dst string) error {
 	return CopyFile(src, dst)
 }
 
+// WriteFileAtomic writes content to a temporary file renamed over path,
+// so that a crash during the write does not leave a truncated file.
+// The parent directories of path are created if needed.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	if err := EnsureParentDirsExist(path); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err = os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err = os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
 // EnsureParentDirsExist makes a path immediately available for
 // writing by creating the necessary parent directories.
 func EnsureParentDirsExist(p string) error {
@@ -204,7 +237,7 @@ func CreateHTTPTransport() *http.Transport {
 	// timeout to our http clients.
 	transport := &http.Transport{
 		TLSClientConfig: tlsConfig,
-		DialContext: (&net.Dialer{
+		DialContext: GetDialContext(&net.Dialer{
 			Timeout: 30 * time.Second,
 			// Enables TCP keepalives to detect broken connections
 			KeepAlive: 30 * time.Second,
@@ -212,7 +245,7 @@ func CreateHTTPTransport() *http.Transport {
 			// At this point we will need to disable it by setting a new attribute to false.
 			// See https://github.com/DataDog/datadog-agent/pull/2464
 			DualStack: false,
-		}).DialContext,
+		}),
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 5,
 		// This parameter is set to avoid connections sitting idle in the pool indefinitely
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+// AgentConfig reads the containerd settings of the agent configuration.
+// The package does not read the agent configuration itself: the agent sets
+// its AgentConfig with SetAgentConfig, the defaults are used otherwise. The
+// settings are read when first needed, once the configuration is loaded.
+type AgentConfig interface {
+	// Options returns the options of the utils handed out for nil options
+	Options() Options
+	// NamespaceFilter returns the namespaces collected by the agent
+	NamespaceFilter() NamespaceFilter
+	// NameResolver returns the resolver of ContainerNameResolver
+	NameResolver() NameResolver
+	// TypeResolver returns the resolver of ContainerTypeResolver
+	TypeResolver() TypeResolver
+}
+
+var agentConfig AgentConfig = defaultAgentConfig{}
+
+// SetAgentConfig sets the AgentConfig read by the package, before the
+// first util is handed out
+func SetAgentConfig(c AgentConfig) {
+	agentConfig = c
+}
+
+// defaultAgentConfig is the AgentConfig of the processes setting none
+type defaultAgentConfig struct{}
+
+func (defaultAgentConfig) Options() Options {
+	return Options{Namespace: DefaultNamespace}
+}
+
+func (defaultAgentConfig) NamespaceFilter() NamespaceFilter {
+	return NewNamespaceFilter([]string{DefaultNamespace}, nil)
+}
+
+func (defaultAgentConfig) NameResolver() NameResolver {
+	return NewNameResolver(DefaultContainerNameLabels)
+}
+
+func (defaultAgentConfig) TypeResolver() TypeResolver {
+	return NewTypeResolver(DefaultInitContainerNames, DefaultSidecarContainerNames)
+}
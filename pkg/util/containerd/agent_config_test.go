@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAgentConfig struct {
+	defaultAgentConfig
+	options Options
+}
+
+func (c testAgentConfig) Options() Options {
+	return c.options
+}
+
+func TestProviderAgentConfig(t *testing.T) {
+	defer SetAgentConfig(agentConfig)
+
+	SetAgentConfig(defaultAgentConfig{})
+	provider := NewProvider()
+	assert.Equal(t, DefaultNamespace, provider.ConfigOptions().Namespace)
+	assert.False(t, agentConfig.NamespaceFilter().IsExcluded(DefaultNamespace))
+	assert.True(t, agentConfig.NamespaceFilter().IsExcluded("moby"))
+
+	SetAgentConfig(testAgentConfig{options: Options{SocketPath: "/run/containerd/containerd.sock", Namespace: "moby"}})
+	// The options are resolved once
+	opts := provider.ConfigOptions()
+	assert.Equal(t, DefaultNamespace, opts.Namespace)
+
+	provider.Reset()
+	opts = provider.ConfigOptions()
+	assert.Equal(t, "/run/containerd/containerd.sock", opts.SocketPath)
+	assert.Equal(t, "moby", opts.Namespace)
+	assert.Equal(t, DefaultQueryTimeout, opts.QueryTimeout)
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// taskMetricsV2MinVersion is the first release reporting the
+// metrics of the tasks running in a cgroup v2 hierarchy
+const taskMetricsV2MinVersion = "1.4.0"
+
+// isCgroupV2Host returns whether the tasks run in a cgroup v2 hierarchy,
+// it is set on linux
+var isCgroupV2Host = func() bool { return false }
+
+// Capabilities lists the optional services of the connected daemon. They
+// are probed once per connection, features relying on them must be
+// disabled when they are missing rather than fail on every query.
+type Capabilities struct {
+	Version string
+	// TaskMetricsV2 is set if the task metrics can be cgroup v2 metrics
+	TaskMetricsV2 bool
+	// SandboxAPI is set if the sandbox store and controller are served
+	SandboxAPI bool
+	// TransferService is set if the image transfer service is served
+	TransferService bool
+}
+
+// String implements the `fmt.Stringer` interface
+func (c *Capabilities) String() string {
+	return fmt.Sprintf("containerd %s: task metrics v2=%t, sandbox API=%t, transfer service=%t",
+		c.Version, c.TaskMetricsV2, c.SandboxAPI, c.TransferService)
+}
+
+// Capabilities probes the plugins of the daemon on the first call
+// following a connection and returns the cached result afterwards
+func (c *ContainerdUtil) Capabilities() (*Capabilities, error) {
+	c.capsMux.Lock()
+	defer c.capsMux.Unlock()
+	if c.caps != nil {
+		return c.caps, nil
+	}
+
+	dump, err := c.ConfigDump()
+	if ErrorKind(err) == ErrUnsupported {
+		// The introspection service is missing, only the version is known
+		v, vErr := c.Metadata()
+		if vErr != nil {
+			return nil, vErr
+		}
+		dump, err = &ConfigDump{Version: v.Version, Revision: v.Revision}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.caps = capabilitiesFromDump(dump)
+	c.log.Infof("Probed the capabilities of %s", c.caps)
+	return c.caps, nil
+}
+
+// checkTaskMetricsSupport returns an ErrUnsupported error if the daemon
+// cannot report the metrics of the tasks of a cgroup v2 host
+func (c *ContainerdUtil) checkTaskMetricsSupport() error {
+	if !isCgroupV2Host() {
+		return nil
+	}
+	caps, err := c.Capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.TaskMetricsV2 {
+		return &Error{
+			Kind: ErrUnsupported,
+			Err:  fmt.Errorf("containerd %s does not report the metrics of cgroup v2 tasks, %s or later is required", caps.Version, taskMetricsV2MinVersion),
+		}
+	}
+	return nil
+}
+
+// checkSandboxSupport returns an ErrUnsupported error if the daemon does
+// not serve the sandbox API
+func (c *ContainerdUtil) checkSandboxSupport() error {
+	caps, err := c.Capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.SandboxAPI {
+		return &Error{
+			Kind: ErrUnsupported,
+			Err:  fmt.Errorf("containerd %s does not serve the sandbox API, 1.7 or later is required", caps.Version),
+		}
+	}
+	return nil
+}
+
+// capabilitiesFromDump derives the capabilities from the loaded plugins
+// and the version of the daemon
+func capabilitiesFromDump(dump *ConfigDump) *Capabilities {
+	caps := &Capabilities{Version: dump.Version}
+	var runtimeV2 bool
+	for _, p := range dump.Plugins {
+		if p.InitError != "" {
+			continue
+		}
+		switch {
+		case p.Type == "io.containerd.runtime.v2" && p.ID == "task":
+			runtimeV2 = true
+		case p.Type == "io.containerd.grpc.v1" && p.ID == "sandbox-controllers":
+			caps.SandboxAPI = true
+		case p.Type == "io.containerd.grpc.v1" && p.ID == "transfer":
+			caps.TransferService = true
+		}
+	}
+	caps.TaskMetricsV2 = runtimeV2 && versionAtLeast(dump.Version, taskMetricsV2MinVersion)
+	return caps
+}
+
+// versionAtLeast returns whether the daemon version v is min or later.
+// Unparsable versions, like development builds, are assumed to be recent.
+func versionAtLeast(v, min string) bool {
+	current, err := version.New(strings.TrimPrefix(v, "v"), "")
+	if err != nil {
+		return true
+	}
+	minimum, err := version.New(min, "")
+	if err != nil {
+		return true
+	}
+	switch {
+	case current.Major != minimum.Major:
+		return current.Major > minimum.Major
+	case current.Minor != minimum.Minor:
+		return current.Minor > minimum.Minor
+	}
+	return current.Patch >= minimum.Patch
+}
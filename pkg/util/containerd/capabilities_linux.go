@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+func init() {
+	isCgroupV2Host = func() bool {
+		procRoot := agentConfig.Options().ProcRoot
+		if procRoot == "" {
+			procRoot = DefaultProcRoot
+		}
+		f, err := os.Open(filepath.Join(procRoot, "mounts"))
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		// containerd places the tasks in the v1 hierarchy on hybrid hosts
+		v, err := types.ParseCgroupVersion(f)
+		return err == nil && v == types.CgroupV2
+	}
+}
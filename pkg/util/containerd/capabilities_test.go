@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilitiesFromDump(t *testing.T) {
+	for name, tc := range map[string]struct {
+		dump     *ConfigDump
+		expected Capabilities
+	}{
+		"containerd 1.2": {
+			dump: &ConfigDump{
+				Version: "v1.2.13",
+				Plugins: []PluginInfo{
+					{Type: "io.containerd.runtime.v2", ID: "task"},
+					{Type: "io.containerd.grpc.v1", ID: "cri"},
+				},
+			},
+			expected: Capabilities{Version: "v1.2.13"},
+		},
+		"containerd 1.6": {
+			dump: &ConfigDump{
+				Version: "1.6.21",
+				Plugins: []PluginInfo{
+					{Type: "io.containerd.runtime.v2", ID: "task"},
+					{Type: "io.containerd.grpc.v1", ID: "introspection"},
+				},
+			},
+			expected: Capabilities{Version: "1.6.21", TaskMetricsV2: true},
+		},
+		"containerd 1.7": {
+			dump: &ConfigDump{
+				Version: "v1.7.2",
+				Plugins: []PluginInfo{
+					{Type: "io.containerd.runtime.v2", ID: "task"},
+					{Type: "io.containerd.grpc.v1", ID: "sandbox-controllers"},
+					{Type: "io.containerd.grpc.v1", ID: "transfer"},
+				},
+			},
+			expected: Capabilities{Version: "v1.7.2", TaskMetricsV2: true, SandboxAPI: true, TransferService: true},
+		},
+		"failed plugins": {
+			dump: &ConfigDump{
+				Version: "v1.7.2",
+				Plugins: []PluginInfo{
+					{Type: "io.containerd.runtime.v2", ID: "task", InitError: "no shim"},
+					{Type: "io.containerd.grpc.v1", ID: "transfer", InitError: "disabled"},
+				},
+			},
+			expected: Capabilities{Version: "v1.7.2"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, *capabilitiesFromDump(tc.dump))
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("v1.4.0", "1.4.0"))
+	assert.True(t, versionAtLeast("1.10.1", "1.4.0"))
+	assert.True(t, versionAtLeast("2.0.0", "1.4.0"))
+	assert.True(t, versionAtLeast("devel", "1.4.0"))
+	assert.False(t, versionAtLeast("v1.3.9", "1.4.0"))
+	assert.False(t, versionAtLeast("0.2.0", "1.4.0"))
+}
+
+func TestTaskMetricsGate(t *testing.T) {
+	defer func(f func() bool) { isCgroupV2Host = f }(isCgroupV2Host)
+	isCgroupV2Host = func() bool { return true }
+
+	c := newContainerdUtil(Options{}.withDefaults())
+	c.caps = &Capabilities{Version: "v1.2.13"}
+	_, err := c.TaskMetrics(nil)
+	assert.Equal(t, ErrUnsupported, ErrorKind(err))
+	assert.Contains(t, err.Error(), "1.4.0 or later is required")
+	assert.False(t, IsTransient(err))
+}
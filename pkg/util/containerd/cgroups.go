@@ -0,0 +1,236 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroupIndexRefreshInterval is the minimum time between two listings of the
+// containers, to avoid querying containerd for every unknown cgroup
+const cgroupIndexRefreshInterval = 10 * time.Second
+
+// cgroupInode returns the inode of a cgroup directory, it is set on linux
+var cgroupInode = func(dir string) (uint64, error) {
+	return 0, &Error{Kind: ErrUnsupported, Err: fmt.Errorf("the cgroups are only available on linux")}
+}
+
+// ContainerCgroup is the cgroup of a container, for the system-probe to map
+// the events of its eBPF programs to the containers
+type ContainerCgroup struct {
+	ContainerID string `json:"container_id"`
+	// Path is the path of the cgroup under the root of the hierarchy
+	Path string `json:"path"`
+	// Inode is the inode of the cgroup directory, which is the cgroup ID
+	// returned by bpf_get_current_cgroup_id on cgroup v2 hosts. It is 0 on
+	// cgroup v1 hosts.
+	Inode uint64 `json:"inode,omitempty"`
+}
+
+// CgroupIndex maps the cgroup paths set in the OCI spec of the containers to
+// their container ID. It allows matching the processes of containers whose
+// ID can't be parsed from their cgroups, like the ones started with ctr.
+type CgroupIndex struct {
+	util ContainerdItf
+
+	sync.Mutex
+	ids         map[string]string
+	lastRefresh time.Time
+
+	// paths are the cgroup paths of the containers, by container ID
+	paths map[string]string
+}
+
+// NewCgroupIndex returns a CgroupIndex backed by util
+func NewCgroupIndex(util ContainerdItf) *CgroupIndex {
+	return &CgroupIndex{
+		util:  util,
+		ids:   make(map[string]string),
+		paths: make(map[string]string),
+	}
+}
+
+// sharedCgroupIndex returns the index of the containers of the namespace of
+// the configuration, see Provider.CgroupIndex
+func sharedCgroupIndex() (*CgroupIndex, error) {
+	return globalProvider.CgroupIndex(nil)
+}
+
+// ContainerIDForCgroup returns the ID of the container matching the cgroup path
+// of one of the controllers of a process, using the shared index.
+func ContainerIDForCgroup(cgroupPath string) (string, error) {
+	idx, err := sharedCgroupIndex()
+	if err != nil {
+		return "", err
+	}
+	return idx.ContainerID(cgroupPath), nil
+}
+
+// CgroupPath returns the path of the cgroup of a container under the root
+// of the cgroup hierarchy, resolved from its spec, using the shared index.
+// An empty path is returned for unknown containers.
+func CgroupPath(containerID string) (string, error) {
+	idx, err := sharedCgroupIndex()
+	if err != nil {
+		return "", err
+	}
+	return idx.CgroupPath(containerID), nil
+}
+
+// ContainerCgroups returns the cgroups of the containers of the shared
+// index. The inodes are read under cgroupRoot on cgroup v2 hosts.
+func ContainerCgroups(cgroupRoot string) ([]ContainerCgroup, error) {
+	idx, err := sharedCgroupIndex()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Cgroups(cgroupRoot), nil
+}
+
+// ContainerID returns the ID of the container matching a cgroup path,
+// or an empty string if none matches. Containers created since the last
+// listing are taken into account after cgroupIndexRefreshInterval.
+func (idx *CgroupIndex) ContainerID(cgroupPath string) string {
+	idx.Lock()
+	defer idx.Unlock()
+
+	if id := idx.lookup(cgroupPath); id != "" {
+		return id
+	}
+	if time.Since(idx.lastRefresh) < cgroupIndexRefreshInterval {
+		return ""
+	}
+	idx.refresh()
+	return idx.lookup(cgroupPath)
+}
+
+// CgroupPath returns the cgroup path of a container, or an empty string if
+// it is unknown. Containers created since the last listing are taken into
+// account after cgroupIndexRefreshInterval.
+func (idx *CgroupIndex) CgroupPath(containerID string) string {
+	idx.Lock()
+	defer idx.Unlock()
+
+	if p, found := idx.paths[containerID]; found {
+		return p
+	}
+	if time.Since(idx.lastRefresh) < cgroupIndexRefreshInterval {
+		return ""
+	}
+	idx.refresh()
+	return idx.paths[containerID]
+}
+
+// Cgroups returns the cgroups of the indexed containers, after a refresh if
+// the last listing is older than cgroupIndexRefreshInterval. The inodes are
+// read under cgroupRoot on cgroup v2 hosts only, the cgroup IDs of the v1
+// hierarchies are not the inodes of their directories.
+func (idx *CgroupIndex) Cgroups(cgroupRoot string) []ContainerCgroup {
+	idx.Lock()
+	defer idx.Unlock()
+
+	if time.Since(idx.lastRefresh) >= cgroupIndexRefreshInterval {
+		idx.refresh()
+	}
+	v2 := isCgroupV2Host()
+	cgroups := make([]ContainerCgroup, 0, len(idx.paths))
+	for id, p := range idx.paths {
+		cg := ContainerCgroup{ContainerID: id, Path: p}
+		if v2 {
+			// the cgroup is gone with the task of the container
+			cg.Inode, _ = cgroupInode(filepath.Join(cgroupRoot, p))
+		}
+		cgroups = append(cgroups, cg)
+	}
+	sort.Slice(cgroups, func(i, j int) bool { return cgroups[i].ContainerID < cgroups[j].ContainerID })
+	return cgroups
+}
+
+// lookup matches the full path for cgroupfs paths, and the scope
+// name for systemd ones
+func (idx *CgroupIndex) lookup(cgroupPath string) string {
+	if id, found := idx.ids[cgroupPath]; found {
+		return id
+	}
+	return idx.ids[path.Base(cgroupPath)]
+}
+
+// refresh rebuilds the index from the spec of the containers
+func (idx *CgroupIndex) refresh() {
+	idx.lastRefresh = time.Now()
+
+	ctns, err := idx.util.Containers()
+	if err != nil {
+		logFor(idx.util).Debugf("Cannot list containers to index their cgroups: %s", err)
+		return
+	}
+	ids := make(map[string]string, len(ctns))
+	paths := make(map[string]string, len(ctns))
+	for _, ctn := range ctns {
+		spec, err := idx.util.Spec(ctn)
+		if err != nil || spec.Linux == nil || spec.Linux.CgroupsPath == "" {
+			continue
+		}
+		ids[cgroupKey(spec.Linux.CgroupsPath)] = ctn.ID()
+		paths[ctn.ID()] = resolveCgroupPath(spec.Linux.CgroupsPath)
+	}
+	idx.ids = ids
+	idx.paths = paths
+}
+
+// cgroupKey returns the index key of an OCI cgroups path. With the systemd
+// cgroup driver, the path is formatted as slice:prefix:name, and the container
+// processes are placed in the prefix-name.scope unit.
+func cgroupKey(cgroupsPath string) string {
+	parts := strings.Split(cgroupsPath, ":")
+	if len(parts) == 3 && !strings.HasPrefix(cgroupsPath, "/") {
+		return parts[1] + "-" + parts[2] + ".scope"
+	}
+	return cgroupsPath
+}
+
+// resolveCgroupPath returns the path of the cgroup of an OCI cgroups path
+// under the root of the hierarchy. With the systemd cgroup driver, the
+// slice:prefix:name path is the prefix-name.scope unit in the slice, whose
+// dash-separated parents are expanded: kubepods-besteffort.slice is
+// /kubepods.slice/kubepods-besteffort.slice. The cgroupfs paths are kept,
+// the relative ones are relative to the cgroup of the runtime.
+func resolveCgroupPath(cgroupsPath string) string {
+	parts := strings.Split(cgroupsPath, ":")
+	if len(parts) != 3 || strings.HasPrefix(cgroupsPath, "/") {
+		return cgroupsPath
+	}
+	slice := parts[0]
+	if slice == "" {
+		slice = "system.slice"
+	}
+	return path.Join("/", expandSlice(slice), cgroupKey(cgroupsPath))
+}
+
+// expandSlice returns the path of a systemd slice, with its parents
+func expandSlice(slice string) string {
+	name := strings.TrimSuffix(slice, ".slice")
+	if name == "-" || name == "" {
+		return ""
+	}
+	var p, prefix string
+	for _, part := range strings.Split(name, "-") {
+		if prefix != "" {
+			prefix += "-"
+		}
+		prefix += part
+		p = path.Join(p, prefix+".slice")
+	}
+	return p
+}
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func init() {
+	cgroupInode = func(dir string) (uint64, error) {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return 0, err
+		}
+		stat, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return 0, fmt.Errorf("cannot read the inode of %s", dir)
+		}
+		return stat.Ino, nil
+	}
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupKey(t *testing.T) {
+	assert.Equal(t, "/default/redis", cgroupKey("/default/redis"))
+	assert.Equal(t, "cri-containerd-foo.scope", cgroupKey("kubepods-besteffort-pod1234.slice:cri-containerd:foo"))
+}
+
+func TestCgroupIndex(t *testing.T) {
+	cgroupsPaths := map[string]string{
+		"redis": "/default/redis",
+		"nginx": "system.slice:cri-containerd:nginx",
+	}
+	listings := 0
+	itf := &mockItf{
+		mockContainers: func() ([]Container, error) {
+			listings++
+			var ctns []Container
+			for id := range cgroupsPaths {
+				ctns = append(ctns, newMockContainers(id)...)
+			}
+			return ctns, nil
+		},
+		mockSpec: func(ctn containerd.Container) (*oci.Spec, error) {
+			return &oci.Spec{Linux: &specs.Linux{CgroupsPath: cgroupsPaths[ctn.ID()]}}, nil
+		},
+	}
+	idx := NewCgroupIndex(itf)
+
+	assert.Equal(t, "redis", idx.ContainerID("/default/redis"))
+	assert.Equal(t, "nginx", idx.ContainerID("/system.slice/cri-containerd-nginx.scope"))
+	assert.Equal(t, 1, listings)
+
+	// Unknown cgroups don't trigger a listing before the refresh interval
+	cgroupsPaths["mysql"] = "/default/mysql"
+	assert.Equal(t, "", idx.ContainerID("/default/mysql"))
+	assert.Equal(t, 1, listings)
+
+	idx.lastRefresh = time.Now().Add(-cgroupIndexRefreshInterval)
+	assert.Equal(t, "mysql", idx.ContainerID("/default/mysql"))
+	assert.Equal(t, 2, listings)
+}
+
+func TestResolveCgroupPath(t *testing.T) {
+	assert.Equal(t, "/default/redis", resolveCgroupPath("/default/redis"))
+	assert.Equal(t, "/system.slice/cri-containerd-nginx.scope", resolveCgroupPath("system.slice:cri-containerd:nginx"))
+	assert.Equal(t, "/system.slice/cri-containerd-nginx.scope", resolveCgroupPath(":cri-containerd:nginx"))
+	assert.Equal(t, "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1234.slice/cri-containerd-foo.scope",
+		resolveCgroupPath("kubepods-besteffort-pod1234.slice:cri-containerd:foo"))
+	assert.Equal(t, "/cri-containerd-foo.scope", resolveCgroupPath("-.slice:cri-containerd:foo"))
+}
+
+func TestCgroupIndexPaths(t *testing.T) {
+	itf := &mockItf{
+		mockContainers: func() ([]Container, error) {
+			return append(newMockContainers("redis"), newMockContainers("nginx")...), nil
+		},
+		mockSpec: func(ctn containerd.Container) (*oci.Spec, error) {
+			if ctn.ID() == "redis" {
+				return &oci.Spec{Linux: &specs.Linux{CgroupsPath: "/default/redis"}}, nil
+			}
+			return &oci.Spec{Linux: &specs.Linux{CgroupsPath: "system.slice:cri-containerd:nginx"}}, nil
+		},
+	}
+	idx := NewCgroupIndex(itf)
+
+	assert.Equal(t, "/system.slice/cri-containerd-nginx.scope", idx.CgroupPath("nginx"))
+	assert.Equal(t, "", idx.CgroupPath("unknown"))
+
+	defer func(f func() bool) { isCgroupV2Host = f }(isCgroupV2Host)
+	isCgroupV2Host = func() bool { return false }
+	assert.Equal(t, []ContainerCgroup{
+		{ContainerID: "nginx", Path: "/system.slice/cri-containerd-nginx.scope"},
+		{ContainerID: "redis", Path: "/default/redis"},
+	}, idx.Cgroups("/sys/fs/cgroup"))
+
+	// The inodes are the cgroup IDs on v2 hosts, the cgroup of nginx is gone
+	root, err := ioutil.TempDir("", "cgroups")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "default", "redis"), 0755))
+	isCgroupV2Host = func() bool { return true }
+	cgroups := idx.Cgroups(root)
+	require.Len(t, cgroups, 2)
+	assert.Zero(t, cgroups[0].Inode)
+	if runtime.GOOS == "linux" {
+		assert.NotZero(t, cgroups[1].Inode)
+	}
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// classifyError wraps the errors of the containerd client in an Error if
+// their kind is known, other errors are returned as is
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	kind := errorKindOf(err)
+	if kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// errorKindOf returns the kind of an error of the containerd client. The
+// client converts most of the gRPC errors to errdefs ones, the dial errors
+// are only known by their message.
+func errorKindOf(err error) error {
+	switch {
+	case errdefs.IsDeadlineExceeded(err):
+		return ErrTimeout
+	case errdefs.IsUnavailable(err):
+		return ErrNotServing
+	case errdefs.IsPermissionDenied(err), os.IsPermission(err):
+		return ErrPermissionDenied
+	case errdefs.IsNotImplemented(err):
+		return ErrUnsupported
+	case errdefs.IsNotFound(err) && strings.HasPrefix(err.Error(), "namespace "):
+		return ErrNamespaceNotFound
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return ErrTimeout
+	case codes.Unavailable:
+		return ErrNotServing
+	case codes.PermissionDenied:
+		return ErrPermissionDenied
+	case codes.Unimplemented:
+		return ErrUnsupported
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "permission denied"):
+		return ErrPermissionDenied
+	case strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such file or directory"):
+		return ErrNotServing
+	}
+	return nil
+}
+
+// CheckNamespace returns an ErrNamespaceNotFound error if the namespace the
+// util is bound to does not exist on the daemon. Queries in a missing
+// namespace succeed with empty results, so a typo in the namespace setting
+// is not detected otherwise.
+func CheckNamespace(cu ContainerdItf) error {
+	namespaces, err := cu.Namespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if ns == cu.Namespace() {
+			return nil
+		}
+	}
+	return &Error{
+		Kind: ErrNamespaceNotFound,
+		Err:  fmt.Errorf("namespace %q not found, existing namespaces: %s", cu.Namespace(), strings.Join(namespaces, ", ")),
+	}
+}
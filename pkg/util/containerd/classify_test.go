@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+func TestClassifyError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		kind error
+	}{
+		"deadline":          {err: context.DeadlineExceeded, kind: ErrTimeout},
+		"unavailable":       {err: fmt.Errorf("connection closed: %w", errdefs.ErrUnavailable), kind: ErrNotServing},
+		"grpc unavailable":  {err: status.Error(codes.Unavailable, "transport is closing"), kind: ErrNotServing},
+		"permission":        {err: fmt.Errorf("dial unix /run/containerd/containerd.sock: connect: permission denied"), kind: ErrPermissionDenied},
+		"refused":           {err: fmt.Errorf("dial unix /run/containerd/containerd.sock: connect: connection refused"), kind: ErrNotServing},
+		"missing namespace": {err: fmt.Errorf("namespace %q: %w", "k8s", errdefs.ErrNotFound), kind: ErrNamespaceNotFound},
+		"unimplemented":     {err: status.Error(codes.Unimplemented, "unknown service containerd.services.transfer.v1.Transfer"), kind: ErrUnsupported},
+		"not implemented":   {err: fmt.Errorf("sandbox: %w", errdefs.ErrNotImplemented), kind: ErrUnsupported},
+		"missing container": {err: fmt.Errorf("container %q in namespace %q: %w", "foo", "k8s.io", errdefs.ErrNotFound)},
+		"other":             {err: errors.New("invalid image reference")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := classifyError(tc.err)
+			assert.Equal(t, tc.kind, ErrorKind(err))
+			assert.EqualError(t, err, tc.err.Error())
+		})
+	}
+	assert.NoError(t, classifyError(nil))
+}
+
+func TestCheckNamespace(t *testing.T) {
+	cu := &mockItf{
+		mockNamespace: func() string { return "k8s.io" },
+		mockNamespaces: func() ([]string, error) {
+			return []string{"default", "moby"}, nil
+		},
+	}
+	err := CheckNamespace(cu)
+	assert.Equal(t, ErrNamespaceNotFound, ErrorKind(err))
+	assert.EqualError(t, err, `namespace "k8s.io" not found, existing namespaces: default, moby`)
+
+	cu.mockNamespace = func() string { return "moby" }
+	assert.NoError(t, CheckNamespace(cu))
+
+	cu.mockNamespaces = func() ([]string, error) {
+		return nil, &Error{Kind: ErrTimeout, Err: context.DeadlineExceeded}
+	}
+	assert.Equal(t, ErrTimeout, ErrorKind(CheckNamespace(cu)))
+}
+
+func TestEnsureConnectedErrorKind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newContainerdUtil(Options{
+		SocketPath:        filepath.Join(dir, "containerd.sock"),
+		ConnectionTimeout: 100 * time.Millisecond,
+	}.withDefaults())
+	defer c.Close()
+
+	err = c.EnsureConnected()
+	assert.True(t, retry.IsErrWillRetry(err))
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+
+	// The kind of the last attempt is kept until the next one
+	err = c.EnsureConnected()
+	assert.Contains(t, err.Error(), "try delay not elapsed yet")
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+}
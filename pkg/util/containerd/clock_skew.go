@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+)
+
+// ClockSkewTracker estimates the skew between the clock of the agent and
+// the one of containerd from the events received live, as the uptimes and
+// the churn are computed from the timestamps set by containerd. The skew
+// of an event is the time it was received minus its timestamp, it includes
+// the delivery delay, so the smallest skew observed is the estimate.
+type ClockSkewTracker struct {
+	mu      sync.Mutex
+	skew    time.Duration
+	samples int
+}
+
+// NewClockSkewTracker returns an empty ClockSkewTracker
+func NewClockSkewTracker() *ClockSkewTracker {
+	return &ClockSkewTracker{}
+}
+
+// Observe records the skew of an event with timestamp received at
+// received. The replayed events must not be observed.
+func (t *ClockSkewTracker) Observe(timestamp, received time.Time) {
+	if timestamp.IsZero() {
+		return
+	}
+	skew := received.Sub(timestamp)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.samples == 0 || skew < t.skew {
+		t.skew = skew
+	}
+	t.samples++
+}
+
+// Flush returns the skew estimated from the events observed since the last
+// flush, positive if the clock of containerd is behind the one of the
+// agent, and false if no event was observed
+func (t *ClockSkewTracker) Flush() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	skew, observed := t.skew, t.samples > 0
+	t.skew, t.samples = 0, 0
+	return skew, observed
+}
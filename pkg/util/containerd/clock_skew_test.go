@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockSkewTracker(t *testing.T) {
+	tracker := NewClockSkewTracker()
+	_, observed := tracker.Flush()
+	assert.False(t, observed)
+
+	// The delivery delay is not counted as skew
+	now := time.Now()
+	tracker.Observe(now.Add(-12*time.Second), now)
+	tracker.Observe(now.Add(-10*time.Second), now.Add(50*time.Millisecond))
+	tracker.Observe(time.Time{}, now)
+	skew, observed := tracker.Flush()
+	assert.True(t, observed)
+	assert.Equal(t, 10*time.Second+50*time.Millisecond, skew)
+
+	// The clock of containerd is ahead
+	tracker.Observe(now.Add(3*time.Second), now)
+	skew, observed = tracker.Flush()
+	assert.True(t, observed)
+	assert.Equal(t, -3*time.Second, skew)
+	_, observed = tracker.Flush()
+	assert.False(t, observed)
+}
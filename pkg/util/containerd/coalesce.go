@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+)
+
+// Keys of the calls coalesced by ContainerdUtil
+const (
+	containersCall = "containers"
+	imagesCall     = "images"
+	namespacesCall = "namespaces"
+	versionCall    = "version"
+)
+
+// callGroup coalesces the concurrent identical calls to the daemon: the
+// callers arriving while a call is in flight wait for it and share its
+// result, instead of sending the same RPC again. The checks, the tagger and
+// the process agent often list the containers within the same second. The
+// results are not cached, a call arriving after the in-flight one returned
+// sends a new RPC.
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// do runs fn unless a call of key is in flight, in which case its result
+// is returned once it completes. The result is shared by the callers, it
+// must be copied before being modified.
+func (g *callGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if call, found := g.calls[key]; found {
+		g.mu.Unlock()
+		<-call.done
+		return call.val, call.err
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err
+}
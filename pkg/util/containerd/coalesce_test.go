@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallGroupCoalesces(t *testing.T) {
+	var g callGroup
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []string{"k8s.io"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val, err := g.do(namespacesCall, fn)
+			assert.NoError(t, err)
+			results[i] = val
+		}(i)
+	}
+	// Let the callers join the in-flight call
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, val := range results {
+		assert.Equal(t, []string{"k8s.io"}, val)
+	}
+
+	// The results are not cached
+	_, err := g.do(namespacesCall, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCallGroupKeys(t *testing.T) {
+	var g callGroup
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		g.do(containersCall, func() (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		close(done)
+	}()
+
+	// Another call is not blocked by the in-flight one, and its error is returned
+	_, err := g.do(imagesCall, func() (interface{}, error) {
+		return nil, errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "unavailable")
+	close(release)
+	<-done
+}
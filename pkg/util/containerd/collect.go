@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// ContainerTaskMetrics holds the task metrics of a container, or the
+// error preventing their collection
+type ContainerTaskMetrics struct {
+	ContainerID string
+	// Pid is the pid of the init process of the task
+	Pid     uint32
+	Metrics *types.Metric
+	Err     error
+}
+
+// CollectAll returns the task metrics of the running containers of the
+// namespace. The tasks are listed once, then their metrics are queried by
+// at most MaxConcurrentQueries workers, each query being bounded by the
+// query timeout. A failed query only fails the result of its container,
+// the results of the containers not queried before ctx is done carry the
+// error of ctx. An error is returned if the tasks cannot be listed.
+func (c *ContainerdUtil) CollectAll(ctx context.Context) ([]ContainerTaskMetrics, error) {
+	if err := c.checkTaskMetricsSupport(); err != nil {
+		return nil, err
+	}
+
+	ctx = namespaces.WithNamespace(ctx, c.namespace)
+	listCtx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+	resp, err := c.tasks().List(listCtx, &tasks.ListTasksRequest{})
+	cancel()
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	results := make([]ContainerTaskMetrics, len(resp.Tasks))
+	for i, t := range resp.Tasks {
+		results[i].ContainerID = t.ID
+		results[i].Pid = t.Pid
+	}
+
+	workers := c.maxConcurrentQueries
+	if workers > len(results) {
+		workers = len(results)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i].Metrics, results[i].Err = c.taskMetrics(ctx, results[i].ContainerID)
+			}
+		}()
+	}
+
+	queued := 0
+feed:
+	for ; queued < len(results); queued++ {
+		select {
+		case jobs <- queued:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := queued; i < len(results); i++ {
+		results[i].Err = classifyError(ctx.Err())
+	}
+	return results, nil
+}
+
+// taskMetrics queries the metrics of a single task
+func (c *ContainerdUtil) taskMetrics(ctx context.Context, id string) (*types.Metric, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+	defer cancel()
+	resp, err := c.tasks().Metrics(ctx, &tasks.MetricsRequest{Filters: []string{"id==" + id}})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	if len(resp.Metrics) == 0 {
+		return nil, fmt.Errorf("task %s exited since it was listed", id)
+	}
+	return resp.Metrics[0], nil
+}
+
+// tasks returns the task service of the client
+func (c *ContainerdUtil) tasks() tasks.TasksClient {
+	if c.taskService != nil {
+		return c.taskService
+	}
+	return c.client().TaskService()
+}
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTasks struct {
+	tasks.TasksClient
+	ids   []string
+	delay time.Duration
+
+	sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (f *fakeTasks) List(ctx context.Context, in *tasks.ListTasksRequest, opts ...grpc.CallOption) (*tasks.ListTasksResponse, error) {
+	resp := &tasks.ListTasksResponse{}
+	for _, id := range f.ids {
+		resp.Tasks = append(resp.Tasks, &task.Process{ID: id})
+	}
+	return resp, nil
+}
+
+func (f *fakeTasks) Metrics(ctx context.Context, in *tasks.MetricsRequest, opts ...grpc.CallOption) (*tasks.MetricsResponse, error) {
+	f.Lock()
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.Unlock()
+	defer func() {
+		f.Lock()
+		f.running--
+		f.Unlock()
+	}()
+
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	id := strings.TrimPrefix(in.Filters[0], "id==")
+	switch {
+	case strings.HasPrefix(id, "exited"):
+		return &tasks.MetricsResponse{}, nil
+	case strings.HasPrefix(id, "broken"):
+		return nil, status.Error(codes.Unavailable, "shim is not responding")
+	}
+	return &tasks.MetricsResponse{Metrics: []*types.Metric{{ID: id}}}, nil
+}
+
+func TestCollectAll(t *testing.T) {
+	fake := &fakeTasks{delay: 5 * time.Millisecond}
+	for i := 0; i < 50; i++ {
+		fake.ids = append(fake.ids, fmt.Sprintf("ctn-%d", i))
+	}
+	fake.ids = append(fake.ids, "exited-1", "broken-1")
+
+	c := newContainerdUtil(Options{MaxConcurrentQueries: 4}.withDefaults())
+	c.taskService = fake
+
+	results, err := c.CollectAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 52)
+	assert.True(t, fake.maxRunning <= 4, "%d concurrent queries", fake.maxRunning)
+
+	for _, r := range results[:50] {
+		assert.NoError(t, r.Err)
+		assert.Equal(t, r.ContainerID, r.Metrics.ID)
+	}
+	assert.Equal(t, "exited-1", results[50].ContainerID)
+	assert.Error(t, results[50].Err)
+	assert.Equal(t, "broken-1", results[51].ContainerID)
+	assert.Equal(t, ErrNotServing, ErrorKind(results[51].Err))
+}
+
+func TestCollectAllCancel(t *testing.T) {
+	fake := &fakeTasks{delay: time.Second}
+	for i := 0; i < 10; i++ {
+		fake.ids = append(fake.ids, fmt.Sprintf("ctn-%d", i))
+	}
+
+	c := newContainerdUtil(Options{MaxConcurrentQueries: 2}.withDefaults())
+	c.taskService = fake
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := c.CollectAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 10)
+	for _, r := range results {
+		assert.Equal(t, ErrTimeout, ErrorKind(r.Err), r.ContainerID)
+	}
+}
@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/pelletier/go-toml"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+// Compliance rules evaluated on the containerd resources, modeled
+// after the docker-bench rules for the docker daemon.
+const (
+	RuleSocketOwnership    = "containerd-socket-ownership"
+	RuleSocketPermissions  = "containerd-socket-permissions"
+	RuleConfigOwnership    = "containerd-config-ownership"
+	RuleConfigPermissions  = "containerd-config-permissions"
+	RuleGRPCTCPDisabled    = "containerd-grpc-tcp-disabled"
+	RuleStreamServerLocal  = "containerd-cri-stream-server-local"
+	RulePluginsInitialized = "containerd-plugins-initialized"
+	RuleSeccompConfined    = "containerd-container-seccomp-confined"
+	RuleAppArmorConfined   = "containerd-container-apparmor-confined"
+	RuleSELinuxConfined    = "containerd-container-selinux-confined"
+)
+
+const (
+	maxSocketPermissions    = 0660
+	maxConfigPermissions    = 0644
+	grpcTCPAddressKey       = "grpc.tcp_address"
+	defaultStreamServerAddr = "127.0.0.1"
+	apparmorUnconfined      = "unconfined"
+)
+
+// The CRI plugin section was renamed in config version 2, the paths are
+// split explicitly as the plugin name contains dots
+var (
+	criStreamServerPath   = []string{"plugins", "cri", "stream_server_address"}
+	criStreamServerPathV2 = []string{"plugins", "io.containerd.grpc.v1.cri", "stream_server_address"}
+)
+
+// unconfinedSELinuxTypes are the SELinux types not confining the processes
+// of a container
+var unconfinedSELinuxTypes = map[string]bool{
+	"spc_t":        true,
+	"unconfined_t": true,
+}
+
+// ComplianceResult is the outcome of a compliance rule on a containerd resource
+type ComplianceResult struct {
+	Rule     string
+	Resource string
+	Passed   bool
+	Details  string
+}
+
+// CheckSocketCompliance checks that the containerd socket is owned by root
+// and is not accessible by other users.
+func CheckSocketCompliance(path string) ([]ComplianceResult, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("%s is not a unix socket", path)
+	}
+	return []ComplianceResult{
+		checkRootOwnership(RuleSocketOwnership, path, fi),
+		checkPermissions(RuleSocketPermissions, path, fi, maxSocketPermissions),
+	}, nil
+}
+
+// CheckConfigCompliance checks the ownership and permissions of the containerd
+// configuration file, then the settings exposing the daemon over the network.
+func CheckConfigCompliance(path string) ([]ComplianceResult, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	results := []ComplianceResult{
+		checkRootOwnership(RuleConfigOwnership, path, fi),
+		checkPermissions(RuleConfigPermissions, path, fi, maxConfigPermissions),
+	}
+
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return results, fmt.Errorf("cannot parse %s: %s", path, err)
+	}
+	return append(results, checkConfigSettings(path, tree)...), nil
+}
+
+// CheckPluginsCompliance checks that every plugin of the daemon was
+// initialized. The plugins the daemon skipped as not applicable to the host,
+// like the snapshotters of the filesystems it does not use, are not checked.
+func CheckPluginsCompliance(dump *ConfigDump) []ComplianceResult {
+	var results []ComplianceResult
+	for _, p := range dump.Plugins {
+		if p.Skipped() {
+			continue
+		}
+		result := ComplianceResult{
+			Rule:     RulePluginsInitialized,
+			Resource: p.Type + "." + p.ID,
+			Passed:   p.InitError == "",
+		}
+		if !result.Passed {
+			result.Details = p.InitError
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// CheckContainersCompliance checks that the containers of the namespace of
+// cu are confined by seccomp and AppArmor, and not run with an unconfined
+// SELinux type. The containers without a SELinux label are not flagged, as
+// most hosts do not enable SELinux. The resources are the entities of the
+// containers.
+func CheckContainersCompliance(cu ContainerdItf) ([]ComplianceResult, error) {
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		return nil, err
+	}
+	var results []ComplianceResult
+	for _, ctn := range ctns {
+		if ctn.Security == nil {
+			continue
+		}
+		results = append(results, CheckSecurityProfileCompliance(EntityID(ctn.ID), *ctn.Security)...)
+	}
+	return results, nil
+}
+
+// CheckSecurityProfileCompliance checks the security profile of a container
+func CheckSecurityProfileCompliance(resource string, profile types.SecurityProfile) []ComplianceResult {
+	seccomp := ComplianceResult{
+		Rule:     RuleSeccompConfined,
+		Resource: resource,
+		Passed:   profile.SeccompMode != types.SeccompModeUnconfined,
+	}
+	if !seccomp.Passed {
+		seccomp.Details = "the container runs without a seccomp filter"
+	}
+
+	apparmor := ComplianceResult{
+		Rule:     RuleAppArmorConfined,
+		Resource: resource,
+		Passed:   profile.AppArmorProfile != "" && profile.AppArmorProfile != apparmorUnconfined,
+	}
+	if !apparmor.Passed {
+		apparmor.Details = "the container runs without an AppArmor profile"
+	}
+
+	results := []ComplianceResult{seccomp, apparmor}
+	if profile.SELinuxLabel != "" {
+		// The labels are user:role:type:level
+		var selinuxType string
+		if parts := strings.SplitN(profile.SELinuxLabel, ":", 4); len(parts) > 2 {
+			selinuxType = parts[2]
+		}
+		selinux := ComplianceResult{
+			Rule:     RuleSELinuxConfined,
+			Resource: resource,
+			Passed:   !unconfinedSELinuxTypes[selinuxType],
+		}
+		if !selinux.Passed {
+			selinux.Details = fmt.Sprintf("the container runs with the unconfined SELinux type %s", selinuxType)
+		}
+		results = append(results, selinux)
+	}
+	return results
+}
+
+func checkConfigSettings(path string, tree *toml.Tree) []ComplianceResult {
+	tcpAddress, _ := tree.Get(grpcTCPAddressKey).(string)
+	tcp := ComplianceResult{
+		Rule:     RuleGRPCTCPDisabled,
+		Resource: path,
+		Passed:   tcpAddress == "",
+	}
+	if !tcp.Passed {
+		tcp.Details = fmt.Sprintf("the GRPC API is exposed on %s", tcpAddress)
+	}
+
+	streamAddress, found := tree.GetPath(criStreamServerPath).(string)
+	if !found {
+		streamAddress, found = tree.GetPath(criStreamServerPathV2).(string)
+	}
+	if !found {
+		streamAddress = defaultStreamServerAddr
+	}
+	ip := net.ParseIP(streamAddress)
+	stream := ComplianceResult{
+		Rule:     RuleStreamServerLocal,
+		Resource: path,
+		Passed:   ip != nil && ip.IsLoopback(),
+	}
+	if !stream.Passed {
+		stream.Details = fmt.Sprintf("the CRI stream server listens on %q", streamAddress)
+	}
+
+	return []ComplianceResult{tcp, stream}
+}
+
+func checkRootOwnership(rule, path string, fi os.FileInfo) ComplianceResult {
+	result := ComplianceResult{
+		Rule:     rule,
+		Resource: path,
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		result.Details = "cannot read file ownership"
+		return result
+	}
+	result.Passed = stat.Uid == 0
+	if !result.Passed {
+		result.Details = fmt.Sprintf("owned by uid %d", stat.Uid)
+	}
+	return result
+}
+
+func checkPermissions(rule, path string, fi os.FileInfo, max os.FileMode) ComplianceResult {
+	perm := fi.Mode().Perm()
+	result := ComplianceResult{
+		Rule:     rule,
+		Resource: path,
+		Passed:   perm&^max == 0,
+	}
+	if !result.Passed {
+		result.Details = fmt.Sprintf("permissions are %#o, expected %#o or more restrictive", perm, max)
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+func resultsByRule(results []ComplianceResult) map[string]ComplianceResult {
+	byRule := make(map[string]ComplianceResult)
+	for _, r := range results {
+		byRule[r.Rule] = r
+	}
+	return byRule
+}
+
+func writeConfig(t *testing.T, content string, perm os.FileMode) string {
+	dir, err := ioutil.TempDir("", "containerd-compliance")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "config.toml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), perm))
+	require.NoError(t, os.Chmod(path, perm))
+	return path
+}
+
+func TestCheckConfigComplianceDefault(t *testing.T) {
+	path := writeConfig(t, "root = \"/var/lib/containerd\"\n", 0600)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	results, err := CheckConfigCompliance(path)
+	require.NoError(t, err)
+	byRule := resultsByRule(results)
+	assert.True(t, byRule[RuleConfigPermissions].Passed)
+	assert.True(t, byRule[RuleGRPCTCPDisabled].Passed)
+	assert.True(t, byRule[RuleStreamServerLocal].Passed)
+}
+
+func TestCheckConfigComplianceExposed(t *testing.T) {
+	content := `
+[grpc]
+  tcp_address = "0.0.0.0:8080"
+
+[plugins.cri]
+  stream_server_address = "0.0.0.0"
+`
+	path := writeConfig(t, content, 0666)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	results, err := CheckConfigCompliance(path)
+	require.NoError(t, err)
+	byRule := resultsByRule(results)
+	assert.False(t, byRule[RuleConfigPermissions].Passed)
+	assert.Equal(t, "permissions are 0666, expected 0644 or more restrictive", byRule[RuleConfigPermissions].Details)
+	assert.False(t, byRule[RuleGRPCTCPDisabled].Passed)
+	assert.False(t, byRule[RuleStreamServerLocal].Passed)
+}
+
+func TestCheckConfigComplianceExposedV2(t *testing.T) {
+	content := `
+version = 2
+
+[plugins."io.containerd.grpc.v1.cri"]
+  stream_server_address = "0.0.0.0"
+`
+	path := writeConfig(t, content, 0600)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	results, err := CheckConfigCompliance(path)
+	require.NoError(t, err)
+	byRule := resultsByRule(results)
+	assert.True(t, byRule[RuleGRPCTCPDisabled].Passed)
+	assert.False(t, byRule[RuleStreamServerLocal].Passed)
+	assert.Equal(t, `the CRI stream server listens on "0.0.0.0"`, byRule[RuleStreamServerLocal].Details)
+}
+
+func TestCheckPluginsCompliance(t *testing.T) {
+	dump := &ConfigDump{
+		Plugins: []PluginInfo{
+			{Type: "io.containerd.grpc.v1", ID: "cri"},
+			{Type: "io.containerd.snapshotter.v1", ID: "btrfs", InitError: "path /var/lib/containerd/io.containerd.snapshotter.v1.btrfs must be a btrfs filesystem to be used with the btrfs snapshotter: skip plugin"},
+			{Type: "io.containerd.snapshotter.v1", ID: "devmapper", InitError: "devmapper not configured"},
+		},
+	}
+	results := CheckPluginsCompliance(dump)
+	// The skipped btrfs snapshotter is not applicable
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "io.containerd.snapshotter.v1.devmapper", results[1].Resource)
+	assert.Equal(t, "devmapper not configured", results[1].Details)
+}
+
+func TestCheckContainersCompliance(t *testing.T) {
+	cu := &mockItf{
+		mockCachedContainers: func() ([]CachedContainer, error) {
+			return []CachedContainer{
+				{ID: "redis", Security: &types.SecurityProfile{
+					SELinuxLabel:    "system_u:system_r:container_t:s0:c1,c2",
+					AppArmorProfile: "cri-containerd.apparmor.d",
+					SeccompMode:     types.SeccompModeFilter,
+				}},
+				{ID: "debug", Security: &types.SecurityProfile{
+					SELinuxLabel:    "system_u:system_r:spc_t:s0",
+					AppArmorProfile: "unconfined",
+					SeccompMode:     types.SeccompModeUnconfined,
+				}},
+				{ID: "nospec"},
+			}, nil
+		},
+	}
+
+	results, err := CheckContainersCompliance(cu)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+	for _, r := range results[:3] {
+		assert.Equal(t, "container_id://redis", r.Resource)
+		assert.True(t, r.Passed, r.Rule)
+	}
+	debug := resultsByRule(results[3:])
+	assert.False(t, debug[RuleSeccompConfined].Passed)
+	assert.False(t, debug[RuleAppArmorConfined].Passed)
+	assert.False(t, debug[RuleSELinuxConfined].Passed)
+	assert.Equal(t, "the container runs with the unconfined SELinux type spc_t", debug[RuleSELinuxConfined].Details)
+
+	// The SELinux label is only checked when set
+	results = CheckSecurityProfileCompliance("container_id://redis", types.SecurityProfile{SeccompMode: types.SeccompModeFilter})
+	assert.Len(t, results, 2)
+}
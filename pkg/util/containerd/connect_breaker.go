@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// connectCoolDown is the delay after a failed connection to a socket during
+// which the other utils of the socket do not dial it
+var connectCoolDown = 10 * time.Second
+
+var (
+	connectBreakers    = make(map[string]*connectBreaker)
+	connectBreakersMux sync.Mutex
+)
+
+// connectBreaker serializes the connection attempts of the utils of a
+// socket. When containerd restarts, the utils of every namespace and every
+// check reconnect at once: a single one dials, and the others fail fast
+// until its attempt is over, or until the cool-down following a failure
+// elapsed.
+type connectBreaker struct {
+	socketPath string
+
+	mu      sync.Mutex
+	dialing bool
+	// retryAt is the end of the attempt in flight, or of the cool-down
+	// following the last failure
+	retryAt time.Time
+	lastErr error
+}
+
+// connectBreakerFor returns the breaker shared by the utils of a socket
+func connectBreakerFor(socketPath string) *connectBreaker {
+	connectBreakersMux.Lock()
+	defer connectBreakersMux.Unlock()
+	b, found := connectBreakers[socketPath]
+	if !found {
+		b = &connectBreaker{socketPath: socketPath}
+		connectBreakers[socketPath] = b
+	}
+	return b
+}
+
+// enter reserves a connection attempt bounded by timeout, leave must be
+// called once it is over. It returns an ErrNotServing error, or the kind of
+// the last failure, telling when to retry if another attempt is in flight
+// or if the last one failed less than connectCoolDown ago.
+func (b *connectBreaker) enter(timeout time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if b.dialing {
+		return &Error{Kind: ErrNotServing, Err: fmt.Errorf("a connection to %s is in progress, retry at %s", b.socketPath, b.retryAt.Format(time.RFC3339))}
+	}
+	if b.lastErr != nil && now.Before(b.retryAt) {
+		kind := ErrorKind(b.lastErr)
+		if kind == nil {
+			kind = ErrNotServing
+		}
+		return &Error{Kind: kind, Err: fmt.Errorf("containerd is unavailable on %s, retry at %s: %s", b.socketPath, b.retryAt.Format(time.RFC3339), b.lastErr)}
+	}
+	b.dialing = true
+	b.retryAt = now.Add(timeout)
+	return nil
+}
+
+// record sets the outcome of the attempt in flight, a failure opens the
+// breaker for connectCoolDown
+func (b *connectBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastErr = err
+	if err != nil {
+		b.retryAt = time.Now().Add(connectCoolDown)
+	}
+}
+
+// leave ends the attempt reserved by enter
+func (b *connectBreaker) leave() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dialing = false
+}
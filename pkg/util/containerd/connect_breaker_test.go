@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+func TestConnectBreaker(t *testing.T) {
+	defer func(d time.Duration) { connectCoolDown = d }(connectCoolDown)
+	connectCoolDown = 50 * time.Millisecond
+	b := connectBreakerFor("/run/containerd/breaker.sock")
+	assert.True(t, b == connectBreakerFor("/run/containerd/breaker.sock"))
+
+	require.NoError(t, b.enter(time.Second))
+	// A single attempt at a time
+	err := b.enter(time.Second)
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+	assert.Contains(t, err.Error(), "is in progress, retry at")
+
+	// The breaker opens on failures
+	b.record(&Error{Kind: ErrPermissionDenied, Err: errors.New("permission denied")})
+	b.leave()
+	err = b.enter(time.Second)
+	assert.Equal(t, ErrPermissionDenied, ErrorKind(err))
+	assert.Contains(t, err.Error(), "containerd is unavailable on /run/containerd/breaker.sock, retry at")
+
+	// and closes after the cool-down
+	time.Sleep(connectCoolDown)
+	require.NoError(t, b.enter(time.Second))
+	b.record(nil)
+	b.leave()
+	require.NoError(t, b.enter(time.Second))
+	b.leave()
+}
+
+func TestEnsureConnectedBreaker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := Options{
+		SocketPath:        filepath.Join(dir, "containerd.sock"),
+		ConnectionTimeout: 100 * time.Millisecond,
+	}.withDefaults()
+	c := newContainerdUtil(opts)
+	defer c.Close()
+	other := newContainerdUtil(opts)
+	defer other.Close()
+
+	err = c.EnsureConnected()
+	assert.True(t, retry.IsErrWillRetry(err))
+
+	// The other utils of the socket do not dial during the cool-down,
+	// nor count it as an attempt
+	err = other.EnsureConnected()
+	assert.True(t, retry.IsErrWillRetry(err))
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+	assert.Contains(t, err.Error(), "retry at")
+	assert.Equal(t, retry.Idle, other.initRetry.RetryStatus())
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd"
+	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+)
+
+// clockTicks is the USER_HZ of the start times of procfs, 100 on every
+// architecture supported by the agent
+const clockTicks = 100
+
+// Container is a container listed by Containers, with the times it was
+// created and its task started, eg. to meter the container-hours
+type Container struct {
+	containerd.Container
+	createdAt time.Time
+	startedAt time.Time
+}
+
+// NewContainer returns a Container, startedAt is zero if the container has
+// no running task
+func NewContainer(ctn containerd.Container, createdAt, startedAt time.Time) Container {
+	return Container{Container: ctn, createdAt: createdAt, startedAt: startedAt}
+}
+
+// CreatedAt returns the creation time of the container
+func (c Container) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// StartedAt returns the start time of the running or paused task of the
+// container. It is zero if the container has no such task, or if the start
+// time of its init process cannot be read from the procfs of the host.
+func (c Container) StartedAt() time.Time {
+	return c.startedAt
+}
+
+// Containers lists the containers of the namespace, with their creation
+// time and the start time of their task. The concurrent calls share the
+// same queries.
+func (c *ContainerdUtil) Containers() ([]Container, error) {
+	val, err := c.calls.do(containersCall, func() (interface{}, error) {
+		return c.listContainers()
+	})
+	ctns, _ := val.([]Container)
+	return append([]Container(nil), ctns...), err
+}
+
+func (c *ContainerdUtil) listContainers() ([]Container, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	ctns, err := c.client().Containers(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	startTimes := c.taskStartTimes(ctx)
+
+	result := make([]Container, 0, len(ctns))
+	for _, ctn := range ctns {
+		// The metadata is listed with the container, it is not queried again
+		info, err := ctn.Info(ctx, containerd.WithoutRefreshedMetadata)
+		if err != nil {
+			return nil, classifyError(err)
+		}
+		result = append(result, NewContainer(ctn, info.CreatedAt, startTimes[ctn.ID()]))
+	}
+	return result, nil
+}
+
+// taskStartTimes returns the start time of the running and paused tasks of
+// the namespace by container ID, read from the init process of the task.
+// The tasks whose start time cannot be read are missing.
+func (c *ContainerdUtil) taskStartTimes(ctx context.Context) map[string]time.Time {
+	resp, err := c.tasks().List(ctx, &tasks.ListTasksRequest{})
+	if err != nil {
+		c.log.Debugf("Cannot list the tasks for their start time: %s", err)
+		return nil
+	}
+	bootTime, err := readBootTime(c.procRoot)
+	if err != nil {
+		c.log.Debugf("Cannot read the start time of the tasks: %s", err)
+		return nil
+	}
+	startTimes := make(map[string]time.Time, len(resp.Tasks))
+	for _, t := range resp.Tasks {
+		if t.Status != task.Status_RUNNING && t.Status != task.Status_PAUSED {
+			continue
+		}
+		startedAt, err := readProcessStartTime(c.procRoot, t.Pid, bootTime)
+		if err != nil {
+			c.containerLog(t.ID).Debugf("Cannot read the start time of the task: %s", err)
+			continue
+		}
+		startTimes[t.ID] = startedAt
+	}
+	return startTimes
+}
+
+// readBootTime reads the boot time of the host from the btime line of the
+// stat file of procRoot
+func readBootTime(procRoot string) (time.Time, error) {
+	f, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unexpected btime %q", fields[1])
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("no btime in %s", f.Name())
+}
+
+// readProcessStartTime reads the start time of a process, the 22nd field
+// of its stat file, in clock ticks since the boot of the host
+func readProcessStartTime(procRoot string, pid uint32, bootTime time.Time) (time.Time, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// The fields are counted after the name, which can contain spaces
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return time.Time{}, fmt.Errorf("unexpected stat format")
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("unexpected stat format")
+	}
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected start time %q", fields[19])
+	}
+	return bootTime.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
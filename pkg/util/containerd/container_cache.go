@@ -0,0 +1,289 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+// Topics of the container events handled by a ContainerCache, along
+// with ContainerDeleteTopic
+const (
+	ContainerCreateTopic = "/containers/create"
+	ContainerUpdateTopic = "/containers/update"
+)
+
+// ContainerCacheFilters are the subscription filters matching the events
+// handled by a ContainerCache
+var ContainerCacheFilters = []string{
+	`topic=="` + ContainerCreateTopic + `"`,
+	`topic=="` + ContainerUpdateTopic + `"`,
+	`topic=="` + ContainerDeleteTopic + `"`,
+}
+
+// cacheResubscribeDelay is the wait before subscribing again to the
+// container events after the stream broke
+var cacheResubscribeDelay = 10 * time.Second
+
+// CachedContainer is the metadata of a container kept by a ContainerCache
+type CachedContainer struct {
+	ID string
+	// Name is resolved by the ContainerNameResolver
+	Name      string
+	Image     string
+	Labels    map[string]string
+	CreatedAt time.Time
+	// SandboxID is the sandbox of the container, set by the sandbox API
+	SandboxID string
+	// RuntimeHandler is the runtime running the container, see RuntimeHandler
+	RuntimeHandler string
+	// Security is the security profile set in the spec of the container,
+	// nil if it has no spec
+	Security *types.SecurityProfile
+	// Limits are the resource limits set in the spec of the container
+	Limits ResourceLimits
+}
+
+func newCachedContainer(info containers.Container) CachedContainer {
+	var security *types.SecurityProfile
+	var annotations map[string]string
+	var limits ResourceLimits
+	if spec := decodeRecordSpec(info.Spec); spec != nil {
+		profile := SecurityProfileFromSpec(spec)
+		security = &profile
+		annotations = spec.Annotations
+		limits = SpecResourceLimits(spec)
+	}
+	return CachedContainer{
+		ID:        info.ID,
+		Name:      ContainerNameResolver().Resolve(info.ID, info.Labels, annotations),
+		Image:     info.Image,
+		Labels:    info.Labels,
+		CreatedAt: info.CreatedAt,
+		SandboxID: info.SandboxID,
+
+		RuntimeHandler: RuntimeHandler(info.Runtime.Name),
+		Security:       security,
+		Limits:         limits,
+	}
+}
+
+// ContainerCache holds the metadata of the containers of a namespace. It is
+// filled by listing the containers, then kept up to date by the container
+// events. The containers are listed again when the last listing is older
+// than the max staleness, in case events were missed, or when the cache is
+// invalidated because the event stream broke.
+type ContainerCache struct {
+	cu           ContainerdItf
+	maxStaleness time.Duration
+
+	mu         sync.Mutex
+	containers map[string]CachedContainer // container ID -> metadata
+	lastSync   time.Time                  // zero if the cache must be listed again
+}
+
+// NewContainerCache returns an empty ContainerCache, filled on the first
+// call to Containers
+func NewContainerCache(cu ContainerdItf, maxStaleness time.Duration) *ContainerCache {
+	return &ContainerCache{
+		cu:           cu,
+		maxStaleness: maxStaleness,
+		containers:   make(map[string]CachedContainer),
+	}
+}
+
+// Containers returns the cached containers sorted by ID, listing them
+// first if the cache is stale
+func (cc *ContainerCache) Containers() ([]CachedContainer, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.lastSync.IsZero() || time.Since(cc.lastSync) > cc.maxStaleness {
+		if err := cc.resync(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctns := make([]CachedContainer, 0, len(cc.containers))
+	for _, ctn := range cc.containers {
+		ctns = append(ctns, ctn)
+	}
+	sort.Slice(ctns, func(i, j int) bool { return ctns[i].ID < ctns[j].ID })
+	return ctns, nil
+}
+
+// Len returns the number of containers in the cache
+func (cc *ContainerCache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.containers)
+}
+
+// Invalidate makes the next call to Containers list the containers again,
+// it is called when container events may have been missed
+func (cc *ContainerCache) Invalidate() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.lastSync = time.Time{}
+}
+
+// resync replaces the cache content by a listing of the containers,
+// cc.mu must be held
+func (cc *ContainerCache) resync() error {
+	ctns, err := cc.cu.Containers()
+	if err != nil {
+		return err
+	}
+	fresh := make(map[string]CachedContainer, len(ctns))
+	for _, ctn := range ctns {
+		info, err := cc.cu.Info(ctn)
+		if err != nil {
+			// The container was likely deleted since the listing
+			continue
+		}
+		fresh[info.ID] = newCachedContainer(info)
+	}
+	cc.containers = fresh
+	cc.lastSync = time.Now()
+	return nil
+}
+
+// load adds a container to the cache from its record, cc.mu must be held
+func (cc *ContainerCache) load(id string) error {
+	ctn, err := cc.cu.LoadContainer(id)
+	if err != nil {
+		return err
+	}
+	info, err := cc.cu.Info(ctn)
+	if err != nil {
+		return err
+	}
+	cc.containers[id] = newCachedContainer(info)
+	return nil
+}
+
+// HandleEnvelope processes an event matching ContainerCacheFilters. Events
+// of other namespaces are ignored. The create events do not carry the
+// labels of the container, its record is loaded instead. If it cannot be
+// loaded, the cache is invalidated.
+func (cc *ContainerCache) HandleEnvelope(envelope *events.Envelope) error {
+	if envelope == nil || envelope.Namespace != cc.cu.Namespace() {
+		return nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	switch e := ev.(type) {
+	case *apievents.ContainerCreate:
+		if err := cc.load(e.ID); err != nil {
+			cc.lastSync = time.Time{}
+			return err
+		}
+	case *apievents.ContainerUpdate:
+		ctn, found := cc.containers[e.ID]
+		if !found {
+			if err := cc.load(e.ID); err != nil {
+				cc.lastSync = time.Time{}
+				return err
+			}
+			return nil
+		}
+		resolver := ContainerNameResolver()
+		if resolver.NeedsAnnotations() {
+			// The annotations of the spec are not in the event
+			if err := cc.load(e.ID); err != nil {
+				cc.lastSync = time.Time{}
+				return err
+			}
+			return nil
+		}
+		ctn.Image = e.Image
+		ctn.Labels = e.Labels
+		ctn.Name = resolver.Resolve(e.ID, e.Labels, nil)
+		cc.containers[e.ID] = ctn
+	case *apievents.ContainerDelete:
+		delete(cc.containers, e.ID)
+	}
+	return nil
+}
+
+// CachedContainers returns the metadata of the containers of the namespace.
+// It is served from a cache kept up to date by the container events, the
+// containers are only listed when the cache is older than the configured
+// max staleness or when the event stream broke.
+func (c *ContainerdUtil) CachedContainers() ([]CachedContainer, error) {
+	c.cacheOnce.Do(func() {
+		c.cache = NewContainerCache(c, c.cacheMaxStaleness)
+		c.stats.setCache(c.cache)
+		go c.watchContainerEvents()
+	})
+	return c.cache.Containers()
+}
+
+// watchContainerEvents feeds the container events to the cache until the
+// util is closed, subscribing again when the stream breaks
+func (c *ContainerdUtil) watchContainerEvents() {
+	for {
+		err := c.streamContainerEvents()
+		c.cache.Invalidate()
+		select {
+		case <-c.stopProbe:
+			return
+		default:
+		}
+		c.log.Debugf("containerd container events stream broke, subscribing again in %s: %v", cacheResubscribeDelay, err)
+		select {
+		case <-c.stopProbe:
+			return
+		case <-time.After(cacheResubscribeDelay):
+		}
+	}
+}
+
+// streamContainerEvents feeds the events of one subscription to the cache
+// and returns when the stream breaks or the util is closed
+func (c *ContainerdUtil) streamContainerEvents() error {
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), c.namespace))
+	defer cancel()
+
+	// The events of the other namespaces are dropped by containerd
+	messages, errs := c.GetEvents().Subscribe(ctx, NewNamespaceFilter([]string{c.namespace}, nil).EventFilters(ContainerCacheFilters...)...)
+	// Events sent before the subscription are lost
+	c.cache.Invalidate()
+	for {
+		select {
+		case <-c.stopProbe:
+			return nil
+		case envelope := <-messages:
+			if envelope != nil {
+				c.stats.recordEvent(envelope.Timestamp)
+			}
+			if err := c.cache.HandleEnvelope(envelope); err != nil {
+				c.log.Debugf("Cannot handle containerd event %s: %s", envelope.Topic, err)
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
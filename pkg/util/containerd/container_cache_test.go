@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerCache(t *testing.T) {
+	created := time.Unix(1540000000, 0)
+	records := map[string]containers.Container{
+		"redis": {ID: "redis", Image: "docker.io/library/redis:5", CreatedAt: created},
+	}
+	listings := 0
+	cu := &mockItf{
+		mockNamespace: func() string { return "k8s.io" },
+		mockContainers: func() ([]Container, error) {
+			listings++
+			var ctns []Container
+			for id := range records {
+				ctns = append(ctns, newMockContainers(id)...)
+			}
+			return ctns, nil
+		},
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			if _, found := records[id]; !found {
+				return nil, errors.New("container not found")
+			}
+			return &mockContainer{id: id}, nil
+		},
+		mockInfo: func(ctn containerd.Container) (containers.Container, error) {
+			return records[ctn.ID()], nil
+		},
+	}
+	cache := NewContainerCache(cu, time.Hour)
+
+	ctns, err := cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, []CachedContainer{{ID: "redis", Name: "redis", Image: "docker.io/library/redis:5", CreatedAt: created}}, ctns)
+
+	// The events update the cache without listing the containers again
+	records["nginx"] = containers.Container{
+		ID:        "nginx",
+		Image:     "docker.io/library/nginx:1",
+		Labels:    map[string]string{"app": "web"},
+		CreatedAt: created,
+	}
+	envelope := buildEnvelope(t, ContainerCreateTopic, &apievents.ContainerCreate{ID: "nginx"}, created)
+	envelope.Namespace = "k8s.io"
+	require.NoError(t, cache.HandleEnvelope(envelope))
+
+	envelope = buildEnvelope(t, ContainerUpdateTopic, &apievents.ContainerUpdate{
+		ID:     "redis",
+		Image:  "docker.io/library/redis:6",
+		Labels: map[string]string{"app": "cache"},
+	}, created)
+	envelope.Namespace = "k8s.io"
+	require.NoError(t, cache.HandleEnvelope(envelope))
+
+	ctns, err = cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, []CachedContainer{
+		{ID: "nginx", Name: "nginx", Image: "docker.io/library/nginx:1", Labels: map[string]string{"app": "web"}, CreatedAt: created},
+		{ID: "redis", Name: "redis", Image: "docker.io/library/redis:6", Labels: map[string]string{"app": "cache"}, CreatedAt: created},
+	}, ctns)
+
+	envelope = buildEnvelope(t, ContainerDeleteTopic, &apievents.ContainerDelete{ID: "nginx"}, created)
+	envelope.Namespace = "k8s.io"
+	require.NoError(t, cache.HandleEnvelope(envelope))
+
+	// Events of other namespaces are ignored
+	envelope = buildEnvelope(t, ContainerDeleteTopic, &apievents.ContainerDelete{ID: "redis"}, created)
+	envelope.Namespace = "moby"
+	require.NoError(t, cache.HandleEnvelope(envelope))
+
+	ctns, err = cache.Containers()
+	require.NoError(t, err)
+	require.Len(t, ctns, 1)
+	assert.Equal(t, "redis", ctns[0].ID)
+	assert.Equal(t, 1, listings)
+
+	// A container that cannot be loaded invalidates the cache
+	envelope = buildEnvelope(t, ContainerCreateTopic, &apievents.ContainerCreate{ID: "gone"}, created)
+	envelope.Namespace = "k8s.io"
+	assert.Error(t, cache.HandleEnvelope(envelope))
+	_, err = cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, 2, listings)
+}
+
+func TestContainerCacheStaleness(t *testing.T) {
+	listings := 0
+	cu := &mockItf{
+		mockContainers: func() ([]Container, error) {
+			listings++
+			return nil, nil
+		},
+	}
+	cache := NewContainerCache(cu, time.Minute)
+
+	_, err := cache.Containers()
+	require.NoError(t, err)
+	_, err = cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, 1, listings)
+
+	cache.lastSync = time.Now().Add(-2 * time.Minute)
+	_, err = cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, 2, listings)
+
+	cache.Invalidate()
+	_, err = cache.Containers()
+	require.NoError(t, err)
+	assert.Equal(t, 3, listings)
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/events"
+)
+
+// ChurnWindow is the rolling window over which a ContainerChurnTracker
+// counts the container creations and deletions
+const ChurnWindow = time.Minute
+
+// ContainerChurnFilters are the subscription filters matching the events
+// handled by a ContainerChurnTracker
+var ContainerChurnFilters = []string{
+	`topic=="` + ContainerCreateTopic + `"`,
+	`topic=="` + ContainerDeleteTopic + `"`,
+}
+
+// ContainerChurn is the number of containers created and deleted within
+// the last ChurnWindow
+type ContainerChurn struct {
+	Created int
+	Deleted int
+}
+
+// Total returns the number of creations and deletions
+func (c ContainerChurn) Total() int {
+	return c.Created + c.Deleted
+}
+
+// churnEvent is a creation or deletion counted by a ContainerChurnTracker
+type churnEvent struct {
+	namespace string
+	created   bool
+	timestamp time.Time
+}
+
+// ContainerChurnTracker counts the container creations and deletions per
+// namespace over a rolling window, to spot the nodes cycling many
+// short-lived containers, like the batch nodes. The containers are
+// counted from the events, without listing them, and only the events
+// received since the tracker was created are counted.
+type ContainerChurnTracker struct {
+	mu sync.Mutex
+	// events are sorted by timestamp, the events older than ChurnWindow
+	// are pruned when a new one is added or the churn is read
+	events []churnEvent
+}
+
+// NewContainerChurnTracker returns an empty ContainerChurnTracker
+func NewContainerChurnTracker() *ContainerChurnTracker {
+	return &ContainerChurnTracker{}
+}
+
+// HandleEnvelope processes an event matching ContainerChurnFilters
+func (t *ContainerChurnTracker) HandleEnvelope(envelope *events.Envelope) {
+	if envelope == nil {
+		return
+	}
+	var created bool
+	switch envelope.Topic {
+	case ContainerCreateTopic:
+		created = true
+	case ContainerDeleteTopic:
+	default:
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ev := churnEvent{namespace: envelope.Namespace, created: created, timestamp: envelope.Timestamp}
+	// The replayed events may be older than the last ones received
+	i := len(t.events)
+	for i > 0 && t.events[i-1].timestamp.After(ev.timestamp) {
+		i--
+	}
+	t.events = append(t.events, churnEvent{})
+	copy(t.events[i+1:], t.events[i:])
+	t.events[i] = ev
+	t.pruneLocked(envelope.Timestamp)
+}
+
+// Churn returns the churn of every namespace with containers created or
+// deleted within the ChurnWindow preceding now
+func (t *ContainerChurnTracker) Churn(now time.Time) map[string]ContainerChurn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked(now)
+
+	churn := make(map[string]ContainerChurn)
+	for _, ev := range t.events {
+		c := churn[ev.namespace]
+		if ev.created {
+			c.Created++
+		} else {
+			c.Deleted++
+		}
+		churn[ev.namespace] = c
+	}
+	return churn
+}
+
+// pruneLocked drops the events older than the window preceding now
+func (t *ContainerChurnTracker) pruneLocked(now time.Time) {
+	cutoff := now.Add(-ChurnWindow)
+	i := 0
+	for i < len(t.events) && !t.events[i].timestamp.After(cutoff) {
+		i++
+	}
+	t.events = t.events[i:]
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerChurnTracker(t *testing.T) {
+	tracker := NewContainerChurnTracker()
+	now := time.Now()
+	add := func(ns, topic string, ts time.Time) {
+		tracker.HandleEnvelope(&events.Envelope{Namespace: ns, Topic: topic, Timestamp: ts})
+	}
+
+	assert.Empty(t, tracker.Churn(now))
+
+	for i := 0; i < 5; i++ {
+		add("k8s.io", ContainerCreateTopic, now.Add(-time.Duration(i)*time.Second))
+	}
+	add("k8s.io", ContainerDeleteTopic, now.Add(-10*time.Second))
+	add("buildkit", ContainerCreateTopic, now.Add(-30*time.Second))
+	add("buildkit", ContainerUpdateTopic, now)
+	// Replayed events come out of order
+	add("buildkit", ContainerDeleteTopic, now.Add(-50*time.Second))
+	// Out of the window
+	add("k8s.io", ContainerDeleteTopic, now.Add(-2*time.Minute))
+
+	churn := tracker.Churn(now)
+	assert.Equal(t, map[string]ContainerChurn{
+		"k8s.io":   {Created: 5, Deleted: 1},
+		"buildkit": {Created: 1, Deleted: 1},
+	}, churn)
+	assert.Equal(t, 6, churn["k8s.io"].Total())
+
+	// The window rolls
+	assert.Equal(t, map[string]ContainerChurn{
+		"k8s.io":   {Created: 5, Deleted: 1},
+		"buildkit": {Created: 1},
+	}, tracker.Churn(now.Add(15*time.Second)))
+	assert.Empty(t, tracker.Churn(now.Add(2*time.Minute)))
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	// kubernetesContainerNameLabel is set by the CRI plugin on the
+	// containers it creates
+	kubernetesContainerNameLabel = "io.kubernetes.container.name"
+	// annotationNameSourcePrefix marks the sources of the container names
+	// read from the OCI annotations rather than from the labels
+	annotationNameSourcePrefix = "annotation:"
+	// shortIDLength is the length of the container IDs used as names
+	shortIDLength = 12
+)
+
+// DefaultContainerNameLabels are the default sources of the container names:
+// the name of the pod container set by the CRI plugin, and the name given
+// to nerdctl
+var DefaultContainerNameLabels = []string{
+	kubernetesContainerNameLabel,
+	"nerdctl/name",
+}
+
+var (
+	globalNameResolver     NameResolver
+	globalNameResolverOnce sync.Once
+)
+
+// NameResolver resolves the name of the containers from the first of its
+// sources set on them. The sources are label names, or annotation names
+// prefixed with "annotation:" for the annotations of the OCI spec. The
+// containers with none of them are named after their short ID.
+type NameResolver struct {
+	sources []string
+}
+
+// NewNameResolver returns a NameResolver trying sources in order
+func NewNameResolver(sources []string) NameResolver {
+	return NameResolver{sources: sources}
+}
+
+// ContainerNameResolver returns the NameResolver of the agent configuration,
+// shared by the checks and the tagger so that a container has the same name
+// everywhere
+func ContainerNameResolver() NameResolver {
+	globalNameResolverOnce.Do(func() {
+		globalNameResolver = agentConfig.NameResolver()
+	})
+	return globalNameResolver
+}
+
+// NeedsAnnotations returns whether some sources are annotations, for the
+// callers to read the spec of the containers only if needed
+func (r NameResolver) NeedsAnnotations() bool {
+	for _, source := range r.sources {
+		if strings.HasPrefix(source, annotationNameSourcePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the name of a container from its labels and the
+// annotations of its spec, either can be nil
+func (r NameResolver) Resolve(id string, labels, annotations map[string]string) string {
+	for _, source := range r.sources {
+		values, key := labels, source
+		if strings.HasPrefix(source, annotationNameSourcePrefix) {
+			values, key = annotations, strings.TrimPrefix(source, annotationNameSourcePrefix)
+		}
+		if name := values[key]; name != "" {
+			return name
+		}
+	}
+	return ShortID(id)
+}
+
+// ShortID returns the first characters of a container ID, like docker does
+func ShortID(id string) string {
+	if len(id) > shortIDLength {
+		return id[:shortIDLength]
+	}
+	return id
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameResolver(t *testing.T) {
+	id := "3f7a9c1e5b2d4a6f8e0c1b3d5f7a9c1e"
+	resolver := NewNameResolver(DefaultContainerNameLabels)
+	assert.False(t, resolver.NeedsAnnotations())
+	assert.Equal(t, "redis", resolver.Resolve(id, map[string]string{"io.kubernetes.container.name": "redis", "nerdctl/name": "other"}, nil))
+	assert.Equal(t, "web", resolver.Resolve(id, map[string]string{"nerdctl/name": "web"}, nil))
+	assert.Equal(t, "3f7a9c1e5b2d", resolver.Resolve(id, nil, nil))
+	assert.Equal(t, "short", resolver.Resolve("short", map[string]string{"io.kubernetes.container.name": ""}, nil))
+
+	// The sources are tried in order across the labels and the annotations
+	resolver = NewNameResolver([]string{"annotation:io.kubernetes.cri.container-name", "com.example.name"})
+	assert.True(t, resolver.NeedsAnnotations())
+	assert.Equal(t, "api", resolver.Resolve(id, map[string]string{"com.example.name": "label"}, map[string]string{"io.kubernetes.cri.container-name": "api"}))
+	assert.Equal(t, "label", resolver.Resolve(id, map[string]string{"com.example.name": "label"}, nil))
+	// The annotations are not looked up in the labels
+	assert.Equal(t, "3f7a9c1e5b2d", resolver.Resolve(id, map[string]string{"io.kubernetes.cri.container-name": "api"}, nil))
+}
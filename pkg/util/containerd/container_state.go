@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+)
+
+// Topics of the task and container events tracked by a ContainerStateTracker
+const (
+	TaskCreateTopic       = "/tasks/create"
+	TaskStartTopic        = "/tasks/start"
+	TaskExitTopic         = "/tasks/exit"
+	TaskPausedTopic       = "/tasks/paused"
+	TaskResumedTopic      = "/tasks/resumed"
+	TaskCheckpointedTopic = "/tasks/checkpointed"
+	ContainerDeleteTopic  = "/containers/delete"
+)
+
+// ContainerStateFilters are the subscription filters matching the events
+// handled by a ContainerStateTracker
+var ContainerStateFilters = []string{
+	`topic=="` + TaskCreateTopic + `"`,
+	`topic=="` + TaskStartTopic + `"`,
+	`topic=="` + TaskExitTopic + `"`,
+	`topic=="` + TaskPausedTopic + `"`,
+	`topic=="` + TaskResumedTopic + `"`,
+	`topic=="` + TaskCheckpointedTopic + `"`,
+	`topic=="` + ContainerDeleteTopic + `"`,
+}
+
+// CheckpointExitWindow is the delay between the checkpoint of a task and
+// the exit of its init process within which the exit is the one of a
+// checkpoint taken with --exit, not a crash. The shim may publish the exit
+// before containerd publishes the checkpoint, so it applies both ways.
+const CheckpointExitWindow = 10 * time.Second
+
+// IsCheckpointExit returns whether a task exit and a checkpoint of the same
+// task are the two events of a checkpoint taken with --exit
+func IsCheckpointExit(exitedAt, checkpointedAt time.Time) bool {
+	if exitedAt.IsZero() || checkpointedAt.IsZero() {
+		return false
+	}
+	delta := exitedAt.Sub(checkpointedAt)
+	return delta <= CheckpointExitWindow && delta >= -CheckpointExitWindow
+}
+
+// ContainerStatus is the status of the task of a container
+type ContainerStatus string
+
+// Statuses of a ContainerState
+const (
+	ContainerStatusRunning ContainerStatus = "running"
+	ContainerStatusPaused  ContainerStatus = "paused"
+	ContainerStatusStopped ContainerStatus = "stopped"
+	// ContainerStatusCheckpointed is the status of the containers whose
+	// task exited when it was checkpointed, to be restored later
+	ContainerStatusCheckpointed ContainerStatus = "checkpointed"
+)
+
+// ContainerState is the lifecycle of a container seen through its task events
+type ContainerState struct {
+	Status ContainerStatus
+	// RestartCount is the number of task starts following the first one,
+	// excluding the restores from a checkpoint
+	RestartCount int
+	// CheckpointCount and RestoreCount are the number of checkpoints of
+	// the task and of tasks restored from a checkpoint
+	CheckpointCount int
+	RestoreCount    int
+	// StartedAt is the start time of the running task, zero if the
+	// task is not running
+	StartedAt time.Time
+
+	exitedAt       time.Time
+	checkpointedAt time.Time
+	// restoring is set by the creation of a task from a checkpoint,
+	// until the task starts
+	restoring bool
+	// namespace is the namespace of the last task event, to persist the
+	// state, see container_store.go
+	namespace string
+}
+
+// Running returns whether the task of the container is running, paused
+// tasks included
+func (s ContainerState) Running() bool {
+	return !s.StartedAt.IsZero()
+}
+
+// ContainerStateTracker turns the task events of containerd into a restart
+// count and a start time per container ID. A restart is a new task started
+// in an existing container, eg. by the containerd restart monitor. The tasks
+// checkpointed and restored with CRIU are counted apart, so that their exit
+// and new start are not taken for a crash. Only the events received since
+// the tracker was created are counted: the tasks running before are not
+// tracked until they restart.
+type ContainerStateTracker struct {
+	mu     sync.RWMutex
+	states map[string]*ContainerState // container ID -> state
+}
+
+// NewContainerStateTracker returns an empty ContainerStateTracker
+func NewContainerStateTracker() *ContainerStateTracker {
+	return &ContainerStateTracker{
+		states: make(map[string]*ContainerState),
+	}
+}
+
+// HandleEnvelope processes an event matching ContainerStateFilters
+func (t *ContainerStateTracker) HandleEnvelope(envelope *events.Envelope) error {
+	if envelope == nil {
+		return nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e := ev.(type) {
+	case *apievents.TaskCreate:
+		if e.Checkpoint == "" {
+			break
+		}
+		state, found := t.states[e.ContainerID]
+		if !found {
+			state = &ContainerState{Status: ContainerStatusStopped}
+			t.states[e.ContainerID] = state
+		}
+		state.restoring = true
+	case *apievents.TaskStart:
+		state, found := t.states[e.ContainerID]
+		switch {
+		case !found:
+			state = &ContainerState{}
+			t.states[e.ContainerID] = state
+		case state.restoring:
+			state.RestoreCount++
+		default:
+			state.RestartCount++
+		}
+		state.Status = ContainerStatusRunning
+		state.namespace = envelope.Namespace
+		state.StartedAt = envelope.Timestamp
+		state.restoring = false
+		state.exitedAt = time.Time{}
+		state.checkpointedAt = time.Time{}
+	case *apievents.TaskExit:
+		// Processes exec'd in the container exit with their own ID
+		state, found := t.states[e.ContainerID]
+		if !found || e.ID != e.ContainerID {
+			break
+		}
+		state.StartedAt = time.Time{}
+		state.exitedAt = envelope.Timestamp
+		state.Status = ContainerStatusStopped
+		if IsCheckpointExit(state.exitedAt, state.checkpointedAt) {
+			state.Status = ContainerStatusCheckpointed
+		}
+	case *apievents.TaskPaused:
+		if state, found := t.states[e.ContainerID]; found && state.Running() {
+			state.Status = ContainerStatusPaused
+		}
+	case *apievents.TaskResumed:
+		if state, found := t.states[e.ContainerID]; found && state.Running() {
+			state.Status = ContainerStatusRunning
+		}
+	case *apievents.TaskCheckpointed:
+		state, found := t.states[e.ContainerID]
+		if !found {
+			break
+		}
+		state.CheckpointCount++
+		state.checkpointedAt = envelope.Timestamp
+		if !state.Running() && IsCheckpointExit(state.exitedAt, state.checkpointedAt) {
+			state.Status = ContainerStatusCheckpointed
+		}
+	case *apievents.ContainerDelete:
+		delete(t.states, e.ID)
+	}
+	return nil
+}
+
+// State returns the state of a container, false if no task start
+// was seen for it
+func (t *ContainerStateTracker) State(containerID string) (ContainerState, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	state, found := t.states[containerID]
+	if !found {
+		return ContainerState{}, false
+	}
+	return *state, true
+}
+
+// States returns the state of the tracked containers, by container ID
+func (t *ContainerStateTracker) States() map[string]ContainerState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	states := make(map[string]ContainerState, len(t.states))
+	for id, state := range t.states {
+		states[id] = *state
+	}
+	return states
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerStateTracker(t *testing.T) {
+	tracker := NewContainerStateTracker()
+	now := time.Now()
+
+	_, found := tracker.State("redis")
+	assert.False(t, found)
+
+	// The exit of a task started before the tracker is ignored
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "nginx", ID: "nginx"}, now)))
+	assert.Len(t, tracker.States(), 0)
+
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 42}, now.Add(-time.Minute))))
+	state, found := tracker.State("redis")
+	require.True(t, found)
+	assert.Equal(t, 0, state.RestartCount)
+	assert.True(t, state.Running())
+	assert.True(t, now.Add(-time.Minute).Equal(state.StartedAt))
+
+	// Exec'd processes don't stop the container
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "exec-1"}, now)))
+	state, _ = tracker.State("redis")
+	assert.True(t, state.Running())
+
+	// Crash loop
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis", ExitStatus: 1}, now)))
+		state, _ = tracker.State("redis")
+		assert.False(t, state.Running())
+		require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 42}, now.Add(time.Duration(i)*time.Second))))
+	}
+	state, _ = tracker.State("redis")
+	assert.Equal(t, 3, state.RestartCount)
+	assert.True(t, now.Add(3*time.Second).Equal(state.StartedAt))
+	assert.Len(t, tracker.States(), 1)
+
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ContainerDeleteTopic, &apievents.ContainerDelete{ID: "redis"}, now)))
+	_, found = tracker.State("redis")
+	assert.False(t, found)
+}
+
+func TestContainerStateTrackerCheckpointRestore(t *testing.T) {
+	tracker := NewContainerStateTracker()
+	now := time.Now()
+
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 42}, now)))
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskPausedTopic, &apievents.TaskPaused{ContainerID: "redis"}, now)))
+	state, _ := tracker.State("redis")
+	assert.Equal(t, ContainerStatusPaused, state.Status)
+	assert.True(t, state.Running())
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskResumedTopic, &apievents.TaskResumed{ContainerID: "redis"}, now)))
+	state, _ = tracker.State("redis")
+	assert.Equal(t, ContainerStatusRunning, state.Status)
+
+	// A checkpoint leaving the task running
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskCheckpointedTopic, &apievents.TaskCheckpointed{ContainerID: "redis", Checkpoint: "sha256:1"}, now.Add(time.Minute))))
+	state, _ = tracker.State("redis")
+	assert.Equal(t, ContainerStatusRunning, state.Status)
+	assert.Equal(t, 1, state.CheckpointCount)
+
+	// A crash long after the checkpoint
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis", ExitStatus: 137}, now.Add(time.Hour))))
+	state, _ = tracker.State("redis")
+	assert.Equal(t, ContainerStatusStopped, state.Status)
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 43}, now.Add(time.Hour))))
+
+	// A checkpoint with --exit, the shim publishes the exit first
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis", ExitStatus: 137}, now.Add(2*time.Hour))))
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskCheckpointedTopic, &apievents.TaskCheckpointed{ContainerID: "redis", Checkpoint: "sha256:2"}, now.Add(2*time.Hour+time.Second))))
+	state, _ = tracker.State("redis")
+	assert.Equal(t, ContainerStatusCheckpointed, state.Status)
+	assert.False(t, state.Running())
+
+	// The restore is not a restart
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskCreateTopic, &apievents.TaskCreate{ContainerID: "redis", Checkpoint: "sha256:2"}, now.Add(3*time.Hour))))
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 44}, now.Add(3*time.Hour))))
+	state, _ = tracker.State("redis")
+	assert.Equal(t, ContainerStatusRunning, state.Status)
+	assert.Equal(t, 1, state.RestartCount)
+	assert.Equal(t, 2, state.CheckpointCount)
+	assert.Equal(t, 1, state.RestoreCount)
+
+	// A container created from the checkpoint of another host
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskCreateTopic, &apievents.TaskCreate{ContainerID: "migrated", Checkpoint: "sha256:3"}, now)))
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "migrated", Pid: 45}, now)))
+	state, _ = tracker.State("migrated")
+	assert.Equal(t, 0, state.RestartCount)
+	assert.Equal(t, 1, state.RestoreCount)
+
+	// Plain task creations are not tracked
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, TaskCreateTopic, &apievents.TaskCreate{ContainerID: "nginx"}, now)))
+	_, found := tracker.State("nginx")
+	assert.False(t, found)
+}
+
+func TestIsCheckpointExit(t *testing.T) {
+	now := time.Now()
+	assert.True(t, IsCheckpointExit(now, now.Add(time.Second)))
+	assert.True(t, IsCheckpointExit(now.Add(time.Second), now))
+	assert.False(t, IsCheckpointExit(now, now.Add(time.Minute)))
+	assert.False(t, IsCheckpointExit(now, time.Time{}))
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// containerStoreVersion is the version of the format of the store file
+const containerStoreVersion = 1
+
+// StoredContainer is the metadata and the state of a container persisted
+// across the restarts of the agent
+type StoredContainer struct {
+	Namespace string    `json:"namespace"`
+	ID        string    `json:"id"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Status          ContainerStatus `json:"status,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	RestartCount    int             `json:"restart_count"`
+	CheckpointCount int             `json:"checkpoint_count"`
+	RestoreCount    int             `json:"restore_count"`
+}
+
+// ChurnRecord is a container creation or deletion counted by a
+// ContainerChurnTracker
+type ChurnRecord struct {
+	Namespace string    `json:"namespace"`
+	Created   bool      `json:"created"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ContainerStore is the container metadata persisted so that the restart
+// counts and the churn do not reset when the agent restarts, eg. when it
+// is upgraded. The changes missed while the agent was not running are
+// replayed from the EventBookmark on top of it.
+type ContainerStore struct {
+	SavedAt    time.Time         `json:"saved_at"`
+	Containers []StoredContainer `json:"containers"`
+	Churn      []ChurnRecord     `json:"churn"`
+}
+
+type containerStoreFile struct {
+	Version int `json:"version"`
+	ContainerStore
+}
+
+// LoadContainerStore reads the store persisted at path, it is empty if the
+// file does not exist yet
+func LoadContainerStore(path string) (ContainerStore, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ContainerStore{}, nil
+	}
+	if err != nil {
+		return ContainerStore{}, err
+	}
+
+	var f containerStoreFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return ContainerStore{}, fmt.Errorf("cannot parse the containerd metadata store %s: %s", path, err)
+	}
+	if f.Version != containerStoreVersion {
+		return ContainerStore{}, fmt.Errorf("unsupported version %d of the containerd metadata store %s", f.Version, path)
+	}
+	return f.ContainerStore, nil
+}
+
+// Save writes the store to path
+func (s ContainerStore) Save(path string) error {
+	content, err := json.Marshal(containerStoreFile{
+		Version:        containerStoreVersion,
+		ContainerStore: s,
+	})
+	if err != nil {
+		return err
+	}
+	// The store is replaced at once so that a crash does not leave it truncated
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Snapshot returns the state of the tracked containers to be stored, sorted
+// by ID, the metadata of the containers is not filled
+func (t *ContainerStateTracker) Snapshot() []StoredContainer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	ctns := make([]StoredContainer, 0, len(t.states))
+	for id, state := range t.states {
+		ctns = append(ctns, StoredContainer{
+			Namespace:       state.namespace,
+			ID:              id,
+			Status:          state.Status,
+			StartedAt:       state.StartedAt,
+			RestartCount:    state.RestartCount,
+			CheckpointCount: state.CheckpointCount,
+			RestoreCount:    state.RestoreCount,
+		})
+	}
+	sort.Slice(ctns, func(i, j int) bool {
+		return ctns[i].ID < ctns[j].ID
+	})
+	return ctns
+}
+
+// Restore tracks the stored containers as they were when they were stored.
+// The containers already tracked, ie. whose events were received since the
+// tracker was created, are kept as they are.
+func (t *ContainerStateTracker) Restore(ctns []StoredContainer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, ctn := range ctns {
+		if _, found := t.states[ctn.ID]; found {
+			continue
+		}
+		t.states[ctn.ID] = &ContainerState{
+			Status:          ctn.Status,
+			RestartCount:    ctn.RestartCount,
+			CheckpointCount: ctn.CheckpointCount,
+			RestoreCount:    ctn.RestoreCount,
+			StartedAt:       ctn.StartedAt,
+			namespace:       ctn.Namespace,
+		}
+	}
+}
+
+// Snapshot returns the creations and deletions within the ChurnWindow
+// preceding now, to be stored
+func (t *ContainerChurnTracker) Snapshot(now time.Time) []ChurnRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked(now)
+	records := make([]ChurnRecord, 0, len(t.events))
+	for _, ev := range t.events {
+		records = append(records, ChurnRecord{Namespace: ev.namespace, Created: ev.created, Timestamp: ev.timestamp})
+	}
+	return records
+}
+
+// Restore counts the stored creations and deletions along with the ones
+// received since the tracker was created
+func (t *ContainerChurnTracker) Restore(records []ChurnRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range records {
+		t.events = append(t.events, churnEvent{namespace: r.Namespace, created: r.Created, timestamp: r.Timestamp})
+	}
+	sort.SliceStable(t.events, func(i, j int) bool {
+		return t.events[i].timestamp.Before(t.events[j].timestamp)
+	})
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerStoreSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "containerd_metadata.json")
+
+	// Nothing was stored yet
+	store, err := LoadContainerStore(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.Containers)
+
+	ts := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	store = ContainerStore{
+		SavedAt: ts,
+		Containers: []StoredContainer{{
+			Namespace:    "k8s.io",
+			ID:           "redis",
+			Tags:         []string{"kube_deployment:redis"},
+			CreatedAt:    ts.Add(-time.Hour),
+			Status:       ContainerStatusRunning,
+			StartedAt:    ts.Add(-time.Minute),
+			RestartCount: 3,
+		}},
+		Churn: []ChurnRecord{{Namespace: "k8s.io", Created: true, Timestamp: ts}},
+	}
+	require.NoError(t, store.Save(path))
+	loaded, err := LoadContainerStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, store, loaded)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 2}`), 0644))
+	loaded, err = LoadContainerStore(path)
+	assert.Error(t, err)
+	assert.Empty(t, loaded.Containers)
+}
+
+func TestContainerStateTrackerRestore(t *testing.T) {
+	ts := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewContainerStateTracker()
+	handle := func(topic string, ev interface{}, ts time.Time) {
+		envelope := buildEnvelope(t, topic, ev, ts)
+		envelope.Namespace = "k8s.io"
+		require.NoError(t, tracker.HandleEnvelope(envelope))
+	}
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, ts)
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, ts.Add(time.Second))
+	snapshot := tracker.Snapshot()
+	assert.Equal(t, []StoredContainer{{
+		Namespace:    "k8s.io",
+		ID:           "redis",
+		Status:       ContainerStatusRunning,
+		StartedAt:    ts.Add(time.Second),
+		RestartCount: 1,
+	}}, snapshot)
+
+	// The restart count goes on after a restart of the agent
+	restarted := NewContainerStateTracker()
+	restarted.Restore(snapshot)
+	tracker = restarted
+	handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis"}, ts.Add(2*time.Second))
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, ts.Add(3*time.Second))
+	state, found := restarted.State("redis")
+	require.True(t, found)
+	assert.Equal(t, 2, state.RestartCount)
+
+	// The states received since the tracker was created are kept
+	restarted.Restore([]StoredContainer{{Namespace: "k8s.io", ID: "redis", RestartCount: 7}})
+	state, _ = restarted.State("redis")
+	assert.Equal(t, 2, state.RestartCount)
+}
+
+func TestContainerChurnTrackerRestore(t *testing.T) {
+	now := time.Now()
+	tracker := NewContainerChurnTracker()
+	tracker.HandleEnvelope(&events.Envelope{Namespace: "k8s.io", Topic: ContainerCreateTopic, Timestamp: now.Add(-40 * time.Second)})
+	snapshot := tracker.Snapshot(now)
+	require.Len(t, snapshot, 1)
+
+	restarted := NewContainerChurnTracker()
+	restarted.HandleEnvelope(&events.Envelope{Namespace: "k8s.io", Topic: ContainerDeleteTopic, Timestamp: now.Add(-10 * time.Second)})
+	restarted.Restore(append(snapshot, ChurnRecord{Namespace: "k8s.io", Created: true, Timestamp: now.Add(-2 * ChurnWindow)}))
+	assert.Equal(t, map[string]ContainerChurn{"k8s.io": {Created: 1, Deleted: 1}}, restarted.Churn(now))
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type listedTasks struct {
+	tasks.TasksClient
+	processes []*task.Process
+}
+
+func (l *listedTasks) List(ctx context.Context, in *tasks.ListTasksRequest, opts ...grpc.CallOption) (*tasks.ListTasksResponse, error) {
+	return &tasks.ListTasksResponse{Tasks: l.processes}, nil
+}
+
+func TestTaskStartTimes(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(procRoot, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, path), []byte(content), 0644))
+	}
+	write("stat", "cpu  10 0 20 300 0 0 0 0 0 0\nbtime 1540000000\nprocesses 4242\n")
+	// The init process of redis started 12.5s after the boot
+	write("42/stat", "42 (redis server) S 1 42 42 0 -1 4194560 1042 0 0 0 5 3 0 0 20 0 4 0 1250 52240384 2412 18446744073709551615\n")
+	write("43/stat", "43 (nginx) S 1 43 43 0 -1\n")
+
+	c := newContainerdUtil(Options{ProcRoot: procRoot}.withDefaults())
+	c.taskService = &listedTasks{processes: []*task.Process{
+		{ID: "redis", Pid: 42, Status: task.Status_RUNNING},
+		{ID: "truncated", Pid: 43, Status: task.Status_RUNNING},
+		{ID: "exited", Pid: 44, Status: task.Status_STOPPED},
+	}}
+
+	startTimes := c.taskStartTimes(context.Background())
+	assert.Equal(t, map[string]time.Time{
+		"redis": time.Unix(1540000012, 500000000),
+	}, startTimes)
+}
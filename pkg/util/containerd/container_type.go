@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// criContainerTypeAnnotation is set by the CRI plugin in the spec of the
+// containers it creates, sandbox for the container running the sandbox
+const criContainerTypeAnnotation = "io.kubernetes.cri.container-type"
+
+// Types of the containers of the pods
+const (
+	ContainerTypeInit    = "init"
+	ContainerTypeSidecar = "sidecar"
+	ContainerTypeMain    = "main"
+)
+
+// DefaultInitContainerNames are the default patterns of the names of the
+// init containers: the ones injected by the service meshes and the secret
+// managers, and the usual naming conventions
+var DefaultInitContainerNames = []string{
+	"istio-init",
+	"istio-validation",
+	"linkerd-init",
+	"vault-agent-init",
+	"init-*",
+	"*-init",
+}
+
+// DefaultSidecarContainerNames are the default patterns of the names of
+// the sidecar containers: the proxies of the service meshes and of the
+// cloud databases, the secret managers, and the usual naming conventions
+var DefaultSidecarContainerNames = []string{
+	"istio-proxy",
+	"linkerd-proxy",
+	"envoy",
+	"vault-agent",
+	"cloud-sql-proxy",
+	"cloudsql-proxy",
+	"*-sidecar",
+}
+
+var (
+	globalTypeResolver     TypeResolver
+	globalTypeResolverOnce sync.Once
+)
+
+// TypeResolver tells the init and the sidecar containers of the pods from
+// their main containers, from the name the CRI plugin labels them with, so
+// that the init containers can be left out of the usage of the workloads
+// without querying the kubelet. The names are matched as shell patterns.
+type TypeResolver struct {
+	initNames    []string
+	sidecarNames []string
+}
+
+// NewTypeResolver returns a TypeResolver matching the names of the pod
+// containers with initNames, then with sidecarNames
+func NewTypeResolver(initNames, sidecarNames []string) TypeResolver {
+	return TypeResolver{initNames: initNames, sidecarNames: sidecarNames}
+}
+
+// ContainerTypeResolver returns the TypeResolver of the agent
+// configuration, shared by the checks and the tagger
+func ContainerTypeResolver() TypeResolver {
+	globalTypeResolverOnce.Do(func() {
+		globalTypeResolver = agentConfig.TypeResolver()
+	})
+	return globalTypeResolver
+}
+
+// Resolve returns the type of a container from its labels and the
+// annotations of its spec, either can be nil. It is empty for the
+// containers not created by the CRI plugin, and for the sandboxes.
+func (r TypeResolver) Resolve(labels, annotations map[string]string) string {
+	name := labels[kubernetesContainerNameLabel]
+	if name == "" || annotations[criContainerTypeAnnotation] == "sandbox" {
+		return ""
+	}
+	switch {
+	case matchAnyName(r.initNames, name):
+		return ContainerTypeInit
+	case matchAnyName(r.sidecarNames, name):
+		return ContainerTypeSidecar
+	}
+	return ContainerTypeMain
+}
+
+func matchAnyName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeResolver(t *testing.T) {
+	resolver := NewTypeResolver(DefaultInitContainerNames, DefaultSidecarContainerNames)
+	named := func(name string) map[string]string {
+		return map[string]string{"io.kubernetes.container.name": name, "io.kubernetes.pod.name": "web-0"}
+	}
+	assert.Equal(t, ContainerTypeInit, resolver.Resolve(named("istio-init"), nil))
+	assert.Equal(t, ContainerTypeInit, resolver.Resolve(named("init-schema"), nil))
+	assert.Equal(t, ContainerTypeInit, resolver.Resolve(named("migrations-init"), nil))
+	assert.Equal(t, ContainerTypeSidecar, resolver.Resolve(named("istio-proxy"), nil))
+	assert.Equal(t, ContainerTypeSidecar, resolver.Resolve(named("log-shipper-sidecar"), nil))
+	assert.Equal(t, ContainerTypeMain, resolver.Resolve(named("web"), map[string]string{"io.kubernetes.cri.container-type": "container"}))
+
+	// The sandboxes and the containers out of the pods have no type
+	assert.Equal(t, "", resolver.Resolve(map[string]string{"io.kubernetes.pod.name": "web-0", "io.cri-containerd.kind": "sandbox"}, map[string]string{"io.kubernetes.cri.container-type": "sandbox"}))
+	assert.Equal(t, "", resolver.Resolve(map[string]string{"nerdctl/name": "web"}, nil))
+	assert.Equal(t, "", resolver.Resolve(nil, nil))
+
+	// The init patterns are matched first
+	resolver = NewTypeResolver([]string{"setup"}, []string{"setup", "proxy-*"})
+	assert.Equal(t, ContainerTypeInit, resolver.Resolve(named("setup"), nil))
+	assert.Equal(t, ContainerTypeSidecar, resolver.Resolve(named("proxy-http"), nil))
+	assert.Equal(t, ContainerTypeMain, resolver.Resolve(named("istio-proxy"), nil))
+}
@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/filters"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/protobuf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WalkContainers calls fn with the metadata of the containers of the
+// namespace matching filters, one at a time as the daemon streams them, so
+// that the containers of the hosts running thousands of them are processed
+// without holding them all in memory. The filters use the containerd
+// syntax, eg. labels."io.kubernetes.pod.name", and are applied by the
+// daemon. The walk stops at the first error of fn, which is returned as is.
+// The daemons older than 1.5, without the streaming API, send the listing
+// in a single response, it is still walked one container at a time.
+func (c *ContainerdUtil) WalkContainers(ctx context.Context, fn func(CachedContainer) error, filters ...string) error {
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(ctx, c.namespace))
+	defer cancel()
+	service := c.containersClient()
+
+	stream, err := service.ListStream(ctx, &containersapi.ListContainersRequest{Filters: filters})
+	if err != nil {
+		return classifyError(err)
+	}
+	for {
+		msg, err := stream.Recv()
+		switch {
+		case err == io.EOF:
+			return nil
+		case status.Code(err) == codes.Unimplemented:
+			return c.walkListedContainers(ctx, service, fn, filters)
+		case err != nil:
+			return classifyError(err)
+		}
+		if err := fn(newCachedContainer(containerRecordFromProto(msg.Container))); err != nil {
+			return err
+		}
+	}
+}
+
+// walkListedContainers is the WalkContainers of the daemons without the
+// streaming API
+func (c *ContainerdUtil) walkListedContainers(ctx context.Context, service containersapi.ContainersClient, fn func(CachedContainer) error, filters []string) error {
+	resp, err := service.List(ctx, &containersapi.ListContainersRequest{Filters: filters})
+	if err != nil {
+		return classifyError(err)
+	}
+	for i, ctn := range resp.Containers {
+		// The records already walked are released along the way
+		resp.Containers[i] = nil
+		if err := fn(newCachedContainer(containerRecordFromProto(ctn))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containersClient returns the containers service of the daemon, or the one
+// of the tests
+func (c *ContainerdUtil) containersClient() containersapi.ContainersClient {
+	if c.containerService != nil {
+		return c.containerService
+	}
+	return containersapi.NewContainersClient(c.client().Conn())
+}
+
+// containerRecordFromProto converts the fields of a streamed container
+// read by newCachedContainer, the snapshot and the extensions are left out
+func containerRecordFromProto(ctn *containersapi.Container) containers.Container {
+	record := containers.Container{
+		ID:        ctn.ID,
+		Labels:    ctn.Labels,
+		Image:     ctn.Image,
+		CreatedAt: protobuf.FromTimestamp(ctn.CreatedAt),
+		UpdatedAt: protobuf.FromTimestamp(ctn.UpdatedAt),
+		SandboxID: ctn.Sandbox,
+	}
+	if ctn.Spec != nil {
+		record.Spec = ctn.Spec
+	}
+	if ctn.Runtime != nil {
+		record.Runtime.Name = ctn.Runtime.Name
+	}
+	return record
+}
+
+// WalkCachedContainers calls fn with the containers of ctns matching
+// filters, the way WalkContainers does for the containers of a daemon. The
+// filters can select the id, the image and the labels of the containers.
+func WalkCachedContainers(ctns []CachedContainer, fn func(CachedContainer) error, filterExprs ...string) error {
+	filter, err := filters.ParseAll(filterExprs...)
+	if err != nil {
+		return fmt.Errorf("invalid container filters %q: %s", filterExprs, err)
+	}
+	for _, ctn := range ctns {
+		if !filter.Match(cachedContainerAdaptor(ctn)) {
+			continue
+		}
+		if err := fn(ctn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedContainerAdaptor exposes the fields of a container to the filters
+// like the adaptor of the daemon
+func cachedContainerAdaptor(ctn CachedContainer) filters.Adaptor {
+	return filters.AdapterFunc(func(fieldpath []string) (string, bool) {
+		if len(fieldpath) == 0 {
+			return "", false
+		}
+		switch fieldpath[0] {
+		case "id":
+			return ctn.ID, len(ctn.ID) > 0
+		case "image":
+			return ctn.Image, len(ctn.Image) > 0
+		case "labels":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			value, found := ctn.Labels[strings.Join(fieldpath[1:], ".")]
+			return value, found
+		}
+		return "", false
+	})
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamedContainers serves its containers with ListStream, or with List
+// only if streamUnimplemented is set
+type streamedContainers struct {
+	containersapi.ContainersClient
+	containers          []*containersapi.Container
+	streamUnimplemented bool
+	filters             []string
+	listed              bool
+}
+
+func (s *streamedContainers) ListStream(ctx context.Context, in *containersapi.ListContainersRequest, opts ...grpc.CallOption) (containersapi.Containers_ListStreamClient, error) {
+	s.filters = in.Filters
+	return &containerStream{service: s}, nil
+}
+
+func (s *streamedContainers) List(ctx context.Context, in *containersapi.ListContainersRequest, opts ...grpc.CallOption) (*containersapi.ListContainersResponse, error) {
+	s.filters = in.Filters
+	s.listed = true
+	return &containersapi.ListContainersResponse{Containers: s.containers}, nil
+}
+
+type containerStream struct {
+	containersapi.Containers_ListStreamClient
+	service *streamedContainers
+	sent    int
+}
+
+func (s *containerStream) Recv() (*containersapi.ListContainerMessage, error) {
+	if s.service.streamUnimplemented {
+		return nil, status.Error(codes.Unimplemented, "unknown method ListStream")
+	}
+	if s.sent == len(s.service.containers) {
+		return nil, io.EOF
+	}
+	s.sent++
+	return &containersapi.ListContainerMessage{Container: s.service.containers[s.sent-1]}, nil
+}
+
+func TestWalkContainers(t *testing.T) {
+	for name, streamUnimplemented := range map[string]bool{
+		"streamed": false,
+		"listed":   true,
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := &streamedContainers{
+				containers: []*containersapi.Container{
+					{ID: "redis", Image: "docker.io/library/redis:latest", Labels: map[string]string{"io.kubernetes.pod.name": "redis-0"}, Runtime: &containersapi.Container_Runtime{Name: "io.containerd.runc.v2"}},
+					{ID: "pause", Image: "k8s.gcr.io/pause:3.2"},
+				},
+				streamUnimplemented: streamUnimplemented,
+			}
+			c := newContainerdUtil(Options{}.withDefaults())
+			c.containerService = service
+
+			var walked []CachedContainer
+			err := c.WalkContainers(context.Background(), func(ctn CachedContainer) error {
+				walked = append(walked, ctn)
+				return nil
+			}, PodContainersFilter)
+			require.NoError(t, err)
+			assert.Equal(t, []string{`labels."io.kubernetes.pod.name"`}, service.filters)
+			assert.Equal(t, streamUnimplemented, service.listed)
+			require.Len(t, walked, 2)
+			assert.Equal(t, "redis", walked[0].ID)
+			assert.Equal(t, "docker.io/library/redis:latest", walked[0].Image)
+			assert.Equal(t, "runc", walked[0].RuntimeHandler)
+			assert.Equal(t, "pause", walked[1].ID)
+		})
+	}
+}
+
+func TestWalkContainersStopsOnError(t *testing.T) {
+	c := newContainerdUtil(Options{}.withDefaults())
+	c.containerService = &streamedContainers{containers: []*containersapi.Container{{ID: "redis"}, {ID: "nginx"}}}
+
+	stop := errors.New("stop")
+	var walked []string
+	err := c.WalkContainers(context.Background(), func(ctn CachedContainer) error {
+		walked = append(walked, ctn.ID)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"redis"}, walked)
+}
+
+func TestWalkCachedContainers(t *testing.T) {
+	ctns := []CachedContainer{
+		{ID: "redis", Image: "redis:latest", Labels: map[string]string{"io.kubernetes.pod.name": "redis-0"}},
+		{ID: "pause", Image: "pause:3.2", Labels: map[string]string{"io.kubernetes.pod.name": "redis-0", "io.cri-containerd.kind": "sandbox"}},
+		{ID: "buildkit", Image: "moby/buildkit:latest"},
+	}
+	for name, tc := range map[string]struct {
+		filters  []string
+		expected []string
+	}{
+		"no filter":    {expected: []string{"redis", "pause", "buildkit"}},
+		"pods":         {filters: []string{PodContainersFilter}, expected: []string{"redis", "pause"}},
+		"label value":  {filters: []string{`labels."io.cri-containerd.kind"==sandbox`}, expected: []string{"pause"}},
+		"image regexp": {filters: []string{`image~=^redis`}, expected: []string{"redis"}},
+		"any filter":   {filters: []string{"id==buildkit", "id==redis"}, expected: []string{"redis", "buildkit"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var walked []string
+			err := WalkCachedContainers(ctns, func(ctn CachedContainer) error {
+				walked = append(walked, ctn.ID)
+				return nil
+			}, tc.filters...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, walked)
+		})
+	}
+
+	err := WalkCachedContainers(ctns, func(CachedContainer) error { return nil }, "id==")
+	assert.Error(t, err)
+}
@@ -0,0 +1,507 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/sandbox"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+// ContainerdItf is the interface implementing a subset of methods that leverage the containerd API.
+// Consumers should rely on it instead of the ContainerdUtil struct.
+type ContainerdItf interface {
+	CachedContainers() ([]CachedContainer, error)
+	Capabilities() (*Capabilities, error)
+	CaptureTaskOutput(ctx context.Context, ctn containerd.Container, limit int) (*TaskOutput, error)
+	Close() error
+	CollectAll(ctx context.Context) ([]ContainerTaskMetrics, error)
+	ConfigDump() (*ConfigDump, error)
+	Containers() ([]Container, error)
+	ContentSizes() (map[string]int64, error)
+	ContentStatuses() ([]content.Status, error)
+	ContentStoreUsage(ctx context.Context) (*ContentUsage, error)
+	Exec(ctx context.Context, ctn containerd.Container, cmd []string) (*ExecResult, error)
+	GetEvents() containerd.EventService
+	Health() error
+	ImageManifest(ctn containerd.Container) (*ImageManifest, error)
+	ImageSize(ctn containerd.Container) (int64, error)
+	Images() ([]Image, error)
+	Info(ctn containerd.Container) (containers.Container, error)
+	LeaseContent(ctx context.Context, desc ocispec.Descriptor) error
+	LoadContainer(id string) (containerd.Container, error)
+	Metadata() (containerd.Version, error)
+	Namespace() string
+	Namespaces() ([]string, error)
+	NetNSPath(ctx context.Context, ctn containerd.Container) (string, error)
+	Plugins(ctx context.Context) ([]PluginInfo, error)
+	RegistryMirrors() ([]RegistryMirror, error)
+	RestartTask(ctx context.Context, id string) error
+	SandboxStatus(id string) (sandbox.ControllerStatus, error)
+	Sandboxes() ([]sandbox.Sandbox, error)
+	Spec(ctn containerd.Container) (*oci.Spec, error)
+	TaskMetrics(ctn containerd.Container) (*types.Metric, error)
+	TaskPids(ctn containerd.Container) ([]containerd.ProcessInfo, error)
+	TaskPressure(pid uint32) (*TaskPressure, error)
+	TaskProcesses(ctx context.Context, ctn containerd.Container) ([]TaskProcess, error)
+	TaskStatus(ctn containerd.Container) (containerd.Status, error)
+	TaskVMStats(id string, pid uint32) (*TaskVMStats, error)
+	UpperDirSize(ctx context.Context, ctn containerd.Container) (int64, error)
+	VerifyImageContent(ctx context.Context, ctn containerd.Container) (*ImageVerification, error)
+	WalkContainers(ctx context.Context, fn func(CachedContainer) error, filters ...string) error
+	WithLease(ctx context.Context) (context.Context, func(), error)
+}
+
+// ContainerdUtil is the util used to interact with the containerd API.
+type ContainerdUtil struct {
+	// used to setup the ContainerdUtil
+	initRetry retry.Retrier
+	// breaker serializes the connections of the utils of the socket, see
+	// connect_breaker.go
+	breaker *connectBreaker
+
+	log Logger
+	// cl is the client of the daemon, replaced by connect and read with
+	// client. A closed util does not connect again.
+	clMux  sync.RWMutex
+	cl     *containerd.Client
+	closed bool
+
+	socketPath        string
+	namespace         string
+	queryTimeout      time.Duration
+	connectionTimeout time.Duration
+	keepaliveTime     time.Duration
+	configPath        string
+	// stateDir is the state directory of the daemon, see TaskVMStats
+	stateDir string
+	// maxConcurrentQueries bounds the parallel queries of CollectAll
+	maxConcurrentQueries int
+	// taskService is overridden in tests, the service of the client is used if nil
+	taskService tasks.TasksClient
+	// containerService is overridden in tests, see WalkContainers
+	containerService containersapi.ContainersClient
+	// procRoot and cgroupRoot are read for the pressure of the tasks
+	procRoot   string
+	cgroupRoot string
+
+	// background health probe, see health.go
+	healthCheckInterval time.Duration
+	isServing           func(ctx context.Context) (bool, error)
+	probeOnce           sync.Once
+	stopProbe           chan struct{}
+	healthMux           sync.RWMutex
+	healthErr           error
+	// connectErr is the error of the last connection attempt, protected by
+	// healthMux. Its kind is kept while the retrier waits for the next attempt.
+	connectErr error
+
+	// capabilities of the daemon, probed once per connection
+	capsMux sync.Mutex
+	caps    *Capabilities
+
+	// container metadata cache, see container_cache.go
+	cacheMaxStaleness time.Duration
+	cacheOnce         sync.Once
+	cache             *ContainerCache
+
+	// gRPC call log, see grpc_debug.go
+	debugGRPC       bool
+	grpcCallHistory int
+
+	// allowTaskRestart enables RestartTask, see restart.go
+	allowTaskRestart bool
+
+	// scrubber scrubs the specs returned by Spec, see env_scrubber.go
+	scrubber *EnvScrubber
+
+	// calls coalesces the concurrent listings, see coalesce.go
+	calls callGroup
+
+	// stats are published in the expvars, see expvars.go
+	stats utilStats
+
+	// rate limit of the gRPC calls, see rate_limit.go
+	rateLimit      float64
+	rateLimitBurst int
+}
+
+// NewContainerdUtil returns a ContainerdUtil connected to the socket
+// described by opts. Connection failures are retried on the next call
+// to EnsureConnected.
+func NewContainerdUtil(opts Options) (*ContainerdUtil, error) {
+	c := newContainerdUtil(opts.withDefaults())
+	return c, c.EnsureConnected()
+}
+
+// newContainerdUtil returns a ContainerdUtil ready to connect
+func newContainerdUtil(opts Options) *ContainerdUtil {
+	c := &ContainerdUtil{
+		log:               withFields(opts.Logger, "socket", opts.SocketPath, "namespace", opts.Namespace),
+		socketPath:        opts.SocketPath,
+		namespace:         opts.Namespace,
+		queryTimeout:      opts.QueryTimeout,
+		connectionTimeout: opts.ConnectionTimeout,
+		keepaliveTime:     opts.KeepaliveTime,
+		configPath:        opts.ConfigPath,
+		stateDir:          opts.StateDir,
+
+		maxConcurrentQueries: opts.MaxConcurrentQueries,
+		cacheMaxStaleness:    opts.CacheMaxStaleness,
+		procRoot:             opts.ProcRoot,
+		cgroupRoot:           opts.CgroupRoot,
+		debugGRPC:            opts.DebugGRPC,
+		grpcCallHistory:      opts.GRPCCallHistory,
+		allowTaskRestart:     opts.AllowTaskRestart,
+		scrubber:             NewEnvScrubber(opts.EnvScrubPatterns),
+		rateLimit:            opts.RateLimit,
+		rateLimitBurst:       opts.RateLimitBurst,
+
+		healthCheckInterval: opts.HealthCheckInterval,
+		stopProbe:           make(chan struct{}),
+
+		breaker: connectBreakerFor(opts.SocketPath),
+	}
+	c.isServing = func(ctx context.Context) (bool, error) {
+		return c.client().IsServing(ctx)
+	}
+	c.initRetry.SetupRetrier(&retry.Config{
+		Name:          "containerdutil",
+		AttemptMethod: c.connect,
+		Strategy:      retry.RetryCount,
+		RetryCount:    10,
+		RetryDelay:    30 * time.Second,
+	})
+	return c
+}
+
+// EnsureConnected triggers a connection attempt if the client is not
+// connected yet, and returns the retrier error if it is still unavailable.
+// Once connected, the daemon health is probed in the background and the
+// error of the last probe is returned while the util is degraded. A single
+// util of the socket dials at a time, the others fail fast meanwhile.
+func (c *ContainerdUtil) EnsureConnected() error {
+	if err := c.closedErr(); err != nil {
+		return err
+	}
+	if c.willDial() {
+		if err := c.breaker.enter(c.connectionTimeout); err != nil {
+			c.log.Debugf("containerd init error: %s", err)
+			return &retry.Error{RessourceName: "containerdutil", RetryStatus: retry.FailWillRetry, LogicError: err}
+		}
+		defer c.breaker.leave()
+	}
+	if err := c.initRetry.TriggerRetry(); err != nil {
+		c.log.Debugf("containerd init error: %s", err)
+		c.healthMux.RLock()
+		kind := ErrorKind(c.connectErr)
+		c.healthMux.RUnlock()
+		if ErrorKind(err) == nil && kind != nil {
+			err.LogicError = &Error{Kind: kind, Err: err.LogicError}
+		}
+		return err
+	}
+	c.startHealthProbe()
+	return c.Health()
+}
+
+// willDial returns whether the retrier dials on the next TriggerRetry
+func (c *ContainerdUtil) willDial() bool {
+	switch c.initRetry.RetryStatus() {
+	case retry.Idle, retry.FailWillRetry:
+		return !time.Now().Before(c.initRetry.NextRetry())
+	}
+	return false
+}
+
+// socketPreflight returns an actionable error if the socket cannot be used
+// by the agent, it is set on platforms supporting the checks
+var socketPreflight = func(socketPath string) error { return nil }
+
+// connect makes an empty ContainerdUtil bootstrap itself.
+// This is not exposed as public API but is called by the retrier embed.
+func (c *ContainerdUtil) connect() error {
+	start := time.Now()
+	err := c.doConnect()
+	c.recordConnectionAttempt(start, err)
+	c.breaker.record(err)
+	c.healthMux.Lock()
+	c.connectErr = err
+	c.healthMux.Unlock()
+	return err
+}
+
+func (c *ContainerdUtil) doConnect() error {
+	c.clMux.Lock()
+	if c.closed {
+		c.clMux.Unlock()
+		return c.closedErr()
+	}
+	if c.cl != nil {
+		// Previous attempt got a client but failed to validate it
+		c.cl.Close()
+		c.cl = nil
+	}
+	c.clMux.Unlock()
+	// The daemon may have been upgraded since the last connection
+	c.capsMux.Lock()
+	c.caps = nil
+	c.capsMux.Unlock()
+
+	// Setting the dial options replaces the ones of the client, hence the
+	// defaults of the client being repeated before the keepalive parameters
+	dialOpts := []grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithInsecure(),
+		grpc.FailOnNonTempDialError(true),
+		grpc.WithContextDialer(dialContext),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.keepaliveTime,
+			Timeout:             DefaultKeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+	}
+	dialOpts = append(dialOpts, c.stats.dialOptions()...)
+	if c.rateLimit > 0 {
+		dialOpts = append(dialOpts, c.rateLimitInterceptors()...)
+	}
+	if c.debugGRPC {
+		dialOpts = append(dialOpts, c.grpcDebugInterceptors()...)
+	}
+	cl, err := containerd.New(socketAddress(c.socketPath),
+		containerd.WithTimeout(c.connectionTimeout),
+		containerd.WithDialOpts(dialOpts),
+	)
+	if err != nil {
+		// The socket checks explain permission and mount issues better
+		// than the gRPC dial error
+		if preflightErr := socketPreflight(c.socketPath); preflightErr != nil {
+			return preflightErr
+		}
+		// The dial is blocking, a timeout means nothing answers on the socket
+		kind := errorKindOf(err)
+		if kind == nil || kind == ErrTimeout {
+			kind = ErrNotServing
+		}
+		return &Error{Kind: kind, Err: fmt.Errorf("failed to connect to %s: %v", c.socketPath, err)}
+	}
+	c.clMux.Lock()
+	if c.closed {
+		// The util was closed while dialing
+		c.clMux.Unlock()
+		cl.Close()
+		return c.closedErr()
+	}
+	c.cl = cl
+	c.clMux.Unlock()
+
+	// Validating the connection by fetching the version
+	v, err := c.Metadata()
+	if err != nil {
+		return err
+	}
+	c.log.Debugf("Successfully connected to containerd %s %s", v.Version, v.Revision)
+
+	return nil
+}
+
+// queryContext returns a context bound to the util namespace and query timeout
+func (c *ContainerdUtil) queryContext() (context.Context, context.CancelFunc) {
+	ctx := namespaces.WithNamespace(context.Background(), c.namespace)
+	return context.WithTimeout(ctx, c.queryTimeout)
+}
+
+// Close stops the health probe and the container events subscription,
+// and closes the underlying GRPC connection
+func (c *ContainerdUtil) Close() error {
+	c.healthMux.Lock()
+	select {
+	case <-c.stopProbe:
+	default:
+		close(c.stopProbe)
+	}
+	c.healthMux.Unlock()
+
+	c.clMux.Lock()
+	defer c.clMux.Unlock()
+	c.closed = true
+	if c.cl == nil {
+		return nil
+	}
+	return c.cl.Close()
+}
+
+// client returns the client of the daemon, nil until the util connects
+func (c *ContainerdUtil) client() *containerd.Client {
+	c.clMux.RLock()
+	defer c.clMux.RUnlock()
+	return c.cl
+}
+
+// closedErr returns an error if the util was closed, eg. after a change of
+// the containerd endpoint, the callers get a new util from the Provider
+func (c *ContainerdUtil) closedErr() error {
+	c.clMux.RLock()
+	defer c.clMux.RUnlock()
+	if !c.closed {
+		return nil
+	}
+	return &Error{Kind: ErrNotServing, Err: fmt.Errorf("the containerd util of %s (namespace %s) was closed", c.socketPath, c.namespace)}
+}
+
+// Namespace returns the namespace the util is bound to
+func (c *ContainerdUtil) Namespace() string {
+	return c.namespace
+}
+
+// Namespaces lists the namespaces of the daemon, the concurrent calls share
+// the same query
+func (c *ContainerdUtil) Namespaces() ([]string, error) {
+	val, err := c.calls.do(namespacesCall, func() (interface{}, error) {
+		ctx, cancel := c.queryContext()
+		defer cancel()
+		namespaces, err := c.client().NamespaceService().List(ctx)
+		return namespaces, classifyError(err)
+	})
+	namespaces, _ := val.([]string)
+	return append([]string(nil), namespaces...), err
+}
+
+// Metadata returns the version and revision of the containerd daemon, the
+// concurrent calls share the same query
+func (c *ContainerdUtil) Metadata() (containerd.Version, error) {
+	val, err := c.calls.do(versionCall, func() (interface{}, error) {
+		ctx, cancel := c.queryContext()
+		defer cancel()
+		v, err := c.client().Version(ctx)
+		return v, classifyError(err)
+	})
+	v, _ := val.(containerd.Version)
+	return v, err
+}
+
+// LoadContainer returns the container matching the given ID
+func (c *ContainerdUtil) LoadContainer(id string) (containerd.Container, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	ctn, err := c.client().LoadContainer(ctx, id)
+	return ctn, classifyError(err)
+}
+
+// ContentSizes returns the size of the blobs of the content store, by digest
+func (c *ContainerdUtil) ContentSizes() (map[string]int64, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	sizes := make(map[string]int64)
+	err := c.client().ContentStore().Walk(ctx, func(info content.Info) error {
+		sizes[info.Digest.String()] = info.Size
+		return nil
+	})
+	return sizes, classifyError(err)
+}
+
+// ContentStatuses returns the blobs being written to the content store,
+// eg. the layers being downloaded by image pulls
+func (c *ContainerdUtil) ContentStatuses() ([]content.Status, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	statuses, err := c.client().ContentStore().ListStatuses(ctx)
+	return statuses, classifyError(err)
+}
+
+// GetEvents returns the event service of the client
+func (c *ContainerdUtil) GetEvents() containerd.EventService {
+	return c.client().EventService()
+}
+
+// Info returns the metadata stored by containerd for a container
+func (c *ContainerdUtil) Info(ctn containerd.Container) (containers.Container, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	info, err := ctn.Info(ctx)
+	return info, classifyError(err)
+}
+
+// Spec returns the OCI spec of a container
+func (c *ContainerdUtil) Spec(ctn containerd.Container) (*oci.Spec, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	spec, err := ctn.Spec(ctx)
+	return c.scrubber.ScrubSpec(spec), classifyError(err)
+}
+
+// ImageSize returns the size of the image of a container
+func (c *ContainerdUtil) ImageSize(ctn containerd.Container) (int64, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	img, err := ctn.Image(ctx)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	size, err := img.Size(ctx)
+	return size, classifyError(err)
+}
+
+// TaskMetrics returns the raw metrics of the task of a container. It
+// returns an ErrUnsupported error without querying the daemon if it cannot
+// report the metrics of the tasks of a cgroup v2 host.
+func (c *ContainerdUtil) TaskMetrics(ctn containerd.Container) (*types.Metric, error) {
+	if err := c.checkTaskMetricsSupport(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	t, err := ctn.Task(ctx, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	metric, err := t.Metrics(ctx)
+	return metric, classifyError(err)
+}
+
+// TaskPids returns the processes running in the task of a container
+func (c *ContainerdUtil) TaskPids(ctn containerd.Container) ([]containerd.ProcessInfo, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	t, err := ctn.Task(ctx, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	pids, err := t.Pids(ctx)
+	return pids, classifyError(err)
+}
+
+// TaskStatus returns the status of the task of a container, the error is
+// a not found one if the container has no task
+func (c *ContainerdUtil) TaskStatus(ctn containerd.Container) (containerd.Status, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	t, err := ctn.Task(ctx, nil)
+	if err != nil {
+		return containerd.Status{}, classifyError(err)
+	}
+	st, err := t.Status(ctx)
+	return st, classifyError(err)
+}
@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/filters"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/typeurl/v2"
+)
+
+// subscriberBuffer is the number of events queued for a subscriber before
+// the publishers block
+const subscriberBuffer = 1024
+
+// EventService is an in-memory containerd.EventService. The events sent
+// by Send, Play or Publish are passed to the subscriptions whose filters
+// match them, in order. Like the containerd daemon, the events of every
+// namespace are passed to every subscription.
+type EventService struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ctx    context.Context
+	filter filters.Filter
+	ch     chan *events.Envelope
+	errs   chan error
+}
+
+func newEventService() *EventService {
+	return &EventService{subscribers: make(map[*subscriber]struct{})}
+}
+
+// ScriptedEvent is an event sent by EventService.Play
+type ScriptedEvent struct {
+	// Delay is the wait before sending the event
+	Delay     time.Duration
+	Namespace string
+	Topic     string
+	// Event is the containerd event, eg. a *events.TaskExit of the
+	// github.com/containerd/containerd/api/events package
+	Event interface{}
+	// Timestamp defaults to the time the event is sent
+	Timestamp time.Time
+}
+
+// Subscribe implements events.Subscriber. The subscription ends when ctx is
+// done or when Fail is called.
+func (s *EventService) Subscribe(ctx context.Context, fs ...string) (<-chan *events.Envelope, <-chan error) {
+	sub := &subscriber{
+		ctx:  ctx,
+		ch:   make(chan *events.Envelope, subscriberBuffer),
+		errs: make(chan error, 1),
+	}
+	filter, err := filters.ParseAll(fs...)
+	if err != nil {
+		sub.errs <- err
+		return sub.ch, sub.errs
+	}
+	sub.filter = filter
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		s.unsubscribe(sub, ctx.Err())
+	}()
+	return sub.ch, sub.errs
+}
+
+// unsubscribe ends a subscription with err, unless it already ended
+func (s *EventService) unsubscribe(sub *subscriber, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.subscribers[sub]; !found {
+		return
+	}
+	delete(s.subscribers, sub)
+	sub.errs <- err
+}
+
+// Subscribers returns the number of active subscriptions, to wait for a
+// consumer to subscribe before sending events
+func (s *EventService) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// WaitForSubscribers waits until there are at least n active
+// subscriptions, it returns false if the timeout expires first
+func (s *EventService) WaitForSubscribers(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for s.Subscribers() < n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// Fail ends every active subscription with err, as if the connection to
+// the daemon broke
+func (s *EventService) Fail(err error) {
+	s.mu.Lock()
+	subs := make([]*subscriber, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		subs = append(subs, sub)
+	}
+	s.mu.Unlock()
+	for _, sub := range subs {
+		s.unsubscribe(sub, err)
+	}
+}
+
+// Send marshals a containerd event and forwards it to the subscriptions
+func (s *EventService) Send(namespace, topic string, event interface{}) error {
+	return s.send(ScriptedEvent{Namespace: namespace, Topic: topic, Event: event})
+}
+
+// Play sends the events of a script in order, waiting for their delay
+// before each of them. It stops at the first event that cannot be
+// marshaled.
+func (s *EventService) Play(script []ScriptedEvent) error {
+	for _, ev := range script {
+		if ev.Delay > 0 {
+			time.Sleep(ev.Delay)
+		}
+		if err := s.send(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *EventService) send(ev ScriptedEvent) error {
+	any, err := typeurl.MarshalAny(ev.Event)
+	if err != nil {
+		return err
+	}
+	ts := ev.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return s.Forward(context.Background(), &events.Envelope{
+		Timestamp: ts,
+		Namespace: ev.Namespace,
+		Topic:     ev.Topic,
+		Event:     any,
+	})
+}
+
+// Publish implements events.Publisher, the event is sent in the namespace
+// of ctx
+func (s *EventService) Publish(ctx context.Context, topic string, event events.Event) error {
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Send(ns, topic, event)
+}
+
+// Forward implements events.Forwarder, the envelope is passed to the
+// matching subscriptions
+func (s *EventService) Forward(ctx context.Context, envelope *events.Envelope) error {
+	s.mu.Lock()
+	var subs []*subscriber
+	for sub := range s.subscribers {
+		if sub.filter.Match(envelope) {
+			subs = append(subs, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- envelope:
+		case <-sub.ctx.Done():
+		}
+	}
+	return nil
+}
@@ -0,0 +1,876 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+// Package containerdtest provides an in-memory containerd daemon to test
+// the consumers of containerd.ContainerdItf, like the checks and the tagger
+// collectors, without a containerd socket.
+package containerdtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/sandbox"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+// Container is a container of a Daemon. Its fields are read by the methods
+// of ContainerdItf taking the container, they can be left empty.
+type Container struct {
+	// The methods of containerd.Container other than ID, Info and Labels
+	// are not implemented
+	containerdclient.Container
+
+	Record       containers.Container
+	OCISpec      *oci.Spec
+	ImageSize    int64
+	Manifest     *containerd.ImageManifest
+	Verification *containerd.ImageVerification
+	// UpperDirSize is the disk usage of the writable layer of the
+	// container, measured if its Record has a snapshot key
+	UpperDirSize int64
+	// TaskState is nil if the container has no task
+	TaskState *Task
+}
+
+// ID implements containerd.Container
+func (c *Container) ID() string {
+	return c.Record.ID
+}
+
+// Info implements containerd.Container
+func (c *Container) Info(ctx context.Context, opts ...containerdclient.InfoOpts) (containers.Container, error) {
+	return c.Record, nil
+}
+
+// Labels implements containerd.Container
+func (c *Container) Labels(ctx context.Context) (map[string]string, error) {
+	return c.Record.Labels, nil
+}
+
+// snapshot copies the container and its task, the daemon must be locked
+func (c *Container) snapshot() *Container {
+	snapshot := *c
+	if c.TaskState != nil {
+		task := *c.TaskState
+		snapshot.TaskState = &task
+	}
+	return &snapshot
+}
+
+// Task is the task of a Container
+type Task struct {
+	Pid    uint32
+	Status containerdclient.Status
+	// StartedAt is set by StartTask if it is zero
+	StartedAt time.Time
+	// Pids defaults to the Pid of the task
+	Pids      []containerdclient.ProcessInfo
+	Processes []containerd.TaskProcess
+	Metrics   *types.Metric
+	Pressure  *containerd.TaskPressure
+	// VMStats is nil for the tasks not run in virtual machines
+	VMStats *containerd.TaskVMStats
+	// Exec runs the commands exec'd in the task, the exec is not
+	// supported if nil
+	Exec func(cmd []string) (*containerd.ExecResult, error)
+	// Output is written by the task when its output is captured, the
+	// capture is not supported if empty
+	Output string
+}
+
+// running returns whether the task is running, paused tasks included
+func (t *Task) running() bool {
+	return t != nil && (t.Status.Status == containerdclient.Running || t.Status.Status == containerdclient.Paused)
+}
+
+// Daemon is an in-memory containerd daemon. The Utils bound to its
+// namespaces implement containerd.ContainerdItf. The exported fields are
+// read by the Utils, they are set before using them. The containers are
+// changed with the methods of Daemon, which send the matching events.
+type Daemon struct {
+	Version         containerdclient.Version
+	Capabilities    *containerd.Capabilities
+	ConfigDump      *containerd.ConfigDump
+	RegistryMirrors []containerd.RegistryMirror
+	ContentSizes    map[string]int64
+	ContentStatuses []content.Status
+	// ProcRoot is the proc root the network namespaces are resolved
+	// under, see NetNSPath
+	ProcRoot string
+
+	events *EventService
+
+	mu         sync.RWMutex
+	healthErr  error
+	namespaces map[string]*namespace
+	// leases holds the content of the leases not released, by lease ID
+	leases  map[string][]string
+	leaseID int
+}
+
+type namespace struct {
+	containers map[string]*Container
+	sandboxes  map[string]sandbox.Sandbox
+	statuses   map[string]sandbox.ControllerStatus
+	images     map[string]containerd.Image
+	// content holds the size of the blobs by digest
+	content map[string]int64
+}
+
+// NewDaemon returns a Daemon without namespaces
+func NewDaemon() *Daemon {
+	return &Daemon{
+		Version:    containerdclient.Version{Version: "v1.7.0", Revision: "containerdtest"},
+		events:     newEventService(),
+		namespaces: make(map[string]*namespace),
+	}
+}
+
+// Events returns the event service of the daemon, to send events and
+// break the subscriptions
+func (d *Daemon) Events() *EventService {
+	return d.events
+}
+
+// Util returns a containerd.ContainerdItf bound to a namespace of the daemon
+func (d *Daemon) Util(ns string) *Util {
+	return &Util{daemon: d, namespace: ns}
+}
+
+// SetHealth sets the error returned by Health, nil if the daemon is healthy
+func (d *Daemon) SetHealth(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.healthErr = err
+}
+
+// AddNamespace creates an empty namespace, and sends a namespace create
+// event if it did not exist. Like with containerd, the namespaces created
+// along with their first container send no event.
+func (d *Daemon) AddNamespace(ns string) {
+	d.mu.Lock()
+	_, found := d.namespaces[ns]
+	d.namespaceLocked(ns)
+	d.mu.Unlock()
+	if !found {
+		d.events.Send(ns, containerd.NamespaceCreateTopic, &apievents.NamespaceCreate{Name: ns})
+	}
+}
+
+// DeleteNamespace deletes a namespace with its resources and sends a
+// namespace delete event
+func (d *Daemon) DeleteNamespace(ns string) error {
+	d.mu.Lock()
+	_, found := d.namespaces[ns]
+	delete(d.namespaces, ns)
+	d.mu.Unlock()
+	if !found {
+		return notFound("namespace %q", ns)
+	}
+	return d.events.Send(ns, containerd.NamespaceDeleteTopic, &apievents.NamespaceDelete{Name: ns})
+}
+
+// namespaceLocked returns a namespace, creating it if needed. The daemon
+// must be write locked.
+func (d *Daemon) namespaceLocked(ns string) *namespace {
+	n, found := d.namespaces[ns]
+	if !found {
+		n = &namespace{
+			containers: make(map[string]*Container),
+			sandboxes:  make(map[string]sandbox.Sandbox),
+			statuses:   make(map[string]sandbox.ControllerStatus),
+			images:     make(map[string]containerd.Image),
+			content:    make(map[string]int64),
+		}
+		d.namespaces[ns] = n
+	}
+	return n
+}
+
+// AddContainer adds a container to a namespace, created now if its
+// creation time is not set, and sends a container create event. The
+// container is started if it has a running task.
+func (d *Daemon) AddContainer(ns string, ctn *Container) error {
+	if ctn.Record.CreatedAt.IsZero() {
+		ctn.Record.CreatedAt = time.Now()
+	}
+	task := ctn.TaskState
+	ctn.TaskState = nil
+
+	d.mu.Lock()
+	d.namespaceLocked(ns).containers[ctn.ID()] = ctn
+	d.mu.Unlock()
+
+	err := d.events.send(ScriptedEvent{
+		Namespace: ns,
+		Topic:     containerd.ContainerCreateTopic,
+		Event: &apievents.ContainerCreate{
+			ID:      ctn.ID(),
+			Image:   ctn.Record.Image,
+			Runtime: &apievents.ContainerCreate_Runtime{Name: ctn.Record.Runtime.Name},
+		},
+		Timestamp: ctn.Record.CreatedAt,
+	})
+	if err != nil || task == nil {
+		return err
+	}
+	return d.StartTask(ns, ctn.ID(), task)
+}
+
+// StartTask sets the running task of a container and sends a task start event
+func (d *Daemon) StartTask(ns, id string, task *Task) error {
+	task.Status = containerdclient.Status{Status: containerdclient.Running}
+	if task.StartedAt.IsZero() {
+		task.StartedAt = time.Now()
+	}
+	if err := d.updateContainer(ns, id, func(ctn *Container) { ctn.TaskState = task }); err != nil {
+		return err
+	}
+	return d.events.Send(ns, containerd.TaskStartTopic, &apievents.TaskStart{ContainerID: id, Pid: task.Pid})
+}
+
+// ExitTask stops the task of a container and sends a task exit event
+func (d *Daemon) ExitTask(ns, id string, exitStatus uint32) error {
+	exitedAt := time.Now()
+	var pid uint32
+	err := d.updateContainer(ns, id, func(ctn *Container) {
+		if ctn.TaskState == nil {
+			return
+		}
+		pid = ctn.TaskState.Pid
+		ctn.TaskState.Status = containerdclient.Status{
+			Status:     containerdclient.Stopped,
+			ExitStatus: exitStatus,
+			ExitTime:   exitedAt,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return d.events.send(ScriptedEvent{
+		Namespace: ns,
+		Topic:     containerd.TaskExitTopic,
+		Event: &apievents.TaskExit{
+			ContainerID: id,
+			ID:          id,
+			Pid:         pid,
+			ExitStatus:  exitStatus,
+			ExitedAt:    timestamppb.New(exitedAt),
+		},
+		Timestamp: exitedAt,
+	})
+}
+
+// SetLabels replaces the labels of a container and sends a container update
+// event, like ctr containers label
+func (d *Daemon) SetLabels(ns, id string, labels map[string]string) error {
+	var image string
+	err := d.updateContainer(ns, id, func(ctn *Container) {
+		ctn.Record.Labels = labels
+		ctn.Record.UpdatedAt = time.Now()
+		image = ctn.Record.Image
+	})
+	if err != nil {
+		return err
+	}
+	return d.events.Send(ns, containerd.ContainerUpdateTopic, &apievents.ContainerUpdate{ID: id, Image: image, Labels: labels})
+}
+
+// DeleteContainer removes a container and sends a container delete event
+func (d *Daemon) DeleteContainer(ns, id string) error {
+	d.mu.Lock()
+	n, found := d.namespaces[ns]
+	if found {
+		_, found = n.containers[id]
+		delete(n.containers, id)
+	}
+	d.mu.Unlock()
+	if !found {
+		return notFound("container %q in namespace %q", id, ns)
+	}
+	return d.events.Send(ns, containerd.ContainerDeleteTopic, &apievents.ContainerDelete{ID: id})
+}
+
+// AddSandbox adds a pod sandbox to a namespace, with its controller status
+func (d *Daemon) AddSandbox(ns string, sb sandbox.Sandbox, status sandbox.ControllerStatus) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := d.namespaceLocked(ns)
+	n.sandboxes[sb.ID] = sb
+	n.statuses[sb.ID] = status
+}
+
+// AddImage adds an image to the image store of a namespace, replacing the
+// image of the same name
+func (d *Daemon) AddImage(ns string, img containerd.Image) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.namespaceLocked(ns).images[img.Name] = img
+}
+
+// AddContent adds a blob to the content store of a namespace
+func (d *Daemon) AddContent(ns, digest string, size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.namespaceLocked(ns).content[digest] = size
+}
+
+func (d *Daemon) updateContainer(ns, id string, update func(*Container)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, found := d.namespaces[ns]
+	if !found {
+		return notFound("container %q in namespace %q", id, ns)
+	}
+	ctn, found := n.containers[id]
+	if !found {
+		return notFound("container %q in namespace %q", id, ns)
+	}
+	update(ctn)
+	return nil
+}
+
+// notFound returns an error matching errdefs.IsNotFound, like the ones of
+// the containerd client
+func notFound(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), errdefs.ErrNotFound)
+}
+
+// unsupported returns an ErrUnsupported error, for the features the daemon
+// is not set up with
+func unsupported(feature string) error {
+	return &containerd.Error{Kind: containerd.ErrUnsupported, Err: fmt.Errorf("%s is not set up in the containerdtest daemon", feature)}
+}
+
+// Util is a containerd.ContainerdItf bound to a namespace of a Daemon
+type Util struct {
+	daemon    *Daemon
+	namespace string
+}
+
+var _ containerd.ContainerdItf = &Util{}
+
+// container returns a snapshot of a container of the namespace of the util
+func (u *Util) container(id string) (*Container, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	if n, found := u.daemon.namespaces[u.namespace]; found {
+		if ctn, found := n.containers[id]; found {
+			return ctn.snapshot(), nil
+		}
+	}
+	return nil, notFound("container %q in namespace %q", id, u.namespace)
+}
+
+// containers returns snapshots of the containers of the namespace of the
+// util, sorted by ID
+func (u *Util) containers() []*Container {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	n, found := u.daemon.namespaces[u.namespace]
+	if !found {
+		return nil
+	}
+	ctns := make([]*Container, 0, len(n.containers))
+	for _, ctn := range n.containers {
+		ctns = append(ctns, ctn.snapshot())
+	}
+	sort.Slice(ctns, func(i, j int) bool { return ctns[i].ID() < ctns[j].ID() })
+	return ctns
+}
+
+// task returns the task of a container, a not found error if it has none
+func (u *Util) task(ctn containerdclient.Container) (*Task, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return nil, err
+	}
+	if c.TaskState == nil {
+		return nil, notFound("task of container %q", c.ID())
+	}
+	return c.TaskState, nil
+}
+
+// CachedContainers implements containerd.ContainerdItf
+func (u *Util) CachedContainers() ([]containerd.CachedContainer, error) {
+	var cached []containerd.CachedContainer
+	for _, ctn := range u.containers() {
+		var annotations map[string]string
+		if ctn.OCISpec != nil {
+			annotations = ctn.OCISpec.Annotations
+		}
+		limits := containerd.SpecResourceLimits(ctn.OCISpec)
+		cached = append(cached, containerd.CachedContainer{
+			ID:        ctn.Record.ID,
+			Name:      containerd.ContainerNameResolver().Resolve(ctn.Record.ID, ctn.Record.Labels, annotations),
+			Image:     ctn.Record.Image,
+			Labels:    ctn.Record.Labels,
+			CreatedAt: ctn.Record.CreatedAt,
+			SandboxID: ctn.Record.SandboxID,
+
+			RuntimeHandler: containerd.RuntimeHandler(ctn.Record.Runtime.Name),
+			Limits:         limits,
+		})
+	}
+	return cached, nil
+}
+
+// Capabilities implements containerd.ContainerdItf, they default to the
+// ones of a containerd 1.7 daemon
+func (u *Util) Capabilities() (*containerd.Capabilities, error) {
+	if u.daemon.Capabilities != nil {
+		return u.daemon.Capabilities, nil
+	}
+	return &containerd.Capabilities{
+		Version:         u.daemon.Version.Version,
+		TaskMetricsV2:   true,
+		SandboxAPI:      true,
+		TransferService: true,
+	}, nil
+}
+
+// CaptureTaskOutput implements containerd.ContainerdItf, the task must be
+// running
+func (u *Util) CaptureTaskOutput(ctx context.Context, ctn containerdclient.Container, limit int) (*containerd.TaskOutput, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return nil, err
+	}
+	if !task.running() {
+		return nil, fmt.Errorf("task of container %q is not running: %w", ctn.ID(), errdefs.ErrFailedPrecondition)
+	}
+	if task.Output == "" {
+		return nil, unsupported("output capture")
+	}
+	if len(task.Output) > limit {
+		return &containerd.TaskOutput{Output: task.Output[:limit], Truncated: true}, nil
+	}
+	return &containerd.TaskOutput{Output: task.Output}, nil
+}
+
+// Close implements containerd.ContainerdItf
+func (u *Util) Close() error {
+	return nil
+}
+
+// CollectAll implements containerd.ContainerdItf, the running tasks
+// without metrics are skipped
+func (u *Util) CollectAll(ctx context.Context) ([]containerd.ContainerTaskMetrics, error) {
+	var all []containerd.ContainerTaskMetrics
+	for _, ctn := range u.containers() {
+		task := ctn.TaskState
+		if !task.running() || task.Metrics == nil {
+			continue
+		}
+		all = append(all, containerd.ContainerTaskMetrics{
+			ContainerID: ctn.ID(),
+			Pid:         task.Pid,
+			Metrics:     task.Metrics,
+		})
+	}
+	return all, nil
+}
+
+// ConfigDump implements containerd.ContainerdItf
+func (u *Util) ConfigDump() (*containerd.ConfigDump, error) {
+	if u.daemon.ConfigDump == nil {
+		return nil, unsupported("the introspection service")
+	}
+	return u.daemon.ConfigDump, nil
+}
+
+// Containers implements containerd.ContainerdItf, the start time of the
+// running and paused tasks is the one of their Task
+func (u *Util) Containers() ([]containerd.Container, error) {
+	var ctns []containerd.Container
+	for _, ctn := range u.containers() {
+		var startedAt time.Time
+		if ctn.TaskState.running() {
+			startedAt = ctn.TaskState.StartedAt
+		}
+		ctns = append(ctns, containerd.NewContainer(ctn, ctn.Record.CreatedAt, startedAt))
+	}
+	return ctns, nil
+}
+
+// ContentSizes implements containerd.ContainerdItf
+func (u *Util) ContentSizes() (map[string]int64, error) {
+	return u.daemon.ContentSizes, nil
+}
+
+// ContentStatuses implements containerd.ContainerdItf
+func (u *Util) ContentStatuses() ([]content.Status, error) {
+	return u.daemon.ContentStatuses, nil
+}
+
+// ContentStoreUsage implements containerd.ContainerdItf
+func (u *Util) ContentStoreUsage(ctx context.Context) (*containerd.ContentUsage, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	usage := containerd.NewContentUsage()
+	seen := make(map[string]struct{})
+	for ns, n := range u.daemon.namespaces {
+		for digest, size := range n.content {
+			usage.Add(ns, digest, size, seen)
+		}
+	}
+	return usage, nil
+}
+
+// Exec implements containerd.ContainerdItf, the task must be running
+func (u *Util) Exec(ctx context.Context, ctn containerdclient.Container, cmd []string) (*containerd.ExecResult, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return nil, err
+	}
+	if !task.running() {
+		return nil, fmt.Errorf("task of container %q is not running: %w", ctn.ID(), errdefs.ErrFailedPrecondition)
+	}
+	if task.Exec == nil {
+		return nil, unsupported("exec")
+	}
+	return task.Exec(cmd)
+}
+
+// GetEvents implements containerd.ContainerdItf
+func (u *Util) GetEvents() containerdclient.EventService {
+	return u.daemon.events
+}
+
+// Health implements containerd.ContainerdItf
+func (u *Util) Health() error {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	return u.daemon.healthErr
+}
+
+// ImageManifest implements containerd.ContainerdItf
+func (u *Util) ImageManifest(ctn containerdclient.Container) (*containerd.ImageManifest, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return nil, err
+	}
+	if c.Manifest == nil {
+		return nil, notFound("image %q", c.Record.Image)
+	}
+	return c.Manifest, nil
+}
+
+// ImageSize implements containerd.ContainerdItf
+func (u *Util) ImageSize(ctn containerdclient.Container) (int64, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return 0, err
+	}
+	return c.ImageSize, nil
+}
+
+// Images implements containerd.ContainerdItf
+func (u *Util) Images() ([]containerd.Image, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	n, found := u.daemon.namespaces[u.namespace]
+	if !found {
+		return nil, nil
+	}
+	images := make([]containerd.Image, 0, len(n.images))
+	for _, img := range n.images {
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Name < images[j].Name })
+	return images, nil
+}
+
+// Leases returns the digests of the content of the leases not released
+// yet, by lease ID
+func (d *Daemon) Leases() map[string][]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	result := make(map[string][]string, len(d.leases))
+	for id, digests := range d.leases {
+		result[id] = append([]string(nil), digests...)
+	}
+	return result
+}
+
+// Info implements containerd.ContainerdItf
+func (u *Util) Info(ctn containerdclient.Container) (containers.Container, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return containers.Container{}, err
+	}
+	return c.Record, nil
+}
+
+// LeaseContent implements containerd.ContainerdItf
+func (u *Util) LeaseContent(ctx context.Context, desc ocispec.Descriptor) error {
+	id, found := leases.FromContext(ctx)
+	if !found {
+		return fmt.Errorf("the context holds no lease")
+	}
+	u.daemon.mu.Lock()
+	defer u.daemon.mu.Unlock()
+	if _, found := u.daemon.leases[id]; !found {
+		return notFound("lease %q", id)
+	}
+	u.daemon.leases[id] = append(u.daemon.leases[id], desc.Digest.String())
+	return nil
+}
+
+// LoadContainer implements containerd.ContainerdItf
+func (u *Util) LoadContainer(id string) (containerdclient.Container, error) {
+	c, err := u.container(id)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Metadata implements containerd.ContainerdItf
+func (u *Util) Metadata() (containerdclient.Version, error) {
+	return u.daemon.Version, nil
+}
+
+// Namespace implements containerd.ContainerdItf
+func (u *Util) Namespace() string {
+	return u.namespace
+}
+
+// Namespaces implements containerd.ContainerdItf
+func (u *Util) Namespaces() ([]string, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	namespaces := make([]string, 0, len(u.daemon.namespaces))
+	for ns := range u.daemon.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// NetNSPath implements containerd.ContainerdItf, the namespace of the
+// process of a running task is resolved under the ProcRoot of the Daemon
+func (u *Util) NetNSPath(ctx context.Context, ctn containerdclient.Container) (string, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return "", err
+	}
+	var pid uint32
+	if c.TaskState.running() {
+		pid = c.TaskState.Pid
+	}
+	return containerd.ResolveNetNSPath(c.OCISpec, pid, u.daemon.ProcRoot)
+}
+
+// Plugins implements containerd.ContainerdItf, the plugins are the ones of
+// the ConfigDump of the Daemon
+func (u *Util) Plugins(ctx context.Context) ([]containerd.PluginInfo, error) {
+	if u.daemon.ConfigDump == nil {
+		return nil, unsupported("the introspection service")
+	}
+	return u.daemon.ConfigDump.Plugins, nil
+}
+
+// RegistryMirrors implements containerd.ContainerdItf
+func (u *Util) RegistryMirrors() ([]containerd.RegistryMirror, error) {
+	return u.daemon.RegistryMirrors, nil
+}
+
+// RestartTask implements containerd.ContainerdItf, the task exits with
+// the status of a SIGTERM and is started again, its other fields are kept
+func (u *Util) RestartTask(ctx context.Context, id string) error {
+	ctn, err := u.container(id)
+	if err != nil {
+		return err
+	}
+	if ctn.TaskState == nil {
+		return notFound("task of container %q", id)
+	}
+	if err := u.daemon.ExitTask(u.namespace, id, 143); err != nil {
+		return err
+	}
+	restarted := *ctn.TaskState
+	restarted.StartedAt = time.Time{}
+	return u.daemon.StartTask(u.namespace, id, &restarted)
+}
+
+// SandboxStatus implements containerd.ContainerdItf
+func (u *Util) SandboxStatus(id string) (sandbox.ControllerStatus, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	if n, found := u.daemon.namespaces[u.namespace]; found {
+		if status, found := n.statuses[id]; found {
+			return status, nil
+		}
+	}
+	return sandbox.ControllerStatus{}, notFound("sandbox %q in namespace %q", id, u.namespace)
+}
+
+// Sandboxes implements containerd.ContainerdItf
+func (u *Util) Sandboxes() ([]sandbox.Sandbox, error) {
+	u.daemon.mu.RLock()
+	defer u.daemon.mu.RUnlock()
+	n, found := u.daemon.namespaces[u.namespace]
+	if !found {
+		return nil, nil
+	}
+	sandboxes := make([]sandbox.Sandbox, 0, len(n.sandboxes))
+	for _, sb := range n.sandboxes {
+		sandboxes = append(sandboxes, sb)
+	}
+	sort.Slice(sandboxes, func(i, j int) bool { return sandboxes[i].ID < sandboxes[j].ID })
+	return sandboxes, nil
+}
+
+// Spec implements containerd.ContainerdItf
+func (u *Util) Spec(ctn containerdclient.Container) (*oci.Spec, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return nil, err
+	}
+	if c.OCISpec == nil {
+		return &oci.Spec{}, nil
+	}
+	return c.OCISpec, nil
+}
+
+// TaskMetrics implements containerd.ContainerdItf
+func (u *Util) TaskMetrics(ctn containerdclient.Container) (*types.Metric, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return nil, err
+	}
+	if task.Metrics == nil {
+		return nil, notFound("metrics of the task of container %q", ctn.ID())
+	}
+	return task.Metrics, nil
+}
+
+// TaskPids implements containerd.ContainerdItf
+func (u *Util) TaskPids(ctn containerdclient.Container) ([]containerdclient.ProcessInfo, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return nil, err
+	}
+	if task.Pids == nil {
+		return []containerdclient.ProcessInfo{{Pid: task.Pid}}, nil
+	}
+	return task.Pids, nil
+}
+
+// TaskPressure implements containerd.ContainerdItf, the pid is matched
+// against the pid of the tasks
+func (u *Util) TaskPressure(pid uint32) (*containerd.TaskPressure, error) {
+	for _, ctn := range u.containers() {
+		if ctn.TaskState != nil && ctn.TaskState.Pid == pid {
+			if ctn.TaskState.Pressure == nil {
+				return nil, unsupported("the pressure stall information")
+			}
+			return ctn.TaskState.Pressure, nil
+		}
+	}
+	return nil, notFound("task of pid %d", pid)
+}
+
+// TaskProcesses implements containerd.ContainerdItf
+func (u *Util) TaskProcesses(ctx context.Context, ctn containerdclient.Container) ([]containerd.TaskProcess, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return nil, err
+	}
+	return task.Processes, nil
+}
+
+// TaskStatus implements containerd.ContainerdItf
+func (u *Util) TaskStatus(ctn containerdclient.Container) (containerdclient.Status, error) {
+	task, err := u.task(ctn)
+	if err != nil {
+		return containerdclient.Status{}, err
+	}
+	return task.Status, nil
+}
+
+// TaskVMStats implements containerd.ContainerdItf
+func (u *Util) TaskVMStats(id string, pid uint32) (*containerd.TaskVMStats, error) {
+	ctn, err := u.container(id)
+	if err != nil {
+		return nil, err
+	}
+	if ctn.TaskState == nil || ctn.TaskState.VMStats == nil {
+		return nil, notFound("VM of the task of container %q", id)
+	}
+	return ctn.TaskState.VMStats, nil
+}
+
+// UpperDirSize implements containerd.ContainerdItf
+func (u *Util) UpperDirSize(ctx context.Context, ctn containerdclient.Container) (int64, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return 0, err
+	}
+	if c.Record.SnapshotKey == "" {
+		return 0, unsupported("the snapshot of the container")
+	}
+	return c.UpperDirSize, nil
+}
+
+// VerifyImageContent implements containerd.ContainerdItf
+func (u *Util) VerifyImageContent(ctx context.Context, ctn containerdclient.Container) (*containerd.ImageVerification, error) {
+	c, err := u.container(ctn.ID())
+	if err != nil {
+		return nil, err
+	}
+	if c.Verification == nil {
+		return nil, unsupported("the verification of image contents")
+	}
+	return c.Verification, nil
+}
+
+// WalkContainers implements containerd.ContainerdItf, the filters are
+// applied like containerd.WalkCachedContainers does
+func (u *Util) WalkContainers(ctx context.Context, fn func(containerd.CachedContainer) error, filters ...string) error {
+	ctns, err := u.CachedContainers()
+	if err != nil {
+		return err
+	}
+	return containerd.WalkCachedContainers(ctns, fn, filters...)
+}
+
+// WithLease implements containerd.ContainerdItf
+func (u *Util) WithLease(ctx context.Context) (context.Context, func(), error) {
+	if _, found := leases.FromContext(ctx); found {
+		return ctx, func() {}, nil
+	}
+	u.daemon.mu.Lock()
+	defer u.daemon.mu.Unlock()
+	if u.daemon.leases == nil {
+		u.daemon.leases = make(map[string][]string)
+	}
+	u.daemon.leaseID++
+	id := fmt.Sprintf("lease-%d", u.daemon.leaseID)
+	u.daemon.leases[id] = nil
+	return leases.WithLease(ctx, id), func() {
+		u.daemon.mu.Lock()
+		defer u.daemon.mu.Unlock()
+		delete(u.daemon.leases, id)
+	}, nil
+}
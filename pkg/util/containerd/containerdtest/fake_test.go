@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerdtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	containerdclient "github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func receive(t *testing.T, ch <-chan *events.Envelope) *events.Envelope {
+	select {
+	case envelope := <-ch:
+		return envelope
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+	}
+	return nil
+}
+
+func TestDaemonContainers(t *testing.T) {
+	d := NewDaemon()
+	d.AddNamespace("default")
+	cu := d.Util("k8s.io")
+
+	require.NoError(t, d.AddContainer("k8s.io", &Container{
+		Record: containers.Container{ID: "redis", Image: "redis:5", Labels: map[string]string{"app": "redis"}},
+		TaskState: &Task{
+			Pid:     42,
+			Metrics: &types.Metric{ID: "redis"},
+		},
+	}))
+	require.NoError(t, d.AddContainer("k8s.io", &Container{Record: containers.Container{ID: "nginx"}}))
+
+	namespaces, err := cu.Namespaces()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "k8s.io"}, namespaces)
+
+	ctns, err := cu.Containers()
+	require.NoError(t, err)
+	require.Len(t, ctns, 2)
+	assert.Equal(t, "nginx", ctns[0].ID())
+	assert.Equal(t, "redis", ctns[1].ID())
+
+	labels, err := ctns[1].Labels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "redis"}, labels)
+
+	status, err := cu.TaskStatus(ctns[1])
+	require.NoError(t, err)
+	assert.Equal(t, containerdclient.Running, status.Status)
+	_, err = cu.TaskStatus(ctns[0])
+	assert.True(t, errdefs.IsNotFound(err))
+
+	all, err := cu.CollectAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []containerd.ContainerTaskMetrics{{ContainerID: "redis", Pid: 42, Metrics: &types.Metric{ID: "redis"}}}, all)
+
+	_, err = cu.Exec(context.Background(), ctns[1], []string{"redis-cli", "ping"})
+	assert.Equal(t, containerd.ErrUnsupported, containerd.ErrorKind(err))
+	_, err = cu.CaptureTaskOutput(context.Background(), ctns[1], 16)
+	assert.Equal(t, containerd.ErrUnsupported, containerd.ErrorKind(err))
+	require.NoError(t, d.AddContainer("k8s.io", &Container{
+		Record:    containers.Container{ID: "sidecar"},
+		TaskState: &Task{Pid: 43, Output: "Ready to accept connections\n"},
+	}))
+	sidecar, err := cu.LoadContainer("sidecar")
+	require.NoError(t, err)
+	output, err := cu.CaptureTaskOutput(context.Background(), sidecar, 16)
+	require.NoError(t, err)
+	assert.Equal(t, &containerd.TaskOutput{Output: "Ready to accept ", Truncated: true}, output)
+
+	require.NoError(t, d.ExitTask("k8s.io", "redis", 137))
+	_, err = cu.Exec(context.Background(), ctns[1], []string{"redis-cli", "ping"})
+	assert.True(t, errdefs.IsFailedPrecondition(err))
+	status, err = cu.TaskStatus(ctns[1])
+	require.NoError(t, err)
+	assert.Equal(t, containerdclient.Stopped, status.Status)
+	assert.Equal(t, uint32(137), status.ExitStatus)
+
+	require.NoError(t, d.DeleteContainer("k8s.io", "redis"))
+	_, err = cu.LoadContainer("redis")
+	assert.True(t, errdefs.IsNotFound(err))
+	assert.True(t, errdefs.IsNotFound(d.DeleteContainer("k8s.io", "redis")))
+
+	_, err = cu.ConfigDump()
+	assert.Equal(t, containerd.ErrUnsupported, containerd.ErrorKind(err))
+}
+
+func TestDaemonEvents(t *testing.T) {
+	d := NewDaemon()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, errs := d.Util("default").GetEvents().Subscribe(ctx, `topic=="`+containerd.TaskExitTopic+`"`)
+	require.True(t, d.Events().WaitForSubscribers(1, time.Second))
+
+	require.NoError(t, d.AddContainer("default", &Container{
+		Record:    containers.Container{ID: "redis"},
+		TaskState: &Task{Pid: 42},
+	}))
+	require.NoError(t, d.ExitTask("default", "redis", 1))
+	envelope := receive(t, ch)
+	assert.Equal(t, "default", envelope.Namespace)
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	require.NoError(t, err)
+	assert.Equal(t, "redis", ev.(*apievents.TaskExit).ContainerID)
+	assert.Equal(t, uint32(42), ev.(*apievents.TaskExit).Pid)
+
+	ts := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, d.Events().Play([]ScriptedEvent{
+		{Namespace: "k8s.io", Topic: containerd.TaskStartTopic, Event: &apievents.TaskStart{ContainerID: "web"}},
+		{Delay: time.Millisecond, Namespace: "k8s.io", Topic: containerd.TaskExitTopic, Event: &apievents.TaskExit{ContainerID: "web"}, Timestamp: ts},
+	}))
+	envelope = receive(t, ch)
+	assert.Equal(t, "k8s.io", envelope.Namespace)
+	assert.Equal(t, ts, envelope.Timestamp)
+
+	broken := errors.New("connection reset by peer")
+	d.Events().Fail(broken)
+	assert.Equal(t, broken, <-errs)
+	assert.Equal(t, 0, d.Events().Subscribers())
+}
+
+func TestDaemonNamespaceEvents(t *testing.T) {
+	d := NewDaemon()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	filter := containerd.NewNamespaceFilter([]string{"k8s.io", "team-*"}, []string{"team-build"})
+	ch, errs := d.Util("k8s.io").GetEvents().Subscribe(ctx, filter.EventFilters(`topic=="`+containerd.TaskStartTopic+`"`)...)
+	require.True(t, d.Events().WaitForSubscribers(1, time.Second))
+
+	for _, ns := range []string{"buildkit", "team-build", "team-web", "k8s.io"} {
+		require.NoError(t, d.Events().Send(ns, containerd.TaskStartTopic, &apievents.TaskStart{ContainerID: ns}))
+		require.NoError(t, d.Events().Send(ns, containerd.TaskExitTopic, &apievents.TaskExit{ContainerID: ns}))
+	}
+	assert.Equal(t, "team-web", receive(t, ch).Namespace)
+	assert.Equal(t, "k8s.io", receive(t, ch).Namespace)
+	select {
+	case envelope := <-ch:
+		assert.Fail(t, "unexpected event", "%s in %s", envelope.Topic, envelope.Namespace)
+	case err := <-errs:
+		require.NoError(t, err)
+	default:
+	}
+}
+
+func TestDaemonNamespaceLifecycle(t *testing.T) {
+	d := NewDaemon()
+	d.AddNamespace("k8s.io")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	set := containerd.NewNamespaceSet()
+	cu := d.Util("k8s.io")
+	ch, _ := cu.GetEvents().Subscribe(ctx, containerd.NamespaceSetFilters...)
+	require.True(t, d.Events().WaitForSubscribers(1, time.Second))
+	listed, err := cu.Namespaces()
+	require.NoError(t, err)
+	set.Reset(listed)
+
+	d.AddNamespace("tenant-a")
+	require.NoError(t, d.AddContainer("tenant-b", &Container{Record: containers.Container{ID: "web"}}))
+	require.NoError(t, d.DeleteNamespace("tenant-a"))
+	assert.Error(t, d.DeleteNamespace("tenant-a"))
+
+	var changes []string
+	for i := 0; i < 3; i++ {
+		ns, deleted, err := set.HandleEnvelope(receive(t, ch))
+		require.NoError(t, err)
+		if deleted {
+			ns = "-" + ns
+		}
+		changes = append(changes, ns)
+	}
+	assert.Equal(t, []string{"tenant-a", "tenant-b", "-tenant-a"}, changes)
+	namespaces, synced := set.Namespaces(containerd.NamespaceFilter{})
+	assert.True(t, synced)
+	assert.Equal(t, []string{"k8s.io", "tenant-b"}, namespaces)
+}
+
+func TestDaemonLeases(t *testing.T) {
+	d := NewDaemon()
+	u := d.Util("k8s.io")
+	desc := ocispec.Descriptor{Digest: "sha256:a8e0f5ec5a0f0bd4d2bc0fe71f17e8bd9a3dc1a4c8c0a84cb7d60aa6c6f5e4f2"}
+
+	assert.Error(t, u.LeaseContent(context.Background(), desc))
+
+	ctx, release, err := u.WithLease(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, u.LeaseContent(ctx, desc))
+	// A nested lease is the outer one
+	nested, releaseNested, err := u.WithLease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ctx, nested)
+	releaseNested()
+	assert.Equal(t, map[string][]string{"lease-1": {desc.Digest.String()}}, d.Leases())
+
+	release()
+	assert.Empty(t, d.Leases())
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+// EntityID returns the tagger entity of a containerd container,
+// container_id://<id>. It is the entity used by the tagger, the checks,
+// DogStatsD origin detection and the logs for the containerd containers.
+func EntityID(containerID string) string {
+	return types.ContainerEntityName(containerID)
+}
+
+// legacyRuntimeName is the runtime of the containers run by the v1 shim of runc
+const legacyRuntimeName = "io.containerd.runtime.v1.linux"
+
+// runtimeHandlerAliases maps the shims whose name differs from the one of
+// their runtime
+var runtimeHandlerAliases = map[string]string{
+	"runsc": "gvisor",
+}
+
+// RuntimeHandler returns the runtime running a container, eg. runc, kata or
+// gvisor, from the name of its shim in the container info, eg.
+// io.containerd.kata.v2. The names not following the shim naming are
+// returned as is.
+func RuntimeHandler(runtimeName string) string {
+	if runtimeName == legacyRuntimeName {
+		return "runc"
+	}
+	// io.containerd.<runtime>.v<version>
+	parts := strings.Split(runtimeName, ".")
+	if len(parts) != 4 || parts[0] != "io" || parts[1] != "containerd" || !strings.HasPrefix(parts[3], "v") {
+		return runtimeName
+	}
+	if alias, found := runtimeHandlerAliases[parts[2]]; found {
+		return alias
+	}
+	return parts[2]
+}
+
+// RunningContainer is a container with a running task, see RunningContainers
+type RunningContainer struct {
+	CachedContainer
+	// Pids are the PIDs of the task
+	Pids []int32
+	// StartedAt is the start time of the task, zero if unknown
+	StartedAt time.Time
+}
+
+// RunningContainers returns the running containers of the namespace, with
+// the PIDs and the start time of their task. Containers without a running
+// task are skipped. The metadata of the containers comes from the container
+// cache when they are in it.
+func RunningContainers(cu ContainerdItf) ([]RunningContainer, error) {
+	ctns, err := cu.Containers()
+	if err != nil {
+		return nil, err
+	}
+	cached := make(map[string]CachedContainer)
+	if cachedCtns, err := cu.CachedContainers(); err == nil {
+		for _, ctn := range cachedCtns {
+			cached[ctn.ID] = ctn
+		}
+	} else {
+		logFor(cu).Debugf("Cannot get the cached containers, querying their info: %s", err)
+	}
+
+	var running []RunningContainer
+	for _, ctn := range ctns {
+		pids, err := cu.TaskPids(ctn)
+		if err != nil || len(pids) == 0 {
+			// No task, the container is not running
+			continue
+		}
+		info, found := cached[ctn.ID()]
+		if !found {
+			ctnInfo, err := cu.Info(ctn)
+			if err != nil {
+				withFields(logFor(cu), "container_id", ctn.ID()).Debugf("Cannot get info of container: %s", err)
+				continue
+			}
+			info = newCachedContainer(ctnInfo)
+		}
+
+		c := RunningContainer{CachedContainer: info, StartedAt: ctn.StartedAt()}
+		for _, p := range pids {
+			c.Pids = append(c.Pids, int32(p.Pid))
+		}
+		running = append(running, c)
+	}
+	return running, nil
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningContainers(t *testing.T) {
+	created := time.Unix(1540000000, 0)
+	started := created.Add(time.Minute)
+	itf := &mockItf{
+		mockContainers: func() ([]Container, error) {
+			ctns := newMockContainers("running", "stopped", "standalone")
+			ctns[0] = NewContainer(ctns[0].Container, created, started)
+			return ctns, nil
+		},
+		mockTaskPids: func(ctn containerd.Container) ([]containerd.ProcessInfo, error) {
+			if ctn.ID() == "stopped" {
+				return nil, errors.New("no running task found")
+			}
+			return []containerd.ProcessInfo{{Pid: 42}, {Pid: 43}}, nil
+		},
+		mockInfo: func(ctn containerd.Container) (containers.Container, error) {
+			info := containers.Container{
+				ID:        ctn.ID(),
+				Image:     "docker.io/library/redis:latest",
+				CreatedAt: created,
+			}
+			if ctn.ID() == "running" {
+				info.Labels = map[string]string{kubernetesContainerNameLabel: "redis"}
+			}
+			return info, nil
+		},
+	}
+
+	ctrList, err := RunningContainers(itf)
+	require.NoError(t, err)
+	require.Len(t, ctrList, 2)
+
+	assert.Equal(t, "running", ctrList[0].ID)
+	assert.Equal(t, "redis", ctrList[0].Name)
+	assert.Equal(t, "docker.io/library/redis:latest", ctrList[0].Image)
+	assert.Equal(t, created, ctrList[0].CreatedAt)
+	assert.Equal(t, []int32{42, 43}, ctrList[0].Pids)
+	assert.Equal(t, started, ctrList[0].StartedAt)
+	assert.Equal(t, "standalone", ctrList[1].Name)
+	assert.True(t, ctrList[1].StartedAt.IsZero())
+}
+
+func TestRunningContainersFromCache(t *testing.T) {
+	created := time.Unix(1540000000, 0)
+	itf := &mockItf{
+		mockContainers: func() ([]Container, error) {
+			return newMockContainers("running"), nil
+		},
+		mockTaskPids: func(ctn containerd.Container) ([]containerd.ProcessInfo, error) {
+			return []containerd.ProcessInfo{{Pid: 42}}, nil
+		},
+		mockCachedContainers: func() ([]CachedContainer, error) {
+			return []CachedContainer{{
+				ID:        "running",
+				Name:      "redis",
+				Image:     "docker.io/library/redis:latest",
+				Labels:    map[string]string{kubernetesContainerNameLabel: "redis"},
+				CreatedAt: created,
+			}}, nil
+		},
+		mockInfo: func(ctn containerd.Container) (containers.Container, error) {
+			return containers.Container{}, errors.New("the cached metadata should be used")
+		},
+	}
+
+	ctrList, err := RunningContainers(itf)
+	require.NoError(t, err)
+	require.Len(t, ctrList, 1)
+	assert.Equal(t, "redis", ctrList[0].Name)
+	assert.Equal(t, "docker.io/library/redis:latest", ctrList[0].Image)
+	assert.Equal(t, created, ctrList[0].CreatedAt)
+}
+
+func TestRuntimeHandler(t *testing.T) {
+	for runtimeName, handler := range map[string]string{
+		"io.containerd.runc.v2":          "runc",
+		"io.containerd.runtime.v1.linux": "runc",
+		"io.containerd.kata.v2":          "kata",
+		"io.containerd.kata-qemu.v2":     "kata-qemu",
+		"io.containerd.runsc.v1":         "gvisor",
+		"custom":                         "custom",
+		"":                               "",
+	} {
+		assert.Equal(t, handler, RuntimeHandler(runtimeName), runtimeName)
+	}
+}
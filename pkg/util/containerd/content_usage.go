@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// ContentUsage is the disk usage of the content store, the blobs of the
+// images pulled by every namespace of the daemon
+type ContentUsage struct {
+	// Total counts the blobs shared by several namespaces once, as they
+	// are stored once on disk
+	Total int64
+	// Namespaces holds the size of the blobs of each namespace
+	Namespaces map[string]int64
+}
+
+// NewContentUsage returns an empty ContentUsage
+func NewContentUsage() *ContentUsage {
+	return &ContentUsage{Namespaces: make(map[string]int64)}
+}
+
+// Add records a blob of a namespace, seen holds the digests of the blobs
+// already counted in Total
+func (u *ContentUsage) Add(namespace, digest string, size int64, seen map[string]struct{}) {
+	u.Namespaces[namespace] += size
+	if _, found := seen[digest]; !found {
+		seen[digest] = struct{}{}
+		u.Total += size
+	}
+}
+
+// ContentStoreUsage returns the disk usage of the content store, the
+// counterpart of the data space reported by docker. The blobs of every
+// namespace are walked, the namespace filter does not apply.
+func (c *ContainerdUtil) ContentStoreUsage(ctx context.Context) (*ContentUsage, error) {
+	nss, err := c.client().NamespaceService().List(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	usage := NewContentUsage()
+	seen := make(map[string]struct{})
+	for _, ns := range nss {
+		err := c.client().ContentStore().Walk(namespaces.WithNamespace(ctx, ns), func(info content.Info) error {
+			usage.Add(ns, info.Digest.String(), info.Size, seen)
+			return nil
+		})
+		if err != nil {
+			return nil, classifyError(err)
+		}
+	}
+	return usage, nil
+}
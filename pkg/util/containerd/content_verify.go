@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ContentMismatch is a blob of the content store whose data does not
+// match the digest it is stored under
+type ContentMismatch struct {
+	Digest    digest.Digest
+	Actual    digest.Digest
+	MediaType string
+	Size      int64
+}
+
+// ImageVerification is the outcome of the verification of the config
+// and layers of an image against the content store
+type ImageVerification struct {
+	Image  string
+	Target digest.Digest
+	// Verified is the number of blobs read from the content store
+	Verified int
+	// Missing is the number of blobs absent from the content store, like
+	// the layers discarded once unpacked by the CRI plugin
+	Missing    int
+	Mismatches []ContentMismatch
+}
+
+// VerifyImageContent reads the config and the layers of the image of a
+// container from the content store, and compares their data with their
+// digest to detect the tampering of the image content on disk. Every blob
+// is read, the verification is only bounded by ctx.
+func (c *ContainerdUtil) VerifyImageContent(ctx context.Context, ctn containerd.Container) (*ImageVerification, error) {
+	ctx = namespaces.WithNamespace(ctx, c.namespace)
+	img, err := ctn.Image(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	ctx, release := c.leaseImage(ctx, img)
+	defer release()
+	manifest, err := images.Manifest(ctx, img.ContentStore(), img.Target(), img.Platform())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	result, err := verifyBlobs(ctx, img.ContentStore(), append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...))
+	if err != nil {
+		return nil, err
+	}
+	result.Image = img.Name()
+	result.Target = img.Target().Digest
+	return result, nil
+}
+
+// verifyBlobs compares the data of the blobs of provider with their digest
+func verifyBlobs(ctx context.Context, provider content.Provider, descs []ocispec.Descriptor) (*ImageVerification, error) {
+	result := &ImageVerification{}
+	for _, desc := range descs {
+		actual, err := blobDigest(ctx, provider, desc)
+		if errdefs.IsNotFound(err) {
+			result.Missing++
+			continue
+		}
+		if err != nil {
+			return nil, classifyError(err)
+		}
+		result.Verified++
+		if actual != desc.Digest {
+			result.Mismatches = append(result.Mismatches, ContentMismatch{
+				Digest:    desc.Digest,
+				Actual:    actual,
+				MediaType: desc.MediaType,
+				Size:      desc.Size,
+			})
+		}
+	}
+	return result, nil
+}
+
+// blobDigest computes the digest of the data of a blob, with the
+// algorithm of the digest it is stored under
+func blobDigest(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (digest.Digest, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return "", err
+	}
+	ra, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return "", err
+	}
+	defer ra.Close()
+
+	digester := desc.Digest.Algorithm().Digester()
+	if _, err := io.Copy(digester.Hash(), content.NewReader(ra)); err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return digester.Digest(), nil
+}
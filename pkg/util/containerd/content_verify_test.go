@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryBlob struct {
+	*bytes.Reader
+}
+
+func (memoryBlob) Close() error { return nil }
+
+// memoryProvider serves blobs stored under arbitrary digests
+type memoryProvider map[digest.Digest][]byte
+
+func (p memoryProvider) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	data, found := p[desc.Digest]
+	if !found {
+		return nil, errdefs.ErrNotFound
+	}
+	return memoryBlob{bytes.NewReader(data)}, nil
+}
+
+func TestVerifyBlobs(t *testing.T) {
+	config := []byte(`{"architecture":"amd64"}`)
+	layer := []byte("layer data")
+	tampered := []byte("tampered layer data")
+	discarded := []byte("discarded layer")
+	tamperedDigest := digest.FromString("original layer data")
+
+	provider := memoryProvider{
+		digest.FromBytes(config): config,
+		digest.FromBytes(layer):  layer,
+		tamperedDigest:           tampered,
+	}
+	result, err := verifyBlobs(context.Background(), provider, []ocispec.Descriptor{
+		{MediaType: ocispec.MediaTypeImageConfig, Digest: digest.FromBytes(config)},
+		{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(layer)},
+		{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: tamperedDigest, Size: 19},
+		{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(discarded)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &ImageVerification{
+		Verified: 3,
+		Missing:  1,
+		Mismatches: []ContentMismatch{{
+			Digest:    tamperedDigest,
+			Actual:    digest.FromBytes(tampered),
+			MediaType: ocispec.MediaTypeImageLayerGzip,
+			Size:      19,
+		}},
+	}, result)
+
+	_, err = verifyBlobs(context.Background(), provider, []ocispec.Descriptor{{Digest: "sha256:invalid"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// criContainerMetadataExtension is the extension of the container records
+// holding the metadata of the CRI plugin, a versioned JSON document
+const criContainerMetadataExtension = "io.cri-containerd.container.metadata"
+
+// logFollowInterval is the interval at which TailLog polls the log file
+// for new lines and rotations
+var logFollowInterval = 500 * time.Millisecond
+
+// criContainerMetadata holds the fields of the CRI metadata read by the agent
+type criContainerMetadata struct {
+	Version  string
+	Metadata struct {
+		LogPath string
+	}
+}
+
+// ContainerLogPath returns the path of the log file a container writes its
+// outputs to, read from the metadata stored by the CRI plugin. The
+// containers not created by the CRI plugin have no log file, their outputs
+// go to the client which created their task.
+func ContainerLogPath(cu ContainerdItf, id string) (string, error) {
+	ctn, err := cu.LoadContainer(id)
+	if err != nil {
+		return "", err
+	}
+	info, err := cu.Info(ctn)
+	if err != nil {
+		return "", err
+	}
+	ext, found := info.Extensions[criContainerMetadataExtension]
+	if !found || ext == nil {
+		return "", fmt.Errorf("container %s was not created by the CRI plugin, it has no log file", id)
+	}
+	var metadata criContainerMetadata
+	if err := json.Unmarshal(ext.GetValue(), &metadata); err != nil {
+		return "", fmt.Errorf("cannot decode the CRI metadata of container %s: %s", id, err)
+	}
+	if metadata.Metadata.LogPath == "" {
+		return "", fmt.Errorf("container %s has no log path", id)
+	}
+	return metadata.Metadata.LogPath, nil
+}
+
+// TailLog writes the messages of the last lines of a CRI log file to w,
+// all of them if lines is negative. If follow is set, the new lines are
+// written until ctx is done, and the file is reopened when the kubelet
+// rotates it. The lines are in the CRI format, the timestamp, stream and
+// tag prefixing every message are stripped, and the partial lines are
+// joined.
+func TailLog(ctx context.Context, path string, lines int, follow bool, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	offset, err := tailOffset(f, lines)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := newLogLineReader(f)
+	if err := reader.copyLines(w); err != nil {
+		return err
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := reader.copyLines(w); err != nil {
+			return err
+		}
+		rotated, err := logRotated(f, path)
+		if err != nil || !rotated {
+			continue
+		}
+		// The lines written before the rotation are read from the old file
+		if err := reader.copyLines(w); err != nil {
+			return err
+		}
+		newFile, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		f = newFile
+		reader = newLogLineReader(f)
+	}
+}
+
+// tailOffset returns the offset of the last lines of f
+func tailOffset(f *os.File, lines int) (int64, error) {
+	if lines < 0 {
+		return 0, nil
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	const chunkSize = 4096
+	offset := stat.Size()
+	buf := make([]byte, chunkSize)
+	// The newline ending the file does not start a line
+	count := -1
+	for offset > 0 {
+		size := int64(chunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+		if _, err := f.ReadAt(buf[:size], offset); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			count++
+			if count == lines {
+				return offset + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// logLineReader reads the complete lines of a log file being written
+type logLineReader struct {
+	reader *bufio.Reader
+	// partial is the beginning of a line whose end is not written yet
+	partial []byte
+}
+
+func newLogLineReader(r io.Reader) *logLineReader {
+	return &logLineReader{reader: bufio.NewReader(r)}
+}
+
+// copyLines writes the messages of the complete lines available to w, an
+// incomplete line is kept for the next call
+func (l *logLineReader) copyLines(w io.Writer) error {
+	for {
+		line, err := l.reader.ReadBytes('\n')
+		l.partial = append(l.partial, line...)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, l.partial = l.partial, nil
+		if _, err := io.WriteString(w, parseCRILogLine(string(line))); err != nil {
+			return err
+		}
+	}
+}
+
+// parseCRILogLine returns the message of a CRI log line, with its newline
+// unless the line is partial. The lines are formatted as
+// "<RFC3339Nano time> <stdout|stderr> <P|F> <message>"; the lines not in
+// this format are returned unchanged.
+func parseCRILogLine(line string) string {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 {
+		return line
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+		return line
+	}
+	if fields[2] == "P" {
+		return strings.TrimSuffix(fields[3], "\n")
+	}
+	return fields[3]
+}
+
+// logRotated returns whether the file at path is not f anymore
+func logRotated(f *os.File, path string) (bool, error) {
+	current, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, stat), nil
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/typeurl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestContainerLogPath(t *testing.T) {
+	records := map[string]containers.Container{
+		"redis": {
+			ID: "redis",
+			Extensions: map[string]typeurl.Any{
+				criContainerMetadataExtension: &anypb.Any{
+					TypeUrl: "github.com/containerd/cri/pkg/store/container/Metadata",
+					Value:   []byte(`{"Version":"v1","Metadata":{"ID":"redis","Name":"redis","SandboxID":"sb-redis","LogPath":"/var/log/pods/default_redis-0_f3fb6a6a/redis/0.log"}}`),
+				},
+			},
+		},
+		"standalone": {ID: "standalone"},
+	}
+	cu := &mockItf{
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			return &mockContainer{id: id}, nil
+		},
+		mockInfo: func(ctn containerd.Container) (containers.Container, error) {
+			return records[ctn.ID()], nil
+		},
+	}
+
+	path, err := ContainerLogPath(cu, "redis")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/log/pods/default_redis-0_f3fb6a6a/redis/0.log", path)
+
+	_, err = ContainerLogPath(cu, "standalone")
+	assert.Error(t, err)
+}
+
+func TestParseCRILogLine(t *testing.T) {
+	assert.Equal(t, "ready to accept connections\n", parseCRILogLine("2019-04-02T09:38:43.156918391Z stdout F ready to accept connections\n"))
+	assert.Equal(t, "long li", parseCRILogLine("2019-04-02T09:38:43.156918391Z stderr P long li\n"))
+	assert.Equal(t, "not a CRI line\n", parseCRILogLine("not a CRI line\n"))
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer and a reader goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "0.log")
+	content := "2019-04-02T09:38:43.000000001Z stdout F one\n" +
+		"2019-04-02T09:38:43.000000002Z stdout F two\n" +
+		"2019-04-02T09:38:43.000000003Z stderr P thr\n" +
+		"2019-04-02T09:38:43.000000004Z stderr F ee\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	for _, tc := range []struct {
+		lines    int
+		expected string
+	}{
+		{-1, "one\ntwo\nthree\n"},
+		{0, ""},
+		{1, "ee\n"},
+		{3, "two\nthree\n"},
+		{10, "one\ntwo\nthree\n"},
+	} {
+		var out bytes.Buffer
+		require.NoError(t, TailLog(context.Background(), path, tc.lines, false, &out))
+		assert.Equal(t, tc.expected, out.String(), "lines: %d", tc.lines)
+	}
+}
+
+func TestTailLogFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func(interval time.Duration) { logFollowInterval = interval }(logFollowInterval)
+	logFollowInterval = 10 * time.Millisecond
+	path := filepath.Join(dir, "0.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("2019-04-02T09:38:43.000000001Z stdout F one\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() { done <- TailLog(ctx, path, 10, true, out) }()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("2019-04-02T09:38:44.000000001Z stdout F tw")
+	require.NoError(t, err)
+	_, err = f.WriteString("o\n")
+	require.NoError(t, err)
+	f.Close()
+	assert.Eventually(t, func() bool { return out.String() == "one\ntwo\n" }, 2*time.Second, 10*time.Millisecond)
+
+	// The kubelet rotates the file by renaming it
+	require.NoError(t, os.Rename(path, path+".20190402-093845"))
+	require.NoError(t, ioutil.WriteFile(path, []byte("2019-04-02T09:38:45.000000001Z stdout F three\n"), 0644))
+	assert.Eventually(t, func() bool { return out.String() == "one\ntwo\nthree\n" }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
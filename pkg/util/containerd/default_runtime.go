@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pelletier/go-toml"
+)
+
+// defaultRuntimeName is the runtime handler of the CRI plugin when the
+// configuration does not set one
+const defaultRuntimeName = "runc"
+
+// runtimePluginsConfig holds the containerd section of the CRI plugin. The
+// plugin is named "cri" in version 1 of the configuration,
+// "io.containerd.grpc.v1.cri" in version 2 and
+// "io.containerd.cri.v1.runtime" in version 3.
+type runtimePluginsConfig struct {
+	Plugins map[string]struct {
+		Containerd struct {
+			DefaultRuntimeName string `toml:"default_runtime_name"`
+		} `toml:"containerd"`
+	} `toml:"plugins"`
+}
+
+// ReadDefaultRuntimeName returns the runtime handler the CRI plugin runs
+// the pods without a runtime class with, eg. runc, declared in a
+// containerd configuration file
+func ReadDefaultRuntimeName(configPath string) (string, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	var cfg runtimePluginsConfig
+	if err = toml.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("cannot parse %s: %s", configPath, err)
+	}
+	for _, plugin := range cfg.Plugins {
+		if name := plugin.Containerd.DefaultRuntimeName; name != "" {
+			return name, nil
+		}
+	}
+	return defaultRuntimeName, nil
+}
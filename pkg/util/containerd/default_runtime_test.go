@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDefaultRuntimeName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-runtime")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, tc := range map[string]struct {
+		config   string
+		expected string
+	}{
+		"version 1": {
+			config: `[plugins.cri.containerd]
+  default_runtime_name = "kata"
+`,
+			expected: "kata",
+		},
+		"version 2": {
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  snapshotter = "overlayfs"
+  default_runtime_name = "nvidia"
+`,
+			expected: "nvidia",
+		},
+		"version 3": {
+			config: `version = 3
+
+[plugins."io.containerd.cri.v1.runtime".containerd]
+  default_runtime_name = "crun"
+`,
+			expected: "crun",
+		},
+		"default": {
+			config:   "version = 2\n",
+			expected: "runc",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.toml")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.config), 0644))
+			runtime, err := ReadDefaultRuntimeName(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, runtime)
+		})
+	}
+
+	_, err = ReadDefaultRuntimeName(filepath.Join(dir, "missing.toml"))
+	assert.Error(t, err)
+}
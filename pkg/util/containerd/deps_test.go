@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agentPackages are the agent packages the util must not depend on, see doc.go
+var agentPackages = []string{
+	"github.com/DataDog/datadog-agent/pkg/config",
+	"github.com/DataDog/datadog-agent/pkg/clusteragent",
+	"github.com/DataDog/datadog-agent/pkg/diagnose",
+	"github.com/DataDog/datadog-agent/pkg/metadata",
+	"github.com/DataDog/datadog-agent/pkg/tagger",
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent",
+	"github.com/DataDog/datadog-agent/pkg/util/docker",
+	"github.com/DataDog/datadog-agent/pkg/util/hostname",
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics",
+}
+
+func TestNoAgentDependencies(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not in the PATH")
+	}
+	out, err := exec.Command(goBin, "list", "-tags", "containerd", "-f", `{{join .Deps "\n"}}`, ".").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	for _, dep := range strings.Split(string(out), "\n") {
+		for _, pkg := range agentPackages {
+			if dep == pkg || strings.HasPrefix(dep, pkg+"/") {
+				assert.Fail(t, "the containerd util depends on an agent package", "%s", dep)
+			}
+		}
+	}
+	// pkg/util/containers reads the agent configuration, its types are in
+	// the config-free pkg/util/containers/types
+	assert.NotContains(t, strings.Split(string(out), "\n"), "github.com/DataDog/datadog-agent/pkg/util/containers")
+}
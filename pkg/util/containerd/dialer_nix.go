@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,!windows
+
+package containerd
+
+import (
+	"strings"
+
+	"github.com/containerd/containerd/pkg/dialer"
+)
+
+// dialContext connects to the unix socket of the daemon
+var dialContext = dialer.ContextDialer
+
+// socketAddress returns the address of the socket given to the containerd
+// client, which prepends the unix:// scheme itself
+func socketAddress(socketPath string) string {
+	return strings.TrimPrefix(socketPath, "unix://")
+}
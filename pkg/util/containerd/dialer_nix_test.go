@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,!windows
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSocketAddress(t *testing.T) {
+	assert.Equal(t, "/run/containerd/containerd.sock", socketAddress("/run/containerd/containerd.sock"))
+	assert.Equal(t, "/run/containerd/containerd.sock", socketAddress("unix:///run/containerd/containerd.sock"))
+}
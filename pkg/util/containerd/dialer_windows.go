@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,windows
+
+package containerd
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+
+	winio "github.com/Microsoft/go-winio"
+)
+
+const npipeScheme = "npipe://"
+
+// dialContext connects to the named pipe of the daemon. The address is the
+// one built by the containerd client, npipe:// followed by the pipe path,
+// eg. npipe:////./pipe/containerd-containerd.
+func dialContext(ctx context.Context, address string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, strings.TrimPrefix(filepath.ToSlash(address), npipeScheme))
+}
+
+// socketAddress returns the address of the named pipe given to the
+// containerd client, which prepends the npipe:// scheme if it is missing.
+// Both \\.\pipe\containerd-containerd and npipe:////./pipe/containerd-containerd
+// are accepted.
+func socketAddress(socketPath string) string {
+	return filepath.ToSlash(socketPath)
+}
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiskDevices maps the major:minor numbers of the block devices of the host
+// to their name, read from the diskstats file of procRoot. It names the
+// devices of the cgroup v2 task metrics, see DeviceIO.
+// Format:
+// 8       0 sda 24398 2788 1317975 40488 25201 46267 1584744 142336 0 22352 182660
+// 8       1 sda1 24232 2788 1312025 40376 25201 46267 1584744 142336 0 22320 182552
+func DiskDevices(procRoot string) (map[string]string, error) {
+	path := filepath.Join(procRoot, "diskstats")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		devices[fields[0]+":"+fields[1]] = fields[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return devices, fmt.Errorf("error reading %s: %s", path, err)
+	}
+	return devices, nil
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+/*
+Package containerd wraps interactions with the containerd API.
+
+The package is meant to be importable by other tools than the core agent
+(cluster agent, process agent, external tooling). To that end it must not
+depend on the pkg/config globals: callers build an Options struct and pass
+it to NewContainerdUtil or GetContainerdUtil. The settings used when
+GetContainerdUtil is given nil options are read from the AgentConfig, which
+the agent sets by importing pkg/config/containerdconfig.
+Nor does it import the agent packages reading them, pkg/util/containers
+included: the helpers it shares with them are in pkg/util/containers/types,
+and TestNoAgentDependencies checks its dependencies.
+Consumers should depend on the ContainerdItf
+interface rather than on the concrete ContainerdUtil, so that the
+implementation can evolve without breaking them.
+
+The shared utils are handed out by a Provider. GetContainerdUtil uses the
+DefaultProvider; long-lived consumers can be given a Provider instead, which
+tests reset or point at fakes with SetForTests. The container resolvers and
+cgroup indexes of the utils are held by the Provider too, and dropped with
+their util.
+
+The client code requires the containerd build tag.
+*/
+package containerd
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd/oci"
+)
+
+// ScrubbedValue replaces the values of the sensitive environment variables
+// and annotations, like the credentials scrubbed from the logs
+const ScrubbedValue = "********"
+
+// DefaultEnvScrubPatterns are the patterns of the names of the sensitive
+// environment variables and annotations, always scrubbed. The keys are
+// matched at the end of the variable names and as API keys only, so that
+// the annotations like io.kubernetes.cri.sandbox-key or keyboard-layout
+// are kept.
+var DefaultEnvScrubPatterns = []string{"*PASSWORD*", "*TOKEN*", "*_KEY", "*API_KEY*", "*API-KEY*", "*APIKEY*"}
+
+// EnvScrubber replaces the values of the environment variables and of the
+// annotations of the container specs whose name matches a pattern, before
+// they leave the util, so that the secrets passed to the containers do not
+// reach the tags, the flares or the inventories. The patterns are shell
+// patterns matched against the upper-cased names.
+type EnvScrubber struct {
+	patterns []string
+}
+
+// NewEnvScrubber returns an EnvScrubber matching the extra patterns on top
+// of DefaultEnvScrubPatterns. The malformed patterns are logged and ignored.
+func NewEnvScrubber(extra []string) *EnvScrubber {
+	s := &EnvScrubber{patterns: append([]string(nil), DefaultEnvScrubPatterns...)}
+	for _, p := range extra {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			agentLogger{}.Warnf("Ignoring the containerd env scrub pattern %q: %s", p, err)
+			continue
+		}
+		s.patterns = append(s.patterns, p)
+	}
+	return s
+}
+
+// Sensitive returns whether the value of the variable or annotation name
+// is scrubbed. A nil EnvScrubber matches DefaultEnvScrubPatterns.
+func (s *EnvScrubber) Sensitive(name string) bool {
+	patterns := DefaultEnvScrubPatterns
+	if s != nil {
+		patterns = s.patterns
+	}
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ScrubEnv returns a copy of env, a list of NAME=value variables, with the
+// values of the sensitive ones replaced by ScrubbedValue
+func (s *EnvScrubber) ScrubEnv(env []string) []string {
+	if env == nil {
+		return nil
+	}
+	scrubbed := make([]string, len(env))
+	for i, v := range env {
+		name := strings.SplitN(v, "=", 2)[0]
+		if s.Sensitive(name) {
+			v = name + "=" + ScrubbedValue
+		}
+		scrubbed[i] = v
+	}
+	return scrubbed
+}
+
+// ScrubSpec returns a copy of spec with its environment and annotations
+// scrubbed. The other fields are shared with spec.
+func (s *EnvScrubber) ScrubSpec(spec *oci.Spec) *oci.Spec {
+	if spec == nil {
+		return nil
+	}
+	scrubbed := *spec
+	if spec.Process != nil {
+		process := *spec.Process
+		process.Env = s.ScrubEnv(process.Env)
+		scrubbed.Process = &process
+	}
+	if spec.Annotations != nil {
+		scrubbed.Annotations = make(map[string]string, len(spec.Annotations))
+		for name, value := range spec.Annotations {
+			if s.Sensitive(name) {
+				value = ScrubbedValue
+			}
+			scrubbed.Annotations[name] = value
+		}
+	}
+	return &scrubbed
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvScrubber(t *testing.T) {
+	s := NewEnvScrubber([]string{"*secret*", " ", "[malformed"})
+
+	spec := &oci.Spec{
+		Process: &specs.Process{Env: []string{
+			"PATH=/usr/bin",
+			"DB_PASSWORD=hunter2",
+			"github_token=ghp_1234",
+			"AWS_SECRET_ACCESS_KEY=abcd=efgh",
+			"APP_SECRET=shh",
+			"EMPTY",
+		}},
+		Annotations: map[string]string{
+			"io.kubernetes.cri.container-type": "container",
+			"io.kubernetes.cri.sandbox-key":    "/var/run/netns/cni-1234",
+			"com.example.keyboard-layout":      "azerty",
+			"com.example.api-key":              "1234",
+		},
+	}
+	scrubbed := s.ScrubSpec(spec)
+	assert.Equal(t, []string{
+		"PATH=/usr/bin",
+		"DB_PASSWORD=********",
+		"github_token=********",
+		"AWS_SECRET_ACCESS_KEY=********",
+		"APP_SECRET=********",
+		"EMPTY",
+	}, scrubbed.Process.Env)
+	assert.Equal(t, map[string]string{
+		"io.kubernetes.cri.container-type": "container",
+		"io.kubernetes.cri.sandbox-key":    "/var/run/netns/cni-1234",
+		"com.example.keyboard-layout":      "azerty",
+		"com.example.api-key":              "********",
+	}, scrubbed.Annotations)
+
+	// The spec read from the daemon is not changed
+	assert.Equal(t, "DB_PASSWORD=hunter2", spec.Process.Env[1])
+	assert.Equal(t, "1234", spec.Annotations["com.example.api-key"])
+
+	// The extra patterns are not matched by the default scrubber
+	var defaults *EnvScrubber
+	assert.True(t, defaults.Sensitive("API_TOKEN"))
+	assert.False(t, defaults.Sensitive("APP_SECRET"))
+
+	// The keys are matched at the end of the names or as API keys
+	for name, sensitive := range map[string]bool{
+		"SSH_KEY":     true,
+		"DD_API_KEY":  true,
+		"apikey":      true,
+		"MONKEY":      false,
+		"KEYBOARD":    false,
+		"sandbox-key": false,
+	} {
+		assert.Equal(t, sensitive, defaults.Sensitive(name), name)
+	}
+	assert.Nil(t, defaults.ScrubSpec(nil))
+}
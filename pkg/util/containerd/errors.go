@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+// Kinds of the errors returned by the ContainerdUtil methods, see ErrorKind
+var (
+	// ErrNotServing means the daemon cannot be reached or is not serving
+	ErrNotServing = errors.New("containerd is not serving")
+	// ErrNamespaceNotFound means the namespace the util is bound to does not exist
+	ErrNamespaceNotFound = errors.New("containerd namespace not found")
+	// ErrPermissionDenied means the agent is not allowed to use the socket
+	ErrPermissionDenied = errors.New("permission denied on the containerd socket")
+	// ErrTimeout means the daemon did not answer before the query timeout
+	ErrTimeout = errors.New("containerd query timed out")
+	// ErrUnsupported means the daemon version does not implement the query
+	ErrUnsupported = errors.New("not supported by this containerd version")
+	// ErrRestartDisabled means the restarts of the tasks are disabled, see
+	// Options.AllowTaskRestart
+	ErrRestartDisabled = errors.New("the restart of the containers is disabled, see containerd_allow_task_restart")
+)
+
+// Error is an error of the containerd API classified by kind
+type Error struct {
+	// Kind is one of ErrNotServing, ErrNamespaceNotFound,
+	// ErrPermissionDenied, ErrTimeout, ErrUnsupported and ErrRestartDisabled
+	Kind error
+	// Err is the error returned by the containerd client
+	Err error
+}
+
+// Error implements the `error` interface
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// ErrorKind returns the kind of an error returned by the util, including
+// through the retrier of the connection, or nil if it is not classified
+func ErrorKind(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *Error:
+		return e.Kind
+	case *retry.Error:
+		return ErrorKind(e.LogicError)
+	}
+	switch err {
+	case ErrNotServing, ErrNamespaceNotFound, ErrPermissionDenied, ErrTimeout, ErrUnsupported, ErrRestartDisabled:
+		return err
+	}
+	return nil
+}
+
+// IsTransient returns whether an error is a connectivity issue expected to
+// resolve by itself, as opposed to a configuration issue
+func IsTransient(err error) bool {
+	switch ErrorKind(err) {
+	case ErrNotServing, ErrTimeout:
+		return true
+	}
+	return false
+}
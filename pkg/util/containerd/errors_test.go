@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+func TestErrorKind(t *testing.T) {
+	permissionErr := &Error{Kind: ErrPermissionDenied, Err: errors.New("dial unix /run/containerd/containerd.sock: connect: permission denied")}
+
+	for name, tc := range map[string]struct {
+		err       error
+		kind      error
+		transient bool
+	}{
+		"nil":          {err: nil},
+		"unclassified": {err: errors.New("container not found")},
+		"sentinel":     {err: ErrTimeout, kind: ErrTimeout, transient: true},
+		"classified":   {err: permissionErr, kind: ErrPermissionDenied},
+		"restart":      {err: &Error{Kind: ErrRestartDisabled, Err: ErrRestartDisabled}, kind: ErrRestartDisabled},
+		"retried": {
+			err:       &retry.Error{RessourceName: "containerdutil", RetryStatus: retry.FailWillRetry, LogicError: &Error{Kind: ErrNotServing, Err: errors.New("connection refused")}},
+			kind:      ErrNotServing,
+			transient: true,
+		},
+		"retried unclassified": {
+			err: &retry.Error{RessourceName: "containerdutil", RetryStatus: retry.PermaFail, LogicError: errors.New("retry number exceeded")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.kind, ErrorKind(tc.err))
+			assert.Equal(t, tc.transient, IsTransient(tc.err))
+		})
+	}
+
+	// The message of the client error is kept
+	assert.EqualError(t, permissionErr, "dial unix /run/containerd/containerd.sock: connect: permission denied")
+}
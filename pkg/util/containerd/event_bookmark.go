@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventBookmarkVersion is the version of the format of the bookmark file
+const eventBookmarkVersion = 1
+
+// EventBookmarkFilters are the subscription filters matching the events
+// updating an EventBookmark
+var EventBookmarkFilters = []string{
+	`topic=="` + ContainerCreateTopic + `"`,
+	`topic=="` + TaskStartTopic + `"`,
+	`topic=="` + TaskExitTopic + `"`,
+	`topic=="` + ContainerDeleteTopic + `"`,
+}
+
+// EventBookmark is the time of the last containerd event processed by the
+// agent and the containers known after it, per namespace. It is persisted
+// so that the events missed while the agent was not running are replayed
+// when it starts again. containerd neither numbers nor keeps its events,
+// the missed ones are rebuilt by comparing the bookmark to the current
+// containers and tasks.
+type EventBookmark struct {
+	mu         sync.Mutex
+	path       string
+	namespaces map[string]*namespaceBookmark
+	dirty      bool
+}
+
+type namespaceBookmark struct {
+	LastEvent time.Time `json:"last_event"`
+	// Containers maps the container IDs to whether their task is running
+	Containers map[string]bool `json:"containers"`
+}
+
+type eventBookmarkFile struct {
+	Version    int                           `json:"version"`
+	Namespaces map[string]*namespaceBookmark `json:"namespaces"`
+}
+
+// LoadEventBookmark reads the bookmark persisted at path, it is empty if
+// the file does not exist yet
+func LoadEventBookmark(path string) (*EventBookmark, error) {
+	b := &EventBookmark{
+		path:       path,
+		namespaces: make(map[string]*namespaceBookmark),
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+
+	var f eventBookmarkFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return b, fmt.Errorf("cannot parse the containerd event bookmark %s: %s", path, err)
+	}
+	if f.Version != eventBookmarkVersion {
+		return b, fmt.Errorf("unsupported version %d of the containerd event bookmark %s", f.Version, path)
+	}
+	for ns, nb := range f.Namespaces {
+		if nb.Containers == nil {
+			nb.Containers = make(map[string]bool)
+		}
+		b.namespaces[ns] = nb
+	}
+	return b, nil
+}
+
+// Save writes the bookmark if it changed since the last save
+func (b *EventBookmark) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.dirty {
+		return nil
+	}
+	content, err := json.Marshal(eventBookmarkFile{
+		Version:    eventBookmarkVersion,
+		Namespaces: b.namespaces,
+	})
+	if err != nil {
+		return err
+	}
+	// The bookmark is replaced at once so that a crash does not leave it truncated
+	tmp := b.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return err
+	}
+	b.dirty = false
+	return nil
+}
+
+// HandleEnvelope updates the bookmark with an event matching
+// EventBookmarkFilters. It returns false if the event precedes the last
+// replay of its namespace, in which case it was already replayed and
+// should be dropped. Other events are always returned true.
+func (b *EventBookmark) HandleEnvelope(envelope *events.Envelope) (bool, error) {
+	if envelope == nil {
+		return false, nil
+	}
+	switch envelope.Topic {
+	case ContainerCreateTopic, TaskStartTopic, TaskExitTopic, ContainerDeleteTopic:
+	default:
+		return true, nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return true, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	nb, found := b.namespaces[envelope.Namespace]
+	if !found {
+		nb = &namespaceBookmark{Containers: make(map[string]bool)}
+		b.namespaces[envelope.Namespace] = nb
+	}
+	if envelope.Timestamp.Before(nb.LastEvent) {
+		return false, nil
+	}
+
+	switch e := ev.(type) {
+	case *apievents.ContainerCreate:
+		if _, found := nb.Containers[e.ID]; !found {
+			nb.Containers[e.ID] = false
+		}
+	case *apievents.TaskStart:
+		nb.Containers[e.ContainerID] = true
+	case *apievents.TaskExit:
+		// Processes exec'd in the container exit with their own ID
+		if e.ID == e.ContainerID {
+			nb.Containers[e.ContainerID] = false
+		}
+	case *apievents.ContainerDelete:
+		delete(nb.Containers, e.ID)
+	}
+	nb.LastEvent = envelope.Timestamp
+	b.dirty = true
+	return true, nil
+}
+
+// Replay returns the events of the namespace of cu missed since the
+// bookmark, sorted by time, and moves the bookmark to the current state of
+// the namespace. Nothing is replayed for the namespaces seen for the first
+// time. The containers created, the tasks started and exited and the
+// containers deleted are replayed; the start time of the tasks and the
+// deletion time of the containers are unknown, they are replayed at the
+// current time.
+func (b *EventBookmark) Replay(cu ContainerdItf) ([]*events.Envelope, error) {
+	return b.replay(cu, time.Now())
+}
+
+func (b *EventBookmark) replay(cu ContainerdItf, now time.Time) ([]*events.Envelope, error) {
+	ns := cu.Namespace()
+	ctns, err := cu.Containers()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	prev := b.namespaces[ns]
+	b.mu.Unlock()
+
+	var missed []*events.Envelope
+	add := func(topic string, ev interface{}, ts time.Time) error {
+		any, err := typeurl.MarshalAny(ev)
+		if err != nil {
+			return err
+		}
+		missed = append(missed, &events.Envelope{Timestamp: ts, Namespace: ns, Topic: topic, Event: any})
+		return nil
+	}
+
+	current := make(map[string]bool, len(ctns))
+	for _, ctn := range ctns {
+		id := ctn.ID()
+		wasRunning, known := false, false
+		if prev != nil {
+			wasRunning, known = prev.Containers[id]
+		}
+
+		status, err := cu.TaskStatus(ctn)
+		if err != nil && !errdefs.IsNotFound(err) {
+			// The task is kept in its previous state until the next replay
+			current[id] = wasRunning
+			continue
+		}
+		running := err == nil && (status.Status == containerd.Running || status.Status == containerd.Paused)
+		current[id] = running
+		if prev == nil {
+			continue
+		}
+
+		if !known {
+			info, err := cu.Info(ctn)
+			if err == nil && info.CreatedAt.After(prev.LastEvent) {
+				create := &apievents.ContainerCreate{
+					ID:      id,
+					Image:   info.Image,
+					Runtime: &apievents.ContainerCreate_Runtime{Name: info.Runtime.Name},
+				}
+				if err := add(ContainerCreateTopic, create, info.CreatedAt); err != nil {
+					return nil, err
+				}
+			}
+		}
+		switch {
+		case running && !wasRunning:
+			if err := add(TaskStartTopic, &apievents.TaskStart{ContainerID: id}, now); err != nil {
+				return nil, err
+			}
+		case !running && wasRunning && status.Status == containerd.Stopped:
+			exit := &apievents.TaskExit{
+				ContainerID: id,
+				ID:          id,
+				ExitStatus:  status.ExitStatus,
+				ExitedAt:    timestamppb.New(status.ExitTime),
+			}
+			if err := add(TaskExitTopic, exit, status.ExitTime); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if prev != nil {
+		var deleted []string
+		for id := range prev.Containers {
+			if _, found := current[id]; !found {
+				deleted = append(deleted, id)
+			}
+		}
+		sort.Strings(deleted)
+		for _, id := range deleted {
+			if err := add(ContainerDeleteTopic, &apievents.ContainerDelete{ID: id}, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].Timestamp.Before(missed[j].Timestamp)
+	})
+
+	b.mu.Lock()
+	b.namespaces[ns] = &namespaceBookmark{LastEvent: now, Containers: current}
+	b.dirty = true
+	b.mu.Unlock()
+	return missed, nil
+}
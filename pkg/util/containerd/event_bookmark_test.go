@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBookmark(t *testing.T) (*EventBookmark, func()) {
+	dir, err := ioutil.TempDir("", "containerd-bookmark")
+	require.NoError(t, err)
+	b, err := LoadEventBookmark(filepath.Join(dir, "containerd_event_bookmark.json"))
+	require.NoError(t, err)
+	return b, func() { os.RemoveAll(dir) }
+}
+
+func TestEventBookmarkSave(t *testing.T) {
+	b, cleanup := newTestBookmark(t)
+	defer cleanup()
+
+	ts := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	for _, envelope := range []*events.Envelope{
+		buildEnvelope(t, ContainerCreateTopic, &apievents.ContainerCreate{ID: "redis"}, ts),
+		buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, ts.Add(time.Second)),
+		buildEnvelope(t, ContainerCreateTopic, &apievents.ContainerCreate{ID: "nginx"}, ts.Add(2*time.Second)),
+		// An exec'd process exits
+		buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis-cli"}, ts.Add(3*time.Second)),
+	} {
+		envelope.Namespace = "k8s.io"
+		fresh, err := b.HandleEnvelope(envelope)
+		require.NoError(t, err)
+		assert.True(t, fresh)
+	}
+	require.NoError(t, b.Save())
+
+	loaded, err := LoadEventBookmark(b.path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*namespaceBookmark{
+		"k8s.io": {
+			LastEvent:  ts.Add(3 * time.Second),
+			Containers: map[string]bool{"redis": true, "nginx": false},
+		},
+	}, loaded.namespaces)
+}
+
+func TestLoadEventBookmarkInvalid(t *testing.T) {
+	b, cleanup := newTestBookmark(t)
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(b.path, []byte(`{"version": 2}`), 0644))
+	loaded, err := LoadEventBookmark(b.path)
+	assert.Error(t, err)
+	assert.Empty(t, loaded.namespaces)
+}
+
+func TestEventBookmarkReplay(t *testing.T) {
+	b, cleanup := newTestBookmark(t)
+	defer cleanup()
+
+	bookmarked := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	b.namespaces["k8s.io"] = &namespaceBookmark{
+		LastEvent: bookmarked,
+		Containers: map[string]bool{
+			"redis":   true,
+			"nginx":   true,
+			"etcd":    true,
+			"removed": false,
+		},
+	}
+
+	created := bookmarked.Add(time.Minute)
+	exited := bookmarked.Add(2 * time.Minute)
+	now := bookmarked.Add(5 * time.Minute)
+	cu := &mockItf{
+		mockNamespace: func() string { return "k8s.io" },
+		mockContainers: func() ([]Container, error) {
+			return newMockContainers("redis", "nginx", "etcd", "web"), nil
+		},
+		mockTaskStatus: func(ctn containerd.Container) (containerd.Status, error) {
+			switch ctn.ID() {
+			case "nginx":
+				return containerd.Status{Status: containerd.Stopped, ExitStatus: 137, ExitTime: exited}, nil
+			case "etcd":
+				return containerd.Status{}, &Error{Kind: ErrTimeout, Err: errors.New("context deadline exceeded")}
+			}
+			return containerd.Status{Status: containerd.Running}, nil
+		},
+		mockInfo: func(ctn containerd.Container) (containers.Container, error) {
+			return containers.Container{ID: ctn.ID(), Image: "nginx:1.15", CreatedAt: created}, nil
+		},
+	}
+
+	missed, err := b.replay(cu, now)
+	require.NoError(t, err)
+	require.Len(t, missed, 4)
+
+	var topics []string
+	for _, envelope := range missed {
+		topics = append(topics, envelope.Topic)
+		assert.Equal(t, "k8s.io", envelope.Namespace)
+	}
+	assert.Equal(t, []string{ContainerCreateTopic, TaskExitTopic, TaskStartTopic, ContainerDeleteTopic}, topics)
+	assert.Equal(t, created, missed[0].Timestamp)
+
+	ev, err := typeurl.UnmarshalAny(missed[1].Event)
+	require.NoError(t, err)
+	exit := ev.(*apievents.TaskExit)
+	assert.Equal(t, "nginx", exit.ContainerID)
+	assert.Equal(t, uint32(137), exit.ExitStatus)
+	assert.Equal(t, exited, exit.ExitedAt.AsTime())
+
+	ev, err = typeurl.UnmarshalAny(missed[2].Event)
+	require.NoError(t, err)
+	assert.Equal(t, "web", ev.(*apievents.TaskStart).ContainerID)
+	ev, err = typeurl.UnmarshalAny(missed[3].Event)
+	require.NoError(t, err)
+	assert.Equal(t, "removed", ev.(*apievents.ContainerDelete).ID)
+
+	// The task of etcd could not be inspected, it is kept running
+	assert.Equal(t, &namespaceBookmark{
+		LastEvent:  now,
+		Containers: map[string]bool{"redis": true, "nginx": false, "etcd": true, "web": true},
+	}, b.namespaces["k8s.io"])
+
+	// The events preceding the replay were replayed
+	envelope := buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "web"}, now.Add(-time.Second))
+	envelope.Namespace = "k8s.io"
+	fresh, err := b.HandleEnvelope(envelope)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+}
+
+func TestEventBookmarkReplayNewNamespace(t *testing.T) {
+	b, cleanup := newTestBookmark(t)
+	defer cleanup()
+
+	cu := &mockItf{
+		mockNamespace: func() string { return "default" },
+		mockContainers: func() ([]Container, error) {
+			return newMockContainers("redis", "stopped"), nil
+		},
+		mockTaskStatus: func(ctn containerd.Container) (containerd.Status, error) {
+			if ctn.ID() == "stopped" {
+				return containerd.Status{}, errdefs.ErrNotFound
+			}
+			return containerd.Status{Status: containerd.Running}, nil
+		},
+	}
+
+	now := time.Now()
+	missed, err := b.replay(cu, now)
+	require.NoError(t, err)
+	assert.Empty(t, missed)
+	assert.Equal(t, map[string]bool{"redis": true, "stopped": false}, b.namespaces["default"].Containers)
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/containerd/containerd/events"
+)
+
+// EventBuffer queues the events of a subscription while its consumer is
+// busy, so that the event stream is read even during pod churn storms. The
+// queue is bounded: when it is full, the new events are dropped and
+// counted, except the delete events which evict the oldest event that is
+// not a delete. The delete events are never dropped, the consumers rely
+// on them to clean up their state, eg. the tags of the deleted containers.
+type EventBuffer struct {
+	size    int
+	dropped int64 // accessed atomically
+}
+
+// NewEventBuffer returns a buffer holding at most size events, deletes
+// aside
+func NewEventBuffer(size int) *EventBuffer {
+	return &EventBuffer{size: size}
+}
+
+// Buffer reads the events of in until ctx is done, and passes them to the
+// returned channel in order, dropping the ones exceeding the buffer
+func (b *EventBuffer) Buffer(ctx context.Context, in <-chan *events.Envelope) <-chan *events.Envelope {
+	out := make(chan *events.Envelope)
+	go func() {
+		var queue []*events.Envelope
+		for {
+			// Nothing is sent while the queue is empty
+			var send chan<- *events.Envelope
+			var next *events.Envelope
+			if len(queue) > 0 {
+				send = out
+				next = queue[0]
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case envelope, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = b.push(queue, envelope)
+			case send <- next:
+				queue[0] = nil
+				queue = queue[1:]
+			}
+		}
+	}()
+	return out
+}
+
+// push queues an envelope, or drops it if the queue is full
+func (b *EventBuffer) push(queue []*events.Envelope, envelope *events.Envelope) []*events.Envelope {
+	if envelope == nil {
+		return queue
+	}
+	if len(queue) < b.size {
+		return append(queue, envelope)
+	}
+	if !isDeleteTopic(envelope.Topic) {
+		b.drop()
+		return queue
+	}
+	for i, queued := range queue {
+		if !isDeleteTopic(queued.Topic) {
+			b.drop()
+			return append(append(queue[:i], queue[i+1:]...), envelope)
+		}
+	}
+	// The queue holds deletes only, it grows past its size
+	return append(queue, envelope)
+}
+
+func (b *EventBuffer) drop() {
+	atomic.AddInt64(&b.dropped, 1)
+	containerdExpvars.Add("EventsDropped", 1)
+}
+
+// Dropped returns the number of events dropped by the buffer
+func (b *EventBuffer) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// isDeleteTopic returns whether the events of a topic are deletes, eg.
+// /containers/delete or /images/delete
+func isDeleteTopic(topic string) bool {
+	return strings.HasSuffix(topic, "/delete")
+}
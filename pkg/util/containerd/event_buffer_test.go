@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBufferPush(t *testing.T) {
+	b := NewEventBuffer(2)
+	ts := time.Now()
+	start := buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, ts)
+	exit := buildEnvelope(t, TaskExitTopic, &apievents.TaskExit{ContainerID: "redis"}, ts)
+	oom := buildEnvelope(t, "/tasks/oom", &apievents.TaskOOM{ContainerID: "redis"}, ts)
+	del := buildEnvelope(t, ContainerDeleteTopic, &apievents.ContainerDelete{ID: "redis"}, ts)
+
+	queue := b.push(nil, start)
+	queue = b.push(queue, exit)
+	queue = b.push(queue, nil)
+	assert.Equal(t, []*events.Envelope{start, exit}, queue)
+
+	// The buffer is full
+	queue = b.push(queue, oom)
+	assert.Equal(t, []*events.Envelope{start, exit}, queue)
+	assert.Equal(t, int64(1), b.Dropped())
+
+	// The deletes evict the oldest events, then exceed the size
+	queue = b.push(queue, del)
+	assert.Equal(t, []*events.Envelope{exit, del}, queue)
+	queue = b.push(queue, del)
+	queue = b.push(queue, del)
+	assert.Equal(t, []*events.Envelope{del, del, del}, queue)
+	assert.Equal(t, int64(3), b.Dropped())
+}
+
+func TestEventBuffer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan *events.Envelope)
+	b := NewEventBuffer(2)
+	out := b.Buffer(ctx, in)
+
+	// The events are read while the consumer is busy
+	for i := 0; i < 5; i++ {
+		in <- buildEnvelope(t, TaskStartTopic, &apievents.TaskStart{ContainerID: "redis"}, time.Now())
+	}
+	in <- buildEnvelope(t, ContainerDeleteTopic, &apievents.ContainerDelete{ID: "redis"}, time.Now())
+
+	var topics []string
+	for i := 0; i < 2; i++ {
+		select {
+		case envelope := <-out:
+			topics = append(topics, envelope.Topic)
+		case <-time.After(time.Second):
+			require.FailNow(t, "no event received")
+		}
+	}
+	assert.Equal(t, []string{TaskStartTopic, ContainerDeleteTopic}, topics)
+	assert.Equal(t, int64(4), b.Dropped())
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// execOutputLimit bounds the output kept by Exec, like the output of the
+// docker healthchecks
+const execOutputLimit = 4096
+
+// execCounter makes the IDs of the exec'd processes unique
+var execCounter uint64
+
+// ExecResult is the result of a command run in a container by Exec
+type ExecResult struct {
+	ExitCode uint32
+	// Output holds the first 4096 bytes written by the command to its
+	// stdout and stderr
+	Output   string
+	Duration time.Duration
+}
+
+// Exec runs a command in the task of a container, as the process of the
+// task, and waits for it to exit. The command is killed when ctx is done,
+// an ErrTimeout error is returned then. The outputs of the command are read
+// from FIFOs created in /run/containerd/fifo, the agent must share this
+// directory with the host.
+func (c *ContainerdUtil) Exec(ctx context.Context, ctn containerd.Container, cmd []string) (*ExecResult, error) {
+	if len(cmd) == 0 {
+		return nil, errors.New("no command to run")
+	}
+	ctx = namespaces.WithNamespace(ctx, c.namespace)
+	task, err := ctn.Task(ctx, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	spec, err := ctn.Spec(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	if spec.Process == nil {
+		return nil, fmt.Errorf("container %s has no process spec", ctn.ID())
+	}
+	processSpec := *spec.Process
+	processSpec.Args = cmd
+	processSpec.Terminal = false
+
+	output := &execOutput{}
+	id := fmt.Sprintf("datadog-exec-%d", atomic.AddUint64(&execCounter, 1))
+	process, err := task.Exec(ctx, id, &processSpec, cio.NewCreator(cio.WithStreams(nil, output, output)))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer func() {
+		// ctx may be done, the process is killed if it is still running
+		deleteCtx, cancel := c.queryContext()
+		defer cancel()
+		if _, err := process.Delete(deleteCtx, containerd.WithProcessKill); err != nil {
+			c.containerLog(ctn.ID()).Debugf("Cannot delete the exec'd process %s: %s", id, err)
+		}
+	}()
+
+	exitCh, err := process.Wait(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	start := time.Now()
+	if err := process.Start(ctx); err != nil {
+		return nil, classifyError(err)
+	}
+
+	select {
+	case status := <-exitCh:
+		if ctx.Err() == nil {
+			code, _, err := status.Result()
+			if err != nil {
+				return nil, classifyError(err)
+			}
+			// The outputs are copied until the FIFOs are closed
+			process.IO().Wait()
+			return &ExecResult{ExitCode: code, Output: output.String(), Duration: time.Since(start)}, nil
+		}
+	case <-ctx.Done():
+	}
+	return nil, &Error{Kind: ErrTimeout, Err: fmt.Errorf("command %s in container %s: %s", cmd[0], ctn.ID(), ctx.Err())}
+}
+
+// execOutput keeps the first execOutputLimit bytes written to the stdout
+// and the stderr of a command, the rest is discarded
+type execOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer
+func (o *execOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if room := execOutputLimit - o.buf.Len(); room > 0 {
+		if len(p) > room {
+			o.buf.Write(p[:room])
+		} else {
+			o.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (o *execOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
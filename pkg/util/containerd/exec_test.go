@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecOutput(t *testing.T) {
+	output := &execOutput{}
+	n, err := output.Write([]byte("PONG\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	// The output past the limit is discarded, the writes succeed
+	long := strings.Repeat("x", execOutputLimit)
+	n, err = output.Write([]byte(long))
+	assert.NoError(t, err)
+	assert.Equal(t, execOutputLimit, n)
+	_, err = output.Write([]byte("error"))
+	assert.NoError(t, err)
+	assert.Equal(t, "PONG\n"+long[:execOutputLimit-5], output.String())
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+// containerdExpvars are served under /debug/vars on the expvar port of the
+// agent, to monitor the utils from outside of the agent
+var containerdExpvars = expvar.NewMap("containerd")
+
+func init() {
+	containerdExpvars.Set("Utils", expvar.Func(func() interface{} {
+		return UtilStates()
+	}))
+}
+
+// MethodError is the last error returned by a method of the containerd API
+type MethodError struct {
+	Time  time.Time
+	Error string
+}
+
+// UtilState is the internal state of a util, published in the expvars
+type UtilState struct {
+	SocketPath string
+	Namespace  string
+	// Connected is set if the util is connected and the daemon serving
+	Connected bool
+	// LastError is the error of the last connection attempt or health
+	// probe, empty if they succeeded
+	LastError string
+	// CachedContainers is the size of the container metadata cache, -1 if
+	// the cache is not used
+	CachedContainers int
+	// LastEvent is the reception time of the last event of the cache, and
+	// EventLag the time it took to reach the agent since it was emitted
+	LastEvent time.Time
+	EventLag  time.Duration
+	// MethodErrors are the last errors of the gRPC methods, by full method
+	// name, eg. /containerd.services.tasks.v1.Tasks/Metrics
+	MethodErrors map[string]MethodError
+	// Calls counts the calls of the gRPC methods, by full method name and
+	// status code, eg. OK or DeadlineExceeded
+	Calls map[string]map[string]uint64
+}
+
+// utilStats holds the state of a util published in the expvars which is
+// not kept by the util otherwise
+type utilStats struct {
+	mu           sync.Mutex
+	cache        *ContainerCache
+	lastEvent    time.Time
+	eventLag     time.Duration
+	methodErrors map[string]MethodError
+	calls        map[string]map[string]uint64
+}
+
+func (s *utilStats) setCache(cache *ContainerCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = cache
+}
+
+// recordEvent records the reception of an event emitted at emittedAt
+func (s *utilStats) recordEvent(emittedAt time.Time) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastEvent = now
+	s.eventLag = now.Sub(emittedAt)
+}
+
+func (s *utilStats) recordMethodError(method string, err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.methodErrors == nil {
+		s.methodErrors = make(map[string]MethodError)
+	}
+	s.methodErrors[method] = MethodError{Time: time.Now(), Error: err.Error()}
+}
+
+// recordCall counts a call by status code, and records its error
+func (s *utilStats) recordCall(method string, err error) {
+	code := status.Code(err).String()
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = make(map[string]map[string]uint64)
+	}
+	if s.calls[method] == nil {
+		s.calls[method] = make(map[string]uint64)
+	}
+	s.calls[method][code]++
+	s.mu.Unlock()
+	s.recordMethodError(method, err)
+}
+
+// dialOptions returns the interceptors counting the calls and recording
+// their errors
+func (s *utilStats) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			s.recordCall(method, err)
+			return err
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			s.recordCall(method, err)
+			return stream, err
+		}),
+	}
+}
+
+// State returns the internal state of the util
+func (c *ContainerdUtil) State() UtilState {
+	state := UtilState{
+		SocketPath:       c.socketPath,
+		Namespace:        c.namespace,
+		CachedContainers: -1,
+	}
+	c.healthMux.RLock()
+	err := c.connectErr
+	if err == nil {
+		err = c.healthErr
+	}
+	c.healthMux.RUnlock()
+	state.Connected = err == nil && c.initRetry.RetryStatus() == retry.OK
+	if err != nil {
+		state.LastError = err.Error()
+	}
+
+	c.stats.mu.Lock()
+	cache := c.stats.cache
+	state.LastEvent = c.stats.lastEvent
+	state.EventLag = c.stats.eventLag
+	if len(c.stats.methodErrors) > 0 {
+		state.MethodErrors = make(map[string]MethodError, len(c.stats.methodErrors))
+		for method, e := range c.stats.methodErrors {
+			state.MethodErrors[method] = e
+		}
+	}
+	if len(c.stats.calls) > 0 {
+		state.Calls = make(map[string]map[string]uint64, len(c.stats.calls))
+		for method, codes := range c.stats.calls {
+			state.Calls[method] = make(map[string]uint64, len(codes))
+			for code, count := range codes {
+				state.Calls[method][code] = count
+			}
+		}
+	}
+	c.stats.mu.Unlock()
+	if cache != nil {
+		state.CachedContainers = cache.Len()
+	}
+	return state
+}
+
+// UtilStates returns the state of the shared utils, by socket path and
+// namespace
+func UtilStates() []UtilState {
+	utils := globalProvider.sharedUtils()
+	states := make([]UtilState, 0, len(utils))
+	for _, util := range utils {
+		states = append(states, util.State())
+	}
+	return states
+}
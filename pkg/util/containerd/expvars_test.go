@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUtilState(t *testing.T) {
+	opts := Options{SocketPath: "/run/containerd/expvars.sock", Namespace: "k8s.io"}
+	c := newContainerdUtil(opts)
+
+	// The util never connected
+	state := c.State()
+	assert.Equal(t, "/run/containerd/expvars.sock", state.SocketPath)
+	assert.Equal(t, "k8s.io", state.Namespace)
+	assert.False(t, state.Connected)
+	assert.Equal(t, -1, state.CachedContainers)
+	assert.Nil(t, state.MethodErrors)
+
+	c.healthMux.Lock()
+	c.connectErr = &Error{Kind: ErrNotServing, Err: errors.New("connection refused")}
+	c.healthMux.Unlock()
+	cache := NewContainerCache(c, time.Minute)
+	cache.containers = map[string]CachedContainer{"redis": newCachedContainer(containers.Container{ID: "redis"})}
+	c.stats.setCache(cache)
+	c.stats.recordEvent(time.Now().Add(-2 * time.Second))
+	c.stats.recordCall("/containerd.services.tasks.v1.Tasks/Metrics", status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+	c.stats.recordCall("/containerd.services.tasks.v1.Tasks/Metrics", nil)
+	c.stats.recordCall("/containerd.services.version.v1.Version/Version", nil)
+
+	state = c.State()
+	assert.False(t, state.Connected)
+	assert.Equal(t, "connection refused", state.LastError)
+	assert.Equal(t, 1, state.CachedContainers)
+	assert.True(t, state.EventLag >= 2*time.Second)
+	assert.False(t, state.LastEvent.IsZero())
+	require.Len(t, state.MethodErrors, 1)
+	assert.Equal(t, "rpc error: code = DeadlineExceeded desc = deadline exceeded", state.MethodErrors["/containerd.services.tasks.v1.Tasks/Metrics"].Error)
+	assert.Equal(t, map[string]map[string]uint64{
+		"/containerd.services.tasks.v1.Tasks/Metrics":     {"OK": 1, "DeadlineExceeded": 1},
+		"/containerd.services.version.v1.Version/Version": {"OK": 1},
+	}, state.Calls)
+
+	// The shared utils are published in the expvars
+	globalProvider.mu.Lock()
+	globalProvider.utils[opts.key()] = c
+	globalProvider.mu.Unlock()
+	defer globalProvider.forget(func(util *ContainerdUtil) bool { return util == c })
+	var published []UtilState
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("containerd").(*expvar.Map).Get("Utils").String()), &published))
+	var found bool
+	for _, s := range published {
+		if s.SocketPath == opts.SocketPath {
+			found = true
+			assert.Equal(t, 1, s.CachedContainers)
+			assert.Equal(t, "connection refused", s.LastError)
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"time"
+
+	"github.com/containerd/containerd/events"
+)
+
+const (
+	// FlareEventHistory is the number of containerd events kept for the flare
+	FlareEventHistory = 50
+	// FlareConnectionHistory is the number of connection attempts kept for the flare
+	FlareConnectionHistory = 20
+)
+
+// RecordedEvent is a containerd event received by the agent
+type RecordedEvent struct {
+	Timestamp time.Time
+	Namespace string
+	Topic     string
+}
+
+// ConnectionAttempt is an attempt of a util to connect to containerd
+type ConnectionAttempt struct {
+	Start      time.Time
+	SocketPath string
+	Namespace  string
+	Duration   time.Duration
+	// Err is the error of the attempt, empty if it succeeded
+	Err string
+}
+
+var (
+	recordedEvents     = newHistory(FlareEventHistory)
+	connectionAttempts = newHistory(FlareConnectionHistory)
+)
+
+// RecordEvent keeps the event for the flare, the last FlareEventHistory
+// events are kept
+func RecordEvent(envelope *events.Envelope) {
+	if envelope == nil {
+		return
+	}
+	recordedEvents.add(RecordedEvent{
+		Timestamp: envelope.Timestamp,
+		Namespace: envelope.Namespace,
+		Topic:     envelope.Topic,
+	})
+}
+
+// RecordedEvents returns the last events received by the agent, from the
+// oldest to the latest
+func RecordedEvents() []RecordedEvent {
+	items := recordedEvents.list()
+	evs := make([]RecordedEvent, 0, len(items))
+	for _, item := range items {
+		evs = append(evs, item.(RecordedEvent))
+	}
+	return evs
+}
+
+// recordConnectionAttempt keeps the attempt for the flare
+func (c *ContainerdUtil) recordConnectionAttempt(start time.Time, err error) {
+	attempt := ConnectionAttempt{
+		Start:      start,
+		SocketPath: c.socketPath,
+		Namespace:  c.namespace,
+		Duration:   time.Since(start),
+	}
+	if err != nil {
+		attempt.Err = err.Error()
+	}
+	connectionAttempts.add(attempt)
+}
+
+// ConnectionAttempts returns the last connection attempts of the utils,
+// from the oldest to the latest
+func ConnectionAttempts() []ConnectionAttempt {
+	items := connectionAttempts.list()
+	attempts := make([]ConnectionAttempt, 0, len(items))
+	for _, item := range items {
+		attempts = append(attempts, item.(ConnectionAttempt))
+	}
+	return attempts
+}
+
+// NamespaceDiagnostics is the state of a collected namespace
+type NamespaceDiagnostics struct {
+	Name       string
+	Containers int
+	// Err is the error listing the containers of the namespace
+	Err string
+}
+
+// Diagnostics is the state of the containerd runtime included in the flare
+type Diagnostics struct {
+	SocketPath string
+	Version    string
+	Revision   string
+	Namespaces []NamespaceDiagnostics
+	// Err is the error connecting to containerd, the other fields but
+	// SocketPath are empty if it is set
+	Err string
+}
+
+// GetDiagnostics returns the resolved socket path, the version of the
+// daemon and the number of containers of every collected namespace.
+// Connection errors are reported in the returned Diagnostics.
+func GetDiagnostics() *Diagnostics {
+	d := &Diagnostics{SocketPath: globalProvider.ConfigOptions().SocketPath}
+	cu, err := GetContainerdUtil(nil)
+	if err != nil {
+		d.Err = err.Error()
+		return d
+	}
+	utils, err := GetNamespacedUtils()
+	if err != nil {
+		d.Err = err.Error()
+		return d
+	}
+	if err := d.collect(cu, utils); err != nil {
+		d.Err = err.Error()
+	}
+	return d
+}
+
+// collect fills the version of the daemon and the namespaces of utils
+func (d *Diagnostics) collect(cu ContainerdItf, utils []ContainerdItf) error {
+	v, err := cu.Metadata()
+	if err != nil {
+		return err
+	}
+	d.Version = v.Version
+	d.Revision = v.Revision
+
+	for _, nsUtil := range utils {
+		ns := NamespaceDiagnostics{Name: nsUtil.Namespace()}
+		if ctns, err := nsUtil.CachedContainers(); err != nil {
+			ns.Err = err.Error()
+		} else {
+			ns.Containers = len(ctns)
+		}
+		d.Namespaces = append(d.Namespaces, ns)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordEvent(t *testing.T) {
+	ts := time.Now()
+	for i := 0; i < FlareEventHistory+2; i++ {
+		RecordEvent(&events.Envelope{Timestamp: ts, Namespace: "k8s.io", Topic: fmt.Sprintf("/tasks/%d", i)})
+	}
+	RecordEvent(nil)
+
+	evs := RecordedEvents()
+	require.Len(t, evs, FlareEventHistory)
+	assert.Equal(t, RecordedEvent{Timestamp: ts, Namespace: "k8s.io", Topic: "/tasks/2"}, evs[0])
+	assert.Equal(t, "/tasks/51", evs[FlareEventHistory-1].Topic)
+}
+
+func TestRecordConnectionAttempt(t *testing.T) {
+	c := newContainerdUtil(Options{SocketPath: "/run/containerd/containerd.sock", Namespace: "k8s.io"})
+	c.recordConnectionAttempt(time.Now(), &Error{Kind: ErrNotServing, Err: errors.New("connection refused")})
+	c.recordConnectionAttempt(time.Now(), nil)
+
+	attempts := ConnectionAttempts()
+	require.True(t, len(attempts) >= 2)
+	failed, succeeded := attempts[len(attempts)-2], attempts[len(attempts)-1]
+	assert.Equal(t, "/run/containerd/containerd.sock", failed.SocketPath)
+	assert.Equal(t, "k8s.io", failed.Namespace)
+	assert.Equal(t, "connection refused", failed.Err)
+	assert.Empty(t, succeeded.Err)
+}
+
+func TestDiagnosticsCollect(t *testing.T) {
+	cu := &mockItf{
+		mockMetadata: func() (containerd.Version, error) {
+			return containerd.Version{Version: "v1.4.3", Revision: "269548fa"}, nil
+		},
+	}
+	utils := []ContainerdItf{
+		&mockItf{
+			mockNamespace: func() string { return "default" },
+			mockCachedContainers: func() ([]CachedContainer, error) {
+				return []CachedContainer{{ID: "redis"}, {ID: "nginx"}}, nil
+			},
+		},
+		&mockItf{
+			mockNamespace: func() string { return "k8s.io" },
+			mockCachedContainers: func() ([]CachedContainer, error) {
+				return nil, errors.New("context deadline exceeded")
+			},
+		},
+	}
+
+	d := &Diagnostics{SocketPath: "/run/containerd/containerd.sock"}
+	require.NoError(t, d.collect(cu, utils))
+	assert.Equal(t, &Diagnostics{
+		SocketPath: "/run/containerd/containerd.sock",
+		Version:    "v1.4.3",
+		Revision:   "269548fa",
+		Namespaces: []NamespaceDiagnostics{
+			{Name: "default", Containers: 2},
+			{Name: "k8s.io", Err: "context deadline exceeded"},
+		},
+	}, d)
+}
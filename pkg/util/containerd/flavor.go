@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+// Flavor is a distribution of containerd, whose socket, configuration and
+// state paths can differ from the ones of the upstream daemon, like the
+// containerd embedded in k3s. They all run the containers of Kubernetes in
+// the k8s.io namespace, with the labels of the upstream CRI plugin.
+type Flavor string
+
+// Flavors of containerd, FlavorAuto detects the flavor from the socket
+const (
+	FlavorAuto         Flavor = "auto"
+	FlavorUpstream     Flavor = "upstream"
+	FlavorK3s          Flavor = "k3s"
+	FlavorBottlerocket Flavor = "bottlerocket"
+)
+
+// flavorPaths are the conventional paths of a flavor of containerd
+type flavorPaths struct {
+	socketPath string
+	configPath string
+	// stateDir is the state directory of the daemon, holding the bundles of
+	// the tasks run by the v2 shims
+	stateDir string
+	// explicitOnly is set if the socket can be served by another runtime, the
+	// socket is then neither discovered nor detected as this flavor
+	explicitOnly bool
+}
+
+// IsKnown returns whether the flavor is auto or a flavor of the platform
+func (f Flavor) IsKnown() bool {
+	_, found := flavors[f]
+	return f == FlavorAuto || found
+}
+
+// paths returns the paths of the flavor, the upstream ones if the flavor is
+// unknown or auto
+func (f Flavor) paths() flavorPaths {
+	if paths, found := flavors[f]; found {
+		return paths
+	}
+	return flavors[FlavorUpstream]
+}
+
+// socketFlavor returns the flavor listening on a socket, upstream if it is
+// not the socket of another detectable flavor
+func socketFlavor(path string) Flavor {
+	for flavor, paths := range flavors {
+		if flavor != FlavorUpstream && !paths.explicitOnly && paths.socketPath == path {
+			return flavor
+		}
+	}
+	return FlavorUpstream
+}
@@ -0,0 +1,289 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Provider hands out the utils shared by the consumers, one per socket path
+// and namespace, so that callers targeting different endpoints don't
+// override each other's settings. The agent uses the DefaultProvider, whose
+// utils are kept up to date with the configuration, through
+// GetContainerdUtil. A Provider can be reset to connect again from scratch,
+// and set up with the utils of the tests.
+type Provider struct {
+	mu    sync.Mutex
+	utils map[string]*ContainerdUtil
+	// relay builds the utils of the options with ViaClusterAgent, see
+	// SetRelay, they are shared by namespace in relays
+	relay  func(Options) ContainerdItf
+	relays map[string]ContainerdItf
+	// resolvers and cgroupIndexes cache the containers of a util, they are
+	// dropped with it
+	resolvers     map[ContainerdItf]*ContainerResolver
+	cgroupIndexes map[ContainerdItf]*CgroupIndex
+	// config holds the options of the agent configuration, see ConfigOptions
+	config *Options
+	// fakes are returned instead of the utils if set, see SetForTests
+	fakes []ContainerdItf
+}
+
+var globalProvider = NewProvider()
+
+// NewProvider returns an empty Provider
+func NewProvider() *Provider {
+	return &Provider{
+		utils:         make(map[string]*ContainerdUtil),
+		relays:        make(map[string]ContainerdItf),
+		resolvers:     make(map[ContainerdItf]*ContainerResolver),
+		cgroupIndexes: make(map[ContainerdItf]*CgroupIndex),
+	}
+}
+
+// DefaultProvider returns the Provider of the agent, the one of
+// GetContainerdUtil
+func DefaultProvider() *Provider {
+	return globalProvider
+}
+
+// GetContainerdUtil returns a ready to use ContainerdItf from the
+// DefaultProvider, see Provider.Get.
+func GetContainerdUtil(opts *Options) (ContainerdItf, error) {
+	return globalProvider.Get(opts)
+}
+
+// Get returns a ready to use ContainerdItf, shared with the other callers
+// of the same options, see Options.key.
+// If opts is nil, the options of the agent configuration are used, see
+// ConfigOptions, and the util is closed when the socket or namespace of the
+// configuration change. The long-lived consumers get a new util when it is closed.
+// If opts.ViaClusterAgent is set, the util built by the relay is returned,
+// see SetRelay.
+// The first util returned closes the Ready channel.
+func (p *Provider) Get(opts *Options) (ContainerdItf, error) {
+	if fake, found := p.fake(opts); found {
+		return fake, nil
+	}
+	var o Options
+	if opts == nil {
+		o = p.ConfigOptions()
+	} else {
+		o = opts.withDefaults()
+	}
+	if o.ViaClusterAgent {
+		util, err := p.relayUtil(o)
+		if err != nil {
+			return nil, err
+		}
+		globalReadiness.markReady()
+		return util, nil
+	}
+
+	p.mu.Lock()
+	util, found := p.utils[o.key()]
+	if !found {
+		util = newContainerdUtil(o)
+		p.utils[o.key()] = util
+	}
+	p.mu.Unlock()
+
+	if err := util.EnsureConnected(); err != nil {
+		return nil, err
+	}
+	globalReadiness.markReady()
+	return util, nil
+}
+
+// ConfigOptions returns the options of the AgentConfig with their defaults.
+// They are resolved once, as the discovery of the socket probes the
+// well-known sockets, and switched by UpdateConfig when the endpoint
+// changes, or resolved again after a Reset.
+func (p *Provider) ConfigOptions() Options {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config == nil {
+		o := agentConfig.Options().withDefaults()
+		p.config = &o
+	}
+	return *p.config
+}
+
+// NamespaceUtil returns the util of the socket of the agent configuration
+// bound to namespace, see Get
+func (p *Provider) NamespaceUtil(namespace string) (ContainerdItf, error) {
+	opts := p.ConfigOptions()
+	opts.Namespace = namespace
+	return p.Get(&opts)
+}
+
+// Resolver returns the ContainerResolver of the util of opts, see Get
+func (p *Provider) Resolver(opts *Options) (*ContainerResolver, error) {
+	util, err := p.Get(opts)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, found := p.resolvers[util]
+	if !found {
+		r = NewContainerResolver(util)
+		r.Start()
+		p.resolvers[util] = r
+	}
+	return r, nil
+}
+
+// CgroupIndex returns the CgroupIndex of the util of opts, see Get
+func (p *Provider) CgroupIndex(opts *Options) (*CgroupIndex, error) {
+	util, err := p.Get(opts)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	idx, found := p.cgroupIndexes[util]
+	if !found {
+		idx = NewCgroupIndex(util)
+		p.cgroupIndexes[util] = idx
+	}
+	return idx, nil
+}
+
+// SetRelay sets the function building the utils of the options with
+// ViaClusterAgent, serving the container metadata relayed by the
+// cluster-agent. The relay lives outside of the package, which does not
+// depend on the cluster-agent client.
+func (p *Provider) SetRelay(relay func(Options) ContainerdItf) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.relay = relay
+}
+
+// relayUtil returns the shared relayed util of the namespace of o
+func (p *Provider) relayUtil(o Options) (ContainerdItf, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if u, found := p.relays[o.Namespace]; found {
+		return u, nil
+	}
+	if p.relay == nil {
+		return nil, &Error{Kind: ErrUnsupported, Err: fmt.Errorf("the containers relayed by the cluster-agent are not available in this process")}
+	}
+	u := p.relay(o)
+	p.relays[o.Namespace] = u
+	return u, nil
+}
+
+// Reset closes and forgets the utils handed out, with their resolvers and
+// cgroup indexes, the next calls to Get resolve the configuration and
+// connect again, eg. once the socket of a fatal error is fixed. The
+// consumers holding a util get an error from it, and get a new one.
+func (p *Provider) Reset() {
+	p.mu.Lock()
+	utils, relays, resolvers := p.utils, p.relays, p.resolvers
+	p.utils = make(map[string]*ContainerdUtil)
+	p.relays = make(map[string]ContainerdItf)
+	p.resolvers = make(map[ContainerdItf]*ContainerResolver)
+	p.cgroupIndexes = make(map[ContainerdItf]*CgroupIndex)
+	p.config = nil
+	p.mu.Unlock()
+	for _, r := range resolvers {
+		r.Stop()
+	}
+	for _, relay := range relays {
+		relay.Close()
+	}
+	for _, util := range utils {
+		if err := util.Close(); err != nil {
+			util.log.Debugf("Cannot close the containerd client of %s: %s", util.socketPath, err)
+		}
+	}
+}
+
+// SetForTests makes Get return the fake of the namespace of its options,
+// or the first fake if none is bound to it, instead of connecting to
+// containerd. It returns the function restoring the Provider.
+func (p *Provider) SetForTests(fakes ...ContainerdItf) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous := p.fakes
+	p.fakes = fakes
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.fakes = previous
+	}
+}
+
+// fake returns the fake set by SetForTests for opts
+func (p *Provider) fake(opts *Options) (ContainerdItf, bool) {
+	p.mu.Lock()
+	fakes := p.fakes
+	p.mu.Unlock()
+	if len(fakes) == 0 {
+		return nil, false
+	}
+	var namespace string
+	if opts != nil {
+		namespace = opts.Namespace
+	} else {
+		namespace = agentConfig.Options().Namespace
+	}
+	for _, fake := range fakes {
+		if fake.Namespace() == namespace {
+			return fake, true
+		}
+	}
+	return fakes[0], true
+}
+
+// forget removes the utils matching match from the Provider, stops their
+// resolvers and drops their cgroup indexes. It returns the utils, for the
+// caller to close them.
+func (p *Provider) forget(match func(*ContainerdUtil) bool) []*ContainerdUtil {
+	p.mu.Lock()
+	var forgotten []*ContainerdUtil
+	var resolvers []*ContainerResolver
+	for key, util := range p.utils {
+		if !match(util) {
+			continue
+		}
+		forgotten = append(forgotten, util)
+		delete(p.utils, key)
+		if r, found := p.resolvers[util]; found {
+			resolvers = append(resolvers, r)
+			delete(p.resolvers, util)
+		}
+		delete(p.cgroupIndexes, util)
+	}
+	p.mu.Unlock()
+	for _, r := range resolvers {
+		r.Stop()
+	}
+	return forgotten
+}
+
+// sharedUtils returns the utils of the Provider, sorted by socket path and
+// namespace
+func (p *Provider) sharedUtils() []*ContainerdUtil {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]string, 0, len(p.utils))
+	for key := range p.utils {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	utils := make([]*ContainerdUtil, 0, len(keys))
+	for _, key := range keys {
+		utils = append(utils, p.utils[key])
+	}
+	return utils
+}
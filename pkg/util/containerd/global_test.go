@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderSetForTests(t *testing.T) {
+	provider := NewProvider()
+	k8s := &mockItf{mockNamespace: func() string { return "k8s.io" }}
+	moby := &mockItf{mockNamespace: func() string { return "moby" }}
+	restore := provider.SetForTests(k8s, moby)
+
+	cu, err := provider.Get(&Options{Namespace: "moby"})
+	require.NoError(t, err)
+	assert.Equal(t, moby, cu)
+	// The first fake is returned for the other namespaces
+	cu, err = provider.Get(&Options{Namespace: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, k8s, cu)
+
+	utils, err := provider.NamespacedUtils(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerdItf{k8s, moby}, utils)
+
+	restore()
+	assert.Empty(t, provider.fakes)
+	assert.Empty(t, provider.utils)
+}
+
+func TestProviderReset(t *testing.T) {
+	provider := NewProvider()
+	opts := Options{SocketPath: "/var/run/containerd/containerd.sock", Namespace: "k8s.io"}.withDefaults()
+	util := newContainerdUtil(opts)
+	provider.utils[opts.key()] = util
+	resolver := NewContainerResolver(util)
+	provider.resolvers[util] = resolver
+	provider.cgroupIndexes[util] = NewCgroupIndex(util)
+	provider.config = &opts
+	provider.SetRelay(func(o Options) ContainerdItf { return &closeRecorder{} })
+	relayOpts := opts
+	relayOpts.ViaClusterAgent = true
+	relay, err := provider.Get(&relayOpts)
+	require.NoError(t, err)
+
+	provider.Reset()
+	assert.Empty(t, provider.utils)
+	assert.True(t, isClosed(util))
+	// The caches of the util are dropped with it
+	assert.Empty(t, provider.resolvers)
+	assert.Empty(t, provider.cgroupIndexes)
+	_, open := <-resolver.stop
+	assert.False(t, open)
+	// The configuration is resolved again
+	assert.Nil(t, provider.config)
+	// The relayed utils are closed and built again too
+	assert.True(t, relay.(*closeRecorder).closed)
+	next, err := provider.Get(&relayOpts)
+	require.NoError(t, err)
+	assert.NotSame(t, relay, next)
+}
+
+func TestProviderRelay(t *testing.T) {
+	provider := NewProvider()
+	opts := Options{Namespace: "k8s.io", ViaClusterAgent: true}
+	// The relayed utils are only available if a relay is set
+	_, err := provider.Get(&opts)
+	assert.Equal(t, ErrUnsupported, ErrorKind(err))
+
+	var built []string
+	provider.SetRelay(func(o Options) ContainerdItf {
+		built = append(built, o.Namespace)
+		return &closeRecorder{}
+	})
+	relay, err := provider.Get(&opts)
+	require.NoError(t, err)
+	shared, err := provider.Get(&opts)
+	require.NoError(t, err)
+	assert.Same(t, relay, shared)
+	assert.Equal(t, []string{"k8s.io"}, built)
+}
+
+// closeRecorder is a ContainerdItf recording whether it was closed
+type closeRecorder struct {
+	mockItf
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestProviderConfigOptions(t *testing.T) {
+	provider := NewProvider()
+	resolved := Options{SocketPath: "/run/k3s/containerd/containerd.sock", Namespace: "k8s.io"}.withDefaults()
+	provider.config = &resolved
+	// The resolved options are reused, the socket is not discovered again
+	assert.Equal(t, resolved, provider.ConfigOptions())
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/containerd/oci"
+)
+
+const (
+	// nvidiaVisibleDevicesEnv lists the GPUs exposed to a container by the
+	// NVIDIA container runtime, by index or UUID
+	nvidiaVisibleDevicesEnv = "NVIDIA_VISIBLE_DEVICES"
+	// cdiAnnotationPrefix prefixes the CDI annotations set by the device
+	// plugins, eg. cdi.k8s.io/nvidia-device-plugin: nvidia.com/gpu=0
+	cdiAnnotationPrefix = "cdi.k8s.io/"
+	// nvidiaCDIKind is the CDI kind of the NVIDIA GPUs
+	nvidiaCDIKind = "nvidia.com/gpu="
+	// nvidiaGPUMajor is the major number of the /dev/nvidia<index> devices,
+	// the minor numbers above nvidiaMaxGPUMinor are the control devices,
+	// like /dev/nvidiactl
+	nvidiaGPUMajor    = 195
+	nvidiaMaxGPUMinor = 253
+)
+
+// GPUDevices are the NVIDIA GPUs allocated to a container, to join the
+// metrics of the nvml check with the ones of the container
+type GPUDevices struct {
+	// UUIDs are the GPU-<uuid> or MIG-<uuid> identifiers of the GPUs
+	UUIDs []string
+	// Indexes are the indexes of the GPUs, the minor numbers of their
+	// /dev/nvidia<index> device
+	Indexes []string
+}
+
+// Empty returns whether no GPU is allocated
+func (g GPUDevices) Empty() bool {
+	return len(g.UUIDs) == 0 && len(g.Indexes) == 0
+}
+
+// SpecGPUDevices returns the GPUs allocated to a container from its spec:
+// the NVIDIA_VISIBLE_DEVICES environment variable, the nvidia.com/gpu CDI
+// annotations and the device cgroup rules. The GPUs exposed as "all" are
+// not reported, they are not known from the spec.
+func SpecGPUDevices(spec *oci.Spec) GPUDevices {
+	if spec == nil {
+		return GPUDevices{}
+	}
+	uuids := make(map[string]struct{})
+	indexes := make(map[string]struct{})
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		switch {
+		case id == "" || id == "all" || id == "none" || id == "void":
+		case isIndex(id):
+			indexes[id] = struct{}{}
+		default:
+			uuids[id] = struct{}{}
+		}
+	}
+
+	if spec.Process != nil {
+		for _, env := range spec.Process.Env {
+			if value := strings.TrimPrefix(env, nvidiaVisibleDevicesEnv+"="); value != env {
+				for _, id := range strings.Split(value, ",") {
+					add(id)
+				}
+			}
+		}
+	}
+	for name, value := range spec.Annotations {
+		if !strings.HasPrefix(name, cdiAnnotationPrefix) {
+			continue
+		}
+		for _, device := range strings.Split(value, ",") {
+			if device = strings.TrimSpace(device); strings.HasPrefix(device, nvidiaCDIKind) {
+				add(strings.TrimPrefix(device, nvidiaCDIKind))
+			}
+		}
+	}
+	if spec.Linux != nil && spec.Linux.Resources != nil {
+		for _, rule := range spec.Linux.Resources.Devices {
+			// The wildcard rules, eg. of the privileged containers, do
+			// not tell the GPUs
+			if !rule.Allow || rule.Type != "c" || rule.Major == nil || rule.Minor == nil {
+				continue
+			}
+			if *rule.Major == nvidiaGPUMajor && *rule.Minor >= 0 && *rule.Minor <= nvidiaMaxGPUMinor {
+				add(strconv.FormatInt(*rule.Minor, 10))
+			}
+		}
+	}
+
+	return GPUDevices{UUIDs: sortedKeys(uuids), Indexes: sortedKeys(indexes)}
+}
+
+func isIndex(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 32)
+	return err == nil
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecGPUDevices(t *testing.T) {
+	number := func(v int64) *int64 { return &v }
+
+	for _, tc := range []struct {
+		name     string
+		spec     *oci.Spec
+		expected GPUDevices
+	}{
+		{
+			name: "no spec",
+		},
+		{
+			name: "no GPU",
+			spec: &oci.Spec{Process: &specs.Process{Env: []string{"PATH=/usr/bin"}}},
+		},
+		{
+			name: "environment",
+			spec: &oci.Spec{Process: &specs.Process{Env: []string{
+				"NVIDIA_VISIBLE_DEVICES=0, GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3,MIG-5c6d7e8f-1a2b-3c4d-5e6f-7a8b9c0d1e2f",
+			}}},
+			expected: GPUDevices{
+				UUIDs:   []string{"GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3", "MIG-5c6d7e8f-1a2b-3c4d-5e6f-7a8b9c0d1e2f"},
+				Indexes: []string{"0"},
+			},
+		},
+		{
+			name: "all",
+			spec: &oci.Spec{Process: &specs.Process{Env: []string{"NVIDIA_VISIBLE_DEVICES=all"}}},
+		},
+		{
+			name: "CDI annotations",
+			spec: &oci.Spec{Annotations: map[string]string{
+				"cdi.k8s.io/nvidia-device-plugin_uuid": "nvidia.com/gpu=GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3,nvidia.com/gpu=2",
+				"cdi.k8s.io/other":                     "vendor.com/fpga=0",
+			}},
+			expected: GPUDevices{
+				UUIDs:   []string{"GPU-8a1e4f1c-2b3d-4e5f-a6b7-c8d9e0f1a2b3"},
+				Indexes: []string{"2"},
+			},
+		},
+		{
+			name: "device cgroup rules",
+			spec: &oci.Spec{Linux: &specs.Linux{Resources: &specs.LinuxResources{Devices: []specs.LinuxDeviceCgroup{
+				{Allow: false, Access: "rwm"},
+				{Allow: true, Type: "c", Major: number(195), Minor: number(1), Access: "rw"},
+				{Allow: true, Type: "c", Major: number(195), Minor: number(0), Access: "rw"},
+				// nvidiactl
+				{Allow: true, Type: "c", Major: number(195), Minor: number(255), Access: "rw"},
+				{Allow: true, Type: "c", Major: number(1), Minor: number(3), Access: "rwm"},
+				{Allow: true, Type: "c", Major: number(195), Access: "rw"},
+			}}}},
+			expected: GPUDevices{Indexes: []string{"0", "1"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gpus := SpecGPUDevices(tc.spec)
+			assert.Equal(t, tc.expected, gpus)
+			assert.Equal(t, tc.expected.Empty(), gpus.Empty())
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/namespaces"
+	"google.golang.org/grpc"
+)
+
+// GRPCCall is a gRPC call made to the containerd API
+type GRPCCall struct {
+	Start     time.Time
+	Method    string
+	Namespace string
+	Duration  time.Duration
+	// Err is the error returned by the call, empty if it succeeded
+	Err string
+	// Stream is set for the streaming calls, eg. the event subscriptions,
+	// whose duration is the one of the stream setup
+	Stream bool
+}
+
+// grpcCallLog keeps the last gRPC calls
+type grpcCallLog struct {
+	calls *history
+}
+
+func newGRPCCallLog(size int) *grpcCallLog {
+	return &grpcCallLog{calls: newHistory(size)}
+}
+
+func (l *grpcCallLog) add(call GRPCCall) {
+	l.calls.add(call)
+}
+
+// list returns the calls from the oldest to the latest
+func (l *grpcCallLog) list() []GRPCCall {
+	items := l.calls.list()
+	calls := make([]GRPCCall, 0, len(items))
+	for _, item := range items {
+		calls = append(calls, item.(GRPCCall))
+	}
+	return calls
+}
+
+// The calls of every util are kept in the same log, sized by the options of
+// the first util enabling DebugGRPC
+var (
+	grpcCalls    *grpcCallLog
+	grpcCallsMux sync.Mutex
+)
+
+// GRPCCalls returns the last gRPC calls made to containerd, from the oldest
+// to the latest, for the flare. It is empty unless containerd_debug_grpc is
+// enabled.
+func GRPCCalls() []GRPCCall {
+	grpcCallsMux.Lock()
+	calls := grpcCalls
+	grpcCallsMux.Unlock()
+	if calls == nil {
+		return nil
+	}
+	return calls.list()
+}
+
+// grpcDebugInterceptors returns the dial options logging the gRPC calls
+func (c *ContainerdUtil) grpcDebugInterceptors() []grpc.DialOption {
+	grpcCallsMux.Lock()
+	if grpcCalls == nil {
+		grpcCalls = newGRPCCallLog(c.grpcCallHistory)
+	}
+	r := &grpcCallRecorder{calls: grpcCalls, log: c.log}
+	grpcCallsMux.Unlock()
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(r.intercept),
+		grpc.WithChainStreamInterceptor(r.interceptStream),
+	}
+}
+
+// grpcCallRecorder logs the gRPC calls and adds them to a grpcCallLog
+type grpcCallRecorder struct {
+	calls *grpcCallLog
+	log   Logger
+}
+
+func (r *grpcCallRecorder) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	r.record(ctx, method, start, false, err)
+	return err
+}
+
+func (r *grpcCallRecorder) interceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	r.record(ctx, method, start, true, err)
+	return stream, err
+}
+
+func (r *grpcCallRecorder) record(ctx context.Context, method string, start time.Time, stream bool, err error) {
+	call := GRPCCall{
+		Start:    start,
+		Method:   method,
+		Duration: time.Since(start),
+		Stream:   stream,
+	}
+	call.Namespace, _ = namespaces.Namespace(ctx)
+	if err != nil {
+		call.Err = err.Error()
+	}
+	r.calls.add(call)
+	withFields(r.log, "grpc_method", method).Debugf("containerd gRPC call in namespace %q took %s, error: %v", call.Namespace, call.Duration, err)
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/containerd/containerd/namespaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestGRPCCallLog(t *testing.T) {
+	l := newGRPCCallLog(3)
+	assert.Empty(t, l.list())
+
+	for _, method := range []string{"a", "b"} {
+		l.add(GRPCCall{Method: method})
+	}
+	assert.Equal(t, []GRPCCall{{Method: "a"}, {Method: "b"}}, l.list())
+
+	// The oldest calls are overwritten
+	for _, method := range []string{"c", "d", "e"} {
+		l.add(GRPCCall{Method: method})
+	}
+	assert.Equal(t, []GRPCCall{{Method: "c"}, {Method: "d"}, {Method: "e"}}, l.list())
+}
+
+func TestGRPCCallRecorder(t *testing.T) {
+	r := &grpcCallRecorder{calls: newGRPCCallLog(10), log: agentLogger{}}
+	ctx := namespaces.WithNamespace(context.Background(), "k8s.io")
+
+	err := r.intercept(ctx, "/containerd.services.version.v1.Version/Version", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return nil
+		})
+	require.NoError(t, err)
+	err = r.intercept(ctx, "/containerd.services.tasks.v1.Tasks/Metrics", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return errors.New("context deadline exceeded")
+		})
+	assert.Error(t, err)
+	_, err = r.interceptStream(context.Background(), nil, nil, "/containerd.services.events.v1.Events/Subscribe",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, nil
+		})
+	require.NoError(t, err)
+
+	calls := r.calls.list()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "/containerd.services.version.v1.Version/Version", calls[0].Method)
+	assert.Equal(t, "k8s.io", calls[0].Namespace)
+	assert.Empty(t, calls[0].Err)
+	assert.False(t, calls[0].Start.IsZero())
+	assert.Equal(t, "context deadline exceeded", calls[1].Err)
+	assert.True(t, calls[2].Stream)
+	assert.Empty(t, calls[2].Namespace)
+}
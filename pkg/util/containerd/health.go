@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthStatus is the outcome of a background probe of the daemon health
+type HealthStatus struct {
+	SocketPath string
+	Namespace  string
+	// Err is nil if the daemon is serving
+	Err error
+}
+
+var (
+	healthListeners    []func(HealthStatus)
+	healthListenersMux sync.RWMutex
+)
+
+// RegisterHealthListener registers a function called with the outcome of
+// every background health probe, eg. to report it as a service check.
+func RegisterHealthListener(listener func(HealthStatus)) {
+	healthListenersMux.Lock()
+	defer healthListenersMux.Unlock()
+	healthListeners = append(healthListeners, listener)
+}
+
+func notifyHealthListeners(status HealthStatus) {
+	healthListenersMux.RLock()
+	defer healthListenersMux.RUnlock()
+	for _, listener := range healthListeners {
+		listener(status)
+	}
+}
+
+// Health returns the error of the last health probe, nil if the
+// daemon was serving or if no probe ran yet
+func (c *ContainerdUtil) Health() error {
+	c.healthMux.RLock()
+	defer c.healthMux.RUnlock()
+	return c.healthErr
+}
+
+// startHealthProbe starts probing the daemon health in the background,
+// so that a dead socket is detected between two check runs
+func (c *ContainerdUtil) startHealthProbe() {
+	if c.healthCheckInterval <= 0 {
+		return
+	}
+	c.probeOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(c.healthCheckInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					c.probeHealth()
+				case <-c.stopProbe:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// probeHealth checks the daemon is serving, marks the util degraded if not,
+// and notifies the health listeners
+func (c *ContainerdUtil) probeHealth() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+
+	var err error
+	serving, probeErr := c.isServing(ctx)
+	if probeErr != nil {
+		kind := ErrorKind(classifyError(probeErr))
+		if kind == nil {
+			kind = ErrNotServing
+		}
+		err = &Error{Kind: kind, Err: fmt.Errorf("containerd is unreachable on %s: %s", c.socketPath, probeErr)}
+	} else if !serving {
+		err = &Error{Kind: ErrNotServing, Err: fmt.Errorf("containerd is not serving on %s", c.socketPath)}
+	}
+
+	c.healthMux.Lock()
+	previous := c.healthErr
+	c.healthErr = err
+	c.healthMux.Unlock()
+
+	if err != nil && previous == nil {
+		c.log.Warnf("%s, marking the containerd util degraded", err)
+	} else if err == nil && previous != nil {
+		c.log.Infof("containerd is serving again")
+	}
+
+	notifyHealthListeners(HealthStatus{
+		SocketPath: c.socketPath,
+		Namespace:  c.namespace,
+		Err:        err,
+	})
+}
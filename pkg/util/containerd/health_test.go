@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeHealth(t *testing.T) {
+	var statuses []HealthStatus
+	RegisterHealthListener(func(status HealthStatus) {
+		statuses = append(statuses, status)
+	})
+	defer func() { healthListeners = nil }()
+
+	c := newContainerdUtil(Options{SocketPath: "/run/containerd/containerd.sock", Namespace: "k8s.io"}.withDefaults())
+	serving, probeErr := true, error(nil)
+	c.isServing = func(ctx context.Context) (bool, error) {
+		return serving, probeErr
+	}
+	assert.NoError(t, c.Health())
+
+	c.probeHealth()
+	assert.NoError(t, c.Health())
+
+	probeErr = errors.New("connection refused")
+	c.probeHealth()
+	assert.EqualError(t, c.Health(), "containerd is unreachable on /run/containerd/containerd.sock: connection refused")
+	assert.Equal(t, ErrNotServing, ErrorKind(c.Health()))
+
+	serving, probeErr = false, nil
+	c.probeHealth()
+	assert.EqualError(t, c.Health(), "containerd is not serving on /run/containerd/containerd.sock")
+	assert.True(t, IsTransient(c.Health()))
+
+	serving = true
+	c.probeHealth()
+	assert.NoError(t, c.Health())
+
+	require.Len(t, statuses, 4)
+	assert.Equal(t, "/run/containerd/containerd.sock", statuses[0].SocketPath)
+	assert.Equal(t, "k8s.io", statuses[0].Namespace)
+	assert.NoError(t, statuses[0].Err)
+	assert.Error(t, statuses[1].Err)
+	assert.Error(t, statuses[2].Err)
+	assert.NoError(t, statuses[3].Err)
+}
+
+func TestHealthProbeLoop(t *testing.T) {
+	c := newContainerdUtil(Options{HealthCheckInterval: 10 * time.Millisecond}.withDefaults())
+	probes := make(chan struct{}, 10)
+	c.isServing = func(ctx context.Context) (bool, error) {
+		probes <- struct{}{}
+		return false, nil
+	}
+
+	c.startHealthProbe()
+	c.startHealthProbe()
+	select {
+	case <-probes:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the daemon health was not probed")
+	}
+	assert.Error(t, c.Health())
+
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Close())
+}
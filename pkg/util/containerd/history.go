@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+)
+
+// history is a ring buffer of the last items added to it
+type history struct {
+	mu    sync.Mutex
+	items []interface{}
+	next  int
+	full  bool
+}
+
+func newHistory(size int) *history {
+	return &history{items: make([]interface{}, size)}
+}
+
+func (h *history) add(item interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.items[h.next] = item
+	h.next = (h.next + 1) % len(h.items)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the items from the oldest to the latest
+func (h *history) list() []interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]interface{}(nil), h.items[:h.next]...)
+	}
+	return append(append([]interface{}(nil), h.items[h.next:]...), h.items[:h.next]...)
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// criSandboxIDAnnotation is set by the CRI plugin in the spec of the
+// containers of a pod, to the ID of the sandbox of the pod
+const criSandboxIDAnnotation = "io.kubernetes.cri.sandbox-id"
+
+// nodeNameEnvVars are the variables the downward API of the agent
+// manifests set to the nodeName of the pod spec, in order of precedence
+var nodeNameEnvVars = []string{
+	"DD_KUBERNETES_KUBELET_NODENAME",
+	"KUBERNETES_NODE_NAME",
+	"NODE_NAME",
+}
+
+// NodeName returns the name of the node of the pod of container id, from
+// the spec of the container and of its sandbox: the nodeName of the pod
+// spec, set in the environment of the container by the downward API, or
+// the hostname of the sandbox of the pod when it runs in the network
+// namespace of the host. It backs the containerd hostname provider.
+func NodeName(cu ContainerdItf, id string) (string, error) {
+	spec, err := containerSpec(cu, id)
+	if err != nil {
+		return "", err
+	}
+	if name := nodeNameFromEnv(spec); name != "" {
+		return name, nil
+	}
+	sandboxID := spec.Annotations[criSandboxIDAnnotation]
+	if sandboxID == "" {
+		return "", fmt.Errorf("container %s is not in a pod and has no node name in its environment", id)
+	}
+	sandboxSpec, err := containerSpec(cu, sandboxID)
+	if err != nil {
+		return "", err
+	}
+	if name := hostNetworkHostname(sandboxSpec); name != "" {
+		return name, nil
+	}
+	return "", fmt.Errorf("the pod of container %s has no node name in its metadata", id)
+}
+
+func containerSpec(cu ContainerdItf, id string) (*oci.Spec, error) {
+	ctn, err := cu.LoadContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := cu.Spec(ctn)
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("container %s has no spec", id)
+	}
+	return spec, nil
+}
+
+// nodeNameFromEnv returns the first of nodeNameEnvVars set in the
+// environment of the process of spec
+func nodeNameFromEnv(spec *oci.Spec) string {
+	if spec.Process == nil {
+		return ""
+	}
+	env := make(map[string]string, len(spec.Process.Env))
+	for _, variable := range spec.Process.Env {
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	for _, name := range nodeNameEnvVars {
+		if value := env[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hostNetworkHostname returns the hostname of a sandbox sharing the
+// network namespace of the host, set by the kubelet to the one of the
+// node, empty for the other sandboxes, named after their pod
+func hostNetworkHostname(spec *oci.Spec) string {
+	if spec.Linux == nil {
+		return ""
+	}
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.NetworkNamespace {
+			return ""
+		}
+	}
+	return spec.Hostname
+}
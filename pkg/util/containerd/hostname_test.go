@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeName(t *testing.T) {
+	podNetwork := &specs.Linux{Namespaces: []specs.LinuxNamespace{{Type: specs.PIDNamespace}, {Type: specs.NetworkNamespace}}}
+	hostNetwork := &specs.Linux{Namespaces: []specs.LinuxNamespace{{Type: specs.PIDNamespace}}}
+	ctnSpecs := map[string]*oci.Spec{
+		"downward-api": {
+			Process:     &specs.Process{Env: []string{"PATH=/bin", "NODE_NAME=ignored", "DD_KUBERNETES_KUBELET_NODENAME=ip-10-0-0-1.ec2.internal"}},
+			Annotations: map[string]string{criSandboxIDAnnotation: "host-sandbox"},
+		},
+		"host-network": {
+			Process:     &specs.Process{Env: []string{"PATH=/bin"}},
+			Annotations: map[string]string{criSandboxIDAnnotation: "host-sandbox"},
+		},
+		"pod-network": {
+			Annotations: map[string]string{criSandboxIDAnnotation: "pod-sandbox"},
+		},
+		"standalone":   {Process: &specs.Process{Env: []string{"PATH=/bin"}}},
+		"host-sandbox": {Hostname: "ip-10-0-0-2", Linux: hostNetwork},
+		"pod-sandbox":  {Hostname: "datadog-agent-x7k2p", Linux: podNetwork},
+	}
+	cu := &mockItf{
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			if _, found := ctnSpecs[id]; !found {
+				return nil, fmt.Errorf("container %s not found", id)
+			}
+			return &mockContainer{id: id}, nil
+		},
+		mockSpec: func(ctn containerd.Container) (*oci.Spec, error) {
+			return ctnSpecs[ctn.ID()], nil
+		},
+	}
+
+	name, err := NodeName(cu, "downward-api")
+	require.NoError(t, err)
+	assert.Equal(t, "ip-10-0-0-1.ec2.internal", name)
+
+	name, err = NodeName(cu, "host-network")
+	require.NoError(t, err)
+	assert.Equal(t, "ip-10-0-0-2", name)
+
+	// The sandboxes in their own network namespace are named after their pod
+	_, err = NodeName(cu, "pod-network")
+	assert.Error(t, err)
+	_, err = NodeName(cu, "standalone")
+	assert.Error(t, err)
+	_, err = NodeName(cu, "gone")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+)
+
+// Topics of the image and content events
+const (
+	ImageCreateTopic   = "/images/create"
+	ImageUpdateTopic   = "/images/update"
+	ContentDeleteTopic = "/content/delete"
+)
+
+// ingestMaxAge bounds how long an ingest is attributed to an upcoming pull,
+// ingests of failed pulls are never followed by an image event.
+const ingestMaxAge = 1 * time.Hour
+
+// ImageEventFilters are the subscription filters matching the events
+// handled by an ImageEventTracker
+var ImageEventFilters = []string{
+	`topic=="` + ImageCreateTopic + `"`,
+	`topic=="` + ImageUpdateTopic + `"`,
+	`topic=="` + ContentDeleteTopic + `"`,
+}
+
+// ImageEventHooks are called by an ImageEventTracker, nil hooks are ignored
+type ImageEventHooks struct {
+	// PullStarted is called when a blob starts being downloaded
+	PullStarted func(ref string)
+	// PullCompleted is called when an image is stored. The duration is zero
+	// if the start of the pull was not seen.
+	PullCompleted func(image string, duration time.Duration)
+	// ContentDeleted is called when a blob is garbage collected. The size
+	// is zero if the blob was stored after the last RefreshContentSizes.
+	ContentDeleted func(digest string, size int64)
+}
+
+// ImageEventTracker turns the image and content events of containerd into
+// pull and garbage collection hooks. containerd does not publish the start
+// of pulls: it is detected by polling the content ingests, the downloads in
+// progress, with PollIngests. As ingests are not bound to an image, a pull
+// lasts from the oldest pending ingest to the next image event.
+type ImageEventTracker struct {
+	hooks ImageEventHooks
+
+	mu      sync.Mutex
+	ingests map[string]time.Time // ref -> start time
+	sizes   map[string]int64     // digest -> size
+}
+
+// NewImageEventTracker returns an ImageEventTracker calling hooks
+func NewImageEventTracker(hooks ImageEventHooks) *ImageEventTracker {
+	return &ImageEventTracker{
+		hooks:   hooks,
+		ingests: make(map[string]time.Time),
+		sizes:   make(map[string]int64),
+	}
+}
+
+// HandleEnvelope processes an event matching ImageEventFilters
+func (t *ImageEventTracker) HandleEnvelope(envelope *events.Envelope) error {
+	if envelope == nil {
+		return nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e := ev.(type) {
+	case *apievents.ImageCreate:
+		t.pullCompleted(e.Name, envelope.Timestamp)
+	case *apievents.ImageUpdate:
+		// Updates are only pulls if new content was downloaded, not re-tags
+		if len(t.ingests) > 0 {
+			t.pullCompleted(e.Name, envelope.Timestamp)
+		}
+	case *apievents.ContentDelete:
+		digest := e.Digest
+		size := t.sizes[digest]
+		delete(t.sizes, digest)
+		if t.hooks.ContentDeleted != nil {
+			t.hooks.ContentDeleted(digest, size)
+		}
+	}
+	return nil
+}
+
+// pullCompleted must be called with the lock held
+func (t *ImageEventTracker) pullCompleted(image string, ts time.Time) {
+	var duration time.Duration
+	for ref, startedAt := range t.ingests {
+		if d := ts.Sub(startedAt); d > duration {
+			duration = d
+		}
+		delete(t.ingests, ref)
+	}
+	if t.hooks.PullCompleted != nil {
+		t.hooks.PullCompleted(image, duration)
+	}
+}
+
+// PollIngests lists the content ingests to detect the pulls being started.
+// It should be called frequently as short downloads can be missed.
+func (t *ImageEventTracker) PollIngests(cu ContainerdItf) error {
+	statuses, err := cu.ContentStatuses()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for ref, startedAt := range t.ingests {
+		if now.Sub(startedAt) > ingestMaxAge {
+			delete(t.ingests, ref)
+		}
+	}
+	for _, status := range statuses {
+		if _, found := t.ingests[status.Ref]; found {
+			continue
+		}
+		t.ingests[status.Ref] = status.StartedAt
+		if t.hooks.PullStarted != nil {
+			t.hooks.PullStarted(status.Ref)
+		}
+	}
+	return nil
+}
+
+// RefreshContentSizes stores the size of the blobs of the content
+// store, to report the bytes reclaimed when they are deleted
+func (t *ImageEventTracker) RefreshContentSizes(cu ContainerdItf) error {
+	sizes, err := cu.ContentSizes()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.sizes = sizes
+	t.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildEnvelope(t *testing.T, topic string, ev interface{}, ts time.Time) *events.Envelope {
+	any, err := typeurl.MarshalAny(ev)
+	require.NoError(t, err)
+	return &events.Envelope{
+		Timestamp: ts,
+		Topic:     topic,
+		Event:     any,
+	}
+}
+
+func TestImageEventTrackerPulls(t *testing.T) {
+	var started []string
+	completed := make(map[string]time.Duration)
+	tracker := NewImageEventTracker(ImageEventHooks{
+		PullStarted:   func(ref string) { started = append(started, ref) },
+		PullCompleted: func(image string, d time.Duration) { completed[image] = d },
+	})
+
+	now := time.Now()
+	statuses := []content.Status{
+		{Ref: "layer-sha256:aaa", StartedAt: now.Add(-10 * time.Second)},
+		{Ref: "layer-sha256:bbb", StartedAt: now.Add(-5 * time.Second)},
+	}
+	cu := &mockItf{
+		mockContentStatuses: func() ([]content.Status, error) { return statuses, nil },
+	}
+	require.NoError(t, tracker.PollIngests(cu))
+	require.NoError(t, tracker.PollIngests(cu))
+	assert.ElementsMatch(t, []string{"layer-sha256:aaa", "layer-sha256:bbb"}, started)
+
+	// The pull lasts from the oldest ingest
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ImageCreateTopic, &apievents.ImageCreate{Name: "docker.io/library/redis:5"}, now)))
+	assert.Equal(t, map[string]time.Duration{"docker.io/library/redis:5": 10 * time.Second}, completed)
+
+	// Re-tags are not pulls
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ImageUpdateTopic, &apievents.ImageUpdate{Name: "docker.io/library/redis:latest"}, now)))
+	assert.Len(t, completed, 1)
+
+	// The start of the pull was not seen
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ImageCreateTopic, &apievents.ImageCreate{Name: "docker.io/library/nginx:1"}, now)))
+	assert.Equal(t, time.Duration(0), completed["docker.io/library/nginx:1"])
+}
+
+func TestImageEventTrackerContentDeleted(t *testing.T) {
+	deleted := make(map[string]int64)
+	tracker := NewImageEventTracker(ImageEventHooks{
+		ContentDeleted: func(digest string, size int64) { deleted[digest] = size },
+	})
+
+	cu := &mockItf{
+		mockContentSizes: func() (map[string]int64, error) {
+			return map[string]int64{"sha256:aaa": 1024}, nil
+		},
+	}
+	require.NoError(t, tracker.RefreshContentSizes(cu))
+
+	now := time.Now()
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ContentDeleteTopic, &apievents.ContentDelete{Digest: "sha256:aaa"}, now)))
+	require.NoError(t, tracker.HandleEnvelope(buildEnvelope(t, ContentDeleteTopic, &apievents.ContentDelete{Digest: "sha256:bbb"}, now)))
+	assert.Equal(t, map[string]int64{"sha256:aaa": 1024, "sha256:bbb": 0}, deleted)
+}
@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ImageManifest describes the manifest of an image for the platform of
+// the host, as read from the content store
+type ImageManifest struct {
+	Name string
+	// Digest is the digest of the image target, the index of a multi
+	// platform image or the manifest of a single platform one
+	Digest       digest.Digest
+	MediaType    string
+	ConfigDigest digest.Digest
+	// Platform is the platform of the manifest, read from the image
+	// config. It is empty if the config cannot be read.
+	Platform ocispec.Platform
+	Layers   []ImageLayer
+}
+
+// ImageLayer is a layer of an image manifest
+type ImageLayer struct {
+	Digest    digest.Digest
+	MediaType string
+	Size      int64
+	// DiffID is the digest of the uncompressed layer, read from the
+	// image config. It is empty if the config cannot be read.
+	DiffID digest.Digest
+}
+
+// ImageManifest reads the manifest of the image of a container, with the
+// digests of its layers and its platform, from the content store. It does
+// not require the image to be unpacked, nor a docker daemon.
+func (c *ContainerdUtil) ImageManifest(ctn containerd.Container) (*ImageManifest, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	img, err := ctn.Image(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	ctx, release := c.leaseImage(ctx, img)
+	defer release()
+	manifest, err := readImageManifest(ctx, img.ContentStore(), img.Target(), img.Platform())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	manifest.Name = img.Name()
+	return manifest, nil
+}
+
+// readImageManifest walks the image content from its target to the
+// manifest matching platform, then to its config
+func readImageManifest(ctx context.Context, provider content.Provider, target ocispec.Descriptor, platform platforms.MatchComparer) (*ImageManifest, error) {
+	manifest, err := resolveManifest(ctx, provider, target, platform)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImageManifest{
+		Digest:       target.Digest,
+		MediaType:    target.MediaType,
+		ConfigDigest: manifest.Config.Digest,
+	}
+	// The layers whose config is garbage collected are still reported
+	var diffIDs []digest.Digest
+	if config, err := readImageConfig(ctx, provider, manifest.Config); err == nil {
+		result.Platform = platforms.Normalize(ocispec.Platform{
+			OS:           config.OS,
+			Architecture: config.Architecture,
+			Variant:      config.Variant,
+		})
+		if len(config.RootFS.DiffIDs) == len(manifest.Layers) {
+			diffIDs = config.RootFS.DiffIDs
+		}
+	}
+	for i, layer := range manifest.Layers {
+		l := ImageLayer{
+			Digest:    layer.Digest,
+			MediaType: layer.MediaType,
+			Size:      layer.Size,
+		}
+		if diffIDs != nil {
+			l.DiffID = diffIDs[i]
+		}
+		result.Layers = append(result.Layers, l)
+	}
+	return result, nil
+}
+
+// maxIndexDepth bounds the nesting of the indexes walked down to a manifest
+const maxIndexDepth = 4
+
+// attestationReferenceType is the annotation of the attestation manifests
+// BuildKit adds to the indexes, which are not manifests of the image
+const attestationReferenceType = "attestation-manifest"
+
+// resolveManifest returns the manifest of the image matching platform. The
+// indexes are walked down to the manifests matching platform, from the best
+// match, and the first manifest whose content was pulled is returned, as an
+// index may list several manifests for a platform, eg. one with gzip and
+// one with zstd compressed layers, of which only one is pulled. If none was
+// pulled for platform, eg. the image was pulled for another platform to run
+// it through an emulator, the first manifest whose content was pulled is
+// returned.
+func resolveManifest(ctx context.Context, provider content.Provider, target ocispec.Descriptor, platform platforms.MatchComparer) (ocispec.Manifest, error) {
+	manifest, err := findManifest(ctx, provider, target, platform, 0)
+	if err == nil || !errdefs.IsNotFound(err) {
+		return manifest, err
+	}
+	if m, ferr := findManifest(ctx, provider, target, nil, 0); ferr == nil {
+		return m, nil
+	}
+	return manifest, err
+}
+
+// findManifest returns the first manifest matching platform reachable from
+// desc whose content is in provider, any manifest if platform is nil
+func findManifest(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, platform platforms.MatchComparer, depth int) (ocispec.Manifest, error) {
+	switch desc.MediaType {
+	case images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest:
+		var manifest ocispec.Manifest
+		if err := readJSONBlob(ctx, provider, desc, &manifest); err != nil {
+			return ocispec.Manifest{}, err
+		}
+		// The platform of the manifests of an index is declared by the
+		// index, or by their config otherwise
+		if depth > 0 && platform != nil && desc.Platform == nil {
+			config, err := readImageConfig(ctx, provider, manifest.Config)
+			if err != nil {
+				return ocispec.Manifest{}, err
+			}
+			if !platform.Match(platforms.Normalize(ocispec.Platform{OS: config.OS, Architecture: config.Architecture})) {
+				return ocispec.Manifest{}, fmt.Errorf("manifest %s does not match the platform: %w", desc.Digest, errdefs.ErrNotFound)
+			}
+		}
+		return manifest, nil
+	case images.MediaTypeDockerSchema2ManifestList, ocispec.MediaTypeImageIndex:
+		if depth >= maxIndexDepth {
+			return ocispec.Manifest{}, fmt.Errorf("index %s is nested more than %d times", desc.Digest, maxIndexDepth)
+		}
+		var index ocispec.Index
+		if err := readJSONBlob(ctx, provider, desc, &index); err != nil {
+			return ocispec.Manifest{}, err
+		}
+		err := fmt.Errorf("no manifest of index %s matches the platform: %w", desc.Digest, errdefs.ErrNotFound)
+		for i, candidate := range indexCandidates(index, platform) {
+			manifest, cerr := findManifest(ctx, provider, candidate, platform, depth+1)
+			if cerr == nil {
+				return manifest, nil
+			}
+			if i == 0 {
+				err = cerr
+			}
+		}
+		return ocispec.Manifest{}, err
+	}
+	return ocispec.Manifest{}, fmt.Errorf("unexpected media type %s of %s: %w", desc.MediaType, desc.Digest, errdefs.ErrNotFound)
+}
+
+// indexCandidates returns the entries of an index matching platform, the
+// best match first, and the ones without platform last. The attestation
+// manifests are skipped.
+func indexCandidates(index ocispec.Index, platform platforms.MatchComparer) []ocispec.Descriptor {
+	var candidates []ocispec.Descriptor
+	for _, desc := range index.Manifests {
+		if desc.Annotations["vnd.docker.reference.type"] == attestationReferenceType {
+			continue
+		}
+		if platform == nil || desc.Platform == nil || platform.Match(*desc.Platform) {
+			candidates = append(candidates, desc)
+		}
+	}
+	if platform != nil {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Platform == nil {
+				return false
+			}
+			if candidates[j].Platform == nil {
+				return true
+			}
+			return platform.Less(*candidates[i].Platform, *candidates[j].Platform)
+		})
+	}
+	return candidates
+}
+
+func readJSONBlob(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, v interface{}) error {
+	p, err := content.ReadBlob(ctx, provider, desc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+func readImageConfig(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (*ocispec.Image, error) {
+	var config ocispec.Image
+	if err := readJSONBlob(ctx, provider, desc, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ContainerImage is the manifest of an image with the containers running it
+type ContainerImage struct {
+	ImageManifest
+	ContainerIDs []string
+}
+
+// ListContainerImages returns the manifests of the images of the containers
+// of the namespace, sorted by name. The manifest of each image is read once,
+// from the first of its containers, and the images whose content cannot be
+// read are skipped.
+func ListContainerImages(cu ContainerdItf) ([]ContainerImage, error) {
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*ContainerImage)
+	for _, cached := range ctns {
+		if image, found := byName[cached.Image]; found {
+			if image != nil {
+				image.ContainerIDs = append(image.ContainerIDs, cached.ID)
+			}
+			continue
+		}
+		byName[cached.Image] = nil
+		ctn, err := cu.LoadContainer(cached.ID)
+		if err != nil {
+			withFields(logFor(cu), "container_id", cached.ID).Debugf("Could not load container: %s", err)
+			continue
+		}
+		manifest, err := cu.ImageManifest(ctn)
+		if err != nil {
+			withFields(logFor(cu), "container_id", cached.ID).Debugf("Could not read the manifest of image %s: %s", cached.Image, err)
+			continue
+		}
+		byName[cached.Image] = &ContainerImage{ImageManifest: *manifest, ContainerIDs: []string{cached.ID}}
+	}
+	images := make([]ContainerImage, 0, len(byName))
+	for _, image := range byName {
+		if image != nil {
+			images = append(images, *image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Name < images[j].Name })
+	return images, nil
+}
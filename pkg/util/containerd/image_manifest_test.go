@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addJSONBlob stores the JSON encoding of v in provider
+func addJSONBlob(t *testing.T, provider memoryProvider, mediaType string, v interface{}) ocispec.Descriptor {
+	content, err := json.Marshal(v)
+	require.NoError(t, err)
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(content), Size: int64(len(content))}
+	provider[desc.Digest] = content
+	return desc
+}
+
+func TestReadImageManifest(t *testing.T) {
+	provider := memoryProvider{}
+	layers := []ocispec.Descriptor{
+		{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromString("layer-1"), Size: 100},
+		{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromString("layer-2"), Size: 200},
+	}
+	config := addJSONBlob(t, provider, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{digest.FromString("diff-1"), digest.FromString("diff-2")},
+		},
+	})
+	manifest := addJSONBlob(t, provider, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    layers,
+	})
+	manifest.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	// Only the content of the amd64 manifest was pulled
+	arm64 := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("arm64-manifest"),
+		Size:      500,
+		Platform:  &ocispec.Platform{OS: "linux", Architecture: "arm64"},
+	}
+	index := addJSONBlob(t, provider, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifest, arm64},
+	})
+
+	result, err := readImageManifest(context.Background(), provider, index, platforms.Only(ocispec.Platform{OS: "linux", Architecture: "amd64"}))
+	require.NoError(t, err)
+	assert.Equal(t, &ImageManifest{
+		Digest:       index.Digest,
+		MediaType:    ocispec.MediaTypeImageIndex,
+		ConfigDigest: config.Digest,
+		Platform:     ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		Layers: []ImageLayer{
+			{Digest: layers[0].Digest, MediaType: ocispec.MediaTypeImageLayerGzip, Size: 100, DiffID: digest.FromString("diff-1")},
+			{Digest: layers[1].Digest, MediaType: ocispec.MediaTypeImageLayerGzip, Size: 200, DiffID: digest.FromString("diff-2")},
+		},
+	}, result)
+
+	// The diff IDs are omitted once the config is garbage collected
+	delete(provider, config.Digest)
+	result, err = readImageManifest(context.Background(), provider, index, platforms.Only(ocispec.Platform{OS: "linux", Architecture: "amd64"}))
+	require.NoError(t, err)
+	assert.Empty(t, result.Layers[0].DiffID)
+
+	// An amd64 image emulated on an arm64 host resolves to the manifest pulled
+	result, err = readImageManifest(context.Background(), provider, index, platforms.Only(ocispec.Platform{OS: "linux", Architecture: "arm64"}))
+	require.NoError(t, err)
+	assert.Equal(t, config.Digest, result.ConfigDigest)
+
+	delete(provider, manifest.Digest)
+	_, err = readImageManifest(context.Background(), provider, index, platforms.Only(ocispec.Platform{OS: "linux", Architecture: "arm64"}))
+	assert.Error(t, err)
+}
+
+func TestResolveManifestFanOut(t *testing.T) {
+	provider := memoryProvider{}
+	amd64 := ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	config := addJSONBlob(t, provider, ocispec.MediaTypeImageConfig, ocispec.Image{Platform: amd64})
+	zstd := addJSONBlob(t, provider, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{{MediaType: ocispec.MediaTypeImageLayerZstd, Digest: digest.FromString("layer-zstd"), Size: 80}},
+	})
+	zstd.Platform = &amd64
+	// The manifest with gzip compressed layers listed first was not pulled
+	gzip := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("gzip-manifest"),
+		Size:      500,
+		Platform:  &amd64,
+	}
+	attestation := addJSONBlob(t, provider, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    ocispec.Descriptor{MediaType: "application/vnd.in-toto+json", Digest: digest.FromString("attestation")},
+	})
+	attestation.Platform = &ocispec.Platform{OS: "unknown", Architecture: "unknown"}
+	attestation.Annotations = map[string]string{"vnd.docker.reference.type": "attestation-manifest"}
+	nested := addJSONBlob(t, provider, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{attestation, gzip, zstd},
+	})
+	index := addJSONBlob(t, provider, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{nested},
+	})
+
+	result, err := readImageManifest(context.Background(), provider, index, platforms.Only(amd64))
+	require.NoError(t, err)
+	assert.Equal(t, config.Digest, result.ConfigDigest)
+	assert.Equal(t, []ImageLayer{{Digest: digest.FromString("layer-zstd"), MediaType: ocispec.MediaTypeImageLayerZstd, Size: 80}}, result.Layers)
+
+	// The attestation manifests are not manifests of the image
+	delete(provider, zstd.Digest)
+	_, err = readImageManifest(context.Background(), provider, index, platforms.Only(amd64))
+	assert.Error(t, err)
+}
+
+func TestListContainerImages(t *testing.T) {
+	cu := &mockItf{
+		mockCachedContainers: func() ([]CachedContainer, error) {
+			return []CachedContainer{
+				{ID: "redis-1", Image: "docker.io/library/redis:7"},
+				{ID: "nginx", Image: "docker.io/library/nginx:1.25"},
+				{ID: "redis-2", Image: "docker.io/library/redis:7"},
+				{ID: "pruned", Image: "docker.io/library/busybox:1"},
+			}, nil
+		},
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			return &mockContainer{id: id}, nil
+		},
+		mockImageManifest: func(ctn containerd.Container) (*ImageManifest, error) {
+			switch ctn.ID() {
+			case "redis-1":
+				return &ImageManifest{Name: "docker.io/library/redis:7", Digest: digest.FromString("redis")}, nil
+			case "nginx":
+				return &ImageManifest{Name: "docker.io/library/nginx:1.25", Digest: digest.FromString("nginx")}, nil
+			}
+			return nil, errors.New("content digest not found")
+		},
+	}
+
+	images, err := ListContainerImages(cu)
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerImage{
+		{
+			ImageManifest: ImageManifest{Name: "docker.io/library/nginx:1.25", Digest: digest.FromString("nginx")},
+			ContainerIDs:  []string{"nginx"},
+		},
+		{
+			ImageManifest: ImageManifest{Name: "docker.io/library/redis:7", Digest: digest.FromString("redis")},
+			ContainerIDs:  []string{"redis-1", "redis-2"},
+		},
+	}, images)
+}
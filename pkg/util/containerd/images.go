@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sort"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Image is a record of the image store of a namespace. The same image is
+// usually recorded under several names, eg. by tag and by digest, which
+// share its Digest.
+type Image struct {
+	Name   string
+	Digest digest.Digest
+	// Size is the size of the content of the image for the platform of
+	// the host, zero if its content is missing
+	Size int64
+	// CreatedAt is the time of the first pull of the image, UpdatedAt the
+	// one of the last pull or tagging
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Images returns the records of the image store of the namespace, sorted
+// by name. The images whose content cannot be read, eg. while they are
+// being pulled, are returned with a zero size. The concurrent calls share
+// the same queries.
+func (c *ContainerdUtil) Images() ([]Image, error) {
+	val, err := c.calls.do(imagesCall, func() (interface{}, error) {
+		return c.listImages()
+	})
+	images, _ := val.([]Image)
+	return append([]Image(nil), images...), err
+}
+
+func (c *ContainerdUtil) listImages() ([]Image, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	imgs, err := c.client().ListImages(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	images := make([]Image, 0, len(imgs))
+	for _, img := range imgs {
+		metadata := img.Metadata()
+		image := Image{
+			Name:      img.Name(),
+			Digest:    img.Target().Digest,
+			CreatedAt: metadata.CreatedAt,
+			UpdatedAt: metadata.UpdatedAt,
+		}
+		if image.Size, err = img.Size(ctx); err != nil {
+			c.log.Debugf("Cannot compute the size of image %s: %s", image.Name, err)
+			image.Size = 0
+		}
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Name < images[j].Name })
+	return images, nil
+}
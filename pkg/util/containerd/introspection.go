@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"strings"
+)
+
+// skipPluginError ends the init error of the plugins the daemon skipped, as
+// not applicable to the host, eg. the btrfs snapshotter without btrfs
+const skipPluginError = "skip plugin"
+
+// PluginInfo describes a plugin loaded by the containerd daemon
+type PluginInfo struct {
+	Type    string
+	ID      string
+	Exports map[string]string
+	// InitError is empty if the plugin was successfully initialized
+	InitError string
+}
+
+// Name returns the type and the ID of the plugin, the name of the plugin in
+// the configuration of containerd, eg. io.containerd.grpc.v1.cri
+func (p PluginInfo) Name() string {
+	return p.Type + "." + p.ID
+}
+
+// Skipped returns whether the daemon skipped the plugin as not applicable to
+// the host, rather than failed to initialize it
+func (p PluginInfo) Skipped() bool {
+	return strings.HasSuffix(p.InitError, skipPluginError)
+}
+
+// ConfigDump holds the daemon settings exposed by the introspection service
+type ConfigDump struct {
+	Version  string
+	Revision string
+	Plugins  []PluginInfo
+}
+
+// ConfigDump returns the version and the plugins of the containerd daemon
+func (c *ContainerdUtil) ConfigDump() (*ConfigDump, error) {
+	v, err := c.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	plugins, err := c.Plugins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigDump{
+		Version:  v.Version,
+		Revision: v.Revision,
+		Plugins:  plugins,
+	}, nil
+}
+
+// Plugins returns the plugins loaded by the containerd daemon, with the
+// error of the plugins which failed to initialize
+func (c *ContainerdUtil) Plugins(ctx context.Context) ([]PluginInfo, error) {
+	resp, err := c.client().IntrospectionService().Plugins(ctx, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	var plugins []PluginInfo
+	for _, p := range resp.Plugins {
+		info := PluginInfo{
+			Type:    p.Type,
+			ID:      p.ID,
+			Exports: p.Exports,
+		}
+		if p.InitErr != nil {
+			info.InitError = p.InitErr.Message
+		}
+		plugins = append(plugins, info)
+	}
+	return plugins, nil
+}
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/namespaces"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// leaseExpiration bounds the lifetime of the leases of the agent, so
+	// that the leases of an agent killed during a read are collected
+	leaseExpiration = time.Hour
+	// leaseOwnerLabel identifies the leases of the agent
+	leaseOwnerLabel = "com.datadoghq.agent.lease"
+)
+
+// WithLease returns a context holding a lease of the namespace of the util,
+// and the function releasing it. The content added to the lease, see
+// LeaseContent, is not garbage collected until the lease is released, even
+// if the image referencing it is deleted meanwhile. The lease expires after
+// an hour if it is not released. The context is returned unchanged if it
+// already holds a lease.
+func (c *ContainerdUtil) WithLease(ctx context.Context) (context.Context, func(), error) {
+	ctx = namespaces.WithNamespace(ctx, c.namespace)
+	ctx, done, err := c.client().WithLease(ctx,
+		leases.WithRandomID(),
+		leases.WithExpiration(leaseExpiration),
+		leases.WithLabels(map[string]string{leaseOwnerLabel: "true"}),
+	)
+	if err != nil {
+		return ctx, func() {}, classifyError(err)
+	}
+	return ctx, func() {
+		// The lease is released even if ctx is done
+		releaseCtx, cancel := c.queryContext()
+		defer cancel()
+		if err := done(releaseCtx); err != nil {
+			c.log.Debugf("Cannot release lease, it expires in %s: %s", leaseExpiration, err)
+		}
+	}, nil
+}
+
+// LeaseContent adds a blob to the lease held by ctx, see WithLease. The
+// blobs it references, like the layers of a manifest, are protected too.
+func (c *ContainerdUtil) LeaseContent(ctx context.Context, desc ocispec.Descriptor) error {
+	id, found := leases.FromContext(ctx)
+	if !found {
+		return errors.New("the context holds no lease")
+	}
+	err := c.client().LeasesService().AddResource(ctx, leases.Lease{ID: id}, leases.Resource{
+		ID:   desc.Digest.String(),
+		Type: "content",
+	})
+	return classifyError(err)
+}
+
+// leaseImage protects the content of an image from the garbage collection
+// while it is read. The reads are not protected if the lease cannot be
+// created, the returned context is ctx then.
+func (c *ContainerdUtil) leaseImage(ctx context.Context, img containerd.Image) (context.Context, func()) {
+	leaseCtx, release, err := c.WithLease(ctx)
+	if err == nil {
+		err = c.LeaseContent(leaseCtx, img.Target())
+	}
+	if err != nil {
+		release()
+		c.log.Debugf("Cannot lease the content of image %s, reading it unprotected: %s", img.Name(), err)
+		return ctx, func() {}
+	}
+	return leaseCtx, release
+}
@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseContentWithoutLease(t *testing.T) {
+	cu := &ContainerdUtil{namespace: "k8s.io"}
+	err := cu.LeaseContent(context.Background(), ocispec.Descriptor{Digest: "sha256:a8e0f5ec5a0f0bd4d2bc0fe71f17e8bd9a3dc1a4c8c0a84cb7d60aa6c6f5e4f2"})
+	assert.EqualError(t, err, "the context holds no lease")
+}
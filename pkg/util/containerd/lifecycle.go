@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+)
+
+// TaskOOMTopic is the topic of the events of the OOM kills in a task
+const TaskOOMTopic = "/tasks/oom"
+
+// LifecycleFilters are the subscription filters matching the events
+// handled by a LifecycleTracker
+var LifecycleFilters = []string{
+	`topic=="` + ContainerCreateTopic + `"`,
+	`topic=="` + TaskStartTopic + `"`,
+	`topic=="` + TaskOOMTopic + `"`,
+	`topic=="` + TaskExitTopic + `"`,
+	`topic=="` + ContainerDeleteTopic + `"`,
+}
+
+// LifecycleState is a state of a container in its lifecycle
+type LifecycleState string
+
+// States of the LifecycleTransitions
+const (
+	// LifecycleUnknown is the previous state of the containers created
+	// before the tracker
+	LifecycleUnknown LifecycleState = "unknown"
+	LifecycleCreated LifecycleState = "created"
+	LifecycleRunning LifecycleState = "running"
+	LifecycleExited  LifecycleState = "exited"
+	LifecycleDeleted LifecycleState = "deleted"
+)
+
+// signalExitBase is added to the number of the signal killing a process
+// in its exit status, up to the last real-time signal
+const (
+	signalExitBase = 128
+	maxSignal      = 64
+)
+
+// LifecycleTransition is a change of state of a container
+type LifecycleTransition struct {
+	Namespace   string
+	ContainerID string
+	Timestamp   time.Time
+	From        LifecycleState
+	To          LifecycleState
+	// ExitCode, Signal and OOMKilled are set on the exit of the task.
+	// Signal is the signal killing the task, zero if it exited by itself.
+	ExitCode  uint32
+	Signal    int
+	OOMKilled bool
+}
+
+// containerLifecycle is the state of a container tracked by a LifecycleTracker
+type containerLifecycle struct {
+	state LifecycleState
+	// oomKilled is set by an OOM kill in the running task
+	oomKilled bool
+}
+
+// LifecycleTracker turns the container and task events of containerd into
+// the transitions of the containers, created, running, exited and deleted,
+// eg. to audit the churn of the workloads. The restarts of a task are
+// transitions from exited to running.
+type LifecycleTracker struct {
+	mu sync.Mutex
+	// containers are keyed by namespace and container ID, until deleted
+	containers map[string]*containerLifecycle
+}
+
+// NewLifecycleTracker returns an empty LifecycleTracker
+func NewLifecycleTracker() *LifecycleTracker {
+	return &LifecycleTracker{containers: make(map[string]*containerLifecycle)}
+}
+
+// HandleEnvelope processes an event matching LifecycleFilters. It returns
+// the transition of the event, nil if the event changes no state.
+func (t *LifecycleTracker) HandleEnvelope(envelope *events.Envelope) (*LifecycleTransition, error) {
+	if envelope == nil {
+		return nil, nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var containerID string
+	var to LifecycleState
+	var exit *apievents.TaskExit
+	switch e := ev.(type) {
+	case *apievents.ContainerCreate:
+		containerID, to = e.ID, LifecycleCreated
+	case *apievents.TaskStart:
+		containerID, to = e.ContainerID, LifecycleRunning
+	case *apievents.TaskOOM:
+		t.container(envelope.Namespace, e.ContainerID).oomKilled = true
+		return nil, nil
+	case *apievents.TaskExit:
+		// Processes exec'd in the container exit with their own ID
+		if e.ID != e.ContainerID {
+			return nil, nil
+		}
+		containerID, to, exit = e.ContainerID, LifecycleExited, e
+	case *apievents.ContainerDelete:
+		containerID, to = e.ID, LifecycleDeleted
+	default:
+		return nil, nil
+	}
+
+	ctn := t.container(envelope.Namespace, containerID)
+	transition := &LifecycleTransition{
+		Namespace:   envelope.Namespace,
+		ContainerID: containerID,
+		Timestamp:   envelope.Timestamp,
+		From:        ctn.state,
+		To:          to,
+	}
+	if exit != nil {
+		transition.ExitCode = exit.ExitStatus
+		if exit.ExitStatus > signalExitBase && exit.ExitStatus <= signalExitBase+maxSignal {
+			transition.Signal = int(exit.ExitStatus - signalExitBase)
+		}
+		transition.OOMKilled = ctn.oomKilled
+		ctn.oomKilled = false
+	}
+
+	if to == LifecycleDeleted {
+		delete(t.containers, envelope.Namespace+"/"+containerID)
+	} else {
+		ctn.state = to
+	}
+	return transition, nil
+}
+
+// container returns the lifecycle of a container, in an unknown state if
+// it is not tracked yet
+func (t *LifecycleTracker) container(namespace, containerID string) *containerLifecycle {
+	key := namespace + "/" + containerID
+	ctn, found := t.containers[key]
+	if !found {
+		ctn = &containerLifecycle{state: LifecycleUnknown}
+		t.containers[key] = ctn
+	}
+	return ctn
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleTracker(t *testing.T) {
+	tracker := NewLifecycleTracker()
+	ts := time.Unix(1540000000, 0)
+	handle := func(topic string, ev interface{}) *LifecycleTransition {
+		envelope := buildEnvelope(t, topic, ev, ts)
+		envelope.Namespace = "k8s.io"
+		transition, err := tracker.HandleEnvelope(envelope)
+		require.NoError(t, err)
+		return transition
+	}
+
+	assert.Equal(t, &LifecycleTransition{
+		Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: LifecycleUnknown, To: LifecycleCreated,
+	}, handle(ContainerCreateTopic, &apievents.ContainerCreate{ID: "redis"}))
+	assert.Equal(t, &LifecycleTransition{
+		Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: LifecycleCreated, To: LifecycleRunning,
+	}, handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 42}))
+
+	// The exits of the exec'd processes are ignored
+	assert.Nil(t, handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "exec-1", ExitStatus: 1}))
+	assert.Nil(t, handle(TaskOOMTopic, &apievents.TaskOOM{ContainerID: "redis"}))
+	assert.Equal(t, &LifecycleTransition{
+		Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: LifecycleRunning, To: LifecycleExited,
+		ExitCode: 137, Signal: 9, OOMKilled: true,
+	}, handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis", ExitStatus: 137}))
+
+	// The OOM flag is reset by the exit, the restarted task exits cleanly
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "redis", Pid: 43})
+	assert.Equal(t, &LifecycleTransition{
+		Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: LifecycleRunning, To: LifecycleExited,
+		ExitCode: 255,
+	}, handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis", ExitStatus: 255}))
+	assert.Equal(t, &LifecycleTransition{
+		Namespace: "k8s.io", ContainerID: "redis", Timestamp: ts, From: LifecycleExited, To: LifecycleDeleted,
+	}, handle(ContainerDeleteTopic, &apievents.ContainerDelete{ID: "redis"}))
+	assert.Empty(t, tracker.containers)
+
+	// The containers created before the tracker come from an unknown state
+	assert.Equal(t, LifecycleUnknown, handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "nginx", ID: "nginx"}).From)
+	assert.Nil(t, handle(ImageCreateTopic, &apievents.ImageCreate{Name: "redis:latest"}))
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldLogger appends structured context to the messages of a Logger, as
+// key=value fields, eg. socket=/run/containerd/containerd.sock
+// namespace=k8s.io, so that the logs of the different sockets, namespaces
+// and containers can be told apart and filtered
+type fieldLogger struct {
+	Logger
+	fields string
+}
+
+// withFields returns a Logger appending the key and value pairs of kv to
+// the messages of l, after the fields of l. The pairs with an empty value
+// are skipped.
+func withFields(l Logger, kv ...string) Logger {
+	fields := make([]string, 0, len(kv)/2+1)
+	if fl, ok := l.(*fieldLogger); ok {
+		l = fl.Logger
+		fields = append(fields, fl.fields)
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			fields = append(fields, kv[i]+"="+kv[i+1])
+		}
+	}
+	return &fieldLogger{Logger: l, fields: strings.Join(fields, " ")}
+}
+
+func (l *fieldLogger) format(format string, params []interface{}) string {
+	msg := fmt.Sprintf(format, params...)
+	if l.fields == "" {
+		return msg
+	}
+	return msg + " " + l.fields
+}
+
+func (l *fieldLogger) Debugf(format string, params ...interface{}) {
+	l.Logger.Debugf("%s", l.format(format, params))
+}
+
+func (l *fieldLogger) Infof(format string, params ...interface{}) {
+	l.Logger.Infof("%s", l.format(format, params))
+}
+
+func (l *fieldLogger) Warnf(format string, params ...interface{}) error {
+	return l.Logger.Warnf("%s", l.format(format, params))
+}
+
+func (l *fieldLogger) Errorf(format string, params ...interface{}) error {
+	return l.Logger.Errorf("%s", l.format(format, params))
+}
+
+// logFor returns the logger of a util, with the context of the util
+func logFor(cu ContainerdItf) Logger {
+	if c, ok := cu.(*ContainerdUtil); ok && c.log != nil {
+		return c.log
+	}
+	return withFields(agentLogger{}, "namespace", cu.Namespace())
+}
+
+// containerLog returns the logger of the util with the ID of a container
+func (c *ContainerdUtil) containerLog(id string) Logger {
+	return withFields(c.log, "container_id", id)
+}
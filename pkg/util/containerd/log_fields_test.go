@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordLogger records the messages logged
+type recordLogger struct {
+	messages []string
+}
+
+func (l *recordLogger) record(level, format string, params []interface{}) {
+	l.messages = append(l.messages, level+" "+fmt.Sprintf(format, params...))
+}
+
+func (l *recordLogger) Debugf(format string, params ...interface{}) {
+	l.record("debug", format, params)
+}
+
+func (l *recordLogger) Infof(format string, params ...interface{}) {
+	l.record("info", format, params)
+}
+
+func (l *recordLogger) Warnf(format string, params ...interface{}) error {
+	l.record("warn", format, params)
+	return nil
+}
+
+func (l *recordLogger) Errorf(format string, params ...interface{}) error {
+	l.record("error", format, params)
+	return nil
+}
+
+func TestWithFields(t *testing.T) {
+	rec := &recordLogger{}
+	l := withFields(rec, "socket", "/run/containerd/containerd.sock", "namespace", "k8s.io")
+	l.Infof("containerd is serving again")
+	withFields(l, "container_id", "redis", "grpc_method", "").Debugf("Cannot read process %d: %s", 42, "not found")
+	withFields(rec).Errorf("100%% unstructured")
+	l.Warnf("%s", "degraded")
+
+	assert.Equal(t, []string{
+		"info containerd is serving again socket=/run/containerd/containerd.sock namespace=k8s.io",
+		"debug Cannot read process 42: not found socket=/run/containerd/containerd.sock namespace=k8s.io container_id=redis",
+		"error 100% unstructured",
+		"warn degraded socket=/run/containerd/containerd.sock namespace=k8s.io",
+	}, rec.messages)
+}
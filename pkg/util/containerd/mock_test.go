@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/sandbox"
+)
+
+type mockItf struct {
+	ContainerdItf
+	mockCachedContainers func() ([]CachedContainer, error)
+	mockContainers       func() ([]Container, error)
+	mockContentSizes     func() (map[string]int64, error)
+	mockContentStatuses  func() ([]content.Status, error)
+	mockImageManifest    func(ctn containerd.Container) (*ImageManifest, error)
+	mockInfo             func(ctn containerd.Container) (containers.Container, error)
+	mockLoadContainer    func(id string) (containerd.Container, error)
+	mockMetadata         func() (containerd.Version, error)
+	mockNamespace        func() string
+	mockNamespaces       func() ([]string, error)
+	mockSandboxStatus    func(id string) (sandbox.ControllerStatus, error)
+	mockSandboxes        func() ([]sandbox.Sandbox, error)
+	mockSpec             func(ctn containerd.Container) (*oci.Spec, error)
+	mockTaskPids         func(ctn containerd.Container) ([]containerd.ProcessInfo, error)
+	mockTaskPressure     func(pid uint32) (*TaskPressure, error)
+	mockTaskStatus       func(ctn containerd.Container) (containerd.Status, error)
+}
+
+func (m *mockItf) CachedContainers() ([]CachedContainer, error) {
+	if m.mockCachedContainers == nil {
+		return nil, errors.New("no container cache")
+	}
+	return m.mockCachedContainers()
+}
+
+func (m *mockItf) Containers() ([]Container, error) {
+	return m.mockContainers()
+}
+
+func (m *mockItf) ContentSizes() (map[string]int64, error) {
+	return m.mockContentSizes()
+}
+
+func (m *mockItf) ContentStatuses() ([]content.Status, error) {
+	return m.mockContentStatuses()
+}
+
+func (m *mockItf) ImageManifest(ctn containerd.Container) (*ImageManifest, error) {
+	return m.mockImageManifest(ctn)
+}
+
+func (m *mockItf) Info(ctn containerd.Container) (containers.Container, error) {
+	return m.mockInfo(ctn)
+}
+
+func (m *mockItf) LoadContainer(id string) (containerd.Container, error) {
+	return m.mockLoadContainer(id)
+}
+
+func (m *mockItf) Metadata() (containerd.Version, error) {
+	return m.mockMetadata()
+}
+
+func (m *mockItf) Namespace() string {
+	if m.mockNamespace == nil {
+		return ""
+	}
+	return m.mockNamespace()
+}
+
+func (m *mockItf) Namespaces() ([]string, error) {
+	return m.mockNamespaces()
+}
+
+func (m *mockItf) SandboxStatus(id string) (sandbox.ControllerStatus, error) {
+	return m.mockSandboxStatus(id)
+}
+
+func (m *mockItf) Sandboxes() ([]sandbox.Sandbox, error) {
+	return m.mockSandboxes()
+}
+
+func (m *mockItf) Spec(ctn containerd.Container) (*oci.Spec, error) {
+	return m.mockSpec(ctn)
+}
+
+func (m *mockItf) TaskPids(ctn containerd.Container) ([]containerd.ProcessInfo, error) {
+	return m.mockTaskPids(ctn)
+}
+
+func (m *mockItf) TaskPressure(pid uint32) (*TaskPressure, error) {
+	return m.mockTaskPressure(pid)
+}
+
+func (m *mockItf) TaskStatus(ctn containerd.Container) (containerd.Status, error) {
+	return m.mockTaskStatus(ctn)
+}
+
+type mockContainer struct {
+	containerd.Container
+	id string
+}
+
+func (m *mockContainer) ID() string {
+	return m.id
+}
+
+// newMockContainers returns the listing of mock containers by Containers
+func newMockContainers(ids ...string) []Container {
+	ctns := make([]Container, 0, len(ids))
+	for _, id := range ids {
+		ctns = append(ctns, NewContainer(&mockContainer{id: id}, time.Time{}, time.Time{}))
+	}
+	return ctns
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NamespaceFilter scopes the collection to some containerd namespaces.
+// The names are shell patterns, eg. buildkit* or * for every namespace.
+// The zero value allows every namespace.
+type NamespaceFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewNamespaceFilter returns a filter allowing the namespaces matching
+// include, or every namespace if include is empty, and not matching exclude
+func NewNamespaceFilter(include, exclude []string) NamespaceFilter {
+	return NamespaceFilter{include: include, exclude: exclude}
+}
+
+// IsExcluded returns whether the namespace is out of the collection scope
+func (f NamespaceFilter) IsExcluded(namespace string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, namespace) {
+		return true
+	}
+	return matchAny(f.exclude, namespace)
+}
+
+func matchAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		// Malformed patterns are only matched by equality
+		if matched, err := path.Match(pattern, namespace); matched || (err != nil && pattern == namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventFilters scopes event subscription filters, or every topic if none is
+// given, to the namespaces of the filter so that containerd drops the
+// events of the other namespaces instead of sending them to be discarded.
+// Only the exclusion of literal names can be expressed by containerd, the
+// events of the namespaces excluded by a pattern must still be dropped
+// with IsExcluded. It returns nil if every event should be received.
+func (f NamespaceFilter) EventFilters(filters ...string) []string {
+	if len(filters) == 0 {
+		filters = []string{""}
+	}
+	var selectors []string
+	for _, pattern := range f.include {
+		selector, ok := namespaceSelector(pattern)
+		if !ok {
+			// The namespaces cannot be narrowed server-side
+			selectors = nil
+			break
+		}
+		selectors = append(selectors, selector)
+	}
+	if len(selectors) == 0 {
+		selectors = []string{""}
+	}
+	var exclusions []string
+	for _, pattern := range f.exclude {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			exclusions = append(exclusions, "namespace!="+strconv.Quote(pattern))
+		}
+	}
+
+	var scoped []string
+	for _, filter := range filters {
+		for _, selector := range selectors {
+			if joined := joinFilter(append([]string{filter, selector}, exclusions...)); joined != "" {
+				scoped = append(scoped, joined)
+			}
+		}
+	}
+	return scoped
+}
+
+// namespaceSelector returns the filter selecting the namespaces matching a
+// shell pattern, empty for every namespace, or false if it cannot be
+// expressed by a filter
+func namespaceSelector(pattern string) (string, bool) {
+	switch {
+	case pattern == "*":
+		return "", true
+	case strings.ContainsAny(pattern, `[\`):
+		return "", false
+	case !strings.ContainsAny(pattern, "*?"):
+		return "namespace==" + strconv.Quote(pattern), true
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return "namespace~=" + strconv.Quote(expr.String()), true
+}
+
+// joinFilter returns the filter matching all the non-empty parts
+func joinFilter(parts []string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
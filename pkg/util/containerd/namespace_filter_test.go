@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	var all NamespaceFilter
+	assert.False(t, all.IsExcluded("moby"))
+
+	filter := NewNamespaceFilter([]string{"*"}, []string{"buildkit", "test-*", "[bad"})
+	assert.False(t, filter.IsExcluded("k8s.io"))
+	assert.False(t, filter.IsExcluded("moby"))
+	assert.True(t, filter.IsExcluded("buildkit"))
+	assert.True(t, filter.IsExcluded("test-e2e"))
+	assert.True(t, filter.IsExcluded("[bad"))
+
+	filter = NewNamespaceFilter([]string{"k8s.io"}, nil)
+	assert.False(t, filter.IsExcluded("k8s.io"))
+	assert.True(t, filter.IsExcluded("moby"))
+}
+
+func TestNamespaceEventFilters(t *testing.T) {
+	var all NamespaceFilter
+	assert.Nil(t, all.EventFilters())
+	assert.Equal(t, []string{`topic=="/tasks/start"`}, all.EventFilters(`topic=="/tasks/start"`))
+
+	filter := NewNamespaceFilter([]string{"k8s.io", "team-?.*"}, []string{"buildkit", "test-*"})
+	assert.Equal(t, []string{
+		`topic=="/tasks/start",namespace=="k8s.io",namespace!="buildkit"`,
+		`topic=="/tasks/start",namespace~="^team-.\\..*$",namespace!="buildkit"`,
+		`topic=="/tasks/exit",namespace=="k8s.io",namespace!="buildkit"`,
+		`topic=="/tasks/exit",namespace~="^team-.\\..*$",namespace!="buildkit"`,
+	}, filter.EventFilters(`topic=="/tasks/start"`, `topic=="/tasks/exit"`))
+	assert.Equal(t, []string{
+		`namespace=="k8s.io",namespace!="buildkit"`,
+		`namespace~="^team-.\\..*$",namespace!="buildkit"`,
+	}, filter.EventFilters())
+
+	// The namespaces cannot be narrowed by the patterns containerd cannot
+	// express, the events are filtered with IsExcluded
+	filter = NewNamespaceFilter([]string{"k8s.io", "[a-z]*"}, []string{"build[kx]it"})
+	assert.Equal(t, []string{`topic=="/tasks/start"`}, filter.EventFilters(`topic=="/tasks/start"`))
+	filter = NewNamespaceFilter([]string{"*"}, nil)
+	assert.Nil(t, filter.EventFilters())
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/typeurl/v2"
+)
+
+// Topics of the namespace events handled by a NamespaceSet
+const (
+	NamespaceCreateTopic = "/namespaces/create"
+	NamespaceDeleteTopic = "/namespaces/delete"
+)
+
+// namespaceWatchRetryDelay is the delay before subscribing again to the
+// namespace events after the stream broke
+const namespaceWatchRetryDelay = 10 * time.Second
+
+// NamespaceSetFilters are the subscription filters matching the events
+// handled by a NamespaceSet. The namespaces created implicitly along with
+// their first container send no namespace event, they are added on the
+// creation of their containers.
+var NamespaceSetFilters = []string{
+	`topic=="` + NamespaceCreateTopic + `"`,
+	`topic=="` + NamespaceDeleteTopic + `"`,
+	`topic=="` + ContainerCreateTopic + `"`,
+}
+
+var (
+	globalNamespaces   = NewNamespaceSet()
+	namespaceWatchOnce sync.Once
+)
+
+// NamespaceSet is the set of the namespaces of a daemon, kept up to date by
+// the namespace events, so that the namespaces of new tenants are collected
+// without listing the namespaces on every collection
+type NamespaceSet struct {
+	mu         sync.RWMutex
+	namespaces map[string]struct{}
+	// synced is unset until the set is reset from a listing, and once
+	// the event stream breaks
+	synced bool
+}
+
+// NewNamespaceSet returns an empty NamespaceSet, not synced
+func NewNamespaceSet() *NamespaceSet {
+	return &NamespaceSet{namespaces: make(map[string]struct{})}
+}
+
+// Reset replaces the namespaces of the set by a listing of the daemon
+func (s *NamespaceSet) Reset(namespaces []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespaces = make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		s.namespaces[ns] = struct{}{}
+	}
+	s.synced = true
+}
+
+// Invalidate marks the set as out of sync, until the next Reset
+func (s *NamespaceSet) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = false
+}
+
+// Namespaces returns the namespaces of the set allowed by the filter,
+// sorted, and whether the set is synced with the daemon
+func (s *NamespaceSet) Namespaces(filter NamespaceFilter) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var allowed []string
+	for ns := range s.namespaces {
+		if !filter.IsExcluded(ns) {
+			allowed = append(allowed, ns)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed, s.synced
+}
+
+// HandleEnvelope processes an event matching NamespaceSetFilters. It
+// returns the namespace added or deleted by the event, if any.
+func (s *NamespaceSet) HandleEnvelope(envelope *events.Envelope) (namespace string, deleted bool, err error) {
+	if envelope == nil {
+		return "", false, nil
+	}
+	switch envelope.Topic {
+	case NamespaceCreateTopic, NamespaceDeleteTopic:
+		ev, err := typeurl.UnmarshalAny(envelope.Event)
+		if err != nil {
+			return "", false, err
+		}
+		switch e := ev.(type) {
+		case *apievents.NamespaceCreate:
+			namespace = e.Name
+		case *apievents.NamespaceDelete:
+			namespace, deleted = e.Name, true
+		default:
+			return "", false, fmt.Errorf("unexpected event %T on topic %s", ev, envelope.Topic)
+		}
+	case ContainerCreateTopic:
+		namespace = envelope.Namespace
+	default:
+		return "", false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.namespaces[namespace]
+	if deleted {
+		delete(s.namespaces, namespace)
+		return namespace, true, nil
+	}
+	if found || namespace == "" {
+		return "", false, nil
+	}
+	s.namespaces[namespace] = struct{}{}
+	return namespace, false, nil
+}
+
+// startNamespaceWatch starts keeping the namespaces of the daemon of the
+// agent configuration up to date in the background
+func startNamespaceWatch() {
+	namespaceWatchOnce.Do(func() {
+		go watchNamespaces(globalNamespaces)
+	})
+}
+
+// watchNamespaces feeds the namespace events to set, subscribing again when
+// the stream breaks, eg. when the endpoint of the configuration changes
+func watchNamespaces(set *NamespaceSet) {
+	for {
+		cu, err := GetContainerdUtil(nil)
+		if err == nil {
+			err = streamNamespaceEvents(cu, set)
+		}
+		set.Invalidate()
+		agentLogger{}.Debugf("containerd namespace events stream broke, subscribing again in %s: %v", namespaceWatchRetryDelay, err)
+		time.Sleep(namespaceWatchRetryDelay)
+	}
+}
+
+// streamNamespaceEvents resets set from a listing of the namespaces once
+// subscribed, then feeds it the events of the subscription until it breaks.
+// The utils of the deleted namespaces are closed.
+func streamNamespaceEvents(cu ContainerdItf, set *NamespaceSet) error {
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), cu.Namespace()))
+	defer cancel()
+	messages, errs := cu.GetEvents().Subscribe(ctx, NamespaceSetFilters...)
+
+	// The namespaces created before the subscription are listed
+	listed, err := cu.Namespaces()
+	if err != nil {
+		return err
+	}
+	set.Reset(listed)
+
+	for {
+		select {
+		case envelope := <-messages:
+			namespace, deleted, err := set.HandleEnvelope(envelope)
+			if err != nil {
+				logFor(cu).Debugf("Cannot handle containerd event %s: %s", envelope.Topic, err)
+				continue
+			}
+			switch {
+			case namespace == "":
+			case deleted:
+				logFor(cu).Infof("The containerd namespace %s was deleted", namespace)
+				globalProvider.releaseNamespace(cu, namespace)
+			default:
+				logFor(cu).Infof("The containerd namespace %s was created", namespace)
+			}
+		case err := <-errs:
+			if err == nil {
+				err = fmt.Errorf("event stream closed")
+			}
+			return err
+		}
+	}
+}
+
+// releaseNamespace closes the shared util of a deleted namespace of the
+// socket of cu, with its event subscriptions. The util of the namespace of
+// cu is kept, it is the one of the agent configuration.
+func (p *Provider) releaseNamespace(cu ContainerdItf, namespace string) {
+	if namespace == cu.Namespace() {
+		return
+	}
+	main, ok := cu.(*ContainerdUtil)
+	if !ok {
+		return
+	}
+	stale := p.forget(func(util *ContainerdUtil) bool {
+		return util.socketPath == main.socketPath && util.namespace == namespace
+	})
+
+	for _, util := range stale {
+		if err := util.Close(); err != nil {
+			util.log.Debugf("Cannot close the containerd client of namespace %s: %s", namespace, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceSet(t *testing.T) {
+	set := NewNamespaceSet()
+	_, synced := set.Namespaces(NamespaceFilter{})
+	assert.False(t, synced)
+
+	set.Reset([]string{"k8s.io", "moby"})
+	namespaces, synced := set.Namespaces(NewNamespaceFilter(nil, []string{"moby"}))
+	assert.True(t, synced)
+	assert.Equal(t, []string{"k8s.io"}, namespaces)
+
+	now := time.Now()
+	ns, deleted, err := set.HandleEnvelope(buildEnvelope(t, NamespaceCreateTopic, &apievents.NamespaceCreate{Name: "tenant-a"}, now))
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", ns)
+	assert.False(t, deleted)
+
+	// The namespaces created along with their first container send no
+	// namespace event
+	created := buildEnvelope(t, ContainerCreateTopic, &apievents.ContainerCreate{ID: "foo"}, now)
+	created.Namespace = "tenant-b"
+	ns, _, err = set.HandleEnvelope(created)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-b", ns)
+	// The next containers are not a namespace change
+	ns, _, err = set.HandleEnvelope(created)
+	require.NoError(t, err)
+	assert.Equal(t, "", ns)
+
+	ns, deleted, err = set.HandleEnvelope(buildEnvelope(t, NamespaceDeleteTopic, &apievents.NamespaceDelete{Name: "k8s.io"}, now))
+	require.NoError(t, err)
+	assert.Equal(t, "k8s.io", ns)
+	assert.True(t, deleted)
+
+	namespaces, _ = set.Namespaces(NewNamespaceFilter([]string{"tenant-*", "k8s.io"}, nil))
+	assert.Equal(t, []string{"tenant-a", "tenant-b"}, namespaces)
+
+	// The other topics are ignored
+	ns, _, err = set.HandleEnvelope(&events.Envelope{Topic: ContainerDeleteTopic, Namespace: "tenant-c"})
+	require.NoError(t, err)
+	assert.Equal(t, "", ns)
+
+	set.Invalidate()
+	namespaces, synced = set.Namespaces(NamespaceFilter{})
+	assert.False(t, synced)
+	assert.Equal(t, []string{"moby", "tenant-a", "tenant-b"}, namespaces)
+}
+
+func TestReleaseNamespace(t *testing.T) {
+	provider := NewProvider()
+	for _, o := range []Options{
+		{SocketPath: "/run/containerd/containerd.sock", Namespace: "k8s.io"},
+		{SocketPath: "/run/containerd/containerd.sock", Namespace: "tenant-a"},
+		{SocketPath: "/run/other.sock", Namespace: "tenant-a"},
+	} {
+		o = o.withDefaults()
+		provider.utils[o.key()] = newContainerdUtil(o)
+	}
+	main := provider.utils[Options{SocketPath: "/run/containerd/containerd.sock", Namespace: "k8s.io"}.withDefaults().key()]
+
+	provider.releaseNamespace(main, "tenant-a")
+	provider.releaseNamespace(main, "k8s.io")
+	require.Len(t, provider.utils, 2)
+	for _, util := range provider.utils {
+		assert.False(t, util.socketPath == main.socketPath && util.namespace == "tenant-a")
+	}
+}
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// GetNamespaces returns the namespaces of the daemon allowed by the
+// filter, sorted
+func GetNamespaces(cu ContainerdItf, filter NamespaceFilter) ([]string, error) {
+	namespaces, err := cu.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	var allowed []string
+	for _, ns := range namespaces {
+		if !filter.IsExcluded(ns) {
+			allowed = append(allowed, ns)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed, nil
+}
+
+// getNamespacesContext is GetNamespaces, returning an ErrTimeout error if
+// ctx is done before the namespaces are listed. The listing is still
+// bounded by the query timeout of the util, it ends in the background.
+func getNamespacesContext(ctx context.Context, cu ContainerdItf, filter NamespaceFilter) ([]string, error) {
+	type result struct {
+		namespaces []string
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		namespaces, err := GetNamespaces(cu, filter)
+		done <- result{namespaces, err}
+	}()
+	select {
+	case r := <-done:
+		return r.namespaces, r.err
+	case <-ctx.Done():
+		return nil, budgetExceeded(ctx, "the namespaces")
+	}
+}
+
+// GetNamespacedUtils returns a util bound to every namespace allowed by
+// the namespace filter of the agent configuration, to iterate over the
+// collected namespaces. The namespaces are kept up to date by their events
+// in the background, see namespace_watch.go.
+func GetNamespacedUtils() ([]ContainerdItf, error) {
+	return globalProvider.NamespacedUtils(context.Background())
+}
+
+// GetNamespacedUtilsContext is GetNamespacedUtils within the time budget of
+// ctx, see Provider.NamespacedUtils
+func GetNamespacedUtilsContext(ctx context.Context) ([]ContainerdItf, error) {
+	return globalProvider.NamespacedUtils(ctx)
+}
+
+// NamespacedUtils returns a util of the Provider bound to every namespace
+// allowed by the namespace filter of the agent configuration, within the
+// time budget of ctx: an ErrTimeout error is returned if the namespaces are
+// not listed before ctx is done, eg. by a daemon whose metadata store is
+// damaged. The fakes set by SetForTests are returned as is.
+func (p *Provider) NamespacedUtils(ctx context.Context) ([]ContainerdItf, error) {
+	p.mu.Lock()
+	fakes := append([]ContainerdItf(nil), p.fakes...)
+	p.mu.Unlock()
+	if len(fakes) > 0 {
+		return fakes, nil
+	}
+	opts := p.ConfigOptions()
+	cu, err := p.Get(&opts)
+	if err != nil {
+		return nil, err
+	}
+	filter := agentConfig.NamespaceFilter()
+	// The namespaces are listed until the namespace watch is synced, the
+	// relay receives no event
+	namespaces, synced := globalNamespaces.Namespaces(filter)
+	if opts.ViaClusterAgent || !synced {
+		if !opts.ViaClusterAgent {
+			startNamespaceWatch()
+		}
+		namespaces, err = getNamespacesContext(ctx, cu, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	utils := make([]ContainerdItf, 0, len(namespaces))
+	for _, ns := range namespaces {
+		nsOpts := opts
+		nsOpts.Namespace = ns
+		nsUtil, err := p.Get(&nsOpts)
+		if err != nil {
+			return nil, err
+		}
+		utils = append(utils, nsUtil)
+	}
+	return utils, nil
+}
+
+// NamespaceContainers is the listing of the containers of a namespace
+type NamespaceContainers struct {
+	Namespace  string
+	Containers []CachedContainer
+	// Err is an ErrTimeout error if the listing did not complete within
+	// the time budget
+	Err error
+}
+
+// ListNamespaceContainers lists the containers of the namespaces of utils
+// in parallel, until ctx is done. The listings are returned in the order of
+// utils, the ones not completed before ctx is done carry an ErrTimeout
+// error, so that a hanging namespace does not delay the others.
+func ListNamespaceContainers(ctx context.Context, utils []ContainerdItf) []NamespaceContainers {
+	listings := make([]NamespaceContainers, len(utils))
+	var wg sync.WaitGroup
+	for i, cu := range utils {
+		listings[i].Namespace = cu.Namespace()
+		wg.Add(1)
+		go func(listing *NamespaceContainers, cu ContainerdItf) {
+			defer wg.Done()
+			type result struct {
+				ctns []CachedContainer
+				err  error
+			}
+			done := make(chan result, 1)
+			go func() {
+				ctns, err := cu.CachedContainers()
+				done <- result{ctns, err}
+			}()
+			select {
+			case r := <-done:
+				listing.Containers, listing.Err = r.ctns, r.err
+			case <-ctx.Done():
+				listing.Err = budgetExceeded(ctx, "the containers of namespace "+listing.Namespace)
+			}
+		}(&listings[i], cu)
+	}
+	wg.Wait()
+	return listings
+}
+
+// budgetExceeded returns the ErrTimeout error of a listing interrupted by
+// the end of the time budget of ctx
+func budgetExceeded(ctx context.Context, listing string) error {
+	return &Error{Kind: ErrTimeout, Err: fmt.Errorf("listing %s: %s", listing, ctx.Err())}
+}
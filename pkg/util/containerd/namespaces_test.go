@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNamespaces(t *testing.T) {
+	cu := &mockItf{
+		mockNamespaces: func() ([]string, error) {
+			return []string{"moby", "k8s.io", "buildkit", "test-e2e"}, nil
+		},
+	}
+	namespaces, err := GetNamespaces(cu, NewNamespaceFilter([]string{"*"}, []string{"buildkit", "test-*"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k8s.io", "moby"}, namespaces)
+}
+
+func TestGetNamespacesContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	cu := &mockItf{
+		mockNamespaces: func() ([]string, error) {
+			<-release
+			return []string{"k8s.io"}, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := getNamespacesContext(ctx, cu, NewNamespaceFilter(nil, nil))
+	assert.Equal(t, ErrTimeout, ErrorKind(err))
+}
+
+func TestListNamespaceContainers(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	namespace := func(ns string) func() string {
+		return func() string { return ns }
+	}
+	utils := []ContainerdItf{
+		&mockItf{
+			mockNamespace: namespace("k8s.io"),
+			mockCachedContainers: func() ([]CachedContainer, error) {
+				return []CachedContainer{{ID: "redis"}}, nil
+			},
+		},
+		// The metadata store of the namespace is damaged
+		&mockItf{
+			mockNamespace: namespace("moby"),
+			mockCachedContainers: func() ([]CachedContainer, error) {
+				<-release
+				return nil, nil
+			},
+		},
+		&mockItf{mockNamespace: namespace("buildkit")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	listings := ListNamespaceContainers(ctx, utils)
+	assert.True(t, time.Since(start) < time.Second)
+	require.Len(t, listings, 3)
+	assert.Equal(t, "k8s.io", listings[0].Namespace)
+	assert.NoError(t, listings[0].Err)
+	assert.Equal(t, []CachedContainer{{ID: "redis"}}, listings[0].Containers)
+	assert.Equal(t, "moby", listings[1].Namespace)
+	assert.Equal(t, ErrTimeout, ErrorKind(listings[1].Err))
+	assert.Equal(t, "buildkit", listings[2].Namespace)
+	assert.EqualError(t, listings[2].Err, "no container cache")
+}
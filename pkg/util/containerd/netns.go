@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// NetNSPath returns the path of the network namespace of a container, to
+// attribute the connections of the host to the container like with docker.
+// It is the namespace of the process of its task, under the proc root of
+// the util, or the namespace declared in its spec if it has no running
+// task, like the namespace the CRI plugin creates for a pod sandbox.
+func (c *ContainerdUtil) NetNSPath(ctx context.Context, ctn containerd.Container) (string, error) {
+	ctx, cancel := context.WithTimeout(namespaces.WithNamespace(ctx, c.namespace), c.queryTimeout)
+	defer cancel()
+
+	var pid uint32
+	task, err := ctn.Task(ctx, nil)
+	switch {
+	case err == nil:
+		pid = task.Pid()
+	case !errdefs.IsNotFound(err):
+		return "", classifyError(err)
+	}
+	spec, err := ctn.Spec(ctx)
+	if err != nil {
+		return "", classifyError(err)
+	}
+	return ResolveNetNSPath(spec, pid, c.procRoot)
+}
+
+// ResolveNetNSPath returns the path of the network namespace of the
+// process pid if it is running, or the one declared in the spec of its
+// container otherwise. The paths of the spec under /proc, eg. the namespace
+// of the sandbox of a pod, are resolved under procRoot. The containers
+// without a network namespace in their spec share the one of the host.
+func ResolveNetNSPath(spec *oci.Spec, pid uint32, procRoot string) (string, error) {
+	if pid > 0 {
+		path := filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), "ns", "net")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	if spec == nil || spec.Linux == nil {
+		return "", fmt.Errorf("the container has no running task nor Linux spec")
+	}
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type != specs.NetworkNamespace {
+			continue
+		}
+		if ns.Path == "" {
+			// The namespace was created for the task, it is gone with it
+			return "", fmt.Errorf("the network namespace of the container is gone with its task")
+		}
+		if strings.HasPrefix(ns.Path, "/proc/") {
+			return filepath.Join(procRoot, strings.TrimPrefix(ns.Path, "/proc/")), nil
+		}
+		return ns.Path, nil
+	}
+	return filepath.Join(procRoot, "1", "ns", "net"), nil
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveNetNSPath(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "42", "ns"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "42", "ns", "net"), nil, 0644))
+
+	withNetNS := func(path string) *oci.Spec {
+		return &oci.Spec{Linux: &specs.Linux{Namespaces: []specs.LinuxNamespace{
+			{Type: specs.PIDNamespace},
+			{Type: specs.NetworkNamespace, Path: path},
+		}}}
+	}
+
+	for name, tc := range map[string]struct {
+		spec     *oci.Spec
+		pid      uint32
+		expected string
+	}{
+		"running task": {
+			spec:     withNetNS(""),
+			pid:      42,
+			expected: filepath.Join(procRoot, "42", "ns", "net"),
+		},
+		"sandbox namespace": {
+			spec:     withNetNS("/proc/7/ns/net"),
+			expected: filepath.Join(procRoot, "7", "ns", "net"),
+		},
+		"exited task in a cni namespace": {
+			spec:     withNetNS("/var/run/netns/cni-1234"),
+			pid:      43,
+			expected: "/var/run/netns/cni-1234",
+		},
+		"host network": {
+			spec:     &oci.Spec{Linux: &specs.Linux{Namespaces: []specs.LinuxNamespace{{Type: specs.PIDNamespace}}}},
+			expected: filepath.Join(procRoot, "1", "ns", "net"),
+		},
+		"namespace gone with the task": {
+			spec: withNetNS(""),
+		},
+		"no linux spec": {
+			spec: &oci.Spec{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, err := ResolveNetNSPath(tc.spec, tc.pid, procRoot)
+			if tc.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, path)
+		})
+	}
+}
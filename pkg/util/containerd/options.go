@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Default values used when an Options field is left empty
+const (
+	DefaultNamespace         = "k8s.io"
+	DefaultConnectionTimeout = 1 * time.Second
+	DefaultQueryTimeout      = 5 * time.Second
+	// gRPC servers reject keepalive pings sent more often than every
+	// 5 minutes by default, closing the connection.
+	DefaultKeepaliveTime    = 5 * time.Minute
+	DefaultKeepaliveTimeout = 20 * time.Second
+	// DefaultMaxConcurrentQueries bounds the queries sent in parallel
+	// by the batch collections
+	DefaultMaxConcurrentQueries = 10
+	// DefaultCacheMaxStaleness bounds the age of the container cache in
+	// case container events are missed
+	DefaultCacheMaxStaleness = 5 * time.Minute
+	// DefaultProcRoot and DefaultCgroupRoot are the host paths of the
+	// procfs and of the cgroup hierarchy
+	DefaultProcRoot   = "/proc"
+	DefaultCgroupRoot = "/sys/fs/cgroup"
+	// DefaultGRPCCallHistory is the number of gRPC calls kept for the
+	// flare when DebugGRPC is set
+	DefaultGRPCCallHistory = 100
+	// DefaultRateLimitBurst is the number of calls sent at once when the
+	// rate of the calls is limited
+	DefaultRateLimitBurst = 20
+)
+
+// Options holds the parameters used to connect to containerd.
+// Empty fields are replaced by their default value.
+type Options struct {
+	// SocketPath is the path to the containerd GRPC socket, or to its named
+	// pipe on Windows. If empty, the socket of Flavor is used, or else the
+	// first well-known socket found, or the socket of a rootless daemon.
+	SocketPath string
+	// Flavor is the distribution of containerd, whose paths are the
+	// default ones. It is detected from the socket if empty or auto.
+	Flavor Flavor
+	// Namespace is the containerd namespace queried by the util
+	Namespace string
+	// ConnectionTimeout bounds the initial connection to the socket
+	ConnectionTimeout time.Duration
+	// QueryTimeout bounds every call made to the containerd API
+	QueryTimeout time.Duration
+	// KeepaliveTime is the idle time after which the client pings the daemon
+	// to check the connection is alive
+	KeepaliveTime time.Duration
+	// HealthCheckInterval is the interval between the background probes of
+	// the daemon health, probes are disabled if zero
+	HealthCheckInterval time.Duration
+	// ConfigPath is the path to the configuration file of the daemon,
+	// read for the settings the containerd API does not expose
+	ConfigPath string
+	// StateDir is the state directory of the daemon, read for the shims of
+	// the tasks
+	StateDir string
+	// MaxConcurrentQueries is the maximum number of queries sent at the
+	// same time by the batch collections, like CollectAll
+	MaxConcurrentQueries int
+	// CacheMaxStaleness is the age after which the containers served by
+	// CachedContainers are listed again, even if no event was missed
+	CacheMaxStaleness time.Duration
+	// ProcRoot and CgroupRoot are the paths where the procfs and the cgroup
+	// hierarchy of the host are mounted, read for the pressure of the tasks
+	ProcRoot   string
+	CgroupRoot string
+	// DebugGRPC logs every gRPC call made to the daemon at debug level,
+	// and keeps the last GRPCCallHistory ones for the flare
+	DebugGRPC       bool
+	GRPCCallHistory int
+	// ViaClusterAgent serves the container metadata relayed by the
+	// cluster-agent instead of connecting to the socket, see Provider.SetRelay
+	ViaClusterAgent bool
+	// AllowTaskRestart enables RestartTask, the only method of the util
+	// changing the state of the containers
+	AllowTaskRestart bool
+	// EnvScrubPatterns extend DefaultEnvScrubPatterns, see EnvScrubber
+	EnvScrubPatterns []string
+	// RateLimit bounds the gRPC calls per second made to the daemon by all
+	// the utils, beyond RateLimitBurst calls at once. It is unlimited if
+	// zero.
+	RateLimit      float64
+	RateLimitBurst int
+	// Logger receives the util logs, pkg/util/log is used if nil
+	Logger Logger
+}
+
+// Logger is the subset of the pkg/util/log API used by the util.
+// It allows alternate callers to route the util logs to their own logger.
+type Logger interface {
+	Debugf(format string, params ...interface{})
+	Infof(format string, params ...interface{})
+	Warnf(format string, params ...interface{}) error
+	Errorf(format string, params ...interface{}) error
+}
+
+// LogModule is the module of the util logs in log_level_overrides
+const LogModule = "util/containerd"
+
+// agentLogger forwards the util logs to pkg/util/log, at the level of
+// LogModule
+type agentLogger struct{}
+
+func (agentLogger) Debugf(format string, params ...interface{}) {
+	log.ModuleDebugf(LogModule, format, params...)
+}
+
+func (agentLogger) Infof(format string, params ...interface{}) {
+	log.ModuleInfof(LogModule, format, params...)
+}
+
+func (agentLogger) Warnf(format string, params ...interface{}) error {
+	return log.ModuleWarnf(LogModule, format, params...)
+}
+
+func (agentLogger) Errorf(format string, params ...interface{}) error {
+	return log.ModuleErrorf(LogModule, format, params...)
+}
+
+// unknownFlavorWarning logs an unknown flavor once, as the options are
+// resolved at every configuration read
+var unknownFlavorWarning sync.Once
+
+// key identifies the util of the options: the callers share a util only if
+// every option matches, so that none of them runs with the timeouts, the
+// logger or the permissions of another caller
+func (o Options) key() string {
+	logger := fmt.Sprintf("%T", o.Logger)
+	if v := reflect.ValueOf(o.Logger); v.Kind() == reflect.Ptr {
+		logger += fmt.Sprintf("@%x", v.Pointer())
+	}
+	o.Logger = nil
+	return fmt.Sprintf("%+v|%s", o, logger)
+}
+
+// endpoint identifies the containerd endpoint targeted by the options
+func (o Options) endpoint() string {
+	return o.SocketPath + "|" + o.Namespace
+}
+
+// withDefaults returns a copy of the options where empty fields
+// are set to their default value.
+func (o Options) withDefaults() Options {
+	if o.Logger == nil {
+		o.Logger = agentLogger{}
+	}
+	if o.Flavor != "" && !o.Flavor.IsKnown() {
+		unknownFlavorWarning.Do(func() {
+			o.Logger.Warnf("Unknown containerd flavor %q, detecting it from the socket", o.Flavor)
+		})
+		o.Flavor = FlavorAuto
+	}
+	if o.Flavor == "" {
+		o.Flavor = FlavorAuto
+	}
+	if o.SocketPath == "" {
+		if o.Flavor == FlavorAuto || o.Flavor == FlavorUpstream {
+			o.SocketPath = discoverSocketPath(o.Logger)
+		} else {
+			o.SocketPath = o.Flavor.paths().socketPath
+		}
+	}
+	if o.Flavor == FlavorAuto {
+		o.Flavor = socketFlavor(o.SocketPath)
+	}
+	if o.Namespace == "" {
+		o.Namespace = DefaultNamespace
+	}
+	if o.ConnectionTimeout <= 0 {
+		o.ConnectionTimeout = DefaultConnectionTimeout
+	}
+	if o.QueryTimeout <= 0 {
+		o.QueryTimeout = DefaultQueryTimeout
+	}
+	if o.KeepaliveTime <= 0 {
+		o.KeepaliveTime = DefaultKeepaliveTime
+	}
+	if o.ConfigPath == "" {
+		o.ConfigPath = o.Flavor.paths().configPath
+	}
+	if o.StateDir == "" {
+		o.StateDir = o.Flavor.paths().stateDir
+	}
+	if o.MaxConcurrentQueries <= 0 {
+		o.MaxConcurrentQueries = DefaultMaxConcurrentQueries
+	}
+	if o.CacheMaxStaleness <= 0 {
+		o.CacheMaxStaleness = DefaultCacheMaxStaleness
+	}
+	if o.ProcRoot == "" {
+		o.ProcRoot = DefaultProcRoot
+	}
+	if o.CgroupRoot == "" {
+		o.CgroupRoot = DefaultCgroupRoot
+	}
+	if o.GRPCCallHistory <= 0 {
+		o.GRPCCallHistory = DefaultGRPCCallHistory
+	}
+	if o.RateLimitBurst <= 0 {
+		o.RateLimitBurst = DefaultRateLimitBurst
+	}
+	return o
+}
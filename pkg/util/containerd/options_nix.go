@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package containerd
+
+// Default paths used when an Options field is left empty
+const (
+	DefaultSocketPath = "/var/run/containerd/containerd.sock"
+	DefaultConfigPath = "/etc/containerd/config.toml"
+)
+
+// DefaultStateDir is the state directory of the upstream daemon
+const DefaultStateDir = "/run/containerd"
+
+// flavors are the paths of the flavors of containerd
+var flavors = map[Flavor]flavorPaths{
+	FlavorUpstream: {
+		socketPath: DefaultSocketPath,
+		configPath: DefaultConfigPath,
+		stateDir:   DefaultStateDir,
+	},
+	// k3s runs its embedded containerd under its own directories
+	FlavorK3s: {
+		socketPath: "/run/k3s/containerd/containerd.sock",
+		configPath: "/var/lib/rancher/k3s/agent/etc/containerd/config.toml",
+		stateDir:   "/run/k3s/containerd",
+	},
+	// Bottlerocket exposes the CRI socket of its host containerd to the pods,
+	// at the path of the CRI shim of docker on the dockershim nodes
+	FlavorBottlerocket: {
+		socketPath:   "/run/dockershim.sock",
+		configPath:   DefaultConfigPath,
+		stateDir:     DefaultStateDir,
+		explicitOnly: true,
+	},
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsWithDefaults(t *testing.T) {
+	opts := Options{}.withDefaults()
+	assert.Equal(t, DefaultSocketPath, opts.SocketPath)
+	assert.Equal(t, FlavorUpstream, opts.Flavor)
+	assert.Equal(t, DefaultNamespace, opts.Namespace)
+	assert.Equal(t, DefaultConnectionTimeout, opts.ConnectionTimeout)
+	assert.Equal(t, DefaultQueryTimeout, opts.QueryTimeout)
+	assert.Equal(t, DefaultKeepaliveTime, opts.KeepaliveTime)
+	assert.Equal(t, time.Duration(0), opts.HealthCheckInterval)
+	assert.Equal(t, DefaultConfigPath, opts.ConfigPath)
+	assert.Equal(t, DefaultStateDir, opts.StateDir)
+	assert.Equal(t, DefaultMaxConcurrentQueries, opts.MaxConcurrentQueries)
+	assert.Equal(t, DefaultCacheMaxStaleness, opts.CacheMaxStaleness)
+	assert.Equal(t, DefaultProcRoot, opts.ProcRoot)
+	assert.Equal(t, DefaultCgroupRoot, opts.CgroupRoot)
+	assert.False(t, opts.DebugGRPC)
+	assert.Equal(t, DefaultGRPCCallHistory, opts.GRPCCallHistory)
+	assert.Equal(t, float64(0), opts.RateLimit)
+	assert.Equal(t, DefaultRateLimitBurst, opts.RateLimitBurst)
+	assert.Equal(t, agentLogger{}, opts.Logger)
+
+	custom := Options{
+		SocketPath:           "/run/k3s/containerd/containerd.sock",
+		Flavor:               FlavorK3s,
+		Namespace:            "moby",
+		ConnectionTimeout:    3 * time.Second,
+		QueryTimeout:         10 * time.Second,
+		KeepaliveTime:        time.Minute,
+		HealthCheckInterval:  30 * time.Second,
+		ConfigPath:           "/var/lib/rancher/k3s/agent/etc/containerd/config.toml",
+		StateDir:             "/run/k3s/containerd",
+		MaxConcurrentQueries: 4,
+		CacheMaxStaleness:    time.Minute,
+		ProcRoot:             "/host/proc",
+		CgroupRoot:           "/host/sys/fs/cgroup",
+		DebugGRPC:            true,
+		GRPCCallHistory:      20,
+		RateLimit:            50,
+		RateLimitBurst:       10,
+		Logger:               agentLogger{},
+	}
+	assert.Equal(t, custom, custom.withDefaults())
+}
+
+func TestOptionsFlavor(t *testing.T) {
+	// The paths of the flavor are the defaults
+	k3s := Options{Flavor: FlavorK3s}.withDefaults()
+	assert.Equal(t, "/run/k3s/containerd/containerd.sock", k3s.SocketPath)
+	assert.Equal(t, "/var/lib/rancher/k3s/agent/etc/containerd/config.toml", k3s.ConfigPath)
+	assert.Equal(t, "/run/k3s/containerd", k3s.StateDir)
+	assert.Equal(t, DefaultNamespace, k3s.Namespace)
+
+	// The flavor is detected from the socket
+	assert.Equal(t, FlavorK3s, Options{SocketPath: "/run/k3s/containerd/containerd.sock"}.withDefaults().Flavor)
+
+	// The dockershim socket can be the CRI shim of docker, it is not
+	// detected as Bottlerocket
+	assert.Equal(t, FlavorUpstream, Options{SocketPath: "/run/dockershim.sock"}.withDefaults().Flavor)
+	bottlerocket := Options{Flavor: FlavorBottlerocket}.withDefaults()
+	assert.Equal(t, "/run/dockershim.sock", bottlerocket.SocketPath)
+	assert.Equal(t, DefaultConfigPath, bottlerocket.ConfigPath)
+	assert.Equal(t, DefaultStateDir, bottlerocket.StateDir)
+
+	// The explicit paths take precedence
+	custom := Options{Flavor: FlavorK3s, SocketPath: "/host/run/k3s.sock", StateDir: "/host/run/k3s"}.withDefaults()
+	assert.Equal(t, FlavorK3s, custom.Flavor)
+	assert.Equal(t, "/host/run/k3s.sock", custom.SocketPath)
+	assert.Equal(t, "/host/run/k3s", custom.StateDir)
+
+	unknown := Options{Flavor: "microk8s", SocketPath: "/run/k3s/containerd/containerd.sock"}.withDefaults()
+	assert.Equal(t, FlavorK3s, unknown.Flavor)
+	assert.False(t, Flavor("microk8s").IsKnown())
+	assert.True(t, FlavorAuto.IsKnown())
+}
+
+// namedLogger is a Logger of a caller of the util
+type namedLogger struct {
+	agentLogger
+	name string
+}
+
+func TestOptionsKey(t *testing.T) {
+	k8s := Options{Namespace: "k8s.io"}.withDefaults()
+	moby := Options{Namespace: "moby"}.withDefaults()
+	assert.NotEqual(t, k8s.key(), moby.key())
+
+	assert.Equal(t, k8s.key(), Options{Namespace: "k8s.io"}.withDefaults().key())
+
+	// The callers with other options get their own util
+	slower := Options{Namespace: "k8s.io", QueryTimeout: time.Minute}.withDefaults()
+	assert.NotEqual(t, k8s.key(), slower.key())
+	restart := Options{Namespace: "k8s.io", AllowTaskRestart: true}.withDefaults()
+	assert.NotEqual(t, k8s.key(), restart.key())
+	scrubbed := Options{Namespace: "k8s.io", EnvScrubPatterns: []string{"*TOKEN*"}}.withDefaults()
+	assert.NotEqual(t, k8s.key(), scrubbed.key())
+	logged := Options{Namespace: "k8s.io", Logger: &namedLogger{name: "caller"}}.withDefaults()
+	assert.NotEqual(t, k8s.key(), logged.key())
+	assert.NotEqual(t, logged.key(), Options{Namespace: "k8s.io", Logger: &namedLogger{name: "caller"}}.withDefaults().key())
+
+	// The endpoint is the same
+	assert.Equal(t, k8s.endpoint(), slower.endpoint())
+}
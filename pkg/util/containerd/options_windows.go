@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+// Default paths used when an Options field is left empty, containerd
+// listens on a named pipe on Windows
+const (
+	DefaultSocketPath = `\\.\pipe\containerd-containerd`
+	DefaultConfigPath = `C:\Program Files\containerd\config.toml`
+	DefaultStateDir   = `C:\ProgramData\containerd\state`
+)
+
+// flavors are the paths of the flavors of containerd, only the upstream
+// daemon runs on Windows
+var flavors = map[Flavor]flavorPaths{
+	FlavorUpstream: {
+		socketPath: DefaultSocketPath,
+		configPath: DefaultConfigPath,
+		stateDir:   DefaultStateDir,
+	},
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// MinimumVersion is the oldest containerd release the agent supports
+const MinimumVersion = "1.1.0"
+
+func init() {
+	socketPreflight = CheckSocket
+}
+
+// CheckSocket returns an error explaining how to fix the socket if the
+// agent cannot use it: the socket is missing, is not a socket, or is not
+// readable and writable by the agent user.
+func CheckSocket(socketPath string) error {
+	fi, err := os.Stat(socketPath)
+	if os.IsNotExist(err) {
+		return &Error{
+			Kind: ErrNotServing,
+			Err:  fmt.Errorf("%s does not exist, mount the containerd socket in the agent container or set cri_socket_path to its location", socketPath),
+		}
+	}
+	if err != nil {
+		return &Error{Kind: classifyKind(err), Err: fmt.Errorf("cannot stat %s: %s", socketPath, err)}
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return &Error{
+			Kind: ErrNotServing,
+			Err:  fmt.Errorf("%s is not a socket (mode %s), set cri_socket_path to the containerd socket", socketPath, fi.Mode()),
+		}
+	}
+
+	if err := unix.Access(socketPath, unix.R_OK|unix.W_OK); err != nil {
+		hint := "run the agent as root"
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			hint = fmt.Sprintf("the socket is owned by uid %d and gid %d with mode %s, run the agent as root or add the agent user to group %d and make the socket group writable",
+				st.Uid, st.Gid, fi.Mode().Perm(), st.Gid)
+		}
+		return &Error{
+			Kind: ErrPermissionDenied,
+			Err:  fmt.Errorf("the agent user (uid %d) cannot read and write %s: %s, %s", os.Geteuid(), socketPath, err, hint),
+		}
+	}
+	return nil
+}
+
+// classifyKind returns the kind of a filesystem error on the socket
+func classifyKind(err error) error {
+	if os.IsPermission(err) {
+		return ErrPermissionDenied
+	}
+	return ErrNotServing
+}
+
+// CheckVersion returns an error if the daemon version is older than
+// MinimumVersion. Unparsable versions, like development builds, are accepted.
+func CheckVersion(v string) error {
+	if !versionAtLeast(v, MinimumVersion) {
+		return fmt.Errorf("containerd %s is not supported, upgrade containerd to %s or later", v, MinimumVersion)
+	}
+	return nil
+}
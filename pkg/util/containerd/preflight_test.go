@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-preflight")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "containerd.sock")
+	err = CheckSocket(socketPath)
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+	assert.Contains(t, err.Error(), "cri_socket_path")
+
+	filePath := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(filePath, nil, 0644))
+	err = CheckSocket(filePath)
+	assert.Equal(t, ErrNotServing, ErrorKind(err))
+	assert.Contains(t, err.Error(), "is not a socket")
+
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.NoError(t, CheckSocket(socketPath))
+
+	if os.Geteuid() == 0 {
+		// Root bypasses the permission checks
+		return
+	}
+	require.NoError(t, os.Chmod(socketPath, 0400))
+	err = CheckSocket(socketPath)
+	assert.Equal(t, ErrPermissionDenied, ErrorKind(err))
+	assert.Contains(t, err.Error(), "add the agent user to group")
+}
+
+func TestCheckVersion(t *testing.T) {
+	assert.NoError(t, CheckVersion("v1.4.3"))
+	assert.NoError(t, CheckVersion("1.1.0"))
+	assert.NoError(t, CheckVersion("2.0.0-rc.1"))
+	assert.NoError(t, CheckVersion("dev"))
+
+	err := CheckVersion("v1.0.3")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade containerd to 1.1.0")
+}
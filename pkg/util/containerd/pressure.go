@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Pressure is the pressure stall information of a resource, read from the
+// <resource>.pressure files of cgroup v2. Full is not reported for the CPU
+// by the kernels older than 5.13.
+type Pressure struct {
+	Some PressureData
+	Full PressureData
+}
+
+// PressureData is the share of time some or all the tasks were stalled,
+// averaged over 10, 60 and 300 seconds in percents, and the total stall
+// time in microseconds
+type PressureData struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// TaskPressure is the pressure stall information of the cgroup of a task
+type TaskPressure struct {
+	CPU    *Pressure
+	Memory *Pressure
+	IO     *Pressure
+}
+
+// TaskPressure returns the pressure stall information of the cgroup of the
+// process pid, usually the init process of a task. It is only available on
+// cgroup v2 hosts, an ErrUnsupported error is returned on cgroup v1 ones.
+// The resources whose pressure file is missing, like on kernels built
+// without PSI, are left nil.
+func (c *ContainerdUtil) TaskPressure(pid uint32) (*TaskPressure, error) {
+	if !isCgroupV2Host() {
+		return nil, &Error{Kind: ErrUnsupported, Err: fmt.Errorf("the pressure stall information requires a cgroup v2 host")}
+	}
+	cgroupPath, err := unifiedCgroupPath(filepath.Join(c.procRoot, strconv.FormatUint(uint64(pid), 10), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(c.cgroupRoot, cgroupPath)
+
+	tp := &TaskPressure{}
+	for resource, p := range map[string]**Pressure{"cpu": &tp.CPU, "memory": &tp.Memory, "io": &tp.IO} {
+		*p, err = readPressure(filepath.Join(dir, resource+".pressure"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tp, nil
+}
+
+// unifiedCgroupPath returns the path of the cgroup v2 entry of a
+// /proc/<pid>/cgroup file, the "0::<path>" line
+func unifiedCgroupPath(procCgroup string) (string, error) {
+	f, err := os.Open(procCgroup)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in %s", procCgroup)
+}
+
+// readPressure parses a pressure file, nil is returned if it does not exist
+func readPressure(file string) (*Pressure, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := parsePressure(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", file, err)
+	}
+	return p, nil
+}
+
+// parsePressure parses a pressure file, eg.
+//   some avg10=0.00 avg60=0.12 avg300=0.05 total=16521
+//   full avg10=0.00 avg60=0.00 avg300=0.00 total=8765
+func parsePressure(r io.Reader) (*Pressure, error) {
+	p := &Pressure{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var data *PressureData
+		switch fields[0] {
+		case "some":
+			data = &p.Some
+		case "full":
+			data = &p.Full
+		default:
+			return nil, fmt.Errorf("unexpected pressure line %q", scanner.Text())
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("unexpected pressure field %q", field)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				data.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				data.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				data.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				data.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unexpected pressure field %q: %s", field, err)
+			}
+		}
+	}
+	return p, scanner.Err()
+}
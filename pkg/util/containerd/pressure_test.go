@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure(strings.NewReader(
+		"some avg10=1.50 avg60=0.12 avg300=0.05 total=16521\n" +
+			"full avg10=0.75 avg60=0.00 avg300=0.00 total=8765\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &Pressure{
+		Some: PressureData{Avg10: 1.5, Avg60: 0.12, Avg300: 0.05, Total: 16521},
+		Full: PressureData{Avg10: 0.75, Total: 8765},
+	}, p)
+
+	_, err = parsePressure(strings.NewReader("some avg10=abc avg60=0.00 avg300=0.00 total=0\n"))
+	assert.Error(t, err)
+	_, err = parsePressure(strings.NewReader("partial avg10=0.00\n"))
+	assert.Error(t, err)
+}
+
+func TestTaskPressure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-pressure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	procRoot := filepath.Join(dir, "proc")
+	cgroupRoot := filepath.Join(dir, "cgroup")
+	cgroupDir := filepath.Join(cgroupRoot, "kubepods", "pod1", "redis")
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "42"), 0755))
+	require.NoError(t, os.MkdirAll(cgroupDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "42", "cgroup"), []byte("0::/kubepods/pod1/redis\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupDir, "cpu.pressure"), []byte("some avg10=2.00 avg60=1.00 avg300=0.50 total=100\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupDir, "memory.pressure"), []byte("some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"), 0644))
+
+	c := newContainerdUtil(Options{ProcRoot: procRoot, CgroupRoot: cgroupRoot}.withDefaults())
+
+	defer func(f func() bool) { isCgroupV2Host = f }(isCgroupV2Host)
+	isCgroupV2Host = func() bool { return false }
+	_, err = c.TaskPressure(42)
+	assert.Equal(t, ErrUnsupported, ErrorKind(err))
+
+	isCgroupV2Host = func() bool { return true }
+	tp, err := c.TaskPressure(42)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, tp.CPU.Some.Avg10)
+	assert.NotNil(t, tp.Memory)
+	assert.Nil(t, tp.IO)
+
+	_, err = c.TaskPressure(43)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// The calls of every util share the same limiter, as they are served by the
+// same daemon, sized by the options of the first util enabling RateLimit
+var (
+	callLimiter    *rate.Limiter
+	callLimiterMux sync.Mutex
+	// rateLimitedCalls counts the calls delayed or failed by the limiter
+	rateLimitedCalls = new(expvar.Int)
+)
+
+func init() {
+	containerdExpvars.Set("RateLimitedCalls", rateLimitedCalls)
+}
+
+// sharedCallLimiter returns the limiter of the calls of every util,
+// created with the given rate and burst if it does not exist yet
+func sharedCallLimiter(limit float64, burst int) *rate.Limiter {
+	callLimiterMux.Lock()
+	defer callLimiterMux.Unlock()
+	if callLimiter == nil {
+		callLimiter = rate.NewLimiter(rate.Limit(limit), burst)
+	}
+	return callLimiter
+}
+
+// rateLimitInterceptors returns the dial options bounding the rate of the
+// gRPC calls made to the daemon, so that the agent does not overload a
+// struggling daemon, eg. on a node under pressure. The calls wait for the
+// limiter within their deadline, and fail with ErrTimeout if it is too
+// short. A stream, like an event subscription, counts as one call.
+func (c *ContainerdUtil) rateLimitInterceptors() []grpc.DialOption {
+	limiter := sharedCallLimiter(c.rateLimit, c.rateLimitBurst)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if err := waitCallLimiter(ctx, limiter, method); err != nil {
+				return err
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if err := waitCallLimiter(ctx, limiter, method); err != nil {
+				return nil, err
+			}
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+	}
+}
+
+func waitCallLimiter(ctx context.Context, limiter *rate.Limiter, method string) error {
+	if limiter.Allow() {
+		return nil
+	}
+	rateLimitedCalls.Add(1)
+	if err := limiter.Wait(ctx); err != nil {
+		return &Error{Kind: ErrTimeout, Err: fmt.Errorf("call %s rate limited: %s", method, err)}
+	}
+	return nil
+}
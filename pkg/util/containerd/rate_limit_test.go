@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestWaitCallLimiter(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 2)
+	limited := rateLimitedCalls.Value()
+
+	// The burst is sent at once
+	assert.NoError(t, waitCallLimiter(context.Background(), limiter, "/containerd.services.tasks.v1.Tasks/Metrics"))
+	assert.NoError(t, waitCallLimiter(context.Background(), limiter, "/containerd.services.tasks.v1.Tasks/Metrics"))
+	assert.Equal(t, limited, rateLimitedCalls.Value())
+
+	// The calls waiting past their deadline fail
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := waitCallLimiter(ctx, limiter, "/containerd.services.tasks.v1.Tasks/Metrics")
+	assert.Equal(t, ErrTimeout, ErrorKind(err))
+	assert.True(t, IsTransient(err))
+	assert.Equal(t, limited+1, rateLimitedCalls.Value())
+}
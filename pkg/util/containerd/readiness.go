@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// readinessPollInterval is the interval of the connection attempts while
+// waiting for the readiness, the retrier of the utils paces the dials
+const readinessPollInterval = 5 * time.Second
+
+var globalReadiness = newReadiness()
+
+// readiness is closed on the first util returned by GetContainerdUtil
+type readiness struct {
+	once  sync.Once
+	ready chan struct{}
+}
+
+func newReadiness() *readiness {
+	return &readiness{ready: make(chan struct{})}
+}
+
+func (r *readiness) markReady() {
+	r.once.Do(func() { close(r.ready) })
+}
+
+// wait returns a channel closed once ready or after maxWait, calling
+// connect every interval meanwhile for the connection to be attempted even
+// if nothing else gets a util
+func (r *readiness) wait(maxWait, interval time.Duration, connect func() error) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		timeout := time.NewTimer(maxWait)
+		defer timeout.Stop()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		connect()
+		for {
+			select {
+			case <-r.ready:
+				return
+			case <-timeout.C:
+				log.Warnf("containerd is still unavailable after %s, not waiting for it anymore", maxWait)
+				return
+			case <-ticker.C:
+				connect()
+			}
+		}
+	}()
+	return done
+}
+
+// Ready returns a channel closed once GetContainerdUtil returned a util
+// for the first time
+func Ready() <-chan struct{} {
+	return globalReadiness.ready
+}
+
+// WaitReady returns a channel closed once GetContainerdUtil returned a util
+// for the first time, or after maxWait. The connection to the socket of
+// the configuration is attempted meanwhile. It is the readiness gate of the
+// checks relying on containerd, see check.RegisterReadinessGate.
+func WaitReady(maxWait time.Duration) <-chan struct{} {
+	return globalReadiness.wait(maxWait, readinessPollInterval, func() error {
+		_, err := GetContainerdUtil(nil)
+		return err
+	})
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessWait(t *testing.T) {
+	r := newReadiness()
+	var attempts int32
+	done := r.wait(time.Minute, 10*time.Millisecond, func() error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("not started")
+		}
+		r.markReady()
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "not ready after the connection")
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&attempts))
+	// Marking it ready again is a no-op
+	r.markReady()
+}
+
+func TestReadinessMaxWait(t *testing.T) {
+	r := newReadiness()
+	done := r.wait(20*time.Millisecond, time.Hour, func() error {
+		return errors.New("not started")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "still waiting past the max wait")
+	}
+	select {
+	case <-r.ready:
+		assert.Fail(t, "ready without a connection")
+	default:
+	}
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// hostsFileName is the file describing the hosts of a registry, in the
+// directories of the CRI registry config_path
+const hostsFileName = "hosts.toml"
+
+// RegistryMirror is an endpoint the CRI plugin pulls the images of a registry from
+type RegistryMirror struct {
+	// Registry is the host the mirror stands for, eg. docker.io
+	Registry string
+	// Endpoint is the URL of the mirror, eg. https://mirror.local:5000
+	Endpoint string
+}
+
+// criRegistryConfig is the registry section of the CRI plugin configuration
+type criRegistryConfig struct {
+	ConfigPath string `toml:"config_path"`
+	Mirrors    map[string]struct {
+		Endpoints []string `toml:"endpoint"`
+	} `toml:"mirrors"`
+	// Configs hold the credentials of the registries, by host
+	Configs map[string]struct {
+		Auth *registryAuth `toml:"auth"`
+	} `toml:"configs"`
+}
+
+// pluginsConfig holds the registry section of the CRI plugin. The plugin
+// is named "cri" in version 1 of the configuration and
+// "io.containerd.grpc.v1.cri" in version 2.
+type pluginsConfig struct {
+	Plugins map[string]struct {
+		Registry criRegistryConfig `toml:"registry"`
+	} `toml:"plugins"`
+}
+
+// hostsConfig is the content of a hosts.toml file
+type hostsConfig struct {
+	Hosts map[string]map[string]interface{} `toml:"host"`
+}
+
+// RegistryMirrors returns the registry mirrors configured in the CRI plugin
+func (c *ContainerdUtil) RegistryMirrors() ([]RegistryMirror, error) {
+	return ReadRegistryMirrors(c.configPath)
+}
+
+// ReadRegistryMirrors returns the registry mirrors declared in a containerd
+// configuration file, either inline or in the hosts.toml files of the
+// registry config_path.
+func ReadRegistryMirrors(configPath string) ([]RegistryMirror, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg pluginsConfig
+	if err = toml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", configPath, err)
+	}
+
+	var mirrors []RegistryMirror
+	for _, plugin := range cfg.Plugins {
+		for registry, mirror := range plugin.Registry.Mirrors {
+			for _, endpoint := range mirror.Endpoints {
+				mirrors = append(mirrors, RegistryMirror{Registry: registry, Endpoint: endpoint})
+			}
+		}
+		if plugin.Registry.ConfigPath != "" {
+			hosts, err := readHostsDirectories(plugin.Registry.ConfigPath)
+			if err != nil {
+				return nil, err
+			}
+			mirrors = append(mirrors, hosts...)
+		}
+	}
+
+	sort.Slice(mirrors, func(i, j int) bool {
+		if mirrors[i].Registry != mirrors[j].Registry {
+			return mirrors[i].Registry < mirrors[j].Registry
+		}
+		return mirrors[i].Endpoint < mirrors[j].Endpoint
+	})
+	return mirrors, nil
+}
+
+// readHostsDirectories reads the mirrors of the <config_path>/<registry>/hosts.toml files.
+// The config_path can hold several directories, separated as in the PATH variable.
+func readHostsDirectories(configPath string) ([]RegistryMirror, error) {
+	var mirrors []RegistryMirror
+	for _, root := range filepath.SplitList(configPath) {
+		dirs, err := ioutil.ReadDir(root)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(root, dir.Name(), hostsFileName))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			var hosts hostsConfig
+			if err = toml.Unmarshal(data, &hosts); err != nil {
+				return nil, fmt.Errorf("cannot parse the hosts of %s: %s", dir.Name(), err)
+			}
+			for endpoint := range hosts.Hosts {
+				mirrors = append(mirrors, RegistryMirror{Registry: dir.Name(), Endpoint: endpoint})
+			}
+		}
+	}
+	return mirrors, nil
+}
+
+// CheckRegistryMirror queries the version check endpoint of the registry API
+// on a mirror. Authentication challenges are expected from private mirrors and
+// don't make them unhealthy.
+func CheckRegistryMirror(client *http.Client, endpoint string) error {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	resp, err := client.Get(strings.TrimSuffix(endpoint, "/") + "/v2/")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusUnauthorized:
+		return nil
+	default:
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+}
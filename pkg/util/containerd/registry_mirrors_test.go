@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRegistryMirrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-mirrors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certsDir := filepath.Join(dir, "certs.d")
+	require.NoError(t, os.MkdirAll(filepath.Join(certsDir, "quay.io"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(certsDir, "gcr.io"), 0755))
+	hosts := `server = "https://quay.io"
+
+[host."https://quay-mirror.local"]
+  capabilities = ["pull", "resolve"]
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(certsDir, "quay.io", "hosts.toml"), []byte(hosts), 0644))
+
+	for name, tc := range map[string]struct {
+		config   string
+		expected []RegistryMirror
+	}{
+		"version 1": {
+			config: `[plugins.cri.registry.mirrors."docker.io"]
+  endpoint = ["https://mirror.local:5000", "https://registry-1.docker.io"]
+`,
+			expected: []RegistryMirror{
+				{Registry: "docker.io", Endpoint: "https://mirror.local:5000"},
+				{Registry: "docker.io", Endpoint: "https://registry-1.docker.io"},
+			},
+		},
+		"version 2 with config_path": {
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "` + certsDir + `"
+
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["https://mirror.local:5000"]
+`,
+			expected: []RegistryMirror{
+				{Registry: "docker.io", Endpoint: "https://mirror.local:5000"},
+				{Registry: "quay.io", Endpoint: "https://quay-mirror.local"},
+			},
+		},
+		"no mirrors": {
+			config: `[plugins.cri]
+  stream_server_address = "127.0.0.1"
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.toml")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.config), 0644))
+
+			mirrors, err := ReadRegistryMirrors(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, mirrors)
+		})
+	}
+
+	_, err = ReadRegistryMirrors(filepath.Join(dir, "missing.toml"))
+	assert.Error(t, err)
+}
+
+func TestCheckRegistryMirror(t *testing.T) {
+	var status int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		status  int
+		healthy bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusNotFound, false},
+		{http.StatusServiceUnavailable, false},
+	} {
+		status = tc.status
+		err := CheckRegistryMirror(ts.Client(), ts.URL+"/")
+		assert.Equal(t, tc.healthy, err == nil, "status %d", tc.status)
+	}
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	refdocker "github.com/containerd/containerd/reference/docker"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/pelletier/go-toml"
+)
+
+// registryAuth holds the credentials of a registry in the CRI plugin
+// configuration
+type registryAuth struct {
+	Username      string `toml:"username"`
+	Password      string `toml:"password"`
+	Auth          string `toml:"auth"`
+	IdentityToken string `toml:"identitytoken"`
+}
+
+// credentials returns the credentials of the docker authorizer, the
+// identity token is sent with an empty user name
+func (a registryAuth) credentials() (string, string, error) {
+	switch {
+	case a.IdentityToken != "":
+		return "", a.IdentityToken, nil
+	case a.Auth != "":
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("cannot decode the auth: %s", err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return "", "", fmt.Errorf("the auth is not a user:password pair")
+		}
+		return parts[0], parts[1], nil
+	}
+	return a.Username, a.Password, nil
+}
+
+// readRegistryAuths returns the credentials of the registries configured
+// in the CRI plugin, by host. The configuration file is optional.
+func readRegistryAuths(configPath string) (map[string]registryAuth, error) {
+	auths := make(map[string]registryAuth)
+	data, err := ioutil.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return auths, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg pluginsConfig
+	if err = toml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", configPath, err)
+	}
+	for _, plugin := range cfg.Plugins {
+		for host, config := range plugin.Registry.Configs {
+			if config.Auth != nil {
+				auths[host] = *config.Auth
+			}
+		}
+	}
+	return auths, nil
+}
+
+// RegistryOf returns the registry host of an image reference, docker.io
+// for the references without registry, empty if it is malformed
+func RegistryOf(ref string) string {
+	named, err := refdocker.ParseDockerRef(ref)
+	if err != nil {
+		return ""
+	}
+	return refdocker.Domain(named)
+}
+
+// ProbeRegistry resolves an image reference with the resolver of the
+// containerd client, authenticated with the credentials the CRI plugin
+// configured at configPath pulls from its registry with, to check that the
+// registry is reachable and that the credentials are valid. The daemon is
+// not queried. A NotFound error is returned if the registry is reachable
+// but the reference does not exist.
+func ProbeRegistry(ctx context.Context, configPath, ref string, client *http.Client) error {
+	named, err := refdocker.ParseDockerRef(ref)
+	if err != nil {
+		return err
+	}
+	auths, err := readRegistryAuths(configPath)
+	if err != nil {
+		return err
+	}
+	creds := func(host string) (string, string, error) {
+		auth, found := auths[host]
+		if !found && host == "registry-1.docker.io" {
+			auth = auths["docker.io"]
+		}
+		return auth.credentials()
+	}
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(
+			docker.WithClient(client),
+			docker.WithAuthorizer(docker.NewDockerAuthorizer(
+				docker.WithAuthClient(client),
+				docker.WithAuthCreds(creds),
+			)),
+		),
+	})
+	_, _, err = resolver.Resolve(ctx, named.String())
+	return err
+}
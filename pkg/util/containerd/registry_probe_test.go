@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeRegistry(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "ci" || password != "s3cr3t" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/team/app/manifests/1.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		w.Header().Set("Content-Length", "512")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer registry.Close()
+	host := strings.TrimPrefix(registry.URL, "https://")
+
+	dir, err := ioutil.TempDir("", "containerd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeConfig := func(auth string) string {
+		path := filepath.Join(dir, "config.toml")
+		require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(`version = 2
+[plugins."io.containerd.grpc.v1.cri".registry.configs."%s".auth]
+%s
+`, host, auth)), 0644))
+		return path
+	}
+	ctx := context.Background()
+
+	config := writeConfig(`username = "ci"
+password = "s3cr3t"`)
+	assert.NoError(t, ProbeRegistry(ctx, config, host+"/team/app:1.0", registry.Client()))
+	err = ProbeRegistry(ctx, config, host+"/team/app:2.0", registry.Client())
+	assert.True(t, errdefs.IsNotFound(err), "unexpected error: %v", err)
+
+	// The credentials have expired
+	config = writeConfig(fmt.Sprintf(`auth = "%s"`, base64.StdEncoding.EncodeToString([]byte("ci:expired"))))
+	err = ProbeRegistry(ctx, config, host+"/team/app:1.0", registry.Client())
+	assert.Error(t, err)
+	assert.False(t, errdefs.IsNotFound(err))
+
+	// Without configuration, the registry is probed anonymously
+	err = ProbeRegistry(ctx, filepath.Join(dir, "missing.toml"), host+"/team/app:1.0", registry.Client())
+	assert.Error(t, err)
+}
+
+func TestRegistryOf(t *testing.T) {
+	assert.Equal(t, "docker.io", RegistryOf("redis"))
+	assert.Equal(t, "registry.example.com:5000", RegistryOf("registry.example.com:5000/team/app:1.0"))
+	assert.Equal(t, "", RegistryOf("Invalid Reference"))
+}
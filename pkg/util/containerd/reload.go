@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+// UpdateConfig switches the options of the agent configuration, see
+// ConfigOptions, to next if its endpoint changed, eg. when the agent
+// configuration file is reloaded. The utils of the previous endpoint are
+// closed, with their resolvers and cgroup indexes, so that the next calls to
+// Get, Resolve and the cgroup lookups use the new one, the event
+// subscriptions of the closed utils end with an error. Every namespace of
+// the previous socket is closed if the socket changed, only the previous
+// namespace otherwise.
+func (p *Provider) UpdateConfig(next Options) {
+	next = next.withDefaults()
+	p.mu.Lock()
+	if p.config == nil || p.config.endpoint() == next.endpoint() {
+		// Unchanged, or not resolved by ConfigOptions yet
+		p.mu.Unlock()
+		return
+	}
+	previous := *p.config
+	p.config = &next
+	p.mu.Unlock()
+
+	socketChanged := previous.SocketPath != next.SocketPath
+	stale := p.forget(func(util *ContainerdUtil) bool {
+		return util.socketPath == previous.SocketPath && (socketChanged || util.namespace == previous.Namespace)
+	})
+
+	next.Logger.Infof("The containerd endpoint changed from %s (namespace %s) to %s (namespace %s), reconnecting",
+		previous.SocketPath, previous.Namespace, next.SocketPath, next.Namespace)
+	for _, util := range stale {
+		if err := util.Close(); err != nil {
+			next.Logger.Debugf("Cannot close the containerd client of %s: %s", util.socketPath, err)
+		}
+	}
+}
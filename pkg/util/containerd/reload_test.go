@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderUpdateConfig(t *testing.T) {
+	provider := NewProvider()
+	register := func(socketPath, namespace string) (Options, *ContainerdUtil) {
+		opts := Options{SocketPath: socketPath, Namespace: namespace}.withDefaults()
+		util := newContainerdUtil(opts)
+		provider.utils[opts.key()] = util
+		return opts, util
+	}
+	k8s, k8sUtil := register("/var/run/containerd/containerd.sock", "k8s.io")
+	_, mobyUtil := register("/var/run/containerd/containerd.sock", "moby")
+	_, otherUtil := register("/var/run/other/containerd.sock", "k8s.io")
+	resolver := NewContainerResolver(k8sUtil)
+	provider.resolvers[k8sUtil] = resolver
+	provider.cgroupIndexes[k8sUtil] = NewCgroupIndex(k8sUtil)
+
+	// The options are not resolved yet, nothing is closed
+	next := k8s
+	next.Namespace = "default"
+	provider.UpdateConfig(next)
+	assert.Nil(t, provider.config)
+	assert.Len(t, provider.utils, 3)
+
+	// The endpoint is unchanged
+	provider.config = &k8s
+	provider.UpdateConfig(k8s)
+	assert.Len(t, provider.utils, 3)
+
+	// Only the namespace changed, the other namespaces are kept
+	provider.UpdateConfig(next)
+	assert.NotContains(t, provider.utils, k8s.key())
+	assert.Len(t, provider.utils, 2)
+	assert.True(t, isClosed(k8sUtil))
+	assert.False(t, isClosed(mobyUtil))
+	assert.Equal(t, next, *provider.config)
+	// The resolvers and cgroup indexes of the closed util are dropped
+	assert.Empty(t, provider.resolvers)
+	assert.Empty(t, provider.cgroupIndexes)
+	_, open := <-resolver.stop
+	assert.False(t, open)
+	// The closed util does not connect again
+	assert.Equal(t, ErrNotServing, ErrorKind(k8sUtil.EnsureConnected()))
+	assert.Equal(t, ErrNotServing, ErrorKind(k8sUtil.doConnect()))
+	assert.Nil(t, k8sUtil.client())
+
+	// Every namespace of the previous socket is closed
+	moved := next
+	moved.SocketPath = "/run/containerd/containerd.sock"
+	provider.UpdateConfig(moved)
+	assert.Len(t, provider.utils, 1)
+	assert.True(t, isClosed(mobyUtil))
+	assert.False(t, isClosed(otherUtil))
+}
+
+func isClosed(util *ContainerdUtil) bool {
+	select {
+	case <-util.stopProbe:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+const (
+	containerDeleteTopic = "/containers/delete"
+	// resolverReconnectDelay is the time waited before subscribing
+	// again to the event service after an error
+	resolverReconnectDelay = 10 * time.Second
+)
+
+// ErrNotContainerdEntity is returned when resolving a container ID
+// that belongs to another runtime than containerd.
+var ErrNotContainerdEntity = errors.New("not a containerd container ID")
+
+// ContainerResolver caches the containerd Container handles by ID, so that
+// resolving the container of a kubelet container ID does not require
+// listing all the containers. Entries are removed when containerd sends
+// the matching delete event.
+type ContainerResolver struct {
+	util ContainerdItf
+
+	sync.RWMutex
+	containers map[string]containerd.Container
+	stop       chan struct{}
+}
+
+// NewContainerResolver returns a ContainerResolver backed by util.
+// Start must be called for the cache to be invalidated on container deletion.
+func NewContainerResolver(util ContainerdItf) *ContainerResolver {
+	return &ContainerResolver{
+		util:       util,
+		containers: make(map[string]containerd.Container),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Resolve returns the containerd Container matching an entity ID
+// (container_id://<id>), a kubelet container ID (containerd://<id>) or a
+// raw container ID, using the resolver of the util of the agent
+// configuration, see Provider.Resolver.
+func Resolve(containerID string) (containerd.Container, error) {
+	r, err := globalProvider.Resolver(nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(containerID)
+}
+
+// ResolveNamespace is Resolve in a namespace of the socket of the agent
+// configuration, the namespace of the configuration if empty
+func ResolveNamespace(namespace, containerID string) (containerd.Container, error) {
+	if namespace == "" {
+		return Resolve(containerID)
+	}
+	opts := globalProvider.ConfigOptions()
+	opts.Namespace = namespace
+	r, err := globalProvider.Resolver(&opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(containerID)
+}
+
+// Resolve returns the containerd Container matching an entity ID
+// (container_id://<id>), a kubelet container ID (containerd://<id>) or a
+// raw container ID.
+func (r *ContainerResolver) Resolve(containerID string) (containerd.Container, error) {
+	id := containerID
+	if types.IsEntityName(containerID) {
+		entity := types.CanonicalEntityName(containerID)
+		if !strings.HasPrefix(entity, types.ContainerEntityPrefix) {
+			return nil, ErrNotContainerdEntity
+		}
+		id = strings.TrimPrefix(entity, types.ContainerEntityPrefix)
+	}
+
+	r.RLock()
+	ctn, found := r.containers[id]
+	r.RUnlock()
+	if found {
+		return ctn, nil
+	}
+
+	ctn, err := r.util.LoadContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	r.Lock()
+	r.containers[id] = ctn
+	r.Unlock()
+
+	return ctn, nil
+}
+
+// Start listens to the containerd events to invalidate the cache
+func (r *ContainerResolver) Start() {
+	go r.listen()
+}
+
+// Stop stops listening to the containerd events
+func (r *ContainerResolver) Stop() {
+	close(r.stop)
+}
+
+// listen processes the delete events until Stop is called, re-subscribing
+// to the event service on errors.
+func (r *ContainerResolver) listen() {
+	for {
+		ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), r.util.Namespace()))
+		envelopes, errs := r.util.GetEvents().Subscribe(ctx, NewNamespaceFilter([]string{r.util.Namespace()}, nil).EventFilters(`topic=="`+containerDeleteTopic+`"`)...)
+
+	RECEIVE:
+		for {
+			select {
+			case <-r.stop:
+				cancel()
+				return
+			case envelope := <-envelopes:
+				r.handleEvent(envelope)
+			case err := <-errs:
+				logFor(r.util).Warnf("Error receiving containerd events, waiting for %s: %s", resolverReconnectDelay, err)
+				cancel()
+				// Entries deleted while disconnected would be missed
+				r.flush()
+				select {
+				case <-r.stop:
+					return
+				case <-time.After(resolverReconnectDelay):
+				}
+				break RECEIVE
+			}
+		}
+	}
+}
+
+// handleEvent removes the deleted containers from the cache
+func (r *ContainerResolver) handleEvent(envelope *events.Envelope) {
+	if envelope == nil || envelope.Topic != containerDeleteTopic {
+		return
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		logFor(r.util).Debugf("Cannot decode containerd event: %s", err)
+		return
+	}
+	deleted, ok := ev.(*apievents.ContainerDelete)
+	if !ok {
+		return
+	}
+	r.Lock()
+	delete(r.containers, deleted.ID)
+	r.Unlock()
+}
+
+// flush empties the cache
+func (r *ContainerResolver) flush() {
+	r.Lock()
+	r.containers = make(map[string]containerd.Container)
+	r.Unlock()
+}
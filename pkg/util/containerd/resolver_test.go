@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	loads := 0
+	itf := &mockItf{
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			loads++
+			return &mockContainer{id: id}, nil
+		},
+	}
+	r := NewContainerResolver(itf)
+
+	ctn, err := r.Resolve("containerd://foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", ctn.ID())
+	ctn, err = r.Resolve("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", ctn.ID())
+	ctn, err = r.Resolve(EntityID("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", ctn.ID())
+	assert.Equal(t, 1, loads)
+
+	_, err = r.Resolve("docker://foo")
+	assert.Equal(t, ErrNotContainerdEntity, err)
+	assert.Equal(t, 1, loads)
+}
+
+func TestResolverHandleEvent(t *testing.T) {
+	loads := 0
+	itf := &mockItf{
+		mockLoadContainer: func(id string) (containerd.Container, error) {
+			loads++
+			return &mockContainer{id: id}, nil
+		},
+	}
+	r := NewContainerResolver(itf)
+	_, err := r.Resolve("containerd://foo")
+	require.NoError(t, err)
+
+	deleted, err := typeurl.MarshalAny(&apievents.ContainerDelete{ID: "foo"})
+	require.NoError(t, err)
+	r.handleEvent(&events.Envelope{Topic: containerDeleteTopic, Event: deleted})
+	assert.Len(t, r.containers, 0)
+
+	_, err = r.Resolve("containerd://foo")
+	require.NoError(t, err)
+	assert.Equal(t, 2, loads)
+}
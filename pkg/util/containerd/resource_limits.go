@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"github.com/containerd/containerd/oci"
+)
+
+// defaultCFSPeriod is the CFS period of the kernel, in microseconds, used
+// when the spec sets a quota without period
+const defaultCFSPeriod = 100000
+
+// ResourceLimits are the CPU and memory limits set in the spec of a
+// container, zero if the container is unlimited
+type ResourceLimits struct {
+	// CPUs is the number of CPUs the container can use, its CFS quota
+	// divided by its period
+	CPUs float64
+	// Memory is the memory limit in bytes
+	Memory uint64
+}
+
+// SpecResourceLimits returns the limits set in the spec of a container
+func SpecResourceLimits(spec *oci.Spec) ResourceLimits {
+	var limits ResourceLimits
+	if spec == nil || spec.Linux == nil || spec.Linux.Resources == nil {
+		return limits
+	}
+	resources := spec.Linux.Resources
+	if cpu := resources.CPU; cpu != nil && cpu.Quota != nil && *cpu.Quota > 0 {
+		period := uint64(defaultCFSPeriod)
+		if cpu.Period != nil && *cpu.Period > 0 {
+			period = *cpu.Period
+		}
+		limits.CPUs = float64(*cpu.Quota) / float64(period)
+	}
+	if memory := resources.Memory; memory != nil && memory.Limit != nil && *memory.Limit > 0 {
+		limits.Memory = uint64(*memory.Limit)
+	}
+	return limits
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecResourceLimits(t *testing.T) {
+	signed := func(v int64) *int64 { return &v }
+	unsigned := func(v uint64) *uint64 { return &v }
+
+	for _, tc := range []struct {
+		name     string
+		spec     *oci.Spec
+		expected ResourceLimits
+	}{
+		{
+			name: "no spec",
+		},
+		{
+			name: "unlimited",
+			spec: &oci.Spec{Linux: &specs.Linux{Resources: &specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: signed(-1), Period: unsigned(100000)},
+				Memory: &specs.LinuxMemory{Limit: signed(-1)},
+			}}},
+		},
+		{
+			name: "limited",
+			spec: &oci.Spec{Linux: &specs.Linux{Resources: &specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: signed(50000), Period: unsigned(20000)},
+				Memory: &specs.LinuxMemory{Limit: signed(256 << 20)},
+			}}},
+			expected: ResourceLimits{CPUs: 2.5, Memory: 256 << 20},
+		},
+		{
+			name: "default period",
+			spec: &oci.Spec{Linux: &specs.Linux{Resources: &specs.LinuxResources{
+				CPU: &specs.LinuxCPU{Quota: signed(50000)},
+			}}},
+			expected: ResourceLimits{CPUs: 0.5},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, SpecResourceLimits(tc.spec))
+		})
+	}
+}
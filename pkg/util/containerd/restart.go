@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// restartStopTimeout is the time given to the task to exit after SIGTERM,
+// it is killed with SIGKILL then
+const restartStopTimeout = 10 * time.Second
+
+// RestartTask stops the task of a container, with SIGTERM then SIGKILL after
+// 10 seconds, and starts a new one, for the remediations of the containers
+// run without an orchestrator. It is disabled unless AllowTaskRestart is set,
+// an ErrRestartDisabled error is returned then.
+// Every restart is logged at info level, for audit.
+// The new task writes its outputs to the log URI of the stopped one, they
+// are discarded if the stopped task wrote them to FIFOs.
+func (c *ContainerdUtil) RestartTask(ctx context.Context, id string) error {
+	log := c.containerLog(id)
+	if !c.allowTaskRestart {
+		log.Infof("Audit: denied the restart of the container: %s", ErrRestartDisabled)
+		return &Error{Kind: ErrRestartDisabled, Err: ErrRestartDisabled}
+	}
+	log.Infof("Audit: restarting the task of the container")
+	err := c.restartTask(namespaces.WithNamespace(ctx, c.namespace), id)
+	if err != nil {
+		log.Infof("Audit: failed to restart the task of the container: %s", err)
+		return err
+	}
+	log.Infof("Audit: restarted the task of the container")
+	return nil
+}
+
+func (c *ContainerdUtil) restartTask(ctx context.Context, id string) error {
+	ctn, err := c.client().LoadContainer(ctx, id)
+	if err != nil {
+		return classifyError(err)
+	}
+	task, err := ctn.Task(ctx, cio.Load)
+	if err != nil {
+		return classifyError(err)
+	}
+	var ioConfig cio.Config
+	if task.IO() != nil {
+		ioConfig = task.IO().Config()
+	}
+
+	if err := c.stopTask(ctx, task); err != nil {
+		return err
+	}
+	if _, err := task.Delete(ctx); err != nil {
+		return classifyError(err)
+	}
+
+	ioCreator := cio.NullIO
+	if logURI := restartLogURI(ioConfig); logURI != nil {
+		ioCreator = cio.LogURI(logURI)
+	}
+	task, err = ctn.NewTask(ctx, ioCreator)
+	if err != nil {
+		return classifyError(err)
+	}
+	return classifyError(task.Start(ctx))
+}
+
+// stopTask sends SIGTERM to the task and waits for it to exit, it is
+// killed if it is still running after restartStopTimeout
+func (c *ContainerdUtil) stopTask(ctx context.Context, task containerd.Task) error {
+	exitCh, err := task.Wait(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+	if err := task.Kill(ctx, syscall.SIGTERM); err != nil {
+		return classifyError(err)
+	}
+	select {
+	case <-exitCh:
+		return nil
+	case <-time.After(restartStopTimeout):
+	case <-ctx.Done():
+		return &Error{Kind: ErrTimeout, Err: ctx.Err()}
+	}
+	c.containerLog(task.ID()).Infof("Audit: task still running %s after SIGTERM, killing it", restartStopTimeout)
+	if err := task.Kill(ctx, syscall.SIGKILL); err != nil {
+		return classifyError(err)
+	}
+	select {
+	case <-exitCh:
+		return nil
+	case <-ctx.Done():
+		return &Error{Kind: ErrTimeout, Err: ctx.Err()}
+	}
+}
+
+// restartLogURI returns the log URI the outputs of a task are written to,
+// or nil if they are written to FIFOs only readable by the client which
+// created the task
+func restartLogURI(cfg cio.Config) *url.URL {
+	u, err := url.Parse(cfg.Stdout)
+	if err != nil {
+		return nil
+	}
+	switch u.Scheme {
+	case "binary", "file":
+		return u
+	}
+	return nil
+}
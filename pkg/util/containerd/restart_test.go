@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/cio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartTaskDisabled(t *testing.T) {
+	cu := newContainerdUtil(Options{}.withDefaults())
+	// The client is not used, the restart is denied first
+	assert.Equal(t, ErrRestartDisabled, ErrorKind(cu.RestartTask(context.Background(), "foo")))
+}
+
+func TestRestartLogURI(t *testing.T) {
+	for stdout, expected := range map[string]string{
+		"binary:///usr/local/bin/nerdctl?_NERDCTL_INTERNAL_LOGGING=/var/lib/nerdctl": "binary:///usr/local/bin/nerdctl?_NERDCTL_INTERNAL_LOGGING=/var/lib/nerdctl",
+		"file:///var/log/containers/foo.log":                                         "file:///var/log/containers/foo.log",
+		"/run/containerd/fifo/1234/foo-stdout":                                       "",
+		"":                                                                           "",
+	} {
+		logURI := restartLogURI(cio.Config{Stdout: stdout})
+		if expected == "" {
+			assert.Nil(t, logURI, stdout)
+		} else {
+			assert.Equal(t, expected, logURI.String())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd/sandbox"
+)
+
+// Labels set by the CRI plugin on the pod sandboxes and their containers
+const (
+	kubernetesPodNameLabel      = "io.kubernetes.pod.name"
+	kubernetesPodNamespaceLabel = "io.kubernetes.pod.namespace"
+	kubernetesPodUIDLabel       = "io.kubernetes.pod.uid"
+	// criKindLabel is sandbox on the container running the sandbox itself
+	criKindLabel = "io.cri-containerd.kind"
+)
+
+// PodContainersFilter is the WalkContainers filter matching the containers
+// of the pods, sandboxes included
+const PodContainersFilter = `labels."` + kubernetesPodNameLabel + `"`
+
+// SandboxStateUnknown is the state of the sandboxes whose controller
+// status cannot be queried
+const SandboxStateUnknown = "unknown"
+
+// Sandboxes returns the sandboxes of the namespace. The sandbox API is
+// served by containerd 1.7 and later, an ErrUnsupported error is returned
+// by older releases.
+func (c *ContainerdUtil) Sandboxes() ([]sandbox.Sandbox, error) {
+	if err := c.checkSandboxSupport(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	sandboxes, err := c.client().SandboxStore().List(ctx)
+	return sandboxes, classifyError(err)
+}
+
+// SandboxStatus returns the status of a sandbox reported by its controller
+func (c *ContainerdUtil) SandboxStatus(id string) (sandbox.ControllerStatus, error) {
+	if err := c.checkSandboxSupport(); err != nil {
+		return sandbox.ControllerStatus{}, err
+	}
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	status, err := c.client().SandboxController().Status(ctx, id, false)
+	return status, classifyError(err)
+}
+
+// PodSandbox is a pod sandbox with the containers it holds
+type PodSandbox struct {
+	ID           string
+	PodName      string
+	PodNamespace string
+	PodUID       string
+	// State is the lowercase state reported by the sandbox controller,
+	// eg. sandbox_ready, or SandboxStateUnknown
+	State string
+	// Containers are the IDs of the workload containers of the sandbox
+	Containers []string
+}
+
+// ListPodSandboxes returns the sandboxes of the namespace with their state
+// and containers, sorted by ID. The containers are matched to the sandboxes
+// through the sandbox ID of their record, and the pod of a sandbox is read
+// from the CRI labels of the sandbox or else of its containers, so that the
+// pods are known without querying the kubelet.
+func ListPodSandboxes(cu ContainerdItf) ([]PodSandbox, error) {
+	sandboxes, err := cu.Sandboxes()
+	if err != nil {
+		return nil, err
+	}
+	ctns, err := cu.CachedContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make(map[string]*PodSandbox, len(sandboxes))
+	for _, sb := range sandboxes {
+		pod := &PodSandbox{ID: sb.ID, State: SandboxStateUnknown}
+		setPodFromLabels(pod, sb.Labels)
+		if status, err := cu.SandboxStatus(sb.ID); err == nil && status.State != "" {
+			pod.State = strings.ToLower(status.State)
+		}
+		pods[sb.ID] = pod
+	}
+
+	for _, ctn := range ctns {
+		pod, found := pods[ctn.SandboxID]
+		if !found || ctn.Labels[criKindLabel] == "sandbox" {
+			continue
+		}
+		pod.Containers = append(pod.Containers, ctn.ID)
+		setPodFromLabels(pod, ctn.Labels)
+	}
+
+	podList := make([]PodSandbox, 0, len(pods))
+	for _, pod := range pods {
+		sort.Strings(pod.Containers)
+		podList = append(podList, *pod)
+	}
+	sort.Slice(podList, func(i, j int) bool { return podList[i].ID < podList[j].ID })
+	return podList, nil
+}
+
+// PodMember is the pod of a container created by the CRI plugin
+type PodMember struct {
+	PodName      string
+	PodNamespace string
+	PodUID       string
+	// Sandbox is set on the container running the sandbox itself, eg. the
+	// pause container
+	Sandbox bool
+}
+
+// PodMemberOf returns the pod of a container read from its CRI labels, and
+// false for the containers not created by the CRI plugin
+func PodMemberOf(labels map[string]string) (PodMember, bool) {
+	m := PodMember{
+		PodName:      labels[kubernetesPodNameLabel],
+		PodNamespace: labels[kubernetesPodNamespaceLabel],
+		PodUID:       labels[kubernetesPodUIDLabel],
+		Sandbox:      labels[criKindLabel] == "sandbox",
+	}
+	return m, m.PodName != ""
+}
+
+// setPodFromLabels fills the pod fields not known yet from CRI labels
+func setPodFromLabels(pod *PodSandbox, labels map[string]string) {
+	if pod.PodName == "" {
+		pod.PodName = labels[kubernetesPodNameLabel]
+	}
+	if pod.PodNamespace == "" {
+		pod.PodNamespace = labels[kubernetesPodNamespaceLabel]
+	}
+	if pod.PodUID == "" {
+		pod.PodUID = labels[kubernetesPodUIDLabel]
+	}
+}
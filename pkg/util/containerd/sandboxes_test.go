@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/containerd/containerd/sandbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPodSandboxes(t *testing.T) {
+	podLabels := map[string]string{
+		kubernetesPodNameLabel:      "redis-0",
+		kubernetesPodNamespaceLabel: "default",
+		kubernetesPodUIDLabel:       "f3fb6a6a-9d51-11e8-8b6e-42010a840061",
+	}
+	cu := &mockItf{
+		mockSandboxes: func() ([]sandbox.Sandbox, error) {
+			return []sandbox.Sandbox{
+				{ID: "sb-redis"},
+				{ID: "sb-nginx", Labels: map[string]string{kubernetesPodNameLabel: "nginx-1", kubernetesPodNamespaceLabel: "web"}},
+			}, nil
+		},
+		mockSandboxStatus: func(id string) (sandbox.ControllerStatus, error) {
+			if id == "sb-nginx" {
+				return sandbox.ControllerStatus{}, errors.New("shim not found")
+			}
+			return sandbox.ControllerStatus{SandboxID: id, State: "SANDBOX_READY"}, nil
+		},
+		mockCachedContainers: func() ([]CachedContainer, error) {
+			return []CachedContainer{
+				{ID: "sb-redis", SandboxID: "sb-redis", Labels: map[string]string{criKindLabel: "sandbox"}},
+				{ID: "redis", SandboxID: "sb-redis", Labels: podLabels},
+				{ID: "redis-exporter", SandboxID: "sb-redis", Labels: podLabels},
+				{ID: "standalone"},
+			}, nil
+		},
+	}
+
+	pods, err := ListPodSandboxes(cu)
+	require.NoError(t, err)
+	assert.Equal(t, []PodSandbox{
+		{
+			ID:           "sb-nginx",
+			PodName:      "nginx-1",
+			PodNamespace: "web",
+			State:        SandboxStateUnknown,
+		},
+		{
+			ID:           "sb-redis",
+			PodName:      "redis-0",
+			PodNamespace: "default",
+			PodUID:       "f3fb6a6a-9d51-11e8-8b6e-42010a840061",
+			State:        "sandbox_ready",
+			Containers:   []string{"redis", "redis-exporter"},
+		},
+	}, pods)
+}
+
+func TestListPodSandboxesUnsupported(t *testing.T) {
+	cu := &mockItf{
+		mockSandboxes: func() ([]sandbox.Sandbox, error) {
+			return nil, &Error{Kind: ErrUnsupported, Err: errors.New("containerd 1.6.2 does not serve the sandbox API")}
+		},
+	}
+	_, err := ListPodSandboxes(cu)
+	assert.Equal(t, ErrUnsupported, ErrorKind(err))
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"encoding/json"
+
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/typeurl/v2"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+// SecurityProfileFromSpec returns the security profile set in the spec of
+// a container. The seccomp filters allowing every syscall, like the one of
+// the privileged containers, leave the container unconfined.
+func SecurityProfileFromSpec(spec *oci.Spec) types.SecurityProfile {
+	profile := types.SecurityProfile{SeccompMode: types.SeccompModeUnconfined}
+	if spec == nil {
+		return profile
+	}
+	if spec.Process != nil {
+		profile.SELinuxLabel = spec.Process.SelinuxLabel
+		profile.AppArmorProfile = spec.Process.ApparmorProfile
+	}
+	if spec.Linux != nil && spec.Linux.Seccomp != nil {
+		seccomp := spec.Linux.Seccomp
+		if seccomp.DefaultAction != specs.ActAllow || len(seccomp.Syscalls) > 0 {
+			profile.SeccompMode = types.SeccompModeFilter
+		}
+	}
+	return profile
+}
+
+// decodeRecordSpec returns the spec of a container record, nil if the
+// record has no spec or if it cannot be decoded
+func decodeRecordSpec(spec typeurl.Any) *oci.Spec {
+	if spec == nil || len(spec.GetValue()) == 0 {
+		return nil
+	}
+	var s oci.Spec
+	if err := json.Unmarshal(spec.GetValue(), &s); err != nil {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+func TestSecurityProfileFromSpec(t *testing.T) {
+	spec := &oci.Spec{
+		Process: &specs.Process{
+			SelinuxLabel:    "system_u:system_r:container_t:s0:c1,c2",
+			ApparmorProfile: "cri-containerd.apparmor.d",
+		},
+		Linux: &specs.Linux{
+			Seccomp: &specs.LinuxSeccomp{
+				DefaultAction: specs.ActErrno,
+				Syscalls:      []specs.LinuxSyscall{{Names: []string{"read"}, Action: specs.ActAllow}},
+			},
+		},
+	}
+	assert.Equal(t, types.SecurityProfile{
+		SELinuxLabel:    "system_u:system_r:container_t:s0:c1,c2",
+		AppArmorProfile: "cri-containerd.apparmor.d",
+		SeccompMode:     types.SeccompModeFilter,
+	}, SecurityProfileFromSpec(spec))
+
+	// A filter allowing every syscall does not confine the container
+	spec.Linux.Seccomp = &specs.LinuxSeccomp{DefaultAction: specs.ActAllow}
+	assert.Equal(t, types.SeccompModeUnconfined, SecurityProfileFromSpec(spec).SeccompMode)
+	spec.Linux.Seccomp = nil
+	assert.Equal(t, types.SeccompModeUnconfined, SecurityProfileFromSpec(spec).SeccompMode)
+
+	value, err := json.Marshal(spec)
+	require.NoError(t, err)
+	decoded := decodeRecordSpec(&anypb.Any{TypeUrl: "types.containerd.io/opencontainers/runtime-spec/1/Spec", Value: value})
+	require.NotNil(t, decoded)
+	assert.Equal(t, "cri-containerd.apparmor.d", SecurityProfileFromSpec(decoded).AppArmorProfile)
+	assert.Nil(t, decodeRecordSpec(nil))
+}
@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package containerd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rootlessSocketSuffix is the path of the rootless containerd socket
+// relative to the XDG_RUNTIME_DIR of the user running the daemon
+const rootlessSocketSuffix = "containerd/containerd.sock"
+
+var (
+	// wellKnownSocketPaths are the sockets of the system daemon on the
+	// common distributions, by order of preference, overridden in tests
+	wellKnownSocketPaths = []string{
+		DefaultSocketPath,
+		"/run/containerd/containerd.sock",
+		flavors[FlavorK3s].socketPath,
+	}
+	// runtimeDirsGlob matches the XDG_RUNTIME_DIR of every user of the host,
+	// where per-user rootless daemons create their socket
+	runtimeDirsGlob = "/run/user/*"
+	// getenv is overridden in tests
+	getenv = os.Getenv
+
+	multipleSocketsWarning sync.Once
+
+	// selectedSocketPath is the last socket discovered, logged when it changes
+	selectedSocketPath    string
+	selectedSocketPathMux sync.Mutex
+)
+
+// discoverSocketPath returns the first of the well-known containerd sockets
+// that exists, eg. the socket of k3s. Otherwise, it falls
+// back to the socket of a rootless daemon: the one of the agent user first,
+// then the one of the user with the lowest uid. The default socket is
+// returned if no socket is found.
+func discoverSocketPath(logger Logger) string {
+	for _, path := range wellKnownSocketPaths {
+		if isSocket(path) {
+			logSelectedSocket(logger, path, "")
+			return path
+		}
+	}
+	defaultPath := wellKnownSocketPaths[0]
+	sockets := rootlessSockets()
+	if len(sockets) == 0 {
+		return defaultPath
+	}
+	if len(sockets) > 1 {
+		multipleSocketsWarning.Do(func() {
+			logger.Warnf("Found several rootless containerd daemons (%s), only %s is monitored. Set cri_socket_path to select another one.",
+				strings.Join(sockets, ", "), sockets[0])
+		})
+	}
+	logSelectedSocket(logger, sockets[0], "rootless ")
+	return sockets[0]
+}
+
+// logSelectedSocket logs the socket discovered, once until another one is
+// discovered, as the options are resolved at every configuration read
+func logSelectedSocket(logger Logger, path, kind string) {
+	selectedSocketPathMux.Lock()
+	defer selectedSocketPathMux.Unlock()
+	if path == selectedSocketPath {
+		return
+	}
+	selectedSocketPath = path
+	logger.Infof("cri_socket_path is not set, using the %scontainerd socket %s", kind, path)
+}
+
+// rootlessSockets lists the sockets of the rootless containerd daemons
+// running on the host, the one of the agent user being first.
+func rootlessSockets() []string {
+	var sockets []string
+	seen := make(map[string]bool)
+
+	if runtimeDir := getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		path := filepath.Join(runtimeDir, rootlessSocketSuffix)
+		if isSocket(path) {
+			sockets = append(sockets, path)
+			seen[path] = true
+		}
+	}
+
+	dirs, _ := filepath.Glob(runtimeDirsGlob)
+	// Sort by uid rather than lexically, so that 1000 comes before 10000
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirUID(dirs[i]) < dirUID(dirs[j])
+	})
+	for _, dir := range dirs {
+		path := filepath.Join(dir, rootlessSocketSuffix)
+		if !seen[path] && isSocket(path) {
+			sockets = append(sockets, path)
+			seen[path] = true
+		}
+	}
+	return sockets
+}
+
+// dirUID returns the uid a /run/user/<uid> directory belongs to
+func dirUID(dir string) int {
+	uid, err := strconv.Atoi(filepath.Base(dir))
+	if err != nil {
+		return -1
+	}
+	return uid
+}
+
+func isSocket(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
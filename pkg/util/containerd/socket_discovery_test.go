@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package containerd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenSocket(t *testing.T, path string) net.Listener {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	return l
+}
+
+func TestDiscoverSocketPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rootless")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(wellKnown []string, glob string, env func(string) string) {
+		wellKnownSocketPaths, runtimeDirsGlob, getenv = wellKnown, glob, env
+	}(wellKnownSocketPaths, runtimeDirsGlob, getenv)
+	primarySocketPath := filepath.Join(dir, "containerd.sock")
+	k3sSocketPath := filepath.Join(dir, "k3s", "containerd.sock")
+	wellKnownSocketPaths = []string{primarySocketPath, k3sSocketPath}
+	runtimeDirsGlob = filepath.Join(dir, "user", "*")
+	xdgRuntimeDir := ""
+	getenv = func(key string) string {
+		if key == "XDG_RUNTIME_DIR" {
+			return xdgRuntimeDir
+		}
+		return ""
+	}
+
+	// No daemon, the default socket is kept
+	assert.Equal(t, primarySocketPath, discoverSocketPath(agentLogger{}))
+
+	// Rootless daemons are sorted by uid
+	user10000 := filepath.Join(dir, "user", "10000", rootlessSocketSuffix)
+	defer listenSocket(t, user10000).Close()
+	user1000 := filepath.Join(dir, "user", "1000", rootlessSocketSuffix)
+	defer listenSocket(t, user1000).Close()
+	assert.Equal(t, []string{user1000, user10000}, rootlessSockets())
+	assert.Equal(t, user1000, discoverSocketPath(agentLogger{}))
+
+	// The daemon of the agent user comes first
+	xdgRuntimeDir = filepath.Join(dir, "user", "10000")
+	assert.Equal(t, []string{user10000, user1000}, rootlessSockets())
+	assert.Equal(t, user10000, discoverSocketPath(agentLogger{}))
+
+	// Files that are not sockets are ignored
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "user", "1001", "containerd"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "user", "1001", rootlessSocketSuffix), nil, 0600))
+	assert.Len(t, rootlessSockets(), 2)
+
+	// The system daemon is preferred, eg. the one of k3s
+	k3s := listenSocket(t, k3sSocketPath)
+	assert.Equal(t, k3sSocketPath, discoverSocketPath(agentLogger{}))
+	k3s.Close()
+
+	// The well-known sockets are probed in order
+	defer listenSocket(t, k3sSocketPath).Close()
+	defer listenSocket(t, primarySocketPath).Close()
+	assert.Equal(t, primarySocketPath, discoverSocketPath(agentLogger{}))
+	assert.Equal(t, primarySocketPath, Options{}.withDefaults().SocketPath)
+
+	// A configured socket is never overridden
+	assert.Equal(t, "/run/k3s/containerd/containerd.sock", Options{SocketPath: "/run/k3s/containerd/containerd.sock"}.withDefaults().SocketPath)
+}
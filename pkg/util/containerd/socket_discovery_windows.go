@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containerd
+
+// discoverSocketPath returns the default named pipe, there are no rootless
+// daemons to fall back to on Windows
+func discoverSocketPath(logger Logger) string {
+	return DefaultSocketPath
+}
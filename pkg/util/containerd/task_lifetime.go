@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"sync"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl/v2"
+)
+
+const (
+	// maxTaskLifetimes bounds the lifetimes kept until they are flushed,
+	// the oldest ones are dropped first
+	maxTaskLifetimes = 1000
+	// maxConcurrentTaskSamples bounds the samples read at once, when a
+	// batch of containers starts
+	maxConcurrentTaskSamples = 4
+)
+
+// TaskLifetimeFilters are the subscription filters matching the events
+// handled by a TaskLifetimeTracker
+var TaskLifetimeFilters = []string{
+	`topic=="` + TaskStartTopic + `"`,
+	`topic=="` + TaskExitTopic + `"`,
+}
+
+// TaskSample is a snapshot of a running task, see TaskSampler
+type TaskSample struct {
+	Stats *TaskStats
+	// Tags are the tags of the container, read while it exists
+	Tags []string
+}
+
+// TaskSampler reads a TaskSample of the task of a container
+type TaskSampler func(namespace, containerID string) (TaskSample, error)
+
+// TaskLifetime is the resource usage of the task of a container over its
+// lifetime, from the samples read when it started and when it exited
+type TaskLifetime struct {
+	Namespace   string
+	ContainerID string
+	Tags        []string
+	StartedAt   time.Time
+	ExitedAt    time.Time
+	ExitStatus  uint32
+	// Samples is the number of samples read, the usage is unknown if zero
+	Samples int
+	// CPUTotal and the I/O bytes are cumulated over the lifetime, the
+	// memory is the peak usage, or the highest usage sampled if the
+	// kernel does not report the peak
+	CPUTotal   uint64
+	MaxMemory  uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	OOMKills   uint64
+}
+
+// Duration returns the time the task ran
+func (l TaskLifetime) Duration() time.Duration {
+	return l.ExitedAt.Sub(l.StartedAt)
+}
+
+func (l *TaskLifetime) add(sample TaskSample) {
+	if len(sample.Tags) > 0 {
+		l.Tags = sample.Tags
+	}
+	stats := sample.Stats
+	if stats == nil {
+		return
+	}
+	l.Samples++
+	// The cumulated values of a task do not decrease
+	l.CPUTotal = maxUint64(l.CPUTotal, stats.CPUTotal)
+	l.MaxMemory = maxUint64(l.MaxMemory, maxUint64(stats.MemoryMaxUsage, stats.MemoryUsage))
+	l.OOMKills = maxUint64(l.OOMKills, stats.OOMKills)
+	var read, write uint64
+	for _, d := range stats.Devices {
+		read += d.ReadBytes
+		write += d.WriteBytes
+	}
+	l.ReadBytes = maxUint64(l.ReadBytes, read)
+	l.WriteBytes = maxUint64(l.WriteBytes, write)
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// TaskLifetimeTracker captures the usage of the tasks living less than the
+// interval of the checks, like the ones of the batch jobs, that would not
+// be collected otherwise. The task is sampled as soon as it starts, and
+// once more when it exits, before its cgroup is deleted with the task.
+// Only the tasks started since the tracker was created are tracked.
+type TaskLifetimeTracker struct {
+	sample TaskSampler
+	sem    chan struct{}
+	// pending counts the samples being read
+	pending sync.WaitGroup
+
+	mu sync.Mutex
+	// running are the tasks started, by namespace and container ID
+	running   map[string]*TaskLifetime
+	completed []TaskLifetime
+}
+
+// NewTaskLifetimeTracker returns a TaskLifetimeTracker reading the
+// samples of the tasks with sample
+func NewTaskLifetimeTracker(sample TaskSampler) *TaskLifetimeTracker {
+	return &TaskLifetimeTracker{
+		sample:  sample,
+		sem:     make(chan struct{}, maxConcurrentTaskSamples),
+		running: make(map[string]*TaskLifetime),
+	}
+}
+
+// HandleEnvelope processes an event matching TaskLifetimeFilters, the
+// samples are read in the background
+func (t *TaskLifetimeTracker) HandleEnvelope(envelope *events.Envelope) error {
+	if envelope == nil {
+		return nil
+	}
+	ev, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		return err
+	}
+
+	switch e := ev.(type) {
+	case *apievents.TaskStart:
+		key := envelope.Namespace + "/" + e.ContainerID
+		lifetime := &TaskLifetime{
+			Namespace:   envelope.Namespace,
+			ContainerID: e.ContainerID,
+			StartedAt:   envelope.Timestamp,
+		}
+		t.mu.Lock()
+		t.running[key] = lifetime
+		t.mu.Unlock()
+		t.sampleAsync(lifetime, func(sample TaskSample) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// The task may have exited meanwhile
+			if t.running[key] == lifetime {
+				lifetime.add(sample)
+			}
+		})
+	case *apievents.TaskExit:
+		// Processes exec'd in the container exit with their own ID
+		if e.ID != e.ContainerID {
+			break
+		}
+		key := envelope.Namespace + "/" + e.ContainerID
+		t.mu.Lock()
+		lifetime, found := t.running[key]
+		delete(t.running, key)
+		t.mu.Unlock()
+		if !found {
+			break
+		}
+		t.sampleAsync(lifetime, func(sample TaskSample) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			lifetime.add(sample)
+			lifetime.ExitedAt = envelope.Timestamp
+			lifetime.ExitStatus = e.ExitStatus
+			t.completed = append(t.completed, *lifetime)
+			if len(t.completed) > maxTaskLifetimes {
+				t.completed = t.completed[len(t.completed)-maxTaskLifetimes:]
+			}
+		})
+	}
+	return nil
+}
+
+// sampleAsync samples the task of a lifetime in the background, done is
+// called with an empty sample if it cannot be read
+func (t *TaskLifetimeTracker) sampleAsync(lifetime *TaskLifetime, done func(TaskSample)) {
+	t.pending.Add(1)
+	go func() {
+		defer t.pending.Done()
+		t.sem <- struct{}{}
+		sample, err := t.sample(lifetime.Namespace, lifetime.ContainerID)
+		<-t.sem
+		if err != nil {
+			withFields(agentLogger{}, "namespace", lifetime.Namespace, "container_id", lifetime.ContainerID).Debugf("Cannot sample the task: %s", err)
+		}
+		done(sample)
+	}()
+}
+
+// Flush returns the lifetimes of the tasks exited since the last flush
+func (t *TaskLifetimeTracker) Flush() []TaskLifetime {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	completed := t.completed
+	t.completed = nil
+	return completed
+}
@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskLifetimeTracker(t *testing.T) {
+	var mu sync.Mutex
+	samples := map[string][]TaskSample{
+		"job": {
+			{Stats: &TaskStats{CPUTotal: 1000, MemoryUsage: 2048}, Tags: []string{"kube_job:backup"}},
+			{Stats: &TaskStats{CPUTotal: 5000, MemoryUsage: 1024, MemoryMaxUsage: 4096, Devices: []DeviceIO{
+				{Major: 8, ReadBytes: 512, WriteBytes: 64},
+				{Major: 259, WriteBytes: 128},
+			}}},
+		},
+	}
+	tracker := NewTaskLifetimeTracker(func(namespace, containerID string) (TaskSample, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "k8s.io", namespace)
+		if len(samples[containerID]) == 0 {
+			return TaskSample{}, errors.New("task not found")
+		}
+		sample := samples[containerID][0]
+		samples[containerID] = samples[containerID][1:]
+		return sample, nil
+	})
+	handle := func(topic string, ev interface{}, ts time.Time) {
+		envelope := buildEnvelope(t, topic, ev, ts)
+		envelope.Namespace = "k8s.io"
+		require.NoError(t, tracker.HandleEnvelope(envelope))
+		tracker.pending.Wait()
+	}
+	now := time.Now()
+
+	// The start of the tasks running before is not known
+	handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "redis", ID: "redis"}, now)
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "job", Pid: 42}, now)
+	// The exec'd processes are ignored
+	handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "job", ID: "exec-1"}, now.Add(time.Second))
+	assert.Empty(t, tracker.Flush())
+
+	handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "job", ID: "job", ExitStatus: 1}, now.Add(3*time.Second))
+	lifetimes := tracker.Flush()
+	require.Len(t, lifetimes, 1)
+	assert.Equal(t, TaskLifetime{
+		Namespace:   "k8s.io",
+		ContainerID: "job",
+		Tags:        []string{"kube_job:backup"},
+		StartedAt:   now,
+		ExitedAt:    now.Add(3 * time.Second),
+		ExitStatus:  1,
+		Samples:     2,
+		CPUTotal:    5000,
+		MaxMemory:   4096,
+		ReadBytes:   512,
+		WriteBytes:  192,
+	}, lifetimes[0])
+	assert.Equal(t, 3*time.Second, lifetimes[0].Duration())
+	assert.Empty(t, tracker.Flush())
+
+	// The tasks whose cgroup is gone are reported without usage
+	handle(TaskStartTopic, &apievents.TaskStart{ContainerID: "job", Pid: 43}, now)
+	handle(TaskExitTopic, &apievents.TaskExit{ContainerID: "job", ID: "job"}, now.Add(time.Second))
+	lifetimes = tracker.Flush()
+	require.Len(t, lifetimes, 1)
+	assert.Equal(t, 0, lifetimes[0].Samples)
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// TaskOutput is the output of a task captured by CaptureTaskOutput
+type TaskOutput struct {
+	// Output holds what the task wrote to its stdout and stderr while it
+	// was captured, up to the limit of the capture
+	Output string
+	// Truncated is set if some output was discarded past the limit
+	Truncated bool
+}
+
+// CaptureTaskOutput captures up to limit bytes of what the task of a
+// container writes to its stdout and stderr from now on, until ctx is done,
+// the limit is reached or the task exits. The output of the containers of
+// the CRI plugin is read from their log file, as the plugin reads their
+// FIFOs. The output of the other containers is read by attaching to the
+// FIFOs of their task in /run/containerd/fifo, the agent must share this
+// directory with the host, and the task must have been created with FIFOs.
+func (c *ContainerdUtil) CaptureTaskOutput(ctx context.Context, ctn containerd.Container, limit int) (*TaskOutput, error) {
+	if limit <= 0 {
+		return nil, errors.New("the limit of the capture must be positive")
+	}
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(ctx, c.namespace))
+	defer cancel()
+	capture := newOutputCapture(limit)
+
+	if path, err := ContainerLogPath(c, ctn.ID()); err == nil {
+		go func() {
+			select {
+			case <-capture.full:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if err := TailLog(ctx, path, 0, true, capture); err != nil {
+			return nil, err
+		}
+		return capture.result(), nil
+	}
+
+	task, err := ctn.Task(ctx, cio.NewAttach(cio.WithStreams(nil, capture, capture)))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer func() {
+		// Closing the FIFOs on our side leaves the task running
+		task.IO().Cancel()
+		task.IO().Close()
+	}()
+	exitCh, err := task.Wait(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	select {
+	case <-exitCh:
+		// The outputs are copied until the FIFOs are closed
+		task.IO().Wait()
+	case <-capture.full:
+	case <-ctx.Done():
+	}
+	return capture.result(), nil
+}
+
+// outputCapture keeps the first limit bytes written to it, the rest is
+// discarded. full is closed once the limit is reached.
+type outputCapture struct {
+	mu        sync.Mutex
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+	full      chan struct{}
+}
+
+func newOutputCapture(limit int) *outputCapture {
+	return &outputCapture{limit: limit, full: make(chan struct{})}
+}
+
+// Write implements io.Writer
+func (o *outputCapture) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	room := o.limit - o.buf.Len()
+	if room == 0 {
+		o.truncated = o.truncated || len(p) > 0
+		return len(p), nil
+	}
+	if len(p) > room {
+		o.buf.Write(p[:room])
+		o.truncated = true
+	} else {
+		o.buf.Write(p)
+	}
+	if o.buf.Len() == o.limit {
+		close(o.full)
+	}
+	return len(p), nil
+}
+
+func (o *outputCapture) result() *TaskOutput {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &TaskOutput{Output: o.buf.String(), Truncated: o.truncated}
+}
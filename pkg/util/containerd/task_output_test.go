@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputCapture(t *testing.T) {
+	capture := newOutputCapture(8)
+	n, err := capture.Write([]byte("ready\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, &TaskOutput{Output: "ready\n"}, capture.result())
+	select {
+	case <-capture.full:
+		assert.Fail(t, "the capture is not full")
+	default:
+	}
+
+	// The output past the limit is discarded, the writes succeed
+	n, err = capture.Write([]byte("serving\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	_, err = capture.Write([]byte("error\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, &TaskOutput{Output: "ready\nse", Truncated: true}, capture.result())
+	select {
+	case <-capture.full:
+	default:
+		assert.Fail(t, "the capture is full")
+	}
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/namespaces"
+)
+
+// TaskProcess is a process running in the task of a container, the
+// equivalent of a docker top entry. PPID allows rebuilding the process
+// tree of the container.
+type TaskProcess struct {
+	PID     uint32
+	PPID    uint32
+	Name    string
+	Command string
+}
+
+// TaskProcesses returns the processes running in the task of a container,
+// sorted by PID. Their name and command are read from the procfs of the
+// host, and left empty if it is not readable, like on Windows.
+func (c *ContainerdUtil) TaskProcesses(ctx context.Context, ctn containerd.Container) ([]TaskProcess, error) {
+	ctx, cancel := context.WithTimeout(namespaces.WithNamespace(ctx, c.namespace), c.queryTimeout)
+	defer cancel()
+	t, err := ctn.Task(ctx, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	pids, err := t.Pids(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	procs := make([]TaskProcess, 0, len(pids))
+	for _, p := range pids {
+		proc, err := readTaskProcess(c.procRoot, p.Pid)
+		if err != nil {
+			c.containerLog(ctn.ID()).Debugf("Cannot read process %d: %s", p.Pid, err)
+		}
+		procs = append(procs, proc)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// readTaskProcess reads the parent, the name and the command line of a
+// process from procfs, only the PID is set if they cannot be read
+func readTaskProcess(procRoot string, pid uint32) (TaskProcess, error) {
+	proc := TaskProcess{PID: pid}
+	dir := filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10))
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return proc, err
+	}
+	// The name is between parentheses and can contain spaces or parentheses:
+	//   1234 (redis-server) S 1200 ...
+	start, end := strings.IndexByte(string(stat), '('), strings.LastIndexByte(string(stat), ')')
+	if start < 0 || end < start {
+		return proc, fmt.Errorf("unexpected stat format")
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 2 {
+		return proc, fmt.Errorf("unexpected stat format")
+	}
+	ppid, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return proc, fmt.Errorf("unexpected parent pid %q", fields[1])
+	}
+	proc.PPID = uint32(ppid)
+	proc.Name = string(stat[start+1 : end])
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return proc, err
+	}
+	// Kernel threads have an empty command line
+	proc.Command = strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1))
+	if proc.Command == "" {
+		proc.Command = "[" + proc.Name + "]"
+	}
+	return proc, nil
+}
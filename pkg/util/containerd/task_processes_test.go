@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTaskProcess(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "containerd-proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+
+	writeProc := func(pid, stat, cmdline string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(procRoot, pid), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, pid, "stat"), []byte(stat), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, pid, "cmdline"), []byte(cmdline), 0644))
+	}
+	writeProc("42", "42 (redis-server) S 1 42 42 0 -1 4194560 1526 0 0 0\n", "redis-server\x00*:6379\x00")
+	writeProc("43", "43 (weird) name)) S 42 42 42 0 -1\n", "")
+	writeProc("44", "44 (broken\n", "")
+
+	proc, err := readTaskProcess(procRoot, 42)
+	require.NoError(t, err)
+	assert.Equal(t, TaskProcess{PID: 42, PPID: 1, Name: "redis-server", Command: "redis-server *:6379"}, proc)
+
+	proc, err = readTaskProcess(procRoot, 43)
+	require.NoError(t, err)
+	assert.Equal(t, TaskProcess{PID: 43, PPID: 42, Name: "weird) name)", Command: "[weird) name)]"}, proc)
+
+	proc, err = readTaskProcess(procRoot, 44)
+	assert.Error(t, err)
+	assert.Equal(t, TaskProcess{PID: 44}, proc)
+
+	// Exited since the task was listed
+	proc, err = readTaskProcess(procRoot, 45)
+	assert.Error(t, err)
+	assert.Equal(t, TaskProcess{PID: 45}, proc)
+}
@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	v1 "github.com/containerd/cgroups/v3/cgroup1/stats"
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/typeurl/v2"
+)
+
+// Cgroup versions of the task metrics
+const (
+	CgroupV1Stats = "v1"
+	CgroupV2Stats = "v2"
+)
+
+// TaskStats are the task metrics of a container, decoded from the cgroup
+// v1 or v2 stats reported by containerd. The durations are in nanoseconds
+// and the sizes in bytes, a zero limit means unlimited.
+type TaskStats struct {
+	CgroupVersion string
+
+	CPUTotal            uint64
+	CPUUser             uint64
+	CPUSystem           uint64
+	CPUThrottledPeriods uint64
+	CPUThrottledTime    uint64
+
+	MemoryUsage uint64
+	MemoryLimit uint64
+	// MemoryMaxUsage is the peak of the memory usage since the task
+	// started, zero if the kernel does not report it
+	MemoryMaxUsage uint64
+	// MemoryWorkingSet is the usage without the inactive page cache, the
+	// memory the kernel cannot reclaim under pressure, as computed by the
+	// kubelet for the evictions
+	MemoryWorkingSet uint64
+	// MemoryRSS is the anonymous memory, MemoryCache the page cache
+	MemoryRSS   uint64
+	MemoryCache uint64
+	// KernelMemory is the kernel stacks, slab and socket buffers charged
+	// to the cgroup
+	KernelMemory uint64
+	SwapUsage    uint64
+	// OOMKills is only reported by cgroup v2
+	OOMKills uint64
+
+	Pids      uint64
+	PidsLimit uint64
+
+	// Devices are the block devices read or written by the task, sorted
+	Devices []DeviceIO
+}
+
+// DeviceIO is the I/O of a task on a block device, cumulated since the
+// task started
+type DeviceIO struct {
+	Major uint64
+	Minor uint64
+	// Name is the name of the device, eg. sda. It is only reported by
+	// cgroup v1, see DiskDevices for cgroup v2.
+	Name string
+
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+}
+
+// ID returns the major:minor number of the device
+func (d DeviceIO) ID() string {
+	return fmt.Sprintf("%d:%d", d.Major, d.Minor)
+}
+
+// DecodeTaskMetrics decodes the raw task metrics returned by TaskMetrics
+// and CollectAll
+func DecodeTaskMetrics(metric *types.Metric) (*TaskStats, error) {
+	if metric == nil || metric.Data == nil {
+		return nil, fmt.Errorf("no task metrics")
+	}
+	data, err := typeurl.UnmarshalAny(metric.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode the task metrics: %s", err)
+	}
+	switch m := data.(type) {
+	case *v1.Metrics:
+		return statsFromV1(m), nil
+	case *v2.Metrics:
+		return statsFromV2(m), nil
+	default:
+		return nil, fmt.Errorf("unexpected task metrics type %T", data)
+	}
+}
+
+func statsFromV1(m *v1.Metrics) *TaskStats {
+	usage := m.GetCPU().GetUsage()
+	throttling := m.GetCPU().GetThrottling()
+	memory := m.GetMemory()
+	stats := &TaskStats{
+		CgroupVersion:       CgroupV1Stats,
+		CPUTotal:            usage.GetTotal(),
+		CPUUser:             usage.GetUser(),
+		CPUSystem:           usage.GetKernel(),
+		CPUThrottledPeriods: throttling.GetThrottledPeriods(),
+		CPUThrottledTime:    throttling.GetThrottledTime(),
+		MemoryUsage:         memory.GetUsage().GetUsage(),
+		MemoryLimit:         limit(memory.GetUsage().GetLimit()),
+		MemoryMaxUsage:      memory.GetUsage().GetMax(),
+		Pids:                m.GetPids().GetCurrent(),
+		PidsLimit:           m.GetPids().GetLimit(),
+	}
+	// The total_* entries account for the sub-cgroups of the task
+	stats.MemoryRSS = memory.GetTotalRSS()
+	stats.MemoryCache = memory.GetTotalCache()
+	stats.MemoryWorkingSet = workingSet(stats.MemoryUsage, memory.GetTotalInactiveFile())
+	stats.KernelMemory = memory.GetKernel().GetUsage()
+	// The swap entry of v1 accounts for the memory and the swap
+	if memsw := memory.GetSwap().GetUsage(); memsw > stats.MemoryUsage {
+		stats.SwapUsage = memsw - stats.MemoryUsage
+	}
+	stats.Devices = devicesFromV1(m.GetBlkio())
+	return stats
+}
+
+// devicesFromV1 sums the read and write entries of the blkio stats per
+// device, the other operations, eg. Sync or Total, overlap them
+func devicesFromV1(blkio *v1.BlkIOStat) []DeviceIO {
+	devices := make(map[string]*DeviceIO)
+	device := func(e *v1.BlkIOEntry) *DeviceIO {
+		key := fmt.Sprintf("%d:%d", e.GetMajor(), e.GetMinor())
+		d, found := devices[key]
+		if !found {
+			d = &DeviceIO{Major: e.GetMajor(), Minor: e.GetMinor(), Name: e.GetDevice()}
+			devices[key] = d
+		}
+		return d
+	}
+	for _, e := range blkio.GetIoServiceBytesRecursive() {
+		switch strings.ToLower(e.GetOp()) {
+		case "read":
+			device(e).ReadBytes += e.GetValue()
+		case "write":
+			device(e).WriteBytes += e.GetValue()
+		}
+	}
+	for _, e := range blkio.GetIoServicedRecursive() {
+		switch strings.ToLower(e.GetOp()) {
+		case "read":
+			device(e).ReadOps += e.GetValue()
+		case "write":
+			device(e).WriteOps += e.GetValue()
+		}
+	}
+
+	var result []DeviceIO
+	for _, d := range devices {
+		result = append(result, *d)
+	}
+	sortDevices(result)
+	return result
+}
+
+func statsFromV2(m *v2.Metrics) *TaskStats {
+	cpu := m.GetCPU()
+	memory := m.GetMemory()
+	return &TaskStats{
+		CgroupVersion:       CgroupV2Stats,
+		CPUTotal:            cpu.GetUsageUsec() * 1000,
+		CPUUser:             cpu.GetUserUsec() * 1000,
+		CPUSystem:           cpu.GetSystemUsec() * 1000,
+		CPUThrottledPeriods: cpu.GetNrThrottled(),
+		CPUThrottledTime:    cpu.GetThrottledUsec() * 1000,
+		MemoryUsage:         memory.GetUsage(),
+		MemoryLimit:         limit(memory.GetUsageLimit()),
+		MemoryMaxUsage:      memory.GetMaxUsage(),
+		MemoryWorkingSet:    workingSet(memory.GetUsage(), memory.GetInactiveFile()),
+		MemoryRSS:           memory.GetAnon(),
+		MemoryCache:         memory.GetFile(),
+		KernelMemory:        memory.GetKernelStack() + memory.GetSlab() + memory.GetSock(),
+		SwapUsage:           memory.GetSwapUsage(),
+		OOMKills:            m.GetMemoryEvents().GetOomKill(),
+		Pids:                m.GetPids().GetCurrent(),
+		PidsLimit:           m.GetPids().GetLimit(),
+		Devices:             devicesFromV2(m.GetIo()),
+	}
+}
+
+func devicesFromV2(io *v2.IOStat) []DeviceIO {
+	var devices []DeviceIO
+	for _, e := range io.GetUsage() {
+		devices = append(devices, DeviceIO{
+			Major:      e.GetMajor(),
+			Minor:      e.GetMinor(),
+			ReadBytes:  e.GetRbytes(),
+			WriteBytes: e.GetWbytes(),
+			ReadOps:    e.GetRios(),
+			WriteOps:   e.GetWios(),
+		})
+	}
+	sortDevices(devices)
+	return devices
+}
+
+func sortDevices(devices []DeviceIO) {
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Major != devices[j].Major {
+			return devices[i].Major < devices[j].Major
+		}
+		return devices[i].Minor < devices[j].Minor
+	})
+}
+
+// workingSet returns the memory usage without the inactive page cache, the
+// inactive cache can exceed the usage as they are not read atomically
+func workingSet(usage, inactiveFile uint64) uint64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return usage - inactiveFile
+}
+
+// limit returns 0 for the limits set to max, reported as the maximum value
+// of the type by cgroup v2, or as the page counter maximum by cgroup v1
+func limit(value uint64) uint64 {
+	if value >= math.MaxInt64-4095 {
+		return 0
+	}
+	return value
+}
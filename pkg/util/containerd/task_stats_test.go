@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/containerd/cgroups/v3/cgroup1/stats"
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	"github.com/containerd/containerd/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsFromV1(t *testing.T) {
+	stats := statsFromV1(&v1.Metrics{
+		Pids: &v1.PidsStat{Current: 12, Limit: 100},
+		CPU: &v1.CPUStat{
+			Usage:      &v1.CPUUsage{Total: 3000, Kernel: 1000, User: 2000},
+			Throttling: &v1.Throttle{ThrottledPeriods: 4, ThrottledTime: 500},
+		},
+		Memory: &v1.MemoryStat{
+			Usage:             &v1.MemoryEntry{Usage: 1024, Limit: math.MaxInt64 - 4095, Max: 1280},
+			Swap:              &v1.MemoryEntry{Usage: 1536},
+			Kernel:            &v1.MemoryEntry{Usage: 64},
+			RSS:               256,
+			TotalRSS:          512,
+			TotalCache:        448,
+			TotalInactiveFile: 384,
+		},
+		Blkio: &v1.BlkIOStat{
+			IoServiceBytesRecursive: []*v1.BlkIOEntry{
+				{Op: "Read", Device: "sdb", Major: 8, Minor: 16, Value: 2048},
+				{Op: "Read", Device: "sda", Major: 8, Minor: 0, Value: 4096},
+				{Op: "Write", Device: "sda", Major: 8, Minor: 0, Value: 8192},
+				{Op: "Total", Device: "sda", Major: 8, Minor: 0, Value: 12288},
+			},
+			IoServicedRecursive: []*v1.BlkIOEntry{
+				{Op: "Read", Device: "sda", Major: 8, Minor: 0, Value: 1},
+				{Op: "Write", Device: "sda", Major: 8, Minor: 0, Value: 2},
+				{Op: "Sync", Device: "sda", Major: 8, Minor: 0, Value: 3},
+			},
+		},
+	})
+	assert.Equal(t, &TaskStats{
+		CgroupVersion:       CgroupV1Stats,
+		CPUTotal:            3000,
+		CPUUser:             2000,
+		CPUSystem:           1000,
+		CPUThrottledPeriods: 4,
+		CPUThrottledTime:    500,
+		MemoryUsage:         1024,
+		MemoryMaxUsage:      1280,
+		MemoryWorkingSet:    640,
+		MemoryRSS:           512,
+		MemoryCache:         448,
+		KernelMemory:        64,
+		SwapUsage:           512,
+		Pids:                12,
+		PidsLimit:           100,
+		Devices: []DeviceIO{
+			{Major: 8, Minor: 0, Name: "sda", ReadBytes: 4096, WriteBytes: 8192, ReadOps: 1, WriteOps: 2},
+			{Major: 8, Minor: 16, Name: "sdb", ReadBytes: 2048},
+		},
+	}, stats)
+}
+
+func TestStatsFromV2(t *testing.T) {
+	stats := statsFromV2(&v2.Metrics{
+		Pids: &v2.PidsStat{Current: 3},
+		CPU: &v2.CPUStat{
+			UsageUsec:     30,
+			UserUsec:      20,
+			SystemUsec:    10,
+			NrThrottled:   2,
+			ThrottledUsec: 5,
+		},
+		Memory: &v2.MemoryStat{
+			Usage:        2048,
+			UsageLimit:   4096,
+			MaxUsage:     3072,
+			SwapUsage:    128,
+			Anon:         1024,
+			File:         768,
+			InactiveFile: 512,
+			KernelStack:  32,
+			Slab:         64,
+			Sock:         16,
+		},
+		MemoryEvents: &v2.MemoryEvents{OomKill: 1},
+		Io: &v2.IOStat{Usage: []*v2.IOEntry{
+			{Major: 259, Minor: 0, Rbytes: 4096, Wbytes: 8192, Rios: 1, Wios: 2},
+			{Major: 8, Minor: 0, Rbytes: 512, Rios: 1},
+		}},
+	})
+	assert.Equal(t, &TaskStats{
+		CgroupVersion:       CgroupV2Stats,
+		CPUTotal:            30000,
+		CPUUser:             20000,
+		CPUSystem:           10000,
+		CPUThrottledPeriods: 2,
+		CPUThrottledTime:    5000,
+		MemoryUsage:         2048,
+		MemoryLimit:         4096,
+		MemoryMaxUsage:      3072,
+		MemoryWorkingSet:    1536,
+		MemoryRSS:           1024,
+		MemoryCache:         768,
+		KernelMemory:        112,
+		SwapUsage:           128,
+		OOMKills:            1,
+		Pids:                3,
+		Devices: []DeviceIO{
+			{Major: 8, Minor: 0, ReadBytes: 512, ReadOps: 1},
+			{Major: 259, Minor: 0, ReadBytes: 4096, WriteBytes: 8192, ReadOps: 1, WriteOps: 2},
+		},
+	}, stats)
+
+	// Partial stats, like the ones of a task exiting, do not panic
+	assert.Equal(t, &TaskStats{CgroupVersion: CgroupV2Stats}, statsFromV2(&v2.Metrics{}))
+}
+
+func TestDecodeTaskMetricsInvalid(t *testing.T) {
+	_, err := DecodeTaskMetrics(nil)
+	assert.Error(t, err)
+	_, err = DecodeTaskMetrics(&types.Metric{ID: "redis"})
+	assert.Error(t, err)
+}
+
+func TestDiskDevices(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+
+	diskstats := `   8       0 sda 24398 2788 1317975 40488 25201 46267 1584744 142336 0 22352 182660
+   8       1 sda1 24232 2788 1312025 40376 25201 46267 1584744 142336 0 22320 182552
+ 259       0 nvme0n1 189 0 4063 220 0 0 0 0 0 112 204
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "diskstats"), []byte(diskstats), 0644))
+
+	devices, err := DiskDevices(procRoot)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"8:0": "sda", "8:1": "sda1", "259:0": "nvme0n1"}, devices)
+	assert.Equal(t, "nvme0n1", devices[DeviceIO{Major: 259}.ID()])
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// TelemetryPath is the path of the OpenMetrics exposition of the utils, on
+// the expvar server of the agent next to /debug/vars, for the Prometheus
+// servers scraping the telemetry of the agents to watch their connectivity
+// to containerd
+const TelemetryPath = "/telemetry/containerd"
+
+// telemetryPrefix prefixes the names of the telemetry metrics
+const telemetryPrefix = "datadog_agent_containerd_"
+
+func init() {
+	http.HandleFunc(TelemetryPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeTelemetry(w, UtilStates(), rateLimitedCalls.Value())
+	})
+}
+
+// writeTelemetry writes the state of the utils in the Prometheus text
+// exposition format. The metrics of a util are labelled by socket path and
+// namespace, the calls by gRPC method and status code too.
+func writeTelemetry(w io.Writer, states []UtilState, rateLimited int64) {
+	writeFamily(w, "connected", "gauge", "Whether the util is connected and the daemon serving.", states, func(s UtilState, emit func(map[string]string, float64)) {
+		var connected float64
+		if s.Connected {
+			connected = 1
+		}
+		emit(nil, connected)
+	})
+	writeFamily(w, "calls_total", "counter", "Number of gRPC calls made to containerd, by method and status code.", states, func(s UtilState, emit func(map[string]string, float64)) {
+		methods := make([]string, 0, len(s.Calls))
+		for method := range s.Calls {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			codes := make([]string, 0, len(s.Calls[method]))
+			for code := range s.Calls[method] {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			for _, code := range codes {
+				emit(map[string]string{"method": method, "code": code}, float64(s.Calls[method][code]))
+			}
+		}
+	})
+	writeFamily(w, "event_lag_seconds", "gauge", "Time the last event of the container cache took to reach the agent.", states, func(s UtilState, emit func(map[string]string, float64)) {
+		if !s.LastEvent.IsZero() {
+			emit(nil, s.EventLag.Seconds())
+		}
+	})
+	writeFamily(w, "last_event_timestamp_seconds", "gauge", "Reception time of the last event of the container cache.", states, func(s UtilState, emit func(map[string]string, float64)) {
+		if !s.LastEvent.IsZero() {
+			emit(nil, float64(s.LastEvent.UnixNano())/1e9)
+		}
+	})
+	writeFamily(w, "cached_containers", "gauge", "Number of containers in the metadata cache.", states, func(s UtilState, emit func(map[string]string, float64)) {
+		if s.CachedContainers >= 0 {
+			emit(nil, float64(s.CachedContainers))
+		}
+	})
+	name := telemetryPrefix + "rate_limited_calls_total"
+	fmt.Fprintf(w, "# HELP %s Number of calls delayed or failed by the rate limiter of the utils.\n", name)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, rateLimited)
+}
+
+// writeFamily writes a metric family with the samples emitted for every
+// util, the family is left out if no sample is emitted
+func writeFamily(w io.Writer, name, kind, help string, states []UtilState, samples func(UtilState, func(map[string]string, float64))) {
+	name = telemetryPrefix + name
+	var lines []string
+	for _, s := range states {
+		samples(s, func(labels map[string]string, value float64) {
+			pairs := []string{
+				fmt.Sprintf(`socket_path="%s"`, escapeTelemetryLabel(s.SocketPath)),
+				fmt.Sprintf(`namespace="%s"`, escapeTelemetryLabel(s.Namespace)),
+			}
+			keys := make([]string, 0, len(labels))
+			for key := range labels {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escapeTelemetryLabel(labels[key])))
+			}
+			lines = append(lines, fmt.Sprintf("%s{%s} %v", name, strings.Join(pairs, ","), value))
+		})
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+var telemetryLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeTelemetryLabel(v string) string {
+	return telemetryLabelEscaper.Replace(v)
+}
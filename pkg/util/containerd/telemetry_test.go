@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTelemetry(t *testing.T) {
+	states := []UtilState{
+		{
+			SocketPath:       "/run/containerd/containerd.sock",
+			Namespace:        "k8s.io",
+			Connected:        true,
+			CachedContainers: 12,
+			LastEvent:        time.Unix(1600000000, 500000000),
+			EventLag:         1500 * time.Millisecond,
+			Calls: map[string]map[string]uint64{
+				"/containerd.services.tasks.v1.Tasks/Metrics":     {"OK": 40, "DeadlineExceeded": 2},
+				"/containerd.services.version.v1.Version/Version": {"OK": 1},
+			},
+		},
+		{
+			SocketPath:       "/run/containerd/containerd.sock",
+			Namespace:        "moby",
+			CachedContainers: -1,
+		},
+	}
+	var b bytes.Buffer
+	writeTelemetry(&b, states, 3)
+	assert.Equal(t, `# HELP datadog_agent_containerd_connected Whether the util is connected and the daemon serving.
+# TYPE datadog_agent_containerd_connected gauge
+datadog_agent_containerd_connected{socket_path="/run/containerd/containerd.sock",namespace="k8s.io"} 1
+datadog_agent_containerd_connected{socket_path="/run/containerd/containerd.sock",namespace="moby"} 0
+# HELP datadog_agent_containerd_calls_total Number of gRPC calls made to containerd, by method and status code.
+# TYPE datadog_agent_containerd_calls_total counter
+datadog_agent_containerd_calls_total{socket_path="/run/containerd/containerd.sock",namespace="k8s.io",code="DeadlineExceeded",method="/containerd.services.tasks.v1.Tasks/Metrics"} 2
+datadog_agent_containerd_calls_total{socket_path="/run/containerd/containerd.sock",namespace="k8s.io",code="OK",method="/containerd.services.tasks.v1.Tasks/Metrics"} 40
+datadog_agent_containerd_calls_total{socket_path="/run/containerd/containerd.sock",namespace="k8s.io",code="OK",method="/containerd.services.version.v1.Version/Version"} 1
+# HELP datadog_agent_containerd_event_lag_seconds Time the last event of the container cache took to reach the agent.
+# TYPE datadog_agent_containerd_event_lag_seconds gauge
+datadog_agent_containerd_event_lag_seconds{socket_path="/run/containerd/containerd.sock",namespace="k8s.io"} 1.5
+# HELP datadog_agent_containerd_last_event_timestamp_seconds Reception time of the last event of the container cache.
+# TYPE datadog_agent_containerd_last_event_timestamp_seconds gauge
+datadog_agent_containerd_last_event_timestamp_seconds{socket_path="/run/containerd/containerd.sock",namespace="k8s.io"} 1.6000000005e+09
+# HELP datadog_agent_containerd_cached_containers Number of containers in the metadata cache.
+# TYPE datadog_agent_containerd_cached_containers gauge
+datadog_agent_containerd_cached_containers{socket_path="/run/containerd/containerd.sock",namespace="k8s.io"} 12
+# HELP datadog_agent_containerd_rate_limited_calls_total Number of calls delayed or failed by the rate limiter of the utils.
+# TYPE datadog_agent_containerd_rate_limited_calls_total counter
+datadog_agent_containerd_rate_limited_calls_total 3
+`, b.String())
+}
+
+func TestTelemetryHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest("GET", TelemetryPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "datadog_agent_containerd_rate_limited_calls_total ")
+}
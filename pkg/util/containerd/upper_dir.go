@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/namespaces"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxConcurrentDiskUsage is the number of writable layers measured at
+	// the same time by every util
+	maxConcurrentDiskUsage = 2
+	// diskUsageFilesPerSecond bounds the rate of the files stat'ed by the
+	// measures, to keep the load on the disks of the node low
+	diskUsageFilesPerSecond = 5000
+	diskUsageBurst          = 500
+)
+
+// The measures of every util share the same slots and limiter, as they
+// read the same disks
+var (
+	diskUsageSlots   = make(chan struct{}, maxConcurrentDiskUsage)
+	diskUsageLimiter = rate.NewLimiter(diskUsageFilesPerSecond, diskUsageBurst)
+)
+
+// fileDiskUsage returns the bytes used on disk by a file, the links of
+// a file already counted in seen are not counted again. It is the
+// apparent size of the file outside of Linux.
+var fileDiskUsage = func(info os.FileInfo, seen map[uint64]struct{}) int64 {
+	return info.Size()
+}
+
+// UpperDirSize returns the bytes used on disk by the writable layer of a
+// container, the files it wrote outside of its volumes, to find the
+// containers filling the disk of the node. The layer is the upper
+// directory of the active snapshot of the container, resolved under the
+// root of the host through the proc root of the util. The layer is walked
+// like du does, the walk is bounded by ctx, and the files are stat'ed at
+// a bounded rate, the concurrent measures waiting for their turn. The
+// containers without snapshot, and the snapshotters not mounting a
+// directory of the host, like devmapper, are ErrUnsupported.
+func (c *ContainerdUtil) UpperDirSize(ctx context.Context, ctn containerd.Container) (int64, error) {
+	qctx, cancel := context.WithTimeout(namespaces.WithNamespace(ctx, c.namespace), c.queryTimeout)
+	defer cancel()
+	info, err := ctn.Info(qctx, containerd.WithoutRefreshedMetadata)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	if info.Snapshotter == "" || info.SnapshotKey == "" {
+		return 0, &Error{Kind: ErrUnsupported, Err: fmt.Errorf("container %s has no snapshot", ctn.ID())}
+	}
+	mounts, err := c.client().SnapshotService(info.Snapshotter).Mounts(qctx, info.SnapshotKey)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	dir, err := upperDirOfMounts(mounts)
+	if err != nil {
+		return 0, &Error{Kind: ErrUnsupported, Err: fmt.Errorf("snapshotter %s: %s", info.Snapshotter, err)}
+	}
+	return measureDiskUsage(ctx, c.hostPath(dir))
+}
+
+// upperDirOfMounts returns the directory holding the writable layer of the
+// mounts of an active snapshot: the upper directory of an overlay, or the
+// source of a bind mount, like the snapshots of the native snapshotter
+func upperDirOfMounts(mounts []mount.Mount) (string, error) {
+	if len(mounts) != 1 {
+		return "", fmt.Errorf("%d mounts, expected one", len(mounts))
+	}
+	m := mounts[0]
+	switch m.Type {
+	case "overlay":
+		for _, option := range m.Options {
+			if strings.HasPrefix(option, "upperdir=") {
+				return strings.TrimPrefix(option, "upperdir="), nil
+			}
+		}
+		// The snapshots of the committed layers are mounted read-only
+		return "", fmt.Errorf("the overlay has no upper directory")
+	case "bind":
+		return m.Source, nil
+	}
+	return "", fmt.Errorf("cannot measure the mounts of type %s", m.Type)
+}
+
+// hostPath returns the path of a directory of the host, seen through the
+// root of the init process, so that it resolves from the container of the
+// agent too
+func (c *ContainerdUtil) hostPath(path string) string {
+	return filepath.Join(c.procRoot, "1", "root", path)
+}
+
+// measureDiskUsage returns the bytes used on disk by the files of dir, it
+// waits for a free slot, then for the limiter before each file
+func measureDiskUsage(ctx context.Context, dir string) (int64, error) {
+	select {
+	case diskUsageSlots <- struct{}{}:
+		defer func() { <-diskUsageSlots }()
+	case <-ctx.Done():
+		return 0, &Error{Kind: ErrTimeout, Err: fmt.Errorf("waiting to measure %s: %s", dir, ctx.Err())}
+	}
+
+	var total int64
+	seen := make(map[uint64]struct{})
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The files removed during the walk are not counted
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if err := diskUsageLimiter.Wait(ctx); err != nil {
+			return &Error{Kind: ErrTimeout, Err: fmt.Errorf("measuring %s: %s", dir, err)}
+		}
+		total += fileDiskUsage(info, seen)
+		return nil
+	})
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	return total, nil
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"os"
+	"syscall"
+)
+
+func init() {
+	fileDiskUsage = func(info os.FileInfo, seen map[uint64]struct{}) int64 {
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return info.Size()
+		}
+		// The hard links of a file are counted once, like du does
+		if stat.Nlink > 1 && !info.IsDir() {
+			if _, found := seen[stat.Ino]; found {
+				return 0
+			}
+			seen[stat.Ino] = struct{}{}
+		}
+		// The blocks of stat are 512 bytes long whatever the filesystem
+		return stat.Blocks * 512
+	}
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpperDirOfMounts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mounts   []mount.Mount
+		expected string
+	}{
+		"overlay": {
+			mounts: []mount.Mount{{Type: "overlay", Source: "overlay", Options: []string{
+				"index=off",
+				"workdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/42/work",
+				"upperdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/42/fs",
+				"lowerdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/41/fs",
+			}}},
+			expected: "/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/42/fs",
+		},
+		"native": {
+			mounts:   []mount.Mount{{Type: "bind", Source: "/var/lib/containerd/io.containerd.snapshotter.v1.native/snapshots/7", Options: []string{"rbind", "rw"}}},
+			expected: "/var/lib/containerd/io.containerd.snapshotter.v1.native/snapshots/7",
+		},
+		"read-only overlay": {
+			mounts: []mount.Mount{{Type: "overlay", Options: []string{"lowerdir=/a:/b"}}},
+		},
+		"devmapper": {
+			mounts: []mount.Mount{{Type: "ext4", Source: "/dev/mapper/containerd-pool-snap-42"}},
+		},
+		"no mount": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, err := upperDirOfMounts(tc.mounts)
+			if tc.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, dir)
+		})
+	}
+}
+
+func TestMeasureDiskUsage(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	c := newContainerdUtil(Options{ProcRoot: procRoot}.withDefaults())
+
+	upper := "/var/lib/containerd/snapshots/42/fs"
+	dir := c.hostPath(upper)
+	assert.Equal(t, filepath.Join(procRoot, "1", "root", upper), dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tmp"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tmp", "dump"), make([]byte, 64*1024), 0644))
+	require.NoError(t, os.Link(filepath.Join(dir, "tmp", "dump"), filepath.Join(dir, "dump")))
+
+	size, err := measureDiskUsage(context.Background(), dir)
+	require.NoError(t, err)
+	// The link of the dump is counted once, along with the directories
+	assert.True(t, size >= 64*1024, "size %d", size)
+	assert.True(t, size < 2*64*1024, "size %d", size)
+
+	_, err = measureDiskUsage(context.Background(), c.hostPath("/gone"))
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = measureDiskUsage(ctx, dir)
+	assert.Equal(t, ErrTimeout, ErrorKind(err))
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd
+
+package containerd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runtimeV2TaskDir holds the bundles of the tasks run by the v2 shims, by
+// namespace and container ID, in the state directory of the daemon
+const runtimeV2TaskDir = "io.containerd.runtime.v2.task"
+
+// IsVMRuntime returns whether a runtime handler, see RuntimeHandler, runs
+// the containers in virtual machines, like the kata handlers: kata,
+// kata-qemu, kata-clh or kata-fc
+func IsVMRuntime(handler string) bool {
+	return handler == "kata" || strings.HasPrefix(handler, "kata-")
+}
+
+// TaskVMStats is the host usage of the virtual machine running a task,
+// which the task metrics of the VM-isolated runtimes do not account for, as
+// they are read from the cgroups of the guest
+type TaskVMStats struct {
+	// VMMemoryRSS is the resident memory of the hypervisor process
+	VMMemoryRSS uint64
+	// ShimRSS is the resident memory of the shim, it is zero if the shim
+	// process was not found
+	ShimRSS uint64
+}
+
+// TaskVMStats returns the host usage of the virtual machine of a task. The
+// kata shims report the hypervisor process as the pid of the tasks. The
+// shim is found from the shim.pid file of the bundle of the sandbox, under
+// the state directory of the daemon, eg. /run/containerd, which the agent
+// must share with the host, or
+// else from the parent of the hypervisor process if the hypervisor does not
+// daemonize. The VM and the shim are shared by the containers of a pod.
+func (c *ContainerdUtil) TaskVMStats(id string, pid uint32) (*TaskVMStats, error) {
+	hypervisor, err := readProcessStatus(c.procRoot, pid)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TaskVMStats{VMMemoryRSS: hypervisor.rss}
+
+	shimPid, err := readShimPid(filepath.Join(c.stateDir, runtimeV2TaskDir, c.namespace, id, "shim.pid"))
+	if err != nil {
+		shimPid = hypervisor.ppid
+	}
+	if shim, err := readProcessStatus(c.procRoot, shimPid); err == nil && strings.HasPrefix(shim.name, "containerd-shim") {
+		stats.ShimRSS = shim.rss
+	}
+	return stats, nil
+}
+
+// processStatus holds the fields of /proc/<pid>/status read by TaskVMStats
+type processStatus struct {
+	name string
+	ppid uint32
+	// rss is in bytes
+	rss uint64
+}
+
+func readProcessStatus(procRoot string, pid uint32) (*processStatus, error) {
+	f, err := os.Open(filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), "status"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	status := &processStatus{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Name:":
+			status.name = fields[1]
+		case "PPid:":
+			ppid, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected PPid %q", fields[1])
+			}
+			status.ppid = uint32(ppid)
+		case "VmRSS:":
+			// The size is in kB
+			rss, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected VmRSS %q", fields[1])
+			}
+			status.rss = rss * 1024
+		}
+	}
+	return status, scanner.Err()
+}
+
+func readShimPid(path string) (uint32, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected shim pid %q", data)
+	}
+	return uint32(pid), nil
+}
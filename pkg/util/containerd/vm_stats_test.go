@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package containerd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVMRuntime(t *testing.T) {
+	assert.True(t, IsVMRuntime("kata"))
+	assert.True(t, IsVMRuntime("kata-qemu"))
+	assert.True(t, IsVMRuntime(RuntimeHandler("io.containerd.kata-fc.v2")))
+	assert.False(t, IsVMRuntime("runc"))
+	assert.False(t, IsVMRuntime("katana"))
+}
+
+func TestTaskVMStats(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	stateDir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(stateDir)
+
+	writeStatus := func(pid int, name string, ppid int, rssKB int) {
+		dir := filepath.Join(procRoot, fmt.Sprint(pid))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		status := fmt.Sprintf("Name:\t%s\nState:\tS (sleeping)\nPPid:\t%d\nVmRSS:\t  %d kB\nThreads:\t4\n", name, ppid, rssKB)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "status"), []byte(status), 0644))
+	}
+	// qemu daemonizes, the shim is read from the bundle of the sandbox
+	writeStatus(100, "qemu-system-x86", 1, 2048)
+	writeStatus(90, "containerd-shim", 1, 512)
+	// cloud-hypervisor is a child of the shim
+	writeStatus(200, "cloud-hyperviso", 190, 1024)
+	writeStatus(190, "containerd-shim", 1, 256)
+	bundle := filepath.Join(stateDir, runtimeV2TaskDir, "k8s.io", "sandbox")
+	require.NoError(t, os.MkdirAll(bundle, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundle, "shim.pid"), []byte("90"), 0644))
+
+	cu := &ContainerdUtil{namespace: "k8s.io", procRoot: procRoot, stateDir: stateDir}
+	stats, err := cu.TaskVMStats("sandbox", 100)
+	require.NoError(t, err)
+	assert.Equal(t, &TaskVMStats{VMMemoryRSS: 2048 * 1024, ShimRSS: 512 * 1024}, stats)
+
+	stats, err = cu.TaskVMStats("clh", 200)
+	require.NoError(t, err)
+	assert.Equal(t, &TaskVMStats{VMMemoryRSS: 1024 * 1024, ShimRSS: 256 * 1024}, stats)
+
+	// The shim of the other containers of the qemu pod is not found
+	stats, err = cu.TaskVMStats("redis", 100)
+	require.NoError(t, err)
+	assert.Equal(t, &TaskVMStats{VMMemoryRSS: 2048 * 1024}, stats)
+
+	_, err = cu.TaskVMStats("gone", 300)
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package collectors
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	containerdCollectorName = "containerd"
+)
+
+// ContainerdCollector lists containers from the containerd socket, mapping
+// them to their processes with the task PIDs, and populates performance
+// metric from the linux cgroups
+type ContainerdCollector struct {
+	filter *containers.Filter
+}
+
+// Detect tries to connect to the containerd socket and returns success
+func (c *ContainerdCollector) Detect() error {
+	if _, err := containerd.GetContainerdUtil(nil); err != nil {
+		return err
+	}
+	filter, err := containers.GetSharedFilter()
+	if err != nil {
+		return err
+	}
+
+	c.filter = filter
+	return nil
+}
+
+// List gets all running containers of the collected namespaces
+func (c *ContainerdCollector) List() ([]*containers.Container, error) {
+	utils, err := containerd.GetNamespacedUtils()
+	if err != nil {
+		return nil, fmt.Errorf("could not list containerd namespaces: %s", err)
+	}
+	var ctrs []*containers.Container
+	for _, cu := range utils {
+		running, err := containerd.RunningContainers(cu)
+		if err != nil {
+			return nil, fmt.Errorf("could not list containerd containers of namespace %s: %s", cu.Namespace(), err)
+		}
+		for _, ctn := range running {
+			ctrs = append(ctrs, convertContainerdContainer(ctn))
+		}
+	}
+
+	cgByContainer, err := metrics.ScrapeAllCgroups()
+	if err != nil {
+		return nil, fmt.Errorf("could not get cgroups: %s", err)
+	}
+
+	var ctrList []*containers.Container
+	for _, container := range ctrs {
+		if c.filter.IsExcluded(container.Name, container.Image) {
+			continue
+		}
+		ctrList = append(ctrList, container)
+
+		cgroup, ok := cgByContainer[container.ID]
+		if !ok {
+			// Keep the container with its task PIDs, without metrics
+			log.Debugf("No cgroup found for container %s", container.ID)
+			continue
+		}
+		container.SetCgroups(cgroup)
+		err = container.FillCgroupLimits()
+		if err != nil {
+			log.Debugf("Cannot get limits for container %s: %s", container.ID, err)
+			continue
+		}
+	}
+	err = c.UpdateMetrics(ctrList)
+	return ctrList, err
+}
+
+// UpdateMetrics updates metrics on an existing list of containers
+func (c *ContainerdCollector) UpdateMetrics(cList []*containers.Container) error {
+	for _, container := range cList {
+		// The start time of the task, read by RunningContainers, is more
+		// accurate than the one of its cgroup
+		startedAt := container.StartedAt
+		err := container.FillCgroupMetrics()
+		if startedAt != 0 {
+			container.StartedAt = startedAt
+		}
+		if err != nil {
+			log.Debugf("Cannot get metrics for container %s: %s", container.ID, err)
+			continue
+		}
+		err = container.FillNetworkMetrics(nil)
+		if err != nil {
+			log.Debugf("Cannot get network stats for container %s: %s", container.ID, err)
+			continue
+		}
+	}
+	return nil
+}
+
+// convertContainerdContainer returns the container of a running containerd
+// container, cgroup limits and metrics are left to the caller
+func convertContainerdContainer(ctn containerd.RunningContainer) *containers.Container {
+	c := &containers.Container{
+		Type:     containers.RuntimeNameContainerd,
+		ID:       ctn.ID,
+		EntityID: containerd.EntityID(ctn.ID),
+		Name:     ctn.Name,
+		Image:    ctn.Image,
+		Created:  ctn.CreatedAt.Unix(),
+		State:    containers.ContainerRunningState,
+		Pids:     ctn.Pids,
+		Security: ctn.Security,
+	}
+	if !ctn.StartedAt.IsZero() {
+		c.StartedAt = ctn.StartedAt.Unix()
+	}
+	return c
+}
+
+func containerdFactory() Collector {
+	return &ContainerdCollector{}
+}
+
+func init() {
+	registerCollector(containerdCollectorName, containerdFactory, NodeLowLevelRuntime)
+}
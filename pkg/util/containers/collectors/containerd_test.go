@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+func TestConvertContainerdContainer(t *testing.T) {
+	created := time.Unix(1540000000, 0)
+	started := created.Add(time.Minute)
+	security := &containers.SecurityProfile{SeccompMode: containers.SeccompModeFilter}
+
+	ctn := containerd.RunningContainer{
+		CachedContainer: containerd.CachedContainer{
+			ID:        "running",
+			Name:      "redis",
+			Image:     "docker.io/library/redis:latest",
+			CreatedAt: created,
+			Security:  security,
+		},
+		Pids:      []int32{42, 43},
+		StartedAt: started,
+	}
+	assert.Equal(t, &containers.Container{
+		Type:      "containerd",
+		ID:        "running",
+		EntityID:  "container_id://running",
+		Name:      "redis",
+		Image:     "docker.io/library/redis:latest",
+		Created:   created.Unix(),
+		State:     containers.ContainerRunningState,
+		Pids:      []int32{42, 43},
+		StartedAt: started.Unix(),
+		Security:  security,
+	}, convertContainerdContainer(ctn))
+
+	// The start time of the cgroup is used if the task has none
+	ctn.StartedAt = time.Time{}
+	assert.Equal(t, int64(0), convertContainerdContainer(ctn).StartedAt)
+}
@@ -233,6 +233,25 @@ func (suite *DetectorTestSuite) TestConfigureTwoByPrio() {
 	assert.Nil(suite.T(), d.detected)
 }
 
+// TestConfigureLowLevelRuntime makes sure containerd is not preferred
+// over docker, that runs on top of it
+func (suite *DetectorTestSuite) TestConfigureLowLevelRuntime() {
+	kubelet := registerMock("kubelet", NodeOrchestrator)
+	kubelet.On("Detect").Return(nil).Once()
+	containerd := registerMock("containerd", NodeLowLevelRuntime)
+	containerd.On("Detect").Return(nil).Once()
+	docker := registerMock("docker", NodeRuntime)
+	docker.On("Detect").Return(nil).Once()
+
+	d := NewDetector("")
+	c, n, err := d.GetPreferred()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "docker", n)
+	assert.Equal(suite.T(), docker, c)
+
+	assert.True(suite.T(), isPrefered("containerd", "kubelet"))
+}
+
 func TestDetectorTestSuite(t *testing.T) {
 	suite.Run(t, new(DetectorTestSuite))
 }
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package collectors
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+func init() {
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "container stats fetch",
+		Stage:       diagnosis.StageStats,
+		Run:         diagnoseStats,
+		Remediation: "Check that the host cgroup and proc filesystems are mounted in the agent container, see the container_cgroup_root and container_proc_root settings",
+	})
+}
+
+// diagnoseStats lists the containers with the preferred collector
+// and checks that the metrics of a running container can be read
+func diagnoseStats() error {
+	collector, name, err := NewDetector("").GetPreferred()
+	if err != nil {
+		return err
+	}
+	log.Infof("using collector %s", name)
+
+	ctrs, err := collector.List()
+	if err != nil {
+		return err
+	}
+	log.Infof("found %d containers", len(ctrs))
+	for _, ctr := range ctrs {
+		if ctr.CPU != nil && ctr.Memory != nil {
+			log.Infof("successfully fetched the stats of container %s", ctr.ID)
+			return nil
+		}
+	}
+	return errors.New("no container with CPU and memory stats")
+}
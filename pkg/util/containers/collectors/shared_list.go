@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package collectors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+var (
+	sharedListProvider *containers.ListProvider
+	sharedListOnce     sync.Once
+)
+
+// GetSharedListProvider returns the ListProvider shared by the checks
+// listing the containers of the host, like the container, containerd and
+// process checks. It lists the containers with the preferred collector,
+// refreshed every container_list_max_staleness seconds in the background.
+func GetSharedListProvider() *containers.ListProvider {
+	sharedListOnce.Do(func() {
+		// The detector is only used by the listings of the provider,
+		// which do not run concurrently
+		detector := NewDetector("")
+		list := func() ([]*containers.Container, error) {
+			collector, _, err := detector.GetPreferred()
+			if err != nil {
+				return nil, err
+			}
+			return collector.List()
+		}
+		maxStaleness := config.Datadog.GetDuration("container_list_max_staleness") * time.Second
+		sharedListProvider = containers.NewListProvider(list, maxStaleness)
+		sharedListProvider.Start()
+	})
+	return sharedListProvider
+}
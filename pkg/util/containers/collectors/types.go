@@ -18,8 +18,11 @@ type Collector interface {
 type CollectorPriority int
 
 // List of collector priorities
-// Order is reverse from the tagger: docker > kubelet
+// Order is reverse from the tagger: docker > containerd > kubelet
 const (
 	NodeOrchestrator CollectorPriority = iota
+	// NodeLowLevelRuntime is used for runtimes that can also run
+	// below NodeRuntime ones, like containerd below docker
+	NodeLowLevelRuntime
 	NodeRuntime
 )
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/retry"
 	"google.golang.org/grpc"
@@ -26,6 +27,11 @@ var (
 	once          sync.Once
 )
 
+// statsCacheDuration is shorter than the check intervals, so that every run
+// of the cri check lists fresh stats, shared with the other callers of the
+// same socket
+const statsCacheDuration = 10 * time.Second
+
 // CRIUtil wraps interactions with the CRI
 // see https://github.com/kubernetes/kubernetes/blob/release-1.12/pkg/kubelet/apis/cri/runtime/v1alpha2/api.proto
 type CRIUtil struct {
@@ -97,8 +103,18 @@ func GetUtil() (*CRIUtil, error) {
 	return globalCRIUtil, nil
 }
 
-// ListContainerStats sends a ListContainerStatsRequest to the server, and parses the returned response
+// ListContainerStats sends a ListContainerStatsRequest to the server, and parses the returned response.
+// The stats of every container are listed in a single call, and cached for 10 seconds.
+// The returned map is a copy, the stats it holds are shared and must not be modified.
 func (c *CRIUtil) ListContainerStats() (map[string]*pb.ContainerStats, error) {
+	cacheKey := c.statsCacheKey()
+	if cached, hit := cache.Cache.Get(cacheKey); hit {
+		if stats, ok := cached.(map[string]*pb.ContainerStats); ok {
+			return copyStats(stats), nil
+		}
+		log.Errorf("Invalid container stats cache format, forcing a cache miss")
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
 	defer cancel()
 	filter := &pb.ContainerStatsFilter{}
@@ -112,5 +128,20 @@ func (c *CRIUtil) ListContainerStats() (map[string]*pb.ContainerStats, error) {
 	for _, s := range r.GetStats() {
 		stats[s.Attributes.Id] = s
 	}
-	return stats, nil
+	cache.Cache.Set(cacheKey, stats, statsCacheDuration)
+	return copyStats(stats), nil
+}
+
+// statsCacheKey returns the cache key of the stats listed from the socket of
+// the util, the utils of other sockets do not share them
+func (c *CRIUtil) statsCacheKey() string {
+	return cache.BuildAgentKey("cri", "container_stats", c.socketPath)
+}
+
+func copyStats(stats map[string]*pb.ContainerStats) map[string]*pb.ContainerStats {
+	copied := make(map[string]*pb.ContainerStats, len(stats))
+	for id, s := range stats {
+		copied[id] = s
+	}
+	return copied
 }
@@ -14,7 +14,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
 	fakeremote "k8s.io/kubernetes/pkg/kubelet/remote/fake"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
 )
 
 func TestCRIUtilInit(t *testing.T) {
@@ -46,10 +49,44 @@ func TestCRIUtilListContainerStats(t *testing.T) {
 	}
 	err := util.init()
 	require.NoError(t, err)
+	defer cache.Cache.Delete(util.statsCacheKey())
 	_, err = util.ListContainerStats()
 	require.NoError(t, err)
 }
 
+func TestCRIUtilListContainerStatsCache(t *testing.T) {
+	fakeRuntime, endpoint := createAndStartFakeRemoteRuntime(t)
+	defer fakeRuntime.Stop()
+	util := &CRIUtil{
+		queryTimeout:      1 * time.Second,
+		connectionTimeout: 1 * time.Second,
+		socketPath:        endpoint[7:], // remove unix://
+	}
+	require.NoError(t, util.init())
+	defer cache.Cache.Delete(util.statsCacheKey())
+
+	// The stats listed from another socket are not shared
+	other := &CRIUtil{socketPath: "/var/run/other.sock"}
+	defer cache.Cache.Delete(other.statsCacheKey())
+	cache.Cache.Set(other.statsCacheKey(), map[string]*pb.ContainerStats{
+		"foo": {Attributes: &pb.ContainerAttributes{Id: "foo"}},
+	}, time.Minute)
+	stats, err := util.ListContainerStats()
+	require.NoError(t, err)
+	assert.NotContains(t, stats, "foo")
+
+	// The client is not set, the stats are read from the cache
+	stats, err = other.ListContainerStats()
+	require.NoError(t, err)
+	assert.Contains(t, stats, "foo")
+
+	// The cached stats are not modified through the returned map
+	delete(stats, "foo")
+	stats, err = other.ListContainerStats()
+	require.NoError(t, err)
+	assert.Contains(t, stats, "foo")
+}
+
 // createAndStartFakeRemoteRuntime creates and starts fakeremote.RemoteRuntime.
 // It returns the RemoteRuntime, endpoint on success.
 // Users should call fakeRuntime.Stop() to cleanup the server.
@@ -8,9 +8,15 @@ package containers
 import (
 	"fmt"
 	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
 )
 
-const entitySeparator = "://"
+const entitySeparator = types.EntitySeparator
+
+// ContainerEntityPrefix is the prefix of the canonical entity names of the
+// containerd containers, container_id://<id>
+const ContainerEntityPrefix = types.ContainerEntityPrefix
 
 // BuildEntityName builds a valid entity name for a given container runtime and cid
 func BuildEntityName(runtime, id string) string {
@@ -20,6 +26,23 @@ func BuildEntityName(runtime, id string) string {
 	return fmt.Sprintf("%s%s%s", runtime, entitySeparator, id)
 }
 
+// BuildContainerEntityName builds the canonical entity name of a container
+// run by a given runtime: container_id://<id> for the containerd containers,
+// <runtime>://<id> for the others
+func BuildContainerEntityName(runtime, id string) string {
+	if runtime == RuntimeNameContainerd && id != "" {
+		return types.ContainerEntityName(id)
+	}
+	return BuildEntityName(runtime, id)
+}
+
+// CanonicalEntityName returns the canonical name of a container entity: the
+// containerd://<id> names, eg. the container IDs of the kubelet pod statuses,
+// are renamed container_id://<id>. Other names are returned as is.
+func CanonicalEntityName(name string) string {
+	return types.CanonicalEntityName(name)
+}
+
 // SplitEntityName returns the runtime and container cid parts of a valid entity name
 func SplitEntityName(name string) (string, string) {
 	if !IsEntityName(name) {
@@ -43,5 +66,5 @@ func ContainerIDForEntity(name string) string {
 
 // IsEntityName tests whether a given entity name is valid
 func IsEntityName(name string) bool {
-	return strings.Contains(name, entitySeparator)
+	return types.IsEntityName(name)
 }
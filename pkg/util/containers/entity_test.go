@@ -59,3 +59,24 @@ func TestSplitEntityName(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildContainerEntityName(t *testing.T) {
+	assert.Equal(t, "container_id://5bef08742407ef", BuildContainerEntityName(RuntimeNameContainerd, "5bef08742407ef"))
+	assert.Equal(t, "docker://5bef08742407ef", BuildContainerEntityName(RuntimeNameDocker, "5bef08742407ef"))
+	assert.Equal(t, "", BuildContainerEntityName(RuntimeNameContainerd, ""))
+}
+
+func TestCanonicalEntityName(t *testing.T) {
+	for _, tc := range []struct {
+		entity   string
+		expected string
+	}{
+		{"containerd://5bef08742407ef", "container_id://5bef08742407ef"},
+		{"container_id://5bef08742407ef", "container_id://5bef08742407ef"},
+		{"docker://5bef08742407ef", "docker://5bef08742407ef"},
+		{"kubernetes_pod://f3fb6a6a", "kubernetes_pod://f3fb6a6a"},
+		{"5bef08742407ef", "5bef08742407ef"},
+	} {
+		assert.Equal(t, tc.expected, CanonicalEntityName(tc.entity), tc.entity)
+	}
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package containers
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metadata/host/container"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const runcVersionPrefix = "runc version "
+
+// For testing
+var kernelReleasePath = "/proc/sys/kernel/osrelease"
+
+func init() {
+	container.RegisterMetadataProvider("container_host", getHostMetadata)
+}
+
+// getHostMetadata returns the host features the container runtimes rely on.
+// The runtime versions are reported by the metadata providers of the
+// runtime utils, eg. docker_version, containerd_version or cri_version.
+func getHostMetadata() (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	if version, err := metrics.CgroupVersion(); err == nil {
+		metadata["cgroup_version"] = version
+	} else {
+		log.Debugf("Cannot detect the cgroup version: %s", err)
+	}
+
+	if release, err := ioutil.ReadFile(kernelReleasePath); err == nil {
+		metadata["kernel_version"] = strings.TrimSpace(string(release))
+	} else {
+		log.Debugf("Cannot read the kernel release: %s", err)
+	}
+
+	// runc is usually not available when the agent runs in a container
+	if version, err := getRuncVersion(); err == nil {
+		metadata["runc_version"] = version
+	} else {
+		log.Debugf("Cannot get the runc version: %s", err)
+	}
+
+	return metadata, nil
+}
+
+func getRuncVersion() (string, error) {
+	// short timeout to minimize metadata collection time
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "runc", "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return parseRuncVersion(string(out)), nil
+}
+
+// parseRuncVersion extracts the version from the output of runc --version:
+//	runc version 1.0.0-rc5+dev
+//	commit: 4bb1fe4ace1a32d3676bb98f5d3b6a4e32bf6c58
+//	spec: 1.0.0
+func parseRuncVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, runcVersionPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, runcVersionPrefix))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRuncVersion(t *testing.T) {
+	output := "runc version 1.0.0-rc5+dev\ncommit: 4bb1fe4ace1a32d3676bb98f5d3b6a4e32bf6c58\nspec: 1.0.0\n"
+	assert.Equal(t, "1.0.0-rc5+dev", parseRuncVersion(output))
+	assert.Equal(t, "", parseRuncVersion("runc: command not found"))
+}
+
+func TestGetHostMetadataKernel(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "osrelease")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString("4.15.0-1023-aws\n")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	kernelReleasePath = tmpFile.Name()
+	defer func() { kernelReleasePath = "/proc/sys/kernel/osrelease" }()
+
+	metadata, err := getHostMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, "4.15.0-1023-aws", metadata["kernel_version"])
+}
@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// DefaultListMaxStaleness is the max staleness of the lists served by a
+// ListProvider when none is configured
+const DefaultListMaxStaleness = 10 * time.Second
+
+// ListFunc lists the containers of the host, eg. the List method of the
+// preferred collector
+type ListFunc func() ([]*Container, error)
+
+// ListSnapshot is a list of containers and the time it was listed at
+type ListSnapshot struct {
+	Containers []*Container
+	ListedAt   time.Time
+	// Err is the error of the listing, Containers is empty if it is set
+	Err error
+}
+
+// ListProvider shares the list of the containers of the host between the
+// checks, so that the host is listed once per interval instead of once per
+// check. The list is refreshed in the background every max staleness once
+// started, by a single goroutine.
+//
+// Freshness contract: Get never returns a list older than the max
+// staleness. If the background refresh is late or not started, Get lists
+// the containers itself, the concurrent callers share that listing. The
+// failed listings are served for the max staleness too, the runtime is
+// not listed again before. The containers of the lists are shared by the
+// callers, they must be copied before being modified.
+type ListProvider struct {
+	list         ListFunc
+	maxStaleness time.Duration
+
+	mu       sync.Mutex
+	snapshot ListSnapshot
+	// inflight is the listing in progress, nil if none
+	inflight *inflightList
+	stop     chan struct{}
+}
+
+type inflightList struct {
+	done     chan struct{}
+	snapshot ListSnapshot
+}
+
+// NewListProvider returns a ListProvider listing the containers with
+// list, the DefaultListMaxStaleness is used if maxStaleness is not
+// positive
+func NewListProvider(list ListFunc, maxStaleness time.Duration) *ListProvider {
+	if maxStaleness <= 0 {
+		maxStaleness = DefaultListMaxStaleness
+	}
+	return &ListProvider{
+		list:         list,
+		maxStaleness: maxStaleness,
+	}
+}
+
+// MaxStaleness returns the max age of the lists returned by Get
+func (p *ListProvider) MaxStaleness() time.Duration {
+	return p.maxStaleness
+}
+
+// Get returns the last list of containers if it is fresh enough, or lists
+// them again otherwise
+func (p *ListProvider) Get() ListSnapshot {
+	p.mu.Lock()
+	snapshot := p.snapshot
+	p.mu.Unlock()
+	if !snapshot.ListedAt.IsZero() && time.Since(snapshot.ListedAt) < p.maxStaleness {
+		return snapshot
+	}
+	return p.refresh()
+}
+
+// refresh lists the containers unless a listing is in progress, in which
+// case its result is returned once it completes
+func (p *ListProvider) refresh() ListSnapshot {
+	p.mu.Lock()
+	if call := p.inflight; call != nil {
+		p.mu.Unlock()
+		<-call.done
+		return call.snapshot
+	}
+	call := &inflightList{done: make(chan struct{})}
+	p.inflight = call
+	p.mu.Unlock()
+
+	containers, err := p.list()
+	if err != nil {
+		containers = nil
+	}
+	call.snapshot = ListSnapshot{Containers: containers, ListedAt: time.Now(), Err: err}
+
+	p.mu.Lock()
+	p.snapshot = call.snapshot
+	p.inflight = nil
+	p.mu.Unlock()
+	close(call.done)
+	return call.snapshot
+}
+
+// Start refreshes the list in the background until Stop is called, so that
+// the callers of Get do not wait for the listings. It is a no-op if the
+// refresh goroutine is already running.
+func (p *ListProvider) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	go p.refreshLoop(p.stop)
+}
+
+// Stop stops the background refresh
+func (p *ListProvider) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *ListProvider) refreshLoop(stop chan struct{}) {
+	// The list is refreshed before it gets stale, so that Get does not
+	// list the containers while the refresh is on time
+	ticker := time.NewTicker(p.maxStaleness / 2)
+	defer ticker.Stop()
+	for {
+		if s := p.refresh(); s.Err != nil {
+			log.Debugf("Cannot refresh the shared container list: %s", s.Err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListProvider(t *testing.T) {
+	var listings int32
+	var fail atomic.Value
+	fail.Store(false)
+	release := make(chan struct{})
+	p := NewListProvider(func() ([]*Container, error) {
+		atomic.AddInt32(&listings, 1)
+		<-release
+		if fail.Load().(bool) {
+			return []*Container{{ID: "partial"}}, errors.New("docker is not running")
+		}
+		return []*Container{{ID: "redis"}}, nil
+	}, 50*time.Millisecond)
+
+	// The concurrent callers share a single listing
+	var wg sync.WaitGroup
+	snapshots := make([]ListSnapshot, 3)
+	for i := range snapshots {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			snapshots[i] = p.Get()
+		}(i)
+	}
+	// Wait for the callers to join the listing
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&listings))
+	for _, s := range snapshots {
+		require.NoError(t, s.Err)
+		require.Len(t, s.Containers, 1)
+		assert.Equal(t, "redis", s.Containers[0].ID)
+		assert.Equal(t, snapshots[0].ListedAt, s.ListedAt)
+	}
+
+	// The list is served until it is stale
+	s := p.Get()
+	assert.Equal(t, snapshots[0].ListedAt, s.ListedAt)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&listings))
+
+	time.Sleep(50 * time.Millisecond)
+	fail.Store(true)
+	s = p.Get()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&listings))
+	assert.Error(t, s.Err)
+	assert.Empty(t, s.Containers)
+	// The failed listings are served for the max staleness too
+	assert.Equal(t, s.ListedAt, p.Get().ListedAt)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&listings))
+}
+
+func TestListProviderBackgroundRefresh(t *testing.T) {
+	var listings int32
+	p := NewListProvider(func() ([]*Container, error) {
+		atomic.AddInt32(&listings, 1)
+		return nil, nil
+	}, 200*time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, p.MaxStaleness())
+
+	p.Start()
+	p.Start()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&listings) >= 3 }, time.Second, 5*time.Millisecond)
+	p.Stop()
+
+	// The callers are served the list refreshed in the background
+	before := atomic.LoadInt32(&listings)
+	s := p.Get()
+	assert.NoError(t, s.Err)
+	assert.True(t, time.Since(s.ListedAt) < 200*time.Millisecond)
+	assert.Equal(t, before, atomic.LoadInt32(&listings))
+}
@@ -195,6 +195,29 @@ func ContainerIDForPID(pid int) (string, error) {
 	return containerID, err
 }
 
+// CgroupPathsForPID returns the cgroup path of every controller of a process,
+// whether a container ID can be parsed from them or not.
+func CgroupPathsForPID(pid int) (map[string]string, error) {
+	f, err := os.Open(hostProc(strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sp := strings.SplitN(scanner.Text(), ":", 3)
+		if len(sp) < 3 {
+			continue
+		}
+		for _, target := range strings.Split(sp[1], ",") {
+			paths[target] = sp[2]
+		}
+	}
+	return paths, scanner.Err()
+}
+
 // ReadCgroupsForPath reads the cgroups from a /proc/$pid/cgroup path.
 func ReadCgroupsForPath(pidCgroupPath, prefix string) (string, map[string]string, error) {
 	f, err := os.Open(pidCgroupPath)
@@ -12,6 +12,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
 )
 
 func TestParseCgroupMountPoints(t *testing.T) {
@@ -303,6 +306,32 @@ func TestCgroupPrefixFiltering(t *testing.T) {
 	assert.Equal(t, "", c)
 }
 
+func TestCgroupPathsForPID(t *testing.T) {
+	dummyProcDir, err := newTempFolder("test-cgroup-paths")
+	require.NoError(t, err)
+	defer dummyProcDir.removeAll() // clean up
+	config.Datadog.SetDefault("container_proc_root", dummyProcDir.RootPath)
+	defer config.Datadog.SetDefault("container_proc_root", "/proc")
+
+	dummyProcDir.add("42/cgroup", strings.Join([]string{
+		"11:memory:/default/redis",
+		"10:cpu,cpuacct:/default/redis",
+		"1:name=systemd:/default/redis",
+	}, "\n"))
+
+	paths, err := CgroupPathsForPID(42)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"memory":       "/default/redis",
+		"cpu":          "/default/redis",
+		"cpuacct":      "/default/redis",
+		"name=systemd": "/default/redis",
+	}, paths)
+
+	_, err = CgroupPathsForPID(43)
+	assert.Error(t, err)
+}
+
 // TestDindContainer is to test if our agent can handle dind container correctly
 func TestDindContainer(t *testing.T) {
 	containerID := "6ab998413f7ae63bb26403dfe9e7ec02aa92b5cfc019de79da925594786c985f"
@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package metrics
+
+import (
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
+)
+
+// Cgroup hierarchies, as reported by CgroupVersion
+const (
+	CgroupV1     = types.CgroupV1
+	CgroupV2     = types.CgroupV2
+	CgroupHybrid = types.CgroupHybrid
+)
+
+// CgroupVersion returns the cgroup hierarchy mounted on the host: v1, v2,
+// or hybrid when the unified hierarchy is mounted next to v1 controllers.
+func CgroupVersion() (string, error) {
+	f, err := os.Open(hostProc("mounts"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return types.ParseCgroupVersion(f)
+}
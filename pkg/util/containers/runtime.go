@@ -24,6 +24,7 @@ const (
 	daemonNameDockerLegacy1  string = "dockerd"
 	daemonNameDockerLegacy2  string = "dockerd-current" // CentOS
 	shimNameContainerd       string = "containerd-shim"
+	shimPrefixContainerdV2   string = "containerd-shim-" // containerd-shim-runc-v2, containerd-shim-kata-v2...
 	shimNameCRIO             string = "conmon"
 	shimNameContainerdUnsure string = "docker-containerd-shim"
 	shimArgContainerdK8s     string = "-namespace k8s.io"
@@ -52,7 +53,7 @@ func EntityForPID(pid int32) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return BuildEntityName(runtime, cID), nil
+	return BuildContainerEntityName(runtime, cID), nil
 }
 
 // GetRuntimeForPID inspects a PID's parents to detect a container runtime.
@@ -82,8 +83,8 @@ func GetRuntimeForPID(pid int32) (string, error) {
 			cmd = cmdParts[len(cmdParts)-1]
 		}
 		// Match with supported shim names
-		switch cmd {
-		case shimNameContainerdUnsure:
+		switch {
+		case cmd == shimNameContainerdUnsure:
 			// Shim can be used either by k8s for direct containerd
 			// or new docker versions, checking arguments
 			if len(cmdline) < 2 {
@@ -96,13 +97,18 @@ func GetRuntimeForPID(pid int32) (string, error) {
 			case strings.Contains(args, shimArgContainerdDocker):
 				return RuntimeNameDocker, nil
 			}
-		case shimNameContainerd:
+		case cmd == shimNameContainerd, strings.HasPrefix(cmd, shimPrefixContainerdV2):
+			// Docker 18.09+ runs its containers in the moby namespace
+			// of the containerd daemon, with the same shims
+			if strings.Contains(strings.Join(cmdline[1:], " "), shimArgContainerdDocker) {
+				return RuntimeNameDocker, nil
+			}
 			return RuntimeNameContainerd, nil
-		case shimNameCRIO:
+		case cmd == shimNameCRIO:
 			return RuntimeNameCRIO, nil
-		case daemonNameDockerLegacy1:
+		case cmd == daemonNameDockerLegacy1:
 			return RuntimeNameDocker, nil
-		case daemonNameDockerLegacy2:
+		case cmd == daemonNameDockerLegacy2:
 			return RuntimeNameDocker, nil
 		}
 
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Values of the container_runtime setting. RuntimeNameDocker and
+// RuntimeNameContainerd select the docker and containerd runtimes.
+const (
+	RuntimeSelectionAuto = "auto"
+	RuntimeSelectionCRI  = "cri"
+)
+
+var (
+	// dockerSocketPath and containerdSocketPath are the default sockets of
+	// the daemons, overridden in tests
+	dockerSocketPath     = "/var/run/docker.sock"
+	containerdSocketPath = "/var/run/containerd/containerd.sock"
+	// getenv is overridden in tests
+	getenv = os.Getenv
+)
+
+// SelectedRuntime returns the container runtime the checks, the tagger
+// collectors, the autodiscovery listeners and the logs launchers collect,
+// set by container_runtime. In auto mode, the runtime is detected from the
+// sockets of the host: docker first, then containerd, then the CRI socket of
+// cri_socket_path. It returns RuntimeSelectionAuto if no socket is found,
+// every runtime is then collected.
+func SelectedRuntime() string {
+	selected := strings.ToLower(config.Datadog.GetString("container_runtime"))
+	switch selected {
+	case RuntimeNameDocker, RuntimeNameContainerd, RuntimeSelectionCRI:
+		return selected
+	case RuntimeSelectionAuto, "":
+	default:
+		log.Warnf("Unknown container_runtime %q, detecting the container runtime", selected)
+	}
+	return detectRuntime()
+}
+
+// IsRuntimeSelected returns whether the components of a runtime, docker,
+// containerd or cri, should initialize
+func IsRuntimeSelected(runtime string) bool {
+	selected := SelectedRuntime()
+	return selected == RuntimeSelectionAuto || selected == runtime
+}
+
+// CheckRuntimeSelected returns an error if the components of a runtime
+// should not initialize, see IsRuntimeSelected
+func CheckRuntimeSelected(runtime string) error {
+	if selected := SelectedRuntime(); selected != RuntimeSelectionAuto && selected != runtime {
+		return fmt.Errorf("the container runtime is %s, %s is not collected, see container_runtime", selected, runtime)
+	}
+	return nil
+}
+
+// detectRuntime returns the runtime whose socket is found on the host
+func detectRuntime() string {
+	if hasDockerSocket() {
+		return RuntimeNameDocker
+	}
+	if criSocket := config.Datadog.GetString("cri_socket_path"); criSocket != "" {
+		// The containerd socket is also the endpoint of its CRI plugin
+		if filepath.Base(criSocket) == filepath.Base(containerdSocketPath) {
+			return RuntimeNameContainerd
+		}
+		if exists(criSocket) {
+			return RuntimeSelectionCRI
+		}
+	}
+	if exists(containerdSocketPath) {
+		return RuntimeNameContainerd
+	}
+	return RuntimeSelectionAuto
+}
+
+// hasDockerSocket returns whether the docker daemon is reachable through
+// the default socket or DOCKER_HOST. The remote daemons are assumed to be
+// reachable.
+func hasDockerSocket() bool {
+	host := getenv("DOCKER_HOST")
+	if host == "" {
+		return exists(dockerSocketPath)
+	}
+	if strings.HasPrefix(host, "unix://") {
+		return exists(strings.TrimPrefix(host, "unix://"))
+	}
+	return true
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestSelectedRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sockets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(docker, containerd string) {
+		dockerSocketPath, containerdSocketPath, getenv = docker, containerd, os.Getenv
+	}(dockerSocketPath, containerdSocketPath)
+	dockerSocketPath = filepath.Join(dir, "docker.sock")
+	containerdSocketPath = filepath.Join(dir, "containerd.sock")
+	env := map[string]string{}
+	getenv = func(key string) string { return env[key] }
+	create := func(path string) {
+		require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	}
+	defer config.Datadog.SetDefault("container_runtime", "auto")
+	defer config.Datadog.SetDefault("cri_socket_path", "")
+
+	// Nothing is detected, every runtime is collected
+	config.Datadog.SetDefault("container_runtime", "auto")
+	assert.Equal(t, RuntimeSelectionAuto, SelectedRuntime())
+	assert.True(t, IsRuntimeSelected(RuntimeNameDocker))
+	assert.True(t, IsRuntimeSelected(RuntimeNameContainerd))
+	assert.NoError(t, CheckRuntimeSelected(RuntimeSelectionCRI))
+
+	create(containerdSocketPath)
+	assert.Equal(t, RuntimeNameContainerd, SelectedRuntime())
+	assert.False(t, IsRuntimeSelected(RuntimeNameDocker))
+	assert.Error(t, CheckRuntimeSelected(RuntimeNameDocker))
+
+	// Docker runs on containerd, it is selected first
+	create(dockerSocketPath)
+	assert.Equal(t, RuntimeNameDocker, SelectedRuntime())
+	env["DOCKER_HOST"] = "unix://" + filepath.Join(dir, "missing.sock")
+	assert.Equal(t, RuntimeNameContainerd, SelectedRuntime())
+	env["DOCKER_HOST"] = "tcp://127.0.0.1:2375"
+	assert.Equal(t, RuntimeNameDocker, SelectedRuntime())
+	delete(env, "DOCKER_HOST")
+	require.NoError(t, os.Remove(dockerSocketPath))
+	require.NoError(t, os.Remove(containerdSocketPath))
+
+	crioSocket := filepath.Join(dir, "crio.sock")
+	create(crioSocket)
+	config.Datadog.SetDefault("cri_socket_path", crioSocket)
+	assert.Equal(t, RuntimeSelectionCRI, SelectedRuntime())
+	config.Datadog.SetDefault("cri_socket_path", "/run/k3s/containerd/containerd.sock")
+	assert.Equal(t, RuntimeNameContainerd, SelectedRuntime())
+
+	// The selection overrides the detection
+	config.Datadog.SetDefault("container_runtime", "Docker")
+	assert.Equal(t, RuntimeNameDocker, SelectedRuntime())
+	assert.True(t, IsRuntimeSelected(RuntimeNameDocker))
+	assert.False(t, IsRuntimeSelected(RuntimeNameContainerd))
+	config.Datadog.SetDefault("container_runtime", "rkt")
+	assert.Equal(t, RuntimeNameContainerd, SelectedRuntime())
+}
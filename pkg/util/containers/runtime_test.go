@@ -41,6 +41,29 @@ func (s *RuntimeDetectionTestSuite) TestContainerd() {
 	assert.Equal(s.T(), RuntimeNameContainerd, runtime)
 }
 
+func (s *RuntimeDetectionTestSuite) TestContainerdShimV2() {
+	s.proc.addDummyProcess("1", "0", "/sbin/init")
+	s.proc.addDummyProcess("25", "1", "/usr/local/bin/containerd --log-level debug")
+	s.proc.addDummyProcess("28", "1", "/usr/local/bin/containerd-shim-runc-v2 -namespace k8s.io -id 6f82f4e18c89 ...")
+	s.proc.addDummyProcess("444", "28", "/opt/datadog-agent/bin/agent/agent start")
+
+	runtime, err := GetRuntimeForPID(444)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), RuntimeNameContainerd, runtime)
+}
+
+func (s *RuntimeDetectionTestSuite) TestDockerContainerdShimMoby() {
+	s.proc.addDummyProcess("1", "0", "/sbin/init")
+	s.proc.addDummyProcess("10", "1", "/usr/bin/dockerd -H fd://")
+	s.proc.addDummyProcess("25", "1", "/usr/bin/containerd")
+	s.proc.addDummyProcess("28", "25", "containerd-shim -namespace moby -workdir /var/lib/containerd/io.containerd.runtime.v1.linux/moby/6f82f4e18c89 ...")
+	s.proc.addDummyProcess("444", "28", "/opt/datadog-agent/bin/agent/agent start")
+
+	runtime, err := GetRuntimeForPID(444)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), RuntimeNameDocker, runtime)
+}
+
 func (s *RuntimeDetectionTestSuite) TestDockerContainerdK8s() {
 	s.proc.addDummyProcess("1", "0", "/sbin/init")
 	s.proc.addDummyProcess("25", "1", "/usr/local/bin/docker-containerd --log-level debug")
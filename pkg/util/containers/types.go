@@ -9,12 +9,13 @@ import (
 	"net"
 
 	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/types"
 )
 
 // Known container runtimes
 const (
 	RuntimeNameDocker     string = "docker"
-	RuntimeNameContainerd string = "containerd"
+	RuntimeNameContainerd string = types.RuntimeNameContainerd
 	RuntimeNameCRIO       string = "cri-o"
 )
 
@@ -29,6 +30,12 @@ const (
 	ContainerDeadState              = "dead"
 )
 
+// Seccomp modes of a SecurityProfile
+const (
+	SeccompModeUnconfined = types.SeccompModeUnconfined
+	SeccompModeFilter     = types.SeccompModeFilter
+)
+
 // Supported container health
 const (
 	ContainerUnknownHealth  string = "unknown"
@@ -63,6 +70,10 @@ type Container struct {
 	AddressList    []NetworkAddress
 	StartedAt      int64
 
+	// Security is the security profile of the container, nil if the
+	// collector does not read it
+	Security *SecurityProfile
+
 	// For internal use only
 	cgroup *metrics.ContainerCgroup
 }
@@ -73,3 +84,7 @@ type NetworkAddress struct {
 	Port     int
 	Protocol string
 }
+
+// SecurityProfile is the confinement of the processes of a container by
+// the Linux security modules and seccomp, as set in its spec
+type SecurityProfile = types.SecurityProfile
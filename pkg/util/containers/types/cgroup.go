@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Cgroup hierarchies, as reported by ParseCgroupVersion
+const (
+	CgroupV1     = "v1"
+	CgroupV2     = "v2"
+	CgroupHybrid = "hybrid"
+)
+
+// ParseCgroupVersion returns the cgroup hierarchy mounted on the host from
+// its mounts, eg. /proc/mounts: v1, v2, or hybrid when the unified hierarchy
+// is mounted next to v1 controllers.
+func ParseCgroupVersion(mounts io.Reader) (string, error) {
+	var v1, v2 bool
+	scanner := bufio.NewScanner(mounts)
+	for scanner.Scan() {
+		tokens := strings.Fields(scanner.Text())
+		if len(tokens) < 3 {
+			continue
+		}
+		switch tokens[2] {
+		case "cgroup":
+			v1 = true
+		case "cgroup2":
+			v2 = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case v1 && v2:
+		return CgroupHybrid, nil
+	case v1:
+		return CgroupV1, nil
+	case v2:
+		return CgroupV2, nil
+	default:
+		return "", fmt.Errorf("no cgroup filesystem mounted")
+	}
+}
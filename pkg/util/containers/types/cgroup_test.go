@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCgroupVersion(t *testing.T) {
+	for _, tc := range []struct {
+		mounts   string
+		expected string
+	}{
+		{
+			mounts:   "cgroup /sys/fs/cgroup/cpuset cgroup rw,relatime,cpuset 0 0\ncgroup /sys/fs/cgroup/memory cgroup rw,relatime,memory 0 0\n",
+			expected: CgroupV1,
+		},
+		{
+			mounts:   "cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime 0 0\n",
+			expected: CgroupV2,
+		},
+		{
+			mounts:   "cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime 0 0\ncgroup /sys/fs/cgroup/memory cgroup rw,relatime,memory 0 0\n",
+			expected: CgroupHybrid,
+		},
+	} {
+		version, err := ParseCgroupVersion(strings.NewReader(tc.mounts))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, version)
+	}
+
+	_, err := ParseCgroupVersion(strings.NewReader("proc /proc proc rw,relatime 0 0\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package types holds the container types and helpers shared by the
+// container runtime utils. It does not depend on the agent configuration, so
+// that the runtime utils can be imported by other tools than the agent.
+package types
+
+import "strings"
+
+// EntitySeparator separates the runtime and the container ID of an entity name
+const EntitySeparator = "://"
+
+// RuntimeNameContainerd is the runtime of the containerd containers
+const RuntimeNameContainerd = "containerd"
+
+// ContainerEntityPrefix is the prefix of the canonical entity names of the
+// containerd containers, container_id://<id>
+const ContainerEntityPrefix = "container_id" + EntitySeparator
+
+// ContainerEntityName returns the canonical entity name of a containerd
+// container, container_id://<id>, empty if id is
+func ContainerEntityName(id string) string {
+	if id == "" {
+		return ""
+	}
+	return ContainerEntityPrefix + id
+}
+
+// CanonicalEntityName returns the canonical name of a container entity: the
+// containerd://<id> names, eg. the container IDs of the kubelet pod statuses,
+// are renamed container_id://<id>. Other names are returned as is.
+func CanonicalEntityName(name string) string {
+	prefix := RuntimeNameContainerd + EntitySeparator
+	if strings.HasPrefix(name, prefix) {
+		return ContainerEntityPrefix + strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+// IsEntityName tests whether a given entity name is valid
+func IsEntityName(name string) bool {
+	return strings.Contains(name, EntitySeparator)
+}
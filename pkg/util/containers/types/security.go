@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package types
+
+// Seccomp modes of a SecurityProfile
+const (
+	SeccompModeUnconfined = "unconfined"
+	SeccompModeFilter     = "filter"
+)
+
+// SecurityProfile is the confinement of the processes of a container by
+// the Linux security modules and seccomp, as set in its spec
+type SecurityProfile struct {
+	// SELinuxLabel is the SELinux label of the processes, empty if none
+	// is set
+	SELinuxLabel string
+	// AppArmorProfile is empty if none is set
+	AppArmorProfile string
+	SeccompMode     string
+}
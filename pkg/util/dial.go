@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// DialContextFunc is the signature of net.Dialer.DialContext
+type DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// GetDialContext returns the DialContext of the dialer, dialing the IPv6
+// addresses of a host before its IPv4 addresses if prefer_ipv6 is set.
+// Otherwise the addresses are dialed in the order of the system resolver.
+func GetDialContext(dialer *net.Dialer) DialContextFunc {
+	if !config.Datadog.GetBool("prefer_ipv6") {
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialPreferIPv6(ctx, dialer, net.DefaultResolver, network, address)
+	}
+}
+
+// dialPreferIPv6 resolves the host and dials its addresses in turn, the
+// IPv6 addresses first
+func dialPreferIPv6(ctx context.Context, dialer *net.Dialer, resolver *net.Resolver, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range sortIPv6First(addrs) {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no address found for %s", host)
+	}
+	return nil, lastErr
+}
+
+// sortIPv6First returns the addresses with the IPv6 ones first, keeping
+// the order of the resolver within each family
+func sortIPv6First(addrs []net.IPAddr) []net.IPAddr {
+	sorted := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		if addr.IP.To4() == nil {
+			sorted = append(sorted, addr)
+		}
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			sorted = append(sorted, addr)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package util
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortIPv6First(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("10.0.0.1")},
+		{IP: net.ParseIP("fd00::1")},
+		{IP: net.ParseIP("10.0.0.2")},
+		{IP: net.ParseIP("fd00::2")},
+	}
+	var sorted []string
+	for _, addr := range sortIPv6First(addrs) {
+		sorted = append(sorted, addr.IP.String())
+	}
+	assert.Equal(t, []string{"fd00::1", "fd00::2", "10.0.0.1", "10.0.0.2"}, sorted)
+}
+
+func TestDialPreferIPv6Literal(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	conn, err := dialPreferIPv6(context.Background(), &net.Dialer{}, net.DefaultResolver, "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	_, err = dialPreferIPv6(context.Background(), &net.Dialer{}, net.DefaultResolver, "tcp", "missing-port")
+	assert.Error(t, err)
+}
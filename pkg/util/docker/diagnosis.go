@@ -8,12 +8,33 @@
 package docker
 
 import (
+	"github.com/docker/docker/api/types"
+
 	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 func init() {
 	diagnosis.Register("Docker availability", diagnose)
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "docker socket reachable",
+		Stage:       diagnosis.StageRuntime,
+		Run:         diagnoseSocket,
+		Remediation: "Check that docker is running and that /var/run/docker.sock is mounted in the agent container, or set DOCKER_HOST. The agent user must be able to read and write the socket",
+	})
+}
+
+func diagnoseSocket() error {
+	du, err := GetDockerUtil()
+	if err != nil {
+		return err
+	}
+	ctrs, err := du.RawContainerList(types.ContainerListOptions{})
+	if err != nil {
+		return err
+	}
+	log.Infof("found %d running containers", len(ctrs))
+	return nil
 }
 
 // diagnose the docker availability on the system
@@ -10,7 +10,9 @@ package ecs
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -204,12 +206,12 @@ func detectAgentURL() (string, error) {
 			log.Debugf("could not get docker default gateway: %s", err)
 		}
 		if gw != nil {
-			urls = append(urls, fmt.Sprintf("http://%s:%d/", gw.String(), DefaultAgentPort))
+			urls = append(urls, agentURL(gw.String()))
 		}
 	}
 
 	// Always try the localhost URL.
-	urls = append(urls, fmt.Sprintf("http://localhost:%d/", DefaultAgentPort))
+	urls = append(urls, agentURL("localhost"))
 
 	detected := testURLs(urls, 1*time.Second)
 	if detected != "" {
@@ -254,10 +256,16 @@ func getAgentContainerURLS() ([]string, error) {
 	}
 
 	for _, network := range ecsConfig.NetworkSettings.Networks {
-		ip := network.IPAddress
-		if ip != "" {
-			urls = append(urls, fmt.Sprintf("http://%s:%d/", ip, DefaultAgentPort))
+		for _, ip := range []string{network.IPAddress, network.GlobalIPv6Address} {
+			if ip != "" {
+				urls = append(urls, agentURL(ip))
+			}
 		}
 	}
 	return urls, nil
 }
+
+// agentURL returns the URL of the ECS agent API on a host or IP address
+func agentURL(host string) string {
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(DefaultAgentPort)) + "/"
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,linux
+
+package hostname
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+)
+
+func init() {
+	RegisterHostnameProvider("containerd", containerdHostname)
+}
+
+// containerdHostname is the containerd hostname provider, for the agents
+// running in a pod of a node without docker nor reachable kubelet. It
+// resolves the name of the node from the metadata of the container of the
+// agent, see containerd.NodeName.
+func containerdHostname(hostName string) (string, error) {
+	cu, err := containerd.GetContainerdUtil(nil)
+	if err != nil {
+		return "", err
+	}
+	id, _, err := metrics.ReadCgroupsForPath("/proc/self/cgroup", config.Datadog.GetString("container_cgroup_prefix"))
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("the agent does not run in a container")
+	}
+	return containerd.NodeName(cu, id)
+}
@@ -31,7 +31,7 @@ func getContainerHostname() (bool, string) {
 		return false, name
 	}
 
-	// Node-agent logic: docker or kubelet
+	// Node-agent logic: docker, kubelet or containerd
 
 	// Docker
 	log.Debug("GetHostname trying Docker API...")
@@ -53,5 +53,13 @@ func getContainerHostname() (bool, string) {
 			return true, name
 		}
 	}
+	// containerd, for the nodes without docker nor reachable kubelet
+	if getContainerdHostname, found := hostname.ProviderCatalog["containerd"]; found {
+		log.Debug("GetHostname trying the pod metadata of containerd...")
+		name, err := getContainerdHostname(name)
+		if err == nil && ValidHostname(name) == nil {
+			return true, name
+		}
+	}
 	return false, name
 }
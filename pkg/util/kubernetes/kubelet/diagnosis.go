@@ -9,12 +9,37 @@
 package kubelet
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 func init() {
 	diagnosis.Register("Kubelet availability", diagnose)
+	diagnosis.RegisterContainerStep(diagnosis.ContainerStep{
+		Name:        "kubelet reachable",
+		Stage:       diagnosis.StageOrchestrator,
+		Run:         diagnoseKubelet,
+		Remediation: "Set kubernetes_kubelet_host to the node IP with the downward API (status.hostIP), and check the kubelet_tls_verify and kubelet_auth_token_path settings",
+	})
+}
+
+// diagnoseKubelet checks that the pods of the node can be listed
+func diagnoseKubelet() error {
+	if !config.IsKubernetes() {
+		log.Info("not running in Kubernetes, skipping")
+		return nil
+	}
+	ku, err := GetKubeUtil()
+	if err != nil {
+		return err
+	}
+	pods, err := ku.GetLocalPodList()
+	if err != nil {
+		return err
+	}
+	log.Infof("found %d pods on the node", len(pods))
+	return nil
 }
 
 // diagnose the API server availability
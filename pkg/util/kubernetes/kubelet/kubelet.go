@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -264,9 +265,11 @@ func (ku *KubeUtil) searchPodForContainerID(podList []*Pod, containerID string)
 	if containerID == "" {
 		return nil, fmt.Errorf("containerID is empty")
 	}
+	// The containerd containers are also known by their canonical entity name
+	containerID = containers.CanonicalEntityName(containerID)
 	for _, pod := range podList {
 		for _, container := range pod.Status.Containers {
-			if container.ID == containerID {
+			if containers.CanonicalEntityName(container.ID) == containerID {
 				return pod, nil
 			}
 		}
@@ -463,7 +466,7 @@ func (ku *KubeUtil) GetRawMetrics() ([]byte, error) {
 
 func (ku *KubeUtil) setupKubeletApiEndpoint() error {
 	// HTTPS
-	ku.kubeletApiEndpoint = fmt.Sprintf("https://%s:%d", ku.kubeletHost, config.Datadog.GetInt("kubernetes_https_kubelet_port"))
+	ku.kubeletApiEndpoint = "https://" + net.JoinHostPort(ku.kubeletHost, config.Datadog.GetString("kubernetes_https_kubelet_port"))
 	_, code, httpsUrlErr := ku.QueryKubelet(kubeletPodPath)
 	if httpsUrlErr == nil {
 		if code == http.StatusOK {
@@ -481,7 +484,7 @@ func (ku *KubeUtil) setupKubeletApiEndpoint() error {
 	ku.resetCredentials()
 
 	// HTTP
-	ku.kubeletApiEndpoint = fmt.Sprintf("http://%s:%d", ku.kubeletHost, config.Datadog.GetInt("kubernetes_http_kubelet_port"))
+	ku.kubeletApiEndpoint = "http://" + net.JoinHostPort(ku.kubeletHost, config.Datadog.GetString("kubernetes_http_kubelet_port"))
 	_, code, httpUrlErr := ku.QueryKubelet(kubeletPodPath)
 	if httpUrlErr == nil {
 		if code == http.StatusOK {
@@ -512,11 +515,11 @@ func (ku *KubeUtil) init() error {
 	c.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
 
 	// HTTPS first
-	if _, errHTTPS := c.Get(fmt.Sprintf("https://%s:%d/", ku.kubeletHost, config.Datadog.GetInt("kubernetes_https_kubelet_port"))); errHTTPS != nil {
+	if _, errHTTPS := c.Get("https://" + net.JoinHostPort(ku.kubeletHost, config.Datadog.GetString("kubernetes_https_kubelet_port")) + "/"); errHTTPS != nil {
 		log.Debugf("Cannot connect through HTTPS: %s, trying through http", errHTTPS)
 
 		// Only try the HTTP if HTTPS failed
-		if _, errHTTP := c.Get(fmt.Sprintf("http://%s:%d/", ku.kubeletHost, config.Datadog.GetInt("kubernetes_http_kubelet_port"))); errHTTP != nil {
+		if _, errHTTP := c.Get("http://" + net.JoinHostPort(ku.kubeletHost, config.Datadog.GetString("kubernetes_http_kubelet_port")) + "/"); errHTTP != nil {
 			log.Debugf("Cannot connect through HTTP: %s", errHTTP)
 			return fmt.Errorf("cannot connect: https: %q, http: %q", errHTTPS, errHTTP)
 		}
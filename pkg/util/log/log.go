@@ -35,7 +35,9 @@ type DatadogLogger struct {
 	inner seelog.LoggerInterface
 	level seelog.LogLevel
 	extra map[string]seelog.LoggerInterface
-	l     sync.Mutex
+	// modules overrides the level of the logs of the Module* functions
+	modules map[string]seelog.LogLevel
+	l       sync.Mutex
 }
 
 // SetupDatadogLogger configure logger singleton with seelog interface
@@ -106,6 +108,23 @@ func (sw *DatadogLogger) shouldLog(level seelog.LogLevel) bool {
 	return (level >= sw.level)
 }
 
+func (sw *DatadogLogger) shouldLogModule(module string, level seelog.LogLevel) bool {
+	sw.l.Lock()
+	defer sw.l.Unlock()
+
+	if lvl, ok := sw.modules[module]; ok {
+		return level >= lvl
+	}
+	return level >= sw.level
+}
+
+func (sw *DatadogLogger) setModuleLevels(levels map[string]seelog.LogLevel) {
+	sw.l.Lock()
+	defer sw.l.Unlock()
+
+	sw.modules = levels
+}
+
 func (sw *DatadogLogger) registerAdditionalLogger(n string, l seelog.LoggerInterface) error {
 	sw.l.Lock()
 	defer sw.l.Unlock()
@@ -478,6 +497,94 @@ func Criticalf(format string, params ...interface{}) error {
 	return err
 }
 
+// ModuleDebugf logs with format at the debug level, unless the level of
+// module is higher, see SetModuleLevels
+func ModuleDebugf(module string, format string, params ...interface{}) {
+	if logger != nil && logger.inner != nil && logger.shouldLogModule(module, seelog.DebugLvl) {
+		logger.debugf(format, params...)
+	} else if bufferLogsBeforeInit && (logger == nil || logger.inner == nil) {
+		addLogToBuffer(func() { ModuleDebugf(module, format, params...) })
+	}
+}
+
+// ModuleInfof logs with format at the info level, unless the level of
+// module is higher, see SetModuleLevels
+func ModuleInfof(module string, format string, params ...interface{}) {
+	if logger != nil && logger.inner != nil && logger.shouldLogModule(module, seelog.InfoLvl) {
+		logger.infof(format, params...)
+	} else if bufferLogsBeforeInit && (logger == nil || logger.inner == nil) {
+		addLogToBuffer(func() { ModuleInfof(module, format, params...) })
+	}
+}
+
+// ModuleWarnf logs with format at the warn level, unless the level of
+// module is higher, see SetModuleLevels, and returns an error containing
+// the formated log message
+func ModuleWarnf(module string, format string, params ...interface{}) error {
+	if logger != nil && logger.inner != nil && logger.shouldLogModule(module, seelog.WarnLvl) {
+		return logger.warnf(format, params...)
+	} else if bufferLogsBeforeInit && (logger == nil || logger.inner == nil) {
+		addLogToBuffer(func() { ModuleWarnf(module, format, params...) })
+	}
+	return formatErrorf(format, params...)
+}
+
+// ModuleErrorf logs with format at the error level, unless the level of
+// module is higher, see SetModuleLevels, and returns an error containing
+// the formated log message
+func ModuleErrorf(module string, format string, params ...interface{}) error {
+	if logger != nil && logger.inner != nil && logger.shouldLogModule(module, seelog.ErrorLvl) {
+		return logger.errorf(format, params...)
+	} else if bufferLogsBeforeInit && (logger == nil || logger.inner == nil) {
+		addLogToBuffer(func() { ModuleErrorf(module, format, params...) })
+	}
+	return formatErrorf(format, params...)
+}
+
+// SetModuleLevels overrides the log level of the modules logging through
+// the Module* functions, by module name, eg. util/containerd: debug. The
+// other modules log at the level of the logger. The inner logger must let
+// the lowest level through, see LowestLevel.
+func SetModuleLevels(levels map[string]string) error {
+	if logger == nil {
+		return errors.New("cannot set the module levels: logger not initialized")
+	}
+	modules := make(map[string]seelog.LogLevel, len(levels))
+	for module, level := range levels {
+		lvl, ok := parseLevel(level)
+		if !ok {
+			return fmt.Errorf("bad log level %q for module %s", level, module)
+		}
+		modules[module] = lvl
+	}
+	logger.setModuleLevels(modules)
+	return nil
+}
+
+// LowestLevel returns the lowest of a log level and of the levels of the
+// modules, the levels which cannot be parsed are ignored
+func LowestLevel(level string, modules map[string]string) string {
+	lowest, ok := parseLevel(level)
+	if !ok {
+		return level
+	}
+	for _, l := range modules {
+		if lvl, ok := parseLevel(l); ok && lvl < lowest {
+			lowest = lvl
+		}
+	}
+	return lowest.String()
+}
+
+// parseLevel parses a log level, warning is accepted as an alias of warn
+func parseLevel(level string) (seelog.LogLevel, bool) {
+	level = strings.ToLower(level)
+	if level == "warning" {
+		level = "warn"
+	}
+	return seelog.LogLevelFromString(level)
+}
+
 // ReplaceLogger allows replacing the internal logger, returns old logger
 func ReplaceLogger(l seelog.LoggerInterface) seelog.LoggerInterface {
 	if logger != nil && logger.inner != nil {
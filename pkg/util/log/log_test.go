@@ -226,3 +226,33 @@ func TestCriticalfNotNil(t *testing.T) {
 
 	assert.NotNil(t, Criticalf("test"))
 }
+
+func TestModuleLevels(t *testing.T) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	l, err := seelog.LoggerFromWriterWithMinLevelAndFormat(w, seelog.DebugLvl, "[%LEVEL] %Msg%n")
+	assert.Nil(t, err)
+
+	SetupDatadogLogger(l, "info")
+	assert.NoError(t, SetModuleLevels(map[string]string{"util/containerd": "debug", "util/docker": "error"}))
+
+	Debugf("%s", "global")
+	ModuleDebugf("util/containerd", "%s", "containerd")
+	ModuleInfof("util/docker", "%s", "docker")
+	ModuleWarnf("util/docker", "%s", "docker")
+	ModuleErrorf("util/docker", "%s", "docker")
+	ModuleDebugf("util/kubelet", "%s", "kubelet")
+	ModuleInfof("util/kubelet", "%s", "kubelet")
+	w.Flush()
+
+	assert.Equal(t, "[DEBUG] containerd\n[ERROR] docker\n[INFO] kubelet\n", b.String())
+
+	assert.Error(t, SetModuleLevels(map[string]string{"util/containerd": "verbose"}))
+}
+
+func TestLowestLevel(t *testing.T) {
+	assert.Equal(t, "info", LowestLevel("info", nil))
+	assert.Equal(t, "debug", LowestLevel("info", map[string]string{"util/containerd": "debug", "util/docker": "error"}))
+	assert.Equal(t, "warn", LowestLevel("WARNING", map[string]string{"util/docker": "verbose"}))
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// ListenUnixgram creates a datagram unix socket on path with the given
+// permissions. The socket is bound on a temporary path where the
+// permissions are applied, then renamed to path, so that the clients never
+// connect to a socket with the default permissions. An existing socket on
+// path is replaced.
+func ListenUnixgram(path string, perms Permissions) (*net.UnixConn, error) {
+	var conn *net.UnixConn
+	err := bindAndRename(path, perms, func(tmpPath string) (func() error, error) {
+		var err error
+		conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: tmpPath, Net: "unixgram"})
+		if err != nil {
+			return nil, err
+		}
+		return conn.Close, nil
+	})
+	return conn, err
+}
+
+// ListenUnix creates a stream unix socket on path with the given
+// permissions, see ListenUnixgram. The socket file is not removed when the
+// listener is closed.
+func ListenUnix(path string, perms Permissions) (*net.UnixListener, error) {
+	var listener *net.UnixListener
+	err := bindAndRename(path, perms, func(tmpPath string) (func() error, error) {
+		var err error
+		listener, err = net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+		if err != nil {
+			return nil, err
+		}
+		// The bound path is renamed, it must not be unlinked on close
+		listener.SetUnlinkOnClose(false)
+		return listener.Close, nil
+	})
+	return listener, err
+}
+
+// bindAndRename binds a socket on a temporary path next to path, applies
+// the permissions and renames it to path. bind returns how to close the
+// socket if it cannot be exposed.
+func bindAndRename(path string, perms Permissions, bind func(tmpPath string) (func() error, error)) error {
+	uid, gid, err := lookupOwnership(perms)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	os.Remove(tmpPath)
+	closeSocket, err := bind(tmpPath)
+	if err != nil {
+		return fmt.Errorf("can't listen on %s: %s", path, err)
+	}
+
+	if err = applyPermissions(tmpPath, uid, gid, perms); err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		closeSocket()
+		os.Remove(tmpPath)
+		return fmt.Errorf("can't create the socket %s: %s", path, err)
+	}
+	return nil
+}
+
+func applyPermissions(path string, uid, gid int, perms Permissions) error {
+	if uid != -1 || gid != -1 {
+		if err := os.Lchown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(path, perms.Mode); err != nil {
+		return err
+	}
+	if perms.SELinuxLabel != "" {
+		return setSELinuxLabel(path, perms.SELinuxLabel)
+	}
+	return nil
+}
+
+// lookupOwnership returns the uid and gid of the socket, -1 to keep the
+// ones of the agent process
+func lookupOwnership(perms Permissions) (int, int, error) {
+	uid, gid := -1, -1
+	if perms.Owner != "" {
+		id := perms.Owner
+		if _, err := strconv.Atoi(id); err != nil {
+			u, err := user.Lookup(perms.Owner)
+			if err != nil {
+				return uid, gid, fmt.Errorf("unknown socket owner: %s", err)
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+	if perms.Group != "" {
+		id := perms.Group
+		if _, err := strconv.Atoi(id); err != nil {
+			g, err := user.LookupGroup(perms.Group)
+			if err != nil {
+				return uid, gid, fmt.Errorf("unknown socket group: %s", err)
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+	return uid, gid, nil
+}
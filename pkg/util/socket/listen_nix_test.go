@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package socket
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dsd.sock")
+
+	// A stale socket is replaced
+	stale, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	stale.Close()
+
+	perms := Permissions{
+		Owner: strconv.Itoa(os.Getuid()),
+		Group: strconv.Itoa(os.Getgid()),
+		Mode:  0722,
+	}
+	conn, err := ListenUnixgram(path, perms)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode()&os.ModeSocket != 0)
+	assert.Equal(t, os.FileMode(0722), fi.Mode().Perm())
+	assert.Equal(t, uint32(os.Getgid()), fi.Sys().(*syscall.Stat_t).Gid)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	client, err := net.Dial("unixgram", path)
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("custom.metric:1|c"))
+	require.NoError(t, err)
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "custom.metric:1|c", string(buf[:n]))
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ipc.sock")
+
+	listener, err := ListenUnix(path, Permissions{Mode: 0660})
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), fi.Mode().Perm())
+
+	listener.Close()
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestListenUnknownOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dsd.sock")
+
+	_, err = ListenUnixgram(path, Permissions{Owner: "no-such-user-dd", Mode: 0722})
+	assert.Error(t, err)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package socket
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrNotSupported is returned on Windows, where the agent serves no unix socket
+var ErrNotSupported = errors.New("unix sockets are not supported on Windows")
+
+// ListenUnixgram is not supported on Windows
+func ListenUnixgram(path string, perms Permissions) (*net.UnixConn, error) {
+	return nil, ErrNotSupported
+}
+
+// ListenUnix is not supported on Windows
+func ListenUnix(path string, perms Permissions) (*net.UnixListener, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package socket creates the unix sockets served by the agent with the
+// ownership, mode and SELinux label configured by the user.
+package socket
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// Permissions are applied to a unix socket before it is exposed on its path
+type Permissions struct {
+	// Owner and Group are names or numeric IDs, the ownership is not
+	// changed if they are empty
+	Owner string
+	Group string
+	Mode  os.FileMode
+	// SELinuxLabel is the security context of the socket file, eg.
+	// system_u:object_r:container_file_t:s0, not changed if empty
+	SELinuxLabel string
+}
+
+// PermissionsFromConfig reads the permissions of a socket from the
+// <prefix>_owner, <prefix>_group, <prefix>_mode and <prefix>_selinux_label
+// settings, eg. dogstatsd_socket_mode.
+func PermissionsFromConfig(prefix string) (Permissions, error) {
+	perms := Permissions{
+		Owner:        config.Datadog.GetString(prefix + "_owner"),
+		Group:        config.Datadog.GetString(prefix + "_group"),
+		SELinuxLabel: config.Datadog.GetString(prefix + "_selinux_label"),
+	}
+	mode, err := parseMode(config.Datadog.Get(prefix + "_mode"))
+	if err != nil {
+		return perms, fmt.Errorf("invalid %s_mode: %s", prefix, err)
+	}
+	perms.Mode = mode
+	return perms, nil
+}
+
+// parseMode accepts the octal strings of the environment variables and of
+// the quoted yaml values, and the integers of the unquoted yaml values,
+// already parsed from octal by the yaml decoder
+func parseMode(value interface{}) (os.FileMode, error) {
+	var mode uint64
+	switch v := value.(type) {
+	case int:
+		mode = uint64(v)
+	case int64:
+		mode = uint64(v)
+	case string:
+		var err error
+		if mode, err = strconv.ParseUint(v, 8, 32); err != nil {
+			return 0, fmt.Errorf("%q is not an octal mode", v)
+		}
+	default:
+		return 0, fmt.Errorf("unexpected mode %v", value)
+	}
+	if mode > 0777 {
+		return 0, fmt.Errorf("%o is not a permission mode", mode)
+	}
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package socket
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		mode  os.FileMode
+		err   bool
+	}{
+		{value: "0722", mode: 0722},
+		{value: "660", mode: 0660},
+		{value: 0722, mode: 0722},
+		{value: "rw-rw----", err: true},
+		{value: "04755", err: true},
+		{value: true, err: true},
+	} {
+		mode, err := parseMode(tc.value)
+		if tc.err {
+			assert.Error(t, err, "%v", tc.value)
+			continue
+		}
+		require.NoError(t, err, "%v", tc.value)
+		assert.Equal(t, tc.mode, mode)
+	}
+}
+
+func TestPermissionsFromConfig(t *testing.T) {
+	mockConfig := config.Mock()
+	perms, err := PermissionsFromConfig("dogstatsd_socket")
+	require.NoError(t, err)
+	assert.Equal(t, Permissions{Mode: 0722}, perms)
+
+	mockConfig.Set("dogstatsd_socket_group", "datadog")
+	mockConfig.Set("dogstatsd_socket_mode", "0720")
+	mockConfig.Set("dogstatsd_socket_selinux_label", "system_u:object_r:container_file_t:s0")
+	perms, err = PermissionsFromConfig("dogstatsd_socket")
+	require.NoError(t, err)
+	assert.Equal(t, Permissions{
+		Group:        "datadog",
+		Mode:         0720,
+		SELinuxLabel: "system_u:object_r:container_file_t:s0",
+	}, perms)
+
+	mockConfig.Set("dogstatsd_socket_mode", "rw")
+	_, err = PermissionsFromConfig("dogstatsd_socket")
+	assert.Error(t, err)
+}
@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !linux,!windows
+
+package socket
+
+import "errors"
+
+func setSELinuxLabel(path, label string) error {
+	return errors.New("SELinux labels are only supported on Linux hosts")
+}
@@ -24,6 +24,7 @@ AGENT_TAG = "datadog/agent:master"
 DEFAULT_BUILD_TAGS = [
     "apm",
     "consul",
+    "containerd",
     "cpython",
     "cri",
     "docker",
@@ -43,6 +44,7 @@ DEFAULT_BUILD_TAGS = [
 ]
 
 AGENT_CORECHECKS = [
+    "containerd",
     "cpu",
     "cri",
     "docker",
@@ -50,6 +52,7 @@ AGENT_CORECHECKS = [
     "go_expvar",
     "io",
     "jmx",
+    "kernel_inventory",
     "kubernetes_apiserver",
     "load",
     "memory",
@@ -264,6 +267,28 @@ def integration_tests(ctx, install_deps=False, race=False, remote_docker=False):
         ctx.run("{} {}".format(go_cmd, prefix))
 
 
+@task
+def containerd_e2e_tests(ctx, race=False):
+    """
+    Run the end-to-end tests of the containerd integration against a real
+    containerd daemon. containerd, its runc shim and runc must be installed,
+    and the tests must run as root.
+    """
+    build_tags = get_default_build_tags() + ["containerd_e2e"]
+    go_cmd = 'go test {race_opt} -tags "{go_build_tags}"'.format(
+        race_opt="-race" if race else "",
+        go_build_tags=" ".join(build_tags),
+    )
+
+    prefixes = [
+        "./test/integration/util/containerd/...",
+        "./test/integration/corechecks/containerd/...",
+    ]
+
+    for prefix in prefixes:
+        ctx.run("{} {}".format(go_cmd, prefix))
+
+
 @task(help={'skip-sign': "On macOS, use this option to build an unsigned package if you don't have Datadog's developer keys."})
 def omnibus_build(ctx, puppy=False, log_level="info", base_dir=None, gem_path=None,
                   skip_deps=False, skip_sign=False, release_version="nightly", omnibus_s3_cache=False):
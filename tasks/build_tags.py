@@ -10,6 +10,7 @@ ALL_TAGS = set([
     "apm",
     "clusterchecks",
     "consul",
+    "containerd",
     "cpython",
     "cri",
     "docker",
@@ -58,7 +59,8 @@ def get_default_build_tags(puppy=False):
     Build the default list of tags based on the current platform.
 
     The container integrations are currently only supported on Linux, disabling on
-    the Windows and Darwin builds.
+    the Windows and Darwin builds. The containerd integration is the exception, it
+    also supports containerd running natively on Windows.
     """
     if puppy:
         return PUPPY_TAGS
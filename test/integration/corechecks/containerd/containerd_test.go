@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,containerd_e2e
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/containers"
+)
+
+const checkConfig = `
+tags:
+  - instance:e2e
+`
+
+func TestContainerdCheck(t *testing.T) {
+	_, err := daemon.RunContainer(namespace, "sleeper", nil, "sleep", "3600")
+	require.NoError(t, err)
+	defer daemon.RemoveContainer(namespace, "sleeper")
+
+	check := containers.ContainerdFactory()
+	require.NoError(t, check.Configure([]byte(checkConfig), []byte("")))
+	sender := mocksender.NewMockSender(check.ID())
+	sender.SetupAcceptAll()
+
+	// The events of the task are collected by the watcher started by the
+	// first run, the rates are sent from the second one
+	require.NoError(t, check.Run())
+	require.NoError(t, daemon.RemoveContainer(namespace, "sleeper"))
+	_, err = daemon.RunContainer(namespace, "sleeper", nil, "sleep", "3600")
+	require.NoError(t, err)
+	time.Sleep(2 * time.Second)
+	require.NoError(t, check.Run())
+
+	sender.AssertCalled(t, "Gauge", "containerd.namespace.tasks", float64(1), "", mock.AnythingOfType("[]string"))
+	sender.AssertCalled(t, "Gauge", "containerd.mem.rss", mock.AnythingOfType("float64"), "", mock.AnythingOfType("[]string"))
+	sender.AssertCalled(t, "Rate", "containerd.cpu.total", mock.AnythingOfType("float64"), "", mock.AnythingOfType("[]string"))
+	sender.AssertCalled(t, "Event", mock.AnythingOfType("metrics.Event"))
+}
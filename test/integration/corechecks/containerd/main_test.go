@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,containerd_e2e
+
+// Package containerd runs the containerd check against a real daemon. The
+// containerd and containerd-shim-runc-v2 binaries, and runc, must be in the
+// PATH, and the tests run as root:
+//
+//	go test -tags "containerd containerd_e2e" ./test/integration/corechecks/containerd/
+package containerd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/test/integration/utils"
+)
+
+const namespace = "e2e-check"
+
+var startTimeout = flag.Duration("start-timeout", 10*time.Second, "maximum time for containerd to start")
+
+var daemon *utils.ContainerdDaemon
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	var err error
+	daemon, err = utils.StartContainerd(*startTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot start containerd: %s\n", err)
+		os.Exit(1)
+	}
+	code := run(m)
+	daemon.Stop()
+	os.Exit(code)
+}
+
+func run(m *testing.M) int {
+	config.Datadog.Set("container_runtime", "containerd")
+	config.Datadog.Set("cri_socket_path", daemon.SocketPath)
+	config.Datadog.Set("containerd_namespace", namespace)
+	config.Datadog.Set("containerd_collect_events", true)
+	// The state of the agent of the host is not read
+	config.Datadog.Set("containerd_event_bookmark_path", "")
+	config.Datadog.Set("containerd_metadata_store_path", "")
+	if err := tagger.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot start the tagger: %s\n", err)
+		return 1
+	}
+	return m.Run()
+}
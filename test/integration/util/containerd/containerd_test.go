@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,containerd_e2e
+
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ddcontainerd "github.com/DataDog/datadog-agent/pkg/util/containerd"
+)
+
+func newUtil(t *testing.T, namespace string) *ddcontainerd.ContainerdUtil {
+	cu, err := ddcontainerd.NewContainerdUtil(ddcontainerd.Options{
+		SocketPath: daemon.SocketPath,
+		Namespace:  namespace,
+	})
+	require.NoError(t, err)
+	return cu
+}
+
+func TestContainerdUtil(t *testing.T) {
+	_, err := daemon.RunContainer("e2e-util", "sleeper", map[string]string{"app": "sleeper"}, "sleep", "3600")
+	require.NoError(t, err)
+	defer daemon.RemoveContainer("e2e-util", "sleeper")
+	cu := newUtil(t, "e2e-util")
+	defer cu.Close()
+
+	version, err := cu.Metadata()
+	require.NoError(t, err)
+	assert.NotEmpty(t, version.Version)
+
+	namespaces, err := cu.Namespaces()
+	require.NoError(t, err)
+	assert.Contains(t, namespaces, "e2e-util")
+
+	ctns, err := cu.Containers()
+	require.NoError(t, err)
+	require.Len(t, ctns, 1)
+	assert.Equal(t, "sleeper", ctns[0].ID())
+	assert.False(t, ctns[0].StartedAt().IsZero())
+
+	pids, err := cu.TaskPids(ctns[0])
+	require.NoError(t, err)
+	assert.NotEmpty(t, pids)
+	metric, err := cu.TaskMetrics(ctns[0])
+	require.NoError(t, err)
+	stats, err := ddcontainerd.DecodeTaskMetrics(metric)
+	require.NoError(t, err)
+	assert.NotZero(t, stats.MemoryUsage)
+
+	all, err := cu.CollectAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "sleeper", all[0].ContainerID)
+
+	cached, err := cu.CachedContainers()
+	require.NoError(t, err)
+	require.Len(t, cached, 1)
+	assert.Equal(t, map[string]string{"app": "sleeper"}, cached[0].Labels)
+
+	running, err := ddcontainerd.RunningContainers(cu)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	assert.Equal(t, "container_id://sleeper", ddcontainerd.EntityID(running[0].ID))
+	assert.NotEmpty(t, running[0].Pids)
+	assert.False(t, running[0].StartedAt.IsZero())
+}
+
+func TestContainerdEvents(t *testing.T) {
+	cu := newUtil(t, "e2e-events")
+	defer cu.Close()
+
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), "e2e-events"))
+	defer cancel()
+	filters := ddcontainerd.NewNamespaceFilter([]string{"e2e-events"}, nil).EventFilters(ddcontainerd.ContainerCacheFilters...)
+	messages, errs := cu.GetEvents().Subscribe(ctx, filters...)
+
+	_, err := daemon.RunContainer("e2e-events", "short-lived", nil, "sleep", "3600")
+	require.NoError(t, err)
+	require.NoError(t, daemon.RemoveContainer("e2e-events", "short-lived"))
+
+	receive := func() *events.Envelope {
+		select {
+		case envelope := <-messages:
+			return envelope
+		case err := <-errs:
+			require.FailNow(t, "the subscription failed", "%s", err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no event received")
+		}
+		return nil
+	}
+	created := receive()
+	assert.Equal(t, ddcontainerd.ContainerCreateTopic, created.Topic)
+	assert.Equal(t, "e2e-events", created.Namespace)
+	deleted := receive()
+	assert.Equal(t, ddcontainerd.ContainerDeleteTopic, deleted.Topic)
+}
+
+func TestContainerdRestartTask(t *testing.T) {
+	_, err := daemon.RunContainer("e2e-restart", "restarted", nil, "sleep", "3600")
+	require.NoError(t, err)
+	defer daemon.RemoveContainer("e2e-restart", "restarted")
+
+	denied := newUtil(t, "e2e-restart")
+	defer denied.Close()
+	err = denied.RestartTask(context.Background(), "restarted")
+	assert.Equal(t, ddcontainerd.ErrRestartDisabled, ddcontainerd.ErrorKind(err))
+
+	cu, err := ddcontainerd.NewContainerdUtil(ddcontainerd.Options{
+		SocketPath:       daemon.SocketPath,
+		Namespace:        "e2e-restart",
+		AllowTaskRestart: true,
+	})
+	require.NoError(t, err)
+	defer cu.Close()
+	pidsOf := func() []containerd.ProcessInfo {
+		ctns, err := cu.Containers()
+		require.NoError(t, err)
+		require.Len(t, ctns, 1)
+		pids, err := cu.TaskPids(ctns[0])
+		require.NoError(t, err)
+		require.NotEmpty(t, pids)
+		return pids
+	}
+	before := pidsOf()
+	require.NoError(t, cu.RestartTask(context.Background(), "restarted"))
+	// The task is running again, with a new process
+	assert.NotEqual(t, before, pidsOf())
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd,containerd_e2e
+
+// Package containerd runs the containerd util against a real daemon. The
+// containerd and containerd-shim-runc-v2 binaries, and runc, must be in the
+// PATH, and the tests run as root:
+//
+//	go test -tags "containerd containerd_e2e" ./test/integration/util/containerd/
+package containerd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/test/integration/utils"
+)
+
+var startTimeout = flag.Duration("start-timeout", 10*time.Second, "maximum time for containerd to start")
+
+var daemon *utils.ContainerdDaemon
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	var err error
+	daemon, err = utils.StartContainerd(*startTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot start containerd: %s\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	daemon.Stop()
+	os.Exit(code)
+}
@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build containerd_e2e
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+)
+
+// containerdConfig disables the CRI plugin, which needs a CNI setup, and
+// the snapshotters needing a specific filesystem
+const containerdConfig = `version = 2
+disabled_plugins = [
+  "io.containerd.grpc.v1.cri",
+  "io.containerd.snapshotter.v1.aufs",
+  "io.containerd.snapshotter.v1.btrfs",
+  "io.containerd.snapshotter.v1.devmapper",
+  "io.containerd.snapshotter.v1.zfs",
+]
+`
+
+// ContainerdDaemon is a containerd daemon run in a temporary directory for
+// the end-to-end tests, see StartContainerd
+type ContainerdDaemon struct {
+	// SocketPath is the socket of the daemon, named like the default one
+	// for the agent to detect the containerd runtime
+	SocketPath string
+	Client     *containerd.Client
+	dir        string
+	cmd        *exec.Cmd
+}
+
+// StartContainerd runs the containerd binary found in the PATH, with its
+// root, state, config and socket in a temporary directory, and waits for it
+// to serve until timeout. Its logs are written to containerd.log in this
+// directory. The tasks are run by the runc shim, which must be in the PATH
+// too, and require root.
+func StartContainerd(timeout time.Duration) (*ContainerdDaemon, error) {
+	binary, err := exec.LookPath("containerd")
+	if err != nil {
+		return nil, fmt.Errorf("containerd is not installed: %s", err)
+	}
+	dir, err := ioutil.TempDir("", "containerd-e2e")
+	if err != nil {
+		return nil, err
+	}
+	d := &ContainerdDaemon{
+		SocketPath: filepath.Join(dir, "containerd.sock"),
+		dir:        dir,
+	}
+	configPath := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(configPath, []byte(containerdConfig), 0644); err != nil {
+		d.Stop()
+		return nil, err
+	}
+	logs, err := os.Create(filepath.Join(dir, "containerd.log"))
+	if err != nil {
+		d.Stop()
+		return nil, err
+	}
+	defer logs.Close()
+
+	d.cmd = exec.Command(binary,
+		"--config", configPath,
+		"--root", filepath.Join(dir, "root"),
+		"--state", filepath.Join(dir, "state"),
+		"--address", d.SocketPath,
+	)
+	d.cmd.Stdout = logs
+	d.cmd.Stderr = logs
+	if err := d.cmd.Start(); err != nil {
+		d.Stop()
+		return nil, err
+	}
+
+	d.Client, err = containerd.New(d.SocketPath, containerd.WithTimeout(timeout))
+	if err != nil {
+		d.Stop()
+		return nil, fmt.Errorf("containerd did not start, see %s: %s", logs.Name(), err)
+	}
+	return d, nil
+}
+
+// Stop stops the daemon and removes its directory. The tasks left running
+// are killed with their shims.
+func (d *ContainerdDaemon) Stop() error {
+	if d.Client != nil {
+		d.Client.Close()
+	}
+	if d.cmd != nil && d.cmd.Process != nil {
+		d.cmd.Process.Signal(syscall.SIGTERM)
+		d.cmd.Wait()
+	}
+	// The mounts of the shims are gone with the daemon
+	return os.RemoveAll(d.dir)
+}
+
+// RunContainer creates a container running args in namespace, with the
+// filesystem of the host as read-only root, and starts its task. No image
+// is pulled, the containers have no image.
+func (d *ContainerdDaemon) RunContainer(namespace, id string, labels map[string]string, args ...string) (containerd.Container, error) {
+	ctx := namespaces.WithNamespace(context.Background(), namespace)
+	ctn, err := d.Client.NewContainer(ctx, id,
+		containerd.WithContainerLabels(labels),
+		containerd.WithRuntime("io.containerd.runc.v2", nil),
+		containerd.WithNewSpec(
+			oci.WithRootFSPath("/"),
+			oci.WithRootFSReadonly(),
+			oci.WithProcessArgs(args...),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	task, err := ctn.NewTask(ctx, cio.NullIO)
+	if err != nil {
+		ctn.Delete(ctx)
+		return nil, err
+	}
+	if err := task.Start(ctx); err != nil {
+		task.Delete(ctx, containerd.WithProcessKill)
+		ctn.Delete(ctx)
+		return nil, err
+	}
+	return ctn, nil
+}
+
+// RemoveContainer kills the task of a container, then deletes it
+func (d *ContainerdDaemon) RemoveContainer(namespace, id string) error {
+	ctx := namespaces.WithNamespace(context.Background(), namespace)
+	ctn, err := d.Client.LoadContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+	task, err := ctn.Task(ctx, nil)
+	switch {
+	case err == nil:
+		if _, err := task.Delete(ctx, containerd.WithProcessKill); err != nil {
+			return err
+		}
+	case !errdefs.IsNotFound(err):
+		return err
+	}
+	return ctn.Delete(ctx)
+}